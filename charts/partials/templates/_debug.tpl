@@ -1,5 +1,5 @@
 {{- define "partials.debug" -}}
-image: {{.Values.debugContainer.image.name}}:{{.Values.debugContainer.image.version | default .Values.linkerdVersion}}
+image: {{.Values.debugContainer.image.name}}:{{.Values.debugContainer.image.version | default .Values.linkerdVersion}}{{with .Values.debugContainer.image.digest}}@{{.}}{{end}}
 imagePullPolicy: {{.Values.debugContainer.image.pullPolicy | default .Values.imagePullPolicy}}
 name: linkerd-debug
 terminationMessagePolicy: FallbackToLogsOnError
@@ -12,3 +12,16 @@ For example "11,22,55,44" will become "11","22","55","44"
 {{- end -}}
 {{- end -}}
 {{- end -}}
+
+{{/*
+Returns the address a webhook server listens on, given its bindAddress and
+containerPort values. IPv6 addresses are enclosed in brackets.
+For example "::" and 8443 will become "[::]:8443"
+*/}}
+{{- define "partials.webhook.addr" -}}
+{{- $host := .bindAddress | default "" -}}
+{{- if contains ":" $host -}}
+{{- $host = printf "[%s]" $host -}}
+{{- end -}}
+{{- printf "%s:%v" $host (.containerPort | default 8443) -}}
+{{- end -}}
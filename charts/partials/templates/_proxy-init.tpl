@@ -16,7 +16,7 @@ args:
 - --timeout-close-wait-secs
 - {{ .Values.proxyInit.closeWaitTimeoutSecs | quote}}
 {{- end }}
-image: {{.Values.proxyInit.image.name}}:{{.Values.proxyInit.image.version}}
+image: {{.Values.proxyInit.image.name}}:{{.Values.proxyInit.image.version}}{{with .Values.proxyInit.image.digest}}@{{.}}{{end}}
 imagePullPolicy: {{.Values.proxyInit.image.pullPolicy | default .Values.imagePullPolicy}}
 name: linkerd-init
 {{ include "partials.resources" .Values.proxyInit.resources }}
@@ -43,9 +43,13 @@ securityContext:
   {{- else }}
   privileged: false
   {{- end }}
-  readOnlyRootFilesystem: true
+  readOnlyRootFilesystem: {{ ne (toString .Values.proxyInit.readOnlyRootFilesystem) "false" }}
   runAsNonRoot: false
   runAsUser: 0
+  {{- if .Values.proxyInit.seccompProfile }}
+  seccompProfile:
+    {{- toYaml .Values.proxyInit.seccompProfile | trim | nindent 4 }}
+  {{- end }}
 terminationMessagePolicy: FallbackToLogsOnError
 {{- if or (not .Values.cniEnabled) .Values.proxyInit.saMountPath }}
 volumeMounts:
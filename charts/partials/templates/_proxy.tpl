@@ -24,6 +24,18 @@ env:
 - name: LINKERD2_PROXY_OUTBOUND_CONNECT_TIMEOUT
   value: {{.Values.proxy.outboundConnectTimeout | quote}}
 {{ end -}}
+{{ if .Values.proxy.inboundMaxInFlight -}}
+- name: LINKERD2_PROXY_INBOUND_MAX_IN_FLIGHT
+  value: {{.Values.proxy.inboundMaxInFlight | quote}}
+{{ end -}}
+{{ if .Values.proxy.outboundMaxInFlight -}}
+- name: LINKERD2_PROXY_OUTBOUND_MAX_IN_FLIGHT
+  value: {{.Values.proxy.outboundMaxInFlight | quote}}
+{{ end -}}
+{{ if .Values.proxy.bufferCapacity -}}
+- name: LINKERD2_PROXY_BUFFER_CAPACITY
+  value: {{.Values.proxy.bufferCapacity | quote}}
+{{ end -}}
 - name: LINKERD2_PROXY_CONTROL_LISTEN_ADDR
   value: 0.0.0.0:{{.Values.proxy.ports.control}}
 - name: LINKERD2_PROXY_ADMIN_LISTEN_ADDR
@@ -55,6 +67,10 @@ env:
   value: 10000ms
 - name: LINKERD2_PROXY_OUTBOUND_CONNECT_KEEPALIVE
   value: 10000ms
+{{ if .Values.proxy.jobShutdown -}}
+- name: LINKERD2_PROXY_SHUTDOWN_ENDPOINT_ENABLED
+  value: "true"
+{{ end -}}
 {{ if .Values.proxy.opaquePorts -}}
 - name: LINKERD2_PROXY_INBOUND_PORTS_DISABLE_PROTOCOL_DETECTION
   value: {{.Values.proxy.opaquePorts | quote}}
@@ -110,7 +126,7 @@ be used in other contexts.
 - name: LINKERD2_PROXY_DESTINATION_SVC_NAME
   value: linkerd-destination.$(_l5d_ns).serviceaccount.identity.$(_l5d_ns).$(_l5d_trustdomain)
 {{ end -}}
-image: {{.Values.proxy.image.name}}:{{.Values.proxy.image.version | default .Values.linkerdVersion}}
+image: {{.Values.proxy.image.name}}:{{.Values.proxy.image.version | default .Values.linkerdVersion}}{{with .Values.proxy.image.digest}}@{{.}}{{end}}
 imagePullPolicy: {{.Values.proxy.image.pullPolicy | default .Values.imagePullPolicy}}
 livenessProbe:
   httpGet:
@@ -136,8 +152,12 @@ securityContext:
   {{- if .Values.proxy.capabilities -}}
   {{- include "partials.proxy.capabilities" . | nindent 2 -}}
   {{- end }}
-  readOnlyRootFilesystem: true
+  readOnlyRootFilesystem: {{ ne (toString .Values.proxy.readOnlyRootFilesystem) "false" }}
   runAsUser: {{.Values.proxy.uid}}
+  {{- if .Values.proxy.seccompProfile }}
+  seccompProfile:
+    {{- toYaml .Values.proxy.seccompProfile | trim | nindent 4 }}
+  {{- end }}
 terminationMessagePolicy: FallbackToLogsOnError
 {{- if or (.Values.proxy.await) (.Values.proxy.waitBeforeExitSeconds) }}
 lifecycle:
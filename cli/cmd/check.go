@@ -12,7 +12,9 @@ import (
 	"github.com/linkerd/linkerd2/cli/flag"
 	charts "github.com/linkerd/linkerd2/pkg/charts/linkerd2"
 	pkgcmd "github.com/linkerd/linkerd2/pkg/cmd"
+	"github.com/linkerd/linkerd2/pkg/health"
 	"github.com/linkerd/linkerd2/pkg/healthcheck"
+	"github.com/linkerd/linkerd2/pkg/issuercerts"
 	"github.com/linkerd/linkerd2/pkg/k8s"
 	"github.com/linkerd/linkerd2/pkg/version"
 	"github.com/spf13/cobra"
@@ -29,6 +31,7 @@ type checkOptions struct {
 	cniEnabled         bool
 	output             string
 	cliVersionOverride string
+	expiryWindow       time.Duration
 }
 
 func newCheckOptions() *checkOptions {
@@ -41,6 +44,7 @@ func newCheckOptions() *checkOptions {
 		cniEnabled:         false,
 		output:             tableOutput,
 		cliVersionOverride: "",
+		expiryWindow:       issuercerts.DefaultExpiryWindow,
 	}
 }
 
@@ -52,6 +56,7 @@ func (options *checkOptions) nonConfigFlagSet() *pflag.FlagSet {
 	flags.StringVarP(&options.namespace, "namespace", "n", options.namespace, "Namespace to use for --proxy checks (default: all namespaces)")
 	flags.BoolVar(&options.preInstallOnly, "pre", options.preInstallOnly, "Only run pre-installation checks, to determine if the control plane can be installed")
 	flags.BoolVar(&options.dataPlaneOnly, "proxy", options.dataPlaneOnly, "Only run data-plane checks, to determine if the data plane is healthy")
+	flags.DurationVar(&options.expiryWindow, "expiry-window", options.expiryWindow, "Warn about certificates expiring within this window")
 
 	return flags
 }
@@ -78,6 +83,9 @@ func (options *checkOptions) validate() error {
 	if options.output != tableOutput && options.output != jsonOutput && options.output != shortOutput {
 		return fmt.Errorf("Invalid output type '%s'. Supported output types are: %s, %s, %s", options.output, jsonOutput, tableOutput, shortOutput)
 	}
+	if options.expiryWindow <= 0 {
+		return errors.New("--expiry-window must be positive")
+	}
 	return nil
 }
 
@@ -140,8 +148,7 @@ non-zero exit code.`,
 
 	cmd.AddCommand(newCmdCheckConfig(options))
 
-	pkgcmd.ConfigureNamespaceFlagCompletion(cmd, []string{"namespace"},
-		kubeconfigPath, impersonate, impersonateGroup, kubeContext)
+	pkgcmd.ConfigureNamespaceFlagCompletion(cmd, []string{"namespace"})
 
 	return cmd
 }
@@ -170,6 +177,7 @@ func configureAndRunChecks(cmd *cobra.Command, wout io.Writer, werr io.Writer, s
 		} else {
 			checks = append(checks, healthcheck.LinkerdPreInstallCapabilityChecks)
 		}
+		checks = append(checks, healthcheck.LinkerdPreInstallNetworkChecks)
 		installManifest, err = renderInstallManifest(cmd.Context())
 		if err != nil {
 			fmt.Fprint(os.Stderr, fmt.Errorf("Error rendering install manifest: %v", err))
@@ -209,6 +217,7 @@ func configureAndRunChecks(cmd *cobra.Command, wout io.Writer, werr io.Writer, s
 		RetryDeadline:         time.Now().Add(options.wait),
 		CNIEnabled:            options.cniEnabled,
 		InstallManifest:       installManifest,
+		ExpiryWindow:          options.expiryWindow,
 	})
 
 	if options.output != jsonOutput {
@@ -249,11 +258,18 @@ func runExtensionChecks(cmd *cobra.Command, wout io.Writer, werr io.Writer, opts
 	}
 
 	nsLabels := make([]string, len(namespaces))
+	reports := make(map[string][]health.Report)
 	for i, ns := range namespaces {
-		nsLabels[i] = ns.Labels[k8s.LinkerdExtensionLabel]
+		extension := ns.Labels[k8s.LinkerdExtensionLabel]
+		nsLabels[i] = extension
+		// the extension checks report the problems preventing the health
+		// reports from being fetched
+		if r, err := health.FetchReports(cmd.Context(), kubeAPI, ns.Name, extension); err == nil {
+			reports[extension] = r
+		}
 	}
 
-	extensionSuccess := healthcheck.RunExtensionsChecks(wout, werr, nsLabels, getExtensionCheckFlags(cmd.Flags()), opts.output)
+	extensionSuccess := healthcheck.RunExtensionsChecks(wout, werr, nsLabels, getExtensionCheckFlags(cmd.Flags()), reports, opts.output)
 	return extensionSuccess, nil
 }
 
@@ -4,8 +4,12 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"os"
+	"strings"
 
+	"github.com/linkerd/linkerd2/pkg/version"
 	"github.com/spf13/cobra"
+	cobradoc "github.com/spf13/cobra/doc"
 )
 
 // newCmdCompletion creates a new cobra command `completion` which contains commands for
@@ -50,16 +54,32 @@ func newCmdCompletion() *cobra.Command {
   linkerd completion fish | source
 
   # To load fish shell completions for each session, execute once:
-  linkerd completion fish > ~/.config/fish/completions/linkerd.fish`
+  linkerd completion fish > ~/.config/fish/completions/linkerd.fish
+
+  # man:
+  # To generate a man page for each command in the man1 section of your manpath:
+  linkerd completion man --man-dir /usr/local/share/man/man1`
+
+	manDir := "."
 
 	cmd := &cobra.Command{
-		Use:       "completion [bash|zsh|fish]",
-		Short:     "Output shell completion code for the specified shell (bash, zsh or fish)",
-		Long:      "Output shell completion code for the specified shell (bash, zsh or fish).",
+		Use:   "completion [bash|zsh|fish|man]",
+		Short: "Output shell completion code for the specified shell (bash, zsh or fish), or generate man pages",
+		Long: `Output shell completion code for the specified shell (bash, zsh or fish), or generate man pages.
+
+With man, a man page is written for each command to the directory given by --man-dir.`,
 		Example:   example,
 		Args:      cobra.ExactArgs(1),
-		ValidArgs: []string{"bash", "zsh", "fish"},
+		ValidArgs: []string{"bash", "zsh", "fish", "man"},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if args[0] == "man" {
+				if err := generateManPages(cmd.Parent(), manDir); err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "Man pages written to %s\n", manDir)
+				return nil
+			}
+
 			out, err := getCompletion(args[0], cmd.Parent())
 			if err != nil {
 				return err
@@ -70,9 +90,26 @@ func newCmdCompletion() *cobra.Command {
 		},
 	}
 
+	cmd.Flags().StringVar(&manDir, "man-dir", manDir, "Directory to write the man pages to, when generating them with man")
+
 	return cmd
 }
 
+// generateManPages writes a man page for the given command and each of its
+// subcommands to dir.
+func generateManPages(root *cobra.Command, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create the man pages directory: %s", err)
+	}
+	header := &cobradoc.GenManHeader{
+		Title:   strings.ToUpper(root.Name()),
+		Section: "1",
+		Source:  fmt.Sprintf("Linkerd %s", version.Version),
+		Manual:  "Linkerd Manual",
+	}
+	return cobradoc.GenManTree(root, header, dir)
+}
+
 // getCompletion will return the auto completion shell script, if supported
 func getCompletion(sh string, parent *cobra.Command) (string, error) {
 	var err error
@@ -1,6 +1,9 @@
 package cmd
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -33,4 +36,20 @@ func TestCompletion(t *testing.T) {
 			t.Fatalf("Unexpected success for invalid shell type: %+v", out)
 		}
 	})
+	t.Run("Generates man pages", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "linkerd-man")
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		defer os.RemoveAll(dir)
+
+		if err := generateManPages(RootCmd, dir); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		for _, page := range []string{"linkerd.1", "linkerd-install.1", "linkerd-viz-stat.1"} {
+			if _, err := os.Stat(filepath.Join(dir, page)); err != nil {
+				t.Fatalf("Expected man page %s to be generated: %s", page, err)
+			}
+		}
+	})
 }
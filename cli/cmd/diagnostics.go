@@ -27,12 +27,32 @@ This command provides subcommands to diagnose the functionality of Linkerd.`,
  
   # Get the endpoints for authorities in Linkerd's control-plane itself
   linkerd diagnostics endpoints web.linkerd-viz.svc.cluster.local:8084
+
+  # Show how the profile of a short service name is resolved from the emojivoto namespace
+  linkerd diagnostics profile -n emojivoto web-svc:80
+
+  # Show the endpoints and routes the proxy of a pod is served for an authority
+  linkerd diagnostics resolve -n emojivoto --from web-5f86686c4d-58p7k emoji-svc:8080
+
+  # Show the services whose connections hit protocol detection timeouts
+  linkerd diagnostics protocol-detection
+
+  # Summarize the Linkerd installation for a bug report
+  linkerd diagnostics install-state
+
+  # Log the debug entries of the endpoints watcher of the destination controller
+  linkerd diagnostics set-log-level destination --module endpoints-watcher=debug
   `,
 	}
 
 	diagnosticsCmd.AddCommand(newCmdControllerMetrics())
 	diagnosticsCmd.AddCommand(newCmdEndpoints())
+	diagnosticsCmd.AddCommand(newCmdInstallState())
 	diagnosticsCmd.AddCommand(newCmdMetrics())
+	diagnosticsCmd.AddCommand(newCmdDiagnosticsProfile())
+	diagnosticsCmd.AddCommand(newCmdDiagnosticsProtocolDetection())
+	diagnosticsCmd.AddCommand(newCmdDiagnosticsResolve())
+	diagnosticsCmd.AddCommand(newCmdDiagnosticsSetLogLevel())
 
 	return diagnosticsCmd
 }
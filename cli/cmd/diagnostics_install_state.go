@@ -0,0 +1,272 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	l5dcharts "github.com/linkerd/linkerd2/pkg/charts/linkerd2"
+	"github.com/linkerd/linkerd2/pkg/healthcheck"
+	"github.com/linkerd/linkerd2/pkg/k8s"
+	"github.com/spf13/cobra"
+	admissionregistration "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	yamlOutput = "yaml"
+
+	// redactedValue replaces the certificates and keys in the chart values,
+	// which are long and, in the case of keys, secret.
+	redactedValue = "<redacted>"
+)
+
+type (
+	installStateOptions struct {
+		output string
+	}
+
+	// installState summarizes a Linkerd installation.
+	installState struct {
+		ControlPlane controlPlaneState `json:"controlPlane"`
+		Extensions   []extensionState  `json:"extensions"`
+		CRDs         []crdState        `json:"crds"`
+		Webhooks     []webhookState    `json:"webhooks"`
+		Values       *l5dcharts.Values `json:"values,omitempty"`
+	}
+
+	controlPlaneState struct {
+		Namespace        string `json:"namespace"`
+		Version          string `json:"version"`
+		HighAvailability bool   `json:"highAvailability"`
+	}
+
+	extensionState struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+		CreatedBy string `json:"createdBy,omitempty"`
+	}
+
+	crdState struct {
+		Name     string   `json:"name"`
+		Versions []string `json:"versions"`
+	}
+
+	webhookState struct {
+		Configuration string `json:"configuration"`
+		Kind          string `json:"kind"`
+		Name          string `json:"name"`
+		FailurePolicy string `json:"failurePolicy"`
+	}
+)
+
+func newInstallStateOptions() *installStateOptions {
+	return &installStateOptions{
+		output: yamlOutput,
+	}
+}
+
+func (o *installStateOptions) validate() error {
+	if o.output == yamlOutput || o.output == jsonOutput {
+		return nil
+	}
+	return fmt.Errorf("--output currently only supports %s and %s", yamlOutput, jsonOutput)
+}
+
+func newCmdInstallState() *cobra.Command {
+	options := newInstallStateOptions()
+
+	cmd := &cobra.Command{
+		Use:   "install-state [flags]",
+		Short: "Summarize the state of the Linkerd installation",
+		Long: `Summarize the state of the Linkerd installation.
+
+This command prints the chart values in effect, the installed extensions,
+whether the control plane runs in HA mode, the Linkerd CRDs and their versions,
+and the webhook configurations with their failure policies. Certificates and
+keys are redacted, so the output can be pasted into bug reports.`,
+		Example: `  # Summarize the installation
+  linkerd diagnostics install-state
+
+  # Summarize the installation in JSON format
+  linkerd diagnostics install-state -o json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := options.validate(); err != nil {
+				return err
+			}
+
+			k8sAPI, err := k8s.NewAPI(kubeconfigPath, kubeContext, impersonate, impersonateGroup, 0)
+			if err != nil {
+				return err
+			}
+
+			state, err := fetchInstallState(cmd.Context(), k8sAPI, controlPlaneNamespace)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to fetch the install state: %s\n", err)
+				os.Exit(1)
+			}
+
+			return renderInstallState(stdout, state, options.output)
+		},
+	}
+
+	cmd.Flags().StringVarP(&options.output, "output", "o", options.output, fmt.Sprintf("Output format; one of: \"%s\" or \"%s\"", yamlOutput, jsonOutput))
+
+	return cmd
+}
+
+func fetchInstallState(ctx context.Context, k8sAPI *k8s.KubernetesAPI, controlPlaneNamespace string) (*installState, error) {
+	_, values, err := healthcheck.FetchCurrentConfiguration(ctx, k8sAPI, controlPlaneNamespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the control plane configuration: %s", err)
+	}
+
+	state := &installState{
+		ControlPlane: controlPlaneState{Namespace: controlPlaneNamespace},
+		Extensions:   []extensionState{},
+		CRDs:         []crdState{},
+		Webhooks:     []webhookState{},
+	}
+	if values != nil {
+		state.ControlPlane.Version = values.LinkerdVersion
+		state.ControlPlane.HighAvailability = values.HighAvailability
+		state.Values = redactValues(values)
+	}
+
+	namespaces, err := k8sAPI.GetAllNamespacesWithExtensionLabel(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, ns := range namespaces {
+		state.Extensions = append(state.Extensions, extensionState{
+			Name:      ns.Labels[k8s.LinkerdExtensionLabel],
+			Namespace: ns.Name,
+			CreatedBy: ns.Annotations[k8s.CreatedByAnnotation],
+		})
+	}
+	sort.Slice(state.Extensions, func(i, j int) bool {
+		return state.Extensions[i].Name < state.Extensions[j].Name
+	})
+
+	// Resources are labeled either with the control plane namespace or with
+	// the name of the extension they belong to
+	selectors := []string{
+		fmt.Sprintf("%s=%s", k8s.ControllerNSLabel, controlPlaneNamespace),
+		k8s.LinkerdExtensionLabel,
+	}
+	for _, selector := range selectors {
+		options := metav1.ListOptions{LabelSelector: selector}
+
+		crds, err := k8sAPI.Apiextensions.ApiextensionsV1().CustomResourceDefinitions().List(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+		for _, crd := range crds.Items {
+			versions := []string{}
+			for _, version := range crd.Spec.Versions {
+				if version.Served {
+					versions = append(versions, version.Name)
+				}
+			}
+			state.CRDs = append(state.CRDs, crdState{Name: crd.Name, Versions: versions})
+		}
+
+		mwcs, err := k8sAPI.AdmissionregistrationV1().MutatingWebhookConfigurations().List(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+		for _, mwc := range mwcs.Items {
+			for _, webhook := range mwc.Webhooks {
+				state.Webhooks = append(state.Webhooks, newWebhookState(mwc.Name, "mutating", webhook.Name, webhook.FailurePolicy))
+			}
+		}
+
+		vwcs, err := k8sAPI.AdmissionregistrationV1().ValidatingWebhookConfigurations().List(ctx, options)
+		if err != nil {
+			return nil, err
+		}
+		for _, vwc := range vwcs.Items {
+			for _, webhook := range vwc.Webhooks {
+				state.Webhooks = append(state.Webhooks, newWebhookState(vwc.Name, "validating", webhook.Name, webhook.FailurePolicy))
+			}
+		}
+	}
+	sort.Slice(state.CRDs, func(i, j int) bool {
+		return state.CRDs[i].Name < state.CRDs[j].Name
+	})
+	sort.Slice(state.Webhooks, func(i, j int) bool {
+		return state.Webhooks[i].Configuration < state.Webhooks[j].Configuration
+	})
+
+	return state, nil
+}
+
+func newWebhookState(configuration, kind, name string, failurePolicy *admissionregistration.FailurePolicyType) webhookState {
+	// Fail is the default failure policy of admissionregistration.k8s.io/v1
+	policy := string(admissionregistration.Fail)
+	if failurePolicy != nil {
+		policy = string(*failurePolicy)
+	}
+	return webhookState{
+		Configuration: configuration,
+		Kind:          kind,
+		Name:          name,
+		FailurePolicy: policy,
+	}
+}
+
+// redactValues returns a copy of the given values without certificates and
+// keys.
+func redactValues(values *l5dcharts.Values) *l5dcharts.Values {
+	redacted, err := values.DeepCopy()
+	if err != nil {
+		// values can't be copied, so they're not printed at all
+		return nil
+	}
+
+	redact := func(value *string) {
+		if *value != "" {
+			*value = redactedValue
+		}
+	}
+	redact(&redacted.IdentityTrustAnchorsPEM)
+	if redacted.Identity != nil && redacted.Identity.Issuer != nil && redacted.Identity.Issuer.TLS != nil {
+		redact(&redacted.Identity.Issuer.TLS.KeyPEM)
+		redact(&redacted.Identity.Issuer.TLS.CrtPEM)
+	}
+	redactTLS := func(tls *l5dcharts.TLS) {
+		if tls != nil {
+			redact(&tls.KeyPEM)
+			redact(&tls.CrtPEM)
+			redact(&tls.CaBundle)
+		}
+	}
+	if redacted.ProxyInjector != nil {
+		redactTLS(redacted.ProxyInjector.TLS)
+	}
+	if redacted.ProfileValidator != nil {
+		redactTLS(redacted.ProfileValidator.TLS)
+	}
+	return redacted
+}
+
+func renderInstallState(w io.Writer, state *installState, output string) error {
+	var out []byte
+	var err error
+	if output == jsonOutput {
+		out, err = json.MarshalIndent(state, "", "  ")
+	} else {
+		out, err = yaml.Marshal(state)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, strings.TrimSpace(string(out)))
+	return err
+}
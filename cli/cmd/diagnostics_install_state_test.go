@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/linkerd/linkerd2/pkg/k8s"
+)
+
+func TestInstallState(t *testing.T) {
+	k8sConfigs := []string{`
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: linkerd-config
+  namespace: linkerd
+data:
+  values: |
+    linkerdVersion: stable-2.10.2
+    highAvailability: true
+    identityTrustAnchorsPEM: |
+      -----BEGIN CERTIFICATE-----
+      trust-anchor
+      -----END CERTIFICATE-----
+    identity:
+      issuer:
+        tls:
+          crtPEM: issuer-crt
+          keyPEM: issuer-key`, `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: linkerd-viz
+  labels:
+    linkerd.io/extension: viz
+  annotations:
+    linkerd.io/created-by: linkerd/cli stable-2.10.2`, `
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: serviceprofiles.linkerd.io
+  labels:
+    linkerd.io/control-plane-ns: linkerd
+spec:
+  group: linkerd.io
+  versions:
+  - name: v1alpha1
+    served: true
+  - name: v1alpha2
+    served: true
+    storage: true`, `
+apiVersion: admissionregistration.k8s.io/v1
+kind: MutatingWebhookConfiguration
+metadata:
+  name: linkerd-proxy-injector-webhook-config
+  labels:
+    linkerd.io/control-plane-ns: linkerd
+webhooks:
+- name: linkerd-proxy-injector.linkerd.io
+  failurePolicy: Ignore`, `
+apiVersion: admissionregistration.k8s.io/v1
+kind: ValidatingWebhookConfiguration
+metadata:
+  name: linkerd-sp-validator-webhook-config
+  labels:
+    linkerd.io/control-plane-ns: linkerd
+webhooks:
+- name: linkerd-sp-validator.linkerd.io`,
+	}
+
+	k8sAPI, err := k8s.NewFakeAPI(k8sConfigs...)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	state, err := fetchInstallState(context.Background(), k8sAPI, "linkerd")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if state.ControlPlane.Version != "stable-2.10.2" || !state.ControlPlane.HighAvailability {
+		t.Fatalf("Unexpected control plane state: %+v", state.ControlPlane)
+	}
+	if len(state.Extensions) != 1 || state.Extensions[0].Name != "viz" || state.Extensions[0].Namespace != "linkerd-viz" {
+		t.Fatalf("Unexpected extensions: %+v", state.Extensions)
+	}
+	if len(state.CRDs) != 1 || strings.Join(state.CRDs[0].Versions, ",") != "v1alpha1,v1alpha2" {
+		t.Fatalf("Unexpected CRDs: %+v", state.CRDs)
+	}
+
+	expectedPolicies := map[string]string{
+		"linkerd-proxy-injector.linkerd.io": "Ignore",
+		"linkerd-sp-validator.linkerd.io":   "Fail",
+	}
+	if len(state.Webhooks) != len(expectedPolicies) {
+		t.Fatalf("Unexpected webhooks: %+v", state.Webhooks)
+	}
+	for _, webhook := range state.Webhooks {
+		if webhook.FailurePolicy != expectedPolicies[webhook.Name] {
+			t.Fatalf("Expected failure policy %s for %s, got %s", expectedPolicies[webhook.Name], webhook.Name, webhook.FailurePolicy)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := renderInstallState(&buf, state, yamlOutput); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, secret := range []string{"trust-anchor", "issuer-crt", "issuer-key"} {
+		if strings.Contains(buf.String(), secret) {
+			t.Fatalf("Expected %s to be redacted, got:\n%s", secret, buf.String())
+		}
+	}
+}
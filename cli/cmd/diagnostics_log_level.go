@@ -0,0 +1,206 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/linkerd/linkerd2/pkg/admin"
+	"github.com/linkerd/linkerd2/pkg/k8s"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+)
+
+type setLogLevelOptions struct {
+	modules map[string]string
+	token   string
+}
+
+func newCmdDiagnosticsSetLogLevel() *cobra.Command {
+	options := &setLogLevelOptions{modules: map[string]string{}}
+
+	cmd := &cobra.Command{
+		Use:   "set-log-level [flags] COMPONENT [LEVEL]",
+		Short: "Change the log levels of a running control plane component",
+		Long: `Change the log levels of a running control plane component.
+
+The level of the logs of all the pods of the component (destination, identity
+or proxy-injector) is changed through their admin endpoint, until they
+restart. Modules, such as the endpoints-watcher of the destination controller,
+can be given their own level with --module, to get the debug logs of a noisy
+subsystem alone; an empty level resets a module to the level of the component.
+
+The change is authorized by the Kubernetes API: the bearer token of the current
+kubeconfig, or the one given with --token, must belong to a user allowed to put
+the /log-level non-resource URL, as cluster admins are.`,
+		Example: `  # log the debug entries of the destination controller
+  linkerd diagnostics set-log-level destination debug
+
+  # only log the debug entries of the endpoints watcher
+  linkerd diagnostics set-log-level destination --module endpoints-watcher=debug
+
+  # reset the endpoints watcher to the level of the destination controller
+  linkerd diagnostics set-log-level destination --module endpoints-watcher=`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			level := ""
+			if len(args) == 2 {
+				level = args[1]
+			}
+			update, err := newLogLevelsUpdate(level, options.modules)
+			if err != nil {
+				return err
+			}
+
+			k8sAPI, err := k8s.NewAPI(kubeconfigPath, kubeContext, impersonate, impersonateGroup, 0)
+			if err != nil {
+				return err
+			}
+			token := options.token
+			if token == "" {
+				token, err = bearerToken(k8sAPI.Config)
+				if err != nil {
+					return err
+				}
+			}
+
+			pods, err := k8sAPI.CoreV1().Pods(controlPlaneNamespace).List(cmd.Context(), metav1.ListOptions{
+				LabelSelector: fmt.Sprintf("%s=%s", k8s.ControllerComponentLabel, args[0]),
+			})
+			if err != nil {
+				return err
+			}
+			if len(pods.Items) == 0 {
+				return fmt.Errorf("no pods found for component %s in namespace %s", args[0], controlPlaneNamespace)
+			}
+
+			var errs []string
+			for _, pod := range pods.Items {
+				containers, err := getAllContainersWithPort(pod, adminHTTPPortName)
+				if err != nil {
+					errs = append(errs, err.Error())
+					continue
+				}
+				for _, container := range containers {
+					current, err := putLogLevels(k8sAPI, pod, container, token, update)
+					if err != nil {
+						errs = append(errs, fmt.Sprintf("%s/%s: %s", pod.Name, container.Name, err))
+						continue
+					}
+					fmt.Printf("%s/%s: %s\n", pod.Name, container.Name, formatLogLevels(current))
+				}
+			}
+			if len(errs) > 0 {
+				return errors.New(strings.Join(errs, "\n"))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringToStringVar(&options.modules, "module", options.modules, "Level of the logs of a module, as module=level; can be repeated")
+	cmd.Flags().StringVar(&options.token, "token", options.token, "Bearer token authorizing the change, instead of the one of the current kubeconfig")
+
+	return cmd
+}
+
+// newLogLevelsUpdate validates the levels to set.
+func newLogLevelsUpdate(level string, modules map[string]string) (admin.LogLevels, error) {
+	if level == "" && len(modules) == 0 {
+		return admin.LogLevels{}, errors.New("a level or at least one --module must be given")
+	}
+	if level != "" {
+		if _, err := log.ParseLevel(level); err != nil {
+			return admin.LogLevels{}, err
+		}
+	}
+	for module, moduleLevel := range modules {
+		if moduleLevel == "" {
+			continue
+		}
+		if _, err := log.ParseLevel(moduleLevel); err != nil {
+			return admin.LogLevels{}, fmt.Errorf("invalid level of module %s: %s", module, err)
+		}
+	}
+	return admin.LogLevels{Level: level, Modules: modules}, nil
+}
+
+// bearerToken returns the bearer token the given config authenticates with.
+func bearerToken(config *rest.Config) (string, error) {
+	if config.BearerToken != "" {
+		return config.BearerToken, nil
+	}
+	if config.BearerTokenFile != "" {
+		token, err := ioutil.ReadFile(config.BearerTokenFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(token)), nil
+	}
+	return "", errors.New("the current kubeconfig doesn't authenticate with a bearer token; provide one with --token")
+}
+
+// putLogLevels changes the log levels of a container through its admin
+// endpoint, and returns the resulting ones.
+func putLogLevels(k8sAPI *k8s.KubernetesAPI, pod corev1.Pod, container corev1.Container, token string, update admin.LogLevels) (admin.LogLevels, error) {
+	portForward, err := k8s.NewContainerMetricsForward(k8sAPI, pod, container, verbose, adminHTTPPortName)
+	if err != nil {
+		return admin.LogLevels{}, err
+	}
+	defer portForward.Stop()
+	if err := portForward.Init(); err != nil {
+		return admin.LogLevels{}, err
+	}
+
+	body, err := json.Marshal(update)
+	if err != nil {
+		return admin.LogLevels{}, err
+	}
+	req, err := http.NewRequest(http.MethodPut, portForward.URLFor(admin.LogLevelPath), bytes.NewReader(body))
+	if err != nil {
+		return admin.LogLevels{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	rsp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return admin.LogLevels{}, err
+	}
+	defer rsp.Body.Close()
+	rspBody, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return admin.LogLevels{}, err
+	}
+	if rsp.StatusCode == http.StatusNotFound {
+		return admin.LogLevels{}, errors.New("the component doesn't support changing its log levels")
+	}
+	if rsp.StatusCode != http.StatusOK {
+		return admin.LogLevels{}, fmt.Errorf("unexpected status %d: %s", rsp.StatusCode, strings.TrimSpace(string(rspBody)))
+	}
+
+	var current admin.LogLevels
+	if err := json.Unmarshal(rspBody, &current); err != nil {
+		return admin.LogLevels{}, fmt.Errorf("invalid response: %s", err)
+	}
+	return current, nil
+}
+
+func formatLogLevels(levels admin.LogLevels) string {
+	formatted := fmt.Sprintf("level %s", levels.Level)
+	if len(levels.Modules) == 0 {
+		return formatted
+	}
+	modules := make([]string, 0, len(levels.Modules))
+	for module, level := range levels.Modules {
+		modules = append(modules, fmt.Sprintf("%s=%s", module, level))
+	}
+	sort.Strings(modules)
+	return fmt.Sprintf("%s (%s)", formatted, strings.Join(modules, ", "))
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/linkerd/linkerd2/pkg/admin"
+)
+
+func TestNewLogLevelsUpdate(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		level   string
+		modules map[string]string
+		valid   bool
+	}{
+		{name: "level", level: "debug", valid: true},
+		{name: "module", modules: map[string]string{"endpoints-watcher": "debug"}, valid: true},
+		{name: "module reset", modules: map[string]string{"endpoints-watcher": ""}, valid: true},
+		{name: "nothing to set"},
+		{name: "invalid level", level: "loud"},
+		{name: "invalid module level", modules: map[string]string{"endpoints-watcher": "loud"}},
+	} {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := newLogLevelsUpdate(tc.level, tc.modules)
+			if tc.valid && err != nil {
+				t.Errorf("Unexpected error: %s", err)
+			}
+			if !tc.valid && err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestFormatLogLevels(t *testing.T) {
+	formatted := formatLogLevels(admin.LogLevels{
+		Level:   "info",
+		Modules: map[string]string{"server": "warning", "endpoints-watcher": "debug"},
+	})
+	expected := "level info (endpoints-watcher=debug, server=warning)"
+	if formatted != expected {
+		t.Errorf("Expected %q, got %q", expected, formatted)
+	}
+}
@@ -0,0 +1,214 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	destinationPb "github.com/linkerd/linkerd2-proxy-api/go/destination"
+	"github.com/linkerd/linkerd2/controller/api/destination"
+	"github.com/linkerd/linkerd2/controller/api/destination/watcher"
+	pkgcmd "github.com/linkerd/linkerd2/pkg/cmd"
+	"github.com/linkerd/linkerd2/pkg/healthcheck"
+	"github.com/linkerd/linkerd2/pkg/k8s"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/status"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type diagnosticsProfileOptions struct {
+	namespace     string
+	clusterDomain string
+	ndots         int
+	searchDomains []string
+	outputFormat  string
+}
+
+type diagnosticsProfileJSON struct {
+	Authority          string   `json:"authority"`
+	Namespace          string   `json:"namespace"`
+	CanonicalHost      string   `json:"canonicalHost"`
+	Trace              []string `json:"trace"`
+	FullyQualifiedName string   `json:"fullyQualifiedName"`
+	OpaqueProtocol     bool     `json:"opaqueProtocol"`
+	Routes             int      `json:"routes"`
+	Endpoint           string   `json:"endpoint,omitempty"`
+	DstOverrides       []string `json:"dstOverrides,omitempty"`
+}
+
+func newDiagnosticsProfileOptions() *diagnosticsProfileOptions {
+	return &diagnosticsProfileOptions{
+		ndots:        destination.DefaultNdots,
+		outputFormat: tableOutput,
+	}
+}
+
+func (o *diagnosticsProfileOptions) validate() error {
+	if o.ndots < 0 {
+		return fmt.Errorf("--dns-ndots must be non-negative")
+	}
+	if o.outputFormat != tableOutput && o.outputFormat != jsonOutput {
+		return fmt.Errorf("--output currently only supports %s and %s", tableOutput, jsonOutput)
+	}
+	return nil
+}
+
+func newCmdDiagnosticsProfile() *cobra.Command {
+	options := newDiagnosticsProfileOptions()
+
+	cmd := &cobra.Command{
+		Use:   "profile [flags] authority",
+		Short: "Introspect how the destination service resolves the profile of an authority",
+		Long: `Introspect how the destination service resolves the profile of an authority.
+
+This command shows how an authority is canonicalized, as seen from a pod in the
+given namespace: the candidates built from the namespace's DNS search path,
+and why each one was selected or skipped. It then queries the same Destination
+service endpoint as the linkerd-proxy's, and returns a summary of the profile
+served for that authority.
+
+The --dns-ndots and --dns-search-domains flags should match the configuration
+of the destination service.`,
+		Example: `  # get the profile of a short service name, as seen from the emojivoto namespace
+  linkerd diagnostics profile -n emojivoto web-svc:80
+
+  # get the profile of a fully-qualified name in json format
+  linkerd diagnostics profile -o json web-svc.emojivoto.svc.cluster.local:80`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := options.validate(); err != nil {
+				return err
+			}
+			if options.namespace == "" {
+				options.namespace = pkgcmd.GetDefaultNamespace(kubeconfigPath, kubeContext)
+			}
+			authority := args[0]
+
+			k8sAPI, err := k8s.NewAPI(kubeconfigPath, kubeContext, impersonate, impersonateGroup, 0)
+			if err != nil {
+				return err
+			}
+
+			if options.clusterDomain == "" {
+				options.clusterDomain, err = fetchClusterDomain(cmd.Context(), k8sAPI)
+				if err != nil {
+					return err
+				}
+			}
+
+			resolver := destination.NewAuthorityResolver(options.clusterDomain, options.ndots, options.searchDomains)
+			canonical, err := resolver.Canonicalize(authority, options.namespace, func(id watcher.ServiceID) bool {
+				_, err := k8sAPI.CoreV1().Services(id.Namespace).Get(cmd.Context(), id.Name, metav1.GetOptions{})
+				return err == nil
+			})
+			if err != nil {
+				return err
+			}
+
+			client, conn, err := destination.NewExternalClient(cmd.Context(), controlPlaneNamespace, k8sAPI)
+			if err != nil {
+				return fmt.Errorf("Error creating destination client: %s", err)
+			}
+			defer conn.Close()
+
+			rsp, err := client.GetProfile(cmd.Context(), &destinationPb.GetDestination{
+				Scheme:       "k8s",
+				Path:         authority,
+				ContextToken: fmt.Sprintf("{\"ns\":\"%s\"}", options.namespace),
+			})
+			if err != nil {
+				return fmt.Errorf("Destination API error: %s", err)
+			}
+			profile, err := rsp.Recv()
+			if err != nil {
+				if grpcError, ok := status.FromError(err); ok {
+					err = errors.New(grpcError.Message())
+				}
+				return fmt.Errorf("Destination API error: %s", err)
+			}
+
+			output, err := renderDiagnosticsProfile(authority, canonical, profile, options)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Print(output)
+			return err
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&options.namespace, "namespace", "n", options.namespace, "Namespace of the client the authority is resolved for")
+	cmd.PersistentFlags().StringVar(&options.clusterDomain, "cluster-domain", options.clusterDomain, "Cluster domain (defaults to the one in the Linkerd configuration)")
+	cmd.PersistentFlags().IntVar(&options.ndots, "dns-ndots", options.ndots, "Number of dots an authority must have to be looked up as an absolute name before the search path is applied")
+	cmd.PersistentFlags().StringSliceVar(&options.searchDomains, "dns-search-domains", options.searchDomains, "Search domains appended to the search path of each namespace")
+	cmd.PersistentFlags().StringVarP(&options.outputFormat, "output", "o", options.outputFormat, fmt.Sprintf("Output format; one of: \"%s\" or \"%s\"", tableOutput, jsonOutput))
+
+	pkgcmd.ConfigureNamespaceFlagCompletion(cmd, []string{"namespace"})
+	pkgcmd.ConfigureOutputFlagCompletion(cmd)
+
+	return cmd
+}
+
+// fetchClusterDomain returns the cluster domain from the Linkerd
+// configuration, falling back to the default one.
+func fetchClusterDomain(ctx context.Context, k8sAPI *k8s.KubernetesAPI) (string, error) {
+	_, values, err := healthcheck.FetchCurrentConfiguration(ctx, k8sAPI, controlPlaneNamespace)
+	if err != nil {
+		return "", err
+	}
+	if cd := values.ClusterDomain; cd != "" {
+		return cd, nil
+	}
+	return defaultClusterDomain, nil
+}
+
+func renderDiagnosticsProfile(authority string, canonical *destination.CanonicalAuthority, profile *destinationPb.DestinationProfile, options *diagnosticsProfileOptions) (string, error) {
+	entry := diagnosticsProfileJSON{
+		Authority:          authority,
+		Namespace:          options.namespace,
+		CanonicalHost:      fmt.Sprintf("%s:%d", canonical.Host, canonical.Port),
+		Trace:              canonical.Trace,
+		FullyQualifiedName: profile.GetFullyQualifiedName(),
+		OpaqueProtocol:     profile.GetOpaqueProtocol(),
+		Routes:             len(profile.GetRoutes()),
+	}
+	if addr := profile.GetEndpoint().GetAddr(); addr != nil {
+		entry.Endpoint = fmt.Sprintf("%s:%d", getIP(addr), addr.GetPort())
+	}
+	for _, dst := range profile.GetDstOverrides() {
+		entry.DstOverrides = append(entry.DstOverrides, fmt.Sprintf("%s (weight %d)", dst.GetAuthority(), dst.GetWeight()))
+	}
+
+	if options.outputFormat == jsonOutput {
+		b, err := json.MarshalIndent(entry, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s\n", b), nil
+	}
+
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "Canonicalization of %s from namespace %s:\n", entry.Authority, entry.Namespace)
+	for _, step := range entry.Trace {
+		fmt.Fprintf(&buffer, "  %s\n", step)
+	}
+	fmt.Fprintln(&buffer)
+
+	w := tabwriter.NewWriter(&buffer, 0, 0, padding, ' ', 0)
+	fmt.Fprintf(w, "CANONICAL AUTHORITY\t%s\n", entry.CanonicalHost)
+	fmt.Fprintf(w, "FULLY QUALIFIED NAME\t%s\n", entry.FullyQualifiedName)
+	fmt.Fprintf(w, "OPAQUE PROTOCOL\t%t\n", entry.OpaqueProtocol)
+	fmt.Fprintf(w, "ROUTES\t%d\n", entry.Routes)
+	if entry.Endpoint != "" {
+		fmt.Fprintf(w, "ENDPOINT\t%s\n", entry.Endpoint)
+	}
+	if len(entry.DstOverrides) != 0 {
+		fmt.Fprintf(w, "DST OVERRIDES\t%s\n", strings.Join(entry.DstOverrides, ", "))
+	}
+	w.Flush()
+
+	return buffer.String(), nil
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"testing"
+
+	destinationPb "github.com/linkerd/linkerd2-proxy-api/go/destination"
+	netPb "github.com/linkerd/linkerd2-proxy-api/go/net"
+	"github.com/linkerd/linkerd2/controller/api/destination"
+	"github.com/linkerd/linkerd2/controller/api/destination/watcher"
+)
+
+func TestRenderDiagnosticsProfile(t *testing.T) {
+	resolver := destination.NewAuthorityResolver("cluster.local", destination.DefaultNdots, nil)
+	canonical, err := resolver.Canonicalize("web-svc:80", "emojivoto", func(id watcher.ServiceID) bool {
+		return id.Namespace == "emojivoto" && id.Name == "web-svc"
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	profile := &destinationPb.DestinationProfile{
+		FullyQualifiedName: "web-svc.emojivoto.svc.cluster.local",
+		Routes:             []*destinationPb.Route{{}, {}},
+		Endpoint: &destinationPb.WeightedAddr{
+			Addr: &netPb.TcpAddress{
+				Ip:   &netPb.IPAddress{Ip: &netPb.IPAddress_Ipv4{Ipv4: 168430081}},
+				Port: 80,
+			},
+		},
+		DstOverrides: []*destinationPb.WeightedDst{
+			{Authority: "web-svc.emojivoto.svc.cluster.local.:80", Weight: 10000},
+		},
+	}
+
+	options := newDiagnosticsProfileOptions()
+	options.namespace = "emojivoto"
+
+	t.Run("Renders the canonicalization and the profile", func(t *testing.T) {
+		expected := `Canonicalization of web-svc:80 from namespace emojivoto:
+  web-svc.emojivoto.svc.cluster.local: selected
+
+CANONICAL AUTHORITY    web-svc.emojivoto.svc.cluster.local:80
+FULLY QUALIFIED NAME   web-svc.emojivoto.svc.cluster.local
+OPAQUE PROTOCOL        false
+ROUTES                 2
+ENDPOINT               10.10.10.1:80
+DST OVERRIDES          web-svc.emojivoto.svc.cluster.local.:80 (weight 10000)
+`
+		output, err := renderDiagnosticsProfile("web-svc:80", canonical, profile, options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if output != expected {
+			t.Fatalf("Expected:\n%s\nGot:\n%s", expected, output)
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"text/tabwriter"
+
+	"github.com/linkerd/linkerd2/controller/api/destination"
+	pkgcmd "github.com/linkerd/linkerd2/pkg/cmd"
+	"github.com/linkerd/linkerd2/pkg/k8s"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type diagnosticsProtocolDetectionOptions struct {
+	outputFormat string
+}
+
+func newDiagnosticsProtocolDetectionOptions() *diagnosticsProtocolDetectionOptions {
+	return &diagnosticsProtocolDetectionOptions{
+		outputFormat: tableOutput,
+	}
+}
+
+func (o *diagnosticsProtocolDetectionOptions) validate() error {
+	if o.outputFormat != tableOutput && o.outputFormat != jsonOutput {
+		return fmt.Errorf("--output currently only supports %s and %s", tableOutput, jsonOutput)
+	}
+	return nil
+}
+
+func newCmdDiagnosticsProtocolDetection() *cobra.Command {
+	options := newDiagnosticsProtocolDetectionOptions()
+
+	cmd := &cobra.Command{
+		Use:   "protocol-detection [flags]",
+		Short: "Show the protocol detection timeouts of the meshed proxies, per service",
+		Long: `Show the protocol detection timeouts of the meshed proxies, per service.
+
+When a proxy can't detect the protocol of a connection, e.g. because the client
+speaks first and the port isn't marked as opaque, the connection is delayed
+until the detection times out. This command shows the timeouts reported by all
+the meshed proxies, aggregated per target service and port by the destination
+controller, so that the ports missing an opaque annotation can be found.
+
+The aggregation must be enabled with the -protocol-detection-scrape-interval
+flag of the destination controller.`,
+		Example: `  # show the services with protocol detection timeouts
+  linkerd diagnostics protocol-detection
+
+  # get the timeouts in json format
+  linkerd diagnostics protocol-detection -o json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := options.validate(); err != nil {
+				return err
+			}
+
+			k8sAPI, err := k8s.NewAPI(kubeconfigPath, kubeContext, impersonate, impersonateGroup, 0)
+			if err != nil {
+				return err
+			}
+
+			pods, err := k8sAPI.CoreV1().Pods(controlPlaneNamespace).List(cmd.Context(), metav1.ListOptions{
+				LabelSelector: fmt.Sprintf("%s=destination", k8s.ControllerComponentLabel),
+			})
+			if err != nil {
+				return err
+			}
+
+			results, err := fetchProtocolDetectionTimeouts(k8sAPI, pods.Items)
+			if err != nil {
+				return err
+			}
+
+			output, err := renderProtocolDetectionTimeouts(results, options.outputFormat)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Print(output)
+			return err
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&options.outputFormat, "output", "o", options.outputFormat, fmt.Sprintf("Output format; one of: \"%s\" or \"%s\"", tableOutput, jsonOutput))
+	pkgcmd.ConfigureOutputFlagCompletion(cmd)
+
+	return cmd
+}
+
+// fetchProtocolDetectionTimeouts queries the admin server of the first running
+// destination pod; each replica aggregates the timeouts of all the proxies.
+func fetchProtocolDetectionTimeouts(k8sAPI *k8s.KubernetesAPI, pods []corev1.Pod) ([]destination.DetectionTimeouts, error) {
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			if container.Name != "destination" {
+				continue
+			}
+			portForward, err := k8s.NewContainerMetricsForward(k8sAPI, pod, container, verbose, adminHTTPPortName)
+			if err != nil {
+				return nil, err
+			}
+			defer portForward.Stop()
+			if err := portForward.Init(); err != nil {
+				return nil, err
+			}
+
+			rsp, err := http.Get(portForward.URLFor(destination.ProtocolDetectionPath))
+			if err != nil {
+				return nil, err
+			}
+			defer rsp.Body.Close()
+			if rsp.StatusCode == http.StatusNotFound {
+				return nil, errors.New("the protocol detection timeouts aren't aggregated by the destination controller; enable it with its -protocol-detection-scrape-interval flag")
+			}
+			if rsp.StatusCode != http.StatusOK {
+				return nil, fmt.Errorf("unexpected status %d from %s", rsp.StatusCode, pod.Name)
+			}
+
+			body, err := ioutil.ReadAll(rsp.Body)
+			if err != nil {
+				return nil, err
+			}
+			var results []destination.DetectionTimeouts
+			if err := json.Unmarshal(body, &results); err != nil {
+				return nil, fmt.Errorf("invalid response from %s: %s", pod.Name, err)
+			}
+			return results, nil
+		}
+	}
+	return nil, errors.New("no running destination pod found")
+}
+
+func renderProtocolDetectionTimeouts(results []destination.DetectionTimeouts, outputFormat string) (string, error) {
+	if outputFormat == jsonOutput {
+		b, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s\n", b), nil
+	}
+
+	if len(results) == 0 {
+		return "No protocol detection timeouts reported.\n", nil
+	}
+
+	var buffer bytes.Buffer
+	w := tabwriter.NewWriter(&buffer, 0, 0, padding, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tSERVICE\tPORT\tDIRECTION\tTIMEOUTS\tPROXIES")
+	for _, r := range results {
+		if r.Service == "" {
+			fmt.Fprintf(w, "-\t%s\t-\t%s\t%.0f\t%d\n", r.Addr, r.Direction, r.Timeouts, r.Proxies)
+			continue
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%.0f\t%d\n", r.Namespace, r.Service, r.Port, r.Direction, r.Timeouts, r.Proxies)
+	}
+	w.Flush()
+
+	return buffer.String(), nil
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/linkerd/linkerd2/controller/api/destination"
+)
+
+func TestRenderProtocolDetectionTimeouts(t *testing.T) {
+	results := []destination.DetectionTimeouts{
+		{Namespace: "emojivoto", Service: "web-svc", Port: 80, Direction: "outbound", Timeouts: 7, Proxies: 2},
+		{Addr: "10.9.9.9:443", Direction: "outbound", Timeouts: 1, Proxies: 1},
+	}
+
+	testCases := []struct {
+		name     string
+		results  []destination.DetectionTimeouts
+		expected string
+	}{
+		{
+			"Renders the timeouts per service",
+			results,
+			`NAMESPACE   SERVICE        PORT   DIRECTION   TIMEOUTS   PROXIES
+emojivoto   web-svc        80     outbound    7          2
+-           10.9.9.9:443   -      outbound    1          1
+`,
+		},
+		{
+			"Renders the absence of timeouts",
+			[]destination.DetectionTimeouts{},
+			"No protocol detection timeouts reported.\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			output, err := renderProtocolDetectionTimeouts(tc.results, tableOutput)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if output != tc.expected {
+				t.Fatalf("Expected:\n%s\nGot:\n%s", tc.expected, output)
+			}
+		})
+	}
+}
@@ -0,0 +1,323 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	destinationPb "github.com/linkerd/linkerd2-proxy-api/go/destination"
+	"github.com/linkerd/linkerd2/controller/api/destination"
+	"github.com/linkerd/linkerd2/controller/api/destination/watcher"
+	pkgcmd "github.com/linkerd/linkerd2/pkg/cmd"
+	"github.com/linkerd/linkerd2/pkg/k8s"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/status"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type diagnosticsResolveOptions struct {
+	from          string
+	namespace     string
+	clusterDomain string
+	ndots         int
+	searchDomains []string
+	outputFormat  string
+}
+
+type diagnosticsResolveEndpoint struct {
+	Address      string `json:"address"`
+	Weight       uint32 `json:"weight"`
+	Pod          string `json:"pod,omitempty"`
+	Identity     string `json:"identity,omitempty"`
+	ProtocolHint string `json:"protocolHint,omitempty"`
+}
+
+type diagnosticsResolveJSON struct {
+	Authority          string                       `json:"authority"`
+	Pod                string                       `json:"pod"`
+	Namespace          string                       `json:"namespace"`
+	Zone               string                       `json:"zone,omitempty"`
+	ContextToken       string                       `json:"contextToken"`
+	CanonicalHost      string                       `json:"canonicalHost"`
+	FullyQualifiedName string                       `json:"fullyQualifiedName"`
+	OpaqueProtocol     bool                         `json:"opaqueProtocol"`
+	Endpoints          []diagnosticsResolveEndpoint `json:"endpoints"`
+	Routes             []string                     `json:"routes"`
+	DstOverrides       []string                     `json:"dstOverrides,omitempty"`
+}
+
+func newDiagnosticsResolveOptions() *diagnosticsResolveOptions {
+	return &diagnosticsResolveOptions{
+		ndots:        destination.DefaultNdots,
+		outputFormat: tableOutput,
+	}
+}
+
+func (o *diagnosticsResolveOptions) validate() error {
+	if o.from == "" {
+		return errors.New("--from must be set to the pod the lookup is made from")
+	}
+	if o.ndots < 0 {
+		return fmt.Errorf("--dns-ndots must be non-negative")
+	}
+	if o.outputFormat != tableOutput && o.outputFormat != jsonOutput {
+		return fmt.Errorf("--output currently only supports %s and %s", tableOutput, jsonOutput)
+	}
+	return nil
+}
+
+func newCmdDiagnosticsResolve() *cobra.Command {
+	options := newDiagnosticsResolveOptions()
+
+	cmd := &cobra.Command{
+		Use:   "resolve [flags] authority --from pod",
+		Short: "Show what the proxy of a pod is served when looking up an authority",
+		Long: `Show what the proxy of a pod is served when looking up an authority.
+
+This command performs the same Get and GetProfile lookups against the
+Destination service as the proxy of the given pod would, using the same
+context token, so that the endpoints are filtered for the pod's node and zone.
+It returns the endpoints with their weights, identities and protocol hints, as
+well as the routes of the profile.
+
+The --dns-ndots and --dns-search-domains flags should match the configuration
+of the destination service.`,
+		Example: `  # show what the web pod sees when looking up emoji-svc
+  linkerd diagnostics resolve -n emojivoto --from web-5f86686c4d-58p7k emoji-svc:8080
+
+  # get that same information in json format
+  linkerd diagnostics resolve -n emojivoto --from pod/web-5f86686c4d-58p7k -o json emoji-svc.emojivoto.svc.cluster.local:8080`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := options.validate(); err != nil {
+				return err
+			}
+			if options.namespace == "" {
+				options.namespace = pkgcmd.GetDefaultNamespace(kubeconfigPath, kubeContext)
+			}
+			authority := args[0]
+
+			k8sAPI, err := k8s.NewAPI(kubeconfigPath, kubeContext, impersonate, impersonateGroup, 0)
+			if err != nil {
+				return err
+			}
+
+			podName := strings.TrimPrefix(options.from, "pod/")
+			pod, err := k8sAPI.CoreV1().Pods(options.namespace).Get(cmd.Context(), podName, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			var zone string
+			if pod.Spec.NodeName != "" {
+				node, err := k8sAPI.CoreV1().Nodes().Get(cmd.Context(), pod.Spec.NodeName, metav1.GetOptions{})
+				if err != nil {
+					return err
+				}
+				zone = node.Labels[corev1.LabelZoneFailureDomainStable]
+			}
+
+			if options.clusterDomain == "" {
+				options.clusterDomain, err = fetchClusterDomain(cmd.Context(), k8sAPI)
+				if err != nil {
+					return err
+				}
+			}
+
+			resolver := destination.NewAuthorityResolver(options.clusterDomain, options.ndots, options.searchDomains)
+			canonical, err := resolver.Canonicalize(authority, pod.Namespace, func(id watcher.ServiceID) bool {
+				_, err := k8sAPI.CoreV1().Services(id.Namespace).Get(cmd.Context(), id.Name, metav1.GetOptions{})
+				return err == nil
+			})
+			if err != nil {
+				return err
+			}
+
+			client, conn, err := destination.NewExternalClient(cmd.Context(), controlPlaneNamespace, k8sAPI)
+			if err != nil {
+				return fmt.Errorf("Error creating destination client: %s", err)
+			}
+			defer conn.Close()
+
+			dest := &destinationPb.GetDestination{
+				Scheme:       "k8s",
+				Path:         fmt.Sprintf("%s:%d", canonical.Host, canonical.Port),
+				ContextToken: proxyContextToken(pod),
+			}
+
+			// The Get API doesn't support IP queries; the proxy only looks up
+			// the profile of IP addresses.
+			var update *destinationPb.Update
+			if net.ParseIP(canonical.Host) == nil {
+				rsp, err := client.Get(cmd.Context(), dest)
+				if err != nil {
+					return fmt.Errorf("Destination API error: %s", err)
+				}
+				update, err = rsp.Recv()
+				if err != nil {
+					return destinationAPIError(err)
+				}
+			}
+
+			rsp, err := client.GetProfile(cmd.Context(), dest)
+			if err != nil {
+				return fmt.Errorf("Destination API error: %s", err)
+			}
+			profile, err := rsp.Recv()
+			if err != nil {
+				return destinationAPIError(err)
+			}
+
+			entry := newDiagnosticsResolveJSON(authority, pod, zone, dest, update, profile)
+			output, err := renderDiagnosticsResolve(entry, options)
+			if err != nil {
+				return err
+			}
+			_, err = fmt.Print(output)
+			return err
+		},
+	}
+
+	cmd.PersistentFlags().StringVar(&options.from, "from", options.from, "Pod whose proxy the lookup is made for")
+	cmd.PersistentFlags().StringVarP(&options.namespace, "namespace", "n", options.namespace, "Namespace of the pod")
+	cmd.PersistentFlags().StringVar(&options.clusterDomain, "cluster-domain", options.clusterDomain, "Cluster domain (defaults to the one in the Linkerd configuration)")
+	cmd.PersistentFlags().IntVar(&options.ndots, "dns-ndots", options.ndots, "Number of dots an authority must have to be looked up as an absolute name before the search path is applied")
+	cmd.PersistentFlags().StringSliceVar(&options.searchDomains, "dns-search-domains", options.searchDomains, "Search domains appended to the search path of each namespace")
+	cmd.PersistentFlags().StringVarP(&options.outputFormat, "output", "o", options.outputFormat, fmt.Sprintf("Output format; one of: \"%s\" or \"%s\"", tableOutput, jsonOutput))
+
+	pkgcmd.ConfigureNamespaceFlagCompletion(cmd, []string{"namespace"})
+	pkgcmd.ConfigureOutputFlagCompletion(cmd)
+
+	return cmd
+}
+
+// proxyContextToken returns the context token sent by the proxy of the given
+// pod, as set in LINKERD2_PROXY_DESTINATION_CONTEXT by the proxy template.
+func proxyContextToken(pod *corev1.Pod) string {
+	return fmt.Sprintf("{\"ns\":\"%s\", \"nodeName\":\"%s\"}", pod.Namespace, pod.Spec.NodeName)
+}
+
+func destinationAPIError(err error) error {
+	if grpcError, ok := status.FromError(err); ok {
+		err = errors.New(grpcError.Message())
+	}
+	return fmt.Errorf("Destination API error: %s", err)
+}
+
+func newDiagnosticsResolveJSON(authority string, pod *corev1.Pod, zone string, dest *destinationPb.GetDestination, update *destinationPb.Update, profile *destinationPb.DestinationProfile) diagnosticsResolveJSON {
+	entry := diagnosticsResolveJSON{
+		Authority:          authority,
+		Pod:                pod.Name,
+		Namespace:          pod.Namespace,
+		Zone:               zone,
+		ContextToken:       dest.GetContextToken(),
+		CanonicalHost:      dest.GetPath(),
+		FullyQualifiedName: profile.GetFullyQualifiedName(),
+		OpaqueProtocol:     profile.GetOpaqueProtocol(),
+		Endpoints:          []diagnosticsResolveEndpoint{},
+		Routes:             []string{},
+	}
+
+	addrs := update.GetAdd().GetAddrs()
+	if len(addrs) == 0 && profile.GetEndpoint() != nil {
+		addrs = []*destinationPb.WeightedAddr{profile.GetEndpoint()}
+	}
+	for _, addr := range addrs {
+		tcpAddr := addr.GetAddr()
+		entry.Endpoints = append(entry.Endpoints, diagnosticsResolveEndpoint{
+			Address:      fmt.Sprintf("%s:%d", getIP(tcpAddr), tcpAddr.GetPort()),
+			Weight:       addr.GetWeight(),
+			Pod:          addr.GetMetricLabels()["pod"],
+			Identity:     addr.GetTlsIdentity().GetDnsLikeIdentity().GetName(),
+			ProtocolHint: protocolHintString(addr.GetProtocolHint()),
+		})
+	}
+	sort.Slice(entry.Endpoints, func(i, j int) bool {
+		return entry.Endpoints[i].Address < entry.Endpoints[j].Address
+	})
+
+	for _, route := range profile.GetRoutes() {
+		name := route.GetMetricsLabels()["route"]
+		if route.GetIsRetryable() {
+			name += " (retryable)"
+		}
+		entry.Routes = append(entry.Routes, name)
+	}
+	for _, dst := range profile.GetDstOverrides() {
+		entry.DstOverrides = append(entry.DstOverrides, fmt.Sprintf("%s (weight %d)", dst.GetAuthority(), dst.GetWeight()))
+	}
+
+	return entry
+}
+
+func protocolHintString(hint *destinationPb.ProtocolHint) string {
+	var hints []string
+	if hint.GetH2() != nil {
+		hints = append(hints, "h2")
+	}
+	if opaque := hint.GetOpaqueTransport(); opaque != nil {
+		hints = append(hints, fmt.Sprintf("opaque (inbound port %d)", opaque.GetInboundPort()))
+	}
+	return strings.Join(hints, ", ")
+}
+
+func renderDiagnosticsResolve(entry diagnosticsResolveJSON, options *diagnosticsResolveOptions) (string, error) {
+	if options.outputFormat == jsonOutput {
+		b, err := json.MarshalIndent(entry, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s\n", b), nil
+	}
+
+	var buffer bytes.Buffer
+	w := tabwriter.NewWriter(&buffer, 0, 0, padding, ' ', 0)
+	fmt.Fprintf(w, "POD\t%s/%s\n", entry.Namespace, entry.Pod)
+	if entry.Zone != "" {
+		fmt.Fprintf(w, "ZONE\t%s\n", entry.Zone)
+	}
+	fmt.Fprintf(w, "CONTEXT TOKEN\t%s\n", entry.ContextToken)
+	fmt.Fprintf(w, "CANONICAL AUTHORITY\t%s\n", entry.CanonicalHost)
+	fmt.Fprintf(w, "FULLY QUALIFIED NAME\t%s\n", entry.FullyQualifiedName)
+	fmt.Fprintf(w, "OPAQUE PROTOCOL\t%t\n", entry.OpaqueProtocol)
+	if len(entry.DstOverrides) != 0 {
+		fmt.Fprintf(w, "DST OVERRIDES\t%s\n", strings.Join(entry.DstOverrides, ", "))
+	}
+	w.Flush()
+
+	fmt.Fprintln(&buffer)
+	if len(entry.Endpoints) == 0 {
+		fmt.Fprintln(&buffer, "No endpoints found.")
+	} else {
+		w = tabwriter.NewWriter(&buffer, 0, 0, padding, ' ', 0)
+		fmt.Fprintln(w, "ADDRESS\tWEIGHT\tPOD\tIDENTITY\tPROTOCOL HINT")
+		for _, ep := range entry.Endpoints {
+			fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", ep.Address, ep.Weight, valueOrDash(ep.Pod), valueOrDash(ep.Identity), valueOrDash(ep.ProtocolHint))
+		}
+		w.Flush()
+	}
+
+	fmt.Fprintln(&buffer)
+	if len(entry.Routes) == 0 {
+		fmt.Fprintln(&buffer, "No routes found.")
+	} else {
+		fmt.Fprintln(&buffer, "ROUTES")
+		for _, route := range entry.Routes {
+			fmt.Fprintf(&buffer, "  %s\n", route)
+		}
+	}
+
+	return buffer.String(), nil
+}
+
+func valueOrDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
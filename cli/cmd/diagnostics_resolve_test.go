@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"testing"
+
+	destinationPb "github.com/linkerd/linkerd2-proxy-api/go/destination"
+	netPb "github.com/linkerd/linkerd2-proxy-api/go/net"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRenderDiagnosticsResolve(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "emojivoto"},
+		Spec:       corev1.PodSpec{NodeName: "node-1"},
+	}
+	dest := &destinationPb.GetDestination{
+		Scheme:       "k8s",
+		Path:         "emoji-svc.emojivoto.svc.cluster.local:8080",
+		ContextToken: proxyContextToken(pod),
+	}
+	update := &destinationPb.Update{
+		Update: &destinationPb.Update_Add{
+			Add: &destinationPb.WeightedAddrSet{
+				Addrs: []*destinationPb.WeightedAddr{
+					{
+						Addr: &netPb.TcpAddress{
+							Ip:   &netPb.IPAddress{Ip: &netPb.IPAddress_Ipv4{Ipv4: 168430082}},
+							Port: 8080,
+						},
+						Weight: 10000,
+						ProtocolHint: &destinationPb.ProtocolHint{
+							OpaqueTransport: &destinationPb.ProtocolHint_OpaqueTransport{InboundPort: 4143},
+						},
+					},
+					{
+						Addr: &netPb.TcpAddress{
+							Ip:   &netPb.IPAddress{Ip: &netPb.IPAddress_Ipv4{Ipv4: 168430081}},
+							Port: 8080,
+						},
+						Weight:       10000,
+						MetricLabels: map[string]string{"pod": "emoji-1"},
+						TlsIdentity: &destinationPb.TlsIdentity{
+							Strategy: &destinationPb.TlsIdentity_DnsLikeIdentity_{
+								DnsLikeIdentity: &destinationPb.TlsIdentity_DnsLikeIdentity{
+									Name: "emoji.emojivoto.serviceaccount.identity.linkerd.cluster.local",
+								},
+							},
+						},
+						ProtocolHint: &destinationPb.ProtocolHint{
+							Protocol: &destinationPb.ProtocolHint_H2_{H2: &destinationPb.ProtocolHint_H2{}},
+						},
+					},
+				},
+			},
+		},
+	}
+	profile := &destinationPb.DestinationProfile{
+		FullyQualifiedName: "emoji-svc.emojivoto.svc.cluster.local",
+		Routes: []*destinationPb.Route{
+			{MetricsLabels: map[string]string{"route": "GET /api"}, IsRetryable: true},
+			{MetricsLabels: map[string]string{"route": "POST /api"}},
+		},
+	}
+
+	t.Run("Renders the endpoints and routes served to the pod", func(t *testing.T) {
+		expected := `POD                    emojivoto/web-1
+ZONE                   us-east-1a
+CONTEXT TOKEN          {"ns":"emojivoto", "nodeName":"node-1"}
+CANONICAL AUTHORITY    emoji-svc.emojivoto.svc.cluster.local:8080
+FULLY QUALIFIED NAME   emoji-svc.emojivoto.svc.cluster.local
+OPAQUE PROTOCOL        false
+
+ADDRESS           WEIGHT   POD       IDENTITY                                                        PROTOCOL HINT
+10.10.10.1:8080   10000    emoji-1   emoji.emojivoto.serviceaccount.identity.linkerd.cluster.local   h2
+10.10.10.2:8080   10000    -         -                                                               opaque (inbound port 4143)
+
+ROUTES
+  GET /api (retryable)
+  POST /api
+`
+		entry := newDiagnosticsResolveJSON("emoji-svc:8080", pod, "us-east-1a", dest, update, profile)
+		output, err := renderDiagnosticsResolve(entry, newDiagnosticsResolveOptions())
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if output != expected {
+			t.Fatalf("Expected:\n%s\nGot:\n%s", expected, output)
+		}
+	})
+
+	t.Run("Renders the profile endpoint of an IP address", func(t *testing.T) {
+		ipProfile := &destinationPb.DestinationProfile{
+			Endpoint: &destinationPb.WeightedAddr{
+				Addr: &netPb.TcpAddress{
+					Ip:   &netPb.IPAddress{Ip: &netPb.IPAddress_Ipv4{Ipv4: 168430081}},
+					Port: 8080,
+				},
+				Weight: 10000,
+			},
+		}
+		entry := newDiagnosticsResolveJSON("10.10.10.1:8080", pod, "", dest, nil, ipProfile)
+		if len(entry.Endpoints) != 1 || entry.Endpoints[0].Address != "10.10.10.1:8080" {
+			t.Fatalf("Unexpected endpoints: %v", entry.Endpoints)
+		}
+	})
+}
@@ -2,12 +2,14 @@ package cmd
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"strings"
 
 	"github.com/spf13/cobra"
 	cobradoc "github.com/spf13/cobra/doc"
+	"github.com/spf13/pflag"
 	"sigs.k8s.io/yaml"
 
 	"github.com/linkerd/linkerd2/pkg/k8s"
@@ -40,13 +42,48 @@ type annotationDoc struct {
 	Description string
 }
 
+// apiCommand describes a command of the CLI, as output by `linkerd doc
+// --print-api`, so that tools wrapping the CLI can follow its surface.
+type apiCommand struct {
+	Name           string       `json:"name"`
+	Path           string       `json:"path"`
+	Aliases        []string     `json:"aliases,omitempty"`
+	Short          string       `json:"short,omitempty"`
+	Long           string       `json:"long,omitempty"`
+	Example        string       `json:"example,omitempty"`
+	Deprecated     string       `json:"deprecated,omitempty"`
+	Flags          []apiFlag    `json:"flags,omitempty"`
+	InheritedFlags []apiFlag    `json:"inheritedFlags,omitempty"`
+	Commands       []apiCommand `json:"commands,omitempty"`
+}
+
+type apiFlag struct {
+	Name       string `json:"name"`
+	Shorthand  string `json:"shorthand,omitempty"`
+	Type       string `json:"type"`
+	Default    string `json:"default,omitempty"`
+	Usage      string `json:"usage"`
+	Deprecated string `json:"deprecated,omitempty"`
+}
+
 func newCmdDoc() *cobra.Command {
+	printAPI := false
+
 	cmd := &cobra.Command{
 		Use:    "doc",
 		Hidden: true,
 		Short:  "Generate YAML documentation for the Linkerd CLI & Proxy annotations",
 		Args:   cobra.NoArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if printAPI {
+				out, err := json.MarshalIndent(generateAPI(RootCmd), "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Printf("%s\n", out)
+				return nil
+			}
+
 			cmdList, err := generateCLIDocs(RootCmd)
 			if err != nil {
 				return err
@@ -70,9 +107,52 @@ func newCmdDoc() *cobra.Command {
 		},
 	}
 
+	cmd.Flags().BoolVar(&printAPI, "print-api", false, "Output the tree of commands and flags of the CLI as JSON, instead of the YAML documentation")
+
 	return cmd
 }
 
+// generateAPI returns the description of the given command and of its
+// available subcommands.
+func generateAPI(cmd *cobra.Command) apiCommand {
+	api := apiCommand{
+		Name:           cmd.Name(),
+		Path:           cmd.CommandPath(),
+		Aliases:        cmd.Aliases,
+		Short:          cmd.Short,
+		Long:           cmd.Long,
+		Example:        cmd.Example,
+		Deprecated:     cmd.Deprecated,
+		Flags:          generateAPIFlags(cmd.NonInheritedFlags()),
+		InheritedFlags: generateAPIFlags(cmd.InheritedFlags()),
+	}
+	for _, c := range cmd.Commands() {
+		if !c.IsAvailableCommand() || c.IsAdditionalHelpTopicCommand() {
+			continue
+		}
+		api.Commands = append(api.Commands, generateAPI(c))
+	}
+	return api
+}
+
+func generateAPIFlags(flags *pflag.FlagSet) []apiFlag {
+	var apiFlags []apiFlag
+	flags.VisitAll(func(f *pflag.Flag) {
+		if f.Hidden {
+			return
+		}
+		apiFlags = append(apiFlags, apiFlag{
+			Name:       f.Name,
+			Shorthand:  f.Shorthand,
+			Type:       f.Value.Type(),
+			Default:    f.DefValue,
+			Usage:      f.Usage,
+			Deprecated: f.Deprecated,
+		})
+	})
+	return apiFlags
+}
+
 // generateCLIDocs takes a command and recursively walks the tree of commands,
 // adding each as an item to cmdList.
 func generateCLIDocs(cmd *cobra.Command) ([]cmdDoc, error) {
@@ -224,6 +304,18 @@ func generateAnnotationsDocs() []annotationDoc {
 			Name:        k8s.ProxyOutboundConnectTimeout,
 			Description: "Used to configure the outbound TCP connection timeout in the proxy",
 		},
+		{
+			Name:        k8s.ProxyInboundMaxInFlightAnnotation,
+			Description: "Maximum number of requests the proxy processes concurrently on inbound connections",
+		},
+		{
+			Name:        k8s.ProxyOutboundMaxInFlightAnnotation,
+			Description: "Maximum number of requests the proxy processes concurrently on outbound connections",
+		},
+		{
+			Name:        k8s.ProxyBufferCapacityAnnotation,
+			Description: "Number of requests the proxy buffers for each of its services while they are not ready",
+		},
 		{
 			Name:        k8s.ProxyWaitBeforeExitSecondsAnnotation,
 			Description: "The proxy sidecar will stay alive for at least the given period before receiving SIGTERM signal from Kubernetes but no longer than pod's `terminationGracePeriodSeconds`. If not provided, it will be defaulted to `0`",
@@ -232,6 +324,14 @@ func generateAnnotationsDocs() []annotationDoc {
 			Name:        k8s.ProxyAwait,
 			Description: "The application container will not start until the proxy is ready; accepted values are `enabled` and `disabled`",
 		},
+		{
+			Name:        k8s.ProxyJobShutdownAnnotation,
+			Description: "For Job and CronJob pods, wraps the commands of the application containers with `linkerd-await --shutdown` so the proxy exits once the application completes; accepted values are `enabled` and `disabled`. linkerd-await is copied from the debug image, and every application container must have an explicit `command`. On Kubernetes 1.29 and later, or with the `NativeSidecars` feature gate, the proxy is injected as a native sidecar instead",
+		},
+		{
+			Name:        k8s.ConsistentHashAnnotation,
+			Description: "Set on a Service, requests that the clients balance its endpoints with a consistent hash so that requests with the same key stick to the same endpoint; accepted values are `header:<name>` and `source-identity`",
+		},
 		{
 			Name:        k8s.CloseWaitTimeoutAnnotation,
 			Description: "Sets nf_conntrack_tcp_timeout_close_wait. Accepts a duration string, e.g. `1m` or `3600s`",
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestGenerateAPI(t *testing.T) {
+	api := generateAPI(RootCmd)
+	if api.Path != "linkerd" {
+		t.Fatalf("Expected the root command to be linkerd, got %s", api.Path)
+	}
+
+	var install *apiCommand
+	for i, c := range api.Commands {
+		if c.Name == "doc" {
+			t.Fatal("Expected the hidden doc command to be skipped")
+		}
+		if c.Name == "install" {
+			install = &api.Commands[i]
+		}
+	}
+	if install == nil {
+		t.Fatal("Expected the install command to be described")
+	}
+	if install.Path != "linkerd install" {
+		t.Fatalf("Unexpected path for the install command: %s", install.Path)
+	}
+
+	found := false
+	for _, f := range install.Flags {
+		if f.Name == "ha" {
+			found = true
+			if f.Type != "bool" || f.Default != "false" {
+				t.Fatalf("Unexpected description of the ha flag: %+v", f)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("Expected the ha flag of the install command to be described, got %+v", install.Flags)
+	}
+}
@@ -32,9 +32,12 @@ type (
 	// map[ServiceID]map[Port][]podData
 	endpointsInfo map[string]map[uint32][]podData
 	podData       struct {
-		name    string
-		address string
-		ip      string
+		name        string
+		address     string
+		ip          string
+		node        string
+		zone        string
+		imageDigest string
 	}
 )
 
@@ -183,9 +186,12 @@ func requestEndpointsFromAPI(client destinationPb.DestinationClient, authorities
 
 				labels := addr.GetMetricLabels()
 				info[serviceID][port] = append(info[serviceID][port], podData{
-					name:    labels["pod"],
-					address: tcpAddr.String(),
-					ip:      getIP(tcpAddr),
+					name:        labels["pod"],
+					address:     tcpAddr.String(),
+					ip:          getIP(tcpAddr),
+					node:        labels["node"],
+					zone:        labels["zone"],
+					imageDigest: labels["image_digest"],
 				})
 			}
 		}
@@ -211,11 +217,14 @@ func renderEndpoints(endpoints endpointsInfo, options *endpointsOptions) string
 }
 
 type rowEndpoint struct {
-	Namespace string `json:"namespace"`
-	IP        string `json:"ip"`
-	Port      uint32 `json:"port"`
-	Pod       string `json:"pod"`
-	Service   string `json:"service"`
+	Namespace   string `json:"namespace"`
+	IP          string `json:"ip"`
+	Port        uint32 `json:"port"`
+	Pod         string `json:"pod"`
+	Service     string `json:"service"`
+	Node        string `json:"node,omitempty"`
+	Zone        string `json:"zone,omitempty"`
+	ImageDigest string `json:"imageDigest,omitempty"`
 }
 
 func writeEndpointsToBuffer(endpoints endpointsInfo, w *tabwriter.Writer, options *endpointsOptions) {
@@ -236,11 +245,14 @@ func writeEndpointsToBuffer(endpoints endpointsInfo, w *tabwriter.Writer, option
 					name = parts[1]
 				}
 				row := rowEndpoint{
-					Namespace: namespace,
-					IP:        pod.ip,
-					Port:      port,
-					Pod:       name,
-					Service:   serviceID,
+					Namespace:   namespace,
+					IP:          pod.ip,
+					Port:        port,
+					Pod:         name,
+					Service:     serviceID,
+					Node:        pod.node,
+					Zone:        pod.zone,
+					ImageDigest: pod.imageDigest,
 				}
 
 				endpointsTables[namespace] = append(endpointsTables[namespace], row)
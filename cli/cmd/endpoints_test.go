@@ -92,9 +92,12 @@ func TestEndpoints(t *testing.T) {
 					ServiceID: "emoji-svc",
 					Pods: []destination.PodDetails{
 						{
-							Name: "emoji-6bf9f47bd5-jjcrl",
-							IP:   16909060,
-							Port: 8080,
+							Name:        "emoji-6bf9f47bd5-jjcrl",
+							IP:          16909060,
+							Port:        8080,
+							Node:        "node-1",
+							Zone:        "west-1a",
+							ImageDigest: "sha256:1f2b7b8f0b3c",
 						},
 					},
 				},
@@ -127,8 +127,7 @@ This command initiates a port-forward to a given pod or a set of pods and fetche
 	cmd.PersistentFlags().StringVarP(&options.namespace, "namespace", "n", options.namespace, "Namespace of the pod")
 	cmd.PersistentFlags().StringVarP(&options.selector, "selector", "l", options.selector, "Selector (label query) to filter on, supports ‘=’, ‘==’, and ‘!=’ ")
 
-	pkgcmd.ConfigureNamespaceFlagCompletion(cmd, []string{"namespace"},
-		kubeconfigPath, impersonate, impersonateGroup, kubeContext)
+	pkgcmd.ConfigureNamespaceFlagCompletion(cmd, []string{"namespace"})
 
 	return cmd
 }
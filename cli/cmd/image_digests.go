@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	l5dcharts "github.com/linkerd/linkerd2/pkg/charts/linkerd2"
+	"github.com/linkerd/linkerd2/pkg/healthcheck"
+	"github.com/spf13/pflag"
+	"helm.sh/helm/v3/pkg/chartutil"
+	valuespkg "helm.sh/helm/v3/pkg/cli/values"
+	"k8s.io/client-go/kubernetes"
+)
+
+const digestResolutionTimeout = 30 * time.Second
+
+// controlPlaneImage locates the values holding the name, tag and digest of
+// an image of the control plane.
+type controlPlaneImage struct {
+	name    string
+	version string
+	digest  string
+	// defaultsToLinkerdVersion is set when an empty tag stands for
+	// linkerdVersion
+	defaultsToLinkerdVersion bool
+}
+
+var controlPlaneImages = []controlPlaneImage{
+	{"controllerImage", "controllerImageVersion", "controllerImageDigest", true},
+	{"proxy.image.name", "proxy.image.version", "proxy.image.digest", true},
+	{"proxyInit.image.name", "proxyInit.image.version", "proxyInit.image.digest", false},
+	{"debugContainer.image.name", "debugContainer.image.version", "debugContainer.image.digest", true},
+}
+
+// digestResolver resolves image tags to digests.
+type digestResolver interface {
+	Resolve(ctx context.Context, image, tag string) (string, error)
+}
+
+// makeImageDigestFlags returns the flags controlling the pinning of the
+// control plane images to digests, shared by install and upgrade.
+func makeImageDigestFlags() *pflag.FlagSet {
+	imageDigestFlags := pflag.NewFlagSet("image-digests", pflag.ExitOnError)
+	imageDigestFlags.BoolVar(
+		&pinImageDigests, "pin-image-digests", false,
+		"Resolve the tags of the control plane images to digests by querying their registries, and pin the digests in the manifests",
+	)
+	imageDigestFlags.BoolVar(
+		&skipDigestResolution, "skip-digest-resolution", false,
+		"Never query the registries for image digests, e.g. in air-gapped environments; digests set through values are kept as is",
+	)
+	return imageDigestFlags
+}
+
+// resolveImageDigests resolves the tags of the control plane images in the
+// given values to digests, and sets the digest values accordingly.
+func resolveImageDigests(ctx context.Context, vals chartutil.Values, resolver digestResolver) error {
+	resolved := map[string]string{}
+	for _, image := range controlPlaneImages {
+		name, tag, err := imageRef(vals, image)
+		if err != nil {
+			return err
+		}
+
+		ref := fmt.Sprintf("%s:%s", name, tag)
+		digest, ok := resolved[ref]
+		if !ok {
+			digest, err = resolver.Resolve(ctx, name, tag)
+			if err != nil {
+				return fmt.Errorf("failed to pin the digest of %s (use --skip-digest-resolution in air-gapped environments): %s", ref, err)
+			}
+			resolved[ref] = digest
+		}
+		if err := setValue(vals, image.digest, digest); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkPinnedDigests returns an error if the upgrade would keep the digest
+// pinned by the previous installation for an image whose name or tag
+// changes, as the digest would still be that of the previous image, which
+// the runtime would keep pulling. It's only needed when the digests aren't
+// resolved again, with --skip-digest-resolution.
+func checkPinnedDigests(ctx context.Context, k kubernetes.Interface, values *l5dcharts.Values, options valuespkg.Options) error {
+	cm, _, err := healthcheck.FetchLinkerdConfigMap(ctx, k, controlPlaneNamespace)
+	if err != nil {
+		return fmt.Errorf("failed to fetch the images of the previous installation: %s", err)
+	}
+	previous, err := l5dcharts.ValuesFromConfigMap(cm)
+	if err != nil {
+		return fmt.Errorf("failed to load the images of the previous installation: %s", err)
+	}
+	previousVals, err := previous.ToMap()
+	if err != nil {
+		return err
+	}
+
+	upgradedVals, err := values.ToMap()
+	if err != nil {
+		return err
+	}
+	overrides, err := options.MergeValues(nil)
+	if err != nil {
+		return err
+	}
+	upgradedVals = chartutil.CoalesceTables(overrides, upgradedVals)
+
+	stale, err := staleImageDigests(previousVals, upgradedVals)
+	if err != nil {
+		return err
+	}
+	if len(stale) > 0 {
+		return fmt.Errorf("the digests pinned by the previous installation can't be kept with --skip-digest-resolution, as the images change: %s; set the digests of the new images with --set, or don't use --skip-digest-resolution", strings.Join(stale, ", "))
+	}
+	return nil
+}
+
+// staleImageDigests returns the digest values that are unchanged from the
+// previous values while the name or tag of their image changes.
+func staleImageDigests(previous, upgraded chartutil.Values) ([]string, error) {
+	var stale []string
+	for _, image := range controlPlaneImages {
+		digest, err := stringValue(upgraded, image.digest)
+		if err != nil {
+			return nil, err
+		}
+		previousDigest, err := stringValue(previous, image.digest)
+		if err != nil {
+			return nil, err
+		}
+		if digest == "" || digest != previousDigest {
+			continue
+		}
+
+		previousName, previousTag, err := imageRef(previous, image)
+		if err != nil {
+			return nil, err
+		}
+		name, tag, err := imageRef(upgraded, image)
+		if err != nil {
+			return nil, err
+		}
+		if name != previousName || tag != previousTag {
+			stale = append(stale, fmt.Sprintf("%s (pinned for %s:%s, now %s:%s)", image.digest, previousName, previousTag, name, tag))
+		}
+	}
+	return stale, nil
+}
+
+// imageRef returns the name and tag of the given image in the values.
+func imageRef(vals chartutil.Values, image controlPlaneImage) (string, string, error) {
+	name, err := stringValue(vals, image.name)
+	if err != nil {
+		return "", "", err
+	}
+	tag, err := stringValue(vals, image.version)
+	if err != nil {
+		return "", "", err
+	}
+	if tag == "" && image.defaultsToLinkerdVersion {
+		tag, err = stringValue(vals, "linkerdVersion")
+		if err != nil {
+			return "", "", err
+		}
+	}
+	return name, tag, nil
+}
+
+// hasImageDigests returns whether any of the control plane images is pinned
+// to a digest.
+func hasImageDigests(values *l5dcharts.Values) bool {
+	return values.ControllerImageDigest != "" ||
+		values.Proxy.Image.Digest != "" ||
+		values.ProxyInit.Image.Digest != "" ||
+		values.DebugContainer.Image.Digest != ""
+}
+
+// clearImageDigests unpins all the control plane images.
+func clearImageDigests(values *l5dcharts.Values) {
+	values.ControllerImageDigest = ""
+	values.Proxy.Image.Digest = ""
+	values.ProxyInit.Image.Digest = ""
+	values.DebugContainer.Image.Digest = ""
+}
+
+func stringValue(vals chartutil.Values, path string) (string, error) {
+	value, err := vals.PathValue(path)
+	if err != nil {
+		switch err.(type) {
+		case chartutil.ErrNoValue, chartutil.ErrNoTable:
+			return "", nil
+		default:
+			return "", err
+		}
+	}
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%s must be a string, got %v", path, value)
+	}
+	return s, nil
+}
+
+func setValue(vals chartutil.Values, path, value string) error {
+	i := strings.LastIndex(path, ".")
+	if i < 0 {
+		vals[path] = value
+		return nil
+	}
+	table, err := vals.Table(path[:i])
+	if err != nil {
+		return err
+	}
+	table[path[i+1:]] = value
+	return nil
+}
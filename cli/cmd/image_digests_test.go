@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	l5dcharts "github.com/linkerd/linkerd2/pkg/charts/linkerd2"
+	"helm.sh/helm/v3/pkg/chartutil"
+)
+
+type fakeDigestResolver struct {
+	queried []string
+}
+
+func (r *fakeDigestResolver) Resolve(_ context.Context, image, tag string) (string, error) {
+	ref := fmt.Sprintf("%s:%s", image, tag)
+	r.queried = append(r.queried, ref)
+	return fmt.Sprintf("sha256:%d", len(r.queried)), nil
+}
+
+func TestResolveImageDigests(t *testing.T) {
+	values, err := l5dcharts.NewValues()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	values.LinkerdVersion = "stable-2.10.2"
+	values.ControllerImageVersion = ""
+	values.Proxy.Image.Version = ""
+	values.DebugContainer.Image.Version = ""
+	m, err := values.ToMap()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	vals := chartutil.Values(m)
+
+	resolver := &fakeDigestResolver{}
+	if err := resolveImageDigests(context.Background(), vals, resolver); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expectedQueries := []string{
+		"cr.l5d.io/linkerd/controller:stable-2.10.2",
+		"cr.l5d.io/linkerd/proxy:stable-2.10.2",
+		fmt.Sprintf("cr.l5d.io/linkerd/proxy-init:%s", values.ProxyInit.Image.Version),
+		"cr.l5d.io/linkerd/debug:stable-2.10.2",
+	}
+	if len(resolver.queried) != len(expectedQueries) {
+		t.Fatalf("Expected queries %v, got %v", expectedQueries, resolver.queried)
+	}
+	for i, expected := range expectedQueries {
+		if resolver.queried[i] != expected {
+			t.Fatalf("Expected queries %v, got %v", expectedQueries, resolver.queried)
+		}
+	}
+
+	for i, path := range []string{"controllerImageDigest", "proxy.image.digest", "proxyInit.image.digest", "debugContainer.image.digest"} {
+		digest, err := stringValue(vals, path)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if expected := fmt.Sprintf("sha256:%d", i+1); digest != expected {
+			t.Fatalf("Expected %s to be %s, got %s", path, expected, digest)
+		}
+	}
+}
+
+func TestClearImageDigests(t *testing.T) {
+	values, err := l5dcharts.NewValues()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if hasImageDigests(values) {
+		t.Fatal("Expected no image digests by default")
+	}
+
+	values.Proxy.Image.Digest = "sha256:1"
+	if !hasImageDigests(values) {
+		t.Fatal("Expected image digests to be found")
+	}
+	clearImageDigests(values)
+	if hasImageDigests(values) {
+		t.Fatal("Expected image digests to be cleared")
+	}
+}
+
+func TestStaleImageDigests(t *testing.T) {
+	pinnedValues := func() *l5dcharts.Values {
+		values, err := l5dcharts.NewValues()
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		values.LinkerdVersion = "stable-2.10.1"
+		values.ControllerImageVersion = ""
+		values.Proxy.Image.Version = ""
+		values.ControllerImageDigest = "sha256:1"
+		values.Proxy.Image.Digest = "sha256:2"
+		return values
+	}
+	previous, err := pinnedValues().ToMap()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		upgrade  func(values *l5dcharts.Values)
+		expected []string
+	}{
+		{
+			name:    "same images",
+			upgrade: func(*l5dcharts.Values) {},
+		},
+		{
+			name: "new version",
+			upgrade: func(values *l5dcharts.Values) {
+				values.LinkerdVersion = "stable-2.10.2"
+			},
+			expected: []string{
+				"controllerImageDigest (pinned for cr.l5d.io/linkerd/controller:stable-2.10.1, now cr.l5d.io/linkerd/controller:stable-2.10.2)",
+				"proxy.image.digest (pinned for cr.l5d.io/linkerd/proxy:stable-2.10.1, now cr.l5d.io/linkerd/proxy:stable-2.10.2)",
+			},
+		},
+		{
+			name: "new version with new digests",
+			upgrade: func(values *l5dcharts.Values) {
+				values.LinkerdVersion = "stable-2.10.2"
+				values.ControllerImageDigest = "sha256:3"
+				values.Proxy.Image.Digest = "sha256:4"
+			},
+		},
+		{
+			name: "new image name",
+			upgrade: func(values *l5dcharts.Values) {
+				values.Proxy.Image.Name = "registry.example.com/linkerd/proxy"
+			},
+			expected: []string{
+				"proxy.image.digest (pinned for cr.l5d.io/linkerd/proxy:stable-2.10.1, now registry.example.com/linkerd/proxy:stable-2.10.1)",
+			},
+		},
+	} {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			values := pinnedValues()
+			tc.upgrade(values)
+			upgraded, err := values.ToMap()
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+
+			stale, err := staleImageDigests(previous, upgraded)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if len(stale) != len(tc.expected) {
+				t.Fatalf("Expected stale digests %v, got %v", tc.expected, stale)
+			}
+			for i, expected := range tc.expected {
+				if stale[i] != expected {
+					t.Fatalf("Expected stale digests %v, got %v", tc.expected, stale)
+				}
+			}
+		})
+	}
+}
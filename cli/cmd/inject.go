@@ -240,6 +240,7 @@ func (resourceTransformerInject) generateReport(reports []inject.Report, output
 	annotatable := false
 	hostNetwork := []string{}
 	sidecar := []string{}
+	staticPods := []string{}
 	udp := []string{}
 	injectDisabled := []string{}
 	automountServiceAccountTokenFalse := []string{}
@@ -264,6 +265,11 @@ func (resourceTransformerInject) generateReport(reports []inject.Report, output
 			warningsPrinted = true
 		}
 
+		if r.StaticPod {
+			staticPods = append(staticPods, r.ResName())
+			warningsPrinted = true
+		}
+
 		if r.UDP {
 			udp = append(udp, r.ResName())
 			warningsPrinted = true
@@ -299,6 +305,10 @@ func (resourceTransformerInject) generateReport(reports []inject.Report, output
 		output.Write([]byte(fmt.Sprintf("%s %s\n", okStatus, sidecarDesc)))
 	}
 
+	if len(staticPods) > 0 {
+		output.Write([]byte(fmt.Sprintf("%s static pods can't be injected, mirror pod detected in %s\n", warnStatus, strings.Join(staticPods, ", "))))
+	}
+
 	if len(injectDisabled) > 0 {
 		output.Write([]byte(fmt.Sprintf("%s \"%s: %s\" annotation set on %s\n",
 			warnStatus, k8s.ProxyInjectAnnotation, k8s.ProxyInjectDisabled, strings.Join(injectDisabled, ", "))))
@@ -374,6 +384,11 @@ func fetchConfigs(ctx context.Context) (*linkerd2.Values, error) {
 
 	// Get the New Linkerd Configuration
 	_, values, err := healthcheck.FetchCurrentConfiguration(ctx, api, controlPlaneNamespace)
+	if err != nil || values == nil {
+		return values, err
+	}
+
+	values.IdentityTrustAnchorsPEM, err = healthcheck.FetchTrustAnchors(ctx, api, controlPlaneNamespace, values)
 	return values, err
 }
 
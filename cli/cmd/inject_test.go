@@ -102,6 +102,16 @@ func TestUninjectAndInject(t *testing.T) {
 	proxyIgnorePortsConfig.ProxyInit.IgnoreInboundPorts = "22,8100-8102"
 	proxyIgnorePortsConfig.ProxyInit.IgnoreOutboundPorts = "5432"
 
+	readOnlyRootFilesystem := false
+	securityContextConfig := defaultConfig()
+	securityContextConfig.Proxy.ReadOnlyRootFilesystem = &readOnlyRootFilesystem
+	securityContextConfig.Proxy.SeccompProfile = &linkerd2.SeccompProfile{Type: "RuntimeDefault"}
+	securityContextConfig.ProxyInit.ReadOnlyRootFilesystem = &readOnlyRootFilesystem
+	securityContextConfig.ProxyInit.SeccompProfile = &linkerd2.SeccompProfile{
+		Type:             "Localhost",
+		LocalhostProfile: "profiles/proxy-init.json",
+	}
+
 	testCases := []testCase{
 		{
 			inputFileName:    "inject_emojivoto_deployment.input.yml",
@@ -146,6 +156,13 @@ func TestUninjectAndInject(t *testing.T) {
 			injectProxy:      true,
 			testInjectConfig: defaultValues,
 		},
+		{
+			inputFileName:    "inject_emojivoto_deployment.input.yml",
+			goldenFileName:   "inject_emojivoto_deployment_security_context.golden.yml",
+			reportFileName:   "inject_emojivoto_deployment.report",
+			injectProxy:      true,
+			testInjectConfig: securityContextConfig,
+		},
 		{
 			inputFileName:    "inject_emojivoto_deployment_capabilities.input.yml",
 			goldenFileName:   "inject_emojivoto_deployment_capabilities.golden.yml",
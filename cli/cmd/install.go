@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path"
 	"strings"
@@ -18,6 +19,7 @@ import (
 	flagspkg "github.com/linkerd/linkerd2/pkg/flags"
 	"github.com/linkerd/linkerd2/pkg/healthcheck"
 	"github.com/linkerd/linkerd2/pkg/k8s"
+	"github.com/linkerd/linkerd2/pkg/registry"
 	"github.com/linkerd/linkerd2/pkg/tree"
 	"github.com/spf13/cobra"
 	"helm.sh/helm/v3/pkg/chart/loader"
@@ -66,7 +68,9 @@ var (
 		"templates/heartbeat-rbac.yaml",
 		"templates/serviceprofile-crd.yaml",
 		"templates/trafficsplit-crd.yaml",
+		"templates/issuancepolicy-crd.yaml",
 		"templates/proxy-injector-rbac.yaml",
+		"templates/admin-rbac.yaml",
 		"templates/psp.yaml",
 	}
 
@@ -79,6 +83,9 @@ var (
 	}
 
 	ignoreCluster bool
+
+	pinImageDigests      bool
+	skipDigestResolution bool
 )
 
 /* Commands */
@@ -203,6 +210,7 @@ A full list of configurable values can be found at https://www.github.com/linker
 	cmd.Flags().AddFlagSet(installOnlyFlagSet)
 	cmd.Flags().AddFlagSet(installUpgradeFlagSet)
 	cmd.Flags().AddFlagSet(proxyFlagSet)
+	cmd.Flags().AddFlagSet(makeImageDigestFlags())
 	flagspkg.AddValueOptionsFlags(cmd.Flags(), &options)
 
 	cmd.Flags().BoolVar(
@@ -256,6 +264,7 @@ A full list of configurable values can be found at https://www.github.com/linker
 	cmd.Flags().AddFlagSet(installOnlyFlagSet)
 	cmd.Flags().AddFlagSet(installUpgradeFlagSet)
 	cmd.Flags().AddFlagSet(proxyFlagSet)
+	cmd.Flags().AddFlagSet(makeImageDigestFlags())
 	cmd.PersistentFlags().BoolVar(&ignoreCluster, "ignore-cluster", false,
 		"Ignore the current Kubernetes cluster when checking for existing cluster configuration (default false)")
 
@@ -372,6 +381,13 @@ func render(w io.Writer, values *l5dcharts.Values, stage string, options valuesp
 		return err
 	}
 
+	if pinImageDigests && !skipDigestResolution && (stage == "" || stage == controlPlaneStage) {
+		resolver := registry.NewDigestResolver(&http.Client{Timeout: digestResolutionTimeout})
+		if err := resolveImageDigests(context.Background(), vals, resolver); err != nil {
+			return err
+		}
+	}
+
 	// Attach the final values into the `Values` field for rendering to work
 	renderedTemplates, err := engine.Render(chart, map[string]interface{}{"Values": vals})
 	if err != nil {
@@ -6,13 +6,18 @@ import (
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/linkerd/linkerd2/cli/flag"
 	charts "github.com/linkerd/linkerd2/pkg/charts/linkerd2"
+	"github.com/linkerd/linkerd2/pkg/k8s"
 	"github.com/linkerd/linkerd2/pkg/tls"
 	"helm.sh/helm/v3/pkg/cli/values"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
 )
 
 const (
@@ -383,6 +388,29 @@ func TestValidate(t *testing.T) {
 		}
 	})
 
+	t.Run("Rejects disabling the sp-validator with an external secret", func(t *testing.T) {
+		values, err := testInstallOptions()
+		if err != nil {
+			t.Fatalf("Unexpected error: %v\n", err)
+		}
+
+		values.DisableProfileValidator = true
+		if err := validateValues(context.Background(), nil, values); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		values.ProfileValidator.ExternalSecret = true
+		expected := "--disable-sp-validator must not be specified if profileValidator.externalSecret is true"
+
+		err = validateValues(context.Background(), nil, values)
+		if err == nil {
+			t.Fatal("Expected error, got nothing")
+		}
+		if err.Error() != expected {
+			t.Fatalf("Expected error string\"%s\", got \"%s\"", expected, err)
+		}
+	})
+
 	t.Run("Properly validates proxy log level", func(t *testing.T) {
 		testCases := []struct {
 			input string
@@ -522,6 +550,92 @@ func TestValidate(t *testing.T) {
 			}
 		}
 	})
+
+	t.Run("Validates the issuer against the trust roots of an external CA", func(t *testing.T) {
+		crt, err := loadCrtPEM(filepath.Join("testdata", "valid-crt.pem"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, err := loadKeyPEM(filepath.Join("testdata", "valid-key.pem"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		validAnchors, err := ioutil.ReadFile(filepath.Join("testdata", "valid-trust-anchors.pem"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		wrongAnchors, err := ioutil.ReadFile(filepath.Join("testdata", "wrong-domain-trust-anchors.pem"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the issuer secret has no ca.crt, the trust anchors being only in
+		// the trust roots ConfigMap
+		issuerSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: k8s.IdentityIssuerSecretName, Namespace: controlPlaneNamespace},
+			Type:       corev1.SecretTypeTLS,
+			Data:       map[string][]byte{corev1.TLSCertKey: []byte(crt), corev1.TLSPrivateKeyKey: []byte(key)},
+		}
+		trustRoots := func(anchors []byte) *corev1.ConfigMap {
+			return &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{Name: k8s.IdentityTrustRootsConfigMapName, Namespace: controlPlaneNamespace},
+				Data:       map[string]string{k8s.IdentityIssuerTrustAnchorsName: string(anchors)},
+			}
+		}
+
+		testCases := []struct {
+			name          string
+			objects       []runtime.Object
+			anchorsFile   string
+			expectedError string
+		}{
+			{
+				name:    "trusted issuer",
+				objects: []runtime.Object{issuerSecret, trustRoots(validAnchors)},
+			},
+			{
+				name:          "untrusted issuer",
+				objects:       []runtime.Object{issuerSecret, trustRoots(wrongAnchors)},
+				expectedError: "failed to validate issuer credentials: ",
+			},
+			{
+				name:          "no trust roots",
+				objects:       []runtime.Object{issuerSecret},
+				expectedError: `configmaps "linkerd-identity-trust-roots" not found`,
+			},
+			{
+				name:          "trust anchors file",
+				objects:       []runtime.Object{issuerSecret, trustRoots(validAnchors)},
+				anchorsFile:   string(validAnchors),
+				expectedError: "--identity-trust-anchors-file must not be specified if --identity-external-ca=true",
+			},
+		}
+
+		for _, tc := range testCases {
+			tc := tc // pin
+			t.Run(tc.name, func(t *testing.T) {
+				values, err := testInstallOptionsNoCerts(false)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v\n", err)
+				}
+				values.Identity.ExternalCA = true
+				values.Identity.Issuer.Scheme = string(corev1.SecretTypeTLS)
+				values.IdentityTrustAnchorsPEM = tc.anchorsFile
+
+				api := &k8s.KubernetesAPI{Interface: fake.NewSimpleClientset(tc.objects...)}
+				err = validateValues(context.Background(), api, values)
+				if tc.expectedError == "" {
+					if err != nil {
+						t.Fatalf("Expected no error but got \"%s\"", err)
+					}
+					return
+				}
+				if err == nil || !strings.HasPrefix(err.Error(), tc.expectedError) {
+					t.Fatalf("Expected error starting with \"%s\", got \"%v\"", tc.expectedError, err)
+				}
+			})
+		}
+	})
 }
 
 func fakeHeartbeatSchedule() string {
@@ -108,8 +108,7 @@ func newCmdMetrics() *cobra.Command {
 
 	cmd.PersistentFlags().StringVarP(&options.namespace, "namespace", "n", options.namespace, "Namespace of resource")
 
-	pkgcmd.ConfigureNamespaceFlagCompletion(cmd, []string{"namespace"},
-		kubeconfigPath, impersonate, impersonateGroup, kubeContext)
+	pkgcmd.ConfigureNamespaceFlagCompletion(cmd, []string{"namespace"})
 
 	return cmd
 }
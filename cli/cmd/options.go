@@ -85,6 +85,12 @@ func makeInstallUpgradeFlags(defaults *l5dcharts.Values) ([]flag.Flag, *pflag.Fl
 				return nil
 			}),
 
+		flag.NewBoolFlag(installUpgradeFlags, "disable-sp-validator", defaults.DisableProfileValidator,
+			"Disables the service profile validator webhook (default false)", func(values *l5dcharts.Values, value bool) error {
+				values.DisableProfileValidator = value
+				return nil
+			}),
+
 		flag.NewDurationFlag(installUpgradeFlags, "identity-issuance-lifetime", issuanceLifetime,
 			"The amount of time for which the Identity issuer should certify identity",
 			func(values *l5dcharts.Values, value time.Duration) error {
@@ -263,8 +269,8 @@ func makeInstallFlags(defaults *l5dcharts.Values) ([]flag.Flag, *pflag.FlagSet)
 			}),
 
 		flag.NewBoolFlag(installOnlyFlags, "identity-external-ca", false,
-			"Whether to use an external CA provider (default false)", func(values *l5dcharts.Values, value bool) error {
-				values.Identity.Issuer.ExternalCA = value
+			"Whether to use an external CA provider, the trust anchors then being read from the existing linkerd-identity-trust-roots ConfigMap (default false)", func(values *l5dcharts.Values, value bool) error {
+				values.Identity.ExternalCA = value
 				return nil
 			}),
 	}
@@ -283,24 +289,28 @@ func makeProxyFlags(defaults *l5dcharts.Values) ([]flag.Flag, *pflag.FlagSet) {
 		flag.NewStringFlagP(proxyFlags, "proxy-version", "v", defaults.Proxy.Image.Version, "Tag to be used for the Linkerd proxy images",
 			func(values *l5dcharts.Values, value string) error {
 				values.Proxy.Image.Version = value
+				values.Proxy.Image.Digest = ""
 				return nil
 			}),
 
 		flag.NewStringFlag(proxyFlags, "proxy-image", defaults.Proxy.Image.Name, "Linkerd proxy container image name",
 			func(values *l5dcharts.Values, value string) error {
 				values.Proxy.Image.Name = value
+				values.Proxy.Image.Digest = ""
 				return nil
 			}),
 
 		flag.NewStringFlag(proxyFlags, "init-image", defaults.ProxyInit.Image.Name, "Linkerd init container image name",
 			func(values *l5dcharts.Values, value string) error {
 				values.ProxyInit.Image.Name = value
+				values.ProxyInit.Image.Digest = ""
 				return nil
 			}),
 
 		flag.NewStringFlag(proxyFlags, "init-image-version", defaults.ProxyInit.Image.Version,
 			"Linkerd init container image version", func(values *l5dcharts.Values, value string) error {
 				values.ProxyInit.Image.Version = value
+				values.ProxyInit.Image.Digest = ""
 				return nil
 			}),
 
@@ -527,6 +537,10 @@ func validateValues(ctx context.Context, k *k8s.KubernetesAPI, values *l5dcharts
 		return err
 	}
 
+	if values.DisableProfileValidator && values.ProfileValidator.ExternalSecret {
+		return errors.New("--disable-sp-validator must not be specified if profileValidator.externalSecret is true")
+	}
+
 	if values.Identity.Issuer.Scheme == string(corev1.SecretTypeTLS) {
 		if values.Identity.Issuer.TLS.CrtPEM != "" {
 			return errors.New("--identity-issuer-certificate-file must not be specified if --identity-external-issuer=true")
@@ -536,6 +550,35 @@ func validateValues(ctx context.Context, k *k8s.KubernetesAPI, values *l5dcharts
 		}
 	}
 
+	if values.Identity.ExternalCA {
+		if values.IdentityTrustAnchorsPEM != "" {
+			return errors.New("--identity-trust-anchors-file must not be specified if --identity-external-ca=true")
+		}
+		if k != nil {
+			// The trust anchors are only read from the cluster at runtime,
+			// so check that they're there and that they trust the issuer
+			var issuerData *issuercerts.IssuerCertData
+			var err error
+			if values.Identity.Issuer.Scheme == string(corev1.SecretTypeTLS) {
+				issuerData, err = issuercerts.FetchExternalCAIssuerData(ctx, k, values.Identity.Issuer.Scheme, controlPlaneNamespace)
+			} else {
+				issuerData = &issuercerts.IssuerCertData{
+					IssuerCrt: values.Identity.Issuer.TLS.CrtPEM,
+					IssuerKey: values.Identity.Issuer.TLS.KeyPEM,
+				}
+				issuerData.TrustAnchors, err = issuercerts.FetchTrustRoots(ctx, k, controlPlaneNamespace)
+			}
+			if err != nil {
+				return err
+			}
+			_, err = issuerData.VerifyAndBuildCreds()
+			if err != nil {
+				return fmt.Errorf("failed to validate issuer credentials: %s", err)
+			}
+		}
+		return nil
+	}
+
 	if values.Identity.Issuer.Scheme == string(corev1.SecretTypeTLS) && k != nil {
 		externalIssuerData, err := issuercerts.FetchExternalIssuerData(ctx, k, controlPlaneNamespace)
 		if err != nil {
@@ -642,10 +685,20 @@ func validateProxyValues(values *l5dcharts.Values) error {
 
 // initializeIssuerCredentials populates the identity issuer TLS credentials.
 // If we are using an externally managed issuer secret, all we need to do here
-// is copy the trust root from the issuer secret.  Otherwise, if no credentials
-// have already been supplied, we generate them.
+// is copy the trust root from the issuer secret, unless the trust roots are
+// externally managed too, in which case they're read at runtime and nothing
+// is copied.  Otherwise, if no credentials have already been supplied, we
+// generate them.
 func initializeIssuerCredentials(ctx context.Context, k *k8s.KubernetesAPI, values *l5dcharts.Values) error {
-	if values.Identity.Issuer.Scheme == string(corev1.SecretTypeTLS) {
+	if values.Identity.ExternalCA {
+		if k == nil {
+			return errors.New("--ignore-cluster is not supported when --identity-external-ca=true")
+		}
+		if values.Identity.Issuer.Scheme != string(corev1.SecretTypeTLS) && (values.Identity.Issuer.TLS.CrtPEM == "" || values.Identity.Issuer.TLS.KeyPEM == "") {
+			// Generated credentials wouldn't be trusted by the external CA
+			return errors.New("an issuer certificate and private key must be provided if --identity-external-ca=true and --identity-external-issuer=false")
+		}
+	} else if values.Identity.Issuer.Scheme == string(corev1.SecretTypeTLS) {
 		// Using externally managed issuer credentials.  We need to copy the
 		// trust root.
 		if k == nil {
@@ -95,10 +95,7 @@ var RootCmd = &cobra.Command{
 func init() {
 	RootCmd.PersistentFlags().StringVarP(&controlPlaneNamespace, "linkerd-namespace", "L", defaultLinkerdNamespace, "Namespace in which Linkerd is installed ($LINKERD_NAMESPACE)")
 	RootCmd.PersistentFlags().StringVarP(&cniNamespace, "cni-namespace", "", defaultCNINamespace, "Namespace in which the Linkerd CNI plugin is installed")
-	RootCmd.PersistentFlags().StringVar(&kubeconfigPath, "kubeconfig", "", "Path to the kubeconfig file to use for CLI requests")
-	RootCmd.PersistentFlags().StringVar(&kubeContext, "context", "", "Name of the kubeconfig context to use")
-	RootCmd.PersistentFlags().StringVar(&impersonate, "as", "", "Username to impersonate for Kubernetes operations")
-	RootCmd.PersistentFlags().StringArrayVar(&impersonateGroup, "as-group", []string{}, "Group to impersonate for Kubernetes operations")
+	pkgcmd.AddKubeFlags(RootCmd.PersistentFlags(), &kubeconfigPath, &kubeContext, &impersonate, &impersonateGroup)
 	RootCmd.PersistentFlags().StringVar(&apiAddr, "api-addr", "", "Override kubeconfig and communicate directly with the control plane at host:port (mostly for testing)")
 	RootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "Turn on debug logging")
 	RootCmd.AddCommand(newCmdAlpha())
@@ -112,6 +109,7 @@ func init() {
 	RootCmd.AddCommand(newCmdInstallCNIPlugin())
 	RootCmd.AddCommand(newCmdProfile())
 	RootCmd.AddCommand(newCmdRepair())
+	RootCmd.AddCommand(newCmdStatus())
 	RootCmd.AddCommand(newCmdUninject())
 	RootCmd.AddCommand(newCmdUpgrade())
 	RootCmd.AddCommand(newCmdVersion())
@@ -131,11 +129,9 @@ func init() {
 	RootCmd.AddCommand(deprecateCmd(viz.NewCmdTop()))
 
 	// resource-aware completion flag configurations
-	pkgcmd.ConfigureNamespaceFlagCompletion(
-		RootCmd, []string{"linkerd-namespace", "cni-namespace"},
-		kubeconfigPath, impersonate, impersonateGroup, kubeContext)
+	pkgcmd.ConfigureNamespaceFlagCompletion(RootCmd, []string{"linkerd-namespace", "cni-namespace"})
 
-	pkgcmd.ConfigureKubeContextFlagCompletion(RootCmd, kubeconfigPath)
+	pkgcmd.ConfigureKubeContextFlagCompletion(RootCmd)
 }
 
 func deprecateCmd(cmd *cobra.Command) *cobra.Command {
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	pkgcmd "github.com/linkerd/linkerd2/pkg/cmd"
+	"github.com/linkerd/linkerd2/pkg/health"
+	"github.com/linkerd/linkerd2/pkg/k8s"
+	"github.com/spf13/cobra"
+)
+
+type statusOptions struct {
+	output string
+}
+
+// extensionStatus is the health reported by the components of an extension
+type extensionStatus struct {
+	Extension string          `json:"extension"`
+	Namespace string          `json:"namespace"`
+	Reports   []health.Report `json:"components"`
+}
+
+func newStatusOptions() *statusOptions {
+	return &statusOptions{
+		output: tableOutput,
+	}
+}
+
+func (o *statusOptions) validate() error {
+	if o.output != tableOutput && o.output != jsonOutput {
+		return fmt.Errorf("--output currently only supports %s and %s", tableOutput, jsonOutput)
+	}
+	return nil
+}
+
+func newCmdStatus() *cobra.Command {
+	options := newStatusOptions()
+
+	cmd := &cobra.Command{
+		Use:   "status [flags]",
+		Args:  cobra.NoArgs,
+		Short: "Summarize the health reported by the installed extensions",
+		Long: `Summarize the health reported by the installed extensions.
+
+The components of each extension serve a health report on their admin server;
+this command fetches them through port-forwards and summarizes them. The same
+reports are included in the extension checks of "linkerd check".`,
+		Example: `  # Summarize the health of the installed extensions
+  linkerd status
+
+  # Get the health reports in json format
+  linkerd status -o json`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := options.validate(); err != nil {
+				return err
+			}
+
+			k8sAPI, err := k8s.NewAPI(kubeconfigPath, kubeContext, impersonate, impersonateGroup, 0)
+			if err != nil {
+				return err
+			}
+
+			namespaces, err := k8sAPI.GetAllNamespacesWithExtensionLabel(cmd.Context())
+			if err != nil {
+				return err
+			}
+			if len(namespaces) == 0 {
+				fmt.Fprintln(os.Stderr, "No extensions installed.")
+				return nil
+			}
+
+			statuses := []extensionStatus{}
+			for _, ns := range namespaces {
+				extension := ns.Labels[k8s.LinkerdExtensionLabel]
+				reports, err := health.FetchReports(cmd.Context(), k8sAPI, ns.Name, extension)
+				if err != nil {
+					return fmt.Errorf("failed to fetch the health of the %s extension: %s", extension, err)
+				}
+				statuses = append(statuses, extensionStatus{
+					Extension: extension,
+					Namespace: ns.Name,
+					Reports:   reports,
+				})
+			}
+
+			output, err := renderStatus(statuses, options.output)
+			if err != nil {
+				return err
+			}
+			fmt.Print(output)
+
+			if !statusHealthy(statuses) {
+				os.Exit(1)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&options.output, "output", "o", options.output, fmt.Sprintf("Output format; one of: \"%s\" or \"%s\"", tableOutput, jsonOutput))
+	pkgcmd.ConfigureOutputFlagCompletion(cmd)
+
+	return cmd
+}
+
+func statusHealthy(statuses []extensionStatus) bool {
+	for _, status := range statuses {
+		for _, report := range status.Reports {
+			if !report.Healthy {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func renderStatus(statuses []extensionStatus, output string) (string, error) {
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Extension < statuses[j].Extension
+	})
+
+	if output == jsonOutput {
+		b, err := json.MarshalIndent(statuses, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s\n", b), nil
+	}
+
+	var buffer bytes.Buffer
+	w := tabwriter.NewWriter(&buffer, 0, 0, padding, ' ', 0)
+	fmt.Fprintln(w, "EXTENSION\tCOMPONENT\tPOD\tSTATUS")
+	for _, status := range statuses {
+		if len(status.Reports) == 0 {
+			fmt.Fprintf(w, "%s\t-\t-\tno health reported\n", status.Extension)
+			continue
+		}
+		for _, report := range status.Reports {
+			healthy := "healthy"
+			if !report.Healthy {
+				healthy = "unhealthy"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", status.Extension, report.Component, report.Pod, healthy)
+		}
+	}
+	w.Flush()
+
+	// details of the failed checks
+	for _, status := range statuses {
+		for _, report := range status.Reports {
+			for _, check := range report.Checks {
+				if !check.Healthy {
+					fmt.Fprintf(&buffer, "\n%s %s/%s: %s: %s", failStatus, status.Extension, report.Pod, check.Name, check.Message)
+				}
+			}
+		}
+	}
+	if !statusHealthy(statuses) {
+		fmt.Fprintln(&buffer)
+	}
+
+	return buffer.String(), nil
+}
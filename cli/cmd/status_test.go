@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/linkerd/linkerd2/pkg/health"
+)
+
+func TestRenderStatus(t *testing.T) {
+	statuses := []extensionStatus{
+		{
+			Extension: "viz",
+			Namespace: "linkerd-viz",
+			Reports: []health.Report{
+				{
+					Extension: "viz",
+					Component: "metrics-api",
+					Pod:       "metrics-api-6b5d8d4f9-x2kqf",
+					Healthy:   true,
+					Checks:    []health.Check{{Name: "linkerd viz can talk to Kubernetes", Healthy: true}},
+				},
+			},
+		},
+		{
+			Extension: "multicluster",
+			Namespace: "linkerd-multicluster",
+			Reports: []health.Report{
+				{
+					Extension: "multicluster",
+					Component: "service-mirror-east",
+					Pod:       "linkerd-service-mirror-east-7c9f5-pq8lz",
+					Checks: []health.Check{
+						{Name: "target-cluster-watched", Healthy: true},
+						{Name: "gateway-alive", Message: "gateway probe failed: unexpected status 503"},
+					},
+				},
+			},
+		},
+		{
+			Extension: "jaeger",
+			Namespace: "linkerd-jaeger",
+		},
+	}
+
+	expected := `EXTENSION      COMPONENT             POD                                       STATUS
+jaeger         -                     -                                         no health reported
+multicluster   service-mirror-east   linkerd-service-mirror-east-7c9f5-pq8lz   unhealthy
+viz            metrics-api           metrics-api-6b5d8d4f9-x2kqf               healthy
+
+× multicluster/linkerd-service-mirror-east-7c9f5-pq8lz: gateway-alive: gateway probe failed: unexpected status 503
+`
+
+	output, err := renderStatus(statuses, tableOutput)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if output != expected {
+		t.Fatalf("Expected:\n%s\nGot:\n%s", expected, output)
+	}
+	if statusHealthy(statuses) {
+		t.Fatal("Expected the statuses to be unhealthy")
+	}
+}
@@ -123,6 +123,7 @@ A full list of configurable values can be found at https://www.github.com/linker
 	cmd.Flags().AddFlagSet(allStageFlagSet)
 	cmd.Flags().AddFlagSet(installUpgradeFlagSet)
 	cmd.Flags().AddFlagSet(proxyFlagSet)
+	cmd.Flags().AddFlagSet(makeImageDigestFlags())
 	flagspkg.AddValueOptionsFlags(cmd.Flags(), &options)
 
 	return cmd
@@ -183,6 +184,7 @@ A full list of configurable values can be found at https://www.github.com/linker
 	cmd.Flags().AddFlagSet(allStageFlagSet)
 	cmd.Flags().AddFlagSet(installUpgradeFlagSet)
 	cmd.Flags().AddFlagSet(proxyFlagSet)
+	cmd.Flags().AddFlagSet(makeImageDigestFlags())
 	cmd.PersistentFlags().AddFlagSet(upgradeFlagSet)
 	flagspkg.AddValueOptionsFlags(cmd.Flags(), &options)
 
@@ -279,11 +281,35 @@ use Helm to perform upgrades. If Linkerd was not installed with Helm, please use
 the 'linkerd repair' command to repair the Linkerd config`)
 	}
 
+	// With an external CA the trust anchors are read from the
+	// linkerd-identity-trust-roots ConfigMap, so any copy of them in the
+	// stored values is stale.
+	if values.Identity.ExternalCA {
+		values.IdentityTrustAnchorsPEM = ""
+	}
+
+	// The digests pinned by the previous installation are those of the
+	// previous version's images. They're only kept when the registries can't
+	// be queried; otherwise they're resolved again if requested.
+	if !skipDigestResolution && hasImageDigests(values) {
+		clearImageDigests(values)
+		if !pinImageDigests {
+			fmt.Fprintf(os.Stderr, "%s Dropping the image digests pinned by the previous installation; use --pin-image-digests to pin the upgraded images\n", warnStatus)
+		}
+	}
+
 	err = flag.ApplySetFlags(values, flags)
 	if err != nil {
 		return bytes.Buffer{}, err
 	}
 
+	if skipDigestResolution && hasImageDigests(values) {
+		err = checkPinnedDigests(ctx, k, values, options)
+		if err != nil {
+			return bytes.Buffer{}, err
+		}
+	}
+
 	if values.Identity.Issuer.Scheme == string(corev1.SecretTypeTLS) {
 		for _, flag := range flags {
 			if (flag.Name() == "identity-issuer-certificate-file" || flag.Name() == "identity-issuer-key-file") && flag.IsSet() {
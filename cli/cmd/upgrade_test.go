@@ -292,6 +292,22 @@ func TestUpgradeRootFailsWithOldPods(t *testing.T) {
 	}
 }
 
+func TestUpgradeKeepsStaleDigestsFails(t *testing.T) {
+	installOpts, upgradeOpts, _ := testOptions(t)
+	installOpts.ControllerImageDigest = "sha256:1"
+	install := renderInstall(t, installOpts)
+
+	skipDigestResolution = true
+	defer func() { skipDigestResolution = false }()
+
+	_, err := renderUpgrade(install.String(), upgradeOpts)
+
+	expectedErr := "the digests pinned by the previous installation can't be kept with --skip-digest-resolution, as the images change: controllerImageDigest"
+	if err == nil || !strings.HasPrefix(err.Error(), expectedErr) {
+		t.Errorf("Expected error: %s but got %s", expectedErr, err)
+	}
+}
+
 // this test constructs a set of secrets resources
 func TestUpgradeWebhookCrtsNameChange(t *testing.T) {
 	installOpts, upgradeOpts, _ := testOptions(t)
@@ -5,13 +5,18 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/linkerd/linkerd2/pkg/featuregates"
 	"github.com/linkerd/linkerd2/pkg/healthcheck"
 	"github.com/linkerd/linkerd2/pkg/k8s"
 	"github.com/linkerd/linkerd2/pkg/version"
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 )
 
 const defaultVersionString = "unavailable"
@@ -20,6 +25,7 @@ type versionOptions struct {
 	shortVersion      bool
 	onlyClientVersion bool
 	proxy             bool
+	features          bool
 	namespace         string
 }
 
@@ -28,6 +34,7 @@ func newVersionOptions() *versionOptions {
 		shortVersion:      false,
 		onlyClientVersion: false,
 		proxy:             false,
+		features:          false,
 		namespace:         "",
 	}
 }
@@ -57,6 +64,7 @@ func newCmdVersion() *cobra.Command {
 	cmd.PersistentFlags().BoolVar(&options.shortVersion, "short", options.shortVersion, "Print the version number(s) only, with no additional output")
 	cmd.PersistentFlags().BoolVar(&options.onlyClientVersion, "client", options.onlyClientVersion, "Print the client version only")
 	cmd.PersistentFlags().BoolVar(&options.proxy, "proxy", options.proxy, "Print data-plane versions")
+	cmd.PersistentFlags().BoolVar(&options.features, "features", options.features, "Print the feature gates configured in the control plane")
 	cmd.PersistentFlags().StringVarP(&options.namespace, "namespace", "n", options.namespace, "Namespace to use for --proxy versions (default: all namespaces)")
 
 	return cmd
@@ -88,6 +96,14 @@ func configureAndRunVersion(
 			fmt.Fprintf(stdout, "Server version: %s\n", serverVersion)
 		}
 
+		if options.features {
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := printFeatureGates(ctx, stdout, k8sAPI); err != nil {
+				fmt.Fprintln(stdout, "Feature gates: unavailable")
+			}
+		}
+
 		if options.proxy {
 			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 			defer cancel()
@@ -112,3 +128,67 @@ func configureAndRunVersion(
 		}
 	}
 }
+
+// printFeatureGates prints the feature gates set in linkerd-config, followed
+// by the effective gates of the components overriding them with their
+// --feature-gates flag.
+func printFeatureGates(ctx context.Context, w io.Writer, k kubernetes.Interface) error {
+	cm, err := k.CoreV1().ConfigMaps(controlPlaneNamespace).Get(ctx, k8s.ConfigConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	overrides, err := featuregates.FromConfigValues(cm.Data["values"])
+	if err != nil {
+		return err
+	}
+	// The components of the extensions can override the gates as well
+	deploys, err := k.AppsV1().Deployments("").List(ctx, metav1.ListOptions{LabelSelector: k8s.ControllerComponentLabel})
+	if err != nil {
+		return err
+	}
+
+	gates := featuregates.New()
+	gates.SetConfigOverrides(overrides)
+	fmt.Fprintln(w, "Feature gates:")
+	for _, f := range featuregates.Known() {
+		fmt.Fprintf(w, "\t%s=%t\n", f, gates.Enabled(f))
+	}
+
+	var componentGates []string
+	for _, deploy := range deploys.Items {
+		value, ok := featureGatesFlag(deploy.Spec.Template.Spec.Containers)
+		if !ok {
+			continue
+		}
+		gates := featuregates.New()
+		gates.SetConfigOverrides(overrides)
+		if err := gates.ParseFlag(value); err != nil {
+			componentGates = append(componentGates, fmt.Sprintf("%s (%s): invalid --feature-gates: %s", deploy.Name, deploy.Namespace, err))
+			continue
+		}
+		componentGates = append(componentGates, fmt.Sprintf("%s (%s): %s", deploy.Name, deploy.Namespace, gates))
+	}
+	if len(componentGates) > 0 {
+		sort.Strings(componentGates)
+		fmt.Fprintln(w, "Feature gates overridden by components:")
+		for _, gates := range componentGates {
+			fmt.Fprintf(w, "\t%s\n", gates)
+		}
+	}
+	return nil
+}
+
+// featureGatesFlag returns the value of the --feature-gates flag of the
+// given containers, if set.
+func featureGatesFlag(containers []corev1.Container) (string, bool) {
+	for _, c := range containers {
+		for _, arg := range c.Args {
+			for _, prefix := range []string{"-feature-gates=", "--feature-gates="} {
+				if strings.HasPrefix(arg, prefix) {
+					return strings.TrimPrefix(arg, prefix), true
+				}
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/linkerd/linkerd2/pkg/k8s"
+)
+
+func TestPrintFeatureGates(t *testing.T) {
+	k, err := k8s.NewFakeAPI(`
+kind: ConfigMap
+apiVersion: v1
+metadata:
+  name: linkerd-config
+  namespace: linkerd
+data:
+  values: |
+    featureGates:
+      EndpointSlices: true`, `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: linkerd-destination
+  namespace: linkerd
+  labels:
+    linkerd.io/control-plane-component: destination
+spec:
+  template:
+    spec:
+      containers:
+      - name: destination
+        args:
+        - destination
+        - -feature-gates=NativeSidecars=true`, `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: linkerd-identity
+  namespace: linkerd
+  labels:
+    linkerd.io/control-plane-component: identity
+spec:
+  template:
+    spec:
+      containers:
+      - name: identity
+        args:
+        - identity`,
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	var buf bytes.Buffer
+	if err := printFeatureGates(context.Background(), &buf, k); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := `Feature gates:
+	EndpointSlices=true
+	FederatedServices=false
+	NativeSidecars=false
+Feature gates overridden by components:
+	linkerd-destination (linkerd): EndpointSlices=true,FederatedServices=false,NativeSidecars=true
+`
+	if buf.String() != expected {
+		t.Fatalf("Expected:\n%s\nGot:\n%s", expected, buf.String())
+	}
+}
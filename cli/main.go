@@ -7,10 +7,14 @@ import (
 	"strings"
 
 	"github.com/linkerd/linkerd2/cli/cmd"
+	pkgcmd "github.com/linkerd/linkerd2/pkg/cmd"
 )
 
 func main() {
 	root := cmd.RootCmd
+	if pkgcmd.RunningAsKubectlPlugin() {
+		root.Use = pkgcmd.KubectlPluginName
+	}
 	args := os.Args[1:]
 	if _, _, err := root.Find(args); err != nil {
 		if strings.HasPrefix(args[0], "-") {
@@ -0,0 +1,140 @@
+package destination
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/linkerd/linkerd2/controller/api/destination/watcher"
+)
+
+// DefaultNdots is the ndots option Kubernetes sets in the resolv.conf of
+// pods using the ClusterFirst DNS policy.
+const DefaultNdots = 5
+
+type (
+	// AuthorityResolver canonicalizes the authorities of profile lookups the
+	// same way a pod's resolver would: names with fewer than Ndots dots are
+	// first expanded with the search path of the pod's namespace, and
+	// candidates are only retained when they name an existing service.
+	AuthorityResolver struct {
+		clusterDomain string
+		ndots         int
+		searchDomains []string
+	}
+
+	// CanonicalAuthority is the result of canonicalizing an authority.
+	CanonicalAuthority struct {
+		// Host is either the normalized IP or the fully-qualified name of the
+		// authority.
+		Host string
+		Port watcher.Port
+		// IP is set when the authority is an IP literal.
+		IP net.IP
+		// Service and Instance are set when the authority is a service name,
+		// or a pod DNS name when Instance isn't empty.
+		Service  watcher.ServiceID
+		Instance string
+		// Trace describes the steps of the canonicalization, for debugging.
+		Trace []string
+	}
+)
+
+// NewAuthorityResolver returns an AuthorityResolver for the given cluster
+// domain. The search domains are appended to the search path Kubernetes
+// configures for every namespace.
+func NewAuthorityResolver(clusterDomain string, ndots int, searchDomains []string) *AuthorityResolver {
+	domains := []string{}
+	for _, d := range searchDomains {
+		if d = strings.Trim(strings.TrimSpace(d), "."); d != "" {
+			domains = append(domains, d)
+		}
+	}
+	return &AuthorityResolver{
+		clusterDomain: clusterDomain,
+		ndots:         ndots,
+		searchDomains: domains,
+	}
+}
+
+// SearchPath returns the search domains of the pods in the namespace, in the
+// order in which they're tried.
+func (r *AuthorityResolver) SearchPath(namespace string) []string {
+	path := []string{}
+	if namespace != "" {
+		path = append(path, fmt.Sprintf("%s.svc.%s", namespace, r.clusterDomain))
+	}
+	path = append(path, fmt.Sprintf("svc.%s", r.clusterDomain), r.clusterDomain)
+	return append(path, r.searchDomains...)
+}
+
+// Candidates returns the names the host expands to, in the order in which
+// they're tried. Names with a trailing dot are absolute and aren't expanded.
+func (r *AuthorityResolver) Candidates(host, namespace string) []string {
+	if strings.HasSuffix(host, ".") {
+		return []string{strings.TrimSuffix(host, ".")}
+	}
+
+	candidates := []string{}
+	absoluteFirst := strings.Count(host, ".") >= r.ndots
+	if absoluteFirst {
+		candidates = append(candidates, host)
+	}
+	for _, domain := range r.SearchPath(namespace) {
+		candidates = append(candidates, fmt.Sprintf("%s.%s", host, domain))
+	}
+	if !absoluteFirst {
+		candidates = append(candidates, host)
+	}
+	return candidates
+}
+
+// Canonicalize resolves the authority as seen from a pod in the namespace,
+// which may be empty if it isn't known. The exists function reports whether a
+// service exists; it's used to discard the candidates which wouldn't resolve.
+//
+// When none of the candidates names an existing service, the host is used as
+// given, so that profiles can be served for services which don't exist yet.
+func (r *AuthorityResolver) Canonicalize(authority, namespace string, exists func(watcher.ServiceID) bool) (*CanonicalAuthority, error) {
+	host, port, err := getHostAndPort(authority)
+	if err != nil {
+		return nil, fmt.Errorf("invalid authority: %s", err)
+	}
+
+	canonical := &CanonicalAuthority{Port: port}
+	if ip := net.ParseIP(host); ip != nil {
+		canonical.Host = ip.String()
+		canonical.IP = ip
+		canonical.Trace = append(canonical.Trace, fmt.Sprintf("%s is an IP literal", canonical.Host))
+		return canonical, nil
+	}
+
+	host = strings.ToLower(host)
+	for _, candidate := range r.Candidates(host, namespace) {
+		service, instance, err := parseK8sServiceName(candidate, r.clusterDomain)
+		if err != nil {
+			canonical.Trace = append(canonical.Trace, fmt.Sprintf("%s: skipped: %s", candidate, err))
+			continue
+		}
+		if exists != nil && !exists(service) {
+			canonical.Trace = append(canonical.Trace, fmt.Sprintf("%s: skipped: service %s does not exist", candidate, service))
+			continue
+		}
+		canonical.Trace = append(canonical.Trace, fmt.Sprintf("%s: selected", candidate))
+		canonical.Host = candidate
+		canonical.Service = service
+		canonical.Instance = instance
+		return canonical, nil
+	}
+
+	absolute := strings.TrimSuffix(host, ".")
+	service, instance, err := parseK8sServiceName(absolute, r.clusterDomain)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service: %s", err)
+	}
+	canonical.Trace = append(canonical.Trace, fmt.Sprintf("%s: selected as given, no candidate names an existing service", absolute))
+	canonical.Host = absolute
+	canonical.Service = service
+	canonical.Instance = instance
+	return canonical, nil
+}
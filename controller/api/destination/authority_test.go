@@ -0,0 +1,154 @@
+package destination
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/linkerd/linkerd2/controller/api/destination/watcher"
+)
+
+func TestAuthorityResolverCandidates(t *testing.T) {
+	resolver := NewAuthorityResolver("cluster.local", DefaultNdots, []string{"corp.example.com."})
+
+	testCases := []struct {
+		host      string
+		namespace string
+		expected  []string
+	}{
+		{
+			host:      "web",
+			namespace: "emojivoto",
+			expected: []string{
+				"web.emojivoto.svc.cluster.local",
+				"web.svc.cluster.local",
+				"web.cluster.local",
+				"web.corp.example.com",
+				"web",
+			},
+		},
+		{
+			host: "web.emojivoto",
+			expected: []string{
+				"web.emojivoto.svc.cluster.local",
+				"web.emojivoto.cluster.local",
+				"web.emojivoto.corp.example.com",
+				"web.emojivoto",
+			},
+		},
+		{
+			host:      "pod-0.web.emojivoto.svc.cluster.local",
+			namespace: "emojivoto",
+			expected: []string{
+				"pod-0.web.emojivoto.svc.cluster.local",
+				"pod-0.web.emojivoto.svc.cluster.local.emojivoto.svc.cluster.local",
+				"pod-0.web.emojivoto.svc.cluster.local.svc.cluster.local",
+				"pod-0.web.emojivoto.svc.cluster.local.cluster.local",
+				"pod-0.web.emojivoto.svc.cluster.local.corp.example.com",
+			},
+		},
+		{
+			host:      "web.emojivoto.svc.cluster.local.",
+			namespace: "emojivoto",
+			expected:  []string{"web.emojivoto.svc.cluster.local"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // pin
+		t.Run(tc.host, func(t *testing.T) {
+			candidates := resolver.Candidates(tc.host, tc.namespace)
+			if !reflect.DeepEqual(candidates, tc.expected) {
+				t.Fatalf("Expected candidates %v, got %v", tc.expected, candidates)
+			}
+		})
+	}
+}
+
+func TestAuthorityResolverCanonicalize(t *testing.T) {
+	resolver := NewAuthorityResolver("cluster.local", DefaultNdots, nil)
+	existing := map[watcher.ServiceID]struct{}{
+		{Namespace: "emojivoto", Name: "web"}:   {},
+		{Namespace: "emojivoto", Name: "emoji"}: {},
+		{Namespace: "other", Name: "web"}:       {},
+	}
+	exists := func(id watcher.ServiceID) bool {
+		_, ok := existing[id]
+		return ok
+	}
+
+	testCases := []struct {
+		authority string
+		namespace string
+		host      string
+		service   watcher.ServiceID
+		instance  string
+		err       bool
+	}{
+		{
+			authority: "web:8080",
+			namespace: "emojivoto",
+			host:      "web.emojivoto.svc.cluster.local",
+			service:   watcher.ServiceID{Namespace: "emojivoto", Name: "web"},
+		},
+		{
+			authority: "web.other:8080",
+			namespace: "emojivoto",
+			host:      "web.other.svc.cluster.local",
+			service:   watcher.ServiceID{Namespace: "other", Name: "web"},
+		},
+		{
+			authority: "Web.Emojivoto.svc.cluster.local:8080",
+			host:      "web.emojivoto.svc.cluster.local",
+			service:   watcher.ServiceID{Namespace: "emojivoto", Name: "web"},
+		},
+		{
+			authority: "missing.emojivoto.svc.cluster.local.",
+			host:      "missing.emojivoto.svc.cluster.local",
+			service:   watcher.ServiceID{Namespace: "emojivoto", Name: "missing"},
+		},
+		{
+			authority: "pod-0.emoji.emojivoto.svc.cluster.local",
+			host:      "pod-0.emoji.emojivoto.svc.cluster.local",
+			service:   watcher.ServiceID{Namespace: "emojivoto", Name: "emoji"},
+			instance:  "pod-0",
+		},
+		{
+			authority: "10.0.0.1:80",
+			host:      "10.0.0.1",
+		},
+		{
+			authority: "linkerd.io",
+			namespace: "emojivoto",
+			err:       true,
+		},
+		{
+			authority: "web:notaport",
+			err:       true,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // pin
+		t.Run(tc.authority, func(t *testing.T) {
+			canonical, err := resolver.Canonicalize(tc.authority, tc.namespace, exists)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("Expected an error, got %+v", canonical)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if canonical.Host != tc.host {
+				t.Fatalf("Expected host %s, got %s (trace: %v)", tc.host, canonical.Host, canonical.Trace)
+			}
+			if canonical.Service != tc.service {
+				t.Fatalf("Expected service %s, got %s", tc.service, canonical.Service)
+			}
+			if canonical.Instance != tc.instance {
+				t.Fatalf("Expected instance %s, got %s", tc.instance, canonical.Instance)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package destination
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/linkerd/linkerd2/pkg/k8s"
+	logging "github.com/sirupsen/logrus"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	corelisters "k8s.io/client-go/listers/core/v1"
+)
+
+const (
+	// balancerLabel and hashKeyLabel are the labels of the address sets
+	// through which the load balancing hints of a service are sent to the
+	// clients, as the destination API has no field for them.
+	balancerLabel = "balancer"
+	hashKeyLabel  = "hash_key"
+
+	consistentHashBalancer = "consistent_hash"
+	sourceIdentityHashKey  = "source-identity"
+	headerHashKeyPrefix    = "header:"
+)
+
+// validHeaderName matches the HTTP header names, as defined by RFC 7230.
+var validHeaderName = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+// parseConsistentHash parses the value of the ConsistentHashAnnotation into
+// the hash key sent to the clients: `header:<name>`, with the header name in
+// lowercase, or `source-identity`.
+func parseConsistentHash(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == sourceIdentityHashKey {
+		return value, nil
+	}
+	if strings.HasPrefix(value, headerHashKeyPrefix) {
+		header := strings.TrimSpace(strings.TrimPrefix(value, headerHashKeyPrefix))
+		if !validHeaderName.MatchString(header) {
+			return "", fmt.Errorf("invalid header name %q", header)
+		}
+		return headerHashKeyPrefix + strings.ToLower(header), nil
+	}
+	return "", fmt.Errorf("expected header:<name> or %s, got %q", sourceIdentityHashKey, value)
+}
+
+// consistentHashLabels returns a function returning the address set labels
+// holding the consistent hashing hints of the given service, read from its
+// ConsistentHashAnnotation every time so that the changes of the annotation
+// are sent with the next update of the endpoints. It returns no labels when
+// the service doesn't request consistent hashing.
+func consistentHashLabels(services corelisters.ServiceLister, namespace, name string, log *logging.Entry) func() map[string]string {
+	return func() map[string]string {
+		svc, err := services.Services(namespace).Get(name)
+		if err != nil {
+			if !kerrors.IsNotFound(err) {
+				log.Errorf("Failed to get service %s/%s: %s", namespace, name, err)
+			}
+			return nil
+		}
+		value, ok := svc.Annotations[k8s.ConsistentHashAnnotation]
+		if !ok {
+			return nil
+		}
+		key, err := parseConsistentHash(value)
+		if err != nil {
+			log.Warnf("Ignoring the %s annotation of service %s/%s: %s", k8s.ConsistentHashAnnotation, namespace, name, err)
+			return nil
+		}
+		return map[string]string{
+			balancerLabel: consistentHashBalancer,
+			hashKeyLabel:  key,
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package destination
+
+import (
+	"reflect"
+	"testing"
+
+	pb "github.com/linkerd/linkerd2-proxy-api/go/destination"
+	pkgk8s "github.com/linkerd/linkerd2/controller/k8s"
+	logging "github.com/sirupsen/logrus"
+)
+
+func TestParseConsistentHash(t *testing.T) {
+	for value, expected := range map[string]string{
+		"source-identity":   "source-identity",
+		"header:X-User-Id":  "header:x-user-id",
+		" header: x-tenant": "header:x-tenant",
+		"header:":           "",
+		"header:x user":     "",
+		"cookie:session":    "",
+		"":                  "",
+	} {
+		key, err := parseConsistentHash(value)
+		if expected == "" {
+			if err == nil {
+				t.Errorf("Expected %q to be rejected, got %q", value, key)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Unexpected error parsing %q: %s", value, err)
+		} else if key != expected {
+			t.Errorf("Expected %q to be parsed as %q, got %q", value, expected, key)
+		}
+	}
+}
+
+func TestEndpointTranslatorConsistentHashLabels(t *testing.T) {
+	k8sAPI, err := pkgk8s.NewFakeAPI(`
+apiVersion: v1
+kind: Service
+metadata:
+  name: sticky
+  namespace: ns
+  annotations:
+    config.alpha.linkerd.io/consistent-hash: header:X-User-Id
+`, `
+apiVersion: v1
+kind: Service
+metadata:
+  name: invalid
+  namespace: ns
+  annotations:
+    config.alpha.linkerd.io/consistent-hash: cookie:session
+`)
+	if err != nil {
+		t.Fatalf("NewFakeAPI returned an error: %s", err)
+	}
+	k8sAPI.Sync(nil)
+	log := logging.WithField("test", t.Name())
+
+	for _, tc := range []struct {
+		service  string
+		expected map[string]string
+	}{
+		{
+			service: "sticky",
+			expected: map[string]string{
+				"service":     "service-name",
+				"namespace":   "service-ns",
+				balancerLabel: consistentHashBalancer,
+				hashKeyLabel:  "header:x-user-id",
+			},
+		},
+		{
+			service:  "invalid",
+			expected: map[string]string{"service": "service-name", "namespace": "service-ns"},
+		},
+		{
+			service:  "missing",
+			expected: map[string]string{"service": "service-name", "namespace": "service-ns"},
+		},
+	} {
+		tc := tc // pin
+		t.Run(tc.service, func(t *testing.T) {
+			mockGetServer, translator := makeEndpointTranslator(t)
+			translator.hashLabels = consistentHashLabels(k8sAPI.Svc().Lister(), "ns", tc.service, log)
+
+			set := mkAddressSetForPods(normalPod)
+			translator.Add(set)
+
+			if len(mockGetServer.updatesReceived) != 1 {
+				t.Fatalf("Expected 1 update, got %d", len(mockGetServer.updatesReceived))
+			}
+			add := mockGetServer.updatesReceived[0].GetUpdate().(*pb.Update_Add)
+			if labels := add.Add.GetMetricLabels(); !reflect.DeepEqual(labels, tc.expected) {
+				t.Errorf("Expected labels %v, got %v", tc.expected, labels)
+			}
+			if _, ok := set.Labels[balancerLabel]; ok {
+				t.Errorf("Expected the labels of the address set to be left unchanged, got %v", set.Labels)
+			}
+		})
+	}
+}
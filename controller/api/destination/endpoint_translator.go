@@ -2,6 +2,7 @@ package destination
 
 import (
 	"fmt"
+	"math"
 	"strconv"
 	"strings"
 
@@ -30,11 +31,16 @@ type endpointTranslator struct {
 	enableH2Upgrade     bool
 	nodeTopologyLabels  map[string]string
 	defaultOpaquePorts  map[uint32]struct{}
+	nodes               coreinformers.NodeInformer
 
 	availableEndpoints watcher.AddressSet
 	filteredSnapshot   watcher.AddressSet
 	stream             pb.Destination_GetServer
 	log                *logging.Entry
+
+	// hashLabels, if set, returns the labels holding the consistent hashing
+	// hints of the service, added to the labels of each address set sent.
+	hashLabels func() map[string]string
 }
 
 func newEndpointTranslator(
@@ -67,10 +73,12 @@ func newEndpointTranslator(
 		enableH2Upgrade,
 		nodeTopologyLabels,
 		defaultOpaquePorts,
+		nodes,
 		availableEndpoints,
 		filteredSnapshot,
 		stream,
 		log,
+		nil,
 	}
 }
 
@@ -232,6 +240,11 @@ func (et *endpointTranslator) sendClientAdd(set watcher.AddressSet) {
 			}
 
 			wa, err = toWeightedAddr(address, opaquePorts, skippedInboundPorts, et.enableH2Upgrade, et.identityTrustDomain, et.controllerNS, et.log)
+			if err == nil {
+				for k, v := range et.endpointMetadata(address) {
+					wa.MetricLabels[k] = v
+				}
+			}
 		} else {
 			var authOverride *pb.AuthorityOverride
 			if address.AuthorityOverride != "" {
@@ -245,7 +258,7 @@ func (et *endpointTranslator) sendClientAdd(set watcher.AddressSet) {
 			addr, err = toAddr(address)
 			wa = &pb.WeightedAddr{
 				Addr:              addr,
-				Weight:            defaultWeight,
+				Weight:            gatewayWeight(address.Weight),
 				AuthorityOverride: authOverride,
 			}
 
@@ -277,7 +290,7 @@ func (et *endpointTranslator) sendClientAdd(set watcher.AddressSet) {
 	add := &pb.Update{Update: &pb.Update_Add{
 		Add: &pb.WeightedAddrSet{
 			Addrs:        addrs,
-			MetricLabels: set.Labels,
+			MetricLabels: et.setLabels(set),
 		},
 	}}
 
@@ -287,6 +300,26 @@ func (et *endpointTranslator) sendClientAdd(set watcher.AddressSet) {
 	}
 }
 
+// setLabels returns the labels of the given address set, along with the
+// consistent hashing hints of the service if it requests them.
+func (et *endpointTranslator) setLabels(set watcher.AddressSet) map[string]string {
+	if et.hashLabels == nil {
+		return set.Labels
+	}
+	hints := et.hashLabels()
+	if len(hints) == 0 {
+		return set.Labels
+	}
+	labels := make(map[string]string, len(set.Labels)+len(hints))
+	for k, v := range set.Labels {
+		labels[k] = v
+	}
+	for k, v := range hints {
+		labels[k] = v
+	}
+	return labels
+}
+
 func (et *endpointTranslator) sendClientRemove(set watcher.AddressSet) {
 	addrs := []*net.TcpAddress{}
 	for _, address := range set.Addresses {
@@ -383,6 +416,78 @@ func toWeightedAddr(address watcher.Address, opaquePorts, skippedInboundPorts ma
 	}, nil
 }
 
+// endpointMetadata returns the node, zone and container image digest of the
+// pod backing the address, for the ones that are known.
+func (et *endpointTranslator) endpointMetadata(address watcher.Address) map[string]string {
+	metadata := make(map[string]string)
+	nodeName := address.Pod.Spec.NodeName
+	if nodeName != "" {
+		metadata["node"] = nodeName
+	}
+
+	zone := address.TopologyLabels[corev1.LabelZoneFailureDomainStable]
+	if zone == "" && nodeName != "" {
+		node, err := et.nodes.Lister().Get(nodeName)
+		if err != nil {
+			et.log.Debugf("Failed to get node %s: %s", nodeName, err)
+		} else {
+			zone = node.Labels[corev1.LabelZoneFailureDomainStable]
+		}
+	}
+	if zone != "" {
+		metadata["zone"] = zone
+	}
+
+	if digest := getPodImageDigest(address.Pod, address.Port); digest != "" {
+		metadata["image_digest"] = digest
+	}
+	return metadata
+}
+
+// getPodImageDigest returns the image digest of the container exposing the
+// given port, or of the first container other than the proxy if none does.
+// It returns an empty string if the digest isn't known yet.
+func getPodImageDigest(pod *corev1.Pod, port uint32) string {
+	container := ""
+	for _, c := range pod.Spec.Containers {
+		if c.Name == k8s.ProxyContainerName {
+			continue
+		}
+		if container == "" {
+			container = c.Name
+		}
+		for _, p := range c.Ports {
+			if uint32(p.ContainerPort) == port {
+				container = c.Name
+			}
+		}
+	}
+
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != container {
+			continue
+		}
+		// The image ID has the form <runtime>://<repository>@<digest> once
+		// the image has been pulled
+		if i := strings.LastIndex(status.ImageID, "@"); i >= 0 {
+			return status.ImageID[i+1:]
+		}
+	}
+	return ""
+}
+
+// gatewayWeight scales the default weight by the relative weight assigned to
+// a remote gateway address, if any.
+func gatewayWeight(weight uint32) uint32 {
+	if weight == 0 {
+		return defaultWeight
+	}
+	if weight > math.MaxUint32/defaultWeight {
+		return math.MaxUint32
+	}
+	return defaultWeight * weight
+}
+
 func getK8sNodeTopology(nodes coreinformers.NodeInformer, srcNode string) (map[string]string, error) {
 	nodeTopology := make(map[string]string)
 	node, err := nodes.Lister().Get(srcNode)
@@ -168,6 +168,38 @@ func TestEndpointTranslatorForRemoteGateways(t *testing.T) {
 		}
 	})
 
+	t.Run("Sends endpoint metadata with added addresses", func(t *testing.T) {
+		mockGetServer, translator := makeEndpointTranslator(t)
+
+		pod := normalPod
+		pod.Pod = normalPod.Pod.DeepCopy()
+		pod.Pod.Spec.NodeName = "test-123"
+		pod.Pod.Spec.Containers = []corev1.Container{
+			{Name: k8s.ProxyContainerName},
+			{Name: "sidecar"},
+			{Name: "app", Ports: []corev1.ContainerPort{{ContainerPort: 1}}},
+		}
+		pod.Pod.Status.ContainerStatuses = []corev1.ContainerStatus{
+			{Name: k8s.ProxyContainerName, ImageID: "docker-pullable://cr.l5d.io/linkerd/proxy@sha256:proxy"},
+			{Name: "sidecar", ImageID: "docker-pullable://sidecar@sha256:sidecar"},
+			{Name: "app", ImageID: "docker-pullable://app@sha256:app"},
+		}
+
+		translator.Add(mkAddressSetForPods(pod))
+
+		labels := mockGetServer.updatesReceived[0].GetAdd().Addrs[0].MetricLabels
+		expectedMetadata := map[string]string{
+			"node":         "test-123",
+			"zone":         "west-1a",
+			"image_digest": "sha256:app",
+		}
+		for k, v := range expectedMetadata {
+			if labels[k] != v {
+				t.Fatalf("Expected metric label %s to be [%s] but was [%s]", k, v, labels[k])
+			}
+		}
+	})
+
 	t.Run("Sends TlsIdentity when enabled", func(t *testing.T) {
 		expectedTLSIdentity := &pb.TlsIdentity_DnsLikeIdentity{
 			Name: "some-identity",
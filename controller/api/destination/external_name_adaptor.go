@@ -0,0 +1,45 @@
+package destination
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/linkerd/linkerd2/controller/api/destination/watcher"
+	sp "github.com/linkerd/linkerd2/controller/gen/apis/serviceprofile/v1alpha2"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// externalNameAdaptor holds an underlying ProfileUpdateListener and updates
+// that listener with service profiles whose destination is overridden with
+// the external authority of an ExternalName service. This lets clients keep
+// addressing external dependencies through their in-cluster names while the
+// proxy resolves the external authority through DNS.
+type externalNameAdaptor struct {
+	listener  watcher.ProfileUpdateListener
+	authority string
+}
+
+func newExternalNameAdaptor(listener watcher.ProfileUpdateListener, externalName string, port watcher.Port) *externalNameAdaptor {
+	return &externalNameAdaptor{
+		listener: listener,
+		// The proxy expects authorities to be absolute and have the host part
+		// end with a trailing dot.
+		authority: fmt.Sprintf("%s.:%d", strings.TrimSuffix(externalName, "."), port),
+	}
+}
+
+func (ena *externalNameAdaptor) Update(profile *sp.ServiceProfile) {
+	merged := sp.ServiceProfile{}
+	if profile != nil {
+		merged = *profile
+	}
+	// Any dst overrides in the profile refer to in-cluster authorities that
+	// an ExternalName service doesn't have, so they are always replaced.
+	merged.Spec.DstOverrides = []*sp.WeightedDst{
+		{
+			Authority: ena.authority,
+			Weight:    resource.MustParse("1"),
+		},
+	}
+	ena.listener.Update(&merged)
+}
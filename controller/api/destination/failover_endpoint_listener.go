@@ -0,0 +1,188 @@
+package destination
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/linkerd/linkerd2/controller/api/destination/watcher"
+	labels "github.com/linkerd/linkerd2/pkg/k8s"
+)
+
+// localCluster designates the service itself in the failover priorities
+// annotation; the other entries are the names of linked clusters.
+const localCluster = "local"
+
+// failoverEndpointListener takes the endpoints of a service and of its
+// mirrors in an ordered list of clusters, and only propagates the endpoints
+// of the highest-priority one which has any to the underlying listener. When
+// that one loses its last endpoint, the endpoints of the next one are
+// propagated instead.
+type failoverEndpointListener struct {
+	underlying watcher.EndpointUpdateListener
+	candidates []*failoverCandidateListener
+	// active is the index of the candidate whose endpoints are propagated, or
+	// -1 if none has any.
+	active int
+	mutex  sync.Mutex
+}
+
+type failoverCandidateListener struct {
+	parent *failoverEndpointListener
+	id     watcher.ServiceID
+	set    watcher.AddressSet
+	exists bool
+}
+
+// failoverCandidates returns the services the endpoints of the given service
+// are taken from, in priority order, according to its failover priorities
+// annotation. It returns nil if the service has no such annotation.
+func failoverCandidates(id watcher.ServiceID, priorities string) []watcher.ServiceID {
+	candidates := []watcher.ServiceID{}
+	seen := map[watcher.ServiceID]struct{}{}
+	for _, cluster := range strings.Split(priorities, ",") {
+		cluster = strings.TrimSpace(cluster)
+		if cluster == "" {
+			continue
+		}
+		candidate := id
+		if cluster != localCluster {
+			candidate.Name = fmt.Sprintf("%s-%s", id.Name, cluster)
+		}
+		if _, ok := seen[candidate]; ok {
+			continue
+		}
+		seen[candidate] = struct{}{}
+		candidates = append(candidates, candidate)
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+	return candidates
+}
+
+// newFailoverEndpointListener returns one listener per candidate service,
+// in priority order, which must each be subscribed to the endpoints of
+// their service.
+func newFailoverEndpointListener(listener watcher.EndpointUpdateListener, candidates []watcher.ServiceID) []*failoverCandidateListener {
+	failover := &failoverEndpointListener{
+		underlying: listener,
+		active:     -1,
+	}
+	for _, id := range candidates {
+		failover.candidates = append(failover.candidates, &failoverCandidateListener{
+			parent: failover,
+			id:     id,
+			set:    newEmptyAddressSet(),
+		})
+	}
+	return failover.candidates
+}
+
+func (c *failoverCandidateListener) Add(set watcher.AddressSet) {
+	c.parent.mutex.Lock()
+	defer c.parent.mutex.Unlock()
+
+	c.exists = true
+	for id, address := range set.Addresses {
+		c.set.Addresses[id] = address
+	}
+	c.set.Labels = set.Labels
+	c.set.TopologicalPref = set.TopologicalPref
+
+	if c.parent.isActive(c) {
+		c.parent.underlying.Add(set)
+		return
+	}
+	c.parent.failover()
+}
+
+func (c *failoverCandidateListener) Remove(set watcher.AddressSet) {
+	c.parent.mutex.Lock()
+	defer c.parent.mutex.Unlock()
+
+	for id := range set.Addresses {
+		delete(c.set.Addresses, id)
+	}
+
+	if c.parent.isActive(c) {
+		c.parent.underlying.Remove(set)
+		if len(c.set.Addresses) > 0 {
+			return
+		}
+	}
+	c.parent.failover()
+}
+
+func (c *failoverCandidateListener) NoEndpoints(exists bool) {
+	c.parent.mutex.Lock()
+	defer c.parent.mutex.Unlock()
+
+	if c.parent.isActive(c) && len(c.set.Addresses) > 0 {
+		c.parent.underlying.Remove(copyAddressSet(c.set))
+	}
+	c.exists = exists
+	c.set.Addresses = map[watcher.ID]watcher.Address{}
+	c.parent.failover()
+}
+
+func (f *failoverEndpointListener) isActive(c *failoverCandidateListener) bool {
+	return f.active >= 0 && f.candidates[f.active] == c
+}
+
+// failover propagates the endpoints of the highest-priority candidate which
+// has any, if it isn't the active one already, or the absence of endpoints if
+// none has any.
+func (f *failoverEndpointListener) failover() {
+	next := -1
+	for i, c := range f.candidates {
+		if len(c.set.Addresses) > 0 {
+			next = i
+			break
+		}
+	}
+
+	if next >= 0 && next == f.active {
+		return
+	}
+
+	if f.active >= 0 {
+		previous := f.candidates[f.active]
+		if len(previous.set.Addresses) > 0 {
+			f.underlying.Remove(copyAddressSet(previous.set))
+		}
+	}
+	f.active = next
+
+	if next < 0 {
+		exists := false
+		for _, c := range f.candidates {
+			exists = exists || c.exists
+		}
+		f.underlying.NoEndpoints(exists)
+		return
+	}
+	f.underlying.Add(copyAddressSet(f.candidates[next].set))
+}
+
+func copyAddressSet(set watcher.AddressSet) watcher.AddressSet {
+	addresses := make(map[watcher.ID]watcher.Address, len(set.Addresses))
+	for id, address := range set.Addresses {
+		addresses[id] = address
+	}
+	return watcher.AddressSet{
+		Addresses:       addresses,
+		Labels:          set.Labels,
+		TopologicalPref: set.TopologicalPref,
+	}
+}
+
+// serviceFailoverPriorities returns the failover priorities annotation of
+// the service, if it exists.
+func (s *server) serviceFailoverPriorities(id watcher.ServiceID) string {
+	svc, err := s.k8sAPI.Svc().Lister().Services(id.Namespace).Get(id.Name)
+	if err != nil {
+		return ""
+	}
+	return svc.Annotations[labels.FailoverPrioritiesAnnotation]
+}
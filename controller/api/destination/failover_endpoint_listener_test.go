@@ -0,0 +1,131 @@
+package destination
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/linkerd/linkerd2/controller/api/destination/watcher"
+)
+
+type recordingEndpointListener struct {
+	updates []string
+}
+
+func (r *recordingEndpointListener) Add(set watcher.AddressSet) {
+	r.updates = append(r.updates, "add "+addressSetString(set))
+}
+
+func (r *recordingEndpointListener) Remove(set watcher.AddressSet) {
+	r.updates = append(r.updates, "remove "+addressSetString(set))
+}
+
+func (r *recordingEndpointListener) NoEndpoints(exists bool) {
+	r.updates = append(r.updates, fmt.Sprintf("no endpoints %t", exists))
+}
+
+func addressSetString(set watcher.AddressSet) string {
+	ips := []string{}
+	for _, address := range set.Addresses {
+		ips = append(ips, address.IP)
+	}
+	sort.Strings(ips)
+	return strings.Join(ips, ",")
+}
+
+func addressSet(ips ...string) watcher.AddressSet {
+	set := newEmptyAddressSet()
+	for _, ip := range ips {
+		set.Addresses[watcher.ID{Namespace: "ns", Name: ip}] = watcher.Address{IP: ip, Port: 8080}
+	}
+	return set
+}
+
+func TestFailoverCandidates(t *testing.T) {
+	id := watcher.ServiceID{Namespace: "ns", Name: "web"}
+
+	candidates := failoverCandidates(id, "local, west,east,west")
+	expected := []watcher.ServiceID{
+		{Namespace: "ns", Name: "web"},
+		{Namespace: "ns", Name: "web-west"},
+		{Namespace: "ns", Name: "web-east"},
+	}
+	if !reflect.DeepEqual(candidates, expected) {
+		t.Fatalf("Expected %v, got %v", expected, candidates)
+	}
+
+	if candidates := failoverCandidates(id, ""); candidates != nil {
+		t.Fatalf("Expected no candidates, got %v", candidates)
+	}
+}
+
+func TestFailoverEndpointListener(t *testing.T) {
+	candidates := []watcher.ServiceID{
+		{Namespace: "ns", Name: "web"},
+		{Namespace: "ns", Name: "web-west"},
+	}
+
+	t.Run("Serves the endpoints of the highest-priority cluster", func(t *testing.T) {
+		underlying := &recordingEndpointListener{}
+		listeners := newFailoverEndpointListener(underlying, candidates)
+		local, west := listeners[0], listeners[1]
+
+		west.Add(addressSet("10.0.0.2"))
+		local.Add(addressSet("10.0.0.1"))
+		local.Add(addressSet("10.0.0.3"))
+		west.Add(addressSet("10.0.0.4"))
+
+		expected := []string{
+			"add 10.0.0.2",
+			"remove 10.0.0.2",
+			"add 10.0.0.1",
+			"add 10.0.0.3",
+		}
+		if !reflect.DeepEqual(underlying.updates, expected) {
+			t.Fatalf("Expected %v, got %v", expected, underlying.updates)
+		}
+	})
+
+	t.Run("Fails over when the local endpoints are gone", func(t *testing.T) {
+		underlying := &recordingEndpointListener{}
+		listeners := newFailoverEndpointListener(underlying, candidates)
+		local, west := listeners[0], listeners[1]
+
+		local.Add(addressSet("10.0.0.1", "10.0.0.3"))
+		west.Add(addressSet("10.0.0.2"))
+		local.Remove(addressSet("10.0.0.1"))
+		local.NoEndpoints(true)
+		west.NoEndpoints(true)
+
+		expected := []string{
+			"add 10.0.0.1,10.0.0.3",
+			"remove 10.0.0.1",
+			"remove 10.0.0.3",
+			"add 10.0.0.2",
+			"remove 10.0.0.2",
+			"no endpoints true",
+		}
+		if !reflect.DeepEqual(underlying.updates, expected) {
+			t.Fatalf("Expected %v, got %v", expected, underlying.updates)
+		}
+	})
+
+	t.Run("Reports whether any of the services exists", func(t *testing.T) {
+		underlying := &recordingEndpointListener{}
+		listeners := newFailoverEndpointListener(underlying, candidates)
+		local, west := listeners[0], listeners[1]
+
+		local.NoEndpoints(false)
+		west.NoEndpoints(true)
+
+		expected := []string{
+			"no endpoints false",
+			"no endpoints true",
+		}
+		if !reflect.DeepEqual(underlying.updates, expected) {
+			t.Fatalf("Expected %v, got %v", expected, underlying.updates)
+		}
+	})
+}
@@ -0,0 +1,382 @@
+package destination
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linkerd/linkerd2/controller/k8s"
+	pkgK8s "github.com/linkerd/linkerd2/pkg/k8s"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/common/expfmt"
+	logging "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// ProtocolDetectionPath is the path of the admin server endpoint serving the
+// aggregated protocol detection timeouts.
+const ProtocolDetectionPath = "/protocol-detection"
+
+const (
+	// maxConcurrentScrapes bounds the number of proxies scraped at once.
+	maxConcurrentScrapes = 10
+	scrapeTimeout        = 5 * time.Second
+)
+
+// acceptErrorsMetrics are the proxy metrics counting the connections that
+// failed before a protocol could be detected, labeled by error and target
+// address, keyed by their direction.
+var acceptErrorsMetrics = map[string]string{
+	"inbound_tcp_accept_errors":  "inbound",
+	"outbound_tcp_accept_errors": "outbound",
+}
+
+var protocolDetectionTimeouts = promauto.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "protocol_detection_timeouts",
+		Help: "Number of connections for which the meshed proxies timed out detecting the protocol, by target service and port.",
+	},
+	[]string{"namespace", "service", "port", "direction"},
+)
+
+type (
+	// DetectionTimeouts is the number of protocol detection timeouts
+	// reported by the proxies for a target. Namespace, Service and Port are
+	// empty when the target address doesn't belong to a known service, in
+	// which case Addr is set.
+	DetectionTimeouts struct {
+		Namespace string  `json:"namespace,omitempty"`
+		Service   string  `json:"service,omitempty"`
+		Port      uint32  `json:"port,omitempty"`
+		Addr      string  `json:"addr,omitempty"`
+		Direction string  `json:"direction"`
+		Timeouts  float64 `json:"timeouts"`
+		Proxies   int     `json:"proxies"`
+	}
+
+	detectionTarget struct {
+		direction string
+		addr      string
+	}
+
+	servicePort struct {
+		namespace string
+		name      string
+		port      uint32
+	}
+
+	// ProtocolDetectionAggregator periodically scrapes the meshed proxies
+	// and aggregates the protocol detection timeouts they report per target
+	// service and port, so that the cost of missing opaque port annotations
+	// can be seen cluster-wide. The counts are the current totals of the
+	// running proxies: the counts of deleted pods are dropped.
+	ProtocolDetectionAggregator struct {
+		k8sAPI *k8s.API
+		client *http.Client
+		log    *logging.Entry
+
+		sync.RWMutex
+		// counts holds the latest counts scraped from each pod, keyed by the
+		// pod's namespace/name.
+		counts  map[string]map[detectionTarget]float64
+		results []DetectionTimeouts
+	}
+)
+
+// NewProtocolDetectionAggregator returns an aggregator scraping the proxies
+// of the pods known to the API.
+func NewProtocolDetectionAggregator(k8sAPI *k8s.API) *ProtocolDetectionAggregator {
+	return &ProtocolDetectionAggregator{
+		k8sAPI: k8sAPI,
+		client: &http.Client{Timeout: scrapeTimeout},
+		log:    logging.WithField("component", "protocol-detection-aggregator"),
+		counts: make(map[string]map[detectionTarget]float64),
+	}
+}
+
+// Run scrapes the proxies every interval, until the context is canceled.
+func (a *ProtocolDetectionAggregator) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		a.Scrape(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// Scrape fetches the metrics of all the meshed proxies, and updates the
+// aggregated results.
+func (a *ProtocolDetectionAggregator) Scrape(ctx context.Context) {
+	pods, err := a.k8sAPI.Pod().Lister().List(labels.Everything())
+	if err != nil {
+		a.log.Errorf("Failed to list pods: %s", err)
+		return
+	}
+
+	type scrape struct {
+		key    string
+		counts map[detectionTarget]float64
+	}
+	results := make(chan scrape)
+	sem := make(chan struct{}, maxConcurrentScrapes)
+	var wg sync.WaitGroup
+	for _, pod := range pods {
+		url, ok := proxyMetricsURL(pod)
+		if !ok {
+			continue
+		}
+		key := fmt.Sprintf("%s/%s", pod.Namespace, pod.Name)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			counts, err := a.scrapeProxy(ctx, url)
+			if err != nil {
+				a.log.Debugf("Failed to scrape the proxy of %s: %s", key, err)
+			}
+			results <- scrape{key, counts}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	a.RLock()
+	previous := a.counts
+	a.RUnlock()
+
+	counts := make(map[string]map[detectionTarget]float64)
+	for result := range results {
+		if result.counts == nil {
+			// keep the previous counts of the proxies that couldn't be
+			// reached this time
+			if c, ok := previous[result.key]; ok {
+				counts[result.key] = c
+			}
+			continue
+		}
+		counts[result.key] = result.counts
+	}
+
+	aggregated := a.aggregate(counts)
+
+	a.Lock()
+	a.counts = counts
+	a.results = aggregated
+	a.Unlock()
+
+	protocolDetectionTimeouts.Reset()
+	for _, r := range aggregated {
+		if r.Service == "" {
+			continue
+		}
+		protocolDetectionTimeouts.
+			WithLabelValues(r.Namespace, r.Service, strconv.FormatUint(uint64(r.Port), 10), r.Direction).
+			Set(r.Timeouts)
+	}
+}
+
+// Results returns the latest aggregated timeouts, the highest first.
+func (a *ProtocolDetectionAggregator) Results() []DetectionTimeouts {
+	a.RLock()
+	defer a.RUnlock()
+	return append([]DetectionTimeouts{}, a.results...)
+}
+
+// ServeHTTP serves the latest aggregated timeouts as JSON.
+func (a *ProtocolDetectionAggregator) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(a.Results()); err != nil {
+		a.log.Errorf("Failed to write protocol detection timeouts: %s", err)
+	}
+}
+
+func (a *ProtocolDetectionAggregator) scrapeProxy(ctx context.Context, url string) (map[detectionTarget]float64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	rsp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", rsp.StatusCode)
+	}
+
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(rsp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[detectionTarget]float64)
+	for name, family := range families {
+		direction, ok := acceptErrorsMetrics[strings.TrimSuffix(name, "_total")]
+		if !ok {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			var errorLabel, addr string
+			for _, label := range metric.GetLabel() {
+				switch label.GetName() {
+				case "error":
+					errorLabel = label.GetValue()
+				case "target_addr":
+					addr = label.GetValue()
+				}
+			}
+			if !strings.HasSuffix(errorLabel, "detect_timeout") || addr == "" {
+				continue
+			}
+			counts[detectionTarget{direction, addr}] += metric.GetCounter().GetValue()
+		}
+	}
+	return counts, nil
+}
+
+// aggregate sums the counts of all the proxies per target service port.
+func (a *ProtocolDetectionAggregator) aggregate(counts map[string]map[detectionTarget]float64) []DetectionTimeouts {
+	index := a.servicePortIndex()
+
+	type key struct {
+		servicePort
+		addr      string
+		direction string
+	}
+	totals := make(map[key]*DetectionTimeouts)
+	for _, proxyCounts := range counts {
+		for target, count := range proxyCounts {
+			if count == 0 {
+				continue
+			}
+			k := key{direction: target.direction}
+			if sp, ok := index[target.addr]; ok {
+				k.servicePort = sp
+			} else {
+				k.addr = target.addr
+			}
+			total, ok := totals[k]
+			if !ok {
+				total = &DetectionTimeouts{
+					Namespace: k.namespace,
+					Service:   k.name,
+					Port:      k.port,
+					Addr:      k.addr,
+					Direction: k.direction,
+				}
+				totals[k] = total
+			}
+			total.Timeouts += count
+			total.Proxies++
+		}
+	}
+
+	results := make([]DetectionTimeouts, 0, len(totals))
+	for _, total := range totals {
+		results = append(results, *total)
+	}
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Timeouts != results[j].Timeouts {
+			return results[i].Timeouts > results[j].Timeouts
+		}
+		if results[i].Namespace != results[j].Namespace {
+			return results[i].Namespace < results[j].Namespace
+		}
+		if results[i].Service != results[j].Service {
+			return results[i].Service < results[j].Service
+		}
+		if results[i].Port != results[j].Port {
+			return results[i].Port < results[j].Port
+		}
+		if results[i].Addr != results[j].Addr {
+			return results[i].Addr < results[j].Addr
+		}
+		return results[i].Direction < results[j].Direction
+	})
+	return results
+}
+
+// servicePortIndex maps the addresses proxies connect to, i.e. the cluster
+// IPs and the endpoints of the services, to the service ports they belong to.
+func (a *ProtocolDetectionAggregator) servicePortIndex() map[string]servicePort {
+	index := make(map[string]servicePort)
+
+	services, err := a.k8sAPI.Svc().Lister().List(labels.Everything())
+	if err != nil {
+		a.log.Errorf("Failed to list services: %s", err)
+		return index
+	}
+	portsByName := make(map[string]map[string]uint32)
+	for _, svc := range services {
+		names := make(map[string]uint32)
+		for _, port := range svc.Spec.Ports {
+			names[port.Name] = uint32(port.Port)
+			if svc.Spec.ClusterIP != "" && svc.Spec.ClusterIP != corev1.ClusterIPNone {
+				addr := net.JoinHostPort(svc.Spec.ClusterIP, strconv.Itoa(int(port.Port)))
+				index[addr] = servicePort{svc.Namespace, svc.Name, uint32(port.Port)}
+			}
+		}
+		portsByName[fmt.Sprintf("%s/%s", svc.Namespace, svc.Name)] = names
+	}
+
+	endpoints, err := a.k8sAPI.Endpoint().Lister().List(labels.Everything())
+	if err != nil {
+		a.log.Errorf("Failed to list endpoints: %s", err)
+		return index
+	}
+	for _, ep := range endpoints {
+		names, ok := portsByName[fmt.Sprintf("%s/%s", ep.Namespace, ep.Name)]
+		if !ok {
+			continue
+		}
+		for _, subset := range ep.Subsets {
+			for _, port := range subset.Ports {
+				svcPort, ok := names[port.Name]
+				if !ok {
+					continue
+				}
+				for _, address := range subset.Addresses {
+					addr := net.JoinHostPort(address.IP, strconv.Itoa(int(port.Port)))
+					index[addr] = servicePort{ep.Namespace, ep.Name, svcPort}
+				}
+			}
+		}
+	}
+	return index
+}
+
+// proxyMetricsURL returns the URL of the metrics of the pod's proxy, if it's
+// meshed and running.
+func proxyMetricsURL(pod *corev1.Pod) (string, bool) {
+	if pod.Status.Phase != corev1.PodRunning || pod.Status.PodIP == "" {
+		return "", false
+	}
+	for _, container := range pod.Spec.Containers {
+		if container.Name != pkgK8s.ProxyContainerName {
+			continue
+		}
+		for _, port := range container.Ports {
+			if port.Name == pkgK8s.ProxyAdminPortName {
+				return fmt.Sprintf("http://%s/metrics", net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(int(port.ContainerPort)))), true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,103 @@
+package destination
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/linkerd/linkerd2/controller/k8s"
+)
+
+const detectionService = `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: emojivoto
+spec:
+  type: ClusterIP
+  clusterIP: 10.0.0.1
+  ports:
+  - name: http
+    port: 80
+    targetPort: 8080`
+
+const detectionEndpoints = `
+apiVersion: v1
+kind: Endpoints
+metadata:
+  name: web
+  namespace: emojivoto
+subsets:
+- addresses:
+  - ip: 10.1.1.1
+  ports:
+  - name: http
+    port: 8080
+    protocol: TCP`
+
+const detectionMetrics = `# HELP inbound_tcp_accept_errors_total Errors encountered before a protocol could be detected.
+# TYPE inbound_tcp_accept_errors_total counter
+inbound_tcp_accept_errors_total{error="tls_detect_timeout",target_addr="10.1.1.1:8080"} 2
+inbound_tcp_accept_errors_total{error="io",target_addr="10.1.1.1:8080"} 5
+# HELP outbound_tcp_accept_errors_total Errors encountered before a protocol could be detected.
+# TYPE outbound_tcp_accept_errors_total counter
+outbound_tcp_accept_errors_total{error="tls_detect_timeout",target_addr="10.0.0.1:80"} 3
+outbound_tcp_accept_errors_total{error="tls_detect_timeout",target_addr="10.9.9.9:443"} 1
+`
+
+func TestProtocolDetectionAggregator(t *testing.T) {
+	k8sAPI, err := k8s.NewFakeAPI(detectionService, detectionEndpoints)
+	if err != nil {
+		t.Fatalf("NewFakeAPI returned an error: %s", err)
+	}
+	k8sAPI.Sync(nil)
+	aggregator := NewProtocolDetectionAggregator(k8sAPI)
+
+	t.Run("Counts the detection timeouts reported by a proxy", func(t *testing.T) {
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, detectionMetrics)
+		}))
+		defer proxy.Close()
+
+		counts, err := aggregator.scrapeProxy(context.Background(), proxy.URL)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		expected := map[detectionTarget]float64{
+			{"inbound", "10.1.1.1:8080"}: 2,
+			{"outbound", "10.0.0.1:80"}:  3,
+			{"outbound", "10.9.9.9:443"}: 1,
+		}
+		if !reflect.DeepEqual(counts, expected) {
+			t.Fatalf("Expected %v, got %v", expected, counts)
+		}
+	})
+
+	t.Run("Aggregates the counts per service port", func(t *testing.T) {
+		counts := map[string]map[detectionTarget]float64{
+			"emojivoto/vote-bot": {
+				{"outbound", "10.0.0.1:80"}:  3,
+				{"outbound", "10.9.9.9:443"}: 1,
+			},
+			"emojivoto/emoji": {
+				{"outbound", "10.0.0.1:80"}: 4,
+			},
+			"emojivoto/web": {
+				{"inbound", "10.1.1.1:8080"}: 2,
+			},
+		}
+		expected := []DetectionTimeouts{
+			{Namespace: "emojivoto", Service: "web", Port: 80, Direction: "outbound", Timeouts: 7, Proxies: 2},
+			{Namespace: "emojivoto", Service: "web", Port: 80, Direction: "inbound", Timeouts: 2, Proxies: 1},
+			{Addr: "10.9.9.9:443", Direction: "outbound", Timeouts: 1, Proxies: 1},
+		}
+		results := aggregator.aggregate(counts)
+		if !reflect.DeepEqual(results, expected) {
+			t.Fatalf("Expected %+v, got %+v", expected, results)
+		}
+	})
+}
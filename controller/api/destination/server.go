@@ -39,6 +39,7 @@ type (
 		identityTrustDomain string
 		clusterDomain       string
 		defaultOpaquePorts  map[uint32]struct{}
+		resolver            *AuthorityResolver
 
 		k8sAPI   *k8s.API
 		log      *logging.Entry
@@ -67,6 +68,7 @@ func NewServer(
 	k8sAPI *k8s.API,
 	clusterDomain string,
 	defaultOpaquePorts map[uint32]struct{},
+	resolver *AuthorityResolver,
 	shutdown <-chan struct{},
 ) (*grpc.Server, error) {
 	log := logging.WithFields(logging.Fields{
@@ -97,6 +99,7 @@ func NewServer(
 		identityTrustDomain,
 		clusterDomain,
 		defaultOpaquePorts,
+		resolver,
 		k8sAPI,
 		log,
 		shutdown,
@@ -152,16 +155,37 @@ func (s *server) Get(dest *pb.GetDestination, stream pb.Destination_GetServer) e
 		return status.Errorf(codes.InvalidArgument, "Invalid authority: %s", dest.GetPath())
 	}
 
-	err = s.endpoints.Subscribe(service, port, instanceID, translator)
-	if err != nil {
-		if _, ok := err.(watcher.InvalidService); ok {
-			log.Debugf("Invalid service %s", dest.GetPath())
-			return status.Errorf(codes.InvalidArgument, "Invalid authority: %s", dest.GetPath())
+	translator.hashLabels = consistentHashLabels(s.k8sAPI.Svc().Lister(), service.Namespace, service.Name, log)
+
+	// Services with failover priorities are served the endpoints of the
+	// highest-priority cluster that has any. Pod DNS names aren't subject to
+	// failover.
+	var candidates []watcher.ServiceID
+	if instanceID == "" {
+		candidates = failoverCandidates(service, s.serviceFailoverPriorities(service))
+	}
+	if candidates != nil {
+		log.Debugf("Failing over %s to %v", dest.GetPath(), candidates)
+		for _, listener := range newFailoverEndpointListener(translator, candidates) {
+			err = s.endpoints.Subscribe(listener.id, port, instanceID, listener)
+			if err != nil {
+				log.Errorf("Failed to subscribe to %s for %s: %s", listener.id, dest.GetPath(), err)
+				return err
+			}
+			defer s.endpoints.Unsubscribe(listener.id, port, instanceID, listener)
 		}
-		log.Errorf("Failed to subscribe to %s: %s", dest.GetPath(), err)
-		return err
+	} else {
+		err = s.endpoints.Subscribe(service, port, instanceID, translator)
+		if err != nil {
+			if _, ok := err.(watcher.InvalidService); ok {
+				log.Debugf("Invalid service %s", dest.GetPath())
+				return status.Errorf(codes.InvalidArgument, "Invalid authority: %s", dest.GetPath())
+			}
+			log.Errorf("Failed to subscribe to %s: %s", dest.GetPath(), err)
+			return err
+		}
+		defer s.endpoints.Unsubscribe(service, port, instanceID, translator)
 	}
-	defer s.endpoints.Unsubscribe(service, port, instanceID, translator)
 
 	select {
 	case <-s.shutdown:
@@ -181,12 +205,22 @@ func (s *server) GetProfile(dest *pb.GetDestination, stream pb.Destination_GetPr
 	log.Debugf("GetProfile(%+v)", dest)
 
 	path := dest.GetPath()
-	// The host must be fully-qualified or be an IP address.
-	host, port, err := getHostAndPort(path)
+	var ctxToken contextToken
+	if dest.GetContextToken() != "" {
+		ctxToken = s.parseContextToken(dest.GetContextToken())
+	}
+
+	// The authority is canonicalized with the search path of the client's
+	// namespace; the result is either an IP or a fully-qualified name.
+	canonical, err := s.resolver.Canonicalize(path, ctxToken.Ns, s.serviceExists)
 	if err != nil {
-		log.Debugf("Invalid authority %s", path)
-		return status.Errorf(codes.InvalidArgument, "invalid authority: %s", err)
+		log.Debugf("Invalid authority %s: %s", path, err)
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	for _, step := range canonical.Trace {
+		log.Debugf("Canonicalizing %s: %s", path, step)
 	}
+	port := canonical.Port
 
 	// The stream will subscribe to profile updates for `service`.
 	var service watcher.ServiceID
@@ -194,7 +228,7 @@ func (s *server) GetProfile(dest *pb.GetDestination, stream pb.Destination_GetPr
 	// name of the service that the IP maps to.
 	var fqn string
 
-	if ip := net.ParseIP(host); ip != nil {
+	if ip := canonical.IP; ip != nil {
 		// Get the service that the IP currently maps to.
 		svcID, err := getSvcID(s.k8sAPI, ip.String(), log)
 		if err != nil {
@@ -229,12 +263,8 @@ func (s *server) GetProfile(dest *pb.GetDestination, stream pb.Destination_GetPr
 			return nil
 		}
 	} else {
-		var hostname string
-		service, hostname, err = parseK8sServiceName(host, s.clusterDomain)
-		if err != nil {
-			log.Debugf("Invalid service %s", path)
-			return status.Errorf(codes.InvalidArgument, "invalid service: %s", err)
-		}
+		service = canonical.Service
+		hostname := canonical.Instance
 
 		// If the pod name (instance ID) is not empty, it means we parsed a DNS
 		// name. When we fetch the profile using a pod's DNS name, we want to
@@ -260,7 +290,7 @@ func (s *server) GetProfile(dest *pb.GetDestination, stream pb.Destination_GetPr
 			return nil
 		}
 
-		fqn = host
+		fqn = canonical.Host
 	}
 
 	// We build up the pipeline of profile updaters backwards, starting from
@@ -268,34 +298,45 @@ func (s *server) GetProfile(dest *pb.GetDestination, stream pb.Destination_GetPr
 	// and pushes them onto the gRPC stream.
 	translator := newProfileTranslator(stream, log, fqn, port, nil)
 
-	// The traffic split adaptor merges profile updates with traffic split
-	// updates and publishes the result to the profile translator.
-	tsAdaptor := newTrafficSplitAdaptor(translator, service, port, s.clusterDomain)
+	var listener watcher.ProfileUpdateListener
+	if externalName := s.getExternalName(service); externalName != "" {
+		// ExternalName services have no endpoints, traffic splits or opaque
+		// ports; the external name adaptor overrides the destination with
+		// the external authority instead.
+		log.Debugf("Overriding %s with external authority %s", service, externalName)
+		listener = newExternalNameAdaptor(translator, externalName, port)
+	} else {
+		// The traffic split adaptor merges profile updates with traffic split
+		// updates and publishes the result to the profile translator.
+		tsAdaptor := newTrafficSplitAdaptor(translator, service, port, s.clusterDomain)
 
-	// Subscribe the adaptor to traffic split updates.
-	err = s.trafficSplits.Subscribe(service, tsAdaptor)
-	if err != nil {
-		log.Warnf("Failed to subscribe to traffic split for %s: %s", path, err)
-		return err
-	}
-	defer s.trafficSplits.Unsubscribe(service, tsAdaptor)
+		// Subscribe the adaptor to traffic split updates.
+		err = s.trafficSplits.Subscribe(service, tsAdaptor)
+		if err != nil {
+			log.Warnf("Failed to subscribe to traffic split for %s: %s", path, err)
+			return err
+		}
+		defer s.trafficSplits.Unsubscribe(service, tsAdaptor)
 
-	// The opaque ports adaptor merges profile updates with service opaque
-	// port annotation updates; it then publishes the result to the traffic
-	// split adaptor.
-	opaquePortsAdaptor := newOpaquePortsAdaptor(tsAdaptor)
+		// The opaque ports adaptor merges profile updates with service opaque
+		// port annotation updates; it then publishes the result to the traffic
+		// split adaptor.
+		opaquePortsAdaptor := newOpaquePortsAdaptor(tsAdaptor)
 
-	// Subscribe the adaptor to service updates.
-	err = s.opaquePorts.Subscribe(service, opaquePortsAdaptor)
-	if err != nil {
-		log.Warnf("Failed to subscribe to service updates for %s: %s", service, err)
-		return err
+		// Subscribe the adaptor to service updates.
+		err = s.opaquePorts.Subscribe(service, opaquePortsAdaptor)
+		if err != nil {
+			log.Warnf("Failed to subscribe to service updates for %s: %s", service, err)
+			return err
+		}
+		defer s.opaquePorts.Unsubscribe(service, opaquePortsAdaptor)
+
+		listener = opaquePortsAdaptor
 	}
-	defer s.opaquePorts.Unsubscribe(service, opaquePortsAdaptor)
 
 	// The fallback accepts updates from a primary and secondary source and
 	// passes the appropriate profile updates to the adaptor.
-	primary, secondary := newFallbackProfileListener(opaquePortsAdaptor)
+	primary, secondary := newFallbackProfileListener(listener)
 
 	// If we have a context token, we create two subscriptions: one with the
 	// context token which sends updates to the primary listener and one without
@@ -303,8 +344,6 @@ func (s *server) GetProfile(dest *pb.GetDestination, stream pb.Destination_GetPr
 	// up to the fallbackProfileListener to merge updates from the primary and
 	// secondary listeners and send the appropriate updates to the stream.
 	if dest.GetContextToken() != "" {
-		ctxToken := s.parseContextToken(dest.GetContextToken())
-
 		profile, err := profileID(fqn, ctxToken, s.clusterDomain)
 		if err != nil {
 			log.Debugf("Invalid service %s", path)
@@ -428,6 +467,22 @@ func getSvcID(k8sAPI *k8s.API, clusterIP string, log *logging.Entry) (*watcher.S
 	return service, nil
 }
 
+// serviceExists returns true if the service is in the informer cache.
+func (s *server) serviceExists(id watcher.ServiceID) bool {
+	_, err := s.k8sAPI.Svc().Lister().Services(id.Namespace).Get(id.Name)
+	return err == nil
+}
+
+// getExternalName returns the external name of the given service if it's an
+// ExternalName service, or an empty string otherwise.
+func (s *server) getExternalName(id watcher.ServiceID) string {
+	svc, err := s.k8sAPI.Svc().Lister().Services(id.Namespace).Get(id.Name)
+	if err != nil || svc.Spec.Type != corev1.ServiceTypeExternalName {
+		return ""
+	}
+	return svc.Spec.ExternalName
+}
+
 // getPodByHostname returns a pod that maps to the given hostname (or an
 // instanceID). The hostname is generally the prefix of the pod's DNS name;
 // since it may be arbitrary we need to look at the corresponding service's
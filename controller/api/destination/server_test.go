@@ -18,6 +18,7 @@ const fullyQualifiedName = "name1.ns.svc.mycluster.local"
 const fullyQualifiedNameOpaque = "name3.ns.svc.mycluster.local"
 const fullyQualifiedNameOpaqueService = "name4.ns.svc.mycluster.local"
 const fullyQualifiedNameSkipped = "name5.ns.svc.mycluster.local"
+const fullyQualifiedNameExternal = "external.ns.svc.mycluster.local"
 const fullyQualifiedPodDNS = "pod-0.statefulset-svc.ns.svc.mycluster.local"
 const clusterIP = "172.17.12.0"
 const clusterIPOpaque = "172.17.12.1"
@@ -313,12 +314,25 @@ status:
   podIP: 172.17.13.15`,
 	}
 
+	externalNameResources := []string{
+		`
+apiVersion: v1
+kind: Service
+metadata:
+  name: external
+  namespace: ns
+spec:
+  type: ExternalName
+  externalName: api.example.com`,
+	}
+
 	res := append(meshedPodResources, clientSP...)
 	res = append(res, unmeshedPod)
 	res = append(res, meshedOpaquePodResources...)
 	res = append(res, meshedOpaqueServiceResources...)
 	res = append(res, meshedSkippedPodResource...)
 	res = append(res, meshedStatefulSetPodResource...)
+	res = append(res, externalNameResources...)
 	k8sAPI, err := k8s.NewFakeAPI(res...)
 	if err != nil {
 		t.Fatalf("NewFakeAPI returned an error: %s", err)
@@ -359,6 +373,7 @@ status:
 		"trust.domain",
 		"mycluster.local",
 		defaultOpaquePorts,
+		NewAuthorityResolver("mycluster.local", DefaultNdots, nil),
 		k8sAPI,
 		log,
 		make(<-chan struct{}),
@@ -656,6 +671,31 @@ func TestGetProfiles(t *testing.T) {
 		}
 	})
 
+	t.Run("Return profile when using a short service name", func(t *testing.T) {
+		server := makeServer(t)
+		stream := &bufferingGetProfileStream{
+			updates:          []*pb.DestinationProfile{},
+			MockServerStream: util.NewMockServerStream(),
+		}
+		stream.Cancel()
+		err := server.GetProfile(&pb.GetDestination{
+			Scheme:       "k8s",
+			Path:         fmt.Sprintf("name1:%d", port),
+			ContextToken: "{\"ns\":\"ns\"}",
+		}, stream)
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if len(stream.updates) == 0 {
+			t.Fatalf("Expected at least 1 update but got none")
+		}
+		last := stream.updates[len(stream.updates)-1]
+		if last.FullyQualifiedName != fullyQualifiedName {
+			t.Fatalf("Expected fully qualified name '%s', but got '%s'", fullyQualifiedName, last.FullyQualifiedName)
+		}
+	})
+
 	t.Run("Return profile when using cluster IP", func(t *testing.T) {
 		server := makeServer(t)
 		stream := &bufferingGetProfileStream{
@@ -988,6 +1028,40 @@ func TestGetProfiles(t *testing.T) {
 			t.Fatalf("Expected TLS identity for %s to be nil but got %+v", path, addr.TlsIdentity)
 		}
 	})
+
+	t.Run("Return profile with external authority for ExternalName service", func(t *testing.T) {
+		server := makeServer(t)
+		stream := &bufferingGetProfileStream{
+			updates:          []*pb.DestinationProfile{},
+			MockServerStream: util.NewMockServerStream(),
+		}
+		stream.Cancel()
+
+		err := server.GetProfile(&pb.GetDestination{
+			Scheme: "k8s",
+			Path:   fmt.Sprintf("%s:%d", fullyQualifiedNameExternal, 443),
+		}, stream)
+		if err != nil {
+			t.Fatalf("Got error: %s", err)
+		}
+
+		if len(stream.updates) == 0 {
+			t.Fatal("Expected at least one update but got none")
+		}
+
+		last := stream.updates[len(stream.updates)-1]
+		if last.FullyQualifiedName != fullyQualifiedNameExternal {
+			t.Fatalf("Expected fully qualified name '%s', but got '%s'", fullyQualifiedNameExternal, last.FullyQualifiedName)
+		}
+
+		dsts := last.GetDstOverrides()
+		if len(dsts) != 1 {
+			t.Fatalf("Expected 1 dst override but got %d: %v", len(dsts), dsts)
+		}
+		if dsts[0].Authority != "api.example.com.:443" {
+			t.Fatalf("Expected dst override authority 'api.example.com.:443', but got '%s'", dsts[0].Authority)
+		}
+	})
 }
 
 func TestTokenStructure(t *testing.T) {
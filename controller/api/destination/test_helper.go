@@ -64,9 +64,12 @@ type AuthorityEndpoints struct {
 
 // PodDetails holds the details for pod associated to an Endpoint
 type PodDetails struct {
-	Name string
-	IP   uint32
-	Port uint32
+	Name        string
+	IP          uint32
+	Port        uint32
+	Node        string
+	Zone        string
+	ImageDigest string
 }
 
 // BuildAddrSet converts AuthorityEndpoints into its protobuf representation
@@ -78,6 +81,11 @@ func BuildAddrSet(endpoint AuthorityEndpoints) *destinationPb.WeightedAddrSet {
 			Port: pod.Port,
 		}
 		labels := map[string]string{"pod": pod.Name}
+		for k, v := range map[string]string{"node": pod.Node, "zone": pod.Zone, "image_digest": pod.ImageDigest} {
+			if v != "" {
+				labels[k] = v
+			}
+		}
 		weightedAddr := &destinationPb.WeightedAddr{Addr: addr, MetricLabels: labels}
 		addrs = append(addrs, weightedAddr)
 	}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/linkerd/linkerd2/controller/k8s"
 	consts "github.com/linkerd/linkerd2/pkg/k8s"
+	"github.com/linkerd/linkerd2/pkg/multicluster"
 	"github.com/prometheus/client_golang/prometheus"
 	logging "github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
@@ -50,6 +51,9 @@ type (
 		Identity          string
 		AuthorityOverride string
 		TopologyLabels    map[string]string
+		// Weight is the relative weight of a remote gateway address, as set
+		// by the service mirror. Zero means no weight was assigned.
+		Weight uint32
 	}
 
 	// AddressSet is a set of Address, indexed by ID.
@@ -145,7 +149,7 @@ func NewEndpointsWatcher(k8sAPI *k8s.API, log *logging.Entry, enableEndpointSlic
 		}),
 	}
 
-	k8sAPI.Svc().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+	k8sAPI.AddEventHandler(k8s.Svc, cache.ResourceEventHandlerFuncs{
 		AddFunc:    ew.addService,
 		DeleteFunc: ew.deleteService,
 		UpdateFunc: func(_, obj interface{}) { ew.addService(obj) },
@@ -153,14 +157,14 @@ func NewEndpointsWatcher(k8sAPI *k8s.API, log *logging.Entry, enableEndpointSlic
 
 	if ew.enableEndpointSlices {
 		ew.log.Debugf("Watching EndpointSlice resources")
-		k8sAPI.ES().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		k8sAPI.AddEventHandler(k8s.ES, cache.ResourceEventHandlerFuncs{
 			AddFunc:    ew.addEndpointSlice,
 			DeleteFunc: ew.deleteEndpointSlice,
 			UpdateFunc: ew.updateEndpointSlice,
 		})
 	} else {
 		ew.log.Debugf("Watching Endpoints resources")
-		k8sAPI.Endpoint().Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		k8sAPI.AddEventHandler(k8s.Endpoint, cache.ResourceEventHandlerFuncs{
 			AddFunc:    ew.addEndpoints,
 			DeleteFunc: ew.deleteEndpoints,
 			UpdateFunc: func(_, obj interface{}) { ew.addEndpoints(obj) },
@@ -716,6 +720,8 @@ func (pp *portPublisher) endpointSliceToAddresses(es *discovery.EndpointSlice) A
 		pp.log.Errorf("Could not fetch resource service name:%v", err)
 	}
 
+	weights := pp.gatewayWeights(es.Annotations)
+	identities := pp.gatewayIdentities(es.Annotations)
 	for _, endpoint := range es.Endpoints {
 		if endpoint.Hostname != nil {
 			if pp.hostname != "" && pp.hostname != *endpoint.Hostname {
@@ -733,9 +739,13 @@ func (pp *portPublisher) endpointSliceToAddresses(es *discovery.EndpointSlice) A
 					authorityOverride = fmt.Sprintf("%s:%d", fqName, pp.srcPort)
 				}
 
-				identity := es.Annotations[consts.RemoteGatewayIdentity]
+				identity, ok := identities[IPAddr]
+				if !ok {
+					identity = es.Annotations[consts.RemoteGatewayIdentity]
+				}
 				address, id := pp.newServiceRefAddress(resolvedPort, IPAddr, serviceID.Name, es.Namespace)
 				address.Identity, address.AuthorityOverride = authorityOverride, identity
+				address.Weight = weights[IPAddr]
 
 				for k, v := range endpoint.Topology {
 					address.TopologyLabels[k] = v
@@ -770,6 +780,8 @@ func (pp *portPublisher) endpointSliceToAddresses(es *discovery.EndpointSlice) A
 
 func (pp *portPublisher) endpointsToAddresses(endpoints *corev1.Endpoints) AddressSet {
 	addresses := make(map[ID]Address)
+	weights := pp.gatewayWeights(endpoints.Annotations)
+	identities := pp.gatewayIdentities(endpoints.Annotations)
 	for _, subset := range endpoints.Subsets {
 		resolvedPort := pp.resolveTargetPort(subset)
 		if resolvedPort == undefinedEndpointPort {
@@ -786,9 +798,13 @@ func (pp *portPublisher) endpointsToAddresses(endpoints *corev1.Endpoints) Addre
 					authorityOverride = fmt.Sprintf("%s:%d", fqName, pp.srcPort)
 				}
 
-				identity := endpoints.Annotations[consts.RemoteGatewayIdentity]
+				identity, ok := identities[endpoint.IP]
+				if !ok {
+					identity = endpoints.Annotations[consts.RemoteGatewayIdentity]
+				}
 				address, id := pp.newServiceRefAddress(resolvedPort, endpoint.IP, endpoints.Name, endpoints.Namespace)
 				address.Identity, address.AuthorityOverride = identity, authorityOverride
+				address.Weight = weights[endpoint.IP]
 
 				addresses[id] = address
 				continue
@@ -814,6 +830,36 @@ func (pp *portPublisher) endpointsToAddresses(endpoints *corev1.Endpoints) Addre
 	}
 }
 
+// gatewayWeights parses the weights of the remote gateway addresses set by
+// the service mirror on mirrored endpoints, keyed by IP.
+func (pp *portPublisher) gatewayWeights(annotations map[string]string) map[string]uint32 {
+	value, ok := annotations[consts.RemoteGatewayWeights]
+	if !ok {
+		return nil
+	}
+	weights, err := multicluster.ParseGatewayAddressWeights(value)
+	if err != nil {
+		pp.log.Errorf("Ignoring invalid %s annotation: %s", consts.RemoteGatewayWeights, err)
+		return nil
+	}
+	return weights
+}
+
+// gatewayIdentities parses the identities of the remote gateway addresses set
+// by the service federation on federated endpoints, keyed by IP.
+func (pp *portPublisher) gatewayIdentities(annotations map[string]string) map[string]string {
+	value, ok := annotations[consts.RemoteGatewayIdentities]
+	if !ok {
+		return nil
+	}
+	identities, err := multicluster.ParseGatewayIdentities(value)
+	if err != nil {
+		pp.log.Errorf("Ignoring invalid %s annotation: %s", consts.RemoteGatewayIdentities, err)
+		return nil
+	}
+	return identities
+}
+
 func (pp *portPublisher) newServiceRefAddress(endpointPort Port, endpointIP, serviceName, serviceNamespace string) (Address, ServiceID) {
 	id := ServiceID{
 		Name: strings.Join([]string{
@@ -1030,6 +1076,11 @@ func addressChanged(oldAddress Address, newAddress Address) bool {
 		return true
 	}
 
+	if oldAddress.Weight != newAddress.Weight {
+		// the weights of the gateway addresses of a Link have been changed
+		return true
+	}
+
 	if oldAddress.Pod != nil && newAddress.Pod != nil {
 		// if these addresses are owned by pods we can check the resource versions
 		return oldAddress.Pod.ResourceVersion != newAddress.Pod.ResourceVersion
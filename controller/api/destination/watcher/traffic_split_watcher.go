@@ -5,6 +5,7 @@ import (
 	"sync"
 
 	"github.com/linkerd/linkerd2/controller/k8s"
+	"github.com/linkerd/linkerd2/pkg/crash"
 	"github.com/prometheus/client_golang/prometheus"
 	ts "github.com/servicemeshinterface/smi-sdk-go/pkg/apis/split/v1alpha1"
 	tslisters "github.com/servicemeshinterface/smi-sdk-go/pkg/gen/client/split/listers/split/v1alpha1"
@@ -53,11 +54,11 @@ func NewTrafficSplitWatcher(k8sAPI *k8s.API, log *logging.Entry) *TrafficSplitWa
 	}
 
 	k8sAPI.TS().Informer().AddEventHandler(
-		cache.ResourceEventHandlerFuncs{
+		crash.Handler("traffic-split-watcher", cache.ResourceEventHandlerFuncs{
 			AddFunc:    watcher.addTrafficSplit,
 			UpdateFunc: watcher.updateTrafficSplit,
 			DeleteFunc: watcher.deleteTrafficSplit,
-		},
+		}),
 	)
 
 	return watcher
@@ -4,13 +4,18 @@ import (
 	"context"
 	"flag"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/linkerd/linkerd2/controller/api/destination"
 	"github.com/linkerd/linkerd2/controller/k8s"
 	"github.com/linkerd/linkerd2/pkg/admin"
+	"github.com/linkerd/linkerd2/pkg/drain"
+	"github.com/linkerd/linkerd2/pkg/featuregates"
 	"github.com/linkerd/linkerd2/pkg/flags"
 	pkgK8s "github.com/linkerd/linkerd2/pkg/k8s"
 	"github.com/linkerd/linkerd2/pkg/trace"
@@ -32,8 +37,14 @@ func Main(args []string) {
 	trustDomain := cmd.String("identity-trust-domain", "", "configures the name suffix used for identities")
 	clusterDomain := cmd.String("cluster-domain", "", "kubernetes cluster domain")
 	defaultOpaquePorts := cmd.String("default-opaque-ports", "", "configures the default opaque ports")
+	dnsNdots := cmd.Int("dns-ndots", destination.DefaultNdots, "number of dots a profile authority must have to be looked up as an absolute name before the search path is applied")
+	dnsSearchDomains := cmd.String("dns-search-domains", "", "comma-separated list of search domains appended to the search path of each namespace when canonicalizing profile authorities")
+	protocolDetectionScrapeInterval := cmd.Duration("protocol-detection-scrape-interval", 0, "how often to scrape the meshed proxies to aggregate their protocol detection timeouts per service, served on the admin server (0 to disable)")
+	consistencyCheckPeriod := cmd.Duration("cache-consistency-check-period", 10*time.Minute, "how often to compare the caches against the Kubernetes API and relist the diverged ones (0 to disable)")
 
 	traceCollector := flags.AddTraceFlags(cmd)
+	drainConfig := drain.AddFlags(cmd)
+	featureGatesFlag := flags.AddFeatureGatesFlag(cmd)
 
 	flags.ConfigureAndParse(cmd, args)
 
@@ -68,6 +79,15 @@ func Main(args []string) {
 
 	log.Infof("Using default opaque ports: %v", opaquePorts)
 
+	if *dnsNdots < 0 {
+		log.Fatalf("Invalid dns-ndots value %d: must be non-negative", *dnsNdots)
+	}
+	var searchDomains []string
+	if *dnsSearchDomains != "" {
+		searchDomains = strings.Split(*dnsSearchDomains, ",")
+	}
+	resolver := destination.NewAuthorityResolver(*clusterDomain, *dnsNdots, searchDomains)
+
 	if *traceCollector != "" {
 		if err := trace.InitializeTracing("linkerd-destination", *traceCollector); err != nil {
 			log.Warnf("failed to initialize tracing: %s", err)
@@ -83,6 +103,27 @@ func Main(args []string) {
 
 	ctx := context.Background()
 
+	gates := featuregates.New()
+	if err := gates.ParseFlag(*featureGatesFlag); err != nil {
+		log.Fatalf("Failed to parse feature gates: %s", err)
+	}
+	if err := gates.Load(ctx, k8Client, *controllerNamespace); err != nil {
+		log.Warnf("Failed to load feature gates from config: %s", err)
+	}
+	log.Infof("Using feature gates: %s", gates)
+
+	// EndpointSlices determines which informers are started, so the gate is
+	// only honored at startup
+	if gates.Enabled(featuregates.EndpointSlices) {
+		*enableEndpointSlices = true
+	}
+	gates.OnChange(func(f featuregates.Feature, enabled bool) {
+		if f == featuregates.EndpointSlices && enabled != *enableEndpointSlices {
+			log.Warnf("Feature gate %s set to %t; restart the destination controller to apply it", f, enabled)
+		}
+	})
+	go gates.Watch(ctx, k8Client, *controllerNamespace, time.Minute)
+
 	err = pkgK8s.EndpointSliceAccess(ctx, k8Client)
 	if *enableEndpointSlices && err != nil {
 		log.Fatalf("Failed to start with EndpointSlices enabled: %s", err)
@@ -117,6 +158,7 @@ func Main(args []string) {
 		k8sAPI,
 		*clusterDomain,
 		opaquePorts,
+		resolver,
 		done,
 	)
 
@@ -126,16 +168,32 @@ func Main(args []string) {
 
 	k8sAPI.Sync(nil) // blocks until caches are synced
 
+	if *consistencyCheckPeriod > 0 {
+		endpointsResource := k8s.Endpoint
+		if *enableEndpointSlices {
+			endpointsResource = k8s.ES
+		}
+		k8sAPI.StartConsistencyChecks(ctx, *consistencyCheckPeriod, k8s.Svc, k8s.Pod, endpointsResource)
+	}
+
 	go func() {
 		log.Infof("starting gRPC server on %s", *addr)
 		server.Serve(lis)
 	}()
 
-	go admin.StartServer(*metricsAddr)
+	handlers := map[string]http.Handler{}
+	if *protocolDetectionScrapeInterval > 0 {
+		aggregator := destination.NewProtocolDetectionAggregator(k8sAPI)
+		go aggregator.Run(ctx, *protocolDetectionScrapeInterval)
+		handlers[destination.ProtocolDetectionPath] = aggregator
+	}
+
+	go admin.StartServerWithHandlers(*metricsAddr, nil, handlers)
 
 	<-stop
 
 	log.Infof("shutting down gRPC server on %s", *addr)
-	close(done)
-	server.GracefulStop()
+	// the Get and GetProfile streams are only ended once the clients were
+	// told to go away, so that they reopen them on another replica
+	drainConfig.GracefulStop("gRPC server", server, func() { close(done) })
 }
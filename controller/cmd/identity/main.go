@@ -14,6 +14,7 @@ import (
 
 	idctl "github.com/linkerd/linkerd2/controller/identity"
 	"github.com/linkerd/linkerd2/pkg/admin"
+	"github.com/linkerd/linkerd2/pkg/drain"
 	"github.com/linkerd/linkerd2/pkg/flags"
 	"github.com/linkerd/linkerd2/pkg/identity"
 	"github.com/linkerd/linkerd2/pkg/k8s"
@@ -50,6 +51,7 @@ func Main(args []string) {
 	var issuerPathCrt string
 	var issuerPathKey string
 	traceCollector := flags.AddTraceFlags(cmd)
+	drainConfig := drain.AddFlags(cmd)
 	componentName := "linkerd-identity"
 
 	flags.ConfigureAndParse(cmd, args)
@@ -86,6 +88,11 @@ func Main(args []string) {
 	if err != nil {
 		log.Fatalf("Failed to read trust anchors: %s", err)
 	}
+	anchors, err := tls.DecodePEMCertificates(string(identityTrustAnchorPEM))
+	if err != nil {
+		log.Fatalf("Failed to read trust anchors: %s", err)
+	}
+	identity.RecordTrustAnchorsExpiration(anchors)
 
 	validity := tls.Validity{
 		ClockSkewAllowance: tls.DefaultClockSkewAllowance,
@@ -153,10 +160,16 @@ func Main(args []string) {
 		recorder.Event(parent, eventType, reason, message)
 	}
 
+	//
+	// Watch the per-namespace issuance policies
+	//
+	policies := identity.NewIssuancePolicies(validity.Lifetime)
+	policies.Watch(ctx, k8sAPI.DynamicClient)
+
 	//
 	// Create, initialize and run service
 	//
-	svc := identity.NewService(v, trustAnchors, &validity, recordEventFunc, expectedName, issuerPathCrt, issuerPathKey)
+	svc := identity.NewService(v, trustAnchors, &validity, policies, recordEventFunc, expectedName, issuerPathCrt, issuerPathKey)
 	if err = svc.Initialize(); err != nil {
 		log.Fatalf("Failed to initialize identity service: %s", err)
 	}
@@ -186,5 +199,5 @@ func Main(args []string) {
 	}()
 	<-stop
 	log.Infof("shutting down gRPC server on %s", *addr)
-	srv.GracefulStop()
+	drainConfig.GracefulStop("gRPC server", srv, nil)
 }
@@ -9,6 +9,8 @@ import (
 	"github.com/linkerd/linkerd2/controller/cmd/identity"
 	proxyinjector "github.com/linkerd/linkerd2/controller/cmd/proxy-injector"
 	spvalidator "github.com/linkerd/linkerd2/controller/cmd/sp-validator"
+	serviceexport "github.com/linkerd/linkerd2/multicluster/cmd/service-export"
+	servicefederation "github.com/linkerd/linkerd2/multicluster/cmd/service-federation"
 	servicemirror "github.com/linkerd/linkerd2/multicluster/cmd/service-mirror"
 )
 
@@ -29,6 +31,10 @@ func main() {
 		proxyinjector.Main(os.Args[2:])
 	case "sp-validator":
 		spvalidator.Main(os.Args[2:])
+	case "service-export":
+		serviceexport.Main(os.Args[2:])
+	case "service-federation":
+		servicefederation.Main(os.Args[2:])
 	case "service-mirror":
 		servicemirror.Main(os.Args[2:])
 	default:
@@ -8,7 +8,9 @@ import (
 	"github.com/linkerd/linkerd2/controller/k8s"
 	injector "github.com/linkerd/linkerd2/controller/proxy-injector"
 	"github.com/linkerd/linkerd2/controller/webhook"
+	"github.com/linkerd/linkerd2/pkg/featuregates"
 	"github.com/linkerd/linkerd2/pkg/flags"
+	log "github.com/sirupsen/logrus"
 )
 
 // Main executes the proxy-injector subcommand
@@ -17,13 +19,20 @@ func Main(args []string) {
 	metricsAddr := cmd.String("metrics-addr", fmt.Sprintf(":%d", 9995), "address to serve scrapable metrics on")
 	addr := cmd.String("addr", ":8443", "address to serve on")
 	kubeconfig := cmd.String("kubeconfig", "", "path to kubeconfig")
+	featureGatesFlag := flags.AddFeatureGatesFlag(cmd)
 	flags.ConfigureAndParse(cmd, args)
 
+	gates := featuregates.New()
+	if err := gates.ParseFlag(*featureGatesFlag); err != nil {
+		log.Fatalf("Failed to parse feature gates: %s", err)
+	}
+
 	webhook.Launch(
 		context.Background(),
 		[]k8s.APIResource{k8s.NS, k8s.Deploy, k8s.RC, k8s.RS, k8s.Job, k8s.DS, k8s.SS, k8s.Pod, k8s.CJ},
-		injector.Inject,
+		injector.Inject(gates),
 		"linkerd-proxy-injector",
+		"",
 		*metricsAddr,
 		*addr,
 		*kubeconfig,
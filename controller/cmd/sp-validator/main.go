@@ -8,6 +8,7 @@ import (
 	validator "github.com/linkerd/linkerd2/controller/sp-validator"
 	"github.com/linkerd/linkerd2/controller/webhook"
 	"github.com/linkerd/linkerd2/pkg/flags"
+	log "github.com/sirupsen/logrus"
 )
 
 // Main executes the sp-validator subcommand
@@ -16,13 +17,22 @@ func Main(args []string) {
 	metricsAddr := cmd.String("metrics-addr", fmt.Sprintf(":%d", 9997), "address to serve scrapable metrics on")
 	addr := cmd.String("addr", ":8443", "address to serve on")
 	kubeconfig := cmd.String("kubeconfig", "", "path to kubeconfig")
+	mirrorProtection := cmd.String("mirror-protection", validator.MirrorProtectionWarn,
+		fmt.Sprintf("how the manual changes of the mirrored Services and Endpoints are handled; one of: %s (admit them with a warning), %s (reject them, unless made by cluster admins), %s",
+			validator.MirrorProtectionWarn, validator.MirrorProtectionReject, validator.MirrorProtectionOff))
 	flags.ConfigureAndParse(cmd, args)
 
+	handler, err := validator.Admit(*mirrorProtection)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	webhook.Launch(
 		context.Background(),
 		nil,
-		validator.AdmitSP,
+		handler,
 		"linkerd-sp-validator",
+		"",
 		*metricsAddr,
 		*addr,
 		*kubeconfig,
@@ -94,7 +94,9 @@ type API struct {
 	spSharedInformers sp.SharedInformerFactory
 	tsSharedInformers ts.SharedInformerFactory
 
-	gauges []prometheus.GaugeFunc
+	gauges      []prometheus.GaugeFunc
+	consistency consistencyState
+	indexes     map[Index]struct{}
 }
 
 // InitializeAPI creates Kubernetes clients and returns an initialized API wrapper.
@@ -196,9 +198,15 @@ func NewAPI(
 		sharedInformers:   sharedInformers,
 		spSharedInformers: spSharedInformers,
 		tsSharedInformers: tsSharedInformers,
+		consistency: consistencyState{
+			relisters: make(map[APIResource]*relister),
+			pending:   make(map[APIResource]map[string]divergence),
+		},
+		indexes: make(map[Index]struct{}),
 	}
 
 	for _, resource := range resources {
+		api.registerRelister(sharedInformers, resource)
 		switch resource {
 		case CJ:
 			api.cj = sharedInformers.Batch().V1beta1().CronJobs()
@@ -648,7 +656,11 @@ func (api *API) GetPodsFor(obj runtime.Object, includeFailed bool) ([]*corev1.Po
 	// if obj.(type) is Pod, we've already retrieved it and put it in pods
 	// for the other types, pods will still be empty
 	if len(pods) == 0 {
-		pods, err = api.Pod().Lister().Pods(namespace).List(selector)
+		if ownerUID != "" && api.HasIndex(PodOwner) {
+			pods, err = api.getPodsByOwner(namespace, ownerUID)
+		} else {
+			pods, err = api.Pod().Lister().Pods(namespace).List(selector)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -665,6 +677,22 @@ func (api *API) GetPodsFor(obj runtime.Object, includeFailed bool) ([]*corev1.Po
 	return allPods, nil
 }
 
+// getPodsByOwner returns the pods of the namespace owned by the given UID,
+// through the PodOwner index.
+func (api *API) getPodsByOwner(namespace string, ownerUID types.UID) ([]*corev1.Pod, error) {
+	objs, err := api.Pod().Informer().GetIndexer().ByIndex(OwnerIndex, string(ownerUID))
+	if err != nil {
+		return nil, err
+	}
+	pods := make([]*corev1.Pod, 0, len(objs))
+	for _, obj := range objs {
+		if pod := obj.(*corev1.Pod); pod.Namespace == namespace {
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
 func isOwner(u types.UID, ownerRefs []metav1.OwnerReference) bool {
 	for _, or := range ownerRefs {
 		if u == or.UID {
@@ -926,35 +926,45 @@ status:
 				t.Fatalf("could not decode yml: %s", err)
 			}
 
-			api, k8sResults, err := newAPI(true, exp.k8sResResults, exp.k8sResMisc...)
-			if err != nil {
-				t.Fatalf("newAPI error: %s", err)
-			}
+			// the pods are expected to be the same whether they're looked
+			// up through the owner index or not
+			for _, indexed := range []bool{false, true} {
+				api, k8sResults, err := newAPI(false, exp.k8sResResults, exp.k8sResMisc...)
+				if err != nil {
+					t.Fatalf("newAPI error: %s", err)
+				}
+				if indexed {
+					if err := api.AddIndexes(PodOwner); err != nil {
+						t.Fatalf("Unexpected error: %s", err)
+					}
+				}
+				api.Sync(nil)
 
-			k8sResultPods := []*corev1.Pod{}
-			for _, obj := range k8sResults {
-				k8sResultPods = append(k8sResultPods, obj.(*corev1.Pod))
-			}
+				k8sResultPods := []*corev1.Pod{}
+				for _, obj := range k8sResults {
+					k8sResultPods = append(k8sResultPods, obj.(*corev1.Pod))
+				}
 
-			pods, err := api.GetPodsFor(k8sInputObj, false)
-			if err != exp.err {
-				t.Fatalf("api.GetPodsFor() unexpected error, expected [%s] got: [%s]", exp.err, err)
-			}
+				pods, err := api.GetPodsFor(k8sInputObj, false)
+				if err != exp.err {
+					t.Fatalf("api.GetPodsFor() unexpected error, expected [%s] got: [%s]", exp.err, err)
+				}
 
-			if len(pods) != len(k8sResultPods) {
-				t.Fatalf("Expected: %+v, Got: %+v", k8sResultPods, pods)
-			}
+				if len(pods) != len(k8sResultPods) {
+					t.Fatalf("Expected: %+v, Got: %+v (indexed: %t)", k8sResultPods, pods, indexed)
+				}
 
-			for _, pod := range pods {
-				found := false
-				for _, resultPod := range k8sResultPods {
-					if reflect.DeepEqual(pod, resultPod) {
-						found = true
-						break
+				for _, pod := range pods {
+					found := false
+					for _, resultPod := range k8sResultPods {
+						if reflect.DeepEqual(pod, resultPod) {
+							found = true
+							break
+						}
+					}
+					if !found {
+						t.Fatalf("Expected: %+v, Got: %+v (indexed: %t)", k8sResultPods, pods, indexed)
 					}
-				}
-				if !found {
-					t.Fatalf("Expected: %+v, Got: %+v", k8sResultPods, pods)
 				}
 			}
 		}
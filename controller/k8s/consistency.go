@@ -0,0 +1,352 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/linkerd/linkerd2/pkg/crash"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/pager"
+)
+
+const (
+	divergenceMissing = "missing"
+	divergenceStale   = "stale"
+	divergenceDeleted = "deleted"
+)
+
+var cacheDivergences = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "cache_divergences_total",
+		Help: "Number of objects found to be missing, stale or already deleted in the client-go caches, causing them to be relisted.",
+	},
+	[]string{"resource", "divergence"},
+)
+
+type (
+	// consistencyTarget is a cache that can be checked against the API server.
+	consistencyTarget struct {
+		name     string
+		informer cache.SharedIndexInformer
+		relister *relister
+		list     pager.ListPageFunc
+	}
+
+	// divergence is an object whose cached state doesn't match the API
+	// server's.
+	divergence struct {
+		kind          string
+		cachedVersion string
+		listedVersion string
+	}
+
+	// consistencyState holds the divergences found by the last check of each
+	// resource, and the relisters of the informers that can be checked.
+	consistencyState struct {
+		sync.Mutex
+		relisters map[APIResource]*relister
+		pending   map[APIResource]map[string]divergence
+	}
+
+	// relister is the ListerWatcher of an informer whose cache can be
+	// resynced on demand: relist ends the current watch and fails the next
+	// one, upon which the reflector of the informer lists the resource again
+	// and replaces the contents of its cache. The differences are delivered
+	// to the event handlers by the informer itself, in order with the other
+	// events.
+	relister struct {
+		cache.ListerWatcher
+
+		sync.Mutex
+		watch     watch.Interface
+		requested bool
+	}
+)
+
+// AddEventHandler registers the handler with the informer of the given
+// resource, recovering and reporting the panics it raises.
+func (api *API) AddEventHandler(res APIResource, handler cache.ResourceEventHandler) {
+	target, err := api.consistencyTarget(res)
+	if err != nil {
+		panic(err)
+	}
+	target.informer.AddEventHandler(crash.Handler(target.name, handler))
+}
+
+// StartConsistencyChecks periodically compares the contents of the caches of
+// the given resources against a paged LIST from the API server, until the
+// context is cancelled. After prolonged disruptions of the watches, events can
+// be lost and the caches diverge from the cluster state; a cache whose objects
+// diverge in two consecutive checks is relisted.
+func (api *API) StartConsistencyChecks(ctx context.Context, interval time.Duration, resources ...APIResource) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				for _, res := range resources {
+					if err := api.checkConsistency(ctx, res); err != nil {
+						log.Warnf("Failed to check cache consistency: %s", err)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// checkConsistency compares the cache of the given resource against the API
+// server, and relists it if some objects were already found to diverge in the
+// previous check. Divergences are only acted upon when they persist, so that
+// events still in flight when listing aren't mistaken for lost ones.
+func (api *API) checkConsistency(ctx context.Context, res APIResource) error {
+	target, err := api.consistencyTarget(res)
+	if err != nil {
+		return err
+	}
+
+	listed := make(map[string]runtime.Object)
+	list, err := pager.New(target.list).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list %s: %s", target.name, err)
+	}
+	err = meta.EachListItem(list, func(obj runtime.Object) error {
+		o, err := meta.Accessor(obj)
+		if err != nil {
+			return err
+		}
+		key, err := cache.MetaNamespaceKeyFunc(o)
+		if err != nil {
+			return err
+		}
+		listed[key] = obj
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	store := target.informer.GetStore()
+	found := make(map[string]divergence)
+	for _, obj := range store.List() {
+		o, err := meta.Accessor(obj)
+		if err != nil {
+			return err
+		}
+		key, err := cache.MetaNamespaceKeyFunc(o)
+		if err != nil {
+			return err
+		}
+		listedObj, ok := listed[key]
+		if !ok {
+			found[key] = divergence{kind: divergenceDeleted, cachedVersion: o.GetResourceVersion()}
+			continue
+		}
+		l, err := meta.Accessor(listedObj)
+		if err != nil {
+			return err
+		}
+		if l.GetResourceVersion() != o.GetResourceVersion() {
+			found[key] = divergence{kind: divergenceStale, cachedVersion: o.GetResourceVersion(), listedVersion: l.GetResourceVersion()}
+		}
+	}
+	for key, obj := range listed {
+		if _, ok, _ := store.GetByKey(key); ok {
+			continue
+		}
+		l, err := meta.Accessor(obj)
+		if err != nil {
+			return err
+		}
+		found[key] = divergence{kind: divergenceMissing, listedVersion: l.GetResourceVersion()}
+	}
+
+	api.consistency.Lock()
+	pending := api.consistency.pending[res]
+	persisting := make(map[string]divergence)
+	for key, d := range found {
+		if previous, ok := pending[key]; ok && previous == d {
+			persisting[key] = d
+			delete(found, key)
+		}
+	}
+	api.consistency.pending[res] = found
+	api.consistency.Unlock()
+
+	if len(persisting) == 0 {
+		return nil
+	}
+	for key, d := range persisting {
+		log.Warnf("The %s %s is %s in the cache", target.name, key, d.kind)
+		cacheDivergences.WithLabelValues(target.name, d.kind).Inc()
+	}
+	log.Warnf("Relisting the %s cache, which diverged from the API server on %d objects", target.name, len(persisting))
+	target.relister.relist()
+	return nil
+}
+
+// registerRelister makes the informer factory build the informer of the given
+// resource on top of a relister, if consistency checks are supported for it.
+// It must be called before the informer is first accessed.
+func (api *API) registerRelister(factory informers.SharedInformerFactory, res APIResource) {
+	var lw *cache.ListWatch
+	var obj runtime.Object
+	switch res {
+	case Endpoint:
+		obj = &corev1.Endpoints{}
+		lw = &cache.ListWatch{
+			ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+				return api.Client.CoreV1().Endpoints(metav1.NamespaceAll).List(context.TODO(), opts)
+			},
+			WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+				return api.Client.CoreV1().Endpoints(metav1.NamespaceAll).Watch(context.TODO(), opts)
+			},
+		}
+	case ES:
+		obj = &discoveryv1beta1.EndpointSlice{}
+		lw = &cache.ListWatch{
+			ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+				return api.Client.DiscoveryV1beta1().EndpointSlices(metav1.NamespaceAll).List(context.TODO(), opts)
+			},
+			WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+				return api.Client.DiscoveryV1beta1().EndpointSlices(metav1.NamespaceAll).Watch(context.TODO(), opts)
+			},
+		}
+	case Pod:
+		obj = &corev1.Pod{}
+		lw = &cache.ListWatch{
+			ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+				return api.Client.CoreV1().Pods(metav1.NamespaceAll).List(context.TODO(), opts)
+			},
+			WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+				return api.Client.CoreV1().Pods(metav1.NamespaceAll).Watch(context.TODO(), opts)
+			},
+		}
+	case Svc:
+		obj = &corev1.Service{}
+		lw = &cache.ListWatch{
+			ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+				return api.Client.CoreV1().Services(metav1.NamespaceAll).List(context.TODO(), opts)
+			},
+			WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+				return api.Client.CoreV1().Services(metav1.NamespaceAll).Watch(context.TODO(), opts)
+			},
+		}
+	default:
+		return
+	}
+
+	r := &relister{ListerWatcher: lw}
+	factory.InformerFor(obj, func(_ kubernetes.Interface, resync time.Duration) cache.SharedIndexInformer {
+		return cache.NewSharedIndexInformer(r, obj, resync, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc})
+	})
+	api.consistency.relisters[res] = r
+}
+
+// List lists the resource, which fulfills any pending relist.
+func (r *relister) List(options metav1.ListOptions) (runtime.Object, error) {
+	r.Lock()
+	r.requested = false
+	r.Unlock()
+	return r.ListerWatcher.List(options)
+}
+
+// Watch watches the resource, unless a relist is pending, in which case it
+// fails with an expired error so that the reflector relists the resource.
+func (r *relister) Watch(options metav1.ListOptions) (watch.Interface, error) {
+	expired := kerrors.NewResourceExpired("relisting to resync the cache")
+	r.Lock()
+	requested := r.requested
+	r.Unlock()
+	if requested {
+		return nil, expired
+	}
+
+	w, err := r.ListerWatcher.Watch(options)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	if r.requested {
+		w.Stop()
+		return nil, expired
+	}
+	r.watch = w
+	return w, nil
+}
+
+// relist makes the reflector of the informer list the resource again.
+func (r *relister) relist() {
+	r.Lock()
+	defer r.Unlock()
+	r.requested = true
+	if r.watch != nil {
+		r.watch.Stop()
+		r.watch = nil
+	}
+}
+
+func (api *API) consistencyTarget(res APIResource) (*consistencyTarget, error) {
+	api.consistency.Lock()
+	relister := api.consistency.relisters[res]
+	api.consistency.Unlock()
+
+	switch res {
+	case Endpoint:
+		return &consistencyTarget{
+			name:     "endpoints",
+			informer: api.Endpoint().Informer(),
+			relister: relister,
+			list: func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+				return api.Client.CoreV1().Endpoints(metav1.NamespaceAll).List(ctx, opts)
+			},
+		}, nil
+	case ES:
+		return &consistencyTarget{
+			name:     "endpoint_slice",
+			informer: api.ES().Informer(),
+			relister: relister,
+			list: func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+				return api.Client.DiscoveryV1beta1().EndpointSlices(metav1.NamespaceAll).List(ctx, opts)
+			},
+		}, nil
+	case Pod:
+		return &consistencyTarget{
+			name:     "pod",
+			informer: api.Pod().Informer(),
+			relister: relister,
+			list: func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+				return api.Client.CoreV1().Pods(metav1.NamespaceAll).List(ctx, opts)
+			},
+		}, nil
+	case Svc:
+		return &consistencyTarget{
+			name:     "service",
+			informer: api.Svc().Informer(),
+			relister: relister,
+			list: func(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+				return api.Client.CoreV1().Services(metav1.NamespaceAll).List(ctx, opts)
+			},
+		}, nil
+	default:
+		return nil, fmt.Errorf("consistency checks are not supported for resource %d", res)
+	}
+}
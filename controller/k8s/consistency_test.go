@@ -0,0 +1,113 @@
+package k8s
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestCheckConsistency(t *testing.T) {
+	api, err := NewFakeAPI(`
+apiVersion: v1
+kind: Service
+metadata:
+  name: present
+  namespace: ns
+  resourceVersion: "1"`, `
+apiVersion: v1
+kind: Service
+metadata:
+  name: missing
+  namespace: ns
+  resourceVersion: "1"`, `
+apiVersion: v1
+kind: Service
+metadata:
+  name: stale
+  namespace: ns
+  resourceVersion: "2"`,
+	)
+	if err != nil {
+		t.Fatalf("NewFakeAPI returned an error: %s", err)
+	}
+
+	var mu sync.Mutex
+	events := map[string]string{}
+	record := func(obj interface{}, event string) {
+		if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+			obj = tombstone.Obj
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		events[obj.(*corev1.Service).Name] = event
+	}
+	api.AddEventHandler(Svc, cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj interface{}) { record(obj, "add") },
+		UpdateFunc: func(_, obj interface{}) { record(obj, "update") },
+		DeleteFunc: func(obj interface{}) { record(obj, "delete") },
+	})
+	api.Sync(nil)
+
+	// The cache is modified by hand to simulate lost events
+	store := api.Svc().Informer().GetStore()
+	if err := store.Delete(&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "missing", Namespace: "ns"}}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for name, version := range map[string]string{"stale": "1", "gone": "1"} {
+		svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "ns", ResourceVersion: version}}
+		if err := store.Update(svc); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+
+	relister := api.consistency.relisters[Svc]
+	if err := api.checkConsistency(context.Background(), Svc); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	relister.Lock()
+	requested := relister.requested
+	relister.Unlock()
+	if requested {
+		t.Fatal("Expected divergences to be ignored on the first check")
+	}
+
+	if err := api.checkConsistency(context.Background(), Svc); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// The informer relists the services, and notifies the handlers of the
+	// differences with its cache
+	expectedEvents := map[string]string{"stale": "update", "gone": "delete"}
+	expectedVersions := map[string]string{"present": "1", "missing": "1", "stale": "2"}
+	consistent := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		for name, event := range expectedEvents {
+			if events[name] != event {
+				return false
+			}
+		}
+		for name, version := range expectedVersions {
+			obj, ok, err := store.GetByKey("ns/" + name)
+			if err != nil || !ok || obj.(*corev1.Service).ResourceVersion != version {
+				return false
+			}
+		}
+		_, ok, _ := store.GetByKey("ns/gone")
+		return !ok
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for !consistent() {
+		if time.Now().After(deadline) {
+			mu.Lock()
+			defer mu.Unlock()
+			t.Fatalf("Expected the cache to be relisted, got events %v", events)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,229 @@
+package k8s
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Index is an enum for the field indexes that can be added to the informers
+// of an API. An index makes the lookups it serves O(1) rather than a scan of
+// the informer's cache, but costs memory proportional to the number of
+// indexed objects, so components only add the indexes needed by the features
+// they have enabled.
+type Index int
+
+// These constants enumerate the field indexes.
+const (
+	// PodIP indexes the pods outside of the host network by pod IP
+	PodIP Index = iota
+	// PodHostIP indexes the pods exposing host ports by hostIP:hostPort
+	PodHostIP
+	// PodOwner indexes the pods by the UID of their owners
+	PodOwner
+	// PodServiceAccount indexes the pods by namespace/service account
+	PodServiceAccount
+	// SvcClusterIP indexes the services by cluster IP
+	SvcClusterIP
+	// NodeIP indexes the nodes by internal IP
+	NodeIP
+)
+
+// The names of the indexes, as registered on the informers' indexers.
+const (
+	IPIndex             = "ip"
+	HostIPIndex         = "hostIP"
+	OwnerIndex          = "owner"
+	ServiceAccountIndex = "serviceAccount"
+)
+
+func (i Index) String() string {
+	switch i {
+	case PodIP:
+		return "pod IP"
+	case PodHostIP:
+		return "pod host IP"
+	case PodOwner:
+		return "pod owner"
+	case PodServiceAccount:
+		return "pod service account"
+	case SvcClusterIP:
+		return "service cluster IP"
+	case NodeIP:
+		return "node IP"
+	default:
+		return fmt.Sprintf("unknown index %d", int(i))
+	}
+}
+
+// AddIndexes adds the given indexes to the informers of the API, which must
+// have been initialized with the indexed resources. It must be called before
+// the informers are started. Adding an index more than once is a no-op.
+func (api *API) AddIndexes(indexes ...Index) error {
+	for _, index := range indexes {
+		if api.HasIndex(index) {
+			continue
+		}
+
+		var informer cache.SharedIndexInformer
+		var name string
+		var indexFunc cache.IndexFunc
+		switch index {
+		case PodIP:
+			informer, name, indexFunc = api.podInformer(), IPIndex, indexPodByIP
+		case PodHostIP:
+			informer, name, indexFunc = api.podInformer(), HostIPIndex, indexPodByHostIP
+		case PodOwner:
+			informer, name, indexFunc = api.podInformer(), OwnerIndex, indexPodByOwner
+		case PodServiceAccount:
+			informer, name, indexFunc = api.podInformer(), ServiceAccountIndex, indexPodByServiceAccount
+		case SvcClusterIP:
+			if api.svc != nil {
+				informer = api.svc.Informer()
+			}
+			name, indexFunc = IPIndex, indexSvcByClusterIP
+		case NodeIP:
+			if api.node != nil {
+				informer = api.node.Informer()
+			}
+			name, indexFunc = IPIndex, indexNodeByIP
+		default:
+			return fmt.Errorf("unknown index %d", int(index))
+		}
+		if informer == nil {
+			return fmt.Errorf("cannot add the %s index: the indexed resource isn't watched", index)
+		}
+
+		if err := informer.AddIndexers(cache.Indexers{name: indexFunc}); err != nil {
+			return fmt.Errorf("could not add the %s index: %s", index, err)
+		}
+		api.indexes[index] = struct{}{}
+	}
+	return nil
+}
+
+// HasIndex returns true if the given index has been added to the informers of
+// the API.
+func (api *API) HasIndex(index Index) bool {
+	_, ok := api.indexes[index]
+	return ok
+}
+
+func (api *API) podInformer() cache.SharedIndexInformer {
+	if api.pod == nil {
+		return nil
+	}
+	return api.pod.Informer()
+}
+
+func indexPodByIP(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return nil, fmt.Errorf("object is not a pod")
+	}
+	// Pods that run in the host network are indexed by the host IP index;
+	// they share their IP with the node and other host network pods.
+	if pod.Spec.HostNetwork {
+		return nil, nil
+	}
+	return []string{pod.Status.PodIP}, nil
+}
+
+func indexPodByHostIP(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return nil, fmt.Errorf("object is not a pod")
+	}
+	var hostIPPods []string
+	if pod.Status.HostIP != "" {
+		// If the pod is reachable from the host network, then for
+		// each of its containers' ports that exposes a host port, add
+		// that hostIP:hostPort endpoint to the indexer.
+		for _, c := range pod.Spec.Containers {
+			for _, p := range c.Ports {
+				if p.HostPort != 0 {
+					addr := fmt.Sprintf("%s:%d", pod.Status.HostIP, p.HostPort)
+					hostIPPods = append(hostIPPods, addr)
+				}
+			}
+		}
+	}
+	return hostIPPods, nil
+}
+
+func indexPodByOwner(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return nil, fmt.Errorf("object is not a pod")
+	}
+	owners := make([]string, 0, len(pod.OwnerReferences))
+	for _, ref := range pod.OwnerReferences {
+		owners = append(owners, string(ref.UID))
+	}
+	return owners, nil
+}
+
+func indexPodByServiceAccount(obj interface{}) ([]string, error) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return nil, fmt.Errorf("object is not a pod")
+	}
+	return []string{serviceAccountKey(pod.Namespace, pod.Spec.ServiceAccountName)}, nil
+}
+
+func indexSvcByClusterIP(obj interface{}) ([]string, error) {
+	svc, ok := obj.(*corev1.Service)
+	if !ok {
+		return nil, fmt.Errorf("object is not a service")
+	}
+	return []string{svc.Spec.ClusterIP}, nil
+}
+
+func indexNodeByIP(obj interface{}) ([]string, error) {
+	node, ok := obj.(*corev1.Node)
+	if !ok {
+		return nil, fmt.Errorf("object is not a node")
+	}
+	addresses := make([]string, 0)
+	for _, address := range node.Status.Addresses {
+		if address.Type == corev1.NodeInternalIP {
+			addresses = append(addresses, address.Address)
+		}
+	}
+	return addresses, nil
+}
+
+func serviceAccountKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// GetPodsByServiceAccount returns the pods of the given namespace running as
+// the given service account. The PodServiceAccount index is used if it has
+// been added, otherwise the pods of the namespace are scanned.
+func (api *API) GetPodsByServiceAccount(namespace, name string) ([]*corev1.Pod, error) {
+	if !api.HasIndex(PodServiceAccount) {
+		pods, err := api.Pod().Lister().Pods(namespace).List(labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+		matching := []*corev1.Pod{}
+		for _, pod := range pods {
+			if pod.Spec.ServiceAccountName == name {
+				matching = append(matching, pod)
+			}
+		}
+		return matching, nil
+	}
+
+	objs, err := api.Pod().Informer().GetIndexer().ByIndex(ServiceAccountIndex, serviceAccountKey(namespace, name))
+	if err != nil {
+		return nil, err
+	}
+	pods := make([]*corev1.Pod, 0, len(objs))
+	for _, obj := range objs {
+		pods = append(pods, obj.(*corev1.Pod))
+	}
+	return pods, nil
+}
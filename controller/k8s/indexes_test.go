@@ -0,0 +1,75 @@
+package k8s
+
+import (
+	"testing"
+)
+
+func TestAddIndexes(t *testing.T) {
+	api, err := NewFakeAPI()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if err := api.AddIndexes(PodIP, PodOwner); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	// adding an index again is a no-op
+	if err := api.AddIndexes(PodOwner); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !api.HasIndex(PodIP) || !api.HasIndex(PodOwner) || api.HasIndex(PodServiceAccount) {
+		t.Fatalf("Unexpected indexes: %v", api.indexes)
+	}
+
+	nodeless := NewAPI(api.Client, nil, nil, Pod)
+	if err := nodeless.AddIndexes(NodeIP); err == nil {
+		t.Fatal("Expected an error adding an index on a resource that isn't watched")
+	}
+}
+
+func TestGetPodsByServiceAccount(t *testing.T) {
+	configs := []string{`
+apiVersion: v1
+kind: Pod
+metadata:
+  name: emoji-1
+  namespace: emojivoto
+spec:
+  serviceAccountName: emoji`, `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web-1
+  namespace: emojivoto
+spec:
+  serviceAccountName: web`, `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: emoji-1
+  namespace: other
+spec:
+  serviceAccountName: emoji`,
+	}
+
+	for _, indexed := range []bool{false, true} {
+		api, err := NewFakeAPI(configs...)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if indexed {
+			if err := api.AddIndexes(PodServiceAccount); err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+		}
+		api.Sync(nil)
+
+		pods, err := api.GetPodsByServiceAccount("emojivoto", "emoji")
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if len(pods) != 1 || pods[0].Name != "emoji-1" || pods[0].Namespace != "emojivoto" {
+			t.Fatalf("Expected pod emojivoto/emoji-1, got %v (indexed: %t)", pods, indexed)
+		}
+	}
+}
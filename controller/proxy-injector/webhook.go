@@ -5,9 +5,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"strings"
+	"sync"
 
 	"github.com/linkerd/linkerd2/controller/k8s"
+	"github.com/linkerd/linkerd2/controller/webhook"
 	"github.com/linkerd/linkerd2/pkg/config"
+	"github.com/linkerd/linkerd2/pkg/featuregates"
 	"github.com/linkerd/linkerd2/pkg/inject"
 	pkgK8s "github.com/linkerd/linkerd2/pkg/k8s"
 	"github.com/linkerd/linkerd2/pkg/version"
@@ -16,6 +19,7 @@ import (
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	k8sversion "k8s.io/apimachinery/pkg/util/version"
 	"k8s.io/client-go/tools/record"
 )
 
@@ -24,11 +28,32 @@ const (
 	eventTypeInjected = "Injected"
 )
 
-// Inject returns an AdmissionResponse containing the patch, if any, to apply
+var (
+	// kubeVersion caches the version of the cluster once it's been retrieved
+	kubeVersion   *k8sversion.Version
+	kubeVersionMu sync.Mutex
+)
+
+// Inject returns the webhook handler injecting the proxy, with the given
+// feature gates. Their overrides from linkerd-config are refreshed from the
+// mounted config on every request.
+func Inject(gates *featuregates.FeatureGates) webhook.Handler {
+	return func(
+		ctx context.Context,
+		api *k8s.API,
+		request *admissionv1beta1.AdmissionRequest,
+		recorder record.EventRecorder,
+	) (*admissionv1beta1.AdmissionResponse, error) {
+		return inject(ctx, api, gates, request, recorder)
+	}
+}
+
+// inject returns an AdmissionResponse containing the patch, if any, to apply
 // to the pod (proxy sidecar and eventually the init container to set it up)
-func Inject(
+func inject(
 	ctx context.Context,
 	api *k8s.API,
+	gates *featuregates.FeatureGates,
 	request *admissionv1beta1.AdmissionRequest,
 	recorder record.EventRecorder,
 ) (*admissionv1beta1.AdmissionResponse, error) {
@@ -47,6 +72,7 @@ func Inject(
 		return nil, err
 	}
 	valuesConfig.IdentityTrustAnchorsPEM = string(caPEM)
+	gates.SetConfigOverrides(valuesConfig.FeatureGates)
 
 	namespace, err := api.NS().Lister().Get(request.Namespace)
 	if err != nil {
@@ -56,7 +82,9 @@ func Inject(
 	resourceConfig := inject.NewResourceConfig(valuesConfig, inject.OriginWebhook).
 		WithOwnerRetriever(ownerRetriever(ctx, api, request.Namespace)).
 		WithNsAnnotations(nsAnnotations).
-		WithKind(request.Kind.Kind)
+		WithKind(request.Kind.Kind).
+		WithKubernetesVersion(serverVersion(api)).
+		WithFeatureGates(gates)
 
 	// Build the injection report.
 	report, err := resourceConfig.ParseMetaAndYAML(request.Object.Raw)
@@ -66,17 +94,19 @@ func Inject(
 	log.Infof("received %s", report.ResName())
 
 	// If the resource has an owner, then it should be retrieved for recording
-	// events.
+	// events. Mirror pods are owned by their node, which isn't retrieved.
 	var parent *runtime.Object
 	var ownerKind string
 	if ownerRef := resourceConfig.GetOwnerRef(); ownerRef != nil {
-		objs, err := api.GetObjects(request.Namespace, ownerRef.Kind, ownerRef.Name, labels.Everything())
-		if err != nil {
-			log.Warnf("couldn't retrieve parent object %s-%s-%s; error: %s", request.Namespace, ownerRef.Kind, ownerRef.Name, err)
-		} else if len(objs) == 0 {
-			log.Warnf("couldn't retrieve parent object %s-%s-%s", request.Namespace, ownerRef.Kind, ownerRef.Name)
-		} else {
-			parent = &objs[0]
+		if !report.StaticPod {
+			objs, err := api.GetObjects(request.Namespace, ownerRef.Kind, ownerRef.Name, labels.Everything())
+			if err != nil {
+				log.Warnf("couldn't retrieve parent object %s-%s-%s; error: %s", request.Namespace, ownerRef.Kind, ownerRef.Name, err)
+			} else if len(objs) == 0 {
+				log.Warnf("couldn't retrieve parent object %s-%s-%s", request.Namespace, ownerRef.Kind, ownerRef.Name)
+			} else {
+				parent = &objs[0]
+			}
 		}
 		ownerKind = strings.ToLower(ownerRef.Kind)
 	}
@@ -114,8 +144,9 @@ func Inject(
 
 	// If the resource is not injectable but does need the opaque ports
 	// annotation added, then admit it after creating a patch that adds the
-	// annotation.
-	if opaquePorts, opaquePortsOk := resourceConfig.GetConfigAnnotation(pkgK8s.ProxyOpaquePortsAnnotation); opaquePortsOk {
+	// annotation. Mirror pods are never patched, as the kubelet wouldn't apply
+	// the change to the static pod.
+	if opaquePorts, opaquePortsOk := resourceConfig.GetConfigAnnotation(pkgK8s.ProxyOpaquePortsAnnotation); opaquePortsOk && !report.StaticPod {
 		patchJSON, err := resourceConfig.CreateAnnotationPatch(opaquePorts)
 		if err != nil {
 			return nil, err
@@ -164,3 +195,26 @@ func ownerRetriever(ctx context.Context, api *k8s.API, ns string) inject.OwnerRe
 		return api.GetOwnerKindAndName(ctx, p, true)
 	}
 }
+
+// serverVersion returns the version of the cluster, or nil if it can't be
+// retrieved, in which case it's retried on the next request
+func serverVersion(api *k8s.API) *k8sversion.Version {
+	kubeVersionMu.Lock()
+	defer kubeVersionMu.Unlock()
+	if kubeVersion != nil {
+		return kubeVersion
+	}
+
+	info, err := api.Client.Discovery().ServerVersion()
+	if err != nil {
+		log.Warnf("failed to retrieve the version of the cluster: %s", err)
+		return nil
+	}
+	v, err := k8sversion.ParseGeneric(info.GitVersion)
+	if err != nil {
+		log.Warnf("invalid version of the cluster %s: %s", info.GitVersion, err)
+		return nil
+	}
+	kubeVersion = v
+	return kubeVersion
+}
@@ -0,0 +1,124 @@
+package validator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/linkerd/linkerd2/controller/k8s"
+	"github.com/linkerd/linkerd2/controller/webhook"
+	pkgk8s "github.com/linkerd/linkerd2/pkg/k8s"
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	authnv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+// Modes of the protection of the mirrored resources against the manual
+// changes, which are overwritten by the service mirror or cause drift.
+const (
+	// MirrorProtectionWarn admits the changes with a warning
+	MirrorProtectionWarn = "warn"
+	// MirrorProtectionReject rejects the changes, except the ones of the
+	// cluster admins, which are admitted with a warning
+	MirrorProtectionReject = "reject"
+	// MirrorProtectionOff admits the changes silently
+	MirrorProtectionOff = "off"
+
+	serviceMirrorAccountPrefix = "linkerd-service-mirror-"
+	serviceAccountUserPrefix   = "system:serviceaccount:"
+	clusterAdminsGroup         = "system:masters"
+)
+
+// mirrorExemptUsers are the Kubernetes controllers that delete the mirrored
+// resources along with their namespace or owner.
+var mirrorExemptUsers = map[string]struct{}{
+	"system:serviceaccount:kube-system:generic-garbage-collector": {},
+	"system:serviceaccount:kube-system:namespace-controller":      {},
+}
+
+// Admit returns the handler of the validator's admission requests, which
+// validates the ServiceProfiles and protects the mirrored Services and
+// Endpoints according to the given mode.
+func Admit(mirrorProtection string) (webhook.Handler, error) {
+	switch mirrorProtection {
+	case MirrorProtectionWarn, MirrorProtectionReject, MirrorProtectionOff:
+	default:
+		return nil, fmt.Errorf("invalid mirror protection '%s', must be one of: %s, %s, %s", mirrorProtection, MirrorProtectionWarn, MirrorProtectionReject, MirrorProtectionOff)
+	}
+
+	return func(
+		ctx context.Context, api *k8s.API, request *admissionv1beta1.AdmissionRequest, recorder record.EventRecorder,
+	) (*admissionv1beta1.AdmissionResponse, error) {
+		if request.Kind.Group == "" && (request.Kind.Kind == "Service" || request.Kind.Kind == "Endpoints") {
+			return admitMirror(request, mirrorProtection)
+		}
+		return AdmitSP(ctx, api, request, recorder)
+	}, nil
+}
+
+// admitMirror handles the changes of the Services and Endpoints labeled as
+// mirrored. Only the service mirrors and the Kubernetes controllers deleting
+// them are expected to change them.
+func admitMirror(request *admissionv1beta1.AdmissionRequest, mode string) (*admissionv1beta1.AdmissionResponse, error) {
+	response := &admissionv1beta1.AdmissionResponse{
+		UID:     request.UID,
+		Allowed: true,
+	}
+	if mode == MirrorProtectionOff || isMirrorWriter(request.UserInfo) {
+		return response, nil
+	}
+
+	// the labels of the existing resource tell whether it's a mirror, as an
+	// update could remove them
+	raw := request.OldObject.Raw
+	if len(raw) == 0 {
+		raw = request.Object.Raw
+	}
+	var meta metav1.PartialObjectMetadata
+	if err := json.Unmarshal(raw, &meta); err != nil {
+		return nil, fmt.Errorf("failed to decode %s %s/%s: %s", request.Kind.Kind, request.Namespace, request.Name, err)
+	}
+	if meta.Labels[pkgk8s.MirroredResourceLabel] != "true" {
+		return response, nil
+	}
+
+	change := "edits"
+	if request.Operation == admissionv1beta1.Delete {
+		change = "deletions"
+	}
+	message := fmt.Sprintf("%s %s/%s is mirrored from cluster %s by the service mirror: its manual %s are overwritten or cause drift; change the exported service in the target cluster instead",
+		request.Kind.Kind, request.Namespace, request.Name, meta.Labels[pkgk8s.RemoteClusterNameLabel], change)
+
+	if mode == MirrorProtectionReject && !isClusterAdmin(request.UserInfo) {
+		response.Allowed = false
+		response.Result = &metav1.Status{Message: message, Code: 403}
+		return response, nil
+	}
+	response.Warnings = []string{message}
+	return response, nil
+}
+
+// isMirrorWriter returns whether the user is a service mirror, or a
+// Kubernetes controller deleting the mirrors.
+func isMirrorWriter(user authnv1.UserInfo) bool {
+	if _, ok := mirrorExemptUsers[user.Username]; ok {
+		return true
+	}
+	if !strings.HasPrefix(user.Username, serviceAccountUserPrefix) {
+		return false
+	}
+	// system:serviceaccount:<namespace>:<name>
+	parts := strings.Split(strings.TrimPrefix(user.Username, serviceAccountUserPrefix), ":")
+	return len(parts) == 2 && strings.HasPrefix(parts[1], serviceMirrorAccountPrefix)
+}
+
+func isClusterAdmin(user authnv1.UserInfo) bool {
+	for _, group := range user.Groups {
+		if group == clusterAdminsGroup {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package validator
+
+import (
+	"context"
+	"testing"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	authnv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const mirrorService = `{
+  "apiVersion": "v1",
+  "kind": "Service",
+  "metadata": {
+    "name": "web-svc-east",
+    "namespace": "emojivoto",
+    "labels": {
+      "mirror.linkerd.io/mirrored-service": "true",
+      "mirror.linkerd.io/cluster-name": "east"
+    }
+  }
+}`
+
+func TestAdmitMirror(t *testing.T) {
+	admin := authnv1.UserInfo{Username: "kubernetes-admin", Groups: []string{"system:masters", "system:authenticated"}}
+	developer := authnv1.UserInfo{Username: "developer", Groups: []string{"system:authenticated"}}
+	serviceMirror := authnv1.UserInfo{Username: "system:serviceaccount:linkerd-multicluster:linkerd-service-mirror-east"}
+	garbageCollector := authnv1.UserInfo{Username: "system:serviceaccount:kube-system:generic-garbage-collector"}
+
+	for _, tc := range []struct {
+		name      string
+		mode      string
+		user      authnv1.UserInfo
+		operation admissionv1beta1.Operation
+		allowed   bool
+		warned    bool
+	}{
+		{name: "service mirror", mode: MirrorProtectionReject, user: serviceMirror, operation: admissionv1beta1.Update, allowed: true},
+		{name: "garbage collector", mode: MirrorProtectionReject, user: garbageCollector, operation: admissionv1beta1.Delete, allowed: true},
+		{name: "warned edit", mode: MirrorProtectionWarn, user: developer, operation: admissionv1beta1.Update, allowed: true, warned: true},
+		{name: "rejected edit", mode: MirrorProtectionReject, user: developer, operation: admissionv1beta1.Update},
+		{name: "rejected deletion", mode: MirrorProtectionReject, user: developer, operation: admissionv1beta1.Delete},
+		{name: "cluster admin", mode: MirrorProtectionReject, user: admin, operation: admissionv1beta1.Delete, allowed: true, warned: true},
+		{name: "protection off", mode: MirrorProtectionOff, user: developer, operation: admissionv1beta1.Update, allowed: true},
+	} {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			handler, err := Admit(tc.mode)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			request := &admissionv1beta1.AdmissionRequest{
+				Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "Service"},
+				Namespace: "emojivoto",
+				Name:      "web-svc-east",
+				Operation: tc.operation,
+				UserInfo:  tc.user,
+				OldObject: runtime.RawExtension{Raw: []byte(mirrorService)},
+			}
+			if tc.operation == admissionv1beta1.Update {
+				request.Object = runtime.RawExtension{Raw: []byte(mirrorService)}
+			}
+
+			response, err := handler(context.Background(), nil, request, nil)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if response.Allowed != tc.allowed {
+				t.Errorf("Expected allowed to be %t, got %+v", tc.allowed, response)
+			}
+			if warned := len(response.Warnings) > 0; warned != tc.warned {
+				t.Errorf("Expected warned to be %t, got %v", tc.warned, response.Warnings)
+			}
+		})
+	}
+}
+
+func TestAdmitInvalidMirrorProtection(t *testing.T) {
+	if _, err := Admit("block"); err == nil {
+		t.Error("Expected an invalid mirror protection to be rejected")
+	}
+}
@@ -9,16 +9,19 @@ import (
 
 	"github.com/linkerd/linkerd2/controller/k8s"
 	"github.com/linkerd/linkerd2/pkg/admin"
+	"github.com/linkerd/linkerd2/pkg/health"
 	pkgk8s "github.com/linkerd/linkerd2/pkg/k8s"
 	log "github.com/sirupsen/logrus"
 )
 
-// Launch sets up and starts the webhook and metrics servers
+// Launch sets up and starts the webhook and metrics servers. When the webhook
+// is part of an extension, its health is reported by the metrics server.
 func Launch(
 	ctx context.Context,
 	APIResources []k8s.APIResource,
 	handler Handler,
 	component,
+	extension,
 	metricsAddr string,
 	addr string,
 	kubeconfig string,
@@ -39,8 +42,18 @@ func Launch(
 
 	k8sAPI.Sync(nil)
 
+	var reporter *health.Reporter
+	if extension != "" {
+		reporter = health.NewReporter(extension, component)
+		reporter.AddCheck("kubernetes-api", func(context.Context) error {
+			_, err := k8sAPI.Client.Discovery().ServerVersion()
+			return err
+		})
+		reporter.AddCheck("webhook-certificate", s.CheckCertificate)
+	}
+
 	go s.Start()
-	go admin.StartServer(metricsAddr)
+	go admin.StartServerWithHealth(metricsAddr, reporter)
 
 	<-stop
 	log.Info("shutting down webhook server")
@@ -3,10 +3,14 @@ package webhook
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"net/http"
 	"sync/atomic"
+	"time"
 
 	"github.com/linkerd/linkerd2/controller/k8s"
 	pkgk8s "github.com/linkerd/linkerd2/pkg/k8s"
@@ -115,6 +119,23 @@ func (s *Server) getCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error
 	return s.certValue.Load().(*tls.Certificate), nil
 }
 
+// CheckCertificate returns an error if the server's current certificate is
+// missing or expired.
+func (s *Server) CheckCertificate(_ context.Context) error {
+	cert, ok := s.certValue.Load().(*tls.Certificate)
+	if !ok || cert == nil || len(cert.Certificate) == 0 {
+		return errors.New("no certificate loaded")
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return fmt.Errorf("invalid certificate: %s", err)
+	}
+	if now := time.Now(); now.After(leaf.NotAfter) {
+		return fmt.Errorf("certificate expired on %s", leaf.NotAfter.Format(time.RFC3339))
+	}
+	return nil
+}
+
 func (s *Server) serve(res http.ResponseWriter, req *http.Request) {
 	var (
 		data []byte
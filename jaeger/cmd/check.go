@@ -206,9 +206,7 @@ code.`,
 	cmd.Flags().BoolVar(&options.proxy, "proxy", options.proxy, "Also run data-plane checks, to determine if the data plane is healthy")
 	cmd.Flags().StringVarP(&options.namespace, "namespace", "n", options.namespace, "Namespace to use for --proxy checks (default: all namespaces)")
 
-	pkgcmd.ConfigureNamespaceFlagCompletion(
-		cmd, []string{"namespace"},
-		kubeconfigPath, impersonate, impersonateGroup, kubeContext)
+	pkgcmd.ConfigureNamespaceFlagCompletion(cmd, []string{"namespace"})
 	pkgcmd.ConfigureOutputFlagCompletion(cmd)
 
 	return cmd
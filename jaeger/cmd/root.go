@@ -58,10 +58,7 @@ func NewCmdJaeger() *cobra.Command {
 	}
 
 	jaegerCmd.PersistentFlags().StringVarP(&controlPlaneNamespace, "linkerd-namespace", "L", defaultLinkerdNamespace, "Namespace in which Linkerd is installed")
-	jaegerCmd.PersistentFlags().StringVar(&kubeconfigPath, "kubeconfig", "", "Path to the kubeconfig file to use for CLI requests")
-	jaegerCmd.PersistentFlags().StringVar(&kubeContext, "context", "", "Name of the kubeconfig context to use")
-	jaegerCmd.PersistentFlags().StringVar(&impersonate, "as", "", "Username to impersonate for Kubernetes operations")
-	jaegerCmd.PersistentFlags().StringArrayVar(&impersonateGroup, "as-group", []string{}, "Group to impersonate for Kubernetes operations")
+	pkgcmd.AddKubeFlags(jaegerCmd.PersistentFlags(), &kubeconfigPath, &kubeContext, &impersonate, &impersonateGroup)
 	jaegerCmd.PersistentFlags().StringVar(&apiAddr, "api-addr", "", "Override kubeconfig and communicate directly with the control plane at host:port (mostly for testing)")
 	jaegerCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "Turn on debug logging")
 	jaegerCmd.AddCommand(NewCmdCheck())
@@ -71,11 +68,9 @@ func NewCmdJaeger() *cobra.Command {
 	jaegerCmd.AddCommand(newCmdUninstall())
 
 	// resource-aware completion flag configurations
-	pkgcmd.ConfigureNamespaceFlagCompletion(
-		jaegerCmd, []string{"linkerd-namespace"},
-		kubeconfigPath, impersonate, impersonateGroup, kubeContext)
+	pkgcmd.ConfigureNamespaceFlagCompletion(jaegerCmd, []string{"linkerd-namespace"})
 
-	pkgcmd.ConfigureKubeContextFlagCompletion(jaegerCmd, kubeconfigPath)
+	pkgcmd.ConfigureKubeContextFlagCompletion(jaegerCmd)
 	return jaegerCmd
 }
 
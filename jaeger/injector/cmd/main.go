@@ -30,6 +30,7 @@ func main() {
 		[]k8s.APIResource{k8s.NS},
 		mutator.Mutate(*collectorSvcAddr, *collectorSvcAccount),
 		"linkerd-jaeger-injector",
+		"jaeger",
 		*metricsAddr,
 		*addr,
 		*kubeconfig,
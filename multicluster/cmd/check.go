@@ -17,9 +17,6 @@ import (
 	"github.com/linkerd/linkerd2/pkg/servicemirror"
 	"github.com/linkerd/linkerd2/pkg/tls"
 	"github.com/linkerd/linkerd2/pkg/version"
-	vizCmd "github.com/linkerd/linkerd2/viz/cmd"
-	"github.com/linkerd/linkerd2/viz/metrics-api/client"
-	vizPb "github.com/linkerd/linkerd2/viz/metrics-api/gen/viz"
 	"github.com/spf13/cobra"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -37,21 +34,32 @@ const (
 	// linkerdMulticlusterExtensionCheck adds checks related to the multicluster extension
 	linkerdMulticlusterExtensionCheck healthcheck.CategoryID = "linkerd-multicluster"
 
-	linkerdServiceMirrorServiceAccountName = "linkerd-service-mirror-%s"
+	// the names of the service mirror resources are suffixed with the name of
+	// the target cluster, unless the service mirror mirrors all the links of
+	// its namespace
+	linkerdServiceMirrorServiceAccountName = "linkerd-service-mirror%s"
 	linkerdServiceMirrorComponentName      = "service-mirror"
-	linkerdServiceMirrorClusterRoleName    = "linkerd-service-mirror-access-local-resources-%s"
-	linkerdServiceMirrorRoleName           = "linkerd-service-mirror-read-remote-creds-%s"
+	linkerdServiceMirrorClusterRoleName    = "linkerd-service-mirror-access-local-resources%s"
+	linkerdServiceMirrorRoleName           = "linkerd-service-mirror-read-remote-creds%s"
 )
 
 type checkOptions struct {
-	wait   time.Duration
-	output string
+	wait    time.Duration
+	output  string
+	pre     bool
+	preLink preLinkOptions
 }
 
 func newCheckOptions() *checkOptions {
 	return &checkOptions{
 		wait:   300 * time.Second,
 		output: healthcheck.TableOutput,
+		preLink: preLinkOptions{
+			gatewayName:        defaultGatewayName,
+			gatewayNamespace:   defaultMulticlusterNamespace,
+			serviceAccountName: defaultServiceAccountName,
+			namespace:          defaultMulticlusterNamespace,
+		},
 	}
 }
 
@@ -59,6 +67,9 @@ func (options *checkOptions) validate() error {
 	if options.output != healthcheck.TableOutput && options.output != healthcheck.JSONOutput {
 		return fmt.Errorf("Invalid output type '%s'. Supported output types are: %s, %s", options.output, healthcheck.JSONOutput, healthcheck.TableOutput)
 	}
+	if options.pre {
+		return options.preLink.validate()
+	}
 	return nil
 }
 
@@ -86,9 +97,16 @@ func NewCmdCheck() *cobra.Command {
 The check command will perform a series of checks to validate that the
 multicluster extension is configured correctly. If the command encounters a
 failure it will print additional information about the failure and exit with a
-non-zero exit code.`,
+non-zero exit code.
+
+With --pre, the command instead verifies that the cluster the given
+--target-kubeconfig points to can be linked to the current one, before any
+Link is created.`,
 		Example: `  # Check that the multicluster extension is configured correctly
-  linkerd multicluster check`,
+  linkerd multicluster check
+
+  # Check that the east cluster can be linked to the west cluster
+  linkerd --context=west multicluster check --pre --target-kubeconfig east.kubeconfig`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Get the multicluster extension namespace
 			kubeAPI, err := k8s.NewAPI(kubeconfigPath, kubeContext, impersonate, impersonateGroup, 0)
@@ -103,14 +121,18 @@ non-zero exit code.`,
 	}
 	cmd.Flags().StringVarP(&options.output, "output", "o", options.output, "Output format. One of: basic, json")
 	cmd.Flags().DurationVar(&options.wait, "wait", options.wait, "Maximum allowed time for all tests to pass")
+	cmd.Flags().BoolVar(&options.pre, "pre", options.pre, "Only run pre-link checks, to determine if the target cluster can be linked")
+	cmd.Flags().StringVar(&options.preLink.targetKubeconfig, "target-kubeconfig", "", "Path to the kubeconfig of the target cluster, used with --pre")
+	cmd.Flags().StringVar(&options.preLink.targetContext, "target-context", "", "Context of the target cluster kubeconfig to use, used with --pre")
+	cmd.Flags().StringVar(&options.preLink.gatewayName, "gateway-name", options.preLink.gatewayName, "The name of the gateway service in the target cluster, used with --pre")
+	cmd.Flags().StringVar(&options.preLink.gatewayNamespace, "gateway-namespace", options.preLink.gatewayNamespace, "The namespace of the gateway service in the target cluster, used with --pre")
+	cmd.Flags().StringVar(&options.preLink.serviceAccountName, "service-account-name", options.preLink.serviceAccountName, "The name of the service account the Link will use to access the target cluster, used with --pre")
 	cmd.Flags().Bool("proxy", false, "")
 	cmd.Flags().MarkHidden("proxy")
 	cmd.Flags().StringP("namespace", "n", "", "")
 	cmd.Flags().MarkHidden("namespace")
 
-	pkgcmd.ConfigureNamespaceFlagCompletion(
-		cmd, []string{"namespace"},
-		kubeconfigPath, impersonate, impersonateGroup, kubeContext)
+	pkgcmd.ConfigureNamespaceFlagCompletion(cmd, []string{"namespace"})
 	pkgcmd.ConfigureOutputFlagCompletion(cmd)
 
 	return cmd
@@ -124,6 +146,9 @@ func configureAndRunChecks(wout io.Writer, werr io.Writer, options *checkOptions
 	checks := []healthcheck.CategoryID{
 		linkerdMulticlusterExtensionCheck,
 	}
+	if options.pre {
+		checks = []healthcheck.CategoryID{linkerdMulticlusterPreLinkCheck}
+	}
 	linkerdHC := healthcheck.NewHealthChecker(checks, &healthcheck.Options{
 		ControlPlaneNamespace: controlPlaneNamespace,
 		KubeConfig:            kubeconfigPath,
@@ -149,8 +174,11 @@ func configureAndRunChecks(wout io.Writer, werr io.Writer, options *checkOptions
 	}
 
 	hc := newHealthChecker(linkerdHC)
-	category := multiclusterCategory(hc)
-	hc.AppendCategories(category)
+	if options.pre {
+		hc.AppendCategories(preLinkCategory(&preLinkChecker{healthChecker: hc, options: &options.preLink}))
+	} else {
+		hc.AppendCategories(multiclusterCategory(hc))
+	}
 	success := healthcheck.RunChecks(wout, werr, hc, options.output)
 	if !success {
 		os.Exit(1)
@@ -178,7 +206,11 @@ func multiclusterCategory(hc *healthChecker) *healthcheck.Category {
 		*healthcheck.NewChecker("clusters share trust anchors").
 			WithHintAnchor("l5d-multicluster-clusters-share-anchors").
 			WithCheck(func(ctx context.Context) error {
-				localAnchors, err := tls.DecodePEMCertificates(hc.LinkerdConfig().IdentityTrustAnchorsPEM)
+				localAnchorsPEM, err := healthcheck.FetchTrustAnchors(ctx, hc.KubeAPIClient(), hc.ControlPlaneNamespace, hc.LinkerdConfig())
+				if err != nil {
+					return fmt.Errorf("Cannot fetch source trust anchors: %s", err)
+				}
+				localAnchors, err := tls.DecodePEMCertificates(localAnchorsPEM)
 				if err != nil {
 					return fmt.Errorf("Cannot parse source trust anchors: %s", err)
 				}
@@ -404,7 +436,12 @@ func (hc *healthChecker) checkRemoteClusterAnchors(ctx context.Context, localAnc
 			errors = append(errors, fmt.Sprintf("* %s: unable to fetch anchors: %s", link.TargetClusterName, err))
 			continue
 		}
-		remoteAnchors, err := tls.DecodePEMCertificates(values.IdentityTrustAnchorsPEM)
+		remoteAnchorsPEM, err := healthcheck.FetchTrustAnchors(ctx, remoteAPI, link.TargetClusterLinkerdNamespace, values)
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("* %s: unable to fetch anchors: %s", link.TargetClusterName, err))
+			continue
+		}
+		remoteAnchors, err := tls.DecodePEMCertificates(remoteAnchorsPEM)
 		if err != nil {
 			errors = append(errors, fmt.Sprintf("* %s: cannot parse trust anchors", link.TargetClusterName))
 			continue
@@ -442,12 +479,16 @@ func (hc *healthChecker) checkServiceMirrorLocalRBAC(ctx context.Context) error
 	links := []string{}
 	errors := []string{}
 	for _, link := range hc.links {
-		err := healthcheck.CheckServiceAccounts(
+		suffix, selector, err := hc.serviceMirrorOf(ctx, link)
+		if err != nil {
+			return err
+		}
+		err = healthcheck.CheckServiceAccounts(
 			ctx,
 			hc.KubeAPIClient(),
-			[]string{fmt.Sprintf(linkerdServiceMirrorServiceAccountName, link.TargetClusterName)},
+			[]string{fmt.Sprintf(linkerdServiceMirrorServiceAccountName, suffix)},
 			link.Namespace,
-			serviceMirrorComponentsSelector(link.TargetClusterName),
+			selector,
 		)
 		if err != nil {
 			errors = append(errors, err.Error())
@@ -456,8 +497,8 @@ func (hc *healthChecker) checkServiceMirrorLocalRBAC(ctx context.Context) error
 			ctx,
 			hc.KubeAPIClient(),
 			true,
-			[]string{fmt.Sprintf(linkerdServiceMirrorClusterRoleName, link.TargetClusterName)},
-			serviceMirrorComponentsSelector(link.TargetClusterName),
+			[]string{fmt.Sprintf(linkerdServiceMirrorClusterRoleName, suffix)},
+			selector,
 		)
 		if err != nil {
 			errors = append(errors, err.Error())
@@ -466,8 +507,8 @@ func (hc *healthChecker) checkServiceMirrorLocalRBAC(ctx context.Context) error
 			ctx,
 			hc.KubeAPIClient(),
 			true,
-			[]string{fmt.Sprintf(linkerdServiceMirrorClusterRoleName, link.TargetClusterName)},
-			serviceMirrorComponentsSelector(link.TargetClusterName),
+			[]string{fmt.Sprintf(linkerdServiceMirrorClusterRoleName, suffix)},
+			selector,
 		)
 		if err != nil {
 			errors = append(errors, err.Error())
@@ -477,8 +518,8 @@ func (hc *healthChecker) checkServiceMirrorLocalRBAC(ctx context.Context) error
 			hc.KubeAPIClient(),
 			true,
 			link.Namespace,
-			[]string{fmt.Sprintf(linkerdServiceMirrorRoleName, link.TargetClusterName)},
-			serviceMirrorComponentsSelector(link.TargetClusterName),
+			[]string{fmt.Sprintf(linkerdServiceMirrorRoleName, suffix)},
+			selector,
 		)
 		if err != nil {
 			errors = append(errors, err.Error())
@@ -488,8 +529,8 @@ func (hc *healthChecker) checkServiceMirrorLocalRBAC(ctx context.Context) error
 			hc.KubeAPIClient(),
 			true,
 			link.Namespace,
-			[]string{fmt.Sprintf(linkerdServiceMirrorRoleName, link.TargetClusterName)},
-			serviceMirrorComponentsSelector(link.TargetClusterName),
+			[]string{fmt.Sprintf(linkerdServiceMirrorRoleName, suffix)},
+			selector,
 		)
 		if err != nil {
 			errors = append(errors, err.Error())
@@ -509,8 +550,12 @@ func (hc *healthChecker) checkServiceMirrorController(ctx context.Context) error
 	errors := []error{}
 	clusterNames := []string{}
 	for _, link := range hc.links {
+		_, selector, err := hc.serviceMirrorOf(ctx, link)
+		if err != nil {
+			return err
+		}
 		options := metav1.ListOptions{
-			LabelSelector: serviceMirrorComponentsSelector(link.TargetClusterName),
+			LabelSelector: selector,
 		}
 		result, err := hc.KubeAPIClient().AppsV1().Deployments(corev1.NamespaceAll).List(ctx, options)
 		if err != nil {
@@ -562,38 +607,21 @@ func (hc *healthChecker) checkIfGatewayMirrorsHaveEndpoints(ctx context.Context)
 			continue
 		}
 
-		vizNs, err := hc.KubeAPIClient().GetNamespaceWithExtensionLabel(ctx, vizCmd.ExtensionName)
-		if err != nil {
-			return &healthcheck.SkipError{Reason: "failed to fetch gateway metrics"}
-		}
-
-		// Check gateway liveness according to probes
-		vizClient, err := client.NewExternalClient(ctx, vizNs.Name, hc.KubeAPIClient())
+		// Check gateway liveness according to the probes reported by the
+		// service mirror in the Link status, probing the gateway directly
+		// when the service mirror stopped reporting them
+		alive, err := multicluster.GetLinkGatewayAlive(ctx, hc.KubeAPIClient().DynamicClient, link.Namespace, link.Name)
 		if err != nil {
-			errors = append(errors, fmt.Errorf("failed to initialize viz client: %s", err))
-			break
-		}
-		req := vizPb.GatewaysRequest{
-			TimeWindow:        "1m",
-			RemoteClusterName: link.TargetClusterName,
-		}
-		rsp, err := vizClient.Gateways(ctx, &req)
-		if err != nil {
-			errors = append(errors, fmt.Errorf("failed to fetch gateway metrics for %s.%s: %s", svc.Name, svc.Namespace, err))
-			continue
-		}
-		table := rsp.GetOk().GetGatewaysTable()
-		if table == nil {
-			errors = append(errors, fmt.Errorf("failed to fetch gateway metrics for %s.%s: %s", svc.Name, svc.Namespace, rsp.GetError().GetError()))
-			continue
-		}
-		if len(table.Rows) != 1 {
-			errors = append(errors, fmt.Errorf("wrong number of (%d) gateway metrics entries for %s.%s", len(table.Rows), svc.Name, svc.Namespace))
+			errors = append(errors, fmt.Errorf("failed to fetch the status of Link %s: %s", link.Name, err))
 			continue
 		}
-		row := table.Rows[0]
-		if !row.Alive {
-			errors = append(errors, fmt.Errorf("liveness checks failed for %s", link.TargetClusterName))
+		if alive == nil || alive.Stale(time.Now(), link.GatewayAliveMaxAge()) {
+			if err := link.ProbeGateway(ctx); err != nil {
+				errors = append(errors, fmt.Errorf("liveness checks failed for %s (the %s condition of the Link is missing or stale): %s", link.TargetClusterName, multicluster.LinkConditionGatewayAlive, err))
+				continue
+			}
+		} else if alive.Status != metav1.ConditionTrue {
+			errors = append(errors, fmt.Errorf("liveness checks failed for %s: %s", link.TargetClusterName, alive.Message))
 			continue
 		}
 		links = append(links, fmt.Sprintf("\t* %s", link.TargetClusterName))
@@ -677,3 +705,36 @@ func serviceMirrorComponentsSelector(targetCluster string) string {
 		k8s.ControllerComponentLabel, linkerdServiceMirrorComponentName,
 		k8s.RemoteClusterNameLabel, targetCluster)
 }
+
+// allLinksServiceMirrorSelector selects the resources of the service mirror
+// mirroring all the links of its namespace, which aren't labeled with a
+// target cluster.
+func allLinksServiceMirrorSelector() string {
+	return fmt.Sprintf("%s=%s,!%s",
+		k8s.ControllerComponentLabel, linkerdServiceMirrorComponentName,
+		k8s.RemoteClusterNameLabel)
+}
+
+// serviceMirrorOf returns the suffix of the names of the resources of the
+// service mirror of the given link, and their label selector. The link is
+// mirrored by its own service mirror or, if it doesn't have one, by the
+// service mirror mirroring all the links of its namespace, when there is one.
+func (hc *healthChecker) serviceMirrorOf(ctx context.Context, link multicluster.Link) (string, string, error) {
+	suffix, selector := fmt.Sprintf("-%s", link.TargetClusterName), serviceMirrorComponentsSelector(link.TargetClusterName)
+	deployments := hc.KubeAPIClient().AppsV1().Deployments(link.Namespace)
+	own, err := deployments.List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return "", "", err
+	}
+	if len(own.Items) > 0 {
+		return suffix, selector, nil
+	}
+	shared, err := deployments.List(ctx, metav1.ListOptions{LabelSelector: allLinksServiceMirrorSelector()})
+	if err != nil {
+		return "", "", err
+	}
+	if len(shared.Items) > 0 {
+		return "", allLinksServiceMirrorSelector(), nil
+	}
+	return suffix, selector, nil
+}
@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/linkerd/linkerd2/pkg/healthcheck"
+	"github.com/linkerd/linkerd2/pkg/k8s"
+	mc "github.com/linkerd/linkerd2/pkg/multicluster"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// linkerdMulticlusterPreLinkCheck adds checks verifying that a target
+// cluster can be linked, before any Link is created
+const linkerdMulticlusterPreLinkCheck healthcheck.CategoryID = "linkerd-multicluster-pre-link"
+
+// preLinkOptions locates the target cluster and its gateway for the pre-link
+// checks.
+type preLinkOptions struct {
+	targetKubeconfig   string
+	targetContext      string
+	gatewayName        string
+	gatewayNamespace   string
+	serviceAccountName string
+	namespace          string
+}
+
+func (options *preLinkOptions) validate() error {
+	if options.targetKubeconfig == "" {
+		return errors.New("--target-kubeconfig is required with --pre")
+	}
+	return nil
+}
+
+// preLinkChecker holds the state shared by the pre-link checks.
+type preLinkChecker struct {
+	*healthChecker
+	options   *preLinkOptions
+	targetAPI *k8s.KubernetesAPI
+	gateway   *corev1.Service
+}
+
+func preLinkCategory(hc *preLinkChecker) *healthcheck.Category {
+	checkers := []healthcheck.Checker{}
+	checkers = append(checkers,
+		*healthcheck.NewChecker("target cluster API is reachable").
+			WithHintAnchor("l5d-multicluster-pre-link-target-api").
+			Fatal().
+			WithCheck(func(ctx context.Context) error { return hc.checkTargetAPI(ctx) }))
+	checkers = append(checkers,
+		*healthcheck.NewChecker("target cluster credentials can generate a Link").
+			WithHintAnchor("l5d-multicluster-pre-link-target-rbac").
+			WithCheck(func(ctx context.Context) error { return hc.checkLinkPermissions(ctx) }))
+	checkers = append(checkers,
+		*healthcheck.NewChecker("service mirror remote access has required permissions").
+			WithHintAnchor("l5d-multicluster-pre-link-remote-access-rbac").
+			WithCheck(func(ctx context.Context) error { return hc.checkRemoteAccessPermissions(ctx) }))
+	checkers = append(checkers,
+		*healthcheck.NewChecker("gateway service is valid").
+			WithHintAnchor("l5d-multicluster-pre-link-gateway-service").
+			Fatal().
+			WithCheck(func(ctx context.Context) error { return hc.checkGatewayService(ctx) }))
+	checkers = append(checkers,
+		*healthcheck.NewChecker("gateway service has ingress addresses").
+			WithHintAnchor("l5d-multicluster-pre-link-gateway-addresses").
+			Warning().
+			WithCheck(func(ctx context.Context) error { return hc.checkGatewayAddresses() }))
+	checkers = append(checkers,
+		*healthcheck.NewChecker("gateway probe endpoint is reachable").
+			WithHintAnchor("l5d-multicluster-pre-link-gateway-probe").
+			WithCheck(func(ctx context.Context) error { return hc.checkGatewayProbe(ctx) }))
+	checkers = append(checkers,
+		*healthcheck.NewChecker("no clock skew with the target cluster").
+			WithHintAnchor("l5d-multicluster-pre-link-clock-skew").
+			Warning().
+			WithCheck(func(ctx context.Context) error { return hc.checkTargetClockSkew(ctx) }))
+
+	return healthcheck.NewCategory(linkerdMulticlusterPreLinkCheck, checkers, true)
+}
+
+func (hc *preLinkChecker) checkTargetAPI(ctx context.Context) error {
+	api, err := k8s.NewAPI(hc.options.targetKubeconfig, hc.options.targetContext, "", []string{}, healthcheck.RequestTimeout)
+	if err != nil {
+		return fmt.Errorf("could not instantiate api for target cluster: %s", err)
+	}
+	// We use this call just to check connectivity.
+	if _, err := api.Discovery().ServerVersion(); err != nil {
+		return fmt.Errorf("failed to connect to the target cluster API: %s", err)
+	}
+	hc.targetAPI = api
+	return nil
+}
+
+// checkLinkPermissions verifies that the target cluster credentials can read
+// the resources `linkerd multicluster link` reads to generate the Link.
+func (hc *preLinkChecker) checkLinkPermissions(ctx context.Context) error {
+	actions := []struct {
+		namespace string
+		resource  string
+	}{
+		{hc.options.namespace, "serviceaccounts"},
+		{hc.options.namespace, "secrets"},
+		{hc.options.gatewayNamespace, "services"},
+		{controlPlaneNamespace, "configmaps"},
+	}
+	errs := []error{}
+	for _, action := range actions {
+		if err := healthcheck.CheckCanPerformAction(ctx, hc.targetAPI, "get", action.namespace, "", "v1", action.resource); err != nil {
+			errs = append(errs, fmt.Errorf("* missing %s permission [get] in namespace %s: %s", action.resource, action.namespace, err))
+		}
+	}
+	if len(errs) > 0 {
+		return joinErrors(errs, 2)
+	}
+	return nil
+}
+
+// checkRemoteAccessPermissions verifies that the service account whose token
+// the Link uses exists and can watch the services of the target cluster.
+func (hc *preLinkChecker) checkRemoteAccessPermissions(ctx context.Context) error {
+	sa, err := hc.targetAPI.CoreV1().ServiceAccounts(hc.options.namespace).Get(ctx, hc.options.serviceAccountName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get service account %s/%s: %s", hc.options.namespace, hc.options.serviceAccountName, err)
+	}
+	user := fmt.Sprintf("system:serviceaccount:%s:%s", sa.Namespace, sa.Name)
+	groups := []string{"system:serviceaccounts", fmt.Sprintf("system:serviceaccounts:%s", sa.Namespace), "system:authenticated"}
+	errs := []error{}
+	for _, verb := range []string{"get", "list", "watch"} {
+		if err := k8s.ResourceAuthzForUser(ctx, hc.targetAPI, corev1.NamespaceAll, verb, "", "v1", "services", "", "", user, groups); err != nil {
+			errs = append(errs, fmt.Errorf("* missing service permission [%s] for %s: %s", verb, user, err))
+		}
+	}
+	if len(errs) > 0 {
+		return joinErrors(errs, 2)
+	}
+	return nil
+}
+
+func (hc *preLinkChecker) checkGatewayService(ctx context.Context) error {
+	gateway, err := hc.targetAPI.CoreV1().Services(hc.options.gatewayNamespace).Get(ctx, hc.options.gatewayName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get gateway service %s/%s: %s", hc.options.gatewayNamespace, hc.options.gatewayName, err)
+	}
+	switch gateway.Spec.Type {
+	case corev1.ServiceTypeLoadBalancer, corev1.ServiceTypeNodePort:
+	default:
+		return fmt.Errorf("gateway service %s/%s is of type %s; it must be of type LoadBalancer or NodePort to be reachable from other clusters", gateway.Namespace, gateway.Name, gateway.Spec.Type)
+	}
+	if _, err := extractGatewayPort(gateway); err != nil {
+		return err
+	}
+	if _, err := mc.ExtractProbeSpec(gateway); err != nil {
+		return fmt.Errorf("gateway service %s/%s has an invalid probe spec: %s", gateway.Namespace, gateway.Name, err)
+	}
+	if identity := gateway.Annotations[k8s.GatewayIdentity]; identity == "" {
+		return fmt.Errorf("gateway service %s/%s has no %s annotation", gateway.Namespace, gateway.Name, k8s.GatewayIdentity)
+	}
+	hc.gateway = gateway
+	return nil
+}
+
+func (hc *preLinkChecker) checkGatewayAddresses() error {
+	if len(gatewayIngressAddresses(hc.gateway)) == 0 {
+		return fmt.Errorf("gateway service %s/%s has no ingress addresses; they must be provided with --gateway-addresses when linking", hc.gateway.Namespace, hc.gateway.Name)
+	}
+	return nil
+}
+
+// checkGatewayProbe sends a request to the probe endpoint of each of the
+// ingress addresses of the gateway, as the service mirror does.
+func (hc *preLinkChecker) checkGatewayProbe(ctx context.Context) error {
+	addresses := gatewayIngressAddresses(hc.gateway)
+	if len(addresses) == 0 {
+		return &healthcheck.SkipError{Reason: "no gateway ingress addresses"}
+	}
+	probeSpec, err := mc.ExtractProbeSpec(hc.gateway)
+	if err != nil {
+		return err
+	}
+
+	client := http.Client{Timeout: healthcheck.RequestTimeout}
+	errs := []error{}
+	for _, addr := range addresses {
+		url := fmt.Sprintf("http://%s%s", net.JoinHostPort(addr, strconv.Itoa(int(probeSpec.Port))), probeSpec.Path)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("* %s: %s", url, err))
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			errs = append(errs, fmt.Errorf("* %s: unexpected status %d", url, resp.StatusCode))
+		}
+	}
+	if len(errs) > 0 {
+		return joinErrors(errs, 2)
+	}
+	return nil
+}
+
+// checkTargetClockSkew compares the last heartbeats of the ready nodes of
+// the target cluster with the local clock, as the certificates issued in one
+// cluster must be valid in the other.
+func (hc *preLinkChecker) checkTargetClockSkew(ctx context.Context) error {
+	nodes, err := hc.targetAPI.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	var skewedNodes []string
+	for _, node := range nodes.Items {
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == corev1.NodeReady && condition.Status == corev1.ConditionTrue {
+				since := time.Since(condition.LastHeartbeatTime.Time)
+				if since > healthcheck.AllowedClockSkew || since < -healthcheck.AllowedClockSkew {
+					skewedNodes = append(skewedNodes, node.Name)
+				}
+			}
+		}
+	}
+	if len(skewedNodes) > 0 {
+		return fmt.Errorf("clock skew detected for target cluster node(s): %s", strings.Join(skewedNodes, ", "))
+	}
+	return nil
+}
+
+// gatewayIngressAddresses returns the IPs or hostnames the load balancer of
+// the gateway is reachable at.
+func gatewayIngressAddresses(gateway *corev1.Service) []string {
+	addresses := []string{}
+	for _, ingress := range gateway.Status.LoadBalancer.Ingress {
+		addr := ingress.IP
+		if addr == "" {
+			addr = ingress.Hostname
+		}
+		if addr != "" {
+			addresses = append(addresses, addr)
+		}
+	}
+	return addresses
+}
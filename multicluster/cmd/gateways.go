@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"time"
 
 	"github.com/linkerd/linkerd2/cli/table"
 	"github.com/linkerd/linkerd2/pkg/k8s"
@@ -20,6 +21,7 @@ type (
 		gatewayNamespace string
 		clusterName      string
 		timeWindow       string
+		bySource         bool
 	}
 )
 
@@ -36,6 +38,7 @@ func newGatewaysCommand() *cobra.Command {
 				RemoteClusterName: opts.clusterName,
 				GatewayNamespace:  opts.gatewayNamespace,
 				TimeWindow:        opts.timeWindow,
+				BySourceIdentity:  opts.bySource,
 			}
 
 			k8sAPI, err := k8s.NewAPI(kubeconfigPath, kubeContext, impersonate, impersonateGroup, 0)
@@ -61,6 +64,10 @@ func newGatewaysCommand() *cobra.Command {
 				os.Exit(1)
 			}
 
+			if opts.bySource {
+				renderGatewaysTraffic(resp.GetOk().GatewaysTable.Rows, opts.timeWindow, stdout)
+				return nil
+			}
 			renderGateways(resp.GetOk().GatewaysTable.Rows, stdout)
 			return nil
 		},
@@ -69,6 +76,7 @@ func newGatewaysCommand() *cobra.Command {
 	cmd.Flags().StringVar(&opts.clusterName, "cluster-name", "", "the name of the target cluster")
 	cmd.Flags().StringVar(&opts.gatewayNamespace, "gateway-namespace", "", "the namespace in which the gateway resides on the target cluster")
 	cmd.Flags().StringVarP(&opts.timeWindow, "time-window", "t", "1m", "Time window (for example: \"15s\", \"1m\", \"10m\", \"1h\"). Needs to be at least 15s.")
+	cmd.Flags().BoolVar(&opts.bySource, "by-source", false, "Display the connections and bytes received by the gateways of this cluster, broken down by source identity")
 
 	return cmd
 }
@@ -94,6 +102,15 @@ func renderGateways(rows []*pb.GatewaysTable_Row, w io.Writer) {
 	t.Render(w)
 }
 
+var (
+	gatewayNamespaceHeader = "NAMESPACE"
+	gatewayNameHeader      = "NAME"
+	sourceIdentityHeader   = "SOURCE_IDENTITY"
+	connectionsHeader      = "CONNECTIONS"
+	readBytesHeader        = "READ_BYTES/SEC"
+	writeBytesHeader       = "WRITE_BYTES/SEC"
+)
+
 var (
 	clusterNameHeader    = "CLUSTER"
 	aliveHeader          = "ALIVE"
@@ -163,6 +180,66 @@ func gatewaysRowToTableRow(row *pb.GatewaysTable_Row) []string {
 
 }
 
+// renderGatewaysTraffic renders the traffic received by the gateways of this
+// cluster, with a row per gateway and source identity.
+func renderGatewaysTraffic(rows []*pb.GatewaysTable_Row, timeWindow string, w io.Writer) {
+	window, err := time.ParseDuration(timeWindow)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid time window %s: %s", timeWindow, err)
+		os.Exit(1)
+	}
+
+	columns := []table.Column{
+		table.Column{
+			Header:    gatewayNamespaceHeader,
+			Width:     9,
+			Flexible:  true,
+			LeftAlign: true,
+		},
+		table.Column{
+			Header:    gatewayNameHeader,
+			Width:     4,
+			Flexible:  true,
+			LeftAlign: true,
+		},
+		table.Column{
+			Header:    sourceIdentityHeader,
+			Width:     15,
+			Flexible:  true,
+			LeftAlign: true,
+		},
+		table.Column{
+			Header: connectionsHeader,
+			Width:  11,
+		},
+		table.Column{
+			Header: readBytesHeader,
+			Width:  14,
+		},
+		table.Column{
+			Header: writeBytesHeader,
+			Width:  15,
+		},
+	}
+	t := table.NewTable(columns, []table.Row{})
+	t.Sort = []int{0, 1, 2} // Sort by namespace, then name, then identity.
+	for _, row := range rows {
+		identity := row.SourceIdentity
+		if identity == "" {
+			identity = "-"
+		}
+		t.Data = append(t.Data, table.Row{
+			row.Namespace,
+			row.Name,
+			identity,
+			fmt.Sprint(row.OpenConnections),
+			fmt.Sprintf("%.1f", float64(row.ReadBytesTotal)/window.Seconds()),
+			fmt.Sprintf("%.1f", float64(row.WriteBytesTotal)/window.Seconds()),
+		})
+	}
+	t.Render(w)
+}
+
 func extractGatewayPort(gateway *corev1.Service) (uint32, error) {
 	for _, port := range gateway.Spec.Ports {
 		if port.Name == k8s.GatewayPortName {
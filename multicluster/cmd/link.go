@@ -7,6 +7,7 @@ import (
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/linkerd/linkerd2/multicluster/static"
 	multicluster "github.com/linkerd/linkerd2/multicluster/values"
@@ -39,12 +40,28 @@ type (
 		gatewayName             string
 		gatewayNamespace        string
 		serviceMirrorRetryLimit uint32
+		serviceMirrorReplicas   uint32
 		logLevel                string
 		controlPlaneVersion     string
 		dockerRegistry          string
 		selector                string
 		gatewayAddresses        string
+		gatewayAddressWeights   string
+		propagatedLabels        []string
+		propagatedAnnotations   []string
+		namespaceMappings       string
+		namespacePrefix         string
 		gatewayPort             uint32
+		probeTimeout            time.Duration
+		probeFailureThreshold   uint32
+		probeSuccessThreshold   uint32
+		probeScheme             string
+		probeExpectedStatuses   string
+		probeVerifyIdentity     bool
+		secretFormat            string
+		sealedSecretsCert       string
+		sopsAgeRecipients       string
+		sopsPGPFingerprints     string
 	}
 )
 
@@ -64,6 +81,13 @@ func newLinkCommand() *cobra.Command {
 		Example: `  # To link the west cluster to east
   linkerd --context=east multicluster link --cluster-name east | kubectl --context=west apply -f -
 
+  # To output the cluster credentials as a SealedSecret that can be committed to a GitOps repository
+  linkerd --context=east multicluster link --cluster-name east --secret-format sealed-secret --sealed-secrets-cert west-sealed-secrets.pem
+
+  # To link the east and north clusters to west with a single service mirror, mirroring all the links
+  linkerd --context=east multicluster link --cluster-name east --set serviceMirrorAllLinks=true | kubectl --context=west apply -f -
+  linkerd --context=north multicluster link --cluster-name north --set serviceMirror=false | kubectl --context=west apply -f -
+
 The command can be configured by using the --set, --values, --set-string and --set-file flags.
 A full list of configurable values can be found at https://github.com/linkerd/linkerd2/blob/main/multicluster/charts/linkerd-multicluster-link/README.md
   `,
@@ -73,6 +97,10 @@ A full list of configurable values can be found at https://github.com/linkerd/li
 				return errors.New("You need to specify cluster name")
 			}
 
+			if err := validateSecretFormat(opts); err != nil {
+				return err
+			}
+
 			configMap, err := getLinkerdConfigMap(cmd.Context())
 			if err != nil {
 				if kerrors.IsNotFound(err) {
@@ -170,6 +198,10 @@ A full list of configurable values can be found at https://github.com/linkerd/li
 			if err != nil {
 				return err
 			}
+			credsOut, err = encryptCredentials(credsOut, opts)
+			if err != nil {
+				return err
+			}
 
 			gateway, err := k.CoreV1().Services(opts.gatewayNamespace).Get(cmd.Context(), opts.gatewayName, metav1.GetOptions{})
 			if err != nil {
@@ -177,17 +209,7 @@ A full list of configurable values can be found at https://github.com/linkerd/li
 			}
 
 			gatewayAddresses := ""
-			gwAddresses := []string{}
-			for _, ingress := range gateway.Status.LoadBalancer.Ingress {
-				addr := ingress.IP
-				if addr == "" {
-					addr = ingress.Hostname
-				}
-				if addr == "" {
-					continue
-				}
-				gwAddresses = append(gwAddresses, addr)
-			}
+			gwAddresses := gatewayIngressAddresses(gateway)
 			if len(gwAddresses) == 0 && opts.gatewayAddresses == "" {
 				return fmt.Errorf("Gateway %s.%s has no ingress addresses", gateway.Name, gateway.Namespace)
 			} else if len(gwAddresses) > 0 {
@@ -195,6 +217,10 @@ A full list of configurable values can be found at https://github.com/linkerd/li
 			} else {
 				gatewayAddresses = opts.gatewayAddresses
 			}
+			gatewayAddresses, err = mc.NormalizeGatewayAddress(gatewayAddresses)
+			if err != nil {
+				return err
+			}
 
 			gatewayIdentity, ok := gateway.Annotations[k8s.GatewayIdentity]
 			if !ok || gatewayIdentity == "" {
@@ -205,6 +231,24 @@ A full list of configurable values can be found at https://github.com/linkerd/li
 			if err != nil {
 				return err
 			}
+			if opts.probeTimeout < 0 {
+				return errors.New("--probe-timeout must not be negative")
+			}
+			if err := mc.ValidateProbeScheme(opts.probeScheme); err != nil {
+				return err
+			}
+			probeSpec.Timeout = opts.probeTimeout
+			probeSpec.FailureThreshold = opts.probeFailureThreshold
+			probeSpec.SuccessThreshold = opts.probeSuccessThreshold
+			probeSpec.Scheme = opts.probeScheme
+			probeSpec.ExpectedStatuses, err = mc.ParseStatusRanges(opts.probeExpectedStatuses)
+			if err != nil {
+				return fmt.Errorf("invalid --probe-expected-statuses: %s", err)
+			}
+			probeSpec.VerifyIdentity = opts.probeVerifyIdentity
+			if err := mc.ValidateProbeIdentity(probeSpec); err != nil {
+				return err
+			}
 
 			gatewayPort, err := extractGatewayPort(gateway)
 			if err != nil {
@@ -221,6 +265,26 @@ A full list of configurable values can be found at https://github.com/linkerd/li
 				return err
 			}
 
+			gatewayAddressWeights, err := mc.ParseGatewayAddressWeights(opts.gatewayAddressWeights)
+			if err != nil {
+				return err
+			}
+
+			if err := mc.ValidatePropagationPatterns(opts.propagatedLabels); err != nil {
+				return err
+			}
+			if err := mc.ValidatePropagationPatterns(opts.propagatedAnnotations); err != nil {
+				return err
+			}
+
+			namespaceMappings, err := mc.ParseNamespaceMappings(opts.namespaceMappings)
+			if err != nil {
+				return err
+			}
+			if err := mc.ValidateNamespaceMappings(namespaceMappings, opts.namespacePrefix); err != nil {
+				return err
+			}
+
 			link := mc.Link{
 				Name:                          opts.clusterName,
 				Namespace:                     opts.namespace,
@@ -233,6 +297,11 @@ A full list of configurable values can be found at https://github.com/linkerd/li
 				GatewayIdentity:               gatewayIdentity,
 				ProbeSpec:                     probeSpec,
 				Selector:                      *selector,
+				GatewayAddressWeights:         gatewayAddressWeights,
+				PropagatedLabels:              opts.propagatedLabels,
+				PropagatedAnnotations:         opts.propagatedAnnotations,
+				NamespaceMappings:             namespaceMappings,
+				NamespacePrefix:               opts.namespacePrefix,
 			}
 
 			obj, err := link.ToUnstructured()
@@ -326,15 +395,29 @@ A full list of configurable values can be found at https://github.com/linkerd/li
 	cmd.Flags().StringVar(&opts.gatewayName, "gateway-name", defaultGatewayName, "The name of the gateway service")
 	cmd.Flags().StringVar(&opts.gatewayNamespace, "gateway-namespace", defaultMulticlusterNamespace, "The namespace of the gateway service")
 	cmd.Flags().Uint32Var(&opts.serviceMirrorRetryLimit, "service-mirror-retry-limit", opts.serviceMirrorRetryLimit, "The number of times a failed update from the target cluster is allowed to be retried")
+	cmd.Flags().Uint32Var(&opts.serviceMirrorReplicas, "service-mirror-replicas", opts.serviceMirrorReplicas, "The number of replicas of the service mirror controller; a single replica mirrors services at a time, the others take over when it fails")
 	cmd.Flags().StringVar(&opts.logLevel, "log-level", opts.logLevel, "Log level for the Multicluster components")
 	cmd.Flags().StringVar(&opts.dockerRegistry, "registry", opts.dockerRegistry, "Docker registry to pull service mirror controller image from")
 	cmd.Flags().StringVarP(&opts.selector, "selector", "l", opts.selector, "Selector (label query) to filter which services in the target cluster to mirror")
-	cmd.Flags().StringVar(&opts.gatewayAddresses, "gateway-addresses", opts.gatewayAddresses, "If specified, overwrites gateway addresses when gateway service is not type LoadBalancer (comma separated list)")
+	cmd.Flags().StringVar(&opts.gatewayAddresses, "gateway-addresses", opts.gatewayAddresses, "If specified, overwrites gateway addresses when gateway service is not type LoadBalancer (comma separated list of IPs, hostnames, or SRV names such as _gateway._tcp.example.com whose port is used as the gateway port; IPs and hostnames may be followed by a port, with IPv6 addresses bracketed, e.g. [fd00::1]:4143)")
+	cmd.Flags().StringVar(&opts.gatewayAddressWeights, "gateway-address-weights", opts.gatewayAddressWeights, "Comma separated list of address=weight pairs assigning relative weights to the gateway addresses (e.g. 10.0.0.1=3,10.0.0.2=1)")
+	cmd.Flags().StringSliceVar(&opts.propagatedLabels, "propagate-labels", opts.propagatedLabels, "Glob patterns of the keys of the labels copied from exported services onto their mirrors (e.g. team,example.com/*)")
+	cmd.Flags().StringSliceVar(&opts.propagatedAnnotations, "propagate-annotations", opts.propagatedAnnotations, "Glob patterns of the keys of the annotations copied from exported services onto their mirrors")
+	cmd.Flags().StringVar(&opts.namespaceMappings, "namespace-mappings", opts.namespaceMappings, "Comma separated list of remote=local pairs mapping the namespaces of the target cluster to the local namespaces their services are mirrored into (e.g. payments=east-payments)")
+	cmd.Flags().StringVar(&opts.namespacePrefix, "namespace-prefix", opts.namespacePrefix, "Prefix added to the names of the namespaces of the target cluster that aren't in --namespace-mappings to get the local namespaces their services are mirrored into")
 	cmd.Flags().Uint32Var(&opts.gatewayPort, "gateway-port", opts.gatewayPort, "If specified, overwrites gateway port when gateway service is not type LoadBalancer")
-
-	pkgcmd.ConfigureNamespaceFlagCompletion(
-		cmd, []string{"namespace", "gateway-namespace"},
-		kubeconfigPath, impersonate, impersonateGroup, kubeContext)
+	cmd.Flags().DurationVar(&opts.probeTimeout, "probe-timeout", opts.probeTimeout, "Timeout of the probes of the gateway; by default, the probes through the gateway mirror time out after 50s and the probes of the individual gateway addresses after 5s")
+	cmd.Flags().Uint32Var(&opts.probeFailureThreshold, "probe-failure-threshold", opts.probeFailureThreshold, "Number of consecutive failed probes after which the gateway is considered unhealthy; by default 1 for the gateway mirror, and 3 for the individual gateway addresses")
+	cmd.Flags().Uint32Var(&opts.probeSuccessThreshold, "probe-success-threshold", opts.probeSuccessThreshold, "Number of consecutive successful probes after which an unhealthy gateway is considered healthy again (default 1)")
+	cmd.Flags().StringVar(&opts.probeScheme, "probe-scheme", opts.probeScheme, "Scheme of the probes of the gateway: http (default) or https")
+	cmd.Flags().StringVar(&opts.probeExpectedStatuses, "probe-expected-statuses", opts.probeExpectedStatuses, "Comma separated list of the statuses and ranges of statuses of successful probe responses (e.g. 200-299,301); only 200 by default")
+	cmd.Flags().BoolVar(&opts.probeVerifyIdentity, "probe-verify-identity", opts.probeVerifyIdentity, "Probe the gateway over mTLS, with the identity of the service mirror's proxy, and fail the probes unless the gateway has the expected identity")
+	cmd.Flags().StringVar(&opts.secretFormat, "secret-format", opts.secretFormat, "Format of the cluster credentials secret: plain, sealed-secret (requires kubeseal) or sops (requires sops 3.9 or later)")
+	cmd.Flags().StringVar(&opts.sealedSecretsCert, "sealed-secrets-cert", "", "Path or URL of the certificate of the sealed secrets controller in the source cluster, used with --secret-format sealed-secret")
+	cmd.Flags().StringVar(&opts.sopsAgeRecipients, "sops-age", "", "Comma separated list of age recipients to encrypt the cluster credentials for, used with --secret-format sops")
+	cmd.Flags().StringVar(&opts.sopsPGPFingerprints, "sops-pgp", "", "Comma separated list of PGP fingerprints to encrypt the cluster credentials for, used with --secret-format sops")
+
+	pkgcmd.ConfigureNamespaceFlagCompletion(cmd, []string{"namespace", "gateway-namespace"})
 	return cmd
 }
 
@@ -349,10 +432,12 @@ func newLinkOptionsWithDefault() (*linkOptions, error) {
 		namespace:               defaults.Namespace,
 		dockerRegistry:          defaultDockerRegistry,
 		serviceMirrorRetryLimit: defaults.ServiceMirrorRetryLimit,
+		serviceMirrorReplicas:   defaults.ServiceMirrorReplicas,
 		logLevel:                defaults.LogLevel,
 		selector:                k8s.DefaultExportedServiceSelector,
 		gatewayAddresses:        "",
 		gatewayPort:             0,
+		secretFormat:            secretFormatPlain,
 	}, nil
 }
 
@@ -370,6 +455,10 @@ func buildServiceMirrorValues(opts *linkOptions) (*multicluster.Values, error) {
 		return nil, errors.New("you need to setup the multicluster addons in a namespace different than the Linkerd one")
 	}
 
+	if opts.serviceMirrorReplicas == 0 {
+		return nil, errors.New("--service-mirror-replicas must be at least 1")
+	}
+
 	if _, err := log.ParseLevel(opts.logLevel); err != nil {
 		return nil, fmt.Errorf("--log-level must be one of: panic, fatal, error, warn, info, debug")
 	}
@@ -382,6 +471,7 @@ func buildServiceMirrorValues(opts *linkOptions) (*multicluster.Values, error) {
 	defaults.TargetClusterName = opts.clusterName
 	defaults.Namespace = opts.namespace
 	defaults.ServiceMirrorRetryLimit = opts.serviceMirrorRetryLimit
+	defaults.ServiceMirrorReplicas = opts.serviceMirrorReplicas
 	defaults.LogLevel = opts.logLevel
 	defaults.ControllerImageVersion = opts.controlPlaneVersion
 	defaults.ControllerImage = fmt.Sprintf("%s/controller", opts.dockerRegistry)
@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+const (
+	secretFormatPlain        = "plain"
+	secretFormatSealedSecret = "sealed-secret"
+	secretFormatSOPS         = "sops"
+
+	kubesealBinary = "kubeseal"
+	sopsBinary     = "sops"
+)
+
+func validateSecretFormat(opts *linkOptions) error {
+	switch opts.secretFormat {
+	case secretFormatPlain:
+		return nil
+	case secretFormatSealedSecret:
+		// Without a certificate kubeseal would fetch the one from the sealed
+		// secrets controller of the current context, which is the target
+		// cluster and not the one the secret is applied to
+		if opts.sealedSecretsCert == "" {
+			return errors.New("--sealed-secrets-cert is required when --secret-format is sealed-secret")
+		}
+		return nil
+	case secretFormatSOPS:
+		if opts.sopsAgeRecipients == "" && opts.sopsPGPFingerprints == "" {
+			return errors.New("--sops-age or --sops-pgp is required when --secret-format is sops")
+		}
+		return nil
+	default:
+		return fmt.Errorf("--secret-format must be one of: %s, %s, %s", secretFormatPlain, secretFormatSealedSecret, secretFormatSOPS)
+	}
+}
+
+// encryptCredentials returns the given credentials secret manifest in the
+// format requested by the options, so that it can be safely committed to a
+// repository. Encryption is delegated to the kubeseal and sops (3.9 or later)
+// binaries, which must be available in the PATH and read the manifest from
+// their stdin.
+func encryptCredentials(creds []byte, opts *linkOptions) ([]byte, error) {
+	switch opts.secretFormat {
+	case secretFormatSealedSecret:
+		return runEncryption(creds, kubesealBinary, "--format", "yaml", "--cert", opts.sealedSecretsCert)
+	case secretFormatSOPS:
+		// Without a file argument sops encrypts its stdin, whose format
+		// can't be guessed from a file extension
+		args := []string{
+			"encrypt",
+			"--input-type", "yaml",
+			"--output-type", "yaml",
+			// Only encrypt the secret's data, leaving its metadata readable
+			"--encrypted-regex", "^(data|stringData)$",
+		}
+		if opts.sopsAgeRecipients != "" {
+			args = append(args, "--age", opts.sopsAgeRecipients)
+		}
+		if opts.sopsPGPFingerprints != "" {
+			args = append(args, "--pgp", opts.sopsPGPFingerprints)
+		}
+		return runEncryption(creds, sopsBinary, args...)
+	default:
+		return creds, nil
+	}
+}
+
+func runEncryption(in []byte, name string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = bytes.NewReader(in)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return nil, fmt.Errorf("%s must be installed to encrypt the cluster credentials", name)
+		}
+		return nil, fmt.Errorf("failed to encrypt the cluster credentials with %s: %s: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+
+	out := stdout.Bytes()
+	if !bytes.HasSuffix(out, []byte("\n")) {
+		out = append(out, '\n')
+	}
+	return out, nil
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const credentialsManifest = `apiVersion: v1
+kind: Secret
+metadata:
+  name: cluster-credentials-east
+  namespace: linkerd-multicluster
+type: mirror.linkerd.io/remote-kubeconfig
+data:
+  kubeconfig: Y29uZmln
+`
+
+func TestValidateSecretFormat(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		opts linkOptions
+		err  string
+	}{
+		{
+			name: "plain",
+			opts: linkOptions{secretFormat: secretFormatPlain},
+		},
+		{
+			name: "sealed secret",
+			opts: linkOptions{secretFormat: secretFormatSealedSecret, sealedSecretsCert: "cert.pem"},
+		},
+		{
+			name: "sealed secret without a certificate",
+			opts: linkOptions{secretFormat: secretFormatSealedSecret},
+			err:  "--sealed-secrets-cert is required when --secret-format is sealed-secret",
+		},
+		{
+			name: "sops with age recipients",
+			opts: linkOptions{secretFormat: secretFormatSOPS, sopsAgeRecipients: "age1example"},
+		},
+		{
+			name: "sops with PGP fingerprints",
+			opts: linkOptions{secretFormat: secretFormatSOPS, sopsPGPFingerprints: "FBC7B9E2A4F9289AC0C1D4843D16CEE4A27381B4"},
+		},
+		{
+			name: "sops without recipients",
+			opts: linkOptions{secretFormat: secretFormatSOPS},
+			err:  "--sops-age or --sops-pgp is required when --secret-format is sops",
+		},
+		{
+			name: "unknown format",
+			opts: linkOptions{secretFormat: "vault"},
+			err:  "--secret-format must be one of: plain, sealed-secret, sops",
+		},
+	} {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateSecretFormat(&tc.opts)
+			if tc.err == "" {
+				if err != nil {
+					t.Fatalf("Unexpected error: %s", err)
+				}
+				return
+			}
+			if err == nil || err.Error() != tc.err {
+				t.Fatalf("Expected error %q, got %v", tc.err, err)
+			}
+		})
+	}
+}
+
+func TestEncryptCredentialsPlain(t *testing.T) {
+	out, err := encryptCredentials([]byte(credentialsManifest), &linkOptions{secretFormat: secretFormatPlain})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if string(out) != credentialsManifest {
+		t.Fatalf("Expected the credentials to be left as is, got:\n%s", out)
+	}
+}
+
+func TestEncryptCredentialsSOPS(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake sops binary is a shell script")
+	}
+
+	// the fake sops records its arguments and echoes its stdin back
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	script := "#!/bin/sh\necho \"$@\" > " + argsFile + "\ncat\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, sopsBinary), []byte(script), 0755); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	path := os.Getenv("PATH")
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+	defer os.Setenv("PATH", path)
+
+	out, err := encryptCredentials([]byte(credentialsManifest), &linkOptions{
+		secretFormat:      secretFormatSOPS,
+		sopsAgeRecipients: "age1example",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if string(out) != credentialsManifest {
+		t.Fatalf("Expected sops to read the credentials from its stdin, got:\n%s", out)
+	}
+
+	args, err := ioutil.ReadFile(argsFile)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := "encrypt --input-type yaml --output-type yaml --encrypted-regex ^(data|stringData)$ --age age1example"
+	if strings.TrimSpace(string(args)) != expected {
+		t.Fatalf("Expected sops to be run with %q, got %q", expected, strings.TrimSpace(string(args)))
+	}
+}
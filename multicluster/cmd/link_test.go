@@ -0,0 +1,48 @@
+package cmd
+
+import "testing"
+
+func TestBuildServiceMirrorValuesReplicas(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		replicas uint32
+		err      string
+	}{
+		{
+			name:     "single replica",
+			replicas: 1,
+		},
+		{
+			name:     "multiple replicas",
+			replicas: 3,
+		},
+		{
+			name: "no replica",
+			err:  "--service-mirror-replicas must be at least 1",
+		},
+	} {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			opts, err := newLinkOptionsWithDefault()
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			opts.clusterName = "east"
+			opts.serviceMirrorReplicas = tc.replicas
+
+			values, err := buildServiceMirrorValues(opts)
+			if tc.err != "" {
+				if err == nil || err.Error() != tc.err {
+					t.Fatalf("Expected error %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if values.ServiceMirrorReplicas != tc.replicas {
+				t.Fatalf("Expected %d service mirror replicas, got %d", tc.replicas, values.ServiceMirrorReplicas)
+			}
+		})
+	}
+}
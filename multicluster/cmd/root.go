@@ -73,10 +73,7 @@ components on a cluster, manage credentials and link clusters together.`,
 	}
 
 	multiclusterCmd.PersistentFlags().StringVarP(&controlPlaneNamespace, "linkerd-namespace", "L", defaultLinkerdNamespace, "Namespace in which Linkerd is installed")
-	multiclusterCmd.PersistentFlags().StringVar(&kubeconfigPath, "kubeconfig", "", "Path to the kubeconfig file to use for CLI requests")
-	multiclusterCmd.PersistentFlags().StringVar(&kubeContext, "context", "", "Name of the kubeconfig context to use")
-	multiclusterCmd.PersistentFlags().StringVar(&impersonate, "as", "", "Username to impersonate for Kubernetes operations")
-	multiclusterCmd.PersistentFlags().StringArrayVar(&impersonateGroup, "as-group", []string{}, "Group to impersonate for Kubernetes operations")
+	pkgcmd.AddKubeFlags(multiclusterCmd.PersistentFlags(), &kubeconfigPath, &kubeContext, &impersonate, &impersonateGroup)
 	multiclusterCmd.PersistentFlags().StringVar(&apiAddr, "api-addr", "", "Override kubeconfig and communicate directly with the control plane at host:port (mostly for testing)")
 	multiclusterCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "Turn on debug logging")
 	multiclusterCmd.AddCommand(newLinkCommand())
@@ -88,11 +85,9 @@ components on a cluster, manage credentials and link clusters together.`,
 	multiclusterCmd.AddCommand(newAllowCommand())
 
 	// resource-aware completion flag configurations
-	pkgcmd.ConfigureNamespaceFlagCompletion(
-		multiclusterCmd, []string{"linkerd-namespace"},
-		kubeconfigPath, impersonate, impersonateGroup, kubeContext)
+	pkgcmd.ConfigureNamespaceFlagCompletion(multiclusterCmd, []string{"linkerd-namespace"})
 
-	pkgcmd.ConfigureKubeContextFlagCompletion(multiclusterCmd, kubeconfigPath)
+	pkgcmd.ConfigureKubeContextFlagCompletion(multiclusterCmd)
 	return multiclusterCmd
 }
 
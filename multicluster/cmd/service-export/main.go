@@ -0,0 +1,49 @@
+package serviceexport
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+
+	controllerK8s "github.com/linkerd/linkerd2/controller/k8s"
+	serviceexport "github.com/linkerd/linkerd2/multicluster/service-export"
+	"github.com/linkerd/linkerd2/pkg/admin"
+	"github.com/linkerd/linkerd2/pkg/flags"
+	"github.com/linkerd/linkerd2/pkg/k8s"
+	log "github.com/sirupsen/logrus"
+)
+
+// Main executes the service-export controller
+func Main(args []string) {
+	cmd := flag.NewFlagSet("service-export", flag.ExitOnError)
+
+	kubeConfigPath := cmd.String("kubeconfig", "", "path to the local kube config")
+	metricsAddr := cmd.String("metrics-addr", ":9999", "address to serve scrapable metrics on")
+
+	flags.ConfigureAndParse(cmd, args)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	k8sAPI, err := k8s.NewAPI(*kubeConfigPath, "", "", []string{}, 0)
+	if err != nil {
+		log.Fatalf("Failed to initialize K8s API: %s", err)
+	}
+
+	controllerK8sAPI, err := controllerK8s.InitializeAPI(ctx, *kubeConfigPath, false, controllerK8s.Svc)
+	if err != nil {
+		log.Fatalf("Failed to initialize K8s API: %s", err)
+	}
+
+	go admin.StartServer(*metricsAddr)
+
+	serviceexport.NewController(controllerK8sAPI, k8sAPI.DynamicClient).Start(ctx)
+	log.Info("Shutting down")
+}
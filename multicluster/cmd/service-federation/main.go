@@ -0,0 +1,59 @@
+package servicefederation
+
+import (
+	"context"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	controllerK8s "github.com/linkerd/linkerd2/controller/k8s"
+	servicefederation "github.com/linkerd/linkerd2/multicluster/service-federation"
+	"github.com/linkerd/linkerd2/pkg/admin"
+	"github.com/linkerd/linkerd2/pkg/featuregates"
+	"github.com/linkerd/linkerd2/pkg/flags"
+	log "github.com/sirupsen/logrus"
+)
+
+// Main executes the service-federation controller
+func Main(args []string) {
+	cmd := flag.NewFlagSet("service-federation", flag.ExitOnError)
+
+	kubeConfigPath := cmd.String("kubeconfig", "", "path to the local kube config")
+	metricsAddr := cmd.String("metrics-addr", ":9999", "address to serve scrapable metrics on")
+	linkerdNamespace := cmd.String("linkerd-namespace", "linkerd", "namespace of the Linkerd control plane, whose linkerd-config holds the feature gates")
+	featureGatesFlag := flags.AddFeatureGatesFlag(cmd)
+
+	flags.ConfigureAndParse(cmd, args)
+
+	gates := featuregates.New()
+	if err := gates.ParseFlag(*featureGatesFlag); err != nil {
+		log.Fatalf("Failed to parse feature gates: %s", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	controllerK8sAPI, err := controllerK8s.InitializeAPI(ctx, *kubeConfigPath, false, controllerK8s.Svc, controllerK8s.Endpoint)
+	if err != nil {
+		log.Fatalf("Failed to initialize K8s API: %s", err)
+	}
+
+	if err := gates.Load(ctx, controllerK8sAPI.Client, *linkerdNamespace); err != nil {
+		log.Warnf("Failed to load feature gates from config: %s", err)
+	}
+	log.Infof("Using feature gates: %s", gates)
+
+	go admin.StartServer(*metricsAddr)
+
+	c := servicefederation.NewController(controllerK8sAPI, gates)
+	go gates.Watch(ctx, controllerK8sAPI.Client, *linkerdNamespace, time.Minute)
+	c.Start(ctx)
+	log.Info("Shutting down")
+}
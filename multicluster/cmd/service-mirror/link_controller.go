@@ -0,0 +1,478 @@
+package servicemirror
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	controllerK8s "github.com/linkerd/linkerd2/controller/k8s"
+	servicemirror "github.com/linkerd/linkerd2/multicluster/service-mirror"
+	"github.com/linkerd/linkerd2/pkg/crash"
+	"github.com/linkerd/linkerd2/pkg/k8s"
+	"github.com/linkerd/linkerd2/pkg/multicluster"
+	sm "github.com/linkerd/linkerd2/pkg/servicemirror"
+	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	dynamic "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	dynamicclient "k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+)
+
+type (
+	// mirrorConfig holds the clients and settings shared by the controllers
+	// of all the links.
+	mirrorConfig struct {
+		namespace        string
+		linkClient       dynamicclient.ResourceInterface
+		controllerK8sAPI *controllerK8s.API
+		k8sAPI           *k8s.KubernetesAPI
+		recorder         record.EventRecorder
+		watcher          servicemirror.WatcherConfig
+		requeue          servicemirror.RequeueConfig
+		writeBackoff     servicemirror.WriteBackoffConfig
+		circuitBreaker   servicemirror.CircuitBreakerConfig
+		remoteClient     servicemirror.ClientConfig
+		metrics          servicemirror.ProbeMetricVecs
+	}
+
+	// linkController mirrors the services of the target cluster of a link,
+	// restarting its cluster watcher each time the link or its credentials
+	// change, unless only the token of the credentials was rotated. Each
+	// link is handled by its own controller, running in its own goroutine,
+	// so that a failing link doesn't hold up the others.
+	linkController struct {
+		name   string
+		config *mirrorConfig
+
+		clusterWatcher *servicemirror.RemoteClusterServiceWatcher
+		probeWorker    *servicemirror.ProbeWorker
+		currentLink    *multicluster.Link
+		// the credentials and client config the cluster watcher was started
+		// with
+		credentials  *servicemirror.RemoteCredentials
+		remoteConfig *rest.Config
+
+		// the latest state of the link, as received from the link watch,
+		// which is reconciled each time notify is signaled. Updates
+		// received while a reconciliation is in progress are coalesced.
+		pending struct {
+			sync.Mutex
+			obj     *dynamic.Unstructured
+			deleted bool
+			restart bool
+			// rotate is set when the credentials secret changes, so that
+			// its token is applied to the running cluster watcher, which
+			// is only restarted if the rest of the kubeconfig changed too
+			rotate bool
+			// credsVersion is the resource version of the credentials
+			// secret the cluster watcher was last started with
+			credsVersion string
+		}
+		notify chan struct{}
+
+		// credsWatch is the watch of the credentials secret of the link,
+		// only accessed from run
+		credsWatch struct {
+			secret string
+			cancel context.CancelFunc
+		}
+
+		// health is the part of the state read by the health checks
+		health struct {
+			sync.RWMutex
+			link    *multicluster.Link
+			probe   *servicemirror.ProbeWorker
+			watcher *servicemirror.RemoteClusterServiceWatcher
+		}
+	}
+)
+
+func newLinkController(name string, config *mirrorConfig) *linkController {
+	return &linkController{
+		name:   name,
+		config: config,
+		notify: make(chan struct{}, 1),
+	}
+}
+
+// update records the latest state of the link, to be reconciled.
+func (c *linkController) update(obj *dynamic.Unstructured, deleted bool) {
+	c.pending.Lock()
+	c.pending.obj = obj
+	c.pending.deleted = deleted
+	c.pending.Unlock()
+	c.signal()
+}
+
+// requestRestart restarts the cluster watcher of the link on the next
+// reconciliation, even if the link is unchanged.
+func (c *linkController) requestRestart() {
+	c.pending.Lock()
+	c.pending.restart = true
+	c.pending.Unlock()
+	c.signal()
+}
+
+// credentialsChanged rotates the credentials of the cluster watcher of the
+// link if the given secret holds them and isn't the version it was started
+// with.
+func (c *linkController) credentialsChanged(secret *corev1.Secret) {
+	c.pending.Lock()
+	obj, credsVersion := c.pending.obj, c.pending.credsVersion
+	c.pending.Unlock()
+	if obj == nil || secret.ResourceVersion == credsVersion {
+		return
+	}
+	link, err := multicluster.NewLink(*obj)
+	if err != nil || link.ClusterCredentialsSecret != secret.Name {
+		return
+	}
+	log.Infof("Credentials secret %s of link %s changed", secret.Name, c.name)
+	c.pending.Lock()
+	c.pending.rotate = true
+	c.pending.Unlock()
+	c.signal()
+}
+
+func (c *linkController) signal() {
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
+
+// run reconciles the link each time it's updated, until it's deleted or the
+// context is canceled.
+func (c *linkController) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			c.stop()
+			c.stopCredentialsWatch()
+			return
+		case <-c.notify:
+		}
+
+		c.pending.Lock()
+		obj, deleted, restart, rotate := c.pending.obj, c.pending.deleted, c.pending.restart, c.pending.rotate
+		c.pending.restart = false
+		c.pending.rotate = false
+		c.pending.Unlock()
+
+		if deleted {
+			log.Infof("Link %s deleted", c.name)
+			c.stop()
+			c.stopCredentialsWatch()
+			return
+		}
+		if obj != nil {
+			c.reconcile(ctx, obj, restart, rotate)
+		}
+	}
+}
+
+// reconcile (re)starts the cluster watcher of the link if needed. A panic
+// raised while doing so is recovered, so that it only affects this link.
+func (c *linkController) reconcile(ctx context.Context, obj *dynamic.Unstructured, restart, rotate bool) {
+	defer crash.Recover("service-mirror/link", obj)
+
+	link, err := multicluster.NewLink(*obj)
+	if err != nil {
+		log.Errorf("Failed to parse link %s: %s", c.name, err)
+		return
+	}
+	if obj.GetDeletionTimestamp() != nil {
+		c.stopCredentialsWatch()
+		c.handleLinkDeletion(ctx, obj, link)
+		return
+	}
+	if !multicluster.HasLinkFinalizer(obj) {
+		if err := multicluster.AddLinkFinalizer(ctx, c.config.k8sAPI.DynamicClient, c.config.namespace, c.name); err != nil {
+			log.Errorf("Failed to add finalizer to link %s: %s", c.name, err)
+		}
+	}
+	unchanged := c.currentLink != nil && reflect.DeepEqual(*c.currentLink, link)
+	if !restart && unchanged && rotate {
+		if c.rotateCredentials(ctx, link) {
+			c.handleReportRequest(ctx, obj)
+			return
+		}
+		restart = true
+	}
+	if !restart && unchanged {
+		// only the status of the link changed (e.g. a condition reported
+		// by the cluster watcher)
+		log.Debugf("Link %s spec unchanged; not restarting cluster watcher", c.name)
+		c.handleReportRequest(ctx, obj)
+		return
+	}
+
+	log.Infof("Got updated link %s: %+v", c.name, link)
+	creds, err := c.loadCredentials(ctx, link)
+	if err != nil {
+		log.Errorf("Failed to load remote cluster credentials: %s", err)
+	}
+	c.watchCredentials(ctx, link.ClusterCredentialsSecret)
+	if err := c.restartClusterWatcher(ctx, link, creds); err != nil {
+		// failed to restart cluster watcher; give a bit of slack and give
+		// it another try
+		log.Error(err)
+		time.AfterFunc(linkWatchRestartAfter, c.requestRestart)
+		return
+	}
+	c.handleReportRequest(ctx, obj)
+}
+
+// stop stops watching the target cluster of the link.
+func (c *linkController) stop() {
+	c.setHealthState(nil, nil, nil)
+	c.currentLink = nil
+	c.credentials = nil
+	c.remoteConfig = nil
+	if c.clusterWatcher != nil {
+		c.clusterWatcher.Stop(false)
+		c.clusterWatcher = nil
+	}
+	if c.probeWorker != nil {
+		c.probeWorker.Stop()
+		c.probeWorker = nil
+	}
+}
+
+// handleLinkDeletion stops watching the target cluster of the link being
+// deleted, and removes the link's finalizer once the resources mirrored for it
+// have been removed. The cleanup is retried until it completes or the context
+// is canceled.
+func (c *linkController) handleLinkDeletion(ctx context.Context, obj *dynamic.Unstructured, link multicluster.Link) {
+	log.Infof("Link %s is being deleted", c.name)
+	c.stop()
+	if !multicluster.HasLinkFinalizer(obj) {
+		return
+	}
+
+	err := wait.PollImmediateUntil(linkCleanupRetryAfter, func() (bool, error) {
+		done, err := servicemirror.CleanupLink(ctx, c.config.namespace, c.config.controllerK8sAPI, &link, c.config.k8sAPI.DynamicClient, c.config.watcher.ServiceImports, c.config.watcher.DryRun)
+		if err != nil {
+			log.Errorf("Failed to clean up link %s: %s", c.name, err)
+			return false, nil
+		}
+		return done, nil
+	}, ctx.Done())
+	if err != nil {
+		return
+	}
+
+	err = multicluster.RemoveLinkFinalizer(ctx, c.config.k8sAPI.DynamicClient, c.config.namespace, c.name)
+	if err != nil && !kerrors.IsNotFound(err) {
+		log.Errorf("Failed to remove finalizer from link %s: %s", c.name, err)
+		return
+	}
+	log.Infof("Removed the resources mirrored for link %s", c.name)
+}
+
+// handleReportRequest writes the reconciliation report of the link if it's
+// annotated with mirror.linkerd.io/report, and then removes the annotation.
+// The annotation is kept if the report can't be written, so that it's
+// written on the next update of the link.
+func (c *linkController) handleReportRequest(ctx context.Context, link *dynamic.Unstructured) {
+	if _, ok := link.GetAnnotations()[k8s.MirrorReportAnnotation]; !ok {
+		return
+	}
+	if c.clusterWatcher == nil {
+		log.Warnf("Not writing the mirror report of link %s: the target cluster isn't watched", link.GetName())
+		return
+	}
+	if err := c.clusterWatcher.WriteReport(ctx); err != nil {
+		log.Errorf("Failed to write the mirror report of link %s: %s", link.GetName(), err)
+		return
+	}
+
+	patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:null}}}`, k8s.MirrorReportAnnotation))
+	if _, err := c.config.linkClient.Patch(ctx, link.GetName(), types.MergePatchType, patch, metav1.PatchOptions{}); err != nil {
+		log.Errorf("Failed to remove the %s annotation from link %s: %s", k8s.MirrorReportAnnotation, link.GetName(), err)
+	}
+}
+
+// rotateCredentials applies the credentials secret of the link to its running
+// cluster watcher, and returns true, if the new kubeconfig only differs from
+// the one the watcher was started with by its bearer token.
+func (c *linkController) rotateCredentials(ctx context.Context, link multicluster.Link) bool {
+	if c.clusterWatcher == nil || c.credentials == nil {
+		return false
+	}
+	creds, err := c.loadCredentials(ctx, link)
+	if err != nil {
+		log.Errorf("Failed to load remote cluster credentials: %s", err)
+		return false
+	}
+	rotated, err := c.credentials.Rotate(creds)
+	if err != nil {
+		log.Errorf("Invalid remote cluster credentials of link %s: %s", c.name, err)
+		return false
+	}
+	if !rotated {
+		log.Infof("Kubeconfig of link %s changed beyond its token; restarting cluster watcher", c.name)
+		return false
+	}
+
+	log.Infof("Rotated the token of link %s without restarting its cluster watcher", c.name)
+	authErr := servicemirror.CheckCredentials(ctx, c.remoteConfig)
+	if err := servicemirror.SetAuthenticatedCondition(ctx, c.config.k8sAPI.DynamicClient, c.config.namespace, link.Name, c.remoteConfig, authErr); err != nil {
+		log.Errorf("Failed to update the %s condition of link %s: %s", multicluster.LinkConditionAuthenticated, link.Name, err)
+	}
+	return true
+}
+
+func (c *linkController) loadCredentials(ctx context.Context, link multicluster.Link) ([]byte, error) {
+	// Load the credentials secret
+	secret, err := c.config.k8sAPI.Interface.CoreV1().Secrets(c.config.namespace).Get(ctx, link.ClusterCredentialsSecret, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load credentials secret %s: %s", link.ClusterCredentialsSecret, err)
+	}
+	c.pending.Lock()
+	c.pending.credsVersion = secret.ResourceVersion
+	c.pending.Unlock()
+	return sm.ParseRemoteClusterSecret(secret)
+}
+
+// watchCredentials watches the credentials secret of the link, so that the
+// cluster watcher gets its new credentials when the secret changes. The secret is watched
+// by name, which only requires access to that secret.
+func (c *linkController) watchCredentials(ctx context.Context, secret string) {
+	if c.credsWatch.cancel != nil && c.credsWatch.secret == secret {
+		return
+	}
+	c.stopCredentialsWatch()
+
+	ctx, cancel := context.WithCancel(ctx)
+	c.credsWatch.secret = secret
+	c.credsWatch.cancel = cancel
+	go func() {
+		secrets := c.config.k8sAPI.Interface.CoreV1().Secrets(c.config.namespace)
+		options := metav1.ListOptions{
+			FieldSelector: fields.OneTermEqualSelector("metadata.name", secret).String(),
+		}
+		for {
+			w, err := secrets.Watch(ctx, options)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				log.Errorf("Failed to watch credentials secret %s of link %s: %s", secret, c.name, err)
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(linkWatchRestartAfter):
+				}
+				continue
+			}
+			for event := range w.ResultChan() {
+				if s, ok := event.Object.(*corev1.Secret); ok && (event.Type == watch.Added || event.Type == watch.Modified) {
+					c.credentialsChanged(s)
+				}
+			}
+			w.Stop()
+			if ctx.Err() != nil {
+				return
+			}
+			log.Debugf("Watch of credentials secret %s of link %s terminated; restarting watch", secret, c.name)
+		}
+	}()
+}
+
+func (c *linkController) stopCredentialsWatch() {
+	if c.credsWatch.cancel != nil {
+		c.credsWatch.cancel()
+		c.credsWatch.cancel = nil
+		c.credsWatch.secret = ""
+	}
+}
+
+func (c *linkController) restartClusterWatcher(ctx context.Context, link multicluster.Link, creds []byte) error {
+	c.stop()
+
+	credentials, cfg, err := servicemirror.NewRemoteCredentials(creds)
+	if err != nil {
+		if err := servicemirror.SetAuthenticatedCondition(ctx, c.config.k8sAPI.DynamicClient, c.config.namespace, link.Name, nil, err); err != nil {
+			log.Errorf("Failed to update the %s condition of link %s: %s", multicluster.LinkConditionAuthenticated, link.Name, err)
+		}
+		return fmt.Errorf("Unable to parse kube config: %s", err)
+	}
+	c.config.remoteClient.Apply(cfg)
+
+	// Authentication failures, e.g. of an exec credential plugin, are
+	// reported in the Link's status; the watcher is retried on the next
+	// change of the Link or of its credentials.
+	authErr := servicemirror.CheckCredentials(ctx, cfg)
+	if err := servicemirror.SetAuthenticatedCondition(ctx, c.config.k8sAPI.DynamicClient, c.config.namespace, link.Name, cfg, authErr); err != nil {
+		log.Errorf("Failed to update the %s condition of link %s: %s", multicluster.LinkConditionAuthenticated, link.Name, err)
+	}
+
+	clusterWatcher, err := servicemirror.NewRemoteClusterServiceWatcher(
+		ctx,
+		c.config.namespace,
+		c.config.controllerK8sAPI,
+		cfg,
+		&link,
+		c.config.watcher,
+		c.config.requeue,
+		c.config.writeBackoff,
+		c.config.circuitBreaker,
+		c.config.k8sAPI.DynamicClient,
+		c.config.recorder,
+	)
+	if err != nil {
+		return fmt.Errorf("Unable to create cluster watcher: %s", err)
+	}
+	c.clusterWatcher = clusterWatcher
+
+	workerMetrics, err := c.config.metrics.NewWorkerMetrics(link.TargetClusterName)
+	if err != nil {
+		return fmt.Errorf("Failed to create metrics for cluster watcher: %s", err)
+	}
+	c.probeWorker = servicemirror.NewProbeWorker(fmt.Sprintf("probe-gateway-%s", link.TargetClusterName), &link.ProbeSpec, workerMetrics, link.TargetClusterName, &link, c.config.k8sAPI.DynamicClient)
+	// the mirror services fail over when the gateway is dead as a whole
+	clusterWatcher.ShareGatewayLiveness(c.probeWorker)
+
+	err = clusterWatcher.Start(ctx)
+	if err != nil {
+		return fmt.Errorf("Failed to start cluster watcher: %s", err)
+	}
+	c.probeWorker.Start()
+	c.currentLink = &link
+	c.credentials = credentials
+	c.remoteConfig = cfg
+	c.setHealthState(c.currentLink, c.probeWorker, c.clusterWatcher)
+	return nil
+}
+
+func (c *linkController) setHealthState(link *multicluster.Link, probe *servicemirror.ProbeWorker, watcher *servicemirror.RemoteClusterServiceWatcher) {
+	c.health.Lock()
+	defer c.health.Unlock()
+	c.health.link = link
+	c.health.probe = probe
+	c.health.watcher = watcher
+}
+
+func (c *linkController) healthState() (*multicluster.Link, *servicemirror.ProbeWorker) {
+	c.health.RLock()
+	defer c.health.RUnlock()
+	return c.health.link, c.health.probe
+}
+
+// watcherState returns the cluster watcher of the link, or nil if it isn't
+// watching the target cluster.
+func (c *linkController) watcherState() *servicemirror.RemoteClusterServiceWatcher {
+	c.health.RLock()
+	defer c.health.RUnlock()
+	return c.health.watcher
+}
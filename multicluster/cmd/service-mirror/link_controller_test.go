@@ -0,0 +1,279 @@
+package servicemirror
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	controllerK8s "github.com/linkerd/linkerd2/controller/k8s"
+	servicemirror "github.com/linkerd/linkerd2/multicluster/service-mirror"
+	"github.com/linkerd/linkerd2/pkg/k8s"
+	"github.com/linkerd/linkerd2/pkg/multicluster"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	testNamespace = "linkerd-multicluster"
+	testTimeout   = 10 * time.Second
+)
+
+func testLink(name string) multicluster.Link {
+	return multicluster.Link{
+		Name:                          name,
+		Namespace:                     testNamespace,
+		TargetClusterName:             name,
+		TargetClusterDomain:           "cluster.local",
+		TargetClusterLinkerdNamespace: "linkerd",
+		ClusterCredentialsSecret:      "cluster-credentials-" + name,
+		GatewayAddress:                "192.0.2.1",
+		GatewayPort:                   4143,
+		GatewayIdentity:               "linkerd-gateway.linkerd-multicluster.serviceaccount.identity.linkerd.cluster.local",
+		ProbeSpec: multicluster.ProbeSpec{
+			Path:   "/ready",
+			Port:   4191,
+			Period: time.Minute,
+		},
+		Selector: metav1.LabelSelector{
+			MatchLabels: map[string]string{k8s.DefaultExportedServiceSelector: "true"},
+		},
+	}
+}
+
+// newTestMirrorConfig returns the config of the controllers of the given
+// links, whose credentials secrets don't exist, so that their cluster
+// watchers fail to start without reaching out to a target cluster.
+func newTestMirrorConfig(t *testing.T, links ...multicluster.Link) (*mirrorConfig, *dynamicfake.FakeDynamicClient) {
+	t.Helper()
+
+	objs := []runtime.Object{}
+	for _, link := range links {
+		obj, err := link.ToUnstructured()
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		objs = append(objs, &obj)
+	}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{multicluster.LinkGVR: "LinkList"},
+		objs...,
+	)
+
+	k8sAPI, err := k8s.NewFakeAPI()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	k8sAPI.DynamicClient = client
+	controllerK8sAPI, err := controllerK8s.NewFakeAPI()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	return &mirrorConfig{
+		namespace:        testNamespace,
+		linkClient:       client.Resource(multicluster.LinkGVR).Namespace(testNamespace),
+		controllerK8sAPI: controllerK8sAPI,
+		k8sAPI:           k8sAPI,
+		recorder:         record.NewFakeRecorder(100),
+		watcher:          servicemirror.DefaultWatcherConfig(),
+		requeue:          servicemirror.DefaultRequeueConfig(),
+		writeBackoff:     servicemirror.DefaultWriteBackoffConfig(),
+		circuitBreaker:   servicemirror.DefaultCircuitBreakerConfig(),
+		remoteClient:     servicemirror.DefaultClientConfig(),
+	}, client
+}
+
+// startWatchLinks watches the links of the test namespace until the test
+// finishes, and waits for the watch to be established.
+func startWatchLinks(t *testing.T, config *mirrorConfig, client *dynamicfake.FakeDynamicClient) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		watchLinks(ctx, "", config)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	waitFor(t, "the links to be watched", func() bool {
+		for _, action := range client.Actions() {
+			if action.GetVerb() == "watch" {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(testTimeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func controllerNames() []string {
+	links, _ := currentControllers()
+	names := []string{}
+	for _, c := range links {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+func hasController(name string) bool {
+	for _, n := range controllerNames() {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func getLink(t *testing.T, client *dynamicfake.FakeDynamicClient, name string) *unstructured.Unstructured {
+	t.Helper()
+	obj, err := client.Resource(multicluster.LinkGVR).Namespace(testNamespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return obj
+}
+
+func authenticatedCondition(t *testing.T, client *dynamicfake.FakeDynamicClient, name string) *metav1.Condition {
+	t.Helper()
+	condition, err := multicluster.GetLinkCondition(context.Background(), client, testNamespace, name, multicluster.LinkConditionAuthenticated)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return condition
+}
+
+func TestWatchLinksAddsLinks(t *testing.T) {
+	config, client := newTestMirrorConfig(t, testLink("east"))
+	startWatchLinks(t, config, client)
+
+	waitFor(t, "the controller of the listed link", func() bool { return hasController("east") })
+	waitFor(t, "the finalizer of the listed link", func() bool {
+		return multicluster.HasLinkFinalizer(getLink(t, client, "east"))
+	})
+
+	west, err := testLink("west").ToUnstructured()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	_, err = config.linkClient.Create(context.Background(), &west, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	waitFor(t, "the controller of the added link", func() bool { return hasController("west") })
+	waitFor(t, "the finalizer of the added link", func() bool {
+		return multicluster.HasLinkFinalizer(getLink(t, client, "west"))
+	})
+	waitFor(t, "the authentication failure of the added link", func() bool {
+		condition := authenticatedCondition(t, client, "west")
+		return condition != nil && condition.Status == metav1.ConditionFalse && condition.Reason == "InvalidKubeconfig"
+	})
+}
+
+func TestWatchLinksUpdatesLinks(t *testing.T) {
+	config, client := newTestMirrorConfig(t, testLink("east"))
+	startWatchLinks(t, config, client)
+
+	var before *metav1.Condition
+	waitFor(t, "the authentication failure of the link", func() bool {
+		before = authenticatedCondition(t, client, "east")
+		return before != nil
+	})
+
+	// the new credentials can be read, but aren't a valid kubeconfig, so
+	// that the failure differs from the one of the missing credentials
+	_, err := config.k8sAPI.CoreV1().Secrets(testNamespace).Create(context.Background(), &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster-credentials-east-2", Namespace: testNamespace},
+		Type:       k8s.MirrorSecretType,
+		Data:       map[string][]byte{k8s.ConfigKeyName: []byte("{")},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	link := getLink(t, client, "east")
+	if err := unstructured.SetNestedField(link.Object, "cluster-credentials-east-2", "spec", "clusterCredentialsSecret"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, err := config.linkClient.Update(context.Background(), link, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	waitFor(t, "the link to be reconciled with its new credentials", func() bool {
+		after := authenticatedCondition(t, client, "east")
+		return after != nil && after.Message != before.Message
+	})
+	if names := controllerNames(); len(names) != 1 || names[0] != "east" {
+		t.Fatalf("Expected the updated link to keep its controller, got %v", names)
+	}
+}
+
+func TestWatchLinksRemovesLinks(t *testing.T) {
+	config, client := newTestMirrorConfig(t, testLink("east"), testLink("west"))
+	startWatchLinks(t, config, client)
+
+	waitFor(t, "the controllers of the listed links", func() bool {
+		return hasController("east") && hasController("west")
+	})
+
+	if err := config.linkClient.Delete(context.Background(), "west", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	waitFor(t, "the controller of the deleted link to be stopped", func() bool { return !hasController("west") })
+	if !hasController("east") {
+		t.Fatalf("Expected the controller of the remaining link to be kept, got %v", controllerNames())
+	}
+}
+
+func TestSyncControllersRemovesUnlistedLinks(t *testing.T) {
+	config, client := newTestMirrorConfig(t, testLink("east"), testLink("west"))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		stopControllers()
+	}()
+
+	links, err := config.linkClient.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	syncControllers(ctx, links.Items, config)
+	if !hasController("east") || !hasController("west") {
+		t.Fatalf("Expected controllers for the listed links, got %v", controllerNames())
+	}
+
+	// the links deleted while the link watch was down aren't listed anymore
+	// when it's restarted
+	err = client.Resource(multicluster.LinkGVR).Namespace(testNamespace).Delete(ctx, "west", metav1.DeleteOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	links, err = config.linkClient.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	syncControllers(ctx, links.Items, config)
+	if !hasController("east") || hasController("west") {
+		t.Fatalf("Expected only the controller of the listed link, got %v", controllerNames())
+	}
+}
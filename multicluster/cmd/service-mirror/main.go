@@ -4,8 +4,12 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"sort"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -13,21 +17,53 @@ import (
 	servicemirror "github.com/linkerd/linkerd2/multicluster/service-mirror"
 	"github.com/linkerd/linkerd2/pkg/admin"
 	"github.com/linkerd/linkerd2/pkg/flags"
+	"github.com/linkerd/linkerd2/pkg/health"
 	"github.com/linkerd/linkerd2/pkg/k8s"
 	"github.com/linkerd/linkerd2/pkg/multicluster"
-	sm "github.com/linkerd/linkerd2/pkg/servicemirror"
+	"github.com/linkerd/linkerd2/pkg/trace"
 	log "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	dynamic "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/watch"
-	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/kubernetes/scheme"
+	coordinationv1 "k8s.io/client-go/kubernetes/typed/coordination/v1"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+	"k8s.io/client-go/tools/record"
 )
 
-const linkWatchRestartAfter = 10 * time.Second
+const (
+	linkWatchRestartAfter = 10 * time.Second
+	linkCleanupRetryAfter = 5 * time.Second
+
+	// leader election timings, following the defaults of the Kubernetes
+	// controllers
+	leaseDuration = 15 * time.Second
+	renewDeadline = 10 * time.Second
+	retryPeriod   = 2 * time.Second
+
+	// watchersHealthPath is the admin server endpoint reporting the health
+	// of the cluster watchers, probed by Kubernetes
+	watchersHealthPath = "/watchers-health"
+)
 
 var (
-	clusterWatcher *servicemirror.RemoteClusterServiceWatcher
-	probeWorker    *servicemirror.ProbeWorker
+	// controllers holds the controller of each link being mirrored, keyed by
+	// link name
+	controllers = struct {
+		sync.RWMutex
+		byName map[string]*linkController
+	}{byName: map[string]*linkController{}}
+
+	// standby is true while another replica holds the leader lease
+	standby struct {
+		sync.RWMutex
+		value bool
+	}
 )
 
 // Main executes the service-mirror controller
@@ -35,13 +71,68 @@ func Main(args []string) {
 	cmd := flag.NewFlagSet("service-mirror", flag.ExitOnError)
 
 	kubeConfigPath := cmd.String("kubeconfig", "", "path to the local kube config")
-	requeueLimit := cmd.Int("event-requeue-limit", 3, "requeue limit for events")
+	requeue := servicemirror.DefaultRequeueConfig()
+	cmd.IntVar(&requeue.Limit, "event-requeue-limit", requeue.Limit, "requeue limit for events")
+	cmd.DurationVar(&requeue.BaseDelay, "event-requeue-base-delay", requeue.BaseDelay, "delay before the first retry of an event, doubled on each subsequent retry")
+	cmd.DurationVar(&requeue.MaxDelay, "event-requeue-max-delay", requeue.MaxDelay, "maximum delay between two retries of an event")
+	cmd.Float64Var(&requeue.QPS, "event-requeue-qps", requeue.QPS, "maximum number of event retries per second, across all events")
+	cmd.IntVar(&requeue.Burst, "event-requeue-burst", requeue.Burst, "number of event retries allowed above event-requeue-qps")
+	writeBackoff := servicemirror.DefaultWriteBackoffConfig()
+	cmd.IntVar(&writeBackoff.Attempts, "write-attempts", writeBackoff.Attempts, "number of times a write to the local API is attempted when it's throttled or times out, before the event it's part of fails")
+	cmd.DurationVar(&writeBackoff.BaseDelay, "write-backoff-base-delay", writeBackoff.BaseDelay, "delay before the first retry of a write to the local API, doubled on each subsequent retry")
+	cmd.DurationVar(&writeBackoff.MaxDelay, "write-backoff-max-delay", writeBackoff.MaxDelay, "maximum delay between two retries of a write to the local API")
+	localClient := servicemirror.DefaultClientConfig()
+	localQPS := cmd.Float64("local-api-qps", float64(localClient.QPS), "maximum number of requests per second to the local API server")
+	cmd.IntVar(&localClient.Burst, "local-api-burst", localClient.Burst, "number of requests to the local API server allowed above local-api-qps")
+	remoteClient := servicemirror.DefaultClientConfig()
+	remoteQPS := cmd.Float64("remote-api-qps", float64(remoteClient.QPS), "maximum number of requests per second to the API server of each target cluster")
+	cmd.IntVar(&remoteClient.Burst, "remote-api-burst", remoteClient.Burst, "number of requests to the API server of each target cluster allowed above remote-api-qps")
+	circuitBreaker := servicemirror.DefaultCircuitBreakerConfig()
+	cmd.DurationVar(&circuitBreaker.CheckPeriod, "remote-check-period", circuitBreaker.CheckPeriod, "time between two checks of the API server of each target cluster")
+	cmd.DurationVar(&circuitBreaker.Timeout, "remote-check-timeout", circuitBreaker.Timeout, "timeout of a check of the API server of a target cluster")
+	cmd.IntVar(&circuitBreaker.FailureThreshold, "remote-failure-threshold", circuitBreaker.FailureThreshold, "number of consecutive failed checks after which the API server of a target cluster is reported as unreachable, and the processing of its events paused until it's reachable again")
 	metricsAddr := cmd.String("metrics-addr", ":9999", "address to serve scrapable metrics on")
 	namespace := cmd.String("namespace", "", "namespace containing Link and credentials Secret")
-	repairPeriod := cmd.Duration("endpoint-refresh-period", 1*time.Minute, "frequency to refresh endpoint resolution")
+	watcher := servicemirror.DefaultWatcherConfig()
+	cmd.DurationVar(&watcher.RepairPeriod, "endpoint-refresh-period", watcher.RepairPeriod, "frequency to refresh endpoint resolution; backs off up to 8 times as long while the refreshes find nothing to change")
+	cmd.IntVar(&watcher.InitialSyncRate, "initial-sync-rate", watcher.InitialSyncRate, "maximum number of mirror services created per second when starting to watch the target cluster")
+	cmd.BoolVar(&watcher.ServiceImports, "enable-service-imports", watcher.ServiceImports, "maintain a Multi-Cluster Services API ServiceImport for each mirror service")
+	cmd.BoolVar(&watcher.RemoteServiceExports, "enable-remote-service-exports", watcher.RemoteServiceExports, "also mirror the services of the target cluster that have a Multi-Cluster Services API ServiceExport")
+	cmd.IntVar(&watcher.Workers, "event-workers", watcher.Workers, "number of events processed concurrently; the events of a given service are always processed in order")
+	cmd.BoolVar(&watcher.DryRun, "dry-run", watcher.DryRun, "log and count the changes to the mirror resources of the local cluster instead of making them")
+	recordedEventsBurst := cmd.Int("recorded-events-burst", 25, "number of Kubernetes events recorded about the same object before they are rate limited to recorded-events-qps")
+	recordedEventsQPS := cmd.Float64("recorded-events-qps", 1.0/300, "maximum number of Kubernetes events recorded per second about the same object, once recorded-events-burst is exhausted")
+	stallThreshold := cmd.Duration("event-stall-threshold", 5*time.Minute, "time after which a cluster watcher with events waiting, but none processed successfully, is reported as unhealthy on the watchers health endpoint, so that it gets restarted")
+	enableLeaderElection := cmd.Bool("enable-leader-election", false, "only mirror services from the replica holding the Lease of the link, so that the service mirror can run with multiple replicas")
+	credentialPluginsPath := cmd.String("credential-plugins-path", "", "directory searched first for the exec credential plugins, such as aws or gke-gcloud-auth-plugin, of the kubeconfigs of the target clusters")
+	traceCollector := flags.AddTraceFlags(cmd)
 
 	flags.ConfigureAndParse(cmd, args)
+	localClient.QPS = float32(*localQPS)
+	remoteClient.QPS = float32(*remoteQPS)
+	for _, err := range []error{localClient.Validate(), remoteClient.Validate(), writeBackoff.Validate(), circuitBreaker.Validate()} {
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *credentialPluginsPath != "" {
+		if err := servicemirror.UseCredentialPlugins(*credentialPluginsPath); err != nil {
+			log.Fatal(err)
+		}
+	}
+	// When a link name is given, only that link is mirrored; otherwise all the
+	// links of the namespace are, each by its own cluster watcher.
 	linkName := cmd.Arg(0)
+	component := "service-mirror"
+	if linkName != "" {
+		component = fmt.Sprintf("service-mirror-%s", linkName)
+	}
+
+	if *traceCollector != "" {
+		if err := trace.InitializeTracing(fmt.Sprintf("linkerd-%s", component), *traceCollector); err != nil {
+			log.Warnf("failed to initialize tracing: %s", err)
+		}
+	}
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
@@ -52,16 +143,24 @@ func Main(args []string) {
 	//
 	// controllerK8sAPI is used by the cluster watcher to manage
 	// mirror resources such as services, namespaces, and endpoints.
-	k8sAPI, err := k8s.NewAPI(*kubeConfigPath, "", "", []string{}, 0)
+	//
+	// Both are rate limited by the local-api-qps and local-api-burst flags.
+	localConfig, err := k8s.GetConfig(*kubeConfigPath, "")
+	if err != nil {
+		log.Fatalf("Failed to configure K8s API client: %s", err)
+	}
+	localClient.Apply(localConfig)
+
+	k8sAPI, err := k8s.NewAPIForConfig(rest.CopyConfig(localConfig), "", []string{}, 0)
 	//TODO: Use can-i to check for required permissions
 	if err != nil {
 		log.Fatalf("Failed to initialize K8s API: %s", err)
 	}
 
 	ctx := context.Background()
-	controllerK8sAPI, err := controllerK8s.InitializeAPI(
+	controllerK8sAPI, err := controllerK8s.InitializeAPIForConfig(
 		ctx,
-		*kubeConfigPath,
+		rest.CopyConfig(localConfig),
 		false,
 		controllerK8s.NS,
 		controllerK8s.Svc,
@@ -73,131 +172,371 @@ func Main(args []string) {
 
 	linkClient := k8sAPI.DynamicClient.Resource(multicluster.LinkGVR).Namespace(*namespace)
 
-	metrics := servicemirror.NewProbeMetricVecs()
-	go admin.StartServer(*metricsAddr)
+	// Similar events are aggregated, and the events about the same object are
+	// rate limited, so that a failing target cluster doesn't flood the local
+	// cluster with events
+	eventBroadcaster := record.NewBroadcasterWithCorrelatorOptions(record.CorrelatorOptions{
+		BurstSize: *recordedEventsBurst,
+		QPS:       float32(*recordedEventsQPS),
+	})
+	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{
+		Interface: k8sAPI.CoreV1().Events(""),
+	})
+	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: fmt.Sprintf("linkerd-%s", component)})
+
+	config := &mirrorConfig{
+		namespace:        *namespace,
+		linkClient:       linkClient,
+		controllerK8sAPI: controllerK8sAPI,
+		k8sAPI:           k8sAPI,
+		recorder:         recorder,
+		watcher:          watcher,
+		requeue:          requeue,
+		writeBackoff:     writeBackoff,
+		circuitBreaker:   circuitBreaker,
+		remoteClient:     remoteClient,
+		metrics:          servicemirror.NewProbeMetricVecs(),
+	}
+	go admin.StartServerWithHandlers(*metricsAddr, newHealthReporter(component, linkName, *stallThreshold), map[string]http.Handler{
+		watchersHealthPath: newWatchersReporter(component, *stallThreshold),
+	})
 
 	controllerK8sAPI.Sync(nil)
 
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		<-stop
+		cancel()
+	}()
+
+	run := func(ctx context.Context) {
+		watchLinks(ctx, linkName, config)
+	}
+
+	if !*enableLeaderElection {
+		run(ctx)
+		log.Info("Shutting down")
+		return
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		log.Fatalf("Failed to get hostname: %s", err)
+	}
+	runLeaderElection(ctx, k8sAPI.CoordinationV1(), *namespace, component, hostname, run)
+
+	log.Info("Shutting down")
+}
+
+// runLeaderElection calls run while the replica, identified by identity,
+// holds the Lease of the component, standing by otherwise, until the context
+// is canceled. The replica exits when it loses the Lease.
+func runLeaderElection(ctx context.Context, client coordinationv1.LeasesGetter, namespace, component, identity string, run func(context.Context)) {
+	setStandby(true)
+	leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+		Lock: &resourcelock.LeaseLock{
+			LeaseMeta: metav1.ObjectMeta{
+				Name:      strings.Replace(component, "service-mirror", "service-mirror-write", 1),
+				Namespace: namespace,
+			},
+			Client: client,
+			LockConfig: resourcelock.ResourceLockConfig{
+				Identity: identity,
+			},
+		},
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   renewDeadline,
+		RetryPeriod:     retryPeriod,
+		ReleaseOnCancel: true,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				log.Infof("%s is now leading the %s", identity, component)
+				setStandby(false)
+				run(ctx)
+			},
+			OnStoppedLeading: func() {
+				if ctx.Err() != nil {
+					return
+				}
+				// the mirrored state might have diverged while the lease was
+				// lost; restart from scratch rather than resuming
+				log.Fatalf("%s lost the lease of the %s", identity, component)
+			},
+			OnNewLeader: func(leader string) {
+				if leader != identity {
+					log.Infof("Standing by; %s is leading the %s", leader, component)
+				}
+			},
+		},
+	})
+}
+
+// watchLinks watches the links of the namespace, or only the given link if
+// linkName isn't empty, and starts a controller for each of them, until the
+// context is canceled. The controllers are stopped when their link is
+// deleted.
+func watchLinks(ctx context.Context, linkName string, config *mirrorConfig) {
+	defer stopControllers()
+
+	listOptions := metav1.ListOptions{}
+	if linkName != "" {
+		listOptions.FieldSelector = fields.OneTermEqualSelector("metadata.name", linkName).String()
+	}
+
 main:
 	for {
-		// Start link watch
-		linkWatch, err := linkClient.Watch(ctx, metav1.ListOptions{})
+		// List the links before watching them, so that the links deleted
+		// while the watch was down are noticed when it's restarted
+		links, err := config.linkClient.List(ctx, listOptions)
 		if err != nil {
-			log.Fatalf("Failed to watch Link %s: %s", linkName, err)
+			if ctx.Err() != nil {
+				return
+			}
+			log.Errorf("Failed to list links: %s", err)
+			time.Sleep(linkWatchRestartAfter)
+			continue
+		}
+		syncControllers(ctx, links.Items, config)
+
+		watchOptions := listOptions
+		watchOptions.ResourceVersion = links.GetResourceVersion()
+		linkWatch, err := config.linkClient.Watch(ctx, watchOptions)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Errorf("Failed to watch links: %s", err)
+			time.Sleep(linkWatchRestartAfter)
+			continue
 		}
 		results := linkWatch.ResultChan()
 
-		// Each time the link resource is updated, reload the config and restart the
-		// cluster watcher.
 		for {
 			select {
-			case <-stop:
+			case <-ctx.Done():
+				linkWatch.Stop()
 				break main
 			case event, ok := <-results:
 				if !ok {
 					log.Info("Link watch terminated; restarting watch")
 					continue main
 				}
-				switch obj := event.Object.(type) {
-				case *dynamic.Unstructured:
-					if obj.GetName() == linkName {
-						switch event.Type {
-						case watch.Added, watch.Modified:
-							link, err := multicluster.NewLink(*obj)
-							if err != nil {
-								log.Errorf("Failed to parse link %s: %s", linkName, err)
-								continue
-							}
-							log.Infof("Got updated link %s: %+v", linkName, link)
-							creds, err := loadCredentials(ctx, link, *namespace, k8sAPI)
-							if err != nil {
-								log.Errorf("Failed to load remote cluster credentials: %s", err)
-							}
-							err = restartClusterWatcher(ctx, link, *namespace, creds, controllerK8sAPI, *requeueLimit, *repairPeriod, metrics)
-							if err != nil {
-								// failed to restart cluster watcher; give a bit of slack
-								// and restart the link watch to give it another try
-								log.Error(err)
-								time.Sleep(linkWatchRestartAfter)
-								linkWatch.Stop()
-							}
-						case watch.Deleted:
-							log.Infof("Link %s deleted", linkName)
-							if clusterWatcher != nil {
-								clusterWatcher.Stop(false)
-								clusterWatcher = nil
-							}
-							if probeWorker != nil {
-								probeWorker.Stop()
-								probeWorker = nil
-							}
-						default:
-							log.Infof("Ignoring event type %s", event.Type)
-						}
-					}
+				obj, ok := event.Object.(*dynamic.Unstructured)
+				if !ok {
+					log.Errorf("Unknown object type detected: %+v", event.Object)
+					continue
+				}
+				switch event.Type {
+				case watch.Added, watch.Modified:
+					controllerFor(ctx, obj.GetName(), config).update(obj, false)
+				case watch.Deleted:
+					removeController(obj.GetName())
 				default:
-					log.Errorf("Unknown object type detected: %+v", obj)
+					log.Infof("Ignoring event type %s", event.Type)
 				}
 			}
 		}
 	}
-	log.Info("Shutting down")
 }
 
-func loadCredentials(ctx context.Context, link multicluster.Link, namespace string, k8sAPI *k8s.KubernetesAPI) ([]byte, error) {
-	// Load the credentials secret
-	secret, err := k8sAPI.Interface.CoreV1().Secrets(namespace).Get(ctx, link.ClusterCredentialsSecret, metav1.GetOptions{})
-	if err != nil {
-		return nil, fmt.Errorf("Failed to load credentials secret %s: %s", link.ClusterCredentialsSecret, err)
+// syncControllers updates the controllers of the listed links, and stops the
+// controllers of the links that aren't listed anymore.
+func syncControllers(ctx context.Context, links []dynamic.Unstructured, config *mirrorConfig) {
+	listed := make(map[string]struct{}, len(links))
+	for i := range links {
+		link := &links[i]
+		listed[link.GetName()] = struct{}{}
+		controllerFor(ctx, link.GetName(), config).update(link, false)
+	}
+
+	controllers.RLock()
+	var removed []string
+	for name := range controllers.byName {
+		if _, ok := listed[name]; !ok {
+			removed = append(removed, name)
+		}
+	}
+	controllers.RUnlock()
+	for _, name := range removed {
+		removeController(name)
 	}
-	return sm.ParseRemoteClusterSecret(secret)
 }
 
-func restartClusterWatcher(
-	ctx context.Context,
-	link multicluster.Link,
-	namespace string,
-	creds []byte,
-	controllerK8sAPI *controllerK8s.API,
-	requeueLimit int,
-	repairPeriod time.Duration,
-	metrics servicemirror.ProbeMetricVecs,
-) error {
-	if clusterWatcher != nil {
-		clusterWatcher.Stop(false)
+// controllerFor returns the controller of the given link, starting it if the
+// link doesn't have one yet.
+func controllerFor(ctx context.Context, name string, config *mirrorConfig) *linkController {
+	controllers.Lock()
+	defer controllers.Unlock()
+	if c, ok := controllers.byName[name]; ok {
+		return c
 	}
-	if probeWorker != nil {
-		probeWorker.Stop()
+	log.Infof("Starting the controller of link %s", name)
+	c := newLinkController(name, config)
+	controllers.byName[name] = c
+	go c.run(ctx)
+	return c
+}
+
+// removeController stops the controller of the given deleted link.
+func removeController(name string) {
+	controllers.Lock()
+	c, ok := controllers.byName[name]
+	delete(controllers.byName, name)
+	controllers.Unlock()
+	if ok {
+		c.update(nil, true)
 	}
+}
 
-	cfg, err := clientcmd.RESTConfigFromKubeConfig(creds)
-	if err != nil {
-		return fmt.Errorf("Unable to parse kube config: %s", err)
+func stopControllers() {
+	controllers.Lock()
+	defer controllers.Unlock()
+	for name, c := range controllers.byName {
+		c.update(nil, true)
+		delete(controllers.byName, name)
 	}
+}
 
-	clusterWatcher, err = servicemirror.NewRemoteClusterServiceWatcher(
-		ctx,
-		namespace,
-		controllerK8sAPI,
-		cfg,
-		&link,
-		requeueLimit,
-		repairPeriod,
-	)
-	if err != nil {
-		return fmt.Errorf("Unable to create cluster watcher: %s", err)
+func setStandby(value bool) {
+	standby.Lock()
+	defer standby.Unlock()
+	standby.value = value
+}
+
+// currentControllers returns the controllers of the links being mirrored,
+// sorted by link name, or false if the replica is standing by for the leader
+// lease.
+func currentControllers() ([]*linkController, bool) {
+	standby.RLock()
+	standingBy := standby.value
+	standby.RUnlock()
+	if standingBy {
+		return nil, false
 	}
 
-	err = clusterWatcher.Start(ctx)
-	if err != nil {
-		return fmt.Errorf("Failed to start cluster watcher: %s", err)
+	controllers.RLock()
+	links := make([]*linkController, 0, len(controllers.byName))
+	for _, c := range controllers.byName {
+		links = append(links, c)
 	}
+	controllers.RUnlock()
+	sort.Slice(links, func(i, j int) bool { return links[i].name < links[j].name })
+	return links, true
+}
 
-	workerMetrics, err := metrics.NewWorkerMetrics(link.TargetClusterName)
-	if err != nil {
-		return fmt.Errorf("Failed to create metrics for cluster watcher: %s", err)
+func standbyCheck(component string) health.Check {
+	return health.Check{
+		Name:    "leader-election",
+		Healthy: true,
+		Message: fmt.Sprintf("standing by; another replica is leading the %s", component),
+	}
+}
+
+// newHealthReporter returns the reporter of the health of the service mirror,
+// which is healthy when it's watching the target cluster of each link, is
+// processing its events, and the latest probe of each gateway succeeded.
+// Replicas standing by for the leader lease are reported as healthy.
+func newHealthReporter(component, linkName string, stallThreshold time.Duration) *health.Reporter {
+	reporter := health.NewReporter("multicluster", component)
+	reporter.AddChecker(func(context.Context) []health.Check {
+		links, leading := currentControllers()
+		if !leading {
+			return []health.Check{standbyCheck(component)}
+		}
+
+		if linkName != "" && len(links) == 0 {
+			return []health.Check{{
+				Name:    "target-cluster-watched",
+				Message: fmt.Sprintf("not watching the target cluster of link %s", linkName),
+			}}
+		}
+
+		var checks []health.Check
+		for _, c := range links {
+			// the checks are only qualified by the name of the link when the
+			// service mirror is managing all the links of the namespace
+			prefix := ""
+			if linkName == "" {
+				prefix = c.name + "/"
+			}
+			checks = append(checks, linkChecks(c, prefix, stallThreshold)...)
+		}
+		return checks
+	})
+	return reporter
+}
+
+// newWatchersReporter returns the reporter of the health of the cluster
+// watchers, on which the probes of the service mirror rely: it's unhealthy
+// when the informers of a watcher haven't synced, or when its events are
+// stalled. The links whose target cluster isn't watched, e.g. because it's
+// unreachable, aren't reported, as restarting wouldn't help.
+func newWatchersReporter(component string, stallThreshold time.Duration) *health.Reporter {
+	reporter := health.NewReporter("multicluster", component)
+	reporter.AddChecker(func(context.Context) []health.Check {
+		links, leading := currentControllers()
+		if !leading {
+			return []health.Check{standbyCheck(component)}
+		}
+
+		var checks []health.Check
+		for _, c := range links {
+			if watcher := c.watcherState(); watcher != nil {
+				checks = append(checks, watcherChecks(watcher, c.name+"/", stallThreshold)...)
+			}
+		}
+		return checks
+	})
+	return reporter
+}
+
+// linkChecks returns the health checks of the given link controller.
+func linkChecks(c *linkController, prefix string, stallThreshold time.Duration) []health.Check {
+	link, probe := c.healthState()
+
+	watching := health.Check{Name: prefix + "target-cluster-watched", Healthy: link != nil}
+	if link == nil {
+		watching.Message = fmt.Sprintf("not watching the target cluster of link %s", c.name)
+		return []health.Check{watching}
+	}
+	checks := []health.Check{watching}
+	if watcher := c.watcherState(); watcher != nil {
+		checks = append(checks, watcherChecks(watcher, prefix, stallThreshold)...)
+	}
+
+	gateway := health.Check{Name: prefix + "gateway-alive", Healthy: true}
+	if result := probe.Result(); result == nil {
+		gateway.Message = "gateway not probed yet"
+	} else if !result.Alive {
+		gateway.Healthy = false
+		gateway.Message = fmt.Sprintf("gateway probe failed: %s", result.Error)
+	}
+	return append(checks, gateway)
+}
+
+// watcherChecks returns the health checks of the processing of the events
+// of the given cluster watcher.
+func watcherChecks(watcher *servicemirror.RemoteClusterServiceWatcher, prefix string, stallThreshold time.Duration) []health.Check {
+	state := watcher.Health()
+	now := time.Now()
+
+	synced := health.Check{Name: prefix + "informers-synced", Healthy: state.Synced}
+	if !state.Synced {
+		synced.Message = "the informers on the target cluster haven't synced"
+	}
+
+	since := now.Sub(state.LastProcessed).Round(time.Second)
+	processing := health.Check{
+		Name:    prefix + "events-processed",
+		Healthy: true,
+		Message: fmt.Sprintf("%d events waiting; last event processed %s ago", state.Backlog, since),
+	}
+	if state.Unreachable {
+		processing.Message = fmt.Sprintf("%d events waiting; processing paused while the target cluster is unreachable", state.Backlog)
+	}
+	if state.Stalled(stallThreshold, now) {
+		processing.Healthy = false
+		processing.Message = fmt.Sprintf("%d events waiting, but none processed for %s", state.Backlog, since)
 	}
-	probeWorker = servicemirror.NewProbeWorker(fmt.Sprintf("probe-gateway-%s", link.TargetClusterName), &link.ProbeSpec, workerMetrics, link.TargetClusterName)
-	probeWorker.Start()
-	return nil
+	return []health.Check{synced, processing}
 }
@@ -0,0 +1,117 @@
+package servicemirror
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/linkerd/linkerd2/pkg/health"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const testComponent = "service-mirror-east"
+
+// startLeaderElection runs the leader election of the test component as
+// the given replica until the test finishes.
+func startLeaderElection(t *testing.T, client *fake.Clientset, identity string, run func(context.Context)) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		runLeaderElection(ctx, client.CoordinationV1(), testNamespace, testComponent, identity, run)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		setStandby(false)
+	})
+}
+
+func leaseActions(client *fake.Clientset, verb string) int {
+	count := 0
+	for _, action := range client.Actions() {
+		if action.GetResource().Resource == "leases" && action.GetVerb() == verb {
+			count++
+		}
+	}
+	return count
+}
+
+func TestRunLeaderElectionStandsBy(t *testing.T) {
+	holder := "linkerd-service-mirror-east-7d9f8b-x2k4p"
+	duration := int32(leaseDuration.Seconds())
+	now := metav1.NewMicroTime(time.Now())
+	client := fake.NewSimpleClientset(&coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: "service-mirror-write-east", Namespace: testNamespace},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       &holder,
+			LeaseDurationSeconds: &duration,
+			AcquireTime:          &now,
+			RenewTime:            &now,
+		},
+	})
+
+	var ran int32
+	startLeaderElection(t, client, "linkerd-service-mirror-east-7d9f8b-q8w5z", func(ctx context.Context) {
+		atomic.StoreInt32(&ran, 1)
+		<-ctx.Done()
+	})
+	waitFor(t, "the lease to be checked", func() bool { return leaseActions(client, "get") > 0 })
+
+	if atomic.LoadInt32(&ran) != 0 {
+		t.Fatal("Expected the standby replica not to mirror services")
+	}
+	if n := leaseActions(client, "update"); n != 0 {
+		t.Fatalf("Expected the standby replica not to take the lease, got %d updates", n)
+	}
+	if names := controllerNames(); len(names) != 0 {
+		t.Fatalf("Expected the standby replica not to run controllers, got %v", names)
+	}
+
+	for name, reporter := range map[string]*health.Reporter{
+		"health":          newHealthReporter(testComponent, "east", time.Minute),
+		"watchers health": newWatchersReporter(testComponent, time.Minute),
+	} {
+		report := reporter.Report(context.Background())
+		if !report.Healthy {
+			t.Fatalf("Expected the %s of the standby replica to be healthy, got %+v", name, report)
+		}
+		if len(report.Checks) != 1 || report.Checks[0].Name != "leader-election" {
+			t.Fatalf("Expected the %s of the standby replica to only report its leader election, got %+v", name, report.Checks)
+		}
+	}
+}
+
+func TestRunLeaderElectionLeads(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	identity := "linkerd-service-mirror-east-7d9f8b-q8w5z"
+	var ran int32
+	startLeaderElection(t, client, identity, func(ctx context.Context) {
+		atomic.StoreInt32(&ran, 1)
+		<-ctx.Done()
+	})
+	waitFor(t, "the services to be mirrored", func() bool { return atomic.LoadInt32(&ran) == 1 })
+
+	lease, err := client.CoordinationV1().Leases(testNamespace).Get(context.Background(), "service-mirror-write-east", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if lease.Spec.HolderIdentity == nil || *lease.Spec.HolderIdentity != identity {
+		t.Fatalf("Expected the lease to be held by %s, got %v", identity, lease.Spec.HolderIdentity)
+	}
+
+	// the leader reports the health of its links rather than standing by
+	report := newHealthReporter(testComponent, "east", time.Minute).Report(context.Background())
+	if report.Healthy {
+		t.Fatalf("Expected the leader not watching its link to be unhealthy, got %+v", report)
+	}
+	if len(report.Checks) != 1 || report.Checks[0].Name != "target-cluster-watched" {
+		t.Fatalf("Expected the leader to report its link, got %+v", report.Checks)
+	}
+}
@@ -1,10 +1,12 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	pkgcmd "github.com/linkerd/linkerd2/pkg/cmd"
 	"github.com/linkerd/linkerd2/pkg/k8s"
@@ -14,8 +16,9 @@ import (
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	rbac "k8s.io/api/rbac/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/apimachinery/pkg/util/wait"
 )
 
 func newUnlinkCommand() *cobra.Command {
@@ -25,6 +28,11 @@ func newUnlinkCommand() *cobra.Command {
 		os.Exit(1)
 	}
 
+	var drain bool
+	var drainTimeout time.Duration
+	var gc bool
+	var gcTimeout time.Duration
+
 	cmd := &cobra.Command{
 		Use:   "unlink",
 		Short: "Outputs link resources for deletion",
@@ -34,28 +42,40 @@ func newUnlinkCommand() *cobra.Command {
 			if opts.clusterName == "" {
 				return errors.New("You need to specify cluster name")
 			}
+			if cmd.Flags().Changed("drain-timeout") && !drain {
+				return errors.New("--drain-timeout can only be set along with --drain")
+			}
 
-			rules := clientcmd.NewDefaultClientConfigLoadingRules()
-			rules.ExplicitPath = kubeconfigPath
-			loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{})
-			config, err := loader.RawConfig()
+			k, err := k8s.NewAPI(kubeconfigPath, kubeContext, impersonate, impersonateGroup, 0)
 			if err != nil {
 				return err
 			}
 
-			if kubeContext != "" {
-				config.CurrentContext = kubeContext
-			}
-
-			k, err := k8s.NewAPI(kubeconfigPath, config.CurrentContext, impersonate, impersonateGroup, 0)
+			_, err = mc.GetLink(cmd.Context(), k.DynamicClient, opts.namespace, opts.clusterName)
 			if err != nil {
 				return err
 			}
 
-			_, err = mc.GetLink(cmd.Context(), k.DynamicClient, opts.namespace, opts.clusterName)
+			ownServiceMirror, err := hasOwnServiceMirror(cmd.Context(), k, opts.namespace, opts.clusterName)
 			if err != nil {
 				return err
 			}
+			if !ownServiceMirror && (drain || gc) {
+				return fmt.Errorf("the Link %s is mirrored by the service mirror of all the links of the %s namespace, which can't be stopped for it; --drain and --gc aren't supported, the mirrors are removed by the service mirror when the Link is deleted", opts.clusterName, opts.namespace)
+			}
+
+			// the service mirror is deleted along with the Link, so it
+			// wouldn't be around to remove the Link's finalizer; the
+			// resources the finalizer waits for are output for deletion
+			// below instead. A service mirror mirroring all the links
+			// stays around, and removes the finalizer itself once it has
+			// removed the mirrors.
+			if ownServiceMirror {
+				err = mc.RemoveLinkFinalizer(cmd.Context(), k.DynamicClient, opts.namespace, opts.clusterName)
+				if err != nil {
+					return err
+				}
+			}
 
 			secret := resource.NewNamespaced(corev1.SchemeGroupVersion.String(), "Secret", fmt.Sprintf("cluster-credentials-%s", opts.clusterName), opts.namespace)
 			gatewayMirror := resource.NewNamespaced(corev1.SchemeGroupVersion.String(), "Service", fmt.Sprintf("probe-gateway-%s", opts.clusterName), opts.namespace)
@@ -67,15 +87,46 @@ func newUnlinkCommand() *cobra.Command {
 			serviceAccount := resource.NewNamespaced(corev1.SchemeGroupVersion.String(), "ServiceAccount", fmt.Sprintf("linkerd-service-mirror-%s", opts.clusterName), opts.namespace)
 			serviceMirror := resource.NewNamespaced(appsv1.SchemeGroupVersion.String(), "Deployment", fmt.Sprintf("linkerd-service-mirror-%s", opts.clusterName), opts.namespace)
 
-			resources := []resource.Kubernetes{
-				secret, gatewayMirror, link, clusterRole, clusterRoleBinding,
-				role, roleBinding, serviceAccount, serviceMirror,
+			resources := []resource.Kubernetes{secret}
+			// with --gc the gateway mirror is deleted along with the other
+			// mirrors
+			if !gc {
+				resources = append(resources, gatewayMirror)
+			}
+			resources = append(resources, link)
+			if ownServiceMirror {
+				resources = append(resources,
+					clusterRole, clusterRoleBinding,
+					role, roleBinding, serviceAccount, serviceMirror,
+				)
+			}
+
+			selector := mc.MirrorLabelSelector(opts.clusterName)
+
+			if drain {
+				err = drainMirrors(cmd.Context(), k, opts.namespace, opts.clusterName, selector, drainTimeout)
+				if err != nil {
+					return err
+				}
+			}
+
+			if gc {
+				ctx, cancel := context.WithTimeout(cmd.Context(), gcTimeout)
+				defer cancel()
+				// the service mirror would otherwise recreate the mirrors;
+				// it's already scaled down if they were drained
+				if !drain {
+					err = scaleDownServiceMirror(ctx, k, opts.namespace, opts.clusterName)
+					if err != nil {
+						return err
+					}
+				}
+				err = gcMirrors(ctx, k, opts.namespace, opts.clusterName, selector)
+				if err != nil {
+					return err
+				}
 			}
 
-			selector := fmt.Sprintf("%s=%s,%s=%s",
-				k8s.MirroredResourceLabel, "true",
-				k8s.RemoteClusterNameLabel, opts.clusterName,
-			)
 			svcList, err := k.CoreV1().Services(metav1.NamespaceAll).List(cmd.Context(), metav1.ListOptions{LabelSelector: selector})
 			if err != nil {
 				return err
@@ -96,15 +147,204 @@ func newUnlinkCommand() *cobra.Command {
 
 	cmd.Flags().StringVar(&opts.namespace, "namespace", defaultMulticlusterNamespace, "The namespace for the service account")
 	cmd.Flags().StringVar(&opts.clusterName, "cluster-name", "", "Cluster name")
+	cmd.Flags().BoolVar(&gc, "gc", false, "Delete the mirrored services, their endpoints, the gateway mirror and the namespaces created for the mirrors that nothing else is left in, and wait until they're gone before outputting the remaining resources for deletion")
+	cmd.Flags().DurationVar(&gcTimeout, "gc-timeout", 5*time.Minute, "How long to wait for the mirrored resources to be deleted when --gc is set")
+	cmd.Flags().BoolVar(&drain, "drain", false, "Stop the service mirror and remove the addresses of all mirrored Endpoints in the cluster, then wait for --drain-timeout before outputting the resources for deletion; mirrored Services are kept in the meantime so that DNS keeps resolving while clients fail over")
+	cmd.Flags().DurationVar(&drainTimeout, "drain-timeout", time.Minute, "How long to wait for the connections to the mirrored services to drain when --drain is set")
 
-	pkgcmd.ConfigureNamespaceFlagCompletion(
-		cmd, []string{"namespace"},
-		kubeconfigPath, impersonate, impersonateGroup, kubeContext)
+	pkgcmd.ConfigureNamespaceFlagCompletion(cmd, []string{"namespace"})
 
 	configureClusterNameFlagCompletion(cmd)
 	return cmd
 }
 
+// drainMirrors prepares a Link for removal without abruptly resetting
+// connections. The service mirror controller is scaled down so that it stops
+// repairing Endpoints, then the addresses of every mirrored Endpoints and
+// EndpointSlice are removed so that new requests fail fast, and finally it
+// waits for
+// drainTimeout so existing connections can wind down.
+func drainMirrors(ctx context.Context, k *k8s.KubernetesAPI, namespace, clusterName, selector string, drainTimeout time.Duration) error {
+	if err := scaleDownServiceMirror(ctx, k, namespace, clusterName); err != nil {
+		return err
+	}
+
+	epList, err := k.CoreV1().Endpoints(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return err
+	}
+	for _, ep := range epList.Items {
+		ep := ep // pin
+		ep.Subsets = nil
+		if _, err := k.CoreV1().Endpoints(ep.Namespace).Update(ctx, &ep, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to drain Endpoints %s/%s: %s", ep.Namespace, ep.Name, err)
+		}
+		fmt.Fprintf(os.Stderr, "Drained Endpoints %s/%s\n", ep.Namespace, ep.Name)
+	}
+	// the EndpointSlices written by the service mirror for clusters that
+	// don't mirror Endpoints on their own carry the labels of the Endpoints
+	sliceList, err := k.DiscoveryV1beta1().EndpointSlices(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil && !kerrors.IsNotFound(err) {
+		return err
+	}
+	if err == nil {
+		for _, slice := range sliceList.Items {
+			slice := slice // pin
+			slice.Endpoints = nil
+			if _, err := k.DiscoveryV1beta1().EndpointSlices(slice.Namespace).Update(ctx, &slice, metav1.UpdateOptions{}); err != nil {
+				return fmt.Errorf("failed to drain EndpointSlice %s/%s: %s", slice.Namespace, slice.Name, err)
+			}
+			fmt.Fprintf(os.Stderr, "Drained EndpointSlice %s/%s\n", slice.Namespace, slice.Name)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "Waiting %s for connections to drain...\n", drainTimeout)
+	select {
+	case <-time.After(drainTimeout):
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return nil
+}
+
+// hasOwnServiceMirror returns true if the Link of the target cluster is
+// mirrored by its own service mirror controller, rather than by the one
+// mirroring all the links of the namespace.
+func hasOwnServiceMirror(ctx context.Context, k *k8s.KubernetesAPI, namespace, clusterName string) (bool, error) {
+	serviceMirrorName := fmt.Sprintf("linkerd-service-mirror-%s", clusterName)
+	_, err := k.AppsV1().Deployments(namespace).Get(ctx, serviceMirrorName, metav1.GetOptions{})
+	if kerrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// scaleDownServiceMirror scales down the service mirror controller of the
+// target cluster, so that it stops updating the mirrors.
+func scaleDownServiceMirror(ctx context.Context, k *k8s.KubernetesAPI, namespace, clusterName string) error {
+	serviceMirrorName := fmt.Sprintf("linkerd-service-mirror-%s", clusterName)
+	scale, err := k.AppsV1().Deployments(namespace).GetScale(ctx, serviceMirrorName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to get scale of %s: %s", serviceMirrorName, err)
+	}
+	scale.Spec.Replicas = 0
+	if _, err := k.AppsV1().Deployments(namespace).UpdateScale(ctx, serviceMirrorName, scale, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to scale down %s: %s", serviceMirrorName, err)
+	}
+	fmt.Fprintf(os.Stderr, "Scaled down %s/%s\n", namespace, serviceMirrorName)
+	return nil
+}
+
+// gcMirrors deletes the services and endpoints mirrored from the target
+// cluster, its gateway mirror, and the namespaces created for the mirrors
+// that nothing else is left in, and then waits until they're all gone,
+// reporting the progress on stderr.
+func gcMirrors(ctx context.Context, k *k8s.KubernetesAPI, namespace, clusterName, selector string) error {
+	gatewayMirrorName := fmt.Sprintf("probe-gateway-%s", clusterName)
+	listOptions := metav1.ListOptions{LabelSelector: selector}
+
+	svcList, err := k.CoreV1().Services(metav1.NamespaceAll).List(ctx, listOptions)
+	if err != nil {
+		return err
+	}
+	for _, svc := range svcList.Items {
+		err := k.CoreV1().Services(svc.Namespace).Delete(ctx, svc.Name, metav1.DeleteOptions{})
+		if err != nil && !kerrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete Service %s/%s: %s", svc.Namespace, svc.Name, err)
+		}
+		fmt.Fprintf(os.Stderr, "Deleted Service %s/%s\n", svc.Namespace, svc.Name)
+	}
+	epList, err := k.CoreV1().Endpoints(metav1.NamespaceAll).List(ctx, listOptions)
+	if err != nil {
+		return err
+	}
+	for _, ep := range epList.Items {
+		err := k.CoreV1().Endpoints(ep.Namespace).Delete(ctx, ep.Name, metav1.DeleteOptions{})
+		if err != nil && !kerrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete Endpoints %s/%s: %s", ep.Namespace, ep.Name, err)
+		}
+	}
+	// the EndpointSlices written by the service mirror for clusters that
+	// don't mirror Endpoints on their own carry the labels of the Endpoints
+	err = k.DiscoveryV1beta1().EndpointSlices(metav1.NamespaceAll).DeleteCollection(ctx, metav1.DeleteOptions{}, listOptions)
+	if err != nil && !kerrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete the mirrored EndpointSlices: %s", err)
+	}
+	err = k.CoreV1().Services(namespace).Delete(ctx, gatewayMirrorName, metav1.DeleteOptions{})
+	if err != nil && !kerrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete the gateway mirror %s/%s: %s", namespace, gatewayMirrorName, err)
+	}
+	err = k.CoreV1().Endpoints(namespace).Delete(ctx, gatewayMirrorName, metav1.DeleteOptions{})
+	if err != nil && !kerrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete the gateway mirror %s/%s: %s", namespace, gatewayMirrorName, err)
+	}
+
+	namespaces, err := mc.DeleteUnusedMirrorNamespaces(ctx, k, clusterName)
+	for _, ns := range namespaces {
+		fmt.Fprintf(os.Stderr, "Deleted Namespace %s\n", ns)
+	}
+	if err != nil {
+		return err
+	}
+
+	lastProgress := ""
+	err = wait.PollImmediateUntil(time.Second, func() (bool, error) {
+		var remaining []string
+		svcList, err := k.CoreV1().Services(metav1.NamespaceAll).List(ctx, listOptions)
+		if err != nil {
+			return false, err
+		}
+		if n := len(svcList.Items); n > 0 {
+			remaining = append(remaining, fmt.Sprintf("%d mirrored Services", n))
+		}
+		epList, err := k.CoreV1().Endpoints(metav1.NamespaceAll).List(ctx, listOptions)
+		if err != nil {
+			return false, err
+		}
+		if n := len(epList.Items); n > 0 {
+			remaining = append(remaining, fmt.Sprintf("%d mirrored Endpoints", n))
+		}
+		_, err = k.CoreV1().Services(namespace).Get(ctx, gatewayMirrorName, metav1.GetOptions{})
+		if err == nil {
+			remaining = append(remaining, "the gateway mirror")
+		} else if !kerrors.IsNotFound(err) {
+			return false, err
+		}
+		terminating := 0
+		for _, ns := range namespaces {
+			_, err := k.CoreV1().Namespaces().Get(ctx, ns, metav1.GetOptions{})
+			if err == nil {
+				terminating++
+			} else if !kerrors.IsNotFound(err) {
+				return false, err
+			}
+		}
+		if terminating > 0 {
+			remaining = append(remaining, fmt.Sprintf("%d terminating Namespaces", terminating))
+		}
+
+		if len(remaining) == 0 {
+			return true, nil
+		}
+		if progress := strings.Join(remaining, ", "); progress != lastProgress {
+			fmt.Fprintf(os.Stderr, "Waiting for the removal of %s...\n", progress)
+			lastProgress = progress
+		}
+		return false, nil
+	}, ctx.Done())
+	if err != nil {
+		if errors.Is(err, wait.ErrWaitTimeout) {
+			return fmt.Errorf("timed out waiting for the removal of the resources mirrored from cluster %s", clusterName)
+		}
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "All the resources mirrored from cluster %s have been removed\n", clusterName)
+	return nil
+}
+
 func configureClusterNameFlagCompletion(cmd *cobra.Command) {
 	cmd.RegisterFlagCompletionFunc("cluster-name",
 		func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
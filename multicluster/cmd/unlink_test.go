@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/linkerd/linkerd2/pkg/k8s"
+	mc "github.com/linkerd/linkerd2/pkg/multicluster"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestDrainMirrors(t *testing.T) {
+	k, err := k8s.NewFakeAPI(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: linkerd-service-mirror-east
+  namespace: linkerd-multicluster
+spec:
+  replicas: 1`, `
+apiVersion: v1
+kind: Endpoints
+metadata:
+  name: web-east
+  namespace: emojivoto
+  labels:
+    mirror.linkerd.io/mirrored-service: "true"
+    mirror.linkerd.io/cluster-name: east
+subsets:
+- addresses:
+  - ip: 192.0.2.1
+  ports:
+  - port: 8080`, `
+apiVersion: v1
+kind: Endpoints
+metadata:
+  name: web-west
+  namespace: emojivoto
+  labels:
+    mirror.linkerd.io/mirrored-service: "true"
+    mirror.linkerd.io/cluster-name: west
+subsets:
+- addresses:
+  - ip: 192.0.2.2
+  ports:
+  - port: 8080`, `
+apiVersion: discovery.k8s.io/v1beta1
+kind: EndpointSlice
+metadata:
+  name: web-east-5x7kq
+  namespace: emojivoto
+  labels:
+    kubernetes.io/service-name: web-east
+    mirror.linkerd.io/mirrored-service: "true"
+    mirror.linkerd.io/cluster-name: east
+addressType: IPv4
+endpoints:
+- addresses:
+  - 192.0.2.1
+ports:
+- port: 8080`, `
+apiVersion: discovery.k8s.io/v1beta1
+kind: EndpointSlice
+metadata:
+  name: web-west-q2r8d
+  namespace: emojivoto
+  labels:
+    kubernetes.io/service-name: web-west
+    mirror.linkerd.io/mirrored-service: "true"
+    mirror.linkerd.io/cluster-name: west
+addressType: IPv4
+endpoints:
+- addresses:
+  - 192.0.2.2
+ports:
+- port: 8080`,
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// the fake clientset doesn't implement the scale subresource
+	client := k.Interface.(*fake.Clientset)
+	deployments := appsv1.SchemeGroupVersion.WithResource("deployments")
+	client.PrependReactor("get", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		get := action.(k8stesting.GetAction)
+		if get.GetSubresource() != "scale" {
+			return false, nil, nil
+		}
+		obj, err := client.Tracker().Get(deployments, get.GetNamespace(), get.GetName())
+		if err != nil {
+			return true, nil, err
+		}
+		deploy := obj.(*appsv1.Deployment)
+		return true, &autoscalingv1.Scale{
+			ObjectMeta: metav1.ObjectMeta{Name: deploy.Name, Namespace: deploy.Namespace},
+			Spec:       autoscalingv1.ScaleSpec{Replicas: *deploy.Spec.Replicas},
+		}, nil
+	})
+	client.PrependReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		update := action.(k8stesting.UpdateAction)
+		if update.GetSubresource() != "scale" {
+			return false, nil, nil
+		}
+		scale := update.GetObject().(*autoscalingv1.Scale)
+		obj, err := client.Tracker().Get(deployments, update.GetNamespace(), scale.Name)
+		if err != nil {
+			return true, nil, err
+		}
+		deploy := obj.(*appsv1.Deployment).DeepCopy()
+		deploy.Spec.Replicas = &scale.Spec.Replicas
+		return true, scale, client.Tracker().Update(deployments, deploy, update.GetNamespace())
+	})
+
+	err = drainMirrors(context.Background(), k, "linkerd-multicluster", "east", mc.MirrorLabelSelector("east"), time.Millisecond)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	deploy, err := k.AppsV1().Deployments("linkerd-multicluster").Get(context.Background(), "linkerd-service-mirror-east", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if *deploy.Spec.Replicas != 0 {
+		t.Fatalf("Expected the service mirror to be scaled down, got %d replicas", *deploy.Spec.Replicas)
+	}
+
+	drained, err := k.CoreV1().Endpoints("emojivoto").Get(context.Background(), "web-east", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(drained.Subsets) != 0 {
+		t.Fatalf("Expected the mirrored endpoints to be drained, got %v", drained.Subsets)
+	}
+
+	other, err := k.CoreV1().Endpoints("emojivoto").Get(context.Background(), "web-west", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(other.Subsets) != 1 {
+		t.Fatalf("Expected the endpoints mirrored from another cluster to be left alone, got %v", other.Subsets)
+	}
+
+	drainedSlice, err := k.DiscoveryV1beta1().EndpointSlices("emojivoto").Get(context.Background(), "web-east-5x7kq", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(drainedSlice.Endpoints) != 0 {
+		t.Fatalf("Expected the mirrored EndpointSlice to be drained, got %v", drainedSlice.Endpoints)
+	}
+
+	otherSlice, err := k.DiscoveryV1beta1().EndpointSlices("emojivoto").Get(context.Background(), "web-west-q2r8d", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(otherSlice.Endpoints) != 1 {
+		t.Fatalf("Expected the EndpointSlice mirrored from another cluster to be left alone, got %v", otherSlice.Endpoints)
+	}
+}
+
+func TestUnlinkDrainTimeoutRequiresDrain(t *testing.T) {
+	cmd := newUnlinkCommand()
+	cmd.SetArgs([]string{"--cluster-name", "east", "--drain-timeout", "1m"})
+	cmd.SilenceUsage = true
+	cmd.SilenceErrors = true
+	err := cmd.Execute()
+	if err == nil || err.Error() != "--drain-timeout can only be set along with --drain" {
+		t.Fatalf("Expected --drain-timeout to be rejected without --drain, got %v", err)
+	}
+}
+
+func TestHasOwnServiceMirror(t *testing.T) {
+	k, err := k8s.NewFakeAPI(`
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: linkerd-service-mirror-east
+  namespace: linkerd-multicluster`, `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: linkerd-service-mirror
+  namespace: linkerd-multicluster`,
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	testCases := []struct {
+		clusterName string
+		expected    bool
+	}{
+		{"east", true},
+		// mirrored by the service mirror of all the links
+		{"west", false},
+	}
+	for _, tc := range testCases {
+		tc := tc // pin
+		t.Run(tc.clusterName, func(t *testing.T) {
+			own, err := hasOwnServiceMirror(context.Background(), k, "linkerd-multicluster", tc.clusterName)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if own != tc.expected {
+				t.Fatalf("Expected %t, got %t", tc.expected, own)
+			}
+		})
+	}
+}
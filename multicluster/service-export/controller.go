@@ -0,0 +1,225 @@
+package serviceexport
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/linkerd/linkerd2/controller/k8s"
+	"github.com/linkerd/linkerd2/pkg/crash"
+	consts "github.com/linkerd/linkerd2/pkg/k8s"
+	"github.com/linkerd/linkerd2/pkg/multicluster"
+	logging "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	// conditionValid is the type of the ServiceExport status condition
+	// reporting whether the exported service exists.
+	conditionValid = "Valid"
+
+	resyncPeriod = 10 * time.Minute
+	maxRetries   = 5
+)
+
+// Controller translates MCS API ServiceExport resources into the label-based
+// export mechanism of linkerd multicluster: the service named after a
+// ServiceExport gets the default exported label, which is removed again when
+// the ServiceExport is deleted.
+type Controller struct {
+	k8sAPI  *k8s.API
+	client  dynamic.Interface
+	factory dynamicinformer.DynamicSharedInformerFactory
+	exports cache.SharedIndexInformer
+	queue   workqueue.RateLimitingInterface
+	log     *logging.Entry
+}
+
+// NewController returns a Controller reconciling the ServiceExports and
+// services of the cluster. The k8sAPI must have the Svc resource.
+func NewController(k8sAPI *k8s.API, client dynamic.Interface) *Controller {
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(client, resyncPeriod)
+	c := &Controller{
+		k8sAPI:  k8sAPI,
+		client:  client,
+		factory: factory,
+		exports: factory.ForResource(multicluster.ServiceExportGVR).Informer(),
+		queue:   workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		log:     logging.WithField("component", "service-export"),
+	}
+
+	handlers := cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueue,
+		UpdateFunc: func(_, obj interface{}) { c.enqueue(obj) },
+		DeleteFunc: c.enqueue,
+	}
+	c.exports.AddEventHandler(crash.Handler("service-export", handlers))
+	k8sAPI.Svc().Informer().AddEventHandler(crash.Handler("service-export/service", handlers))
+
+	return c
+}
+
+// Start starts the informers and processes the queue until the context is
+// cancelled.
+func (c *Controller) Start(ctx context.Context) {
+	c.factory.Start(ctx.Done())
+	c.k8sAPI.Sync(ctx.Done())
+	if !cache.WaitForCacheSync(ctx.Done(), c.exports.HasSynced) {
+		c.log.Error("Failed to sync ServiceExports")
+		return
+	}
+
+	go func() {
+		<-ctx.Done()
+		c.queue.ShutDown()
+	}()
+	for c.processNextItem(ctx) {
+	}
+}
+
+func (c *Controller) enqueue(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		c.log.Errorf("Failed to get key of %v: %s", obj, err)
+		return
+	}
+	c.queue.Add(key)
+}
+
+func (c *Controller) processNextItem(ctx context.Context) bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	err := c.reconcileSafely(ctx, key.(string))
+	switch {
+	case err == nil:
+		c.queue.Forget(key)
+	case c.queue.NumRequeues(key) < maxRetries:
+		c.log.Warnf("Failed to reconcile %s, retrying: %s", key, err)
+		c.queue.AddRateLimited(key)
+	default:
+		c.log.Errorf("Failed to reconcile %s: %s", key, err)
+		c.queue.Forget(key)
+	}
+	return true
+}
+
+// reconcileSafely calls reconcile, turning a panic into an error so that the
+// key is retried like any other failure.
+func (c *Controller) reconcileSafely(ctx context.Context, key string) (err error) {
+	defer crash.RecoverError(&err, "service-export/reconcile", key)
+	return c.reconcile(ctx, key)
+}
+
+// reconcile labels or unlabels the service with the given key depending on
+// whether a ServiceExport with the same key exists.
+func (c *Controller) reconcile(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+
+	obj, exported, err := c.exports.GetIndexer().GetByKey(key)
+	if err != nil {
+		return err
+	}
+
+	svc, err := c.k8sAPI.Svc().Lister().Services(namespace).Get(name)
+	if err != nil {
+		if !kerrors.IsNotFound(err) {
+			return err
+		}
+		if exported {
+			return c.setValidCondition(ctx, obj.(*unstructured.Unstructured), metav1.ConditionFalse, "ServiceNotFound", fmt.Sprintf("Service %s not found", key))
+		}
+		return nil
+	}
+
+	labeled := svc.Labels[consts.DefaultExportedServiceSelector] == "true"
+	managed := svc.Annotations[consts.ServiceExportAnnotation] == "true"
+
+	switch {
+	case exported && !labeled:
+		c.log.Infof("Exporting service %s", key)
+		svc = svc.DeepCopy()
+		if svc.Labels == nil {
+			svc.Labels = make(map[string]string)
+		}
+		if svc.Annotations == nil {
+			svc.Annotations = make(map[string]string)
+		}
+		svc.Labels[consts.DefaultExportedServiceSelector] = "true"
+		svc.Annotations[consts.ServiceExportAnnotation] = "true"
+		if err := c.updateService(ctx, svc); err != nil {
+			return err
+		}
+	case !exported && labeled && managed:
+		c.log.Infof("Unexporting service %s", key)
+		svc = svc.DeepCopy()
+		delete(svc.Labels, consts.DefaultExportedServiceSelector)
+		delete(svc.Annotations, consts.ServiceExportAnnotation)
+		return c.updateService(ctx, svc)
+	}
+
+	if exported {
+		return c.setValidCondition(ctx, obj.(*unstructured.Unstructured), metav1.ConditionTrue, "Exported", fmt.Sprintf("Service %s is exported", key))
+	}
+	return nil
+}
+
+func (c *Controller) updateService(ctx context.Context, svc *corev1.Service) error {
+	_, err := c.k8sAPI.Client.CoreV1().Services(svc.Namespace).Update(ctx, svc, metav1.UpdateOptions{})
+	return err
+}
+
+// setValidCondition sets the Valid condition in the status of the given
+// ServiceExport, unless it's already set with the same status and reason.
+func (c *Controller) setValidCondition(ctx context.Context, export *unstructured.Unstructured, status metav1.ConditionStatus, reason, message string) error {
+	existing, _, err := unstructured.NestedSlice(export.Object, "status", "conditions")
+	if err != nil {
+		return err
+	}
+
+	conditions := []interface{}{}
+	for _, cond := range existing {
+		cObj, ok := cond.(map[string]interface{})
+		if !ok || cObj["type"] != conditionValid {
+			conditions = append(conditions, cond)
+			continue
+		}
+		if cObj["status"] == string(status) && cObj["reason"] == reason {
+			return nil
+		}
+	}
+
+	condition := metav1.Condition{
+		Type:               conditionValid,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		LastTransitionTime: metav1.Now(),
+	}
+	cObj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&condition)
+	if err != nil {
+		return err
+	}
+	conditions = append(conditions, cObj)
+
+	export = export.DeepCopy()
+	if err := unstructured.SetNestedSlice(export.Object, conditions, "status", "conditions"); err != nil {
+		return err
+	}
+	_, err = c.client.Resource(multicluster.ServiceExportGVR).Namespace(export.GetNamespace()).UpdateStatus(ctx, export, metav1.UpdateOptions{})
+	return err
+}
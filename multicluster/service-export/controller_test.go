@@ -0,0 +1,142 @@
+package serviceexport
+
+import (
+	"context"
+	"testing"
+
+	"github.com/linkerd/linkerd2/controller/k8s"
+	consts "github.com/linkerd/linkerd2/pkg/k8s"
+	"github.com/linkerd/linkerd2/pkg/multicluster"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func serviceExport(namespace, name string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": multicluster.MCSAPIGroupVersion,
+			"kind":       "ServiceExport",
+			"metadata": map[string]interface{}{
+				"name":      name,
+				"namespace": namespace,
+			},
+		},
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	testCases := []struct {
+		name             string
+		service          string
+		exported         bool
+		expectedLabel    bool
+		expectedValidity string
+	}{
+		{
+			name: "labels exported services",
+			service: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: emojivoto`,
+			exported:         true,
+			expectedLabel:    true,
+			expectedValidity: "True",
+		},
+		{
+			name: "unlabels services no longer exported",
+			service: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: emojivoto
+  labels:
+    mirror.linkerd.io/exported: "true"
+  annotations:
+    mirror.linkerd.io/service-export: "true"`,
+			exported:      false,
+			expectedLabel: false,
+		},
+		{
+			name: "keeps labels not set for a ServiceExport",
+			service: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: emojivoto
+  labels:
+    mirror.linkerd.io/exported: "true"`,
+			exported:      false,
+			expectedLabel: true,
+		},
+		{
+			name: "reports exports of missing services",
+			service: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: other
+  namespace: emojivoto`,
+			exported:         true,
+			expectedValidity: "False",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			k8sAPI, err := k8s.NewFakeAPI(tc.service)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			objs := []runtime.Object{}
+			if tc.exported {
+				objs = append(objs, serviceExport("emojivoto", "web"))
+			}
+			client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+				runtime.NewScheme(),
+				map[schema.GroupVersionResource]string{multicluster.ServiceExportGVR: "ServiceExportList"},
+				objs...,
+			)
+
+			c := NewController(k8sAPI, client)
+			k8sAPI.Sync(nil)
+			if tc.exported {
+				if err := c.exports.GetIndexer().Add(serviceExport("emojivoto", "web")); err != nil {
+					t.Fatalf("Unexpected error: %s", err)
+				}
+			}
+
+			if err := c.reconcile(context.Background(), "emojivoto/web"); err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+
+			svc, err := k8sAPI.Client.CoreV1().Services("emojivoto").Get(context.Background(), "web", metav1.GetOptions{})
+			if err == nil {
+				if labeled := svc.Labels[consts.DefaultExportedServiceSelector] == "true"; labeled != tc.expectedLabel {
+					t.Fatalf("Expected exported label to be %t, got labels %v", tc.expectedLabel, svc.Labels)
+				}
+			}
+
+			if tc.expectedValidity != "" {
+				export, err := client.Resource(multicluster.ServiceExportGVR).Namespace("emojivoto").Get(context.Background(), "web", metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("Unexpected error: %s", err)
+				}
+				conditions, _, err := unstructured.NestedSlice(export.Object, "status", "conditions")
+				if err != nil {
+					t.Fatalf("Unexpected error: %s", err)
+				}
+				if len(conditions) != 1 || conditions[0].(map[string]interface{})["status"] != tc.expectedValidity {
+					t.Fatalf("Expected a %s Valid condition, got %v", tc.expectedValidity, conditions)
+				}
+			}
+		})
+	}
+}
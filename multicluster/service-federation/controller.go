@@ -0,0 +1,421 @@
+package servicefederation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/linkerd/linkerd2/controller/k8s"
+	"github.com/linkerd/linkerd2/pkg/crash"
+	"github.com/linkerd/linkerd2/pkg/featuregates"
+	consts "github.com/linkerd/linkerd2/pkg/k8s"
+	"github.com/linkerd/linkerd2/pkg/multicluster"
+	logging "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const maxRetries = 5
+
+// Controller aggregates the mirrors of the same service exported by several
+// linked clusters into a single federated service, named after the remote
+// service with the "-federated" suffix. The endpoints of the federated
+// service are the gateway endpoints of all the mirrors, so its membership
+// follows the mirrors as Links are created and removed. Services are only
+// federated while the FederatedServices feature gate is enabled; otherwise,
+// the federated services are deleted.
+type Controller struct {
+	k8sAPI *k8s.API
+	gates  *featuregates.FeatureGates
+	queue  workqueue.RateLimitingInterface
+	log    *logging.Entry
+}
+
+// member is a mirror of the service being federated.
+type member struct {
+	cluster   string
+	service   *corev1.Service
+	endpoints *corev1.Endpoints
+}
+
+// NewController returns a Controller reconciling the federated services of
+// the cluster. The k8sAPI must have the Svc and Endpoint resources.
+func NewController(k8sAPI *k8s.API, gates *featuregates.FeatureGates) *Controller {
+	c := &Controller{
+		k8sAPI: k8sAPI,
+		gates:  gates,
+		queue:  workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		log:    logging.WithField("component", "service-federation"),
+	}
+
+	handlers := cache.ResourceEventHandlerFuncs{
+		AddFunc:    c.enqueue,
+		UpdateFunc: func(_, obj interface{}) { c.enqueue(obj) },
+		DeleteFunc: c.enqueue,
+	}
+	k8sAPI.Svc().Informer().AddEventHandler(crash.Handler("service-federation/service", handlers))
+	k8sAPI.Endpoint().Informer().AddEventHandler(crash.Handler("service-federation/endpoints", handlers))
+
+	gates.OnChange(func(f featuregates.Feature, _ bool) {
+		if f == featuregates.FederatedServices {
+			c.resync()
+		}
+	})
+
+	return c
+}
+
+// resync queues all the federated services and the mirrors, once the
+// FederatedServices feature gate is switched on or off.
+func (c *Controller) resync() {
+	svcs, err := c.k8sAPI.Svc().Lister().List(labels.Everything())
+	if err != nil {
+		c.log.Errorf("Failed to list the services to resync: %s", err)
+	}
+	for _, svc := range svcs {
+		c.enqueue(svc)
+	}
+	endpoints, err := c.k8sAPI.Endpoint().Lister().List(labels.Everything())
+	if err != nil {
+		c.log.Errorf("Failed to list the endpoints to resync: %s", err)
+	}
+	for _, ep := range endpoints {
+		c.enqueue(ep)
+	}
+}
+
+// Start starts the informers and processes the queue until the context is
+// cancelled.
+func (c *Controller) Start(ctx context.Context) {
+	c.k8sAPI.Sync(ctx.Done())
+
+	go func() {
+		<-ctx.Done()
+		c.queue.ShutDown()
+	}()
+	for c.processNextItem(ctx) {
+	}
+}
+
+// enqueue queues the key of the federated service the given object belongs
+// to: either the object is federated itself, or it is a mirror whose remote
+// service is federated.
+func (c *Controller) enqueue(obj interface{}) {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tombstone.Obj
+	}
+	m, err := metav1.ObjectMetaAccessor(obj)
+	if err != nil {
+		c.log.Errorf("Failed to get metadata of %v: %s", obj, err)
+		return
+	}
+	meta := m.GetObjectMeta()
+
+	if meta.GetLabels()[consts.FederatedServiceLabel] == "true" {
+		c.queue.Add(fmt.Sprintf("%s/%s", meta.GetNamespace(), meta.GetName()))
+		return
+	}
+
+	// Only mirrored Endpoints hold the name of their remote service. Mirrored
+	// services are created and updated along with them.
+	if _, ok := obj.(*corev1.Endpoints); !ok || !isMirror(meta.GetLabels()) {
+		return
+	}
+	name, ok := remoteServiceName(meta.GetAnnotations()[consts.RemoteServiceFqName])
+	if !ok {
+		return
+	}
+	c.queue.Add(fmt.Sprintf("%s/%s", meta.GetNamespace(), multicluster.FederatedServiceName(name)))
+}
+
+func (c *Controller) processNextItem(ctx context.Context) bool {
+	key, quit := c.queue.Get()
+	if quit {
+		return false
+	}
+	defer c.queue.Done(key)
+
+	err := c.reconcileSafely(ctx, key.(string))
+	switch {
+	case err == nil:
+		c.queue.Forget(key)
+	case c.queue.NumRequeues(key) < maxRetries:
+		c.log.Warnf("Failed to reconcile %s, retrying: %s", key, err)
+		c.queue.AddRateLimited(key)
+	default:
+		c.log.Errorf("Failed to reconcile %s: %s", key, err)
+		c.queue.Forget(key)
+	}
+	return true
+}
+
+// reconcileSafely calls reconcile, turning a panic into an error so that the
+// key is retried like any other failure.
+func (c *Controller) reconcileSafely(ctx context.Context, key string) (err error) {
+	defer crash.RecoverError(&err, "service-federation/reconcile", key)
+	return c.reconcile(ctx, key)
+}
+
+// reconcile creates, updates or deletes the federated service with the given
+// key, and its endpoints, according to the mirrors it aggregates.
+func (c *Controller) reconcile(ctx context.Context, key string) error {
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		return err
+	}
+
+	members, err := c.members(namespace, name)
+	if err != nil {
+		return err
+	}
+	if len(members) == 0 || !c.gates.Enabled(featuregates.FederatedServices) {
+		return c.deleteFederated(ctx, namespace, name)
+	}
+
+	svc, endpoints := c.newFederated(namespace, name, members)
+	applied, err := c.applyService(ctx, svc)
+	if err != nil || !applied {
+		return err
+	}
+	return c.applyEndpoints(ctx, endpoints)
+}
+
+// members returns the mirrors aggregated by the given federated service,
+// sorted by cluster name.
+func (c *Controller) members(namespace, name string) ([]member, error) {
+	selector := labels.Set{consts.MirroredResourceLabel: "true"}.AsSelector()
+	mirrors, err := c.k8sAPI.Endpoint().Lister().Endpoints(namespace).List(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	members := []member{}
+	for _, endpoints := range mirrors {
+		if !isMirror(endpoints.Labels) {
+			continue
+		}
+		remoteName, ok := remoteServiceName(endpoints.Annotations[consts.RemoteServiceFqName])
+		if !ok || multicluster.FederatedServiceName(remoteName) != name {
+			continue
+		}
+		svc, err := c.k8sAPI.Svc().Lister().Services(namespace).Get(endpoints.Name)
+		if err != nil {
+			if kerrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		members = append(members, member{
+			cluster:   endpoints.Labels[consts.RemoteClusterNameLabel],
+			service:   svc,
+			endpoints: endpoints,
+		})
+	}
+
+	sort.Slice(members, func(i, j int) bool { return members[i].cluster < members[j].cluster })
+	return members, nil
+}
+
+// newFederated builds the federated service and endpoints aggregating the
+// given members. All the members must route to the same remote authority
+// through their gateway; members whose remote service has a different fully
+// qualified name than the first one's are left out.
+func (c *Controller) newFederated(namespace, name string, members []member) (*corev1.Service, *corev1.Endpoints) {
+	fqName := members[0].endpoints.Annotations[consts.RemoteServiceFqName]
+
+	clusters := []string{}
+	ports := []corev1.ServicePort{}
+	seenPorts := map[string]struct{}{}
+	subsets := []corev1.EndpointSubset{}
+	identities := map[string]string{}
+	weights := map[string]uint32{}
+	for _, m := range members {
+		if m.endpoints.Annotations[consts.RemoteServiceFqName] != fqName {
+			c.log.Warnf("Not federating %s/%s into %s/%s: remote service %s doesn't match %s",
+				m.endpoints.Namespace, m.endpoints.Name, namespace, name, m.endpoints.Annotations[consts.RemoteServiceFqName], fqName)
+			continue
+		}
+		clusters = append(clusters, m.cluster)
+
+		for _, port := range m.service.Spec.Ports {
+			key := fmt.Sprintf("%d/%s", port.Port, port.Protocol)
+			if _, ok := seenPorts[key]; ok {
+				continue
+			}
+			seenPorts[key] = struct{}{}
+			ports = append(ports, corev1.ServicePort{
+				Name:     port.Name,
+				Protocol: port.Protocol,
+				Port:     port.Port,
+			})
+		}
+
+		identity := m.endpoints.Annotations[consts.RemoteGatewayIdentity]
+		for _, subset := range m.endpoints.Subsets {
+			subsets = append(subsets, *subset.DeepCopy())
+			if identity == "" {
+				continue
+			}
+			for _, addr := range subset.Addresses {
+				identities[addr.IP] = identity
+			}
+		}
+
+		if value, ok := m.endpoints.Annotations[consts.RemoteGatewayWeights]; ok {
+			memberWeights, err := multicluster.ParseGatewayAddressWeights(value)
+			if err != nil {
+				c.log.Warnf("Ignoring gateway weights of %s/%s: %s", m.endpoints.Namespace, m.endpoints.Name, err)
+			}
+			for addr, weight := range memberWeights {
+				weights[addr] = weight
+			}
+		}
+	}
+	sort.Slice(ports, func(i, j int) bool { return ports[i].Port < ports[j].Port })
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				consts.FederatedServiceLabel: "true",
+			},
+			Annotations: map[string]string{
+				consts.FederatedClustersAnnotation: strings.Join(clusters, ","),
+			},
+		},
+		Spec: corev1.ServiceSpec{
+			Ports: ports,
+		},
+	}
+
+	endpoints := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				consts.FederatedServiceLabel: "true",
+			},
+			Annotations: map[string]string{
+				consts.RemoteServiceFqName: fqName,
+			},
+		},
+		Subsets: subsets,
+	}
+	if len(identities) > 0 {
+		endpoints.Annotations[consts.RemoteGatewayIdentities] = multicluster.FormatGatewayIdentities(identities)
+	}
+	if len(weights) > 0 {
+		endpoints.Annotations[consts.RemoteGatewayWeights] = multicluster.FormatGatewayAddressWeights(weights)
+	}
+
+	return svc, endpoints
+}
+
+// applyService creates the given federated service, or updates the existing
+// one if it differs. Services with the same name that are not federated are
+// left untouched, in which case false is returned.
+func (c *Controller) applyService(ctx context.Context, svc *corev1.Service) (bool, error) {
+	existing, err := c.k8sAPI.Svc().Lister().Services(svc.Namespace).Get(svc.Name)
+	if err != nil {
+		if !kerrors.IsNotFound(err) {
+			return false, err
+		}
+		c.log.Infof("Creating federated service %s/%s", svc.Namespace, svc.Name)
+		_, err = c.k8sAPI.Client.CoreV1().Services(svc.Namespace).Create(ctx, svc, metav1.CreateOptions{})
+		return err == nil, err
+	}
+
+	if existing.Labels[consts.FederatedServiceLabel] != "true" {
+		c.log.Warnf("Not federating into %s/%s: service already exists and is not federated", svc.Namespace, svc.Name)
+		return false, nil
+	}
+	if equality.Semantic.DeepEqual(existing.Spec.Ports, svc.Spec.Ports) &&
+		equality.Semantic.DeepEqual(existing.Annotations, svc.Annotations) {
+		return true, nil
+	}
+
+	updated := existing.DeepCopy()
+	updated.Annotations = svc.Annotations
+	updated.Spec.Ports = svc.Spec.Ports
+	c.log.Infof("Updating federated service %s/%s", svc.Namespace, svc.Name)
+	_, err = c.k8sAPI.Client.CoreV1().Services(svc.Namespace).Update(ctx, updated, metav1.UpdateOptions{})
+	return err == nil, err
+}
+
+// applyEndpoints creates the given federated endpoints, or updates the
+// existing ones if they differ.
+func (c *Controller) applyEndpoints(ctx context.Context, endpoints *corev1.Endpoints) error {
+	existing, err := c.k8sAPI.Endpoint().Lister().Endpoints(endpoints.Namespace).Get(endpoints.Name)
+	if err != nil {
+		if !kerrors.IsNotFound(err) {
+			return err
+		}
+		_, err = c.k8sAPI.Client.CoreV1().Endpoints(endpoints.Namespace).Create(ctx, endpoints, metav1.CreateOptions{})
+		return err
+	}
+
+	if existing.Labels[consts.FederatedServiceLabel] != "true" {
+		c.log.Warnf("Not federating into %s/%s: endpoints already exist and are not federated", endpoints.Namespace, endpoints.Name)
+		return nil
+	}
+	if equality.Semantic.DeepEqual(existing.Subsets, endpoints.Subsets) &&
+		equality.Semantic.DeepEqual(existing.Annotations, endpoints.Annotations) {
+		return nil
+	}
+
+	updated := existing.DeepCopy()
+	updated.Annotations = endpoints.Annotations
+	updated.Subsets = endpoints.Subsets
+	_, err = c.k8sAPI.Client.CoreV1().Endpoints(endpoints.Namespace).Update(ctx, updated, metav1.UpdateOptions{})
+	return err
+}
+
+// deleteFederated deletes the federated service with the given name, which
+// no longer aggregates any mirror. Its endpoints are garbage collected along
+// with it.
+func (c *Controller) deleteFederated(ctx context.Context, namespace, name string) error {
+	svc, err := c.k8sAPI.Svc().Lister().Services(namespace).Get(name)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if svc.Labels[consts.FederatedServiceLabel] != "true" {
+		return nil
+	}
+
+	c.log.Infof("Deleting federated service %s/%s", namespace, name)
+	err = c.k8sAPI.Client.CoreV1().Services(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !kerrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// isMirror returns whether the given labels are those of a mirrored service
+// or endpoints, as opposed to a mirrored gateway.
+func isMirror(l map[string]string) bool {
+	if l[consts.MirroredResourceLabel] != "true" {
+		return false
+	}
+	_, gateway := l[consts.MirroredGatewayLabel]
+	return !gateway
+}
+
+// remoteServiceName extracts the name of a remote service from its fully
+// qualified name.
+func remoteServiceName(fqName string) (string, bool) {
+	parts := strings.SplitN(fqName, ".", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", false
+	}
+	return parts[0], true
+}
@@ -0,0 +1,176 @@
+package servicefederation
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/linkerd/linkerd2/controller/k8s"
+	"github.com/linkerd/linkerd2/pkg/featuregates"
+	consts "github.com/linkerd/linkerd2/pkg/k8s"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func mirror(cluster, gatewayIP, identity string) []string {
+	return []string{
+		fmt.Sprintf(`
+apiVersion: v1
+kind: Service
+metadata:
+  name: web-%s
+  namespace: emojivoto
+  labels:
+    mirror.linkerd.io/mirrored-service: "true"
+    mirror.linkerd.io/cluster-name: %s
+spec:
+  ports:
+  - name: http
+    port: 80
+    protocol: TCP`, cluster, cluster),
+		fmt.Sprintf(`
+apiVersion: v1
+kind: Endpoints
+metadata:
+  name: web-%s
+  namespace: emojivoto
+  labels:
+    mirror.linkerd.io/mirrored-service: "true"
+    mirror.linkerd.io/cluster-name: %s
+  annotations:
+    mirror.linkerd.io/remote-svc-fq-name: web.emojivoto.svc.cluster.local
+    mirror.linkerd.io/remote-gateway-identity: %s
+subsets:
+- addresses:
+  - ip: %s
+  ports:
+  - name: http
+    port: 4143
+    protocol: TCP`, cluster, cluster, identity, gatewayIP),
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	testCases := []struct {
+		name               string
+		configs            []string
+		disabled           bool
+		expectedFederated  bool
+		expectedClusters   string
+		expectedIdentities string
+		expectedAddresses  int
+	}{
+		{
+			name: "aggregates the mirrors of every cluster",
+			configs: append(
+				mirror("east", "10.0.0.1", "gateway.east"),
+				mirror("west", "10.0.0.2", "gateway.west")...,
+			),
+			expectedFederated:  true,
+			expectedClusters:   "east,west",
+			expectedIdentities: "10.0.0.1=gateway.east,10.0.0.2=gateway.west",
+			expectedAddresses:  2,
+		},
+		{
+			name: "deletes federated services without mirrors",
+			configs: []string{`
+apiVersion: v1
+kind: Service
+metadata:
+  name: web-federated
+  namespace: emojivoto
+  labels:
+    mirror.linkerd.io/federated-service: "true"`,
+			},
+			expectedFederated: false,
+		},
+		{
+			name: "deletes federated services while the feature gate is disabled",
+			configs: append(
+				mirror("east", "10.0.0.1", "gateway.east"),
+				`
+apiVersion: v1
+kind: Service
+metadata:
+  name: web-federated
+  namespace: emojivoto
+  labels:
+    mirror.linkerd.io/federated-service: "true"`,
+			),
+			disabled:          true,
+			expectedFederated: false,
+		},
+		{
+			name: "leaves services that are not federated untouched",
+			configs: append(
+				mirror("east", "10.0.0.1", "gateway.east"),
+				`
+apiVersion: v1
+kind: Service
+metadata:
+  name: web-federated
+  namespace: emojivoto`,
+			),
+			expectedFederated: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			k8sAPI, err := k8s.NewFakeAPI(tc.configs...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			gates := featuregates.New()
+			if !tc.disabled {
+				if err := gates.ParseFlag("FederatedServices=true"); err != nil {
+					t.Fatalf("Unexpected error: %s", err)
+				}
+			}
+			c := NewController(k8sAPI, gates)
+			k8sAPI.Sync(nil)
+
+			if err := c.reconcile(context.Background(), "emojivoto/web-federated"); err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+
+			svc, err := k8sAPI.Client.CoreV1().Services("emojivoto").Get(context.Background(), "web-federated", metav1.GetOptions{})
+			if err != nil && !kerrors.IsNotFound(err) {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			federated := err == nil && svc.Labels[consts.FederatedServiceLabel] == "true"
+			if federated != tc.expectedFederated {
+				t.Fatalf("Expected federated service to exist: %t, got %v", tc.expectedFederated, svc)
+			}
+			if !tc.expectedFederated {
+				if _, err := k8sAPI.Client.CoreV1().Endpoints("emojivoto").Get(context.Background(), "web-federated", metav1.GetOptions{}); !kerrors.IsNotFound(err) {
+					t.Fatalf("Expected no federated endpoints, got error %v", err)
+				}
+				return
+			}
+
+			if clusters := svc.Annotations[consts.FederatedClustersAnnotation]; clusters != tc.expectedClusters {
+				t.Fatalf("Expected clusters %s, got %s", tc.expectedClusters, clusters)
+			}
+			if len(svc.Spec.Ports) != 1 || svc.Spec.Ports[0].Port != 80 {
+				t.Fatalf("Unexpected federated service ports: %v", svc.Spec.Ports)
+			}
+
+			endpoints, err := k8sAPI.Client.CoreV1().Endpoints("emojivoto").Get(context.Background(), "web-federated", metav1.GetOptions{})
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if identities := endpoints.Annotations[consts.RemoteGatewayIdentities]; identities != tc.expectedIdentities {
+				t.Fatalf("Expected gateway identities %s, got %s", tc.expectedIdentities, identities)
+			}
+			addresses := 0
+			for _, subset := range endpoints.Subsets {
+				addresses += len(subset.Addresses)
+			}
+			if addresses != tc.expectedAddresses {
+				t.Fatalf("Expected %d addresses, got %d", tc.expectedAddresses, addresses)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package servicemirror
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	logging "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/rest"
+)
+
+type (
+	// ClientConfig controls the rate of the requests sent to an API server,
+	// by the client of the local cluster or by the clients of the target
+	// clusters. Its defaults match client-go's.
+	ClientConfig struct {
+		// QPS is the number of requests allowed per second
+		QPS float32
+		// Burst is the number of requests allowed above QPS
+		Burst int
+	}
+
+	// WriteBackoffConfig controls how the writes to the local API that are
+	// throttled, or that time out, are retried before the event they're part
+	// of fails.
+	WriteBackoffConfig struct {
+		// Attempts is the number of times a write is attempted, 1 disabling
+		// the retries
+		Attempts int
+		// BaseDelay is the delay before the first retry of a write, doubled
+		// on each subsequent retry
+		BaseDelay time.Duration
+		// MaxDelay caps the delay between two retries of a write
+		MaxDelay time.Duration
+	}
+
+	retryingServices struct {
+		typedcorev1.ServiceInterface
+		namespace string
+		backoff   WriteBackoffConfig
+		log       *logging.Entry
+	}
+
+	retryingEndpoints struct {
+		typedcorev1.EndpointsInterface
+		namespace string
+		backoff   WriteBackoffConfig
+		log       *logging.Entry
+	}
+
+	retryingNamespaces struct {
+		typedcorev1.NamespaceInterface
+		backoff WriteBackoffConfig
+		log     *logging.Entry
+	}
+)
+
+// DefaultClientConfig returns the configuration used when none is given.
+func DefaultClientConfig() ClientConfig {
+	return ClientConfig{
+		QPS:   rest.DefaultQPS,
+		Burst: rest.DefaultBurst,
+	}
+}
+
+// Validate returns an error if the configuration can't be used.
+func (c ClientConfig) Validate() error {
+	if c.QPS <= 0 {
+		return fmt.Errorf("invalid client QPS %v: must be positive", c.QPS)
+	}
+	if c.Burst <= 0 {
+		return fmt.Errorf("invalid client burst %d: must be positive", c.Burst)
+	}
+	return nil
+}
+
+// Apply sets the rate limits of the configuration on the given client
+// configuration.
+func (c ClientConfig) Apply(cfg *rest.Config) {
+	cfg.QPS = c.QPS
+	cfg.Burst = c.Burst
+}
+
+// DefaultWriteBackoffConfig returns the configuration used when none is
+// given.
+func DefaultWriteBackoffConfig() WriteBackoffConfig {
+	return WriteBackoffConfig{
+		Attempts:  5,
+		BaseDelay: 100 * time.Millisecond,
+		MaxDelay:  5 * time.Second,
+	}
+}
+
+// Validate returns an error if the configuration can't be used.
+func (c WriteBackoffConfig) Validate() error {
+	if c.Attempts <= 0 {
+		return fmt.Errorf("invalid write attempts %d: must be positive", c.Attempts)
+	}
+	if c.BaseDelay <= 0 {
+		return fmt.Errorf("invalid write backoff base delay %s: must be positive", c.BaseDelay)
+	}
+	if c.MaxDelay < c.BaseDelay {
+		return fmt.Errorf("invalid write backoff max delay %s: must not be lower than the base delay %s", c.MaxDelay, c.BaseDelay)
+	}
+	return nil
+}
+
+// retry calls write until it succeeds, fails with an error that isn't worth
+// retrying, or has been attempted c.Attempts times, waiting exponentially
+// longer between the attempts.
+func (c WriteBackoffConfig) retry(ctx context.Context, log *logging.Entry, verb, resource, key string, write func() error) error {
+	delay := c.BaseDelay
+	for attempt := 1; ; attempt++ {
+		err := write()
+		if err == nil || attempt >= c.Attempts || !isRetryableWrite(err) {
+			return err
+		}
+		log.Debugf("Retrying %s of %s %s in %s: %s", verb, resource, key, delay, err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return err
+		}
+		delay *= 2
+		if delay > c.MaxDelay {
+			delay = c.MaxDelay
+		}
+	}
+}
+
+// isRetryableWrite returns whether a write failed because the API server is
+// overloaded, in which case it's retried after a while rather than failing
+// the whole event.
+func isRetryableWrite(err error) bool {
+	return kerrors.IsTooManyRequests(err) ||
+		kerrors.IsServerTimeout(err) ||
+		kerrors.IsTimeout(err) ||
+		kerrors.IsServiceUnavailable(err)
+}
+
+func (s retryingServices) Create(ctx context.Context, svc *corev1.Service, opts metav1.CreateOptions) (created *corev1.Service, err error) {
+	err = s.backoff.retry(ctx, s.log, "create", "service", s.namespace+"/"+svc.Name, func() error {
+		created, err = s.ServiceInterface.Create(ctx, svc, opts)
+		return err
+	})
+	return created, err
+}
+
+func (s retryingServices) Update(ctx context.Context, svc *corev1.Service, opts metav1.UpdateOptions) (updated *corev1.Service, err error) {
+	err = s.backoff.retry(ctx, s.log, "update", "service", s.namespace+"/"+svc.Name, func() error {
+		updated, err = s.ServiceInterface.Update(ctx, svc, opts)
+		return err
+	})
+	return updated, err
+}
+
+func (s retryingServices) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return s.backoff.retry(ctx, s.log, "delete", "service", s.namespace+"/"+name, func() error {
+		return s.ServiceInterface.Delete(ctx, name, opts)
+	})
+}
+
+func (s retryingServices) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (patched *corev1.Service, err error) {
+	err = s.backoff.retry(ctx, s.log, patchVerb(pt), "service", s.namespace+"/"+name, func() error {
+		patched, err = s.ServiceInterface.Patch(ctx, name, pt, data, opts, subresources...)
+		return err
+	})
+	return patched, err
+}
+
+func (e retryingEndpoints) Create(ctx context.Context, ep *corev1.Endpoints, opts metav1.CreateOptions) (created *corev1.Endpoints, err error) {
+	err = e.backoff.retry(ctx, e.log, "create", "endpoints", e.namespace+"/"+ep.Name, func() error {
+		created, err = e.EndpointsInterface.Create(ctx, ep, opts)
+		return err
+	})
+	return created, err
+}
+
+func (e retryingEndpoints) Update(ctx context.Context, ep *corev1.Endpoints, opts metav1.UpdateOptions) (updated *corev1.Endpoints, err error) {
+	err = e.backoff.retry(ctx, e.log, "update", "endpoints", e.namespace+"/"+ep.Name, func() error {
+		updated, err = e.EndpointsInterface.Update(ctx, ep, opts)
+		return err
+	})
+	return updated, err
+}
+
+func (e retryingEndpoints) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	return e.backoff.retry(ctx, e.log, "delete", "endpoints", e.namespace+"/"+name, func() error {
+		return e.EndpointsInterface.Delete(ctx, name, opts)
+	})
+}
+
+func (e retryingEndpoints) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (patched *corev1.Endpoints, err error) {
+	err = e.backoff.retry(ctx, e.log, patchVerb(pt), "endpoints", e.namespace+"/"+name, func() error {
+		patched, err = e.EndpointsInterface.Patch(ctx, name, pt, data, opts, subresources...)
+		return err
+	})
+	return patched, err
+}
+
+func (n retryingNamespaces) Create(ctx context.Context, ns *corev1.Namespace, opts metav1.CreateOptions) (created *corev1.Namespace, err error) {
+	err = n.backoff.retry(ctx, n.log, "create", "namespace", ns.Name, func() error {
+		created, err = n.NamespaceInterface.Create(ctx, ns, opts)
+		return err
+	})
+	return created, err
+}
+
+func (n retryingNamespaces) Update(ctx context.Context, ns *corev1.Namespace, opts metav1.UpdateOptions) (updated *corev1.Namespace, err error) {
+	err = n.backoff.retry(ctx, n.log, "update", "namespace", ns.Name, func() error {
+		updated, err = n.NamespaceInterface.Update(ctx, ns, opts)
+		return err
+	})
+	return updated, err
+}
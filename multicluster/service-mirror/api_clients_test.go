@@ -0,0 +1,110 @@
+package servicemirror
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	logging "github.com/sirupsen/logrus"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestWriteBackoffConfigValidate(t *testing.T) {
+	if err := DefaultWriteBackoffConfig().Validate(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := DefaultClientConfig().Validate(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		config func() interface{ Validate() error }
+	}{
+		{"zero attempts", func() interface{ Validate() error } {
+			c := DefaultWriteBackoffConfig()
+			c.Attempts = 0
+			return c
+		}},
+		{"zero base delay", func() interface{ Validate() error } {
+			c := DefaultWriteBackoffConfig()
+			c.BaseDelay = 0
+			return c
+		}},
+		{"max delay lower than base delay", func() interface{ Validate() error } {
+			c := DefaultWriteBackoffConfig()
+			c.MaxDelay = time.Millisecond
+			return c
+		}},
+		{"zero client qps", func() interface{ Validate() error } {
+			c := DefaultClientConfig()
+			c.QPS = 0
+			return c
+		}},
+		{"zero client burst", func() interface{ Validate() error } {
+			c := DefaultClientConfig()
+			c.Burst = 0
+			return c
+		}},
+	} {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			if err := tc.config().Validate(); err == nil {
+				t.Fatal("Expected an error, got none")
+			}
+		})
+	}
+}
+
+func TestWriteBackoffRetry(t *testing.T) {
+	throttled := kerrors.NewTooManyRequests("throttled", 1)
+	conflict := kerrors.NewConflict(schema.GroupResource{Resource: "services"}, "svc", errors.New("conflict"))
+
+	for _, tc := range []struct {
+		name             string
+		errs             []error
+		expectedAttempts int
+		expectedErr      error
+	}{
+		{
+			name:             "succeeds after throttled attempts",
+			errs:             []error{throttled, throttled, nil},
+			expectedAttempts: 3,
+		},
+		{
+			name:             "gives up after the last attempt",
+			errs:             []error{throttled, throttled, throttled, throttled},
+			expectedAttempts: 3,
+			expectedErr:      throttled,
+		},
+		{
+			name:             "doesn't retry other errors",
+			errs:             []error{conflict, nil},
+			expectedAttempts: 1,
+			expectedErr:      conflict,
+		},
+	} {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			backoff := WriteBackoffConfig{
+				Attempts:  3,
+				BaseDelay: time.Millisecond,
+				MaxDelay:  2 * time.Millisecond,
+			}
+			attempts := 0
+			err := backoff.retry(context.Background(), logging.WithField("test", t.Name()), "create", "service", "ns/svc", func() error {
+				err := tc.errs[attempts]
+				attempts++
+				return err
+			})
+			if err != tc.expectedErr {
+				t.Errorf("Expected error %v, got %v", tc.expectedErr, err)
+			}
+			if attempts != tc.expectedAttempts {
+				t.Errorf("Expected %d attempts, got %d", tc.expectedAttempts, attempts)
+			}
+		})
+	}
+}
@@ -0,0 +1,259 @@
+package servicemirror
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	consts "github.com/linkerd/linkerd2/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// fieldManager is the field manager of the server-side applies of the
+	// mirror resources, so that the service mirror only owns the fields it
+	// sets and leaves alone the ones set by other controllers.
+	fieldManager = "linkerd-service-mirror"
+
+	// legacyFieldManager is the field manager of the creates and updates of
+	// the mirror resources, which defaults to the name of the controller
+	// binary.
+	legacyFieldManager = "controller"
+)
+
+// applyOptions returns the options of the server-side applies of the mirror
+// resources. The conflicts are forced, as the service mirror is the source of
+// truth for the fields it sets.
+func applyOptions() metav1.PatchOptions {
+	force := true
+	return metav1.PatchOptions{FieldManager: fieldManager, Force: &force}
+}
+
+// patchVerb returns the verb logged and counted for a patch of the given
+// type.
+func patchVerb(pt types.PatchType) string {
+	if pt == types.ApplyPatchType {
+		return "apply"
+	}
+	return "patch"
+}
+
+// upgradeManagedFields returns the JSON patch moving the fields the service
+// mirror owns through its creates and updates of the given object to its
+// apply field manager, or nil if it owns none. Otherwise, the fields left out
+// of the applies of the mirrors created or updated before they were applied
+// would never be removed, as the entries of the creates and updates would
+// still own them. The patch replaces the resourceVersion, so that it fails if
+// the managed fields changed since they were read.
+func upgradeManagedFields(obj metav1.Object) ([]byte, error) {
+	var fields map[string]interface{}
+	var entries []metav1.ManagedFieldsEntry
+	upgrade := false
+	for _, entry := range obj.GetManagedFields() {
+		legacy := entry.Manager == legacyFieldManager && entry.Operation == metav1.ManagedFieldsOperationUpdate
+		applied := entry.Manager == fieldManager && entry.Operation == metav1.ManagedFieldsOperationApply
+		if !legacy && !applied {
+			entries = append(entries, entry)
+			continue
+		}
+		upgrade = upgrade || legacy
+		if entry.FieldsV1 == nil {
+			continue
+		}
+		var set map[string]interface{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &set); err != nil {
+			return nil, fmt.Errorf("failed to decode the fields managed by %s: %w", entry.Manager, err)
+		}
+		fields = mergeFieldSets(fields, set)
+	}
+	if !upgrade {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	now := metav1.Now()
+	entries = append(entries, metav1.ManagedFieldsEntry{
+		Manager:    fieldManager,
+		Operation:  metav1.ManagedFieldsOperationApply,
+		APIVersion: "v1",
+		Time:       &now,
+		FieldsType: "FieldsV1",
+		FieldsV1:   &metav1.FieldsV1{Raw: raw},
+	})
+	return json.Marshal([]map[string]interface{}{
+		{"op": "replace", "path": "/metadata/managedFields", "value": entries},
+		{"op": "replace", "path": "/metadata/resourceVersion", "value": obj.GetResourceVersion()},
+	})
+}
+
+// mergeFieldSets adds the fields of the src set to the dst one. Field sets are
+// trees of JSON objects, e.g. {"f:metadata":{"f:labels":{"f:app":{}}}}.
+func mergeFieldSets(dst, src map[string]interface{}) map[string]interface{} {
+	if dst == nil {
+		dst = make(map[string]interface{}, len(src))
+	}
+	for k, v := range src {
+		set, ok := v.(map[string]interface{})
+		if !ok {
+			dst[k] = v
+			continue
+		}
+		existing, _ := dst[k].(map[string]interface{})
+		dst[k] = mergeFieldSets(existing, set)
+	}
+	return dst
+}
+
+// upgradeServiceManagedFields upgrades the managed fields of the given local
+// mirror service, if any, before it's applied.
+func (rcsw *RemoteClusterServiceWatcher) upgradeServiceManagedFields(ctx context.Context, namespace, name string) error {
+	svc, err := rcsw.localAPIClient.Svc().Lister().Services(namespace).Get(name)
+	if kerrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	patch, err := upgradeManagedFields(svc)
+	if err != nil || patch == nil {
+		return err
+	}
+	rcsw.log.Debugf("Moving the fields of service %s/%s to the %s field manager", namespace, name, fieldManager)
+	_, err = rcsw.localServices(namespace).Patch(ctx, name, types.JSONPatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// upgradeEndpointsManagedFields upgrades the managed fields of the given local
+// mirror endpoints, if any, before they're applied.
+func (rcsw *RemoteClusterServiceWatcher) upgradeEndpointsManagedFields(ctx context.Context, namespace, name string) error {
+	ep, err := rcsw.localAPIClient.Endpoint().Lister().Endpoints(namespace).Get(name)
+	if kerrors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	patch, err := upgradeManagedFields(ep)
+	if err != nil || patch == nil {
+		return err
+	}
+	rcsw.log.Debugf("Moving the fields of endpoints %s/%s to the %s field manager", namespace, name, fieldManager)
+	_, err = rcsw.localEndpoints(namespace).Patch(ctx, name, types.JSONPatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// applyService server-side applies the labels, annotations and ports of the
+// given mirror service. The other fields, such as the ClusterIP, are left to
+// the API server and the other controllers.
+func (rcsw *RemoteClusterServiceWatcher) applyService(ctx context.Context, svc *corev1.Service) error {
+	applied := &corev1.Service{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Service"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        svc.Name,
+			Namespace:   svc.Namespace,
+			Labels:      svc.Labels,
+			Annotations: svc.Annotations,
+		},
+		Spec: corev1.ServiceSpec{Ports: svc.Spec.Ports},
+	}
+	data, err := json.Marshal(applied)
+	if err != nil {
+		return err
+	}
+	if err := rcsw.upgradeServiceManagedFields(ctx, svc.Namespace, svc.Name); err != nil {
+		return err
+	}
+	_, err = rcsw.localServices(svc.Namespace).Patch(ctx, svc.Name, types.ApplyPatchType, data, applyOptions())
+	return err
+}
+
+// applyEndpoints server-side applies the labels, annotations and subsets of
+// the given mirror endpoints, creating them if they don't exist.
+func (rcsw *RemoteClusterServiceWatcher) applyEndpoints(ctx context.Context, ep *corev1.Endpoints) error {
+	applied := &corev1.Endpoints{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Endpoints"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        ep.Name,
+			Namespace:   ep.Namespace,
+			Labels:      ep.Labels,
+			Annotations: ep.Annotations,
+		},
+		Subsets: ep.Subsets,
+	}
+	data, err := json.Marshal(applied)
+	if err != nil {
+		return err
+	}
+	if err := rcsw.upgradeEndpointsManagedFields(ctx, ep.Namespace, ep.Name); err != nil {
+		return err
+	}
+	_, err = rcsw.localEndpoints(ep.Namespace).Patch(ctx, ep.Name, types.ApplyPatchType, data, applyOptions())
+	return err
+}
+
+// applyEndpointsIfChanged applies the given mirror endpoints unless the local
+// ones are already up to date, and returns whether they were written.
+func (rcsw *RemoteClusterServiceWatcher) applyEndpointsIfChanged(ctx context.Context, ep *corev1.Endpoints) (bool, error) {
+	local, err := rcsw.localAPIClient.Endpoint().Lister().Endpoints(ep.Namespace).Get(ep.Name)
+	if err == nil && endpointsUpToDate(local, ep) {
+		return false, nil
+	}
+	return true, rcsw.applyEndpoints(ctx, ep)
+}
+
+// endpointsUpToDate returns whether the local endpoints hold the subsets,
+// labels and annotations of the desired ones. The gateway weights are
+// compared even when the desired endpoints have none, as applying them then
+// removes the weights.
+func endpointsUpToDate(local, desired *corev1.Endpoints) bool {
+	if !apiequality.Semantic.DeepEqual(local.Subsets, desired.Subsets) {
+		return false
+	}
+	if !containsAll(local.Labels, desired.Labels) || !containsAll(local.Annotations, desired.Annotations) {
+		return false
+	}
+	_, localWeights := local.Annotations[consts.RemoteGatewayWeights]
+	_, desiredWeights := desired.Annotations[consts.RemoteGatewayWeights]
+	return localWeights == desiredWeights
+}
+
+func containsAll(m, subset map[string]string) bool {
+	for k, v := range subset {
+		if value, ok := m[k]; !ok || value != v {
+			return false
+		}
+	}
+	return true
+}
+
+// mirrorEndpoints returns the endpoints of the mirror of the given remote
+// service, pointing at the given gateway addresses. Every apply of the mirror
+// endpoints must be built from it, as the fields previously applied and
+// missing from a later apply are removed.
+func (rcsw *RemoteClusterServiceWatcher) mirrorEndpoints(namespace, name string, remote *corev1.Service, gatewayAddresses []corev1.EndpointAddress, gatewayWeights map[string]uint32) *corev1.Endpoints {
+	ep := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   namespace,
+			Labels:      rcsw.getMirrorLabels(remote),
+			Annotations: rcsw.getPropagatedAnnotations(remote),
+		},
+		Subsets: []corev1.EndpointSubset{
+			{
+				Addresses: gatewayAddresses,
+				Ports:     rcsw.getEndpointsPorts(remote),
+			},
+		},
+	}
+	ep.Annotations[consts.RemoteServiceFqName] = fmt.Sprintf("%s.%s.svc.%s", remote.Name, remote.Namespace, rcsw.link.TargetClusterDomain)
+	ep.Annotations[consts.RemoteGatewayIdentity] = rcsw.link.GatewayIdentity
+	setGatewayWeights(ep.Annotations, gatewayWeights)
+	return ep
+}
@@ -0,0 +1,297 @@
+package servicemirror
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	consts "github.com/linkerd/linkerd2/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMirrorUpdateKeepsForeignFields(t *testing.T) {
+	h := newMirrorHarness(t, harnessLink(), nil, nil)
+	ctx := context.Background()
+
+	h.createRemote(remoteService("service-one", "ns1", "", exportedLabels(), []corev1.ServicePort{
+		{Name: "http", Protocol: "TCP", Port: 80},
+	}))
+	h.eventually(func() error {
+		_, _, err := h.mirror("ns1", "service-one")
+		return err
+	})
+
+	// another controller labels the mirror service and annotates its
+	// endpoints
+	svc, ep, _ := h.mirror("ns1", "service-one")
+	svc = svc.DeepCopy()
+	svc.Labels["team.example.com/owner"] = "payments"
+	if _, err := h.local.Client.CoreV1().Services("ns1").Update(ctx, svc, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	ep = ep.DeepCopy()
+	ep.Annotations["endpoints.example.com/audited"] = "true"
+	if _, err := h.local.Client.CoreV1().Endpoints("ns1").Update(ctx, ep, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	h.updateRemote(remoteService("service-one", "ns1", "2", exportedLabels(), []corev1.ServicePort{
+		{Name: "http", Protocol: "TCP", Port: 80},
+		{Name: "admin", Protocol: "TCP", Port: 9990},
+	}))
+	h.repair()
+	h.eventually(func() error {
+		svc, ep, err := h.mirror("ns1", "service-one")
+		if err != nil {
+			return err
+		}
+		if len(svc.Spec.Ports) != 2 || len(ep.Subsets) != 1 || len(ep.Subsets[0].Ports) != 2 {
+			return fmt.Errorf("expected 2 ports, got %v and %v", svc.Spec.Ports, ep.Subsets)
+		}
+		if svc.Labels["team.example.com/owner"] != "payments" {
+			return fmt.Errorf("expected the foreign label to be kept, got %v", svc.Labels)
+		}
+		if ep.Annotations["endpoints.example.com/audited"] != "true" {
+			return fmt.Errorf("expected the foreign annotation to be kept, got %v", ep.Annotations)
+		}
+		return nil
+	})
+}
+
+func TestEndpointsUpToDate(t *testing.T) {
+	desired := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "service-one-remote",
+			Namespace:   "ns1",
+			Labels:      map[string]string{consts.MirroredResourceLabel: "true"},
+			Annotations: map[string]string{consts.RemoteGatewayIdentity: "gateway-identity"},
+		},
+		Subsets: []corev1.EndpointSubset{{
+			Addresses: []corev1.EndpointAddress{{IP: "192.0.2.127"}},
+			Ports:     []corev1.EndpointPort{{Name: "http", Port: 888, Protocol: "TCP"}},
+		}},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		update   func(*corev1.Endpoints)
+		upToDate bool
+	}{
+		{
+			name:     "same endpoints",
+			update:   func(*corev1.Endpoints) {},
+			upToDate: true,
+		},
+		{
+			name: "foreign label",
+			update: func(ep *corev1.Endpoints) {
+				ep.Labels["team.example.com/owner"] = "payments"
+			},
+			upToDate: true,
+		},
+		{
+			name: "other gateway address",
+			update: func(ep *corev1.Endpoints) {
+				ep.Subsets[0].Addresses[0].IP = "192.0.2.128"
+			},
+		},
+		{
+			name: "other gateway identity",
+			update: func(ep *corev1.Endpoints) {
+				ep.Annotations[consts.RemoteGatewayIdentity] = "other-identity"
+			},
+		},
+		{
+			name: "stale gateway weights",
+			update: func(ep *corev1.Endpoints) {
+				ep.Annotations[consts.RemoteGatewayWeights] = "192.0.2.127=1"
+			},
+		},
+	} {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			local := desired.DeepCopy()
+			tc.update(local)
+			if upToDate := endpointsUpToDate(local, desired); upToDate != tc.upToDate {
+				t.Fatalf("Expected up to date to be %t, got %t", tc.upToDate, upToDate)
+			}
+		})
+	}
+}
+
+// legacyManagedService is the YAML of a mirror service created by the service
+// mirror before its applies, and labelled by another controller since.
+const legacyManagedService = `
+apiVersion: v1
+kind: Service
+metadata:
+  name: service-one-remote
+  namespace: ns1
+  resourceVersion: "42"
+  labels:
+    mirror.linkerd.io/mirrored-service: "true"
+    team.example.com/owner: payments
+  managedFields:
+  - manager: controller
+    operation: Update
+    apiVersion: v1
+    fieldsType: FieldsV1
+    fieldsV1:
+      f:metadata:
+        f:labels:
+          .: {}
+          f:mirror.linkerd.io/mirrored-service: {}
+      f:spec:
+        f:ports:
+          .: {}
+          'k:{"port":80,"protocol":"TCP"}':
+            .: {}
+            f:port: {}
+  - manager: linkerd-service-mirror
+    operation: Apply
+    apiVersion: v1
+    fieldsType: FieldsV1
+    fieldsV1:
+      f:metadata:
+        f:annotations:
+          f:mirror.linkerd.io/remote-resource-version: {}
+  - manager: team-labeller
+    operation: Update
+    apiVersion: v1
+    fieldsType: FieldsV1
+    fieldsV1:
+      f:metadata:
+        f:labels:
+          f:team.example.com/owner: {}
+spec:
+  ports:
+  - port: 80
+    protocol: TCP
+`
+
+func TestUpgradeManagedFields(t *testing.T) {
+	localAPI, err := newFakeAPI(legacyManagedService)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	svc, err := localAPI.Client.CoreV1().Services("ns1").Get(context.Background(), "service-one-remote", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	patch, err := upgradeManagedFields(svc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if patch == nil {
+		t.Fatal("Expected the managed fields to be upgraded")
+	}
+	decoded, err := jsonpatch.DecodePatch(patch)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	original, err := json.Marshal(svc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	patched, err := decoded.Apply(original)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	var upgraded corev1.Service
+	if err := json.Unmarshal(patched, &upgraded); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	if upgraded.ResourceVersion != "42" {
+		t.Fatalf("Expected the patch to be conditioned on the resourceVersion, got %q", upgraded.ResourceVersion)
+	}
+	managers := map[string]metav1.ManagedFieldsEntry{}
+	for _, entry := range upgraded.ManagedFields {
+		if _, ok := managers[entry.Manager]; ok {
+			t.Fatalf("Expected a single entry for %s, got %v", entry.Manager, upgraded.ManagedFields)
+		}
+		managers[entry.Manager] = entry
+	}
+	if _, ok := managers[legacyFieldManager]; ok {
+		t.Fatalf("Expected the fields of %s to be moved, got %v", legacyFieldManager, upgraded.ManagedFields)
+	}
+	if _, ok := managers["team-labeller"]; !ok {
+		t.Fatalf("Expected the fields of other managers to be left alone, got %v", upgraded.ManagedFields)
+	}
+	applied, ok := managers[fieldManager]
+	if !ok || applied.Operation != metav1.ManagedFieldsOperationApply {
+		t.Fatalf("Expected the fields to be applied by %s, got %v", fieldManager, upgraded.ManagedFields)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(applied.FieldsV1.Raw, &fields); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	metadata, _ := fields["f:metadata"].(map[string]interface{})
+	labels, _ := metadata["f:labels"].(map[string]interface{})
+	if _, ok := labels["f:mirror.linkerd.io/mirrored-service"]; !ok {
+		t.Errorf("Expected the legacy fields to be applied, got %s", applied.FieldsV1.Raw)
+	}
+	if _, ok := labels["f:team.example.com/owner"]; ok {
+		t.Errorf("Expected the fields of other managers not to be applied, got %s", applied.FieldsV1.Raw)
+	}
+	if _, ok := metadata["f:annotations"]; !ok {
+		t.Errorf("Expected the applied fields to be kept, got %s", applied.FieldsV1.Raw)
+	}
+	if _, ok := fields["f:spec"]; !ok {
+		t.Errorf("Expected the legacy fields to be applied, got %s", applied.FieldsV1.Raw)
+	}
+
+	// once upgraded, the managed fields are left alone
+	patch, err = upgradeManagedFields(&upgraded)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if patch != nil {
+		t.Fatalf("Expected no upgrade, got %s", patch)
+	}
+}
+
+func TestApplyUpgradesManagedFields(t *testing.T) {
+	localAPI, err := newFakeAPI(legacyManagedService)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	localAPI.Sync(nil)
+	watcher := RemoteClusterServiceWatcher{
+		localAPIClient: localAPI,
+		log:            logging.WithFields(logging.Fields{"cluster": clusterName}),
+	}
+
+	svc := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "service-one-remote",
+			Namespace: "ns1",
+			Labels:    map[string]string{"mirror.linkerd.io/mirrored-service": "true"},
+		},
+		Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{{Protocol: "TCP", Port: 80}}},
+	}
+	if err := watcher.applyService(context.Background(), svc); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	var patchTypes []types.PatchType
+	for _, action := range localAPI.Client.(*fake.Clientset).Actions() {
+		if patch, ok := action.(k8stesting.PatchAction); ok {
+			patchTypes = append(patchTypes, patch.GetPatchType())
+		}
+	}
+	if len(patchTypes) != 2 || patchTypes[0] != types.JSONPatchType || patchTypes[1] != types.ApplyPatchType {
+		t.Fatalf("Expected the managed fields to be upgraded before the apply, got the patches %v", patchTypes)
+	}
+	updated, err := localAPI.Client.CoreV1().Services("ns1").Get(context.Background(), "service-one-remote", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	for _, entry := range updated.ManagedFields {
+		if entry.Manager == legacyFieldManager {
+			t.Fatalf("Expected the fields of %s to be moved, got %v", legacyFieldManager, updated.ManagedFields)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package servicemirror
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/linkerd/linkerd2/pkg/multicluster"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// CircuitBreakerConfig controls when the processing of the events of a cluster
+// watcher is paused because the API server of its target cluster can't be
+// reached, instead of retrying them until they're dropped.
+type CircuitBreakerConfig struct {
+	// CheckPeriod is the time between two checks of the API server
+	CheckPeriod time.Duration
+	// Timeout caps the duration of a check
+	Timeout time.Duration
+	// FailureThreshold is the number of consecutive failed checks after which
+	// the processing of the events is paused
+	FailureThreshold int
+}
+
+// circuitBreaker tracks the results of the checks of the API server of the
+// target cluster. It opens after threshold consecutive failures, and closes
+// on the first success.
+type circuitBreaker struct {
+	threshold int
+
+	sync.Mutex
+	failures int
+	open     bool
+	// closed is closed while the circuit is closed, so that wait returns
+	// right away
+	closed chan struct{}
+}
+
+// DefaultCircuitBreakerConfig returns the configuration used when none is
+// given.
+func DefaultCircuitBreakerConfig() CircuitBreakerConfig {
+	return CircuitBreakerConfig{
+		CheckPeriod:      10 * time.Second,
+		Timeout:          5 * time.Second,
+		FailureThreshold: 3,
+	}
+}
+
+// Validate returns an error if the configuration can't be used.
+func (c CircuitBreakerConfig) Validate() error {
+	if c.CheckPeriod <= 0 {
+		return fmt.Errorf("invalid target cluster check period %s: must be positive", c.CheckPeriod)
+	}
+	if c.Timeout <= 0 {
+		return fmt.Errorf("invalid target cluster check timeout %s: must be positive", c.Timeout)
+	}
+	if c.FailureThreshold <= 0 {
+		return fmt.Errorf("invalid target cluster failure threshold %d: must be positive", c.FailureThreshold)
+	}
+	return nil
+}
+
+func newCircuitBreaker(threshold int) *circuitBreaker {
+	closed := make(chan struct{})
+	close(closed)
+	return &circuitBreaker{threshold: threshold, closed: closed}
+}
+
+// record records the result of a check, and returns true if it opened or
+// closed the circuit.
+func (cb *circuitBreaker) record(err error) bool {
+	cb.Lock()
+	defer cb.Unlock()
+	if err == nil {
+		cb.failures = 0
+		if !cb.open {
+			return false
+		}
+		cb.open = false
+		close(cb.closed)
+		return true
+	}
+
+	cb.failures++
+	if cb.open || cb.failures < cb.threshold {
+		return false
+	}
+	cb.open = true
+	cb.closed = make(chan struct{})
+	return true
+}
+
+func (cb *circuitBreaker) isOpen() bool {
+	cb.Lock()
+	defer cb.Unlock()
+	return cb.open
+}
+
+// wait blocks while the circuit is open, until it closes or stop is closed.
+func (cb *circuitBreaker) wait(stop <-chan struct{}) {
+	cb.Lock()
+	closed := cb.closed
+	cb.Unlock()
+	select {
+	case <-closed:
+	case <-stop:
+	}
+}
+
+// watchRemoteReachability checks the API server of the target cluster every
+// check period, until the watcher is stopped.
+func (rcsw *RemoteClusterServiceWatcher) watchRemoteReachability(ctx context.Context) {
+	ticker := time.NewTicker(rcsw.checkPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			rcsw.recordRemoteCheck(ctx, rcsw.checkRemote())
+		case <-rcsw.stopper:
+			return
+		}
+	}
+}
+
+// recordRemoteCheck records the result of a check of the API server of the
+// target cluster. Once the circuit opens, the events stop being processed and
+// the Link is reported as unreachable; once it closes again, the processing
+// resumes with a full reconcile, as the events of the target cluster may have
+// been missed in the meantime.
+func (rcsw *RemoteClusterServiceWatcher) recordRemoteCheck(ctx context.Context, err error) {
+	if !rcsw.circuit.record(err) {
+		if err != nil {
+			// Only the transitions are logged above debug level, so that an
+			// outage doesn't flood the logs
+			if rcsw.circuit.isOpen() {
+				rcsw.log.Debugf("Target cluster API still unreachable: %s", err)
+			} else {
+				rcsw.log.Warnf("Failed to reach target cluster API: %s", err)
+			}
+		}
+		return
+	}
+
+	clusterName := rcsw.link.TargetClusterName
+	condition := metav1.Condition{Type: multicluster.LinkConditionUnreachable}
+	if err != nil {
+		rcsw.log.Errorf("Target cluster API unreachable, pausing the processing of events: %s", err)
+		remoteUnreachable.WithLabelValues(clusterName).Set(1)
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "APIServerUnreachable"
+		condition.Message = err.Error()
+	} else {
+		rcsw.log.Infof("Target cluster API reachable again, resuming the processing of events")
+		remoteUnreachable.WithLabelValues(clusterName).Set(0)
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "APIServerReachable"
+		condition.Message = "The target cluster API server is reachable"
+		rcsw.reconcileAll()
+	}
+
+	if rcsw.linkClient == nil {
+		return
+	}
+	if err := multicluster.SetLinkCondition(ctx, rcsw.linkClient, rcsw.link.Namespace, rcsw.link.Name, condition); err != nil {
+		rcsw.log.Errorf("Failed to update %s condition on Link %s: %s", condition.Type, rcsw.link.Name, err)
+	}
+}
+
+// reconcileAll queues the events bringing all the mirrors in line with the
+// services of the target cluster: orphaned mirrors are removed, every remote
+// service is mirrored again and the endpoints are repaired.
+func (rcsw *RemoteClusterServiceWatcher) reconcileAll() {
+	rcsw.eventsQueue.Add(&OrphanedServicesGcTriggered{})
+	services, err := rcsw.remoteAPIClient.Svc().Lister().List(labels.Everything())
+	if err != nil {
+		rcsw.log.Errorf("Failed to list the services of the target cluster: %s", err)
+	}
+	for _, svc := range services {
+		rcsw.eventsQueue.Add(&OnAddCalled{svc.DeepCopy()})
+	}
+	rcsw.eventsQueue.Add(&RepairEndpoints{})
+}
@@ -0,0 +1,123 @@
+package servicemirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/linkerd/linkerd2/pkg/multicluster"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCircuitBreakerConfigValidate(t *testing.T) {
+	if err := DefaultCircuitBreakerConfig().Validate(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		config func(*CircuitBreakerConfig)
+	}{
+		{"zero check period", func(c *CircuitBreakerConfig) { c.CheckPeriod = 0 }},
+		{"zero timeout", func(c *CircuitBreakerConfig) { c.Timeout = 0 }},
+		{"zero failure threshold", func(c *CircuitBreakerConfig) { c.FailureThreshold = 0 }},
+	} {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			config := DefaultCircuitBreakerConfig()
+			tc.config(&config)
+			if err := config.Validate(); err == nil {
+				t.Fatal("Expected an error, got none")
+			}
+		})
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	cb := newCircuitBreaker(2)
+	unreachable := errors.New("connection refused")
+
+	if cb.record(unreachable) || cb.isOpen() {
+		t.Fatal("Expected the circuit to stay closed below the failure threshold")
+	}
+	if cb.record(nil) {
+		t.Fatal("Expected a success to leave a closed circuit closed")
+	}
+	if cb.record(unreachable) || cb.isOpen() {
+		t.Fatal("Expected a success to reset the failures")
+	}
+	if !cb.record(unreachable) || !cb.isOpen() {
+		t.Fatal("Expected the circuit to open at the failure threshold")
+	}
+	if cb.record(unreachable) {
+		t.Fatal("Expected further failures not to change an open circuit")
+	}
+
+	waited := make(chan struct{})
+	go func() {
+		cb.wait(make(chan struct{}))
+		close(waited)
+	}()
+	select {
+	case <-waited:
+		t.Fatal("Expected wait to block while the circuit is open")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if !cb.record(nil) || cb.isOpen() {
+		t.Fatal("Expected a success to close the circuit")
+	}
+	select {
+	case <-waited:
+	case <-time.After(time.Second):
+		t.Fatal("Expected wait to return once the circuit closed")
+	}
+}
+
+func TestMirrorHarnessPausesWhileUnreachable(t *testing.T) {
+	h := newMirrorHarness(t, harnessLink(), nil, nil)
+	ctx := context.Background()
+
+	expectCondition := func(status metav1.ConditionStatus) func() error {
+		return func() error {
+			condition, err := multicluster.GetLinkCondition(ctx, h.linkAPI, h.link.Namespace, h.link.Name, multicluster.LinkConditionUnreachable)
+			if err != nil {
+				return err
+			}
+			if condition == nil || condition.Status != status {
+				return fmt.Errorf("expected a %s %s condition, got %v", status, multicluster.LinkConditionUnreachable, condition)
+			}
+			return nil
+		}
+	}
+
+	for i := 0; i < DefaultCircuitBreakerConfig().FailureThreshold; i++ {
+		h.watcher.recordRemoteCheck(ctx, errors.New("connection refused"))
+	}
+	h.eventually(expectCondition(metav1.ConditionTrue))
+	if unreachable := testutil.ToFloat64(remoteUnreachable.WithLabelValues(clusterName)); unreachable != 1 {
+		t.Fatalf("Expected the unreachable gauge to be 1, got %v", unreachable)
+	}
+
+	h.createRemote(remoteService("service-one", "ns1", "", exportedLabels(), []corev1.ServicePort{
+		{Name: "http", Protocol: "TCP", Port: 80},
+	}))
+	time.Sleep(100 * time.Millisecond)
+	if _, _, err := h.mirror("ns1", "service-one"); err == nil {
+		t.Fatal("Expected service-one not to be mirrored while the target cluster is unreachable")
+	}
+
+	h.watcher.recordRemoteCheck(ctx, nil)
+	h.eventually(expectCondition(metav1.ConditionFalse))
+	h.eventually(func() error {
+		_, _, err := h.mirror("ns1", "service-one")
+		return err
+	})
+	if unreachable := testutil.ToFloat64(remoteUnreachable.WithLabelValues(clusterName)); unreachable != 0 {
+		t.Fatalf("Expected the unreachable gauge to be 0, got %v", unreachable)
+	}
+}
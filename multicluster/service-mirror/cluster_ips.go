@@ -0,0 +1,109 @@
+package servicemirror
+
+import (
+	"context"
+	"encoding/json"
+
+	consts "github.com/linkerd/linkerd2/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// maxReservedClusterIPs bounds the number of ClusterIPs reserved in a
+// namespace, so that the annotation doesn't grow unbounded when remote
+// services are deleted for good.
+const maxReservedClusterIPs = 256
+
+// reservedClusterIPs returns the ClusterIPs reserved in the given namespace,
+// indexed by the name of the mirrored service they belonged to.
+func reservedClusterIPs(ns *corev1.Namespace) map[string]string {
+	ips := make(map[string]string)
+	value, ok := ns.Annotations[consts.ReservedClusterIPsAnnotation]
+	if !ok {
+		return ips
+	}
+	if err := json.Unmarshal([]byte(value), &ips); err != nil {
+		return make(map[string]string)
+	}
+	return ips
+}
+
+// updateReservedClusterIPs applies the given function to the ClusterIPs
+// reserved in a namespace and stores the result, retrying on conflicts as
+// the namespace can be shared by the mirrors of several links.
+func (rcsw *RemoteClusterServiceWatcher) updateReservedClusterIPs(ctx context.Context, namespace string, update func(map[string]string) bool) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		ns, err := rcsw.localNamespaces().Get(ctx, namespace, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		ips := reservedClusterIPs(ns)
+		if !update(ips) {
+			return nil
+		}
+
+		ns = ns.DeepCopy()
+		if len(ips) == 0 {
+			delete(ns.Annotations, consts.ReservedClusterIPsAnnotation)
+		} else {
+			value, err := json.Marshal(ips)
+			if err != nil {
+				return err
+			}
+			if ns.Annotations == nil {
+				ns.Annotations = make(map[string]string)
+			}
+			ns.Annotations[consts.ReservedClusterIPsAnnotation] = string(value)
+		}
+		_, err = rcsw.localNamespaces().Update(ctx, ns, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// reserveClusterIP records the ClusterIP of a mirrored service that is about
+// to be deleted. If the remote service comes back, the mirror is recreated
+// with the same ClusterIP so that clients with cached DNS entries aren't
+// broken.
+func (rcsw *RemoteClusterServiceWatcher) reserveClusterIP(ctx context.Context, svc *corev1.Service) {
+	ip := svc.Spec.ClusterIP
+	if ip == "" || ip == corev1.ClusterIPNone {
+		return
+	}
+	err := rcsw.updateReservedClusterIPs(ctx, svc.Namespace, func(ips map[string]string) bool {
+		if _, ok := ips[svc.Name]; !ok && len(ips) >= maxReservedClusterIPs {
+			rcsw.log.Debugf("Not reserving ClusterIP %s of %s/%s: too many reserved ClusterIPs in the namespace", ip, svc.Namespace, svc.Name)
+			return false
+		}
+		ips[svc.Name] = ip
+		return true
+	})
+	if err != nil {
+		rcsw.log.Warnf("Failed to reserve ClusterIP %s of %s/%s: %s", ip, svc.Namespace, svc.Name, err)
+	}
+}
+
+// reservedClusterIP returns the ClusterIP reserved for the given mirrored
+// service, or an empty string if there's none.
+func (rcsw *RemoteClusterServiceWatcher) reservedClusterIP(ctx context.Context, namespace, name string) string {
+	ns, err := rcsw.localNamespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if err != nil {
+		return ""
+	}
+	return reservedClusterIPs(ns)[name]
+}
+
+// releaseClusterIP removes the reservation of the ClusterIP of a mirrored
+// service once the service has been recreated.
+func (rcsw *RemoteClusterServiceWatcher) releaseClusterIP(ctx context.Context, namespace, name string) {
+	err := rcsw.updateReservedClusterIPs(ctx, namespace, func(ips map[string]string) bool {
+		if _, ok := ips[name]; !ok {
+			return false
+		}
+		delete(ips, name)
+		return true
+	})
+	if err != nil {
+		rcsw.log.Warnf("Failed to release the ClusterIP reserved for %s/%s: %s", namespace, name, err)
+	}
+}
@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/linkerd/linkerd2/controller/k8s"
+	"github.com/linkerd/linkerd2/pkg/crash"
 	consts "github.com/linkerd/linkerd2/pkg/k8s"
 	"github.com/linkerd/linkerd2/pkg/multicluster"
 	"github.com/prometheus/client_golang/prometheus"
@@ -16,8 +19,11 @@ import (
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/workqueue"
 )
 
@@ -30,6 +36,16 @@ type (
 	// it can be requeued up to N times, to ensure that the failure is not due to some temporary network
 	// problems or general glitch in the Matrix.
 	RemoteClusterServiceWatcher struct {
+		// lastProcessed is when an event was last processed successfully,
+		// in nanoseconds since the epoch. It's accessed atomically, and
+		// comes first to be 64-bit aligned.
+		lastProcessed int64
+		// lastReconciled is when the mirrors were last reconciled
+		// successfully, through a repair of their endpoints or a collection
+		// of the orphaned ones, in nanoseconds since the epoch. It's accessed
+		// atomically.
+		lastReconciled int64
+
 		serviceMirrorNamespace string
 		link                   *multicluster.Link
 		remoteAPIClient        *k8s.API
@@ -39,6 +55,90 @@ type (
 		eventsQueue            workqueue.RateLimitingInterface
 		requeueLimit           int
 		repairPeriod           time.Duration
+		linkClient             dynamic.Interface
+		recorder               record.EventRecorder
+
+		// repairs adapts the interval between the periodic repairs of the
+		// mirrored endpoints, starting at repairPeriod.
+		repairs *repairSchedule
+
+		// conflicts tracks the mirror names (namespace/name) that could not
+		// be claimed by this Link because they are owned by someone else,
+		// keyed to a description of the current owner. conflictsVersion is
+		// incremented on each change.
+		conflicts        map[string]string
+		conflictsVersion uint64
+		conflictsMu      sync.Mutex
+
+		// conflictsWritten is the version of the conflicts last written to
+		// the MirrorConflict condition of the Link. It's guarded by
+		// conditionMu, which keeps the writes in order without holding
+		// conflictsMu during the API calls.
+		conflictsWritten uint64
+		conditionMu      sync.Mutex
+
+		// workers is the number of events processed concurrently. Events
+		// of the same service are always processed in order.
+		workers int
+
+		// initialSyncRate is the maximum number of mirrors created per second
+		// when the watcher starts.
+		initialSyncRate int
+		initialSync     initialSyncState
+		initialSyncMu   sync.Mutex
+
+		// serviceImports is true if MCS API ServiceImports are maintained
+		// for the mirror services.
+		serviceImports bool
+
+		// remoteExports is an informer on the MCS API ServiceExports of the
+		// target cluster, whose services are mirrored whether or not they
+		// match the Link's selector. It's nil unless enabled.
+		remoteExports cache.SharedIndexInformer
+
+		// gatewayHealth tracks the probe results of the individual gateway
+		// addresses. Unhealthy addresses are left out of the mirrored
+		// Endpoints.
+		gatewayHealth *gatewayHealth
+
+		// gatewayAlive returns whether the probe worker of the Link
+		// considers the gateway as a whole alive. It's nil unless the
+		// liveness is shared with ShareGatewayLiveness.
+		gatewayAlive func() bool
+
+		// gatewayResolver resolves the gateway address, keeping track of
+		// the TTLs of its DNS records and of the gateway port discovered
+		// through SRV records.
+		gatewayResolver *gatewayResolver
+
+		// faults injects failures in the local API writes and remote
+		// informer events, for testing purposes. It's nil unless enabled
+		// through faultsEnvVar.
+		faults *faultInjector
+
+		// writeBackoff controls the retries of the local API writes that
+		// are throttled or time out.
+		writeBackoff WriteBackoffConfig
+
+		// circuit pauses the processing of the events while the API server
+		// of the target cluster, checked through checkRemote every
+		// checkPeriod, is unreachable.
+		circuit     *circuitBreaker
+		checkRemote func() error
+		checkPeriod time.Duration
+
+		// writeSlices is set at Start when the local cluster serves
+		// EndpointSlices but doesn't mirror the Endpoints of the mirror
+		// services into them, in which case the service mirror does.
+		writeSlices bool
+
+		// dryRun logs the writes to the local mirror resources instead of
+		// making them. It's nil unless enabled.
+		dryRun *dryRun
+
+		// traces links the spans of the retries of an event to the span of
+		// its first attempt.
+		traces eventTraces
 	}
 
 	// RemoteServiceCreated is generated whenever a remote service is created Observing
@@ -105,6 +205,11 @@ type (
 	// endpoints should be resolved based on the remote gateway and updated.
 	RepairEndpoints struct{}
 
+	// MirrorDriftCheck is issued periodically to correct the mirror services
+	// and endpoints edited in the local cluster, which would otherwise go
+	// unnoticed until the remote service changes.
+	MirrorDriftCheck struct{}
+
 	// RetryableError is an error that should be retried through requeuing events
 	RetryableError struct{ Inner []error }
 )
@@ -117,6 +222,52 @@ func (re RetryableError) Error() string {
 	return fmt.Sprintf("Inner errors:\n\t%s", strings.Join(errorStrings, "\n\t"))
 }
 
+// WatcherConfig holds the settings of a cluster watcher that aren't covered by
+// the more specific RequeueConfig, WriteBackoffConfig and
+// CircuitBreakerConfig.
+type WatcherConfig struct {
+	// RepairPeriod is the base interval between two repairs of the mirrored
+	// endpoints
+	RepairPeriod time.Duration
+	// InitialSyncRate is the maximum number of mirrors created per second
+	// when the watcher starts
+	InitialSyncRate int
+	// Workers is the number of events processed concurrently
+	Workers int
+	// ServiceImports maintains an MCS API ServiceImport for each mirror
+	// service
+	ServiceImports bool
+	// RemoteServiceExports also mirrors the services of the target cluster
+	// that have an MCS API ServiceExport
+	RemoteServiceExports bool
+	// DryRun logs the writes to the local mirror resources instead of making
+	// them
+	DryRun bool
+}
+
+// DefaultWatcherConfig returns the configuration used when none is given.
+func DefaultWatcherConfig() WatcherConfig {
+	return WatcherConfig{
+		RepairPeriod:    time.Minute,
+		InitialSyncRate: 50,
+		Workers:         1,
+	}
+}
+
+// Validate returns an error if the configuration can't be used.
+func (c WatcherConfig) Validate() error {
+	if c.RepairPeriod <= 0 {
+		return fmt.Errorf("invalid endpoint refresh period %s: must be positive", c.RepairPeriod)
+	}
+	if c.InitialSyncRate <= 0 {
+		return fmt.Errorf("invalid initial sync rate %d: must be positive", c.InitialSyncRate)
+	}
+	if c.Workers <= 0 {
+		return fmt.Errorf("invalid number of workers %d: must be positive", c.Workers)
+	}
+	return nil
+}
+
 // NewRemoteClusterServiceWatcher constructs a new cluster watcher
 func NewRemoteClusterServiceWatcher(
 	ctx context.Context,
@@ -124,33 +275,111 @@ func NewRemoteClusterServiceWatcher(
 	localAPI *k8s.API,
 	cfg *rest.Config,
 	link *multicluster.Link,
-	requeueLimit int,
-	repairPeriod time.Duration,
-
+	config WatcherConfig,
+	requeue RequeueConfig,
+	writeBackoff WriteBackoffConfig,
+	circuitBreaker CircuitBreakerConfig,
+	linkClient dynamic.Interface,
+	recorder record.EventRecorder,
 ) (*RemoteClusterServiceWatcher, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if err := requeue.Validate(); err != nil {
+		return nil, err
+	}
+	if err := writeBackoff.Validate(); err != nil {
+		return nil, err
+	}
+	if err := circuitBreaker.Validate(); err != nil {
+		return nil, err
+	}
+
+	// The checks of the target cluster get their own client, so that they
+	// time out on their own timeout
+	checkCfg := rest.CopyConfig(cfg)
+	checkCfg.Timeout = circuitBreaker.Timeout
+	checkClient, err := discovery.NewDiscoveryClientForConfig(checkCfg)
+	if err != nil {
+		return nil, fmt.Errorf("cannot initialize api for target cluster %s: %s", link.TargetClusterName, err)
+	}
+	checkRemote := func() error {
+		_, err := checkClient.ServerVersion()
+		return err
+	}
+
 	remoteAPI, err := k8s.InitializeAPIForConfig(ctx, cfg, false, k8s.Svc)
 	if err != nil {
-		return nil, fmt.Errorf("cannot initialize api for target cluster %s: %s", clusterName, err)
+		return nil, fmt.Errorf("cannot initialize api for target cluster %s: %s", link.TargetClusterName, err)
+	}
+	err = checkRemote()
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to api for target cluster %s: %s", link.TargetClusterName, err)
 	}
-	_, err = remoteAPI.Client.Discovery().ServerVersion()
+
+	log := logging.WithFields(logging.Fields{
+		"cluster":    link.TargetClusterName,
+		"apiAddress": cfg.Host,
+	})
+	// The Links created by older versions of the CLI weren't validated, so
+	// their invalid entries are only reported, as long as another entry can
+	// reach the gateway
+	entries, err := multicluster.ParseGatewayAddress(link.GatewayAddress)
 	if err != nil {
-		return nil, fmt.Errorf("cannot connect to api for target cluster %s: %s", clusterName, err)
+		if len(entries) == 0 {
+			return nil, fmt.Errorf("no valid gateway address for target cluster %s: %s", link.TargetClusterName, err)
+		}
+		log.Warnf("Ignoring the invalid entries of the gateway address: %s", err)
+	}
+
+	faults, err := newFaultInjectorFromEnv(log)
+	if err != nil {
+		return nil, err
+	}
+
+	var dryRunner *dryRun
+	if config.DryRun {
+		dryRunner = newDryRun(link.TargetClusterName, log)
+	}
+
+	var remoteExports cache.SharedIndexInformer
+	if config.RemoteServiceExports {
+		remoteExports, err = newRemoteExportsInformer(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("cannot watch ServiceExports of target cluster %s: %s", link.TargetClusterName, err)
+		}
 	}
 
 	stopper := make(chan struct{})
 	return &RemoteClusterServiceWatcher{
+		lastProcessed:          time.Now().UnixNano(),
+		lastReconciled:         time.Now().UnixNano(),
 		serviceMirrorNamespace: serviceMirrorNamespace,
 		link:                   link,
 		remoteAPIClient:        remoteAPI,
 		localAPIClient:         localAPI,
 		stopper:                stopper,
-		log: logging.WithFields(logging.Fields{
-			"cluster":    clusterName,
-			"apiAddress": cfg.Host,
-		}),
-		eventsQueue:  workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
-		requeueLimit: requeueLimit,
-		repairPeriod: repairPeriod,
+		log:                    log,
+		eventsQueue:            workqueue.NewRateLimitingQueue(requeue.rateLimiter()),
+		requeueLimit:           requeue.Limit,
+		repairPeriod:           config.RepairPeriod,
+		repairs:                newRepairSchedule(config.RepairPeriod),
+		linkClient:             linkClient,
+		recorder:               recorder,
+		conflicts:              make(map[string]string),
+		workers:                config.Workers,
+
+		initialSyncRate: config.InitialSyncRate,
+		serviceImports:  config.ServiceImports,
+		remoteExports:   remoteExports,
+		gatewayHealth:   newGatewayHealth(log, link.GatewayIdentity),
+		gatewayResolver: newGatewayResolver(log),
+		faults:          faults,
+		writeBackoff:    writeBackoff,
+		circuit:         newCircuitBreaker(circuitBreaker.FailureThreshold),
+		checkRemote:     checkRemote,
+		checkPeriod:     circuitBreaker.CheckPeriod,
+		dryRun:          dryRunner,
 	}, nil
 }
 
@@ -169,11 +398,144 @@ func (rcsw *RemoteClusterServiceWatcher) getMirroredServiceLabels() map[string]s
 	}
 }
 
-func (rcsw *RemoteClusterServiceWatcher) getMirroredServiceAnnotations(remoteService *corev1.Service) map[string]string {
-	annotations := map[string]string{
-		consts.RemoteResourceVersionAnnotation: remoteService.ResourceVersion, // needed to detect real changes
-		consts.RemoteServiceFqName:             fmt.Sprintf("%s.%s.svc.%s", remoteService.Name, remoteService.Namespace, rcsw.link.TargetClusterDomain),
+// getMirrorLabels returns the labels of the mirror Service and Endpoints of
+// the given remote service: the remote labels propagated by the Link, along
+// with the labels identifying the mirror.
+func (rcsw *RemoteClusterServiceWatcher) getMirrorLabels(remoteService *corev1.Service) map[string]string {
+	labels := make(map[string]string)
+	for k, v := range remoteService.GetLabels() {
+		if rcsw.link.PropagatesLabel(k) {
+			labels[k] = v
+		}
+	}
+	for k, v := range rcsw.getMirroredServiceLabels() {
+		labels[k] = v
+	}
+	return labels
+}
+
+// getPropagatedAnnotations returns the annotations of the given remote
+// service that are propagated by the Link.
+func (rcsw *RemoteClusterServiceWatcher) getPropagatedAnnotations(remoteService *corev1.Service) map[string]string {
+	annotations := make(map[string]string)
+	for k, v := range remoteService.GetAnnotations() {
+		if rcsw.link.PropagatesAnnotation(k) {
+			annotations[k] = v
+		}
+	}
+	return annotations
+}
+
+// isOwnedMirror returns true if the given local resource is a mirror created
+// on behalf of this watcher's Link.
+func (rcsw *RemoteClusterServiceWatcher) isOwnedMirror(meta metav1.Object) bool {
+	l := meta.GetLabels()
+	return l[consts.MirroredResourceLabel] == "true" && l[consts.RemoteClusterNameLabel] == rcsw.link.TargetClusterName
+}
+
+// mirrorOwner describes who owns a local resource that collides with one of
+// our mirror names.
+func mirrorOwner(meta metav1.Object) string {
+	if cluster, ok := meta.GetLabels()[consts.RemoteClusterNameLabel]; ok && meta.GetLabels()[consts.MirroredResourceLabel] == "true" {
+		return fmt.Sprintf("mirror of target cluster %s", cluster)
+	}
+	return "a service not managed by a service mirror"
+}
+
+// reportConflict records that the mirror for a remote service could not be
+// claimed because the local name is taken. Resources belonging to other Links
+// are never modified: the first owner of a name keeps it and the conflicting
+// export is isolated until the collision goes away.
+func (rcsw *RemoteClusterServiceWatcher) reportConflict(ctx context.Context, remote *corev1.Service, local *corev1.Service) {
+	key := fmt.Sprintf("%s/%s", local.Namespace, local.Name)
+	owner := mirrorOwner(local)
+	rcsw.log.Warnf("Cannot mirror %s/%s: local service %s already exists and is owned by %s", remote.Namespace, remote.Name, key, owner)
+
+	rcsw.conflictsMu.Lock()
+	if _, ok := rcsw.conflicts[key]; ok {
+		rcsw.conflictsMu.Unlock()
+		return
+	}
+	rcsw.conflicts[key] = owner
+	version, conflicts := rcsw.snapshotConflicts()
+	rcsw.conflictsMu.Unlock()
+
+	if rcsw.recorder != nil {
+		rcsw.recorder.Eventf(local, corev1.EventTypeWarning, "MirrorConflict",
+			"Service %s/%s exported by target cluster %s collides with this service; it will not be mirrored",
+			remote.Namespace, remote.Name, rcsw.link.TargetClusterName)
+	}
+	rcsw.updateConflictCondition(ctx, version, conflicts)
+}
+
+// resolveConflict clears a previously reported conflict for the given mirror
+// name, if any.
+func (rcsw *RemoteClusterServiceWatcher) resolveConflict(ctx context.Context, namespace, name string) {
+	key := fmt.Sprintf("%s/%s", namespace, name)
+	rcsw.conflictsMu.Lock()
+	if _, ok := rcsw.conflicts[key]; !ok {
+		rcsw.conflictsMu.Unlock()
+		return
+	}
+	delete(rcsw.conflicts, key)
+	version, conflicts := rcsw.snapshotConflicts()
+	rcsw.conflictsMu.Unlock()
+
+	rcsw.log.Infof("Mirror conflict for %s resolved", key)
+	rcsw.updateConflictCondition(ctx, version, conflicts)
+}
+
+// snapshotConflicts bumps the version of the conflicts and returns it along
+// with a copy of the conflicts. It must be called with conflictsMu held.
+func (rcsw *RemoteClusterServiceWatcher) snapshotConflicts() (uint64, map[string]string) {
+	rcsw.conflictsVersion++
+	conflicts := make(map[string]string, len(rcsw.conflicts))
+	for name, owner := range rcsw.conflicts {
+		conflicts[name] = owner
+	}
+	return rcsw.conflictsVersion, conflicts
+}
+
+// updateConflictCondition sets the MirrorConflict condition of the Link from
+// the given version of the conflicts, unless a later version was already
+// written.
+func (rcsw *RemoteClusterServiceWatcher) updateConflictCondition(ctx context.Context, version uint64, conflicts map[string]string) {
+	if rcsw.linkClient == nil {
+		return
+	}
+	rcsw.conditionMu.Lock()
+	defer rcsw.conditionMu.Unlock()
+	if version <= rcsw.conflictsWritten {
+		return
 	}
+	rcsw.conflictsWritten = version
+
+	condition := metav1.Condition{
+		Type:    multicluster.LinkConditionMirrorConflict,
+		Status:  metav1.ConditionFalse,
+		Reason:  "NoConflicts",
+		Message: "All exported services are mirrored",
+	}
+	if len(conflicts) > 0 {
+		var names []string
+		for name, owner := range conflicts {
+			names = append(names, fmt.Sprintf("%s (%s)", name, owner))
+		}
+		sort.Strings(names)
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "MirrorNameTaken"
+		condition.Message = fmt.Sprintf("Mirror names already in use: %s", strings.Join(names, ", "))
+	}
+
+	if err := multicluster.SetLinkCondition(ctx, rcsw.linkClient, rcsw.link.Namespace, rcsw.link.Name, condition); err != nil {
+		rcsw.log.Errorf("Failed to update %s condition on Link %s: %s", condition.Type, rcsw.link.Name, err)
+	}
+}
+
+func (rcsw *RemoteClusterServiceWatcher) getMirroredServiceAnnotations(remoteService *corev1.Service) map[string]string {
+	annotations := rcsw.getPropagatedAnnotations(remoteService)
+	annotations[consts.RemoteResourceVersionAnnotation] = remoteService.ResourceVersion // needed to detect real changes
+	annotations[consts.RemoteServiceFqName] = fmt.Sprintf("%s.%s.svc.%s", remoteService.Name, remoteService.Namespace, rcsw.link.TargetClusterDomain)
 	value, ok := remoteService.GetAnnotations()[consts.ProxyOpaquePortsAnnotation]
 	if ok {
 		annotations[consts.ProxyOpaquePortsAnnotation] = value
@@ -185,7 +547,13 @@ func (rcsw *RemoteClusterServiceWatcher) mirrorNamespaceIfNecessary(ctx context.
 	// if the namespace is already present we do not need to change it.
 	// if we are creating it we want to put a label indicating this is a
 	// mirrored resource
-	if _, err := rcsw.localAPIClient.NS().Lister().Get(namespace); err != nil {
+	ns, err := rcsw.localAPIClient.NS().Lister().Get(namespace)
+	if err == nil && ns.DeletionTimestamp != nil {
+		// the namespace is being deleted, e.g. by the cleanup of another
+		// Link that used it, and is recreated once it's gone
+		return RetryableError{[]error{fmt.Errorf("namespace %s is terminating", namespace)}}
+	}
+	if err != nil {
 		if kerrors.IsNotFound(err) {
 			// if the namespace is not found, we can just create it
 			ns := &corev1.Namespace{
@@ -197,8 +565,10 @@ func (rcsw *RemoteClusterServiceWatcher) mirrorNamespaceIfNecessary(ctx context.
 					Name: namespace,
 				},
 			}
-			_, err := rcsw.localAPIClient.Client.CoreV1().Namespaces().Create(ctx, ns, metav1.CreateOptions{})
-			if err != nil {
+			_, err := rcsw.localNamespaces().Create(ctx, ns, metav1.CreateOptions{})
+			// the namespace may have just been created while handling
+			// another service
+			if err != nil && !kerrors.IsAlreadyExists(err) {
 				// something went wrong with the create, we can just retry as well
 				return RetryableError{[]error{err}}
 			}
@@ -220,12 +590,22 @@ func (rcsw *RemoteClusterServiceWatcher) getEndpointsPorts(service *corev1.Servi
 		endpointsPorts = append(endpointsPorts, corev1.EndpointPort{
 			Name:     remotePort.Name,
 			Protocol: remotePort.Protocol,
-			Port:     int32(rcsw.link.GatewayPort),
+			Port:     int32(rcsw.gatewayPort()),
 		})
 	}
 	return endpointsPorts
 }
 
+// gatewayPort returns the port of the gateway discovered through the SRV
+// records or port suffixes of the gateway address, if any, or else the Link's
+// gateway port.
+func (rcsw *RemoteClusterServiceWatcher) gatewayPort() uint32 {
+	if port := rcsw.gatewayResolver.discoveredPort(); port != 0 {
+		return port
+	}
+	return rcsw.link.GatewayPort
+}
+
 func (rcsw *RemoteClusterServiceWatcher) cleanupOrphanedServices(ctx context.Context) error {
 	matchLabels := map[string]string{
 		consts.MirroredResourceLabel:  "true",
@@ -244,11 +624,16 @@ func (rcsw *RemoteClusterServiceWatcher) cleanupOrphanedServices(ctx context.Con
 
 	var errors []error
 	for _, srv := range servicesOnLocalCluster {
-		_, err := rcsw.remoteAPIClient.Svc().Lister().Services(srv.Namespace).Get(rcsw.originalResourceName(srv.Name))
-		if err != nil {
-			if kerrors.IsNotFound(err) {
+		// the mirrors in a namespace no remote namespace is mirrored into
+		// anymore are orphaned as well
+		remoteNamespace, ok := rcsw.link.RemoteNamespace(srv.Namespace)
+		if ok {
+			_, err = rcsw.remoteAPIClient.Svc().Lister().Services(remoteNamespace).Get(rcsw.originalResourceName(srv.Name))
+		}
+		if !ok || err != nil {
+			if !ok || kerrors.IsNotFound(err) {
 				// service does not exist anymore. Need to delete
-				if err := rcsw.localAPIClient.Client.CoreV1().Services(srv.Namespace).Delete(ctx, srv.Name, metav1.DeleteOptions{}); err != nil {
+				if err := rcsw.localServices(srv.Namespace).Delete(ctx, srv.Name, metav1.DeleteOptions{}); err != nil {
 					// something went wrong with deletion, we need to retry
 					errors = append(errors, err)
 				} else {
@@ -269,7 +654,8 @@ func (rcsw *RemoteClusterServiceWatcher) cleanupOrphanedServices(ctx context.Con
 
 // Whenever we stop watching a cluster, we need to cleanup everything that we have
 // created. This piece of code is responsible for doing just that. It takes care of
-// services, endpoints and namespaces (if needed)
+// services, endpoints, the gateway mirror and namespaces (if nothing else is
+// left in them)
 func (rcsw *RemoteClusterServiceWatcher) cleanupMirroredResources(ctx context.Context) error {
 	matchLabels := rcsw.getMirroredServiceLabels()
 
@@ -285,13 +671,14 @@ func (rcsw *RemoteClusterServiceWatcher) cleanupMirroredResources(ctx context.Co
 
 	var errors []error
 	for _, svc := range services {
-		if err := rcsw.localAPIClient.Client.CoreV1().Services(svc.Namespace).Delete(ctx, svc.Name, metav1.DeleteOptions{}); err != nil {
+		if err := rcsw.localServices(svc.Namespace).Delete(ctx, svc.Name, metav1.DeleteOptions{}); err != nil {
 			if kerrors.IsNotFound(err) {
 				continue
 			}
 			errors = append(errors, fmt.Errorf("Could not delete  service %s/%s: %s", svc.Namespace, svc.Name, err))
 		} else {
 			rcsw.log.Infof("Deleted service %s/%s", svc.Namespace, svc.Name)
+			rcsw.deleteServiceImport(ctx, svc.Namespace, svc.Name)
 		}
 	}
 
@@ -305,7 +692,7 @@ func (rcsw *RemoteClusterServiceWatcher) cleanupMirroredResources(ctx context.Co
 	}
 
 	for _, endpoint := range endpoints {
-		if err := rcsw.localAPIClient.Client.CoreV1().Endpoints(endpoint.Namespace).Delete(ctx, endpoint.Name, metav1.DeleteOptions{}); err != nil {
+		if err := rcsw.localEndpoints(endpoint.Namespace).Delete(ctx, endpoint.Name, metav1.DeleteOptions{}); err != nil {
 			if kerrors.IsNotFound(err) {
 				continue
 			}
@@ -315,67 +702,141 @@ func (rcsw *RemoteClusterServiceWatcher) cleanupMirroredResources(ctx context.Co
 		}
 	}
 
+	if err := rcsw.deleteGatewayMirror(ctx); err != nil {
+		errors = append(errors, fmt.Errorf("Could not delete the gateway mirror: %s", err))
+	}
+
 	if len(errors) > 0 {
 		return RetryableError{errors}
 	}
+
+	// the namespaces are only deleted once the mirrors they hold are gone,
+	// which they never are in dry run mode
+	if rcsw.dryRun != nil {
+		return nil
+	}
+	namespaces, err := multicluster.DeleteUnusedMirrorNamespaces(ctx, rcsw.localAPIClient.Client, rcsw.link.TargetClusterName)
+	for _, ns := range namespaces {
+		rcsw.log.Infof("Deleted namespace %s", ns)
+	}
+	if err != nil {
+		return RetryableError{[]error{err}}
+	}
 	return nil
 }
 
 // Deletes a locally mirrored service as it is not present on the remote cluster anymore
 func (rcsw *RemoteClusterServiceWatcher) handleRemoteServiceDeleted(ctx context.Context, ev *RemoteServiceDeleted) error {
+	ctx, span := rcsw.startServiceSpan(ctx, "delete-mirror", &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: ev.Name, Namespace: ev.Namespace}})
+	defer span.End()
 	localServiceName := rcsw.mirroredResourceName(ev.Name)
-	rcsw.log.Infof("Deleting mirrored service %s/%s", ev.Namespace, localServiceName)
+	localNamespace := rcsw.link.LocalNamespace(ev.Namespace)
+	rcsw.resolveConflict(ctx, localNamespace, localServiceName)
+	if localSvc, err := rcsw.localAPIClient.Svc().Lister().Services(localNamespace).Get(localServiceName); err == nil {
+		if !rcsw.isOwnedMirror(localSvc) {
+			rcsw.log.Infof("Not deleting service %s/%s as it is owned by %s", localNamespace, localServiceName, mirrorOwner(localSvc))
+			return nil
+		}
+		rcsw.reserveClusterIP(ctx, localSvc)
+	}
+	rcsw.log.Infof("Deleting mirrored service %s/%s", localNamespace, localServiceName)
 	var errors []error
-	if err := rcsw.localAPIClient.Client.CoreV1().Services(ev.Namespace).Delete(ctx, localServiceName, metav1.DeleteOptions{}); err != nil {
+	if err := rcsw.localServices(localNamespace).Delete(ctx, localServiceName, metav1.DeleteOptions{}); err != nil {
 		if !kerrors.IsNotFound(err) {
-			errors = append(errors, fmt.Errorf("could not delete Service: %s/%s: %s", ev.Namespace, localServiceName, err))
+			errors = append(errors, fmt.Errorf("could not delete Service: %s/%s: %s", localNamespace, localServiceName, err))
 		}
 	}
 
 	if len(errors) > 0 {
 		return RetryableError{errors}
 	}
+	rcsw.deleteServiceImport(ctx, localNamespace, localServiceName)
 
-	rcsw.log.Infof("Successfully deleted Service: %s/%s", ev.Namespace, localServiceName)
+	rcsw.log.Infof("Successfully deleted Service: %s/%s", localNamespace, localServiceName)
 	return nil
 }
 
 // Updates a locally mirrored service. There might have been some pretty fundamental changes such as
 // new gateway being assigned or additional ports exposed. This method takes care of that.
+//
+// Both the Service and the Endpoints are derived from the same remote
+// snapshot so that a port rename on the remote side cannot leave the mirror
+// with Service and Endpoints port names that disagree. Once both writes have
+// gone through, the pair is read back and verified; any drift (e.g. caused by
+// a concurrent repair) results in the event being retried. Both are
+// server-side applied, so that the fields set by other controllers are kept.
 func (rcsw *RemoteClusterServiceWatcher) handleRemoteServiceUpdated(ctx context.Context, ev *RemoteServiceUpdated) error {
+	ctx, span := rcsw.startServiceSpan(ctx, "update-mirror", ev.remoteUpdate)
+	defer span.End()
 	rcsw.log.Infof("Updating mirror service %s/%s", ev.localService.Namespace, ev.localService.Name)
-	gatewayAddresses, err := rcsw.resolveGatewayAddress()
+	gatewayAddresses, gatewayWeights, err := rcsw.resolveGatewayAddress()
 	if err != nil {
 		return err
 	}
 
-	copiedEndpoints := ev.localEndpoints.DeepCopy()
-	copiedEndpoints.Subsets = []corev1.EndpointSubset{
-		{
-			Addresses: gatewayAddresses,
-			Ports:     rcsw.getEndpointsPorts(ev.remoteUpdate),
-		},
-	}
+	remoteSnapshot := ev.remoteUpdate.DeepCopy()
+
+	copiedService := ev.localService.DeepCopy()
+	copiedService.Labels = rcsw.getMirrorLabels(remoteSnapshot)
+	copiedService.Annotations = rcsw.getMirroredServiceAnnotations(remoteSnapshot)
+	copiedService.Spec.Ports = remapRemoteServicePorts(remoteSnapshot.Spec.Ports)
+
+	mirrorEndpoints := rcsw.mirrorEndpoints(ev.localEndpoints.Namespace, ev.localEndpoints.Name, remoteSnapshot, gatewayAddresses, gatewayWeights)
 
-	if copiedEndpoints.Annotations == nil {
-		copiedEndpoints.Annotations = make(map[string]string)
+	if err := rcsw.applyService(ctx, copiedService); err != nil {
+		return RetryableError{[]error{err}}
 	}
-	copiedEndpoints.Annotations[consts.RemoteGatewayIdentity] = rcsw.link.GatewayIdentity
 
-	if _, err := rcsw.localAPIClient.Client.CoreV1().Endpoints(copiedEndpoints.Namespace).Update(ctx, copiedEndpoints, metav1.UpdateOptions{}); err != nil {
+	if err := rcsw.applyEndpoints(ctx, mirrorEndpoints); err != nil {
 		return RetryableError{[]error{err}}
 	}
 
-	ev.localService.Labels = rcsw.getMirroredServiceLabels()
-	ev.localService.Annotations = rcsw.getMirroredServiceAnnotations(ev.remoteUpdate)
-	ev.localService.Spec.Ports = remapRemoteServicePorts(ev.remoteUpdate.Spec.Ports)
+	if err := rcsw.verifyMirrorConsistency(copiedService.Namespace, copiedService.Name); err != nil {
+		return err
+	}
+	rcsw.syncServiceImport(ctx, copiedService.Namespace, copiedService.Name)
+	return nil
+}
 
-	if _, err := rcsw.localAPIClient.Client.CoreV1().Services(ev.localService.Namespace).Update(ctx, ev.localService, metav1.UpdateOptions{}); err != nil {
+// verifyMirrorConsistency reads back a mirrored Service and its Endpoints
+// from the local listers and ensures that every endpoint port maps onto a
+// service port of the same name. A mismatch is reported as a RetryableError
+// so that the update is reapplied from a fresh snapshot.
+func (rcsw *RemoteClusterServiceWatcher) verifyMirrorConsistency(namespace, name string) error {
+	svc, err := rcsw.localAPIClient.Svc().Lister().Services(namespace).Get(name)
+	if err != nil {
+		return RetryableError{[]error{err}}
+	}
+	ep, err := rcsw.localAPIClient.Endpoint().Lister().Endpoints(namespace).Get(name)
+	if err != nil {
 		return RetryableError{[]error{err}}
 	}
+	if !endpointsPortsMatchService(svc, ep) {
+		return RetryableError{[]error{fmt.Errorf("mirror %s/%s has diverging port names: service %v, endpoints %v", namespace, name, svc.Spec.Ports, ep.Subsets)}}
+	}
 	return nil
 }
 
+// endpointsPortsMatchService returns true if the set of port names exposed by
+// the Endpoints subsets is exactly the set of port names of the Service.
+func endpointsPortsMatchService(svc *corev1.Service, ep *corev1.Endpoints) bool {
+	svcPorts := make(map[string]struct{})
+	for _, p := range svc.Spec.Ports {
+		svcPorts[p.Name] = struct{}{}
+	}
+	for _, subset := range ep.Subsets {
+		if len(subset.Ports) != len(svcPorts) {
+			return false
+		}
+		for _, p := range subset.Ports {
+			if _, ok := svcPorts[p.Name]; !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 func remapRemoteServicePorts(ports []corev1.ServicePort) []corev1.ServicePort {
 	// We ignore the NodePort here as its not relevant
 	// to the local cluster
@@ -391,8 +852,40 @@ func remapRemoteServicePorts(ports []corev1.ServicePort) []corev1.ServicePort {
 	return newPorts
 }
 
+// gatewayIPFamilies returns the IP family policy and families of a mirror
+// service, matching the families of the gateway addresses its endpoints
+// point at. Mirrors of a dual-stack gateway prefer being dual-stack, so that
+// they're still created in single-stack clusters, and mirrors of an IPv6
+// gateway are IPv6. The cluster defaults are kept for IPv4 gateways.
+func gatewayIPFamilies(addresses []corev1.EndpointAddress) (*corev1.IPFamilyPolicyType, []corev1.IPFamily) {
+	var families []corev1.IPFamily
+	seen := make(map[corev1.IPFamily]struct{})
+	for _, addr := range addresses {
+		family := corev1.IPv4Protocol
+		if ip := net.ParseIP(addr.IP); ip != nil && ip.To4() == nil {
+			family = corev1.IPv6Protocol
+		}
+		if _, ok := seen[family]; !ok {
+			seen[family] = struct{}{}
+			families = append(families, family)
+		}
+	}
+
+	switch {
+	case len(families) > 1:
+		policy := corev1.IPFamilyPolicyPreferDualStack
+		return &policy, families
+	case len(families) == 1 && families[0] == corev1.IPv6Protocol:
+		return nil, families
+	default:
+		return nil, nil
+	}
+}
+
 func (rcsw *RemoteClusterServiceWatcher) handleRemoteServiceCreated(ctx context.Context, ev *RemoteServiceCreated) error {
-	gatewayAddresses, err := rcsw.resolveGatewayAddress()
+	ctx, span := rcsw.startServiceSpan(ctx, "create-mirror", ev.service)
+	defer span.End()
+	gatewayAddresses, gatewayWeights, err := rcsw.resolveGatewayAddress()
 	if err != nil {
 		return err
 	}
@@ -400,39 +893,39 @@ func (rcsw *RemoteClusterServiceWatcher) handleRemoteServiceCreated(ctx context.
 	remoteService := ev.service.DeepCopy()
 	serviceInfo := fmt.Sprintf("%s/%s", remoteService.Namespace, remoteService.Name)
 	localServiceName := rcsw.mirroredResourceName(remoteService.Name)
+	localNamespace := rcsw.link.LocalNamespace(remoteService.Namespace)
 
-	if err := rcsw.mirrorNamespaceIfNecessary(ctx, remoteService.Namespace); err != nil {
+	if err := rcsw.mirrorNamespaceIfNecessary(ctx, localNamespace); err != nil {
 		return err
 	}
 
+	reservedIP := rcsw.reservedClusterIP(ctx, localNamespace, localServiceName)
 	serviceToCreate := &corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        localServiceName,
-			Namespace:   remoteService.Namespace,
+			Namespace:   localNamespace,
 			Annotations: rcsw.getMirroredServiceAnnotations(remoteService),
-			Labels:      rcsw.getMirroredServiceLabels(),
+			Labels:      rcsw.getMirrorLabels(remoteService),
 		},
 		Spec: corev1.ServiceSpec{
-			Ports: remapRemoteServicePorts(remoteService.Spec.Ports),
+			Ports:     remapRemoteServicePorts(remoteService.Spec.Ports),
+			ClusterIP: reservedIP,
 		},
 	}
+	serviceToCreate.Spec.IPFamilyPolicy, serviceToCreate.Spec.IPFamilies = gatewayIPFamilies(gatewayAddresses)
 
 	endpointsToCreate := &corev1.Endpoints{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      localServiceName,
-			Namespace: ev.service.Namespace,
-			Labels: map[string]string{
-				consts.MirroredResourceLabel:  "true",
-				consts.RemoteClusterNameLabel: rcsw.link.TargetClusterName,
-			},
-			Annotations: map[string]string{
-				consts.RemoteServiceFqName: fmt.Sprintf("%s.%s.svc.%s", remoteService.Name, remoteService.Namespace, rcsw.link.TargetClusterDomain),
-			},
+			Name:        localServiceName,
+			Namespace:   localNamespace,
+			Labels:      rcsw.getMirrorLabels(remoteService),
+			Annotations: rcsw.getPropagatedAnnotations(remoteService),
 		},
 	}
+	endpointsToCreate.Annotations[consts.RemoteServiceFqName] = fmt.Sprintf("%s.%s.svc.%s", remoteService.Name, remoteService.Namespace, rcsw.link.TargetClusterDomain)
 
 	// only if we resolve it, we are updating the endpoints addresses and ports
-	rcsw.log.Infof("Resolved gateway [%v:%d] for %s", gatewayAddresses, rcsw.link.GatewayPort, serviceInfo)
+	rcsw.log.Infof("Resolved gateway [%v:%d] for %s", gatewayAddresses, rcsw.gatewayPort(), serviceInfo)
 
 	if len(gatewayAddresses) > 0 {
 		endpointsToCreate.Subsets = []corev1.EndpointSubset{
@@ -447,22 +940,49 @@ func (rcsw *RemoteClusterServiceWatcher) handleRemoteServiceCreated(ctx context.
 	if rcsw.link.GatewayIdentity != "" {
 		endpointsToCreate.Annotations[consts.RemoteGatewayIdentity] = rcsw.link.GatewayIdentity
 	}
+	setGatewayWeights(endpointsToCreate.Annotations, gatewayWeights)
 
 	rcsw.log.Infof("Creating a new service mirror for %s", serviceInfo)
-	if _, err := rcsw.localAPIClient.Client.CoreV1().Services(remoteService.Namespace).Create(ctx, serviceToCreate, metav1.CreateOptions{}); err != nil {
+	created, err := rcsw.localServices(localNamespace).Create(ctx, serviceToCreate, metav1.CreateOptions{})
+	if err != nil && kerrors.IsInvalid(err) && reservedIP != "" {
+		// the reserved ClusterIP has been allocated to another service in
+		// the meantime, or is outside of the service CIDR
+		rcsw.log.Warnf("Could not reuse ClusterIP %s for %s: %s", reservedIP, serviceInfo, err)
+		serviceToCreate.Spec.ClusterIP = ""
+		created, err = rcsw.localServices(localNamespace).Create(ctx, serviceToCreate, metav1.CreateOptions{})
+	}
+	if err != nil {
 		if !kerrors.IsAlreadyExists(err) {
 			// we might have created it during earlier attempt, if that is not the case, we retry
 			return RetryableError{[]error{err}}
 		}
+		existing, err := rcsw.localServices(localNamespace).Get(ctx, localServiceName, metav1.GetOptions{})
+		if err != nil {
+			return RetryableError{[]error{err}}
+		}
+		if !rcsw.isOwnedMirror(existing) {
+			// the name is taken by somebody else; leave their resources alone
+			rcsw.reportConflict(ctx, remoteService, existing)
+			return nil
+		}
+		created = existing
+	}
+	rcsw.resolveConflict(ctx, localNamespace, localServiceName)
+	if len(gatewayAddresses) == 0 && created != nil {
+		rcsw.recordGatewayUnavailable(created)
 	}
 
 	rcsw.log.Infof("Creating a new Endpoints for %s", serviceInfo)
-	if _, err := rcsw.localAPIClient.Client.CoreV1().Endpoints(ev.service.Namespace).Create(ctx, endpointsToCreate, metav1.CreateOptions{}); err != nil {
+	if _, err := rcsw.localEndpoints(localNamespace).Create(ctx, endpointsToCreate, metav1.CreateOptions{}); err != nil {
 		// we clean up after ourselves
-		rcsw.localAPIClient.Client.CoreV1().Services(ev.service.Namespace).Delete(ctx, localServiceName, metav1.DeleteOptions{})
+		rcsw.localServices(localNamespace).Delete(ctx, localServiceName, metav1.DeleteOptions{})
 		// and retry
 		return RetryableError{[]error{err}}
 	}
+	if reservedIP != "" {
+		rcsw.releaseClusterIP(ctx, localNamespace, localServiceName)
+	}
+	rcsw.syncServiceImport(ctx, localNamespace, localServiceName)
 	return nil
 }
 
@@ -472,17 +992,33 @@ func (rcsw *RemoteClusterServiceWatcher) isExportedService(service *corev1.Servi
 		rcsw.log.Errorf("Invalid service selector: %s", err)
 		return false
 	}
-	return selector.Matches(labels.Set(service.Labels))
+	return selector.Matches(labels.Set(service.Labels)) || rcsw.hasExportAnnotation(service) || rcsw.hasServiceExport(service)
+}
+
+// hasExportAnnotation returns whether the given service is exported to this
+// watcher's Link through its export annotation.
+func (rcsw *RemoteClusterServiceWatcher) hasExportAnnotation(service *corev1.Service) bool {
+	value := strings.TrimSpace(service.Annotations[consts.ExportAnnotation])
+	if value == "true" {
+		return true
+	}
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" && name == rcsw.link.Name {
+			return true
+		}
+	}
+	return false
 }
 
 // this method is common to both CREATE and UPDATE because if we have been
 // offline for some time due to a crash a CREATE for a service that we have
 // observed before is simply a case of UPDATE
-func (rcsw *RemoteClusterServiceWatcher) createOrUpdateService(service *corev1.Service) error {
+func (rcsw *RemoteClusterServiceWatcher) createOrUpdateService(ctx context.Context, service *corev1.Service) error {
 	localName := rcsw.mirroredResourceName(service.Name)
+	localNamespace := rcsw.link.LocalNamespace(service.Namespace)
 
 	if rcsw.isExportedService(service) {
-		localService, err := rcsw.localAPIClient.Svc().Lister().Services(service.Namespace).Get(localName)
+		localService, err := rcsw.localAPIClient.Svc().Lister().Services(localNamespace).Get(localName)
 		if err != nil {
 			if kerrors.IsNotFound(err) {
 				rcsw.eventsQueue.Add(&RemoteServiceCreated{
@@ -492,10 +1028,14 @@ func (rcsw *RemoteClusterServiceWatcher) createOrUpdateService(service *corev1.S
 			}
 			return RetryableError{[]error{err}}
 		}
+		if !rcsw.isOwnedMirror(localService) {
+			rcsw.reportConflict(ctx, service, localService)
+			return nil
+		}
 		// if we have the local service present, we need to issue an update
 		lastMirroredRemoteVersion, ok := localService.Annotations[consts.RemoteResourceVersionAnnotation]
 		if ok && lastMirroredRemoteVersion != service.ResourceVersion {
-			endpoints, err := rcsw.localAPIClient.Endpoint().Lister().Endpoints(service.Namespace).Get(localName)
+			endpoints, err := rcsw.localAPIClient.Endpoint().Lister().Endpoints(localNamespace).Get(localName)
 			if err == nil {
 				rcsw.eventsQueue.Add(&RemoteServiceUpdated{
 					localService:   localService,
@@ -508,7 +1048,7 @@ func (rcsw *RemoteClusterServiceWatcher) createOrUpdateService(service *corev1.S
 		}
 		return nil
 	}
-	localSvc, err := rcsw.localAPIClient.Svc().Lister().Services(service.Namespace).Get(localName)
+	localSvc, err := rcsw.localAPIClient.Svc().Lister().Services(localNamespace).Get(localName)
 	if err == nil {
 		if localSvc.Labels != nil {
 			_, isMirroredRes := localSvc.Labels[consts.MirroredResourceLabel]
@@ -550,6 +1090,8 @@ func (rcsw *RemoteClusterServiceWatcher) handleOnDelete(service *corev1.Service)
 
 func (rcsw *RemoteClusterServiceWatcher) processNextEvent(ctx context.Context) (bool, interface{}, error) {
 	event, done := rcsw.eventsQueue.Get()
+	// The event is held until the target cluster is reachable again
+	rcsw.circuit.wait(rcsw.stopper)
 	if event != nil {
 		rcsw.log.Infof("Received: %s", event)
 	} else {
@@ -558,12 +1100,24 @@ func (rcsw *RemoteClusterServiceWatcher) processNextEvent(ctx context.Context) (
 		}
 	}
 
-	var err error
+	err := rcsw.handleEvent(ctx, event, done)
+	return done, event, err
+}
+
+// handleEvent processes a single event from the queue, in a span of its own.
+// A panic raised while processing it is recovered and returned as a
+// non-retryable error, so that a malformed object doesn't crash the service
+// mirror.
+func (rcsw *RemoteClusterServiceWatcher) handleEvent(ctx context.Context, event interface{}, done bool) (err error) {
+	ctx, span := rcsw.startEventSpan(ctx, event)
+	defer func() { endEventSpan(span, err) }()
+	defer crash.RecoverError(&err, "service-mirror/events", event)
+
 	switch ev := event.(type) {
 	case *OnAddCalled:
-		err = rcsw.createOrUpdateService(ev.svc)
+		err = rcsw.createOrUpdateService(ctx, ev.svc)
 	case *OnUpdateCalled:
-		err = rcsw.createOrUpdateService(ev.svc)
+		err = rcsw.createOrUpdateService(ctx, ev.svc)
 	case *OnDeleteCalled:
 		rcsw.handleOnDelete(ev.svc)
 	case *RemoteServiceCreated:
@@ -578,46 +1132,26 @@ func (rcsw *RemoteClusterServiceWatcher) processNextEvent(ctx context.Context) (
 		err = rcsw.cleanupOrphanedServices(ctx)
 	case *RepairEndpoints:
 		err = rcsw.repairEndpoints(ctx)
+	case *MirrorDriftCheck:
+		err = rcsw.checkMirrorDrift(ctx)
 	default:
 		if ev != nil || !done { // we get a nil in case we are shutting down...
 			rcsw.log.Warnf("Received unknown event: %v", ev)
 		}
 	}
-
-	return done, event, err
-
+	return err
 }
 
 // the main processing loop in which we handle more domain specific events
 // and deal with retries
 func (rcsw *RemoteClusterServiceWatcher) processEvents(ctx context.Context) {
+	if rcsw.workers > 1 {
+		rcsw.processEventsInParallel(ctx)
+		return
+	}
 	for {
 		done, event, err := rcsw.processNextEvent(ctx)
-		rcsw.eventsQueue.Done(event)
-		// the logic here is that there might have been an API
-		// connectivity glitch or something. So its not a bad idea to requeue
-		// the event and try again up to a number of limits, just to ensure
-		// that we are not diverging in states due to bad luck...
-		if err == nil {
-			rcsw.eventsQueue.Forget(event)
-		} else {
-			switch e := err.(type) {
-			case RetryableError:
-				{
-					rcsw.log.Warnf("Requeues: %d, Limit: %d for event %s", rcsw.eventsQueue.NumRequeues(event), rcsw.requeueLimit, event)
-					if (rcsw.eventsQueue.NumRequeues(event) < rcsw.requeueLimit) && !done {
-						rcsw.log.Errorf("Error processing %s (will retry): %s", event, e)
-						rcsw.eventsQueue.AddRateLimited(event)
-					} else {
-						rcsw.log.Errorf("Error processing %s (giving up): %s", event, e)
-						rcsw.eventsQueue.Forget(event)
-					}
-				}
-			default:
-				rcsw.log.Errorf("Error processing %s (will not retry): %s", event, e)
-				rcsw.log.Error(e)
-			}
-		}
+		rcsw.finishEvent(event, done, err)
 		if done {
 			rcsw.log.Infof("Shutting down events processor")
 			return
@@ -625,14 +1159,75 @@ func (rcsw *RemoteClusterServiceWatcher) processEvents(ctx context.Context) {
 	}
 }
 
+// finishEvent marks the given event as processed, and requeues it if it
+// failed with a retryable error.
+func (rcsw *RemoteClusterServiceWatcher) finishEvent(event interface{}, done bool, err error) {
+	rcsw.eventsQueue.Done(event)
+	// the logic here is that there might have been an API
+	// connectivity glitch or something. So its not a bad idea to requeue
+	// the event and try again up to a number of limits, just to ensure
+	// that we are not diverging in states due to bad luck...
+	if err == nil {
+		rcsw.eventsQueue.Forget(event)
+		rcsw.forgetEventTrace(event)
+		if event != nil {
+			rcsw.markProcessed()
+		}
+		switch event.(type) {
+		case *RepairEndpoints, *OrphanedServicesGcTriggered, *MirrorDriftCheck:
+			rcsw.markReconciled()
+		}
+		return
+	}
+	switch e := err.(type) {
+	case RetryableError:
+		{
+			rcsw.log.Warnf("Requeues: %d, Limit: %d for event %s", rcsw.eventsQueue.NumRequeues(event), rcsw.requeueLimit, event)
+			if (rcsw.eventsQueue.NumRequeues(event) < rcsw.requeueLimit) && !done {
+				rcsw.log.Errorf("Error processing %s (will retry): %s", event, e)
+				rcsw.eventsQueue.AddRateLimited(event)
+			} else {
+				rcsw.log.Errorf("Error processing %s (giving up): %s", event, e)
+				rcsw.eventsQueue.Forget(event)
+				rcsw.forgetEventTrace(event)
+				rcsw.recordFailure(event, e)
+			}
+		}
+	default:
+		rcsw.log.Errorf("Error processing %s (will not retry): %s", event, e)
+		rcsw.log.Error(e)
+		rcsw.forgetEventTrace(event)
+		rcsw.recordFailure(event, e)
+	}
+}
+
 // Start starts watching the remote cluster
 func (rcsw *RemoteClusterServiceWatcher) Start(ctx context.Context) error {
 	rcsw.remoteAPIClient.Sync(rcsw.stopper)
+	rcsw.writeSlices = rcsw.negotiateEndpoints(ctx).writesSlices()
+	if err := rcsw.syncRemoteExports(); err != nil {
+		return err
+	}
+
+	initialSyncServices, err := rcsw.initialSyncServices()
+	if err != nil {
+		return err
+	}
+	rcsw.initialSync.pending = make(map[string]struct{}, len(initialSyncServices))
+	for _, svc := range initialSyncServices {
+		if key, err := cache.MetaNamespaceKeyFunc(svc); err == nil {
+			rcsw.initialSync.pending[key] = struct{}{}
+		}
+	}
+
 	rcsw.eventsQueue.Add(&OrphanedServicesGcTriggered{})
 	rcsw.remoteAPIClient.Svc().Informer().AddEventHandler(
-		cache.ResourceEventHandlerFuncs{
+		crash.Handler("service-mirror/remote-services", cache.ResourceEventHandlerFuncs{
 			AddFunc: func(svc interface{}) {
-				rcsw.eventsQueue.Add(&OnAddCalled{svc.(*corev1.Service)})
+				if rcsw.deferToInitialSync(svc.(*corev1.Service)) {
+					return
+				}
+				rcsw.enqueueRemoteEvent(&OnAddCalled{svc.(*corev1.Service)})
 			},
 			DeleteFunc: func(obj interface{}) {
 				service, ok := obj.(*corev1.Service)
@@ -648,72 +1243,271 @@ func (rcsw *RemoteClusterServiceWatcher) Start(ctx context.Context) error {
 						return
 					}
 				}
-				rcsw.eventsQueue.Add(&OnDeleteCalled{service})
+				rcsw.enqueueRemoteEvent(&OnDeleteCalled{service})
 			},
 			UpdateFunc: func(old, new interface{}) {
-				rcsw.eventsQueue.Add(&OnUpdateCalled{new.(*corev1.Service)})
+				rcsw.enqueueRemoteEvent(&OnUpdateCalled{new.(*corev1.Service)})
 			},
-		},
+		}),
 	)
+	rcsw.watchRemoteExports()
+	mirrorStats.add(rcsw)
 	go rcsw.processEvents(ctx)
+	go rcsw.runInitialSync(ctx, initialSyncServices)
+	go rcsw.probeGatewayAddresses()
+	go rcsw.refreshGatewayAddress()
+	go rcsw.watchRemoteReachability(ctx)
 
 	// We need to issue a RepairEndpoints immediately to populate the gateway
 	// mirror endpoints.
 	ev := RepairEndpoints{}
 	rcsw.eventsQueue.Add(&ev)
 
-	go func() {
-		ticker := time.NewTicker(rcsw.repairPeriod)
-		for {
-			select {
-			case <-ticker.C:
-				ev := RepairEndpoints{}
-				rcsw.eventsQueue.Add(&ev)
-			case <-rcsw.stopper:
-				return
-			}
-		}
-	}()
+	go rcsw.scheduleRepairs()
 
 	return nil
 }
 
+// scheduleRepairs periodically triggers a repair of the mirrored endpoints,
+// along with a check of the drift of the mirrors, at the interval adapted by
+// the repair schedule. The next repair is rescheduled whenever the interval
+// changes.
+func (rcsw *RemoteClusterServiceWatcher) scheduleRepairs() {
+	timer := time.NewTimer(rcsw.repairs.next())
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C:
+			// The endpoints are repaired anyway once the target
+			// cluster is reachable again
+			if !rcsw.circuit.isOpen() {
+				rcsw.eventsQueue.Add(&RepairEndpoints{})
+				rcsw.eventsQueue.Add(&MirrorDriftCheck{})
+			}
+		case <-rcsw.repairs.changed:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+		case <-rcsw.stopper:
+			return
+		}
+		timer.Reset(rcsw.repairs.next())
+	}
+}
+
 // Stop stops watching the cluster and cleans up all mirrored resources
 func (rcsw *RemoteClusterServiceWatcher) Stop(cleanupState bool) {
 	close(rcsw.stopper)
+	remoteUnreachable.DeleteLabelValues(rcsw.link.TargetClusterName)
+	mirrorStats.remove(rcsw)
 	if cleanupState {
 		rcsw.eventsQueue.Add(&ClusterUnregistered{})
 	}
 	rcsw.eventsQueue.ShutDown()
 }
 
-func (rcsw *RemoteClusterServiceWatcher) resolveGatewayAddress() ([]corev1.EndpointAddress, error) {
+// resolveGatewayAddress resolves the entries of the Link's gateway address
+// and returns the healthy ones, along with their weights keyed by IP when the
+// Link assigns weights to its gateway addresses.
+func (rcsw *RemoteClusterServiceWatcher) resolveGatewayAddress() ([]corev1.EndpointAddress, map[string]uint32, error) {
+	gatewayEndpoints, weights, err := rcsw.resolveAllGatewayAddresses()
+	if err != nil {
+		return nil, nil, err
+	}
+	gatewayEndpoints, weights = rcsw.mirrorGatewayAddresses(gatewayEndpoints, weights)
+	return gatewayEndpoints, weights, nil
+}
+
+// ShareGatewayLiveness shares the liveness of the gateway, as probed by the
+// given probe worker, with the mirror services. It must be called before the
+// watcher and the probe worker are started. The mirrored Endpoints are
+// repaired whenever the liveness changes.
+func (rcsw *RemoteClusterServiceWatcher) ShareGatewayLiveness(pw *ProbeWorker) {
+	rcsw.gatewayAlive = pw.Alive
+	pw.OnAliveChange(func(bool) {
+		rcsw.repairs.reset()
+		rcsw.eventsQueue.Add(&RepairEndpoints{})
+	})
+}
+
+// mirrorGatewayAddresses returns the gateway addresses of the mirror
+// services: the healthy ones or, when every address fails its probes and the
+// probe worker considers the gateway as a whole dead too, none, so that the
+// services fail over to other clusters rather than keep routing to a dead
+// gateway. The gateway mirror keeps all of them, so that the gateway keeps
+// being probed through it.
+func (rcsw *RemoteClusterServiceWatcher) mirrorGatewayAddresses(addresses []corev1.EndpointAddress, weights map[string]uint32) ([]corev1.EndpointAddress, map[string]uint32) {
+	if rcsw.gatewayAlive != nil && !rcsw.gatewayAlive() && rcsw.gatewayHealth.allUnhealthy(addresses) {
+		rcsw.log.Warn("The gateway and all its addresses are failing their probes, leaving them out of the mirrored endpoints")
+		return nil, nil
+	}
+	return rcsw.healthyGatewayAddresses(addresses, weights)
+}
+
+func (rcsw *RemoteClusterServiceWatcher) healthyGatewayAddresses(addresses []corev1.EndpointAddress, weights map[string]uint32) ([]corev1.EndpointAddress, map[string]uint32) {
+	healthy := rcsw.gatewayHealth.healthy(addresses)
+	if len(weights) == 0 {
+		return healthy, nil
+	}
+
+	healthyWeights := make(map[string]uint32, len(healthy))
+	for _, addr := range healthy {
+		healthyWeights[addr.IP] = weights[addr.IP]
+	}
+	return healthy, healthyWeights
+}
+
+func (rcsw *RemoteClusterServiceWatcher) resolveAllGatewayAddresses() ([]corev1.EndpointAddress, map[string]uint32, error) {
 	var gatewayEndpoints []corev1.EndpointAddress
+	var weights map[string]uint32
 	var errors []error
-	for _, addr := range strings.Split(rcsw.link.GatewayAddress, ",") {
-		ipAddr, err := net.ResolveIPAddr("ip", addr)
-		if err == nil {
-			gatewayEndpoints = append(gatewayEndpoints, corev1.EndpointAddress{
-				IP: ipAddr.String(),
-			})
-		} else {
+	var resolved []string
+	var unresolvable []string
+	var port uint32
+	var ttl time.Duration
+	entries, invalid := multicluster.ParseGatewayAddress(rcsw.link.GatewayAddress)
+	if invalid != nil {
+		rcsw.log.Warn(invalid)
+		errors = append(errors, invalid)
+	}
+	for _, entry := range entries {
+		addr := entry.String()
+		record, err := rcsw.gatewayResolver.resolve(context.Background(), entry.Host)
+		if err != nil {
 			err = fmt.Errorf("Error resolving '%s': %s", addr, err)
 			rcsw.log.Warn(err)
 			errors = append(errors, err)
+			unresolvable = append(unresolvable, addr)
+			continue
+		}
+		if entry.Port != 0 {
+			// the port suffix of the entry overrides the gateway port, like
+			// the port of an SRV record
+			record.port = entry.Port
+		}
+		if record.port != 0 {
+			if port != 0 && port != record.port {
+				rcsw.log.Warnf("Ignoring '%s': its SRV port %d differs from the gateway port %d", addr, record.port, port)
+				continue
+			}
+			port = record.port
+		}
+		if record.ttl != 0 && (ttl == 0 || record.ttl < ttl) {
+			ttl = record.ttl
+		}
+		for _, ip := range record.ips {
+			gatewayEndpoints = append(gatewayEndpoints, corev1.EndpointAddress{
+				IP: ip,
+			})
+			resolved = append(resolved, ip)
+			if len(rcsw.link.GatewayAddressWeights) > 0 {
+				if weights == nil {
+					weights = make(map[string]uint32)
+				}
+				weights[ip] = rcsw.link.GatewayAddressWeight(addr)
+			}
 		}
 	}
+	rcsw.updateGatewayAddressCondition(invalid, unresolvable)
+
 	// one resolved address is enough
 	if len(gatewayEndpoints) > 0 {
-		return gatewayEndpoints, nil
+		if rcsw.gatewayResolver.record(resolved, port, ttl) {
+			// the mirrored endpoints still point at the previous addresses
+			rcsw.repairs.reset()
+			rcsw.eventsQueue.Add(&RepairEndpoints{})
+		}
+		return gatewayEndpoints, weights, nil
+	}
+	return nil, nil, RetryableError{errors}
+}
+
+// gatewayAddressCondition returns the Link status condition listing the
+// entries of the gateway address that are invalid or don't resolve.
+func gatewayAddressCondition(invalid error, unresolvable []string) metav1.Condition {
+	condition := metav1.Condition{
+		Type:    multicluster.LinkConditionGatewayAddress,
+		Status:  metav1.ConditionTrue,
+		Reason:  "AllEntriesResolved",
+		Message: "All the entries of the gateway address resolve",
+	}
+	var problems []string
+	if invalid != nil {
+		condition.Reason = "InvalidEntries"
+		problems = append(problems, invalid.Error())
+	}
+	if len(unresolvable) > 0 {
+		if invalid == nil {
+			condition.Reason = "UnresolvableEntries"
+		}
+		problems = append(problems, fmt.Sprintf("unresolvable gateway address entries: %s", strings.Join(unresolvable, ", ")))
+	}
+	if len(problems) > 0 {
+		condition.Status = metav1.ConditionFalse
+		condition.Message = strings.Join(problems, "; ")
+	}
+	return condition
+}
+
+// updateGatewayAddressCondition reports the invalid and unresolvable entries
+// of the gateway address in the Link status, whenever they change.
+func (rcsw *RemoteClusterServiceWatcher) updateGatewayAddressCondition(invalid error, unresolvable []string) {
+	condition := gatewayAddressCondition(invalid, unresolvable)
+	if !rcsw.gatewayResolver.recordCondition(condition.Message) || rcsw.linkClient == nil {
+		return
+	}
+	if err := multicluster.SetLinkCondition(context.Background(), rcsw.linkClient, rcsw.link.Namespace, rcsw.link.Name, condition); err != nil {
+		rcsw.log.Errorf("Failed to update %s condition on Link %s: %s", condition.Type, rcsw.link.Name, err)
+	}
+}
+
+// refreshGatewayAddress re-resolves the gateway address each time its DNS
+// records expire, so that the mirrored endpoints are repaired as soon as the
+// records change rather than on the next periodic repair.
+func (rcsw *RemoteClusterServiceWatcher) refreshGatewayAddress() {
+	for {
+		after := rcsw.gatewayResolver.refreshAfter(rcsw.repairPeriod)
+		if after == 0 {
+			// the gateway address hasn't been resolved yet, or only has IPs
+			after = rcsw.repairPeriod
+		}
+		select {
+		case <-time.After(after):
+		case <-rcsw.stopper:
+			return
+		}
+		if _, _, err := rcsw.resolveAllGatewayAddresses(); err != nil {
+			rcsw.log.Debugf("Failed to re-resolve the gateway address: %s", err)
+		}
 	}
-	return nil, RetryableError{errors}
 }
 
+// setGatewayWeights records the weights of the gateway addresses in the
+// annotations of a mirrored Endpoints, or removes them if there are none.
+func setGatewayWeights(annotations map[string]string, weights map[string]uint32) {
+	if len(weights) == 0 {
+		delete(annotations, consts.RemoteGatewayWeights)
+		return
+	}
+	annotations[consts.RemoteGatewayWeights] = multicluster.FormatGatewayAddressWeights(weights)
+}
+
+// repairEndpoints rewrites the gateway mirror endpoints and the endpoints of
+// the mirror services whose computed addresses, labels or annotations differ
+// from the local ones, and adapts the interval until the next periodic repair
+// to the outcome.
 func (rcsw *RemoteClusterServiceWatcher) repairEndpoints(ctx context.Context) error {
-	gatewayAddresses, err := rcsw.resolveGatewayAddress()
+	allGatewayAddresses, allGatewayWeights, err := rcsw.resolveAllGatewayAddresses()
 	if err != nil {
+		rcsw.repairs.record(0, 1)
 		return err
 	}
+	written, failed := 0, 0
+	gatewayAddresses, gatewayWeights := rcsw.healthyGatewayAddresses(allGatewayAddresses, allGatewayWeights)
+	mirrorAddresses, mirrorWeights := rcsw.mirrorGatewayAddresses(allGatewayAddresses, allGatewayWeights)
 
 	endpointRepairCounter.With(prometheus.Labels{
 		gatewayClusterName: rcsw.link.TargetClusterName,
@@ -747,9 +1541,12 @@ func (rcsw *RemoteClusterServiceWatcher) repairEndpoints(ctx context.Context) er
 		},
 	}
 
-	err = rcsw.createOrUpdateEndpoints(ctx, gatewayMirrorEndpoints)
+	changed, err := rcsw.applyEndpointsIfChanged(ctx, gatewayMirrorEndpoints)
 	if err != nil {
 		rcsw.log.Errorf("Failed to create/update gateway mirror endpoints: %s", err)
+		failed++
+	} else if changed {
+		written++
 	}
 
 	// Repair mirror service endpoints.
@@ -758,60 +1555,34 @@ func (rcsw *RemoteClusterServiceWatcher) repairEndpoints(ctx context.Context) er
 		rcsw.log.Errorf("Failed to list mirror services: %s", err)
 	}
 	for _, svc := range mirrorServices {
-		updatedService := svc.DeepCopy()
-
-		endpoints, err := rcsw.localAPIClient.Endpoint().Lister().Endpoints(svc.Namespace).Get(svc.Name)
-		if err != nil {
-			rcsw.log.Errorf("Could not get endpoints: %s", err)
+		if _, ok := svc.Labels[consts.MirroredGatewayLabel]; ok {
 			continue
 		}
-
-		updatedEndpoints := endpoints.DeepCopy()
-		updatedEndpoints.Subsets = []corev1.EndpointSubset{
-			{
-				Addresses: gatewayAddresses,
-				Ports:     rcsw.getEndpointsPorts(updatedService),
-			},
-		}
-
-		if updatedEndpoints.Annotations == nil {
-			updatedEndpoints.Annotations = make(map[string]string)
+		// The endpoints are rebuilt from the remote service rather than from
+		// the local ones, as each apply must hold all the fields the service
+		// mirror owns.
+		remoteNamespace, ok := rcsw.link.RemoteNamespace(svc.Namespace)
+		if !ok {
+			continue
 		}
-		updatedEndpoints.Annotations[consts.RemoteGatewayIdentity] = rcsw.link.GatewayIdentity
-
-		_, err = rcsw.localAPIClient.Client.CoreV1().Services(updatedService.Namespace).Update(ctx, updatedService, metav1.UpdateOptions{})
+		remote, err := rcsw.remoteAPIClient.Svc().Lister().Services(remoteNamespace).Get(rcsw.originalResourceName(svc.Name))
 		if err != nil {
-			rcsw.log.Error(err)
+			// left to the GC of the orphaned mirrors if it has been deleted
+			rcsw.log.Debugf("Could not get the remote service of %s/%s: %s", svc.Namespace, svc.Name, err)
 			continue
 		}
 
-		_, err = rcsw.localAPIClient.Client.CoreV1().Endpoints(updatedService.Namespace).Update(ctx, updatedEndpoints, metav1.UpdateOptions{})
+		ep := rcsw.mirrorEndpoints(svc.Namespace, svc.Name, remote, mirrorAddresses, mirrorWeights)
+		changed, err := rcsw.applyEndpointsIfChanged(ctx, ep)
 		if err != nil {
 			rcsw.log.Error(err)
+			failed++
+		} else if changed {
+			written++
 		}
 	}
 
-	return nil
-}
-
-func (rcsw *RemoteClusterServiceWatcher) createOrUpdateEndpoints(ctx context.Context, ep *corev1.Endpoints) error {
-	_, err := rcsw.localAPIClient.Client.CoreV1().Endpoints(ep.Namespace).Get(ctx, ep.Name, metav1.GetOptions{})
-	if err != nil {
-		if kerrors.IsNotFound(err) {
-			// Does not exist so we should create it.
-			_, err = rcsw.localAPIClient.Client.CoreV1().Endpoints(ep.Namespace).Create(ctx, ep, metav1.CreateOptions{})
-			if err != nil {
-				return err
-			}
-		} else {
-			return err
-		}
-	}
-	// Exists so we should update it.
-	_, err = rcsw.localAPIClient.Client.CoreV1().Endpoints(ep.Namespace).Update(ctx, ep, metav1.UpdateOptions{})
-	if err != nil {
-		return err
-	}
-
+	rcsw.log.Debugf("Repaired the mirrored endpoints: %d written, %d failed", written, failed)
+	rcsw.repairs.record(written, failed)
 	return nil
 }
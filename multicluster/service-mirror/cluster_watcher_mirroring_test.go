@@ -7,6 +7,8 @@ import (
 	"testing"
 
 	consts "github.com/linkerd/linkerd2/pkg/k8s"
+	"github.com/linkerd/linkerd2/pkg/multicluster"
+	logging "github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/util/workqueue"
@@ -128,6 +130,21 @@ func TestRemoteServiceCreatedMirroring(t *testing.T) {
 	}
 }
 
+func TestRemoteServiceCreatedConflict(t *testing.T) {
+	for _, tt := range []mirroringTestCase{
+		{
+			description: "leaves a mirror owned by another link untouched",
+			environment: createConflictingService,
+			expectedLocalServices: []*corev1.Service{
+				foreignMirrorService("service-one-remote", "ns1", "other"),
+			},
+		},
+	} {
+		tc := tt // pin
+		tc.run(t)
+	}
+}
+
 func TestRemoteServiceDeletedMirroring(t *testing.T) {
 	for _, tt := range []mirroringTestCase{
 		{
@@ -140,6 +157,30 @@ func TestRemoteServiceDeletedMirroring(t *testing.T) {
 	}
 }
 
+func TestRemoteServiceRecreatedMirroring(t *testing.T) {
+	q := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	localAPI, err := recreateMirrorService.runEnvironment(q)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	svc, err := localAPI.Client.CoreV1().Services("ns1").Get(context.Background(), "service-one-remote", metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Could not find mirrored service: %s", err)
+	}
+	if svc.Spec.ClusterIP != "10.43.0.42" {
+		t.Fatalf("Expected the recreated mirror to reuse ClusterIP 10.43.0.42, got %q", svc.Spec.ClusterIP)
+	}
+
+	ns, err := localAPI.Client.CoreV1().Namespaces().Get(context.Background(), "ns1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, ok := ns.Annotations[consts.ReservedClusterIPsAnnotation]; ok {
+		t.Fatalf("Expected the ClusterIP reservation to be released, got %s", value)
+	}
+}
+
 func TestRemoteServiceUpdatedMirroring(t *testing.T) {
 	for _, tt := range []mirroringTestCase{
 		{
@@ -182,6 +223,127 @@ func TestRemoteServiceUpdatedMirroring(t *testing.T) {
 	}
 }
 
+func TestRemoteServicePortRenameMirroring(t *testing.T) {
+	for _, tt := range []mirroringTestCase{
+		{
+			description: "renames ports on both service and endpoints from the same snapshot",
+			environment: updateServiceWithRenamedPorts,
+			expectedLocalServices: []*corev1.Service{
+				mirrorService("test-service-remote", "test-namespace", "currentServiceResVersion",
+					[]corev1.ServicePort{
+						{
+							Name:     "http",
+							Protocol: "TCP",
+							Port:     111,
+						},
+					}),
+			},
+			expectedLocalEndpoints: []*corev1.Endpoints{
+				endpoints("test-service-remote", "test-namespace", "192.0.2.127", "gateway-identity", []corev1.EndpointPort{
+					{
+						Name:     "http",
+						Port:     888,
+						Protocol: "TCP",
+					},
+				}),
+			},
+		},
+	} {
+		tc := tt // pin
+		tc.run(t)
+	}
+}
+
+func TestEndpointsPortsMatchService(t *testing.T) {
+	svc := mirrorService("test-service-remote", "test-namespace", "", []corev1.ServicePort{
+		{Name: "http", Port: 111},
+	})
+
+	matching := endpoints("test-service-remote", "test-namespace", "192.0.2.127", "", []corev1.EndpointPort{
+		{Name: "http", Port: 888},
+	})
+	if !endpointsPortsMatchService(svc, matching) {
+		t.Fatalf("Expected endpoints %v to match service %v", matching.Subsets, svc.Spec.Ports)
+	}
+
+	stale := endpoints("test-service-remote", "test-namespace", "192.0.2.127", "", []corev1.EndpointPort{
+		{Name: "port1", Port: 888},
+	})
+	if endpointsPortsMatchService(svc, stale) {
+		t.Fatalf("Expected endpoints %v not to match service %v", stale.Subsets, svc.Spec.Ports)
+	}
+}
+
+func TestMirrorNamespaceTerminating(t *testing.T) {
+	localAPI, err := newFakeAPI(`
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: shared
+  deletionTimestamp: "2021-01-01T00:00:00Z"
+  labels:
+    mirror.linkerd.io/mirrored-service: "true"
+    mirror.linkerd.io/cluster-name: other`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	localAPI.Sync(nil)
+
+	watcher := RemoteClusterServiceWatcher{
+		link:           &multicluster.Link{TargetClusterName: clusterName},
+		localAPIClient: localAPI,
+		log:            logging.WithFields(logging.Fields{"cluster": clusterName}),
+	}
+	err = watcher.mirrorNamespaceIfNecessary(context.Background(), "shared")
+	if _, ok := err.(RetryableError); !ok {
+		t.Fatalf("Expected a retryable error while the namespace is terminating, got %v", err)
+	}
+}
+
+func TestGatewayIPFamilies(t *testing.T) {
+	dualStack := corev1.IPFamilyPolicyPreferDualStack
+
+	testCases := []struct {
+		description      string
+		addresses        []string
+		expectedPolicy   *corev1.IPFamilyPolicyType
+		expectedFamilies []corev1.IPFamily
+	}{
+		{
+			description: "IPv4 gateway keeps the cluster defaults",
+			addresses:   []string{"192.0.2.1", "192.0.2.2"},
+		},
+		{
+			description:      "IPv6 gateway",
+			addresses:        []string{"2001:db8::1"},
+			expectedFamilies: []corev1.IPFamily{corev1.IPv6Protocol},
+		},
+		{
+			description:      "dual-stack gateway",
+			addresses:        []string{"2001:db8::1", "192.0.2.1", "2001:db8::2"},
+			expectedPolicy:   &dualStack,
+			expectedFamilies: []corev1.IPFamily{corev1.IPv6Protocol, corev1.IPv4Protocol},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // pin
+		t.Run(tc.description, func(t *testing.T) {
+			var addresses []corev1.EndpointAddress
+			for _, ip := range tc.addresses {
+				addresses = append(addresses, corev1.EndpointAddress{IP: ip})
+			}
+			policy, families := gatewayIPFamilies(addresses)
+			if !reflect.DeepEqual(policy, tc.expectedPolicy) {
+				t.Errorf("Expected IP family policy %v, got %v", tc.expectedPolicy, policy)
+			}
+			if !reflect.DeepEqual(families, tc.expectedFamilies) {
+				t.Errorf("Expected IP families %v, got %v", tc.expectedFamilies, families)
+			}
+		})
+	}
+}
+
 func TestClusterUnregisteredMirroring(t *testing.T) {
 	for _, tt := range []mirroringTestCase{
 		{
@@ -48,7 +48,7 @@ func (te *testEnvironment) runEnvironment(watcherQueue workqueue.RateLimitingInt
 		return nil, err
 	}
 
-	localAPI, err := k8s.NewFakeAPI(te.localResources...)
+	localAPI, err := newFakeAPI(te.localResources...)
 	if err != nil {
 		return nil, err
 	}
@@ -64,6 +64,8 @@ func (te *testEnvironment) runEnvironment(watcherQueue workqueue.RateLimitingInt
 		log:             logging.WithFields(logging.Fields{"cluster": clusterName}),
 		eventsQueue:     watcherQueue,
 		requeueLimit:    0,
+		conflicts:       make(map[string]string),
+		circuit:         newCircuitBreaker(1),
 	}
 
 	for _, ev := range te.events {
@@ -113,6 +115,34 @@ var createExportedService = &testEnvironment{
 	},
 }
 
+var createConflictingService = &testEnvironment{
+	events: []interface{}{
+		&RemoteServiceCreated{
+			service: remoteService("service-one", "ns1", "111", map[string]string{
+				consts.DefaultExportedServiceSelector: "true",
+			}, []corev1.ServicePort{
+				{
+					Name:     "port1",
+					Protocol: "TCP",
+					Port:     555,
+				},
+			}),
+		},
+	},
+	localResources: []string{
+		foreignMirrorServiceAsYaml("service-one-remote", "ns1", "other"),
+	},
+	link: multicluster.Link{
+		TargetClusterName:   clusterName,
+		TargetClusterDomain: clusterDomain,
+		GatewayIdentity:     "gateway-identity",
+		GatewayAddress:      "192.0.2.127",
+		GatewayPort:         888,
+		ProbeSpec:           defaultProbeSpec,
+		Selector:            *defaultSelector,
+	},
+}
+
 var deleteMirrorService = &testEnvironment{
 	events: []interface{}{
 		&RemoteServiceDeleted{
@@ -135,6 +165,37 @@ var deleteMirrorService = &testEnvironment{
 	},
 }
 
+var recreateMirrorService = &testEnvironment{
+	events: []interface{}{
+		&RemoteServiceDeleted{
+			Name:      "service-one",
+			Namespace: "ns1",
+		},
+		&RemoteServiceCreated{
+			service: remoteService("service-one", "ns1", "112", map[string]string{
+				consts.DefaultExportedServiceSelector: "true",
+			}, nil),
+		},
+	},
+	remoteResources: []string{
+		gatewayAsYaml("existing-gateway", "existing-namespace", "222", "192.0.2.127", "mc-gateway", 888, "gateway-identity", defaultProbePort, defaultProbePath, defaultProbePeriod),
+	},
+	localResources: []string{
+		namespaceAsYaml("ns1"),
+		mirrorServiceWithClusterIPAsYaml("service-one-remote", "ns1", "111", "10.43.0.42"),
+		endpointsAsYaml("service-one-remote", "ns1", "192.0.2.127", "gateway-identity", nil),
+	},
+	link: multicluster.Link{
+		TargetClusterName:   clusterName,
+		TargetClusterDomain: clusterDomain,
+		GatewayIdentity:     "gateway-identity",
+		GatewayAddress:      "192.0.2.127",
+		GatewayPort:         888,
+		ProbeSpec:           defaultProbeSpec,
+		Selector:            *defaultSelector,
+	},
+}
+
 var updateServiceWithChangedPorts = &testEnvironment{
 	events: []interface{}{
 		&RemoteServiceUpdated{
@@ -228,6 +289,61 @@ var updateServiceWithChangedPorts = &testEnvironment{
 	},
 }
 
+var updateServiceWithRenamedPorts = &testEnvironment{
+	events: []interface{}{
+		&RemoteServiceUpdated{
+			remoteUpdate: remoteService("test-service", "test-namespace", "currentServiceResVersion", map[string]string{
+				consts.DefaultExportedServiceSelector: "true",
+			}, []corev1.ServicePort{
+				{
+					Name:     "http",
+					Protocol: "TCP",
+					Port:     111,
+				},
+			}),
+			localService: mirrorService("test-service-remote", "test-namespace", "pastServiceResVersion", []corev1.ServicePort{
+				{
+					Name:     "port1",
+					Protocol: "TCP",
+					Port:     111,
+				},
+			}),
+			localEndpoints: endpoints("test-service-remote", "test-namespace", "192.0.2.127", "", []corev1.EndpointPort{
+				{
+					Name:     "port1",
+					Port:     888,
+					Protocol: "TCP",
+				},
+			}),
+		},
+	},
+	localResources: []string{
+		mirrorServiceAsYaml("test-service-remote", "test-namespace", "past", []corev1.ServicePort{
+			{
+				Name:     "port1",
+				Protocol: "TCP",
+				Port:     111,
+			},
+		}),
+		endpointsAsYaml("test-service-remote", "test-namespace", "192.0.2.127", "", []corev1.EndpointPort{
+			{
+				Name:     "port1",
+				Port:     888,
+				Protocol: "TCP",
+			},
+		}),
+	},
+	link: multicluster.Link{
+		TargetClusterName:   clusterName,
+		TargetClusterDomain: clusterDomain,
+		GatewayIdentity:     "gateway-identity",
+		GatewayAddress:      "192.0.2.127",
+		GatewayPort:         888,
+		ProbeSpec:           defaultProbeSpec,
+		Selector:            *defaultSelector,
+	},
+}
+
 var clusterUnregistered = &testEnvironment{
 	events: []interface{}{
 		&ClusterUnregistered{},
@@ -466,6 +582,18 @@ func remoteServiceAsYaml(name, namespace, resourceVersion string, ports []corev1
 	return string(bytes)
 }
 
+func exportedRemoteServiceAsYaml(name, namespace string) string {
+	svc := remoteService(name, namespace, "1", map[string]string{
+		consts.DefaultExportedServiceSelector: "true",
+	}, nil)
+
+	bytes, err := yaml.Marshal(svc)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return string(bytes)
+}
+
 func mirrorService(name, namespace, resourceVersion string, ports []corev1.ServicePort) *corev1.Service {
 	annotations := make(map[string]string)
 	annotations[consts.RemoteResourceVersionAnnotation] = resourceVersion
@@ -501,6 +629,53 @@ func mirrorServiceAsYaml(name, namespace, resourceVersion string, ports []corev1
 	return string(bytes)
 }
 
+func mirrorServiceWithClusterIPAsYaml(name, namespace, resourceVersion, clusterIP string) string {
+	svc := mirrorService(name, namespace, resourceVersion, nil)
+	svc.Spec.ClusterIP = clusterIP
+
+	bytes, err := yaml.Marshal(svc)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return string(bytes)
+}
+
+func namespaceAsYaml(name string) string {
+	ns := &corev1.Namespace{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Namespace",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+		},
+	}
+
+	bytes, err := yaml.Marshal(ns)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return string(bytes)
+}
+
+// foreignMirrorService returns a mirror service that was created on behalf of
+// a Link to a different target cluster.
+func foreignMirrorService(name, namespace, targetCluster string) *corev1.Service {
+	svc := mirrorService(name, namespace, "", nil)
+	svc.Labels[consts.RemoteClusterNameLabel] = targetCluster
+	return svc
+}
+
+func foreignMirrorServiceAsYaml(name, namespace, targetCluster string) string {
+	svc := foreignMirrorService(name, namespace, targetCluster)
+
+	bytes, err := yaml.Marshal(svc)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return string(bytes)
+}
+
 func gateway(name, namespace, resourceVersion, ip, hostname, portName string, port int32, identity string, probePort int32, probePath string, probePeriod int) *corev1.Service {
 	svc := corev1.Service{
 		TypeMeta: metav1.TypeMeta{
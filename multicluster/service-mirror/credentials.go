@@ -0,0 +1,223 @@
+package servicemirror
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/linkerd/linkerd2/pkg/multicluster"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// memoryPersister keeps the config refreshed by an auth provider, such as the
+// tokens of the oidc provider, in memory. The kubeconfig of a target cluster
+// is read from a secret rather than a file, so there's nowhere to persist
+// them; they're refreshed again from the credentials secret after a restart.
+type memoryPersister struct {
+	sync.Mutex
+	config map[string]string
+}
+
+func (p *memoryPersister) Persist(config map[string]string) error {
+	p.Lock()
+	defer p.Unlock()
+	p.config = config
+	return nil
+}
+
+// RemoteConfig returns the client config of a target cluster from its
+// kubeconfig. Along with static tokens and client certificates, the
+// kubeconfig can authenticate through an exec credential plugin, such as the
+// aws, gke-gcloud-auth-plugin or kubelogin CLIs, which are looked up on the
+// PATH (see UseCredentialPlugins), or through an auth provider, such as oidc,
+// whose tokens are then refreshed in memory.
+func RemoteConfig(kubeconfig []byte) (*rest.Config, error) {
+	cfg, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.AuthProvider != nil && cfg.AuthConfigPersister == nil {
+		cfg.AuthConfigPersister = &memoryPersister{}
+	}
+	return cfg, nil
+}
+
+// RemoteCredentials authenticates the clients of a target cluster with the
+// bearer token of its kubeconfig, which can be rotated while they're running:
+// a cluster watcher then keeps its informer caches when only the token of its
+// credentials secret changes, instead of being restarted.
+type RemoteCredentials struct {
+	// config is the config of the kubeconfig, as parsed, which the rotated
+	// kubeconfigs are compared to
+	config *rest.Config
+	token  atomic.Value
+}
+
+type bearerRoundTripper struct {
+	credentials *RemoteCredentials
+	rt          http.RoundTripper
+}
+
+// NewRemoteCredentials returns the credentials of the given kubeconfig, along
+// with the client config using them.
+func NewRemoteCredentials(kubeconfig []byte) (*RemoteCredentials, *rest.Config, error) {
+	cfg, err := RemoteConfig(kubeconfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	credentials := &RemoteCredentials{config: rest.CopyConfig(cfg)}
+	if cfg.BearerToken != "" && cfg.BearerTokenFile == "" {
+		credentials.token.Store(cfg.BearerToken)
+		cfg.BearerToken = ""
+		cfg.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+			return &bearerRoundTripper{credentials: credentials, rt: rt}
+		})
+	}
+	return credentials, cfg, nil
+}
+
+// Rotate switches the clients to the bearer token of the given kubeconfig, and
+// returns true, if the kubeconfig only differs from the current one by its
+// token. Otherwise, the clients must be rebuilt from the new kubeconfig.
+func (c *RemoteCredentials) Rotate(kubeconfig []byte) (bool, error) {
+	cfg, err := RemoteConfig(kubeconfig)
+	if err != nil {
+		return false, err
+	}
+	if c.token.Load() == nil || cfg.BearerToken == "" || cfg.BearerTokenFile != "" {
+		return false, nil
+	}
+	token := cfg.BearerToken
+	cfg.BearerToken = c.config.BearerToken
+	if !reflect.DeepEqual(cfg, c.config) {
+		return false, nil
+	}
+	c.token.Store(token)
+	c.config.BearerToken = token
+	return true, nil
+}
+
+func (rt *bearerRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Header.Get("Authorization") != "" {
+		return rt.rt.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+rt.credentials.token.Load().(string))
+	return rt.rt.RoundTrip(req)
+}
+
+func (rt *bearerRoundTripper) WrappedRoundTripper() http.RoundTripper { return rt.rt }
+
+// UseCredentialPlugins puts the given directory first on the PATH, so that
+// the exec credential plugins of the kubeconfigs of the target clusters are
+// found there.
+func UseCredentialPlugins(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("invalid credential plugins path: %s", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("invalid credential plugins path: %s is not a directory", dir)
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	return os.Setenv("PATH", abs+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// authMethod describes how the given config authenticates to its API server.
+func authMethod(cfg *rest.Config) string {
+	switch {
+	case cfg.ExecProvider != nil:
+		return fmt.Sprintf("the %s exec credential plugin", cfg.ExecProvider.Command)
+	case cfg.AuthProvider != nil:
+		return fmt.Sprintf("the %s auth provider", cfg.AuthProvider.Name)
+	case cfg.BearerToken != "" || cfg.BearerTokenFile != "":
+		return "a bearer token"
+	case len(cfg.CertData) > 0 || cfg.CertFile != "":
+		return "a client certificate"
+	case cfg.Username != "":
+		return "basic authentication"
+	default:
+		return "no credentials"
+	}
+}
+
+// isCredentialPluginError returns whether the error comes from the exec
+// credential plugin or auth provider of the config, rather than from the API
+// server or the network.
+func isCredentialPluginError(cfg *rest.Config, err error) bool {
+	msg := err.Error()
+	if cfg.ExecProvider != nil && strings.Contains(msg, "getting credentials") {
+		return true
+	}
+	return cfg.AuthProvider != nil && (strings.Contains(msg, "refresh") || strings.Contains(msg, cfg.AuthProvider.Name))
+}
+
+// authenticationCondition returns the Link status condition reporting the
+// result of the authentication to the API server of the target cluster, with
+// the given config or, if it's nil, the error parsing the kubeconfig. It
+// returns false if the error isn't related to the credentials, e.g. if the
+// API server can't be reached.
+func authenticationCondition(cfg *rest.Config, err error) (metav1.Condition, bool) {
+	condition := metav1.Condition{
+		Type:   multicluster.LinkConditionAuthenticated,
+		Status: metav1.ConditionFalse,
+	}
+	if cfg == nil {
+		condition.Reason = "InvalidKubeconfig"
+		condition.Message = fmt.Sprintf("Invalid kubeconfig in the credentials secret: %s", err)
+		return condition, true
+	}
+	switch {
+	case err == nil:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "Authenticated"
+		condition.Message = fmt.Sprintf("Authenticated to the target cluster API server with %s", authMethod(cfg))
+		return condition, true
+	case kerrors.IsUnauthorized(err):
+		condition.Reason = "Unauthorized"
+	case kerrors.IsForbidden(err):
+		condition.Reason = "Forbidden"
+	case isCredentialPluginError(cfg, err):
+		condition.Reason = "CredentialPluginFailed"
+	default:
+		return metav1.Condition{}, false
+	}
+	condition.Message = fmt.Sprintf("Failed to authenticate with %s: %s", authMethod(cfg), err)
+	return condition, true
+}
+
+// CheckCredentials checks that the given config of a target cluster can be
+// used to list its services, as the service mirror does.
+func CheckCredentials(ctx context.Context, cfg *rest.Config) error {
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		return err
+	}
+	_, err = client.CoreV1().Services(metav1.NamespaceAll).List(ctx, metav1.ListOptions{Limit: 1})
+	return err
+}
+
+// SetAuthenticatedCondition reports the result of CheckCredentials, or the
+// error of RemoteConfig if cfg is nil, in the Link's Authenticated condition,
+// unless the error is unrelated to the credentials.
+func SetAuthenticatedCondition(ctx context.Context, client dynamic.Interface, namespace, name string, cfg *rest.Config, err error) error {
+	condition, ok := authenticationCondition(cfg, err)
+	if !ok || client == nil {
+		return nil
+	}
+	return multicluster.SetLinkCondition(ctx, client, namespace, name, condition)
+}
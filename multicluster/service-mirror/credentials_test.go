@@ -0,0 +1,236 @@
+package servicemirror
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+const kubeconfigTemplate = `apiVersion: v1
+kind: Config
+clusters:
+- name: remote
+  cluster:
+    server: https://remote.example.com
+contexts:
+- name: remote
+  context:
+    cluster: remote
+    user: remote
+current-context: remote
+users:
+- name: remote
+  user:
+%s`
+
+func TestRemoteConfig(t *testing.T) {
+	for _, tc := range []struct {
+		name       string
+		user       string
+		method     string
+		persisting bool
+	}{
+		{
+			name:   "token",
+			user:   "    token: abc",
+			method: "a bearer token",
+		},
+		{
+			name: "exec plugin",
+			user: `    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: aws
+      args: ["eks", "get-token", "--cluster-name", "remote"]`,
+			method: "the aws exec credential plugin",
+		},
+		{
+			name: "oidc",
+			user: `    auth-provider:
+      name: oidc
+      config:
+        client-id: linkerd
+        idp-issuer-url: https://issuer.example.com
+        refresh-token: xyz`,
+			method:     "the oidc auth provider",
+			persisting: true,
+		},
+	} {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			cfg, err := RemoteConfig([]byte(fmt.Sprintf(kubeconfigTemplate, tc.user)))
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if method := authMethod(cfg); method != tc.method {
+				t.Errorf("Expected auth method %q, got %q", tc.method, method)
+			}
+			if tc.persisting && cfg.AuthConfigPersister == nil {
+				t.Error("Expected the refreshed tokens of the auth provider to be persisted")
+			}
+			if tc.persisting {
+				if err := cfg.AuthConfigPersister.Persist(map[string]string{"id-token": "new"}); err != nil {
+					t.Errorf("Unexpected error: %s", err)
+				}
+			}
+		})
+	}
+
+	if _, err := RemoteConfig([]byte("not a kubeconfig")); err == nil {
+		t.Error("Expected an invalid kubeconfig to be rejected")
+	}
+}
+
+func TestAuthenticationCondition(t *testing.T) {
+	exec := &rest.Config{ExecProvider: &clientcmdapi.ExecConfig{Command: "aws"}}
+	token := &rest.Config{BearerToken: "abc"}
+	services := schema.GroupResource{Resource: "services"}
+
+	for _, tc := range []struct {
+		name     string
+		cfg      *rest.Config
+		err      error
+		reported bool
+		status   metav1.ConditionStatus
+		reason   string
+	}{
+		{
+			name:     "authenticated",
+			cfg:      exec,
+			reported: true,
+			status:   metav1.ConditionTrue,
+			reason:   "Authenticated",
+		},
+		{
+			name:     "invalid kubeconfig",
+			err:      errors.New("couldn't get version/kind"),
+			reported: true,
+			status:   metav1.ConditionFalse,
+			reason:   "InvalidKubeconfig",
+		},
+		{
+			name:     "unauthorized",
+			cfg:      token,
+			err:      kerrors.NewUnauthorized("Unauthorized"),
+			reported: true,
+			status:   metav1.ConditionFalse,
+			reason:   "Unauthorized",
+		},
+		{
+			name:     "forbidden",
+			cfg:      token,
+			err:      kerrors.NewForbidden(services, "", errors.New("no RBAC policy matched")),
+			reported: true,
+			status:   metav1.ConditionFalse,
+			reason:   "Forbidden",
+		},
+		{
+			name:     "plugin failure",
+			cfg:      exec,
+			err:      errors.New(`Get "https://remote.example.com/api/v1/services": getting credentials: exec: executable aws not found`),
+			reported: true,
+			status:   metav1.ConditionFalse,
+			reason:   "CredentialPluginFailed",
+		},
+		{
+			name: "unreachable",
+			cfg:  exec,
+			err:  errors.New(`Get "https://remote.example.com/api/v1/services": dial tcp: i/o timeout`),
+		},
+	} {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			condition, reported := authenticationCondition(tc.cfg, tc.err)
+			if reported != tc.reported {
+				t.Fatalf("Expected reported to be %t, got %t", tc.reported, reported)
+			}
+			if !reported {
+				return
+			}
+			if condition.Status != tc.status || condition.Reason != tc.reason {
+				t.Errorf("Expected condition %s/%s, got %s/%s: %s", tc.status, tc.reason, condition.Status, condition.Reason, condition.Message)
+			}
+		})
+	}
+}
+
+func TestRemoteCredentialsRotate(t *testing.T) {
+	var mu sync.Mutex
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		authorization = req.Header.Get("Authorization")
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"apiVersion": "v1", "kind": "ServiceList", "items": []}`)
+	}))
+	defer server.Close()
+
+	kubeconfig := func(server, token string) []byte {
+		return []byte(fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- name: remote
+  cluster:
+    server: %s
+contexts:
+- name: remote
+  context:
+    cluster: remote
+    user: remote
+current-context: remote
+users:
+- name: remote
+  user:
+    token: %s`, server, token))
+	}
+
+	credentials, cfg, err := NewRemoteCredentials(kubeconfig(server.URL, "first"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	client, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expectAuthorization := func(expected string) {
+		t.Helper()
+		if _, err := client.CoreV1().Services("").List(context.Background(), metav1.ListOptions{}); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if authorization != expected {
+			t.Errorf("Expected authorization %q, got %q", expected, authorization)
+		}
+	}
+	expectAuthorization("Bearer first")
+
+	rotated, err := credentials.Rotate(kubeconfig(server.URL, "second"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !rotated {
+		t.Fatal("Expected the token to be rotated")
+	}
+	expectAuthorization("Bearer second")
+
+	rotated, err = credentials.Rotate(kubeconfig("https://other.example.com", "third"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if rotated {
+		t.Error("Expected a change of server not to be rotated")
+	}
+	expectAuthorization("Bearer second")
+}
@@ -0,0 +1,163 @@
+package servicemirror
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	consts "github.com/linkerd/linkerd2/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// checkMirrorDrift corrects the drift of the mirrors of all the exported
+// remote services. The addresses of the endpoints aren't checked, as the
+// repair of the endpoints rewrites them anyway.
+func (rcsw *RemoteClusterServiceWatcher) checkMirrorDrift(ctx context.Context) error {
+	remoteServices, err := rcsw.remoteAPIClient.Svc().Lister().List(labels.Everything())
+	if err != nil {
+		return RetryableError{[]error{err}}
+	}
+
+	var errors []error
+	for _, remote := range remoteServices {
+		if !rcsw.isExportedService(remote) {
+			continue
+		}
+		namespace := rcsw.link.LocalNamespace(remote.Namespace)
+		name := rcsw.mirroredResourceName(remote.Name)
+		// a missing mirror is created from the informer events
+		svc, err := rcsw.localAPIClient.Svc().Lister().Services(namespace).Get(name)
+		if err != nil || !rcsw.isOwnedMirror(svc) {
+			continue
+		}
+		ep, err := rcsw.localAPIClient.Endpoint().Lister().Endpoints(namespace).Get(name)
+		if err != nil {
+			continue
+		}
+
+		if err := rcsw.correctServiceDrift(ctx, remote, svc); err != nil {
+			errors = append(errors, err)
+		}
+		if err := rcsw.correctEndpointsDrift(ctx, remote, ep); err != nil {
+			errors = append(errors, err)
+		}
+	}
+	if len(errors) > 0 {
+		return RetryableError{errors}
+	}
+	return nil
+}
+
+// correctServiceDrift restores the labels, annotations and ports of a mirror
+// service that differ from the ones computed from its remote service. The
+// labels and annotations set by other controllers are left alone.
+func (rcsw *RemoteClusterServiceWatcher) correctServiceDrift(ctx context.Context, remote, svc *corev1.Service) error {
+	desired := svc.DeepCopy()
+	desired.Labels = rcsw.getMirrorLabels(remote)
+	desired.Annotations = rcsw.getMirroredServiceAnnotations(remote)
+	desired.Spec.Ports = remapRemoteServicePorts(remote.Spec.Ports)
+
+	if !containsAll(svc.Labels, desired.Labels) || !containsAll(svc.Annotations, desired.Annotations) {
+		rcsw.log.Warnf("Correcting the drift of the labels or annotations of mirror service %s/%s", svc.Namespace, svc.Name)
+		driftCorrections.WithLabelValues(rcsw.link.TargetClusterName, "service").Inc()
+		if err := rcsw.applyService(ctx, desired); err != nil {
+			return err
+		}
+	}
+
+	if !sameServicePorts(svc.Spec.Ports, desired.Spec.Ports) {
+		// The ports added by others aren't removed by an apply, so the ports
+		// are restored with an update of the latest version of the service
+		rcsw.log.Warnf("Correcting the drift of the ports of mirror service %s/%s: %v instead of %v", svc.Namespace, svc.Name, svc.Spec.Ports, desired.Spec.Ports)
+		driftCorrections.WithLabelValues(rcsw.link.TargetClusterName, "service").Inc()
+		latest, err := rcsw.localServices(svc.Namespace).Get(ctx, svc.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		latest.Spec.Ports = desired.Spec.Ports
+		if _, err := rcsw.localServices(svc.Namespace).Update(ctx, latest, metav1.UpdateOptions{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// correctEndpointsDrift restores the labels, annotations and ports of mirror
+// endpoints that differ from the ones computed from their remote service.
+func (rcsw *RemoteClusterServiceWatcher) correctEndpointsDrift(ctx context.Context, remote *corev1.Service, ep *corev1.Endpoints) error {
+	gatewayAddresses, gatewayWeights, err := rcsw.resolveGatewayAddress()
+	if err != nil {
+		return err
+	}
+	desired := rcsw.mirrorEndpoints(ep.Namespace, ep.Name, remote, gatewayAddresses, gatewayWeights)
+
+	// the weights follow the health of the gateway addresses, like the
+	// addresses themselves
+	desiredAnnotations := make(map[string]string, len(desired.Annotations))
+	for k, v := range desired.Annotations {
+		if k != consts.RemoteGatewayWeights {
+			desiredAnnotations[k] = v
+		}
+	}
+
+	var ports []corev1.EndpointPort
+	for _, subset := range ep.Subsets {
+		ports = append(ports, subset.Ports...)
+	}
+	if containsAll(ep.Labels, desired.Labels) &&
+		containsAll(ep.Annotations, desiredAnnotations) &&
+		(len(ep.Subsets) == 0 || sameEndpointPorts(ports, desired.Subsets[0].Ports)) {
+		return nil
+	}
+
+	rcsw.log.Warnf("Correcting the drift of mirror endpoints %s/%s", ep.Namespace, ep.Name)
+	driftCorrections.WithLabelValues(rcsw.link.TargetClusterName, "endpoints").Inc()
+	return rcsw.applyEndpoints(ctx, desired)
+}
+
+// containsAll returns whether all the entries of expected are in actual.
+func containsAll(actual, expected map[string]string) bool {
+	for k, v := range expected {
+		if actual[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+func sameServicePorts(actual, expected []corev1.ServicePort) bool {
+	key := func(p corev1.ServicePort) string {
+		return fmt.Sprintf("%s/%s/%d", p.Name, p.Protocol, p.Port)
+	}
+	return sameKeys(len(actual), len(expected), func(i int) string { return key(actual[i]) }, func(i int) string { return key(expected[i]) })
+}
+
+func sameEndpointPorts(actual, expected []corev1.EndpointPort) bool {
+	key := func(p corev1.EndpointPort) string {
+		return fmt.Sprintf("%s/%s/%d", p.Name, p.Protocol, p.Port)
+	}
+	return sameKeys(len(actual), len(expected), func(i int) string { return key(actual[i]) }, func(i int) string { return key(expected[i]) })
+}
+
+// sameKeys returns whether two lists hold the same keys, regardless of their
+// order.
+func sameKeys(actualLen, expectedLen int, actual, expected func(int) string) bool {
+	if actualLen != expectedLen {
+		return false
+	}
+	a := make([]string, actualLen)
+	e := make([]string, expectedLen)
+	for i := range a {
+		a[i], e[i] = actual(i), expected(i)
+	}
+	sort.Strings(a)
+	sort.Strings(e)
+	for i := range a {
+		if a[i] != e[i] {
+			return false
+		}
+	}
+	return true
+}
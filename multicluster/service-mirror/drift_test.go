@@ -0,0 +1,55 @@
+package servicemirror
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	consts "github.com/linkerd/linkerd2/pkg/k8s"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMirrorDriftCheck(t *testing.T) {
+	h := newMirrorHarness(t, harnessLink(), nil, nil)
+	ctx := context.Background()
+
+	h.createRemote(remoteService("service-one", "ns1", "", exportedLabels(), []corev1.ServicePort{
+		{Name: "http", Protocol: "TCP", Port: 80},
+	}))
+	h.eventually(func() error {
+		_, _, err := h.mirror("ns1", "service-one")
+		return err
+	})
+	corrections := testutil.ToFloat64(driftCorrections.WithLabelValues(h.watcher.link.TargetClusterName, "service"))
+
+	// the mirror service is edited locally, without any change in the target
+	// cluster
+	svc, _, _ := h.mirror("ns1", "service-one")
+	svc = svc.DeepCopy()
+	svc.Spec.Ports = append(svc.Spec.Ports, corev1.ServicePort{Name: "debug", Protocol: "TCP", Port: 6060})
+	svc.Annotations[consts.RemoteServiceFqName] = "service-one.ns1.svc.elsewhere.local"
+	if _, err := h.local.Client.CoreV1().Services("ns1").Update(ctx, svc, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	h.eventually(func() error {
+		h.watcher.eventsQueue.Add(&MirrorDriftCheck{})
+		svc, _, err := h.mirror("ns1", "service-one")
+		if err != nil {
+			return err
+		}
+		if len(svc.Spec.Ports) != 1 || svc.Spec.Ports[0].Port != 80 {
+			return fmt.Errorf("expected the ports to be restored, got %v", svc.Spec.Ports)
+		}
+		if svc.Annotations[consts.RemoteServiceFqName] != "service-one.ns1.svc."+h.watcher.link.TargetClusterDomain {
+			return fmt.Errorf("expected the annotations to be restored, got %v", svc.Annotations)
+		}
+		return nil
+	})
+
+	if got := testutil.ToFloat64(driftCorrections.WithLabelValues(h.watcher.link.TargetClusterName, "service")); got < corrections+2 {
+		t.Errorf("Expected at least 2 more corrections, got %v", got-corrections)
+	}
+}
@@ -0,0 +1,99 @@
+package servicemirror
+
+import (
+	"context"
+
+	logging "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+type (
+	// dryRun logs and counts the writes a cluster watcher would make to the
+	// mirror resources of the local cluster, instead of making them. A nil
+	// dryRun means the writes are made.
+	dryRun struct {
+		clusterName string
+		log         *logging.Entry
+	}
+
+	dryRunServices struct {
+		typedcorev1.ServiceInterface
+		namespace string
+		dryRun    *dryRun
+	}
+
+	dryRunEndpoints struct {
+		typedcorev1.EndpointsInterface
+		namespace string
+		dryRun    *dryRun
+	}
+
+	dryRunNamespaces struct {
+		typedcorev1.NamespaceInterface
+		dryRun *dryRun
+	}
+)
+
+func newDryRun(clusterName string, log *logging.Entry) *dryRun {
+	log.Warn("Dry run enabled: the mirror resources of the local cluster won't be modified")
+	return &dryRun{clusterName, log}
+}
+
+// write records a write that would have been made.
+func (d *dryRun) write(verb, resource, key string) {
+	d.log.Infof("Dry run: would %s %s %s", verb, resource, key)
+	dryRunWritesCounter.WithLabelValues(d.clusterName, verb, resource).Inc()
+}
+
+func (s dryRunServices) Create(ctx context.Context, svc *corev1.Service, opts metav1.CreateOptions) (*corev1.Service, error) {
+	s.dryRun.write("create", "service", s.namespace+"/"+svc.Name)
+	return svc.DeepCopy(), nil
+}
+
+func (s dryRunServices) Update(ctx context.Context, svc *corev1.Service, opts metav1.UpdateOptions) (*corev1.Service, error) {
+	s.dryRun.write("update", "service", s.namespace+"/"+svc.Name)
+	return svc.DeepCopy(), nil
+}
+
+func (s dryRunServices) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	s.dryRun.write("delete", "service", s.namespace+"/"+name)
+	return nil
+}
+
+func (s dryRunServices) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*corev1.Service, error) {
+	s.dryRun.write(patchVerb(pt), "service", s.namespace+"/"+name)
+	return &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: s.namespace}}, nil
+}
+
+func (e dryRunEndpoints) Create(ctx context.Context, ep *corev1.Endpoints, opts metav1.CreateOptions) (*corev1.Endpoints, error) {
+	e.dryRun.write("create", "endpoints", e.namespace+"/"+ep.Name)
+	return ep.DeepCopy(), nil
+}
+
+func (e dryRunEndpoints) Update(ctx context.Context, ep *corev1.Endpoints, opts metav1.UpdateOptions) (*corev1.Endpoints, error) {
+	e.dryRun.write("update", "endpoints", e.namespace+"/"+ep.Name)
+	return ep.DeepCopy(), nil
+}
+
+func (e dryRunEndpoints) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	e.dryRun.write("delete", "endpoints", e.namespace+"/"+name)
+	return nil
+}
+
+func (e dryRunEndpoints) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*corev1.Endpoints, error) {
+	e.dryRun.write(patchVerb(pt), "endpoints", e.namespace+"/"+name)
+	return &corev1.Endpoints{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: e.namespace}}, nil
+}
+
+func (n dryRunNamespaces) Create(ctx context.Context, ns *corev1.Namespace, opts metav1.CreateOptions) (*corev1.Namespace, error) {
+	n.dryRun.write("create", "namespace", ns.Name)
+	return ns.DeepCopy(), nil
+}
+
+func (n dryRunNamespaces) Update(ctx context.Context, ns *corev1.Namespace, opts metav1.UpdateOptions) (*corev1.Namespace, error) {
+	n.dryRun.write("update", "namespace", ns.Name)
+	return ns.DeepCopy(), nil
+}
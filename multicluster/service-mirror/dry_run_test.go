@@ -0,0 +1,65 @@
+package servicemirror
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	logging "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestDryRun(t *testing.T) {
+	localAPI, err := newFakeAPI(`
+apiVersion: v1
+kind: Service
+metadata:
+  name: svc-remote
+  namespace: ns
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	log := logging.WithFields(logging.Fields{"cluster": clusterName})
+	watcher := RemoteClusterServiceWatcher{
+		localAPIClient: localAPI,
+		log:            log,
+		dryRun:         newDryRun(clusterName, log),
+	}
+	ctx := context.Background()
+
+	t.Run("skips creations", func(t *testing.T) {
+		before := testutil.ToFloat64(dryRunWritesCounter.WithLabelValues(clusterName, "create", "endpoints"))
+		ep := &corev1.Endpoints{ObjectMeta: metav1.ObjectMeta{Name: "svc-remote", Namespace: "ns"}}
+		created, err := watcher.localEndpoints("ns").Create(ctx, ep, metav1.CreateOptions{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if created.Name != ep.Name {
+			t.Fatalf("Expected the endpoints to be returned, got %v", created)
+		}
+		if _, err := localAPI.Client.CoreV1().Endpoints("ns").Get(ctx, "svc-remote", metav1.GetOptions{}); err == nil {
+			t.Fatal("Expected the endpoints not to be created")
+		}
+		after := testutil.ToFloat64(dryRunWritesCounter.WithLabelValues(clusterName, "create", "endpoints"))
+		if after != before+1 {
+			t.Fatalf("Expected the dry run counter to be incremented, got %v then %v", before, after)
+		}
+	})
+
+	t.Run("skips deletions", func(t *testing.T) {
+		if err := watcher.localServices("ns").Delete(ctx, "svc-remote", metav1.DeleteOptions{}); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if _, err := localAPI.Client.CoreV1().Services("ns").Get(ctx, "svc-remote", metav1.GetOptions{}); err != nil {
+			t.Fatalf("Expected the service not to be deleted: %s", err)
+		}
+	})
+
+	t.Run("passes reads through", func(t *testing.T) {
+		if _, err := watcher.localServices("ns").Get(ctx, "svc-remote", metav1.GetOptions{}); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	})
+}
@@ -0,0 +1,284 @@
+package servicemirror
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	"github.com/linkerd/linkerd2/pkg/multicluster"
+	logging "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/version"
+	"k8s.io/client-go/discovery"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	typeddiscoveryv1beta1 "k8s.io/client-go/kubernetes/typed/discovery/v1beta1"
+)
+
+// endpointSliceManagedBy is the value of the managed-by label of the
+// EndpointSlices written by the service mirror.
+const endpointSliceManagedBy = "linkerd-service-mirror"
+
+// endpointSliceMirroringVersion is the first Kubernetes version whose
+// controller manager mirrors the Endpoints of the services without selector,
+// such as the mirror services, into EndpointSlices.
+var endpointSliceMirroringVersion = version.MustParseGeneric("v1.19.0")
+
+type (
+	// endpointsCapabilities describes how a cluster exposes the endpoints of
+	// its services.
+	endpointsCapabilities struct {
+		// slices is true if the cluster serves EndpointSlices
+		slices bool
+		// mirroring is true if the cluster mirrors Endpoints into
+		// EndpointSlices on its own
+		mirroring bool
+	}
+
+	// slicingEndpoints writes, along with each mirror Endpoints, the
+	// EndpointSlices holding the same addresses, for the local clusters that
+	// serve EndpointSlices but don't mirror Endpoints into them.
+	slicingEndpoints struct {
+		typedcorev1.EndpointsInterface
+		slices    typeddiscoveryv1beta1.EndpointSliceInterface
+		namespace string
+		backoff   WriteBackoffConfig
+		log       *logging.Entry
+	}
+)
+
+// detectEndpointsCapabilities returns how the cluster behind the given
+// discovery client exposes the endpoints of its services.
+func detectEndpointsCapabilities(client discovery.DiscoveryInterface) (endpointsCapabilities, error) {
+	gv := discoveryv1beta1.SchemeGroupVersion.String()
+	res, err := client.ServerResourcesForGroupVersion(gv)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return endpointsCapabilities{}, nil
+		}
+		return endpointsCapabilities{}, err
+	}
+	caps := endpointsCapabilities{}
+	for _, apiRes := range res.APIResources {
+		if apiRes.Kind == "EndpointSlice" {
+			caps.slices = true
+		}
+	}
+	if !caps.slices {
+		return caps, nil
+	}
+
+	info, err := client.ServerVersion()
+	if err != nil {
+		return caps, err
+	}
+	v, err := version.ParseGeneric(info.GitVersion)
+	if err != nil {
+		return caps, fmt.Errorf("invalid server version %s: %s", info.GitVersion, err)
+	}
+	caps.mirroring = v.AtLeast(endpointSliceMirroringVersion)
+	return caps, nil
+}
+
+func (c endpointsCapabilities) String() string {
+	switch {
+	case c.mirroring:
+		return "EndpointSlices, mirrored from Endpoints"
+	case c.slices:
+		return "EndpointSlices, without mirroring from Endpoints"
+	default:
+		return "Endpoints only"
+	}
+}
+
+// writesSlices returns whether the service mirror has to write the
+// EndpointSlices of the mirror services itself in a local cluster with the
+// given capabilities.
+func (c endpointsCapabilities) writesSlices() bool {
+	return c.slices && !c.mirroring
+}
+
+// endpointsCondition returns the Link status condition reporting how the
+// endpoints of the mirror services are exposed, given the capabilities of the
+// local and target clusters.
+func endpointsCondition(local, remote endpointsCapabilities) metav1.Condition {
+	condition := metav1.Condition{
+		Type:    multicluster.LinkConditionEndpointSlices,
+		Status:  metav1.ConditionTrue,
+		Message: fmt.Sprintf("local cluster: %s; target cluster: %s", local, remote),
+	}
+	switch {
+	case local.mirroring:
+		condition.Reason = "MirroredByKubernetes"
+	case local.slices:
+		condition.Reason = "WrittenByServiceMirror"
+	default:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "EndpointsOnly"
+	}
+	return condition
+}
+
+// negotiateEndpoints detects how the local and target clusters expose the
+// endpoints of their services, records it in the Link status, and returns the
+// capabilities of the local cluster. Only the local capabilities matter to
+// the mirrors, as the endpoints of the target cluster are never read, but
+// both are reported to ease troubleshooting.
+func (rcsw *RemoteClusterServiceWatcher) negotiateEndpoints(ctx context.Context) endpointsCapabilities {
+	local, err := detectEndpointsCapabilities(rcsw.localAPIClient.Client.Discovery())
+	if err != nil {
+		rcsw.log.Warnf("Failed to detect the EndpointSlice support of the local cluster, assuming Endpoints only: %s", err)
+	}
+	remote, err := detectEndpointsCapabilities(rcsw.remoteAPIClient.Client.Discovery())
+	if err != nil {
+		rcsw.log.Warnf("Failed to detect the EndpointSlice support of the target cluster: %s", err)
+	}
+	rcsw.log.Infof("Local cluster: %s; target cluster: %s", local, remote)
+
+	if rcsw.linkClient != nil {
+		condition := endpointsCondition(local, remote)
+		if err := multicluster.SetLinkCondition(ctx, rcsw.linkClient, rcsw.link.Namespace, rcsw.link.Name, condition); err != nil {
+			rcsw.log.Errorf("Failed to update %s condition on Link %s: %s", condition.Type, rcsw.link.Name, err)
+		}
+	}
+	return local
+}
+
+// endpointSlicesFor returns the EndpointSlices holding the addresses of the
+// given Endpoints, one per address family, keyed by address type. The
+// families without addresses map to nil.
+func endpointSlicesFor(ep *corev1.Endpoints) map[discoveryv1beta1.AddressType]*discoveryv1beta1.EndpointSlice {
+	slices := map[discoveryv1beta1.AddressType]*discoveryv1beta1.EndpointSlice{
+		discoveryv1beta1.AddressTypeIPv4: nil,
+		discoveryv1beta1.AddressTypeIPv6: nil,
+	}
+	ready := true
+	for _, subset := range ep.Subsets {
+		var ports []discoveryv1beta1.EndpointPort
+		for _, port := range subset.Ports {
+			port := port
+			ports = append(ports, discoveryv1beta1.EndpointPort{
+				Name:     &port.Name,
+				Port:     &port.Port,
+				Protocol: &port.Protocol,
+			})
+		}
+		for _, address := range subset.Addresses {
+			addressType := discoveryv1beta1.AddressTypeIPv4
+			if ip := net.ParseIP(address.IP); ip != nil && ip.To4() == nil {
+				addressType = discoveryv1beta1.AddressTypeIPv6
+			}
+			slice := slices[addressType]
+			if slice == nil {
+				labels := map[string]string{
+					discoveryv1beta1.LabelServiceName: ep.Name,
+					discoveryv1beta1.LabelManagedBy:   endpointSliceManagedBy,
+				}
+				for k, v := range ep.Labels {
+					labels[k] = v
+				}
+				slice = &discoveryv1beta1.EndpointSlice{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        endpointSliceName(ep.Name, addressType),
+						Namespace:   ep.Namespace,
+						Labels:      labels,
+						Annotations: ep.Annotations,
+					},
+					AddressType: addressType,
+					Ports:       ports,
+				}
+				slices[addressType] = slice
+			}
+			slice.Endpoints = append(slice.Endpoints, discoveryv1beta1.Endpoint{
+				Addresses:  []string{address.IP},
+				Conditions: discoveryv1beta1.EndpointConditions{Ready: &ready},
+				Hostname:   stringOrNil(address.Hostname),
+			})
+		}
+	}
+	return slices
+}
+
+func endpointSliceName(endpointsName string, addressType discoveryv1beta1.AddressType) string {
+	if addressType == discoveryv1beta1.AddressTypeIPv6 {
+		return endpointsName + "-ipv6"
+	}
+	return endpointsName
+}
+
+func stringOrNil(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// syncSlices creates, updates or deletes the EndpointSlices of the given
+// Endpoints so that they hold the same addresses.
+func (e slicingEndpoints) syncSlices(ctx context.Context, ep *corev1.Endpoints) error {
+	for addressType, slice := range endpointSlicesFor(ep) {
+		name := endpointSliceName(ep.Name, addressType)
+		key := e.namespace + "/" + name
+		if slice == nil {
+			err := e.backoff.retry(ctx, e.log, "delete", "endpointslice", key, func() error {
+				return e.slices.Delete(ctx, name, metav1.DeleteOptions{})
+			})
+			if err != nil && !kerrors.IsNotFound(err) {
+				return err
+			}
+			continue
+		}
+
+		err := e.backoff.retry(ctx, e.log, "update", "endpointslice", key, func() error {
+			existing, err := e.slices.Get(ctx, name, metav1.GetOptions{})
+			if kerrors.IsNotFound(err) {
+				_, err = e.slices.Create(ctx, slice, metav1.CreateOptions{})
+				return err
+			}
+			if err != nil {
+				return err
+			}
+			slice.ResourceVersion = existing.ResourceVersion
+			_, err = e.slices.Update(ctx, slice, metav1.UpdateOptions{})
+			return err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e slicingEndpoints) Create(ctx context.Context, ep *corev1.Endpoints, opts metav1.CreateOptions) (*corev1.Endpoints, error) {
+	created, err := e.EndpointsInterface.Create(ctx, ep, opts)
+	if err != nil {
+		return created, err
+	}
+	return created, e.syncSlices(ctx, created)
+}
+
+func (e slicingEndpoints) Update(ctx context.Context, ep *corev1.Endpoints, opts metav1.UpdateOptions) (*corev1.Endpoints, error) {
+	updated, err := e.EndpointsInterface.Update(ctx, ep, opts)
+	if err != nil {
+		return updated, err
+	}
+	return updated, e.syncSlices(ctx, updated)
+}
+
+func (e slicingEndpoints) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*corev1.Endpoints, error) {
+	patched, err := e.EndpointsInterface.Patch(ctx, name, pt, data, opts, subresources...)
+	if err != nil {
+		return patched, err
+	}
+	return patched, e.syncSlices(ctx, patched)
+}
+
+func (e slicingEndpoints) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	if err := e.EndpointsInterface.Delete(ctx, name, opts); err != nil {
+		return err
+	}
+	return e.syncSlices(ctx, &corev1.Endpoints{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: e.namespace}})
+}
@@ -0,0 +1,131 @@
+package servicemirror
+
+import (
+	"context"
+	"testing"
+
+	logging "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1beta1 "k8s.io/api/discovery/v1beta1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDetectEndpointsCapabilities(t *testing.T) {
+	for _, tc := range []struct {
+		description string
+		resources   []string
+		version     string
+		expected    endpointsCapabilities
+	}{
+		{
+			description: "no EndpointSlices",
+			resources:   []string{"Endpoints"},
+			version:     "v1.16.3",
+			expected:    endpointsCapabilities{},
+		},
+		{
+			description: "EndpointSlices without mirroring",
+			resources:   []string{"EndpointSlice"},
+			version:     "v1.18.6",
+			expected:    endpointsCapabilities{slices: true},
+		},
+		{
+			description: "EndpointSlices with mirroring",
+			resources:   []string{"EndpointSlice"},
+			version:     "v1.21.1-gke.1800",
+			expected:    endpointsCapabilities{slices: true, mirroring: true},
+		},
+	} {
+		tc := tc
+		t.Run(tc.description, func(t *testing.T) {
+			client := fake.NewSimpleClientset().Discovery().(*fakediscovery.FakeDiscovery)
+			client.FakedServerVersion = &version.Info{GitVersion: tc.version}
+			list := &metav1.APIResourceList{GroupVersion: discoveryv1beta1.SchemeGroupVersion.String()}
+			for _, kind := range tc.resources {
+				list.APIResources = append(list.APIResources, metav1.APIResource{Kind: kind})
+			}
+			client.Resources = []*metav1.APIResourceList{list}
+
+			caps, err := detectEndpointsCapabilities(client)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if caps != tc.expected {
+				t.Errorf("Expected %+v, got %+v", tc.expected, caps)
+			}
+		})
+	}
+}
+
+func TestSlicingEndpoints(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset()
+	endpoints := slicingEndpoints{
+		EndpointsInterface: client.CoreV1().Endpoints("ns1"),
+		slices:             client.DiscoveryV1beta1().EndpointSlices("ns1"),
+		namespace:          "ns1",
+		backoff:            DefaultWriteBackoffConfig(),
+		log:                logging.WithField("test", t.Name()),
+	}
+
+	ep := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "service-one-remote",
+			Namespace: "ns1",
+			Labels:    map[string]string{"mirror.linkerd.io/mirrored-service": "true"},
+		},
+		Subsets: []corev1.EndpointSubset{{
+			Addresses: []corev1.EndpointAddress{{IP: "192.0.2.127"}, {IP: "2001:db8::1"}},
+			Ports:     []corev1.EndpointPort{{Name: "http", Port: 888, Protocol: "TCP"}},
+		}},
+	}
+	if _, err := endpoints.Create(ctx, ep, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	for name, address := range map[string]string{
+		"service-one-remote":      "192.0.2.127",
+		"service-one-remote-ipv6": "2001:db8::1",
+	} {
+		slice, err := client.DiscoveryV1beta1().EndpointSlices("ns1").Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if slice.Labels[discoveryv1beta1.LabelServiceName] != "service-one-remote" {
+			t.Errorf("Expected slice %s to belong to service-one-remote, got labels %v", name, slice.Labels)
+		}
+		if slice.Labels["mirror.linkerd.io/mirrored-service"] != "true" {
+			t.Errorf("Expected slice %s to carry the mirror labels, got %v", name, slice.Labels)
+		}
+		if len(slice.Endpoints) != 1 || slice.Endpoints[0].Addresses[0] != address {
+			t.Errorf("Expected slice %s to hold %s, got %+v", name, address, slice.Endpoints)
+		}
+		if len(slice.Ports) != 1 || *slice.Ports[0].Port != 888 {
+			t.Errorf("Expected slice %s to expose port 888, got %+v", name, slice.Ports)
+		}
+	}
+
+	ep.Subsets[0].Addresses = ep.Subsets[0].Addresses[:1]
+	if _, err := endpoints.Update(ctx, ep, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	_, err := client.DiscoveryV1beta1().EndpointSlices("ns1").Get(ctx, "service-one-remote-ipv6", metav1.GetOptions{})
+	if !kerrors.IsNotFound(err) {
+		t.Errorf("Expected the IPv6 slice to be deleted, got %v", err)
+	}
+
+	if err := endpoints.Delete(ctx, "service-one-remote", metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	slices, err := client.DiscoveryV1beta1().EndpointSlices("ns1").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(slices.Items) != 0 {
+		t.Errorf("Expected no slice left, got %d", len(slices.Items))
+	}
+}
@@ -87,3 +87,7 @@ func (od OnDeleteCalled) String() string {
 func (re RepairEndpoints) String() string {
 	return "RepairEndpoints"
 }
+
+func (mdc MirrorDriftCheck) String() string {
+	return "MirrorDriftCheck"
+}
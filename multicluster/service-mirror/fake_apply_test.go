@@ -0,0 +1,74 @@
+package servicemirror
+
+import (
+	"encoding/json"
+	"reflect"
+
+	jsonpatch "github.com/evanphx/json-patch"
+	"github.com/linkerd/linkerd2/controller/k8s"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newFakeAPI returns a fake API of the local cluster backed by the given
+// resources, whose clientset emulates the server-side applies of the mirror
+// resources.
+func newFakeAPI(configs ...string) (*k8s.API, error) {
+	api, err := k8s.NewFakeAPI(configs...)
+	if err != nil {
+		return nil, err
+	}
+	client := api.Client.(*fake.Clientset)
+	client.PrependReactor("patch", "*", applyPatchReactor(client.Tracker()))
+	return api, nil
+}
+
+// applyPatchReactor emulates the server-side applies, which the fake
+// clientsets don't support, by creating the applied object if it doesn't
+// exist and merging it into the existing one otherwise. Unlike a real apply,
+// the fields missing from the applied object are never removed.
+func applyPatchReactor(tracker k8stesting.ObjectTracker) k8stesting.ReactionFunc {
+	return func(action k8stesting.Action) (bool, runtime.Object, error) {
+		patch, ok := action.(k8stesting.PatchAction)
+		if !ok || patch.GetPatchType() != types.ApplyPatchType {
+			return false, nil, nil
+		}
+		gvr, ns := action.GetResource(), action.GetNamespace()
+
+		existing, err := tracker.Get(gvr, ns, patch.GetName())
+		if kerrors.IsNotFound(err) {
+			obj, _, err := scheme.Codecs.UniversalDeserializer().Decode(patch.GetPatch(), nil, nil)
+			if err != nil {
+				return true, nil, err
+			}
+			if err := tracker.Create(gvr, obj, ns); err != nil {
+				return true, nil, err
+			}
+			return true, obj, nil
+		}
+		if err != nil {
+			return true, nil, err
+		}
+
+		current, err := json.Marshal(existing)
+		if err != nil {
+			return true, nil, err
+		}
+		merged, err := jsonpatch.MergePatch(current, patch.GetPatch())
+		if err != nil {
+			return true, nil, err
+		}
+		obj := reflect.New(reflect.TypeOf(existing).Elem()).Interface().(runtime.Object)
+		if err := json.Unmarshal(merged, obj); err != nil {
+			return true, nil, err
+		}
+		if err := tracker.Update(gvr, obj, ns); err != nil {
+			return true, nil, err
+		}
+		return true, obj, nil
+	}
+}
@@ -0,0 +1,322 @@
+package servicemirror
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	logging "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+)
+
+// faultsEnvVar enables fault injection in the cluster watchers. It is meant
+// for e2e tests and game days only, and holds a comma-separated list of
+// faults, each one applying to a fraction of the operations:
+//
+//	write-error=<rate>           fail the writes to the local API
+//	write-delay=<rate>:<delay>   delay the writes to the local API
+//	event-drop=<rate>            drop the events of the remote informer
+//	event-delay=<rate>:<delay>   delay the events of the remote informer
+//
+// e.g. "write-error=0.1,event-delay=0.5:2s"
+const faultsEnvVar = "LINKERD2_SERVICE_MIRROR_FAULTS"
+
+type (
+	// fault is applied to a fraction of the operations, given by rate.
+	fault struct {
+		rate  float64
+		delay time.Duration
+	}
+
+	// faultInjector delays or fails a fraction of the local API writes and
+	// of the remote informer events of a cluster watcher, so that its retry
+	// and garbage collection logic can be exercised. A nil faultInjector
+	// injects no faults.
+	faultInjector struct {
+		writeError fault
+		writeDelay fault
+		eventDrop  fault
+		eventDelay fault
+
+		log *logging.Entry
+
+		sync.Mutex
+		rand *rand.Rand
+	}
+
+	faultyServices struct {
+		typedcorev1.ServiceInterface
+		namespace string
+		faults    *faultInjector
+	}
+
+	faultyEndpoints struct {
+		typedcorev1.EndpointsInterface
+		namespace string
+		faults    *faultInjector
+	}
+
+	faultyNamespaces struct {
+		typedcorev1.NamespaceInterface
+		faults *faultInjector
+	}
+)
+
+// newFaultInjectorFromEnv returns the faultInjector configured by
+// faultsEnvVar, or nil if it isn't set.
+func newFaultInjectorFromEnv(log *logging.Entry) (*faultInjector, error) {
+	spec := strings.TrimSpace(os.Getenv(faultsEnvVar))
+	if spec == "" {
+		return nil, nil
+	}
+	faults, err := parseFaults(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %s", faultsEnvVar, err)
+	}
+	faults.log = log
+	log.Warnf("Fault injection enabled: %s", spec)
+	return faults, nil
+}
+
+func parseFaults(spec string) (*faultInjector, error) {
+	faults := &faultInjector{
+		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, entry := range strings.Split(spec, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("expected <fault>=<value>, got %q", entry)
+		}
+		name, value := parts[0], parts[1]
+
+		var target *fault
+		withDelay := false
+		switch name {
+		case "write-error":
+			target = &faults.writeError
+		case "write-delay":
+			target, withDelay = &faults.writeDelay, true
+		case "event-drop":
+			target = &faults.eventDrop
+		case "event-delay":
+			target, withDelay = &faults.eventDelay, true
+		default:
+			return nil, fmt.Errorf("unknown fault %q", name)
+		}
+
+		if withDelay {
+			rateAndDelay := strings.SplitN(value, ":", 2)
+			if len(rateAndDelay) != 2 {
+				return nil, fmt.Errorf("expected %s=<rate>:<delay>, got %q", name, entry)
+			}
+			delay, err := time.ParseDuration(rateAndDelay[1])
+			if err != nil || delay <= 0 {
+				return nil, fmt.Errorf("invalid delay for %s: %q", name, rateAndDelay[1])
+			}
+			target.delay = delay
+			value = rateAndDelay[0]
+		}
+
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("invalid rate for %s: %q, must be between 0 and 1", name, value)
+		}
+		target.rate = rate
+	}
+	return faults, nil
+}
+
+func (fi *faultInjector) roll(f fault) bool {
+	if f.rate == 0 {
+		return false
+	}
+	fi.Lock()
+	defer fi.Unlock()
+	return fi.rand.Float64() < f.rate
+}
+
+// write is called before each write to the local API. It may sleep, and
+// returns an error if the write must fail.
+func (fi *faultInjector) write(ctx context.Context, verb, resource, key string) error {
+	if fi == nil {
+		return nil
+	}
+	if fi.roll(fi.writeDelay) {
+		fi.log.Warnf("Fault injection: delaying %s of %s %s by %s", verb, resource, key, fi.writeDelay.delay)
+		select {
+		case <-time.After(fi.writeDelay.delay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if fi.roll(fi.writeError) {
+		fi.log.Warnf("Fault injection: failing %s of %s %s", verb, resource, key)
+		return fmt.Errorf("injected fault: %s of %s %s failed", verb, resource, key)
+	}
+	return nil
+}
+
+// event returns whether a remote informer event must be dropped, and
+// otherwise the delay after which it must be enqueued.
+func (fi *faultInjector) event(ev interface{}) (bool, time.Duration) {
+	if fi == nil {
+		return false, 0
+	}
+	if fi.roll(fi.eventDrop) {
+		fi.log.Warnf("Fault injection: dropping %s", ev)
+		return true, 0
+	}
+	if fi.roll(fi.eventDelay) {
+		fi.log.Warnf("Fault injection: delaying %s by %s", ev, fi.eventDelay.delay)
+		return false, fi.eventDelay.delay
+	}
+	return false, 0
+}
+
+func (s faultyServices) Create(ctx context.Context, svc *corev1.Service, opts metav1.CreateOptions) (*corev1.Service, error) {
+	if err := s.faults.write(ctx, "create", "service", s.namespace+"/"+svc.Name); err != nil {
+		return nil, err
+	}
+	return s.ServiceInterface.Create(ctx, svc, opts)
+}
+
+func (s faultyServices) Update(ctx context.Context, svc *corev1.Service, opts metav1.UpdateOptions) (*corev1.Service, error) {
+	if err := s.faults.write(ctx, "update", "service", s.namespace+"/"+svc.Name); err != nil {
+		return nil, err
+	}
+	return s.ServiceInterface.Update(ctx, svc, opts)
+}
+
+func (s faultyServices) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	if err := s.faults.write(ctx, "delete", "service", s.namespace+"/"+name); err != nil {
+		return err
+	}
+	return s.ServiceInterface.Delete(ctx, name, opts)
+}
+
+func (s faultyServices) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*corev1.Service, error) {
+	if err := s.faults.write(ctx, patchVerb(pt), "service", s.namespace+"/"+name); err != nil {
+		return nil, err
+	}
+	return s.ServiceInterface.Patch(ctx, name, pt, data, opts, subresources...)
+}
+
+func (e faultyEndpoints) Create(ctx context.Context, ep *corev1.Endpoints, opts metav1.CreateOptions) (*corev1.Endpoints, error) {
+	if err := e.faults.write(ctx, "create", "endpoints", e.namespace+"/"+ep.Name); err != nil {
+		return nil, err
+	}
+	return e.EndpointsInterface.Create(ctx, ep, opts)
+}
+
+func (e faultyEndpoints) Update(ctx context.Context, ep *corev1.Endpoints, opts metav1.UpdateOptions) (*corev1.Endpoints, error) {
+	if err := e.faults.write(ctx, "update", "endpoints", e.namespace+"/"+ep.Name); err != nil {
+		return nil, err
+	}
+	return e.EndpointsInterface.Update(ctx, ep, opts)
+}
+
+func (e faultyEndpoints) Delete(ctx context.Context, name string, opts metav1.DeleteOptions) error {
+	if err := e.faults.write(ctx, "delete", "endpoints", e.namespace+"/"+name); err != nil {
+		return err
+	}
+	return e.EndpointsInterface.Delete(ctx, name, opts)
+}
+
+func (e faultyEndpoints) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*corev1.Endpoints, error) {
+	if err := e.faults.write(ctx, patchVerb(pt), "endpoints", e.namespace+"/"+name); err != nil {
+		return nil, err
+	}
+	return e.EndpointsInterface.Patch(ctx, name, pt, data, opts, subresources...)
+}
+
+func (n faultyNamespaces) Create(ctx context.Context, ns *corev1.Namespace, opts metav1.CreateOptions) (*corev1.Namespace, error) {
+	if err := n.faults.write(ctx, "create", "namespace", ns.Name); err != nil {
+		return nil, err
+	}
+	return n.NamespaceInterface.Create(ctx, ns, opts)
+}
+
+func (n faultyNamespaces) Update(ctx context.Context, ns *corev1.Namespace, opts metav1.UpdateOptions) (*corev1.Namespace, error) {
+	if err := n.faults.write(ctx, "update", "namespace", ns.Name); err != nil {
+		return nil, err
+	}
+	return n.NamespaceInterface.Update(ctx, ns, opts)
+}
+
+// localServices returns the client for the local services in the namespace,
+// through which faults are injected, throttled writes retried, and dry runs
+// handled when enabled.
+func (rcsw *RemoteClusterServiceWatcher) localServices(namespace string) typedcorev1.ServiceInterface {
+	var client typedcorev1.ServiceInterface = rcsw.localAPIClient.Client.CoreV1().Services(namespace)
+	if rcsw.faults != nil {
+		client = faultyServices{client, namespace, rcsw.faults}
+	}
+	if rcsw.writeBackoff.Attempts > 1 {
+		client = retryingServices{client, namespace, rcsw.writeBackoff, rcsw.log}
+	}
+	if rcsw.dryRun != nil {
+		client = dryRunServices{client, namespace, rcsw.dryRun}
+	}
+	return client
+}
+
+// localEndpoints returns the client for the local endpoints in the
+// namespace, through which faults are injected, throttled writes retried,
+// EndpointSlices written along when the local cluster doesn't, and dry runs
+// handled when enabled.
+func (rcsw *RemoteClusterServiceWatcher) localEndpoints(namespace string) typedcorev1.EndpointsInterface {
+	var client typedcorev1.EndpointsInterface = rcsw.localAPIClient.Client.CoreV1().Endpoints(namespace)
+	if rcsw.faults != nil {
+		client = faultyEndpoints{client, namespace, rcsw.faults}
+	}
+	if rcsw.writeBackoff.Attempts > 1 {
+		client = retryingEndpoints{client, namespace, rcsw.writeBackoff, rcsw.log}
+	}
+	if rcsw.writeSlices {
+		slices := rcsw.localAPIClient.Client.DiscoveryV1beta1().EndpointSlices(namespace)
+		client = slicingEndpoints{client, slices, namespace, rcsw.writeBackoff, rcsw.log}
+	}
+	if rcsw.dryRun != nil {
+		client = dryRunEndpoints{client, namespace, rcsw.dryRun}
+	}
+	return client
+}
+
+// localNamespaces returns the client for the local namespaces, through which
+// faults are injected, throttled writes retried, and dry runs handled when
+// enabled.
+func (rcsw *RemoteClusterServiceWatcher) localNamespaces() typedcorev1.NamespaceInterface {
+	var client typedcorev1.NamespaceInterface = rcsw.localAPIClient.Client.CoreV1().Namespaces()
+	if rcsw.faults != nil {
+		client = faultyNamespaces{client, rcsw.faults}
+	}
+	if rcsw.writeBackoff.Attempts > 1 {
+		client = retryingNamespaces{client, rcsw.writeBackoff, rcsw.log}
+	}
+	if rcsw.dryRun != nil {
+		client = dryRunNamespaces{client, rcsw.dryRun}
+	}
+	return client
+}
+
+// enqueueRemoteEvent adds an event triggered by the remote informer to the
+// queue, unless the fault injector drops or delays it.
+func (rcsw *RemoteClusterServiceWatcher) enqueueRemoteEvent(ev interface{}) {
+	drop, delay := rcsw.faults.event(ev)
+	if drop {
+		return
+	}
+	if delay > 0 {
+		rcsw.eventsQueue.AddAfter(ev, delay)
+		return
+	}
+	rcsw.eventsQueue.Add(ev)
+}
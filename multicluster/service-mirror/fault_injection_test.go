@@ -0,0 +1,97 @@
+package servicemirror
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	logging "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestParseFaults(t *testing.T) {
+	faults, err := parseFaults("write-error=0.1, write-delay=0.2:2s,event-drop=1,event-delay=0:1s")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := []struct {
+		name     string
+		actual   fault
+		expected fault
+	}{
+		{"write-error", faults.writeError, fault{rate: 0.1}},
+		{"write-delay", faults.writeDelay, fault{rate: 0.2, delay: 2 * time.Second}},
+		{"event-drop", faults.eventDrop, fault{rate: 1}},
+		{"event-delay", faults.eventDelay, fault{delay: time.Second}},
+	}
+	for _, e := range expected {
+		if e.actual != e.expected {
+			t.Errorf("Expected %s to be %+v, got %+v", e.name, e.expected, e.actual)
+		}
+	}
+
+	for _, spec := range []string{
+		"write-error",
+		"write-error=2",
+		"write-error=-0.5",
+		"write-delay=0.5",
+		"write-delay=0.5:forever",
+		"event-delay=0.5:0s",
+		"network-split=0.5",
+	} {
+		if _, err := parseFaults(spec); err == nil {
+			t.Errorf("Expected an error parsing %q", spec)
+		}
+	}
+}
+
+func TestFaultInjection(t *testing.T) {
+	localAPI, err := newFakeAPI()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	faults, err := parseFaults("write-error=1,event-drop=1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	faults.log = logging.WithFields(logging.Fields{"cluster": clusterName})
+
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	watcher := RemoteClusterServiceWatcher{
+		localAPIClient: localAPI,
+		log:            logging.WithFields(logging.Fields{"cluster": clusterName}),
+		eventsQueue:    queue,
+		faults:         faults,
+	}
+
+	t.Run("fails local writes", func(t *testing.T) {
+		svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "svc-remote", Namespace: "ns"}}
+		if _, err := watcher.localServices("ns").Create(context.Background(), svc, metav1.CreateOptions{}); err == nil {
+			t.Fatal("Expected the creation of the service to fail")
+		}
+		if _, err := localAPI.Client.CoreV1().Services("ns").Get(context.Background(), "svc-remote", metav1.GetOptions{}); err == nil {
+			t.Fatal("Expected the service not to be created")
+		}
+	})
+
+	t.Run("drops remote events", func(t *testing.T) {
+		watcher.enqueueRemoteEvent(&OnAddCalled{&corev1.Service{}})
+		if queue.Len() != 0 {
+			t.Fatalf("Expected the event to be dropped, got %d queued events", queue.Len())
+		}
+	})
+
+	t.Run("passes through when disabled", func(t *testing.T) {
+		watcher.faults = nil
+		svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "svc-remote", Namespace: "ns"}}
+		if _, err := watcher.localServices("ns").Create(context.Background(), svc, metav1.CreateOptions{}); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		watcher.enqueueRemoteEvent(&OnAddCalled{svc})
+		if queue.Len() != 1 {
+			t.Fatalf("Expected the event to be queued, got %d queued events", queue.Len())
+		}
+	})
+}
@@ -0,0 +1,225 @@
+package servicemirror
+
+import (
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/linkerd/linkerd2/pkg/multicluster"
+	logging "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// gatewayAddressProbeTimeout bounds the probes of the individual
+	// gateway addresses, unless the Link's probe spec has a timeout.
+	gatewayAddressProbeTimeout = 5 * time.Second
+
+	// gatewayAddressFailureThreshold is the number of consecutive failed
+	// probes after which a gateway address is considered unhealthy, unless
+	// the Link's probe spec has a failure threshold. A single successful
+	// probe makes it healthy again, unless the probe spec has a success
+	// threshold.
+	gatewayAddressFailureThreshold = 3
+)
+
+// gatewayHealth tracks the probe results of the individual addresses of a
+// Link's gateway, so that the addresses failing their probes repeatedly can
+// be left out of the mirrored Endpoints.
+type gatewayHealth struct {
+	sync.RWMutex
+	failures  map[string]int
+	successes map[string]int
+	unhealthy map[string]struct{}
+	probe     func(ip string, spec multicluster.ProbeSpec) error
+	log       *logging.Entry
+}
+
+// newGatewayHealth returns the tracker of the health of the addresses of a
+// gateway with the given identity, which the probes verify when their spec
+// says so.
+func newGatewayHealth(log *logging.Entry, identity string) *gatewayHealth {
+	return &gatewayHealth{
+		failures:  make(map[string]int),
+		successes: make(map[string]int),
+		unhealthy: make(map[string]struct{}),
+		probe: func(ip string, spec multicluster.ProbeSpec) error {
+			return probeGatewayAddress(ip, spec, identity)
+		},
+		log: log,
+	}
+}
+
+// update probes each of the given addresses and records the results,
+// forgetting about the addresses that are no longer part of the gateway. It
+// returns whether any address became unhealthy or recovered.
+func (gh *gatewayHealth) update(addresses []corev1.EndpointAddress, spec multicluster.ProbeSpec) bool {
+	if gh == nil {
+		return false
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failed := make(map[string]struct{})
+	for _, addr := range addresses {
+		wg.Add(1)
+		go func(ip string) {
+			defer wg.Done()
+			if err := gh.probe(ip, spec); err != nil {
+				gh.log.Warnf("Gateway address %s failed its probe: %s", ip, err)
+				mu.Lock()
+				failed[ip] = struct{}{}
+				mu.Unlock()
+			}
+		}(addr.IP)
+	}
+	wg.Wait()
+
+	gh.Lock()
+	defer gh.Unlock()
+
+	failureThreshold := gatewayAddressFailureThreshold
+	if spec.FailureThreshold > 0 {
+		failureThreshold = int(spec.FailureThreshold)
+	}
+	successThreshold := 1
+	if spec.SuccessThreshold > 0 {
+		successThreshold = int(spec.SuccessThreshold)
+	}
+
+	changed := false
+	failures := make(map[string]int)
+	successes := make(map[string]int)
+	unhealthy := make(map[string]struct{})
+	for _, addr := range addresses {
+		_, wasUnhealthy := gh.unhealthy[addr.IP]
+		if _, ok := failed[addr.IP]; !ok {
+			if !wasUnhealthy {
+				continue
+			}
+			successes[addr.IP] = gh.successes[addr.IP] + 1
+			if successes[addr.IP] < successThreshold {
+				unhealthy[addr.IP] = struct{}{}
+				continue
+			}
+			gh.log.Infof("Gateway address %s recovered, restoring it in the mirrored endpoints", addr.IP)
+			changed = true
+			continue
+		}
+		failures[addr.IP] = gh.failures[addr.IP] + 1
+		if wasUnhealthy || failures[addr.IP] >= failureThreshold {
+			unhealthy[addr.IP] = struct{}{}
+			if !wasUnhealthy {
+				gh.log.Warnf("Gateway address %s failed %d consecutive probes, removing it from the mirrored endpoints", addr.IP, failures[addr.IP])
+				changed = true
+			}
+		}
+	}
+	if len(unhealthy) != len(gh.unhealthy) {
+		// some unhealthy addresses are no longer part of the gateway
+		changed = true
+	}
+	gh.failures = failures
+	gh.successes = successes
+	gh.unhealthy = unhealthy
+	return changed
+}
+
+// healthy filters out the addresses that failed their latest probe. When
+// every address is unhealthy, all of them are returned: it's better to keep
+// routing to a gateway that may be failing its probes than to route nowhere.
+func (gh *gatewayHealth) healthy(addresses []corev1.EndpointAddress) []corev1.EndpointAddress {
+	if gh == nil {
+		return addresses
+	}
+
+	gh.RLock()
+	defer gh.RUnlock()
+
+	var healthy []corev1.EndpointAddress
+	for _, addr := range addresses {
+		if _, ok := gh.unhealthy[addr.IP]; !ok {
+			healthy = append(healthy, addr)
+		}
+	}
+	if len(healthy) == 0 {
+		if len(addresses) > 0 {
+			gh.log.Warn("All gateway addresses are unhealthy, keeping all of them")
+		}
+		return addresses
+	}
+	return healthy
+}
+
+// allUnhealthy returns true if every one of the given addresses failed its
+// latest probes.
+func (gh *gatewayHealth) allUnhealthy(addresses []corev1.EndpointAddress) bool {
+	if gh == nil || len(addresses) == 0 {
+		return false
+	}
+
+	gh.RLock()
+	defer gh.RUnlock()
+	for _, addr := range addresses {
+		if _, ok := gh.unhealthy[addr.IP]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func probeGatewayAddress(ip string, spec multicluster.ProbeSpec, identity string) error {
+	timeout := gatewayAddressProbeTimeout
+	if spec.Timeout > 0 {
+		timeout = spec.Timeout
+	}
+	client := http.Client{
+		Timeout: timeout,
+	}
+	host := net.JoinHostPort(ip, strconv.FormatUint(uint64(spec.Port), 10))
+	req, err := newProbeRequest(&spec, host, identity)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if !spec.ExpectsStatus(resp.StatusCode) {
+		return unexpectedStatusError(resp)
+	}
+	return nil
+}
+
+// probeGatewayAddresses probes each of the gateway addresses with the Link's
+// probe spec, at the same period as the ProbeWorker probes the gateway, and
+// repairs the mirrored Endpoints as soon as an address is removed or
+// restored. The ProbeWorker probes the gateway through its mirror, so its
+// results can't be attributed to an address.
+func (rcsw *RemoteClusterServiceWatcher) probeGatewayAddresses() {
+	period := rcsw.link.ProbeSpec.Period
+	if period <= 0 {
+		return
+	}
+	ticker := NewTicker(period, period/10)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			addresses, _, err := rcsw.resolveAllGatewayAddresses()
+			if err != nil {
+				continue
+			}
+			if rcsw.gatewayHealth.update(addresses, rcsw.link.ProbeSpec) {
+				rcsw.repairs.reset()
+				rcsw.eventsQueue.Add(&RepairEndpoints{})
+			}
+		case <-rcsw.stopper:
+			return
+		}
+	}
+}
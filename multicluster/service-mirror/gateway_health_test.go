@@ -0,0 +1,225 @@
+package servicemirror
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/linkerd/linkerd2/pkg/multicluster"
+	logging "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestGatewayHealth(t *testing.T) {
+	addresses := []corev1.EndpointAddress{{IP: "192.0.2.1"}, {IP: "192.0.2.2"}, {IP: "192.0.2.3"}}
+
+	testCases := []struct {
+		name      string
+		unhealthy map[string]bool
+		expected  []corev1.EndpointAddress
+	}{
+		{
+			name:      "all addresses healthy",
+			unhealthy: map[string]bool{},
+			expected:  addresses,
+		},
+		{
+			name:      "unhealthy addresses are removed",
+			unhealthy: map[string]bool{"192.0.2.2": true},
+			expected:  []corev1.EndpointAddress{{IP: "192.0.2.1"}, {IP: "192.0.2.3"}},
+		},
+		{
+			name:      "all addresses are kept when none is healthy",
+			unhealthy: map[string]bool{"192.0.2.1": true, "192.0.2.2": true, "192.0.2.3": true},
+			expected:  addresses,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			gh := newGatewayHealth(logging.WithField("test", t.Name()), "")
+			gh.probe = func(ip string, _ multicluster.ProbeSpec) error {
+				if tc.unhealthy[ip] {
+					return errors.New("probe failed")
+				}
+				return nil
+			}
+
+			for i := 0; i < gatewayAddressFailureThreshold; i++ {
+				gh.update(addresses, multicluster.ProbeSpec{})
+			}
+			healthy := gh.healthy(addresses)
+			if !reflect.DeepEqual(healthy, tc.expected) {
+				t.Fatalf("Expected healthy addresses %v, got %v", tc.expected, healthy)
+			}
+		})
+	}
+}
+
+func TestGatewayHealthFailureThreshold(t *testing.T) {
+	addresses := []corev1.EndpointAddress{{IP: "192.0.2.1"}, {IP: "192.0.2.2"}}
+	failing := true
+	gh := newGatewayHealth(logging.WithField("test", t.Name()), "")
+	gh.probe = func(ip string, _ multicluster.ProbeSpec) error {
+		if ip == "192.0.2.2" && failing {
+			return errors.New("probe failed")
+		}
+		return nil
+	}
+
+	for i := 1; i < gatewayAddressFailureThreshold; i++ {
+		if gh.update(addresses, multicluster.ProbeSpec{}) {
+			t.Fatalf("Expected no change after %d failed probes", i)
+		}
+		if healthy := gh.healthy(addresses); !reflect.DeepEqual(healthy, addresses) {
+			t.Fatalf("Expected all addresses to be healthy after %d failed probes, got %v", i, healthy)
+		}
+	}
+
+	if !gh.update(addresses, multicluster.ProbeSpec{}) {
+		t.Fatal("Expected a change once the failure threshold is reached")
+	}
+	if healthy := gh.healthy(addresses); !reflect.DeepEqual(healthy, []corev1.EndpointAddress{{IP: "192.0.2.1"}}) {
+		t.Fatalf("Expected 192.0.2.2 to be removed, got %v", healthy)
+	}
+	if gh.update(addresses, multicluster.ProbeSpec{}) {
+		t.Fatal("Expected no change while the address keeps failing")
+	}
+
+	failing = false
+	if !gh.update(addresses, multicluster.ProbeSpec{}) {
+		t.Fatal("Expected a change once the address recovers")
+	}
+	if healthy := gh.healthy(addresses); !reflect.DeepEqual(healthy, addresses) {
+		t.Fatalf("Expected 192.0.2.2 to be restored, got %v", healthy)
+	}
+}
+
+func TestHealthyGatewayAddressesWeights(t *testing.T) {
+	gh := newGatewayHealth(logging.WithField("test", t.Name()), "")
+	gh.probe = func(ip string, _ multicluster.ProbeSpec) error {
+		if ip == "192.0.2.2" {
+			return errors.New("probe failed")
+		}
+		return nil
+	}
+	rcsw := RemoteClusterServiceWatcher{gatewayHealth: gh}
+
+	addresses := []corev1.EndpointAddress{{IP: "192.0.2.1"}, {IP: "192.0.2.2"}}
+	for i := 0; i < gatewayAddressFailureThreshold; i++ {
+		gh.update(addresses, multicluster.ProbeSpec{})
+	}
+
+	healthy, weights := rcsw.healthyGatewayAddresses(addresses, map[string]uint32{"192.0.2.1": 3, "192.0.2.2": 1})
+	if !reflect.DeepEqual(healthy, []corev1.EndpointAddress{{IP: "192.0.2.1"}}) {
+		t.Fatalf("Unexpected healthy addresses: %v", healthy)
+	}
+	if !reflect.DeepEqual(weights, map[string]uint32{"192.0.2.1": 3}) {
+		t.Fatalf("Unexpected weights: %v", weights)
+	}
+
+	_, weights = rcsw.healthyGatewayAddresses(addresses, nil)
+	if weights != nil {
+		t.Fatalf("Expected no weights, got %v", weights)
+	}
+}
+
+func TestGatewayHealthProbeSpecThresholds(t *testing.T) {
+	addresses := []corev1.EndpointAddress{{IP: "192.0.2.1"}, {IP: "192.0.2.2"}}
+	spec := multicluster.ProbeSpec{FailureThreshold: 1, SuccessThreshold: 2}
+	failing := true
+	gh := newGatewayHealth(logging.WithField("test", t.Name()), "")
+	gh.probe = func(ip string, _ multicluster.ProbeSpec) error {
+		if ip == "192.0.2.2" && failing {
+			return errors.New("probe failed")
+		}
+		return nil
+	}
+
+	if !gh.update(addresses, spec) {
+		t.Fatal("Expected a change after a single failed probe")
+	}
+
+	failing = false
+	if gh.update(addresses, spec) {
+		t.Fatal("Expected no change before the success threshold is reached")
+	}
+	if healthy := gh.healthy(addresses); !reflect.DeepEqual(healthy, []corev1.EndpointAddress{{IP: "192.0.2.1"}}) {
+		t.Fatalf("Expected 192.0.2.2 to still be removed, got %v", healthy)
+	}
+	if !gh.update(addresses, spec) {
+		t.Fatal("Expected a change once the success threshold is reached")
+	}
+	if healthy := gh.healthy(addresses); !reflect.DeepEqual(healthy, addresses) {
+		t.Fatalf("Expected 192.0.2.2 to be restored, got %v", healthy)
+	}
+}
+
+func TestMirrorGatewayAddressesFailover(t *testing.T) {
+	addresses := []corev1.EndpointAddress{{IP: "192.0.2.1"}, {IP: "192.0.2.2"}}
+	weights := map[string]uint32{"192.0.2.1": 3, "192.0.2.2": 1}
+
+	testCases := []struct {
+		name            string
+		unhealthy       map[string]bool
+		gatewayAlive    func() bool
+		expected        []corev1.EndpointAddress
+		expectedWeights map[string]uint32
+	}{
+		{
+			name:            "all addresses are kept when the liveness isn't shared",
+			unhealthy:       map[string]bool{"192.0.2.1": true, "192.0.2.2": true},
+			expected:        addresses,
+			expectedWeights: weights,
+		},
+		{
+			name:            "all addresses are kept while the gateway is alive",
+			unhealthy:       map[string]bool{"192.0.2.1": true, "192.0.2.2": true},
+			gatewayAlive:    func() bool { return true },
+			expected:        addresses,
+			expectedWeights: weights,
+		},
+		{
+			name:            "healthy addresses are kept when the gateway is dead",
+			unhealthy:       map[string]bool{"192.0.2.2": true},
+			gatewayAlive:    func() bool { return false },
+			expected:        []corev1.EndpointAddress{{IP: "192.0.2.1"}},
+			expectedWeights: map[string]uint32{"192.0.2.1": 3},
+		},
+		{
+			name:         "no address is kept when the gateway and all its addresses are dead",
+			unhealthy:    map[string]bool{"192.0.2.1": true, "192.0.2.2": true},
+			gatewayAlive: func() bool { return false },
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			gh := newGatewayHealth(logging.WithField("test", t.Name()), "")
+			gh.probe = func(ip string, _ multicluster.ProbeSpec) error {
+				if tc.unhealthy[ip] {
+					return errors.New("probe failed")
+				}
+				return nil
+			}
+			for i := 0; i < gatewayAddressFailureThreshold; i++ {
+				gh.update(addresses, multicluster.ProbeSpec{})
+			}
+			rcsw := RemoteClusterServiceWatcher{
+				log:           logging.WithField("test", t.Name()),
+				gatewayHealth: gh,
+				gatewayAlive:  tc.gatewayAlive,
+			}
+
+			mirrored, mirroredWeights := rcsw.mirrorGatewayAddresses(addresses, weights)
+			if !reflect.DeepEqual(mirrored, tc.expected) {
+				t.Fatalf("Expected mirrored addresses %v, got %v", tc.expected, mirrored)
+			}
+			if !reflect.DeepEqual(mirroredWeights, tc.expectedWeights) {
+				t.Fatalf("Expected mirrored weights %v, got %v", tc.expectedWeights, mirroredWeights)
+			}
+		})
+	}
+}
@@ -0,0 +1,410 @@
+package servicemirror
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	logging "github.com/sirupsen/logrus"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	// minGatewayTTL bounds how often the gateway address is re-resolved,
+	// whatever the TTL of its DNS records.
+	minGatewayTTL = 5 * time.Second
+
+	// fallbackGatewayTTL is the TTL assumed for the records resolved through
+	// the system resolver, which doesn't expose them.
+	fallbackGatewayTTL = 30 * time.Second
+
+	// dnsQueryTimeout bounds each query sent to a nameserver.
+	dnsQueryTimeout = 2 * time.Second
+
+	resolvConfPath = "/etc/resolv.conf"
+)
+
+type (
+	// gatewayRecord is the resolution of one entry of a Link's gateway
+	// address.
+	gatewayRecord struct {
+		ips []string
+		// port is the gateway port discovered through an SRV record or the
+		// port suffix of the entry, or 0
+		port uint32
+		// ttl is the time until the records expire, or 0 for IP entries
+		ttl time.Duration
+	}
+
+	// gatewayResolver resolves the entries of a Link's gateway address,
+	// which are IPs, hostnames, or SRV names (starting with an underscore,
+	// e.g. _gateway._tcp.east.example.com) whose port is then used as the
+	// gateway port.
+	//
+	// The nameservers of resolv.conf are queried directly so that the TTLs
+	// of the records are known, and the gateway re-resolved when they
+	// expire. The system resolver is used when they can't be queried.
+	gatewayResolver struct {
+		servers  []string
+		exchange func(ctx context.Context, server string, query []byte) ([]byte, error)
+		log      *logging.Entry
+
+		sync.Mutex
+		// resolved identifies the latest resolution of the gateway address,
+		// to detect changes
+		resolved string
+		port     uint32
+		ttl      time.Duration
+		// condition is the message of the latest GatewayAddressResolved
+		// condition, so that the Link status is only updated on changes
+		condition string
+	}
+)
+
+func newGatewayResolver(log *logging.Entry) *gatewayResolver {
+	servers, err := readNameservers(resolvConfPath)
+	if err != nil {
+		log.Warnf("Failed to read nameservers, DNS TTLs of the gateway address will be ignored: %s", err)
+	}
+	return &gatewayResolver{
+		servers:  servers,
+		exchange: exchangeUDP,
+		log:      log,
+	}
+}
+
+// resolve resolves an entry of the gateway address.
+func (r *gatewayResolver) resolve(ctx context.Context, entry string) (gatewayRecord, error) {
+	if ip := net.ParseIP(entry); ip != nil {
+		return gatewayRecord{ips: []string{ip.String()}}, nil
+	}
+	if strings.HasPrefix(entry, "_") {
+		return r.resolveSRV(ctx, entry)
+	}
+	return r.resolveHost(ctx, entry)
+}
+
+func (r *gatewayResolver) resolveHost(ctx context.Context, host string) (gatewayRecord, error) {
+	if r != nil && len(r.servers) > 0 {
+		record, err := r.queryHost(ctx, host)
+		if err == nil {
+			return record, nil
+		}
+		r.logger().Debugf("Falling back to the system resolver for %s: %s", host, err)
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return gatewayRecord{}, err
+	}
+	record := gatewayRecord{ttl: fallbackGatewayTTL}
+	for _, addr := range addrs {
+		record.ips = append(record.ips, addr.IP.String())
+	}
+	return record, nil
+}
+
+func (r *gatewayResolver) resolveSRV(ctx context.Context, name string) (gatewayRecord, error) {
+	var srvs []*net.SRV
+	ttl := fallbackGatewayTTL
+	if r != nil && len(r.servers) > 0 {
+		var err error
+		srvs, ttl, err = r.querySRV(ctx, name)
+		if err != nil {
+			r.logger().Debugf("Falling back to the system resolver for %s: %s", name, err)
+			srvs = nil
+			ttl = fallbackGatewayTTL
+		}
+	}
+	if srvs == nil {
+		var err error
+		_, srvs, err = net.DefaultResolver.LookupSRV(ctx, "", "", name)
+		if err != nil {
+			return gatewayRecord{}, err
+		}
+	}
+	if len(srvs) == 0 {
+		return gatewayRecord{}, fmt.Errorf("no SRV records for %s", name)
+	}
+
+	record := gatewayRecord{port: uint32(srvs[0].Port), ttl: ttl}
+	var errs []string
+	for _, srv := range srvs {
+		if uint32(srv.Port) != record.port {
+			r.logger().Warnf("Ignoring target %s of %s: its port %d differs from the gateway port %d", srv.Target, name, srv.Port, record.port)
+			continue
+		}
+		target, err := r.resolveHost(ctx, srv.Target)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		record.ips = append(record.ips, target.ips...)
+		if target.ttl < record.ttl {
+			record.ttl = target.ttl
+		}
+	}
+	if len(record.ips) == 0 {
+		return gatewayRecord{}, fmt.Errorf("failed to resolve the targets of %s: %s", name, strings.Join(errs, "; "))
+	}
+	return record, nil
+}
+
+// queryHost resolves both the A and AAAA records of the host, so that
+// dual-stack gateways are resolved to their IPv4 and IPv6 addresses.
+func (r *gatewayResolver) queryHost(ctx context.Context, host string) (gatewayRecord, error) {
+	var record gatewayRecord
+	for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+		answers, ttl, err := r.query(ctx, host, qtype)
+		if err != nil {
+			return gatewayRecord{}, err
+		}
+		found := false
+		for _, answer := range answers {
+			switch body := answer.Body.(type) {
+			case *dnsmessage.AResource:
+				record.ips = append(record.ips, net.IP(body.A[:]).String())
+				found = true
+			case *dnsmessage.AAAAResource:
+				record.ips = append(record.ips, net.IP(body.AAAA[:]).String())
+				found = true
+			}
+		}
+		if found && (record.ttl == 0 || ttl < record.ttl) {
+			record.ttl = ttl
+		}
+	}
+	if len(record.ips) == 0 {
+		return gatewayRecord{}, fmt.Errorf("no A or AAAA records for %s", host)
+	}
+	return record, nil
+}
+
+// querySRV returns the SRV records of the name, sorted by priority and then
+// by decreasing weight.
+func (r *gatewayResolver) querySRV(ctx context.Context, name string) ([]*net.SRV, time.Duration, error) {
+	answers, ttl, err := r.query(ctx, name, dnsmessage.TypeSRV)
+	if err != nil {
+		return nil, 0, err
+	}
+	var srvs []*net.SRV
+	for _, answer := range answers {
+		if body, ok := answer.Body.(*dnsmessage.SRVResource); ok {
+			srvs = append(srvs, &net.SRV{
+				Target:   body.Target.String(),
+				Port:     body.Port,
+				Priority: body.Priority,
+				Weight:   body.Weight,
+			})
+		}
+	}
+	sort.SliceStable(srvs, func(i, j int) bool {
+		if srvs[i].Priority != srvs[j].Priority {
+			return srvs[i].Priority < srvs[j].Priority
+		}
+		return srvs[i].Weight > srvs[j].Weight
+	})
+	return srvs, ttl, nil
+}
+
+// query sends a query to the nameservers in turn, until one of them answers.
+// It returns the answers of the given type along with the lowest TTL of the
+// answers, which includes the CNAMEs the name is an alias of.
+func (r *gatewayResolver) query(ctx context.Context, name string, qtype dnsmessage.Type) ([]dnsmessage.Resource, time.Duration, error) {
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	qname, err := dnsmessage.NewName(name)
+	if err != nil {
+		return nil, 0, err
+	}
+	query := dnsmessage.Message{
+		Header: dnsmessage.Header{ID: uint16(rand.Intn(1 << 16)), RecursionDesired: true},
+		Questions: []dnsmessage.Question{{
+			Name:  qname,
+			Type:  qtype,
+			Class: dnsmessage.ClassINET,
+		}},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var lastErr error
+	for _, server := range r.servers {
+		raw, err := r.exchange(ctx, server, packed)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		var rsp dnsmessage.Message
+		if err := rsp.Unpack(raw); err != nil {
+			lastErr = err
+			continue
+		}
+		if rsp.ID != query.ID {
+			lastErr = fmt.Errorf("mismatched response ID from %s", server)
+			continue
+		}
+		if rsp.Truncated {
+			lastErr = fmt.Errorf("truncated response from %s", server)
+			continue
+		}
+		if rsp.RCode != dnsmessage.RCodeSuccess {
+			return nil, 0, fmt.Errorf("lookup %s: %s", name, rsp.RCode)
+		}
+
+		var answers []dnsmessage.Resource
+		var ttl uint32
+		seen := false
+		for _, answer := range rsp.Answers {
+			if answer.Header.Type != qtype && answer.Header.Type != dnsmessage.TypeCNAME {
+				continue
+			}
+			if !seen || answer.Header.TTL < ttl {
+				ttl = answer.Header.TTL
+				seen = true
+			}
+			if answer.Header.Type == qtype {
+				answers = append(answers, answer)
+			}
+		}
+		return answers, time.Duration(ttl) * time.Second, nil
+	}
+	if lastErr == nil {
+		lastErr = errors.New("no nameservers")
+	}
+	return nil, 0, lastErr
+}
+
+// record records the resolution of the gateway address, and returns whether
+// it changed since the previous one.
+func (r *gatewayResolver) record(ips []string, port uint32, ttl time.Duration) bool {
+	if r == nil {
+		return false
+	}
+	sorted := append([]string(nil), ips...)
+	sort.Strings(sorted)
+	resolved := fmt.Sprintf("%s:%d", strings.Join(sorted, ","), port)
+
+	r.Lock()
+	defer r.Unlock()
+	changed := r.resolved != "" && r.resolved != resolved
+	if changed {
+		r.logger().Infof("Gateway address now resolves to [%s]", resolved)
+	}
+	r.resolved = resolved
+	r.port = port
+	r.ttl = ttl
+	return changed
+}
+
+// recordCondition records the message of the GatewayAddressResolved
+// condition of the Link, and returns whether it changed since the previous
+// one, or is the first one.
+func (r *gatewayResolver) recordCondition(message string) bool {
+	if r == nil {
+		return false
+	}
+	r.Lock()
+	defer r.Unlock()
+	changed := r.condition != message
+	r.condition = message
+	return changed
+}
+
+// discoveredPort returns the gateway port discovered through SRV records or
+// the port suffixes of the gateway address, or 0 if there's none.
+func (r *gatewayResolver) discoveredPort() uint32 {
+	if r == nil {
+		return 0
+	}
+	r.Lock()
+	defer r.Unlock()
+	return r.port
+}
+
+// refreshAfter returns the time until the gateway address should be
+// re-resolved, which is the lowest TTL of its records bounded by
+// minGatewayTTL and the given maximum. It's 0 when the gateway address has
+// no records to refresh.
+func (r *gatewayResolver) refreshAfter(max time.Duration) time.Duration {
+	if r == nil {
+		return 0
+	}
+	r.Lock()
+	ttl := r.ttl
+	r.Unlock()
+	if ttl == 0 {
+		return 0
+	}
+	if ttl < minGatewayTTL {
+		return minGatewayTTL
+	}
+	if max > 0 && ttl > max {
+		return max
+	}
+	return ttl
+}
+
+func (r *gatewayResolver) logger() *logging.Entry {
+	if r == nil || r.log == nil {
+		return logging.NewEntry(logging.StandardLogger())
+	}
+	return r.log
+}
+
+// readNameservers returns the addresses of the nameservers listed in the
+// given resolv.conf file.
+func readNameservers(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var servers []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			servers = append(servers, net.JoinHostPort(fields[1], "53"))
+		}
+	}
+	return servers, scanner.Err()
+}
+
+func exchangeUDP(ctx context.Context, server string, query []byte) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, dnsQueryTimeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 65535)
+	n, err := conn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
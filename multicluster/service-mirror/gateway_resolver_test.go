@@ -0,0 +1,220 @@
+package servicemirror
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/linkerd/linkerd2/pkg/multicluster"
+	logging "github.com/sirupsen/logrus"
+	"golang.org/x/net/dns/dnsmessage"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// fakeNameserver answers the queries of a gatewayResolver from a set of
+// records, keyed by name and type. Names without records of any type don't
+// exist.
+type fakeNameserver map[string][]dnsmessage.Resource
+
+func (ns fakeNameserver) exchange(_ context.Context, _ string, query []byte) ([]byte, error) {
+	var msg dnsmessage.Message
+	if err := msg.Unpack(query); err != nil {
+		return nil, err
+	}
+	q := msg.Questions[0]
+	rsp := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: msg.ID, Response: true},
+		Questions: msg.Questions,
+	}
+	rsp.RCode = dnsmessage.RCodeNameError
+	for key := range ns {
+		if strings.HasPrefix(key, q.Name.String()+"Type") {
+			rsp.RCode = dnsmessage.RCodeSuccess
+		}
+	}
+	rsp.Answers = ns[q.Name.String()+q.Type.String()]
+	return rsp.Pack()
+}
+
+func resource(name string, ttl uint32, body dnsmessage.ResourceBody) dnsmessage.Resource {
+	return dnsmessage.Resource{
+		Header: dnsmessage.ResourceHeader{
+			Name:  dnsmessage.MustNewName(name),
+			Class: dnsmessage.ClassINET,
+			TTL:   ttl,
+		},
+		Body: body,
+	}
+}
+
+func TestGatewayResolver(t *testing.T) {
+	ns := fakeNameserver{
+		"gateway.east.example.com.TypeA": {
+			resource("gateway.east.example.com.", 60, &dnsmessage.AResource{A: [4]byte{192, 0, 2, 1}}),
+			resource("gateway.east.example.com.", 30, &dnsmessage.AResource{A: [4]byte{192, 0, 2, 2}}),
+		},
+		"gateway.west.example.com.TypeA": {
+			resource("gateway.west.example.com.", 60, &dnsmessage.AResource{A: [4]byte{192, 0, 2, 3}}),
+		},
+		"gateway.west.example.com.TypeAAAA": {
+			resource("gateway.west.example.com.", 20, &dnsmessage.AAAAResource{AAAA: [16]byte{0x20, 0x01, 0x0d, 0xb8, 15: 3}}),
+		},
+		"_gateway._tcp.east.example.com.TypeSRV": {
+			resource("_gateway._tcp.east.example.com.", 120, &dnsmessage.SRVResource{
+				Priority: 10,
+				Weight:   10,
+				Port:     4143,
+				Target:   dnsmessage.MustNewName("gateway.east.example.com."),
+			}),
+			resource("_gateway._tcp.east.example.com.", 120, &dnsmessage.SRVResource{
+				Priority: 20,
+				Weight:   10,
+				Port:     4144,
+				Target:   dnsmessage.MustNewName("backup.east.example.com."),
+			}),
+		},
+	}
+	resolver := &gatewayResolver{
+		servers:  []string{"192.0.2.53:53"},
+		exchange: ns.exchange,
+		log:      logging.WithField("test", t.Name()),
+	}
+
+	testCases := []struct {
+		name     string
+		entry    string
+		expected gatewayRecord
+	}{
+		{
+			name:     "IP",
+			entry:    "192.0.2.127",
+			expected: gatewayRecord{ips: []string{"192.0.2.127"}},
+		},
+		{
+			name:  "hostname resolved with the lowest TTL of its records",
+			entry: "gateway.east.example.com",
+			expected: gatewayRecord{
+				ips: []string{"192.0.2.1", "192.0.2.2"},
+				ttl: 30 * time.Second,
+			},
+		},
+		{
+			name:  "dual-stack hostname resolved to its IPv4 and IPv6 addresses",
+			entry: "gateway.west.example.com",
+			expected: gatewayRecord{
+				ips: []string{"192.0.2.3", "2001:db8::3"},
+				ttl: 20 * time.Second,
+			},
+		},
+		{
+			name:     "IPv6",
+			entry:    "2001:db8::127",
+			expected: gatewayRecord{ips: []string{"2001:db8::127"}},
+		},
+		{
+			name:  "SRV name resolved with the port of its preferred target",
+			entry: "_gateway._tcp.east.example.com",
+			expected: gatewayRecord{
+				ips:  []string{"192.0.2.1", "192.0.2.2"},
+				port: 4143,
+				ttl:  30 * time.Second,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			record, err := resolver.resolve(context.Background(), tc.entry)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(record, tc.expected) {
+				t.Errorf("Expected %+v, got %+v", tc.expected, record)
+			}
+		})
+	}
+}
+
+func TestGatewayResolverRecord(t *testing.T) {
+	resolver := &gatewayResolver{log: logging.WithField("test", t.Name())}
+
+	if resolver.record([]string{"192.0.2.1", "192.0.2.2"}, 0, 30*time.Second) {
+		t.Error("Expected the first resolution not to be reported as a change")
+	}
+	if resolver.record([]string{"192.0.2.2", "192.0.2.1"}, 0, 30*time.Second) {
+		t.Error("Expected the same addresses in a different order not to be reported as a change")
+	}
+	if !resolver.record([]string{"192.0.2.1", "192.0.2.3"}, 0, 30*time.Second) {
+		t.Error("Expected a new address to be reported as a change")
+	}
+	if !resolver.record([]string{"192.0.2.1", "192.0.2.3"}, 4143, 30*time.Second) {
+		t.Error("Expected a new port to be reported as a change")
+	}
+	if port := resolver.discoveredPort(); port != 4143 {
+		t.Errorf("Expected discovered port 4143, got %d", port)
+	}
+
+	for _, tc := range []struct {
+		ttl      time.Duration
+		expected time.Duration
+	}{
+		{ttl: time.Second, expected: minGatewayTTL},
+		{ttl: 30 * time.Second, expected: 30 * time.Second},
+		{ttl: time.Hour, expected: time.Minute},
+	} {
+		resolver.record([]string{"192.0.2.1"}, 0, tc.ttl)
+		if after := resolver.refreshAfter(time.Minute); after != tc.expected {
+			t.Errorf("Expected refresh after %s for TTL %s, got %s", tc.expected, tc.ttl, after)
+		}
+	}
+}
+
+func TestResolveGatewayAddressWithInvalidEntries(t *testing.T) {
+	rcsw := &RemoteClusterServiceWatcher{
+		link: &multicluster.Link{
+			GatewayAddress:        "192.0.2.1:4143, bad host,[2001:DB8::1]:4143",
+			GatewayAddressWeights: map[string]uint32{"[2001:db8::1]:4143": 3},
+		},
+		log:             logging.WithField("test", t.Name()),
+		eventsQueue:     workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		gatewayResolver: &gatewayResolver{log: logging.WithField("test", t.Name())},
+	}
+
+	addresses, weights, err := rcsw.resolveAllGatewayAddresses()
+	if err != nil {
+		t.Fatalf("Expected the valid entries to be resolved, got %s", err)
+	}
+	if len(addresses) != 2 || addresses[0].IP != "192.0.2.1" || addresses[1].IP != "2001:db8::1" {
+		t.Errorf("Unexpected addresses %v", addresses)
+	}
+	if weights["192.0.2.1"] != 1 || weights["2001:db8::1"] != 3 {
+		t.Errorf("Unexpected weights %v", weights)
+	}
+	if port := rcsw.gatewayResolver.discoveredPort(); port != 4143 {
+		t.Errorf("Expected the port suffix to be used as the gateway port, got %d", port)
+	}
+}
+
+func TestGatewayAddressCondition(t *testing.T) {
+	condition := gatewayAddressCondition(nil, nil)
+	if condition.Status != metav1.ConditionTrue {
+		t.Errorf("Expected the condition to be true, got %+v", condition)
+	}
+
+	condition = gatewayAddressCondition(nil, []string{"gateway.example.com"})
+	if condition.Status != metav1.ConditionFalse || condition.Reason != "UnresolvableEntries" ||
+		!strings.Contains(condition.Message, "gateway.example.com") {
+		t.Errorf("Expected the unresolvable entries to be listed, got %+v", condition)
+	}
+
+	_, invalid := multicluster.ParseGatewayAddress("10.0.0.1,bad host")
+	condition = gatewayAddressCondition(invalid, []string{"gateway.example.com"})
+	if condition.Status != metav1.ConditionFalse || condition.Reason != "InvalidEntries" ||
+		!strings.Contains(condition.Message, "bad host") || !strings.Contains(condition.Message, "gateway.example.com") {
+		t.Errorf("Expected the invalid and unresolvable entries to be listed, got %+v", condition)
+	}
+}
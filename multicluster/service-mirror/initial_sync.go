@@ -0,0 +1,122 @@
+package servicemirror
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	consts "github.com/linkerd/linkerd2/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/tools/cache"
+)
+
+// initialSyncState tracks the remote services whose mirrors are yet to be
+// created when the watcher starts. Their ADD events are deferred and
+// replayed at a bounded rate, so that linking a cluster exporting many
+// services doesn't flood the local API server with creates.
+type initialSyncState struct {
+	pending map[string]struct{}
+}
+
+// initialSyncServices returns the exported remote services that don't have
+// a local mirror yet, in the order they should be mirrored: services in
+// namespaces that already exist in the local cluster come first, as they're
+// the most likely to have local consumers.
+func (rcsw *RemoteClusterServiceWatcher) initialSyncServices() ([]*corev1.Service, error) {
+	services, err := rcsw.remoteAPIClient.Svc().Lister().List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []*corev1.Service
+	for _, svc := range services {
+		if !rcsw.isExportedService(svc) {
+			continue
+		}
+		if _, err := rcsw.localAPIClient.Svc().Lister().Services(rcsw.link.LocalNamespace(svc.Namespace)).Get(rcsw.mirroredResourceName(svc.Name)); err == nil {
+			continue
+		}
+		pending = append(pending, svc)
+	}
+
+	hasLocalConsumers := make(map[string]bool)
+	for _, svc := range pending {
+		if _, ok := hasLocalConsumers[svc.Namespace]; ok {
+			continue
+		}
+		ns, err := rcsw.localAPIClient.NS().Lister().Get(rcsw.link.LocalNamespace(svc.Namespace))
+		// namespaces created by the service mirror hold no local workloads
+		hasLocalConsumers[svc.Namespace] = err == nil && ns.Labels[consts.MirroredResourceLabel] != "true"
+	}
+
+	sort.SliceStable(pending, func(i, j int) bool {
+		pi, pj := hasLocalConsumers[pending[i].Namespace], hasLocalConsumers[pending[j].Namespace]
+		if pi != pj {
+			return pi
+		}
+		if pending[i].Namespace != pending[j].Namespace {
+			return pending[i].Namespace < pending[j].Namespace
+		}
+		return pending[i].Name < pending[j].Name
+	})
+	return pending, nil
+}
+
+// deferToInitialSync returns true if the ADD event of the given remote
+// service must not be queued, as the initial sync will replay it.
+func (rcsw *RemoteClusterServiceWatcher) deferToInitialSync(svc *corev1.Service) bool {
+	key, err := cache.MetaNamespaceKeyFunc(svc)
+	if err != nil {
+		return false
+	}
+	rcsw.initialSyncMu.Lock()
+	defer rcsw.initialSyncMu.Unlock()
+	_, ok := rcsw.initialSync.pending[key]
+	return ok
+}
+
+// runInitialSync queues the ADD events of the given services, at most
+// createsPerSecond of them per second, until they're all queued or the
+// watcher is stopped.
+func (rcsw *RemoteClusterServiceWatcher) runInitialSync(ctx context.Context, services []*corev1.Service) {
+	clusterName := rcsw.link.TargetClusterName
+	total := len(services)
+	initialSyncTotal.WithLabelValues(clusterName).Set(float64(total))
+	initialSyncPending.WithLabelValues(clusterName).Set(float64(total))
+	if total == 0 {
+		return
+	}
+
+	rcsw.log.Infof("Mirroring %d services at a rate of %d per second", total, rcsw.initialSyncRate)
+	start := time.Now()
+	ticker := time.NewTicker(time.Second / time.Duration(rcsw.initialSyncRate))
+	defer ticker.Stop()
+
+	for i, svc := range services {
+		select {
+		case <-ticker.C:
+		case <-rcsw.stopper:
+			return
+		case <-ctx.Done():
+			return
+		}
+
+		key, err := cache.MetaNamespaceKeyFunc(svc)
+		if err != nil {
+			continue
+		}
+		rcsw.initialSyncMu.Lock()
+		delete(rcsw.initialSync.pending, key)
+		rcsw.initialSyncMu.Unlock()
+
+		// use the latest version of the service, in case it was updated
+		// while waiting
+		latest, err := rcsw.remoteAPIClient.Svc().Lister().Services(svc.Namespace).Get(svc.Name)
+		if err == nil {
+			rcsw.eventsQueue.Add(&OnAddCalled{latest})
+		}
+		initialSyncPending.WithLabelValues(clusterName).Set(float64(total - i - 1))
+	}
+	rcsw.log.Infof("Initial sync of %d services completed in %s", total, time.Since(start))
+}
@@ -0,0 +1,63 @@
+package servicemirror
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/linkerd/linkerd2/controller/k8s"
+	"github.com/linkerd/linkerd2/pkg/multicluster"
+	logging "github.com/sirupsen/logrus"
+)
+
+func TestInitialSyncServices(t *testing.T) {
+	remoteAPI, err := k8s.NewFakeAPI(
+		remoteServiceAsYaml("not-exported", "mirrored-ns", "1", nil),
+		exportedRemoteServiceAsYaml("svc-b", "mirrored-ns"),
+		exportedRemoteServiceAsYaml("svc-a", "mirrored-ns"),
+		exportedRemoteServiceAsYaml("svc-c", "local-ns"),
+		exportedRemoteServiceAsYaml("already-mirrored", "local-ns"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	localAPI, err := newFakeAPI(
+		namespaceAsYaml("local-ns"),
+		`apiVersion: v1
+kind: Namespace
+metadata:
+  name: mirrored-ns
+  labels:
+    mirror.linkerd.io/mirrored-service: "true"`,
+		mirrorServiceAsYaml("already-mirrored-remote", "local-ns", "1", nil),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remoteAPI.Sync(nil)
+	localAPI.Sync(nil)
+
+	watcher := RemoteClusterServiceWatcher{
+		link: &multicluster.Link{
+			TargetClusterName: clusterName,
+			Selector:          *defaultSelector,
+		},
+		remoteAPIClient: remoteAPI,
+		localAPIClient:  localAPI,
+		log:             logging.WithFields(logging.Fields{"cluster": clusterName}),
+	}
+
+	services, err := watcher.initialSyncServices()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"local-ns/svc-c", "mirrored-ns/svc-a", "mirrored-ns/svc-b"}
+	if len(services) != len(expected) {
+		t.Fatalf("Expected services %v, got %d services", expected, len(services))
+	}
+	for i, svc := range services {
+		if key := fmt.Sprintf("%s/%s", svc.Namespace, svc.Name); key != expected[i] {
+			t.Fatalf("Expected service %s at position %d, got %s", expected[i], i, key)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package servicemirror
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/linkerd/linkerd2/controller/k8s"
+	"github.com/linkerd/linkerd2/pkg/multicluster"
+	logging "github.com/sirupsen/logrus"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
+)
+
+// CleanupLink removes the services and endpoints mirrored for the given Link,
+// which is being deleted, along with its gateway mirror and the namespaces
+// created for the mirrors that nothing else is left in. Only the local
+// cluster is accessed, so that the cleanup doesn't depend on the target
+// cluster being reachable. The progress is reported in the MirrorCleanup
+// condition of the Link's status, and true is returned once all the
+// resources are gone. In dry run mode nothing is removed, and true is
+// returned right away.
+func CleanupLink(
+	ctx context.Context,
+	serviceMirrorNamespace string,
+	localAPI *k8s.API,
+	link *multicluster.Link,
+	linkClient dynamic.Interface,
+	serviceImports bool,
+	dryRun bool,
+) (bool, error) {
+	log := logging.WithFields(logging.Fields{"cluster": link.TargetClusterName})
+	if dryRun {
+		log.Infof("Dry run: would remove the resources mirrored for link %s", link.Name)
+		return true, nil
+	}
+
+	rcsw := &RemoteClusterServiceWatcher{
+		serviceMirrorNamespace: serviceMirrorNamespace,
+		link:                   link,
+		localAPIClient:         localAPI,
+		linkClient:             linkClient,
+		serviceImports:         serviceImports,
+		log:                    log,
+	}
+
+	if err := rcsw.cleanupMirroredResources(ctx); err != nil {
+		// the remaining resources are counted below, and the cleanup is
+		// retried until none is left
+		log.Warnf("Failed to remove some of the resources mirrored for link %s: %s", link.Name, err)
+	}
+
+	services, endpoints, gatewayMirror, err := rcsw.countMirroredResources(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	condition := metav1.Condition{
+		Type:    multicluster.LinkConditionMirrorCleanup,
+		Status:  metav1.ConditionTrue,
+		Reason:  "CleanupComplete",
+		Message: "All the mirrored resources have been removed",
+	}
+	done := services == 0 && endpoints == 0 && !gatewayMirror
+	if !done {
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "CleanupInProgress"
+		condition.Message = fmt.Sprintf("Removing %d mirrored services and %d mirrored endpoints", services, endpoints)
+		if gatewayMirror {
+			condition.Message += ", and the gateway mirror"
+		}
+	}
+	if err := multicluster.SetLinkCondition(ctx, linkClient, link.Namespace, link.Name, condition); err != nil {
+		log.Errorf("Failed to set %s condition of link %s: %s", multicluster.LinkConditionMirrorCleanup, link.Name, err)
+	}
+	return done, nil
+}
+
+// deleteGatewayMirror deletes the service and endpoints mirroring the Link's
+// gateway.
+func (rcsw *RemoteClusterServiceWatcher) deleteGatewayMirror(ctx context.Context) error {
+	name := fmt.Sprintf("probe-gateway-%s", rcsw.link.TargetClusterName)
+	err := rcsw.localServices(rcsw.serviceMirrorNamespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !kerrors.IsNotFound(err) {
+		return err
+	}
+	err = rcsw.localEndpoints(rcsw.serviceMirrorNamespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !kerrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// countMirroredResources returns the number of services and endpoints
+// mirrored for the Link, and whether its gateway mirror exists. The API is
+// queried rather than the listers, which could still hold the resources
+// that were just deleted.
+func (rcsw *RemoteClusterServiceWatcher) countMirroredResources(ctx context.Context) (int, int, bool, error) {
+	selector := labels.Set(rcsw.getMirroredServiceLabels()).String()
+	client := rcsw.localAPIClient.Client.CoreV1()
+
+	services, err := client.Services(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return 0, 0, false, err
+	}
+	endpoints, err := client.Endpoints(metav1.NamespaceAll).List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	name := fmt.Sprintf("probe-gateway-%s", rcsw.link.TargetClusterName)
+	_, svcErr := client.Services(rcsw.serviceMirrorNamespace).Get(ctx, name, metav1.GetOptions{})
+	if svcErr != nil && !kerrors.IsNotFound(svcErr) {
+		return 0, 0, false, svcErr
+	}
+	_, epErr := client.Endpoints(rcsw.serviceMirrorNamespace).Get(ctx, name, metav1.GetOptions{})
+	if epErr != nil && !kerrors.IsNotFound(epErr) {
+		return 0, 0, false, epErr
+	}
+	gatewayMirror := svcErr == nil || epErr == nil
+
+	return len(services.Items), len(endpoints.Items), gatewayMirror, nil
+}
@@ -10,6 +10,8 @@ const (
 	gatewayClusterName   = "target_cluster_name"
 	eventTypeLabelName   = "event_type"
 	probeSuccessfulLabel = "probe_successful"
+	verbLabel            = "verb"
+	resourceLabel        = "resource"
 )
 
 // ProbeMetricVecs stores metrics about about gateways collected by probe
@@ -31,7 +33,15 @@ type ProbeMetrics struct {
 	unregister func()
 }
 
-var endpointRepairCounter *prometheus.CounterVec
+var (
+	endpointRepairCounter *prometheus.CounterVec
+	initialSyncTotal      *prometheus.GaugeVec
+	initialSyncPending    *prometheus.GaugeVec
+	dryRunWritesCounter   *prometheus.CounterVec
+	driftCorrections      *prometheus.CounterVec
+	remoteUnreachable     *prometheus.GaugeVec
+	mirrorStats           *mirrorStatsCollector
+)
 
 func init() {
 	endpointRepairCounter = promauto.NewCounterVec(
@@ -41,6 +51,49 @@ func init() {
 		},
 		[]string{gatewayClusterName},
 	)
+
+	initialSyncTotal = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "service_mirror_initial_sync_services",
+			Help: "Number of services to be mirrored when the service mirror controller started watching the target cluster",
+		},
+		[]string{gatewayClusterName},
+	)
+
+	initialSyncPending = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "service_mirror_initial_sync_pending",
+			Help: "Number of services still waiting to be mirrored after the service mirror controller started watching the target cluster",
+		},
+		[]string{gatewayClusterName},
+	)
+
+	dryRunWritesCounter = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "service_mirror_dry_run_writes",
+			Help: "Increments when the service mirror controller skips a write to the local cluster because it runs in dry-run mode",
+		},
+		[]string{gatewayClusterName, verbLabel, resourceLabel},
+	)
+
+	driftCorrections = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "service_mirror_drift_corrections",
+			Help: "Increments when the service mirror controller corrects a mirror resource edited in the local cluster",
+		},
+		[]string{gatewayClusterName, resourceLabel},
+	)
+
+	remoteUnreachable = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "service_mirror_remote_api_unreachable",
+			Help: "A gauge which is 1 while the API server of the target cluster is unreachable and the processing of its events is paused, and 0 otherwise",
+		},
+		[]string{gatewayClusterName},
+	)
+
+	mirrorStats = newMirrorStatsCollector()
+	prometheus.MustRegister(mirrorStats)
 }
 
 // NewProbeMetricVecs creates a new ProbeMetricVecs.
@@ -0,0 +1,89 @@
+package servicemirror
+
+import (
+	"fmt"
+
+	consts "github.com/linkerd/linkerd2/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Reasons of the Kubernetes events recorded on the local cluster by the
+// service mirror. Similar events are aggregated and rate limited by the
+// recorder's broadcaster, so they can be recorded on each occurrence.
+const (
+	eventReasonMirrorFailed       = "MirrorFailed"
+	eventReasonGatewayUnavailable = "GatewayUnavailable"
+)
+
+// linkReference returns a reference to the Link of the watcher, on which the
+// events that don't concern a particular mirror service are recorded.
+func (rcsw *RemoteClusterServiceWatcher) linkReference() *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		APIVersion: consts.LinkAPIGroupVersion,
+		Kind:       consts.LinkKind,
+		Name:       rcsw.link.Name,
+		Namespace:  rcsw.link.Namespace,
+	}
+}
+
+// eventSubject returns the local object on which an event about the
+// processing of the given queue event is recorded, which is the mirror
+// service of the remote service it concerns if it exists, or else the Link.
+// It also returns a description of what the queue event is about.
+func (rcsw *RemoteClusterServiceWatcher) eventSubject(event interface{}) (runtime.Object, string) {
+	var namespace, name string
+	switch ev := event.(type) {
+	case *OnAddCalled:
+		namespace, name = ev.svc.Namespace, ev.svc.Name
+	case *OnUpdateCalled:
+		namespace, name = ev.svc.Namespace, ev.svc.Name
+	case *OnDeleteCalled:
+		namespace, name = ev.svc.Namespace, ev.svc.Name
+	case *RemoteServiceCreated:
+		namespace, name = ev.service.Namespace, ev.service.Name
+	case *RemoteServiceUpdated:
+		namespace, name = ev.remoteUpdate.Namespace, ev.remoteUpdate.Name
+	case *RemoteServiceDeleted:
+		namespace, name = ev.Namespace, ev.Name
+	case *ClusterUnregistered:
+		return rcsw.linkReference(), "the cleanup of the mirrored services"
+	case *OrphanedServicesGcTriggered:
+		return rcsw.linkReference(), "the cleanup of the orphaned mirror services"
+	case *RepairEndpoints:
+		return rcsw.linkReference(), "the repair of the mirrored endpoints"
+	case *MirrorDriftCheck:
+		return rcsw.linkReference(), "the drift check of the mirror services"
+	default:
+		return rcsw.linkReference(), fmt.Sprintf("%T", event)
+	}
+
+	description := fmt.Sprintf("service %s/%s", namespace, name)
+	local, err := rcsw.localAPIClient.Svc().Lister().Services(rcsw.link.LocalNamespace(namespace)).Get(rcsw.mirroredResourceName(name))
+	if err != nil || !rcsw.isOwnedMirror(local) {
+		return rcsw.linkReference(), description
+	}
+	return local, description
+}
+
+// recordFailure records a Warning event for a queue event that failed to be
+// processed and won't be retried anymore, so that the failure is visible to
+// the operators of the local cluster.
+func (rcsw *RemoteClusterServiceWatcher) recordFailure(event interface{}, err error) {
+	if rcsw.recorder == nil || event == nil {
+		return
+	}
+	subject, description := rcsw.eventSubject(event)
+	rcsw.recorder.Eventf(subject, corev1.EventTypeWarning, eventReasonMirrorFailed,
+		"Failed to process %s of target cluster %s: %s", description, rcsw.link.TargetClusterName, err)
+}
+
+// recordGatewayUnavailable records a Warning event on a mirror service
+// created without endpoints because the gateway had no ready addresses.
+func (rcsw *RemoteClusterServiceWatcher) recordGatewayUnavailable(local *corev1.Service) {
+	if rcsw.recorder == nil || rcsw.dryRun != nil {
+		return
+	}
+	rcsw.recorder.Eventf(local, corev1.EventTypeWarning, eventReasonGatewayUnavailable,
+		"The gateway of target cluster %s has no ready addresses; the mirror has no endpoints until it does", rcsw.link.TargetClusterName)
+}
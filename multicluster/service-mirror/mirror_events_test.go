@@ -0,0 +1,91 @@
+package servicemirror
+
+import (
+	"fmt"
+	"testing"
+
+	consts "github.com/linkerd/linkerd2/pkg/k8s"
+	"github.com/linkerd/linkerd2/pkg/multicluster"
+	logging "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// capturingRecorder records the objects events are recorded on, along with
+// their types and reasons.
+type capturingRecorder struct {
+	events []string
+}
+
+func (r *capturingRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	if ref, ok := object.(*corev1.ObjectReference); ok {
+		r.events = append(r.events, fmt.Sprintf("%s %s/%s %s %s", ref.Kind, ref.Namespace, ref.Name, eventtype, reason))
+		return
+	}
+	m, err := meta.Accessor(object)
+	if err != nil {
+		panic(err)
+	}
+	r.events = append(r.events, fmt.Sprintf("%T %s/%s %s %s", object, m.GetNamespace(), m.GetName(), eventtype, reason))
+}
+
+func (r *capturingRecorder) Eventf(object runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.Event(object, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+func (r *capturingRecorder) AnnotatedEventf(object runtime.Object, annotations map[string]string, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.Event(object, eventtype, reason, fmt.Sprintf(messageFmt, args...))
+}
+
+func TestRecordFailure(t *testing.T) {
+	localAPI, err := newFakeAPI(mirrorServiceAsYaml("test-service-remote", "test-namespace", "", nil))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	localAPI.Sync(nil)
+
+	testCases := []struct {
+		description string
+		event       interface{}
+		expected    string
+	}{
+		{
+			description: "recorded on the mirror service",
+			event:       &RemoteServiceDeleted{Name: "test-service", Namespace: "test-namespace"},
+			expected:    "*v1.Service test-namespace/test-service-remote Warning MirrorFailed",
+		},
+		{
+			description: "recorded on the Link when the service isn't mirrored",
+			event:       &RemoteServiceDeleted{Name: "other-service", Namespace: "test-namespace"},
+			expected:    fmt.Sprintf("%s linkerd-multicluster/%s Warning MirrorFailed", consts.LinkKind, clusterName),
+		},
+		{
+			description: "recorded on the Link for events about the whole cluster",
+			event:       &RepairEndpoints{},
+			expected:    fmt.Sprintf("%s linkerd-multicluster/%s Warning MirrorFailed", consts.LinkKind, clusterName),
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // pin
+		t.Run(tc.description, func(t *testing.T) {
+			recorder := &capturingRecorder{}
+			watcher := RemoteClusterServiceWatcher{
+				link: &multicluster.Link{
+					Name:              clusterName,
+					Namespace:         "linkerd-multicluster",
+					TargetClusterName: clusterName,
+				},
+				localAPIClient: localAPI,
+				log:            logging.WithField("cluster", clusterName),
+				recorder:       recorder,
+			}
+
+			watcher.recordFailure(tc.event, RetryableError{})
+			if len(recorder.events) != 1 || recorder.events[0] != tc.expected {
+				t.Errorf("Expected event %q, got %v", tc.expected, recorder.events)
+			}
+		})
+	}
+}
@@ -0,0 +1,439 @@
+package servicemirror
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/linkerd/linkerd2/controller/k8s"
+	consts "github.com/linkerd/linkerd2/pkg/k8s"
+	"github.com/linkerd/linkerd2/pkg/multicluster"
+	logging "github.com/sirupsen/logrus"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	harnessNamespace = "linkerd-multicluster"
+	harnessTimeout   = 10 * time.Second
+)
+
+// mirrorHarness runs a RemoteClusterServiceWatcher end to end against a pair
+// of fake clusters: the remote cluster holds the exported services, and the
+// local cluster receives their mirrors and holds the Link. Unlike the
+// testEnvironment, which feeds events into the watcher directly, the harness
+// starts the watcher so that changes made to the remote cluster go through
+// its informers, initial sync and repair loop.
+//
+// The clusters are fake clientsets rather than real API servers, so the
+// harness doesn't cover what only an API server does: field selectors are
+// ignored, server-side applies are emulated by applyPatchReactor, objects
+// aren't defaulted or validated, the Link CRD schema isn't enforced and
+// namespaces don't need to exist. Mirroring against real clusters is covered
+// by the multicluster integration tests in test/integration/multicluster.
+type mirrorHarness struct {
+	t        *testing.T
+	link     *multicluster.Link
+	local    *k8s.API
+	remote   *k8s.API
+	linkAPI  *dynamicfake.FakeDynamicClient
+	recorder *record.FakeRecorder
+	watcher  *RemoteClusterServiceWatcher
+}
+
+// newMirrorHarness starts a watcher for the given Link. The remote and local
+// resources are YAML manifests loaded into the respective clusters before
+// the watcher is started. The watcher is stopped when the test finishes.
+func newMirrorHarness(t *testing.T, link multicluster.Link, remoteResources, localResources []string) *mirrorHarness {
+	t.Helper()
+	return newMirrorHarnessWithWorkers(t, link, 1, remoteResources, localResources)
+}
+
+// newMirrorHarnessWithWorkers starts a watcher processing the given number
+// of events concurrently.
+func newMirrorHarnessWithWorkers(t *testing.T, link multicluster.Link, workers int, remoteResources, localResources []string) *mirrorHarness {
+	t.Helper()
+
+	remote, err := k8s.NewFakeAPI(remoteResources...)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	local, err := newFakeAPI(localResources...)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	local.Sync(nil)
+
+	if link.Name == "" {
+		link.Name = link.TargetClusterName
+	}
+	if link.Namespace == "" {
+		link.Namespace = harnessNamespace
+	}
+	linkObj, err := link.ToUnstructured()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	linkAPI := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{multicluster.LinkGVR: "LinkList"},
+		&linkObj,
+	)
+
+	h := &mirrorHarness{
+		t:        t,
+		link:     &link,
+		local:    local,
+		remote:   remote,
+		linkAPI:  linkAPI,
+		recorder: record.NewFakeRecorder(100),
+	}
+
+	gh := newGatewayHealth(logging.WithField("cluster", link.TargetClusterName), link.GatewayIdentity)
+	gh.probe = func(string, multicluster.ProbeSpec) error { return nil }
+
+	h.watcher = &RemoteClusterServiceWatcher{
+		serviceMirrorNamespace: harnessNamespace,
+		link:                   h.link,
+		remoteAPIClient:        remote,
+		localAPIClient:         local,
+		stopper:                make(chan struct{}),
+		log:                    logging.WithFields(logging.Fields{"cluster": link.TargetClusterName}),
+		eventsQueue:            workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		requeueLimit:           3,
+		repairPeriod:           time.Hour,
+		repairs:                newRepairSchedule(time.Hour),
+		linkClient:             linkAPI,
+		recorder:               h.recorder,
+		conflicts:              make(map[string]string),
+		workers:                workers,
+		initialSyncRate:        1000,
+		gatewayHealth:          gh,
+		circuit:                newCircuitBreaker(DefaultCircuitBreakerConfig().FailureThreshold),
+		checkRemote:            func() error { return nil },
+		checkPeriod:            time.Hour,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := h.watcher.Start(ctx); err != nil {
+		cancel()
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	t.Cleanup(func() {
+		h.watcher.Stop(false)
+		cancel()
+	})
+
+	return h
+}
+
+// createRemote creates a service in the remote cluster.
+func (h *mirrorHarness) createRemote(svc *corev1.Service) {
+	h.t.Helper()
+	if _, err := h.remote.Client.CoreV1().Services(svc.Namespace).Create(context.Background(), svc, metav1.CreateOptions{}); err != nil {
+		h.t.Fatalf("Unexpected error: %s", err)
+	}
+}
+
+// updateRemote updates a service in the remote cluster.
+func (h *mirrorHarness) updateRemote(svc *corev1.Service) {
+	h.t.Helper()
+	if _, err := h.remote.Client.CoreV1().Services(svc.Namespace).Update(context.Background(), svc, metav1.UpdateOptions{}); err != nil {
+		h.t.Fatalf("Unexpected error: %s", err)
+	}
+}
+
+// deleteRemote deletes a service from the remote cluster.
+func (h *mirrorHarness) deleteRemote(namespace, name string) {
+	h.t.Helper()
+	if err := h.remote.Client.CoreV1().Services(namespace).Delete(context.Background(), name, metav1.DeleteOptions{}); err != nil {
+		h.t.Fatalf("Unexpected error: %s", err)
+	}
+}
+
+// repair triggers an endpoints repair, as done periodically by the watcher.
+func (h *mirrorHarness) repair() {
+	h.watcher.eventsQueue.Add(&RepairEndpoints{})
+}
+
+// mirror returns the local mirror Service and Endpoints of the given remote
+// service, or an error if either of them doesn't exist.
+func (h *mirrorHarness) mirror(namespace, remoteName string) (*corev1.Service, *corev1.Endpoints, error) {
+	name := h.watcher.mirroredResourceName(remoteName)
+	svc, err := h.local.Client.CoreV1().Services(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	ep, err := h.local.Client.CoreV1().Endpoints(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	return svc, ep, nil
+}
+
+// eventually polls the given condition until it succeeds, failing the test
+// with the last error if it doesn't within harnessTimeout.
+func (h *mirrorHarness) eventually(condition func() error) {
+	h.t.Helper()
+	deadline := time.Now().Add(harnessTimeout)
+	for {
+		err := condition()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			h.t.Fatalf("Condition not met after %s: %s", harnessTimeout, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func harnessLink() multicluster.Link {
+	return multicluster.Link{
+		TargetClusterName:   clusterName,
+		TargetClusterDomain: clusterDomain,
+		GatewayIdentity:     "gateway-identity",
+		GatewayAddress:      "192.0.2.127",
+		GatewayPort:         888,
+		ProbeSpec:           defaultProbeSpec,
+		Selector:            *defaultSelector,
+	}
+}
+
+func exportedLabels() map[string]string {
+	return map[string]string{consts.DefaultExportedServiceSelector: "true"}
+}
+
+func TestMirrorHarnessLifecycle(t *testing.T) {
+	h := newMirrorHarness(t, harnessLink(), nil, nil)
+
+	h.createRemote(remoteService("service-one", "ns1", "", exportedLabels(), []corev1.ServicePort{
+		{Name: "http", Protocol: "TCP", Port: 80},
+	}))
+	h.eventually(func() error {
+		_, ep, err := h.mirror("ns1", "service-one")
+		if err != nil {
+			return err
+		}
+		if len(ep.Subsets) != 1 || len(ep.Subsets[0].Addresses) != 1 || ep.Subsets[0].Addresses[0].IP != "192.0.2.127" {
+			return fmt.Errorf("unexpected mirror endpoints subsets: %v", ep.Subsets)
+		}
+		if ep.Annotations[consts.RemoteGatewayIdentity] != "gateway-identity" {
+			return fmt.Errorf("unexpected gateway identity %q", ep.Annotations[consts.RemoteGatewayIdentity])
+		}
+		return nil
+	})
+
+	h.updateRemote(remoteService("service-one", "ns1", "2", exportedLabels(), []corev1.ServicePort{
+		{Name: "http", Protocol: "TCP", Port: 80},
+		{Name: "admin", Protocol: "TCP", Port: 9990},
+	}))
+	h.eventually(func() error {
+		svc, ep, err := h.mirror("ns1", "service-one")
+		if err != nil {
+			return err
+		}
+		if len(svc.Spec.Ports) != 2 {
+			return fmt.Errorf("expected 2 service ports, got %v", svc.Spec.Ports)
+		}
+		if len(ep.Subsets) != 1 || len(ep.Subsets[0].Ports) != 2 {
+			return fmt.Errorf("expected 2 endpoints ports, got %v", ep.Subsets)
+		}
+		return nil
+	})
+
+	h.deleteRemote("ns1", "service-one")
+	h.eventually(func() error {
+		if _, _, err := h.mirror("ns1", "service-one"); err == nil {
+			return fmt.Errorf("mirror of service-one still exists")
+		}
+		return nil
+	})
+}
+
+func TestMirrorHarnessRepairsGatewayMirror(t *testing.T) {
+	h := newMirrorHarness(t, harnessLink(), nil, nil)
+
+	h.repair()
+	h.eventually(func() error {
+		name := fmt.Sprintf("probe-gateway-%s", clusterName)
+		ep, err := h.local.Client.CoreV1().Endpoints(harnessNamespace).Get(context.Background(), name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if len(ep.Subsets) != 1 || len(ep.Subsets[0].Ports) != 1 || ep.Subsets[0].Ports[0].Port != defaultProbePort {
+			return fmt.Errorf("unexpected gateway mirror endpoints subsets: %v", ep.Subsets)
+		}
+		return nil
+	})
+}
+
+func TestMirrorHarnessReportsConflicts(t *testing.T) {
+	h := newMirrorHarness(t, harnessLink(), nil, []string{
+		foreignMirrorServiceAsYaml("service-one-remote", "ns1", "other"),
+	})
+
+	h.createRemote(remoteService("service-one", "ns1", "", exportedLabels(), []corev1.ServicePort{
+		{Name: "http", Protocol: "TCP", Port: 80},
+	}))
+	h.eventually(func() error {
+		condition, err := multicluster.GetLinkCondition(context.Background(), h.linkAPI, h.link.Namespace, h.link.Name, multicluster.LinkConditionMirrorConflict)
+		if err != nil {
+			return err
+		}
+		if condition == nil || condition.Status != metav1.ConditionTrue {
+			return fmt.Errorf("expected a true %s condition, got %v", multicluster.LinkConditionMirrorConflict, condition)
+		}
+		return nil
+	})
+}
+
+func TestMirrorHarnessPropagatesMetadata(t *testing.T) {
+	link := harnessLink()
+	link.PropagatedLabels = []string{"team"}
+	link.PropagatedAnnotations = []string{"example.com/*"}
+	h := newMirrorHarness(t, link, nil, nil)
+
+	labels := exportedLabels()
+	labels["team"] = "emoji"
+	labels["tier"] = "backend"
+	svc := remoteService("service-one", "ns1", "", labels, []corev1.ServicePort{
+		{Name: "http", Protocol: "TCP", Port: 80},
+	})
+	svc.Annotations = map[string]string{
+		"example.com/protocol": "grpc",
+		"other.io/owner":       "someone",
+	}
+	h.createRemote(svc)
+
+	h.eventually(func() error {
+		svc, ep, err := h.mirror("ns1", "service-one")
+		if err != nil {
+			return err
+		}
+		for _, meta := range []metav1.Object{svc, ep} {
+			if meta.GetLabels()["team"] != "emoji" || meta.GetLabels()[consts.MirroredResourceLabel] != "true" {
+				return fmt.Errorf("unexpected labels on %s: %v", meta.GetName(), meta.GetLabels())
+			}
+			if _, ok := meta.GetLabels()["tier"]; ok {
+				return fmt.Errorf("unexpected tier label on %s", meta.GetName())
+			}
+			if meta.GetAnnotations()["example.com/protocol"] != "grpc" {
+				return fmt.Errorf("unexpected annotations on %s: %v", meta.GetName(), meta.GetAnnotations())
+			}
+			if _, ok := meta.GetAnnotations()["other.io/owner"]; ok {
+				return fmt.Errorf("unexpected other.io/owner annotation on %s", meta.GetName())
+			}
+		}
+		return nil
+	})
+}
+
+func TestMirrorHarnessMapsNamespaces(t *testing.T) {
+	link := harnessLink()
+	link.NamespaceMappings = map[string]string{"ns1": "payments"}
+	link.NamespacePrefix = "east-"
+	h := newMirrorHarness(t, link, nil, nil)
+
+	ports := []corev1.ServicePort{{Name: "http", Protocol: "TCP", Port: 80}}
+	h.createRemote(remoteService("service-one", "ns1", "", exportedLabels(), ports))
+	h.createRemote(remoteService("service-two", "ns2", "", exportedLabels(), ports))
+	h.eventually(func() error {
+		for _, mirror := range []struct{ namespace, name string }{
+			{"payments", "service-one"},
+			{"east-ns2", "service-two"},
+		} {
+			if _, _, err := h.mirror(mirror.namespace, mirror.name); err != nil {
+				return err
+			}
+			ns, err := h.local.Client.CoreV1().Namespaces().Get(context.Background(), mirror.namespace, metav1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			if ns.Labels[consts.MirroredResourceLabel] != "true" {
+				return fmt.Errorf("unexpected labels on namespace %s: %v", ns.Name, ns.Labels)
+			}
+		}
+		return nil
+	})
+
+	h.deleteRemote("ns1", "service-one")
+	h.eventually(func() error {
+		if _, _, err := h.mirror("payments", "service-one"); err == nil {
+			return fmt.Errorf("mirror of service-one still exists")
+		}
+		return nil
+	})
+}
+
+func TestMirrorHarnessCleansUpDeletedLink(t *testing.T) {
+	gatewayMirror := fmt.Sprintf(`
+apiVersion: v1
+kind: Service
+metadata:
+  name: probe-gateway-%s
+  namespace: %s
+`, clusterName, harnessNamespace)
+	h := newMirrorHarness(t, harnessLink(), nil, []string{gatewayMirror})
+
+	ports := []corev1.ServicePort{{Name: "http", Protocol: "TCP", Port: 80}}
+	h.createRemote(remoteService("service-one", "ns1", "", exportedLabels(), ports))
+	h.createRemote(remoteService("service-two", "ns2", "", exportedLabels(), ports))
+	h.eventually(func() error {
+		if _, _, err := h.mirror("ns1", "service-one"); err != nil {
+			return err
+		}
+		_, _, err := h.mirror("ns2", "service-two")
+		return err
+	})
+
+	// the namespaces were created for the mirrors, but ns2 now holds a local
+	// workload as well
+	ctx := context.Background()
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "client", Namespace: "ns2"}}
+	if _, err := h.local.Client.CoreV1().Pods("ns2").Create(ctx, pod, metav1.CreateOptions{}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	h.eventually(func() error {
+		done, err := CleanupLink(ctx, harnessNamespace, h.local, h.link, h.linkAPI, false, false)
+		if err != nil {
+			return err
+		}
+		if !done {
+			return fmt.Errorf("cleanup of link %s not done", h.link.Name)
+		}
+		return nil
+	})
+
+	if _, _, err := h.mirror("ns1", "service-one"); err == nil {
+		t.Fatal("Expected the mirror of service-one to be deleted")
+	}
+	gatewayName := fmt.Sprintf("probe-gateway-%s", clusterName)
+	if _, err := h.local.Client.CoreV1().Services(harnessNamespace).Get(ctx, gatewayName, metav1.GetOptions{}); err == nil {
+		t.Fatal("Expected the gateway mirror service to be deleted")
+	}
+	if _, err := h.local.Client.CoreV1().Endpoints(harnessNamespace).Get(ctx, gatewayName, metav1.GetOptions{}); err == nil {
+		t.Fatal("Expected the gateway mirror endpoints to be deleted")
+	}
+	if _, err := h.local.Client.CoreV1().Namespaces().Get(ctx, "ns1", metav1.GetOptions{}); err == nil {
+		t.Fatal("Expected the namespace created for the mirror of service-one to be deleted")
+	}
+	if _, err := h.local.Client.CoreV1().Namespaces().Get(ctx, "ns2", metav1.GetOptions{}); err != nil {
+		t.Fatalf("Expected the namespace holding a local workload to be kept, got %s", err)
+	}
+
+	condition, err := multicluster.GetLinkCondition(ctx, h.linkAPI, h.link.Namespace, h.link.Name, multicluster.LinkConditionMirrorCleanup)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if condition == nil || condition.Status != metav1.ConditionTrue {
+		t.Fatalf("Expected the %s condition to be true, got %v", multicluster.LinkConditionMirrorCleanup, condition)
+	}
+}
@@ -0,0 +1,117 @@
+package servicemirror
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	consts "github.com/linkerd/linkerd2/pkg/k8s"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// mirrorStatsCollector exports, for each target cluster being watched, the
+// number of mirror services and the time since the mirrors were last
+// reconciled. They're computed from the informers' caches when the metrics
+// are scraped, so that they never drift from the mirrors actually present.
+type mirrorStatsCollector struct {
+	sync.RWMutex
+	watchers map[string]*RemoteClusterServiceWatcher
+
+	mirrored       *prometheus.Desc
+	headless       *prometheus.Desc
+	sinceReconcile *prometheus.Desc
+}
+
+func newMirrorStatsCollector() *mirrorStatsCollector {
+	labels := []string{gatewayClusterName}
+	return &mirrorStatsCollector{
+		watchers: make(map[string]*RemoteClusterServiceWatcher),
+		mirrored: prometheus.NewDesc(
+			"service_mirror_mirrored_services",
+			"Number of services of the target cluster mirrored in the local cluster",
+			labels, nil,
+		),
+		headless: prometheus.NewDesc(
+			"service_mirror_headless_mirrored_services",
+			"Number of mirrored services whose service in the target cluster is headless",
+			labels, nil,
+		),
+		sinceReconcile: prometheus.NewDesc(
+			"service_mirror_seconds_since_last_reconcile",
+			"Seconds since the mirrors of the target cluster were last reconciled successfully, or since the service mirror started watching it if they never were",
+			labels, nil,
+		),
+	}
+}
+
+func (c *mirrorStatsCollector) add(rcsw *RemoteClusterServiceWatcher) {
+	c.Lock()
+	defer c.Unlock()
+	c.watchers[rcsw.link.TargetClusterName] = rcsw
+}
+
+// remove stops exporting the stats of the given watcher, unless it has been
+// replaced by a new watcher of the same cluster in the meantime.
+func (c *mirrorStatsCollector) remove(rcsw *RemoteClusterServiceWatcher) {
+	c.Lock()
+	defer c.Unlock()
+	if c.watchers[rcsw.link.TargetClusterName] == rcsw {
+		delete(c.watchers, rcsw.link.TargetClusterName)
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *mirrorStatsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.mirrored
+	ch <- c.headless
+	ch <- c.sinceReconcile
+}
+
+// Collect implements prometheus.Collector.
+func (c *mirrorStatsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.RLock()
+	defer c.RUnlock()
+	now := time.Now()
+	for clusterName, rcsw := range c.watchers {
+		mirrored, headless, err := rcsw.countMirrors()
+		if err != nil {
+			rcsw.log.Errorf("Failed to count mirror services: %s", err)
+		} else {
+			ch <- prometheus.MustNewConstMetric(c.mirrored, prometheus.GaugeValue, float64(mirrored), clusterName)
+			ch <- prometheus.MustNewConstMetric(c.headless, prometheus.GaugeValue, float64(headless), clusterName)
+		}
+		lastReconciled := time.Unix(0, atomic.LoadInt64(&rcsw.lastReconciled))
+		ch <- prometheus.MustNewConstMetric(c.sinceReconcile, prometheus.GaugeValue, now.Sub(lastReconciled).Seconds(), clusterName)
+	}
+}
+
+// countMirrors returns the number of mirror services of the watcher's Link,
+// and how many of them mirror a headless service.
+func (rcsw *RemoteClusterServiceWatcher) countMirrors() (int, int, error) {
+	mirrors, err := rcsw.getMirrorServices()
+	if err != nil {
+		return 0, 0, err
+	}
+	mirrored, headless := 0, 0
+	for _, mirror := range mirrors {
+		if _, ok := mirror.Labels[consts.MirroredGatewayLabel]; ok {
+			continue
+		}
+		mirrored++
+		remoteNamespace, ok := rcsw.link.RemoteNamespace(mirror.Namespace)
+		if !ok {
+			continue
+		}
+		remote, err := rcsw.remoteAPIClient.Svc().Lister().Services(remoteNamespace).Get(rcsw.originalResourceName(mirror.Name))
+		if err == nil && remote.Spec.ClusterIP == corev1.ClusterIPNone {
+			headless++
+		}
+	}
+	return mirrored, headless, nil
+}
+
+// markReconciled records that the mirrors were reconciled successfully.
+func (rcsw *RemoteClusterServiceWatcher) markReconciled() {
+	atomic.StoreInt64(&rcsw.lastReconciled, time.Now().UnixNano())
+}
@@ -0,0 +1,60 @@
+package servicemirror
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestMirrorStatsCollector(t *testing.T) {
+	h := newMirrorHarness(t, harnessLink(), nil, nil)
+
+	ports := []corev1.ServicePort{{Name: "http", Protocol: "TCP", Port: 80}}
+	h.createRemote(remoteService("service-one", "ns1", "", exportedLabels(), ports))
+	headless := remoteService("service-two", "ns1", "", exportedLabels(), ports)
+	headless.Spec.ClusterIP = corev1.ClusterIPNone
+	h.createRemote(headless)
+	h.createRemote(remoteService("not-exported", "ns1", "", nil, ports))
+
+	h.eventually(func() error {
+		mirrored, headless, err := h.watcher.countMirrors()
+		if err != nil {
+			return err
+		}
+		if mirrored != 2 || headless != 1 {
+			return fmt.Errorf("expected 2 mirrors, 1 of them headless, got %d and %d", mirrored, headless)
+		}
+		return nil
+	})
+
+	h.repair()
+	h.eventually(func() error {
+		collector := newMirrorStatsCollector()
+		collector.add(h.watcher)
+		registry := prometheus.NewPedanticRegistry()
+		registry.MustRegister(collector)
+		families, err := registry.Gather()
+		if err != nil {
+			return err
+		}
+
+		values := map[string]float64{}
+		for _, family := range families {
+			for _, metric := range family.GetMetric() {
+				values[family.GetName()] = metric.GetGauge().GetValue()
+			}
+		}
+		if values["service_mirror_mirrored_services"] != 2 {
+			return fmt.Errorf("expected 2 mirrored services, got %v", values["service_mirror_mirrored_services"])
+		}
+		if values["service_mirror_headless_mirrored_services"] != 1 {
+			return fmt.Errorf("expected 1 headless mirrored service, got %v", values["service_mirror_headless_mirrored_services"])
+		}
+		if since := values["service_mirror_seconds_since_last_reconcile"]; since > 60 {
+			return fmt.Errorf("expected a recent reconcile, got one %v seconds ago", since)
+		}
+		return nil
+	})
+}
@@ -1,30 +1,65 @@
 package servicemirror
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"sync"
 	"time"
 
+	consts "github.com/linkerd/linkerd2/pkg/k8s"
 	"github.com/linkerd/linkerd2/pkg/multicluster"
 	"github.com/prometheus/client_golang/prometheus"
 	logging "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
 )
 
-const httpGatewayTimeoutMillis = 50000
+const (
+	httpGatewayTimeoutMillis = 50000
+
+	// proxyErrorHeader is set by the proxy on the responses to the requests
+	// it failed
+	proxyErrorHeader = "l5d-proxy-error"
+)
+
+// ProbeResult is the outcome of the latest probe of a gateway.
+type ProbeResult struct {
+	Alive     bool
+	LatencyMs int64
+	Error     string
+	ProbedAt  time.Time
+}
 
 // ProbeWorker is responsible for monitoring gateways using a probe specification
 type ProbeWorker struct {
 	localGatewayName string
 	*sync.RWMutex
-	probeSpec *multicluster.ProbeSpec
-	stopCh    chan struct{}
-	metrics   *ProbeMetrics
-	log       *logging.Entry
+	probeSpec  *multicluster.ProbeSpec
+	stopCh     chan struct{}
+	metrics    *ProbeMetrics
+	log        *logging.Entry
+	link       *multicluster.Link
+	linkClient dynamic.Interface
+
+	resultMutex sync.RWMutex
+	result      *ProbeResult
+	// the numbers of consecutive failed and successful probes, and the time
+	// the GatewayAlive condition was last reported, only accessed from run
+	failures   uint32
+	successes  uint32
+	reportedAt time.Time
+
+	// onAliveChange is called whenever the gateway's liveness changes
+	onAliveChange func(alive bool)
 }
 
-// NewProbeWorker creates a new probe worker associated with a particular gateway
-func NewProbeWorker(localGatewayName string, spec *multicluster.ProbeSpec, metrics *ProbeMetrics, probekey string) *ProbeWorker {
+// NewProbeWorker creates a new probe worker associated with a particular gateway.
+// The probe results are exported as metrics and, when a linkClient is given,
+// reported in the GatewayAlive condition of the Link, so that every consumer
+// relies on the same measurements.
+func NewProbeWorker(localGatewayName string, spec *multicluster.ProbeSpec, metrics *ProbeMetrics, probekey string, link *multicluster.Link, linkClient dynamic.Interface) *ProbeWorker {
 	return &ProbeWorker{
 		localGatewayName: localGatewayName,
 		RWMutex:          &sync.RWMutex{},
@@ -34,9 +69,32 @@ func NewProbeWorker(localGatewayName string, spec *multicluster.ProbeSpec, metri
 		log: logging.WithFields(logging.Fields{
 			"probe-key": probekey,
 		}),
+		link:       link,
+		linkClient: linkClient,
 	}
 }
 
+// Result returns the outcome of the latest probe, or nil if the gateway
+// hasn't been probed yet.
+func (pw *ProbeWorker) Result() *ProbeResult {
+	pw.resultMutex.RLock()
+	defer pw.resultMutex.RUnlock()
+	return pw.result
+}
+
+// OnAliveChange registers a function called whenever the gateway's liveness
+// changes, before the probe worker is started.
+func (pw *ProbeWorker) OnAliveChange(f func(alive bool)) {
+	pw.onAliveChange = f
+}
+
+// Alive returns whether the gateway is considered alive after the latest
+// probe. The gateway is considered alive until it's probed.
+func (pw *ProbeWorker) Alive() bool {
+	result := pw.Result()
+	return result == nil || result.Alive
+}
+
 // UpdateProbeSpec is used to update the probe specification when something about the gateway changes
 func (pw *ProbeWorker) UpdateProbeSpec(spec *multicluster.ProbeSpec) {
 	pw.Lock()
@@ -68,49 +126,179 @@ probeLoop:
 		case <-pw.stopCh:
 			break probeLoop
 		case <-probeTicker.C:
-			pw.doProbe()
+			pw.recordResult(pw.doProbe())
 		}
 	}
 }
 
-func (pw *ProbeWorker) doProbe() {
+func (pw *ProbeWorker) doProbe() ProbeResult {
 	pw.RLock()
 	defer pw.RUnlock()
 
 	successLabel := prometheus.Labels{probeSuccessfulLabel: "true"}
 	notSuccessLabel := prometheus.Labels{probeSuccessfulLabel: "false"}
 
+	timeout := httpGatewayTimeoutMillis * time.Millisecond
+	if pw.probeSpec.Timeout > 0 {
+		timeout = pw.probeSpec.Timeout
+	}
 	client := http.Client{
-		Timeout: httpGatewayTimeoutMillis * time.Millisecond,
+		Timeout: timeout,
 	}
 
-	req, err := http.NewRequest("GET", fmt.Sprintf("http://%s:%d%s", pw.localGatewayName, pw.probeSpec.Port, pw.probeSpec.Path), nil)
+	result := ProbeResult{ProbedAt: time.Now()}
+
+	identity := ""
+	if pw.link != nil {
+		identity = pw.link.GatewayIdentity
+	}
+	req, err := newProbeRequest(pw.probeSpec, fmt.Sprintf("%s:%d", pw.localGatewayName, pw.probeSpec.Port), identity)
 	if err != nil {
 		pw.log.Errorf("Could not create a GET request to gateway: %s", err)
-		return
+		result.Error = err.Error()
+		return result
 	}
 
 	start := time.Now()
 	resp, err := client.Do(req)
 	end := time.Since(start)
 	if err != nil {
-		pw.log.Warnf("Problem connecting with gateway. Marking as unhealthy %s", err)
-		pw.metrics.alive.Set(0)
+		pw.log.Warnf("Problem connecting with gateway: %s", err)
 		pw.metrics.probes.With(notSuccessLabel).Inc()
-		return
-	} else if resp.StatusCode != 200 {
-		pw.log.Warnf("Gateway returned unexpected status %d. Marking as unhealthy", resp.StatusCode)
-		pw.metrics.alive.Set(0)
+		result.Error = err.Error()
+		return result
+	} else if !pw.probeSpec.ExpectsStatus(resp.StatusCode) {
+		result.Error = unexpectedStatusError(resp).Error()
+		pw.log.Warnf("Gateway probe failed: %s", result.Error)
 		pw.metrics.probes.With(notSuccessLabel).Inc()
 	} else {
-		pw.log.Debug("Gateway is healthy")
-		pw.metrics.alive.Set(1)
+		pw.log.Debug("Gateway probe succeeded")
 		pw.metrics.latencies.Observe(float64(end.Milliseconds()))
 		pw.metrics.probes.With(successLabel).Inc()
+		result.Alive = true
+		result.LatencyMs = end.Milliseconds()
 	}
 
 	if err := resp.Body.Close(); err != nil {
 		pw.log.Warnf("Failed to close response body %s", err)
 	}
 
+	return result
+}
+
+// newProbeRequest returns a GET request to the probe endpoint of the gateway
+// at the given host. When the probe spec verifies the gateway's identity, the
+// request requires that identity from the service mirror's proxy with the
+// l5d-require-id header: the proxy then probes the gateway over mTLS, with its
+// own identity, and fails the probe if the gateway presents another identity,
+// so that a successful probe also validates the trust path to the gateway.
+func newProbeRequest(spec *multicluster.ProbeSpec, host, identity string) (*http.Request, error) {
+	if spec.VerifyIdentity && identity == "" {
+		return nil, errors.New("the Link has no gateway identity to verify")
+	}
+	req, err := http.NewRequest(http.MethodGet, spec.URL(host), nil)
+	if err != nil {
+		return nil, err
+	}
+	if spec.VerifyIdentity {
+		req.Header.Set(consts.RequireIDHeader, identity)
+	}
+	return req, nil
+}
+
+// unexpectedStatusError describes a probe response with an unexpected status,
+// including the error of the service mirror's proxy, such as a mismatch of the
+// gateway's identity, when it's the proxy that failed the probe.
+func unexpectedStatusError(resp *http.Response) error {
+	if proxyErr := resp.Header.Get(proxyErrorHeader); proxyErr != "" {
+		return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, proxyErr)
+	}
+	return fmt.Errorf("unexpected status %d", resp.StatusCode)
+}
+
+// recordResult stores the result of a probe and reports it in the Link's
+// GatewayAlive condition whenever the gateway's liveness changes, and at least
+// every GatewayAliveRefreshPeriod otherwise, so that the consumers of the
+// condition can tell that it's stale when the gateway isn't probed anymore.
+// The liveness only changes once the failure or success threshold of the
+// probe spec is reached; the first probe sets it right away.
+func (pw *ProbeWorker) recordResult(result ProbeResult) {
+	if result.ProbedAt.IsZero() {
+		result.ProbedAt = time.Now()
+	}
+	pw.resultMutex.Lock()
+	previous := pw.result
+	result.Alive = pw.applyThresholds(previous, result.Alive)
+	pw.result = &result
+	pw.resultMutex.Unlock()
+
+	if result.Alive {
+		pw.metrics.alive.Set(1)
+	} else {
+		pw.metrics.alive.Set(0)
+	}
+	changed := previous == nil || previous.Alive != result.Alive
+	if previous != nil && changed {
+		pw.log.Infof("Gateway is now considered alive: %t", result.Alive)
+	}
+	if changed && pw.onAliveChange != nil {
+		pw.onAliveChange(result.Alive)
+	}
+
+	if pw.linkClient == nil || pw.link == nil {
+		return
+	}
+	if !changed && result.ProbedAt.Sub(pw.reportedAt) < multicluster.GatewayAliveRefreshPeriod {
+		return
+	}
+
+	alive := multicluster.GatewayAlive{
+		Condition: metav1.Condition{
+			Status:  metav1.ConditionTrue,
+			Reason:  "ProbeSucceeded",
+			Message: fmt.Sprintf("Gateway responded to probe in %dms", result.LatencyMs),
+		},
+		LastProbeTime: metav1.NewTime(result.ProbedAt),
+	}
+	switch {
+	case !result.Alive:
+		alive.Status = metav1.ConditionFalse
+		alive.Reason = "ProbeFailed"
+		alive.Message = fmt.Sprintf("Gateway probe failed: %s", result.Error)
+	case result.Error != "":
+		// the failure threshold isn't reached yet
+		alive.Message = fmt.Sprintf("Gateway probe failed, below the failure threshold: %s", result.Error)
+	}
+
+	if err := multicluster.SetLinkGatewayAlive(context.Background(), pw.linkClient, pw.link.Namespace, pw.link.Name, alive); err != nil {
+		pw.log.Errorf("Failed to update %s condition on Link %s: %s", multicluster.LinkConditionGatewayAlive, pw.link.Name, err)
+		return
+	}
+	pw.reportedAt = result.ProbedAt
+}
+
+// applyThresholds returns whether the gateway is alive after a probe that
+// succeeded or not.
+func (pw *ProbeWorker) applyThresholds(previous *ProbeResult, succeeded bool) bool {
+	if succeeded {
+		pw.successes++
+		pw.failures = 0
+	} else {
+		pw.failures++
+		pw.successes = 0
+	}
+	if previous == nil {
+		return succeeded
+	}
+
+	pw.RLock()
+	failureThreshold, successThreshold := pw.probeSpec.FailureThreshold, pw.probeSpec.SuccessThreshold
+	pw.RUnlock()
+	if previous.Alive && !succeeded && pw.failures < failureThreshold {
+		return true
+	}
+	if !previous.Alive && succeeded && pw.successes < successThreshold {
+		return false
+	}
+	return succeeded
 }
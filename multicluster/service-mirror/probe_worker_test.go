@@ -0,0 +1,245 @@
+package servicemirror
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/linkerd/linkerd2/pkg/multicluster"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+// probeMetricVecs are registered once, as they can't be registered twice.
+var probeMetricVecs = NewProbeMetricVecs()
+
+func TestProbeWorkerResult(t *testing.T) {
+	metrics, err := probeMetricVecs.NewWorkerMetrics("remote")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	testCases := []struct {
+		name          string
+		status        int
+		expectedAlive bool
+	}{
+		{"healthy gateway", http.StatusOK, true},
+		{"unhealthy gateway", http.StatusServiceUnavailable, false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+			}))
+			defer gateway.Close()
+
+			host, port, err := net.SplitHostPort(gateway.Listener.Addr().String())
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			portNum, err := strconv.ParseUint(port, 10, 32)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+
+			spec := &multicluster.ProbeSpec{Path: "/ready", Port: uint32(portNum), Period: time.Minute}
+			pw := NewProbeWorker(host, spec, metrics, "remote", nil, nil)
+			if pw.Result() != nil {
+				t.Fatal("Expected no result before the first probe")
+			}
+
+			pw.recordResult(pw.doProbe())
+			result := pw.Result()
+			if result == nil {
+				t.Fatal("Expected a result after the first probe")
+			}
+			if result.Alive != tc.expectedAlive {
+				t.Fatalf("Expected alive to be %t, got %t", tc.expectedAlive, result.Alive)
+			}
+			if !result.Alive && result.Error == "" {
+				t.Fatal("Expected the failed probe to have an error")
+			}
+		})
+	}
+}
+
+func TestProbeWorkerThresholds(t *testing.T) {
+	metrics, err := probeMetricVecs.NewWorkerMetrics("thresholds")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	spec := &multicluster.ProbeSpec{Path: "/ready", Period: time.Minute, FailureThreshold: 3, SuccessThreshold: 2}
+	pw := NewProbeWorker("gateway", spec, metrics, "thresholds", nil, nil)
+
+	for i, step := range []struct {
+		succeeded     bool
+		expectedAlive bool
+	}{
+		{true, true},
+		{false, true},
+		{false, true},
+		{false, false},
+		{true, false},
+		{false, false},
+		{true, false},
+		{true, true},
+	} {
+		pw.recordResult(ProbeResult{Alive: step.succeeded})
+		if alive := pw.Result().Alive; alive != step.expectedAlive {
+			t.Fatalf("Expected alive to be %t after probe %d, got %t", step.expectedAlive, i, alive)
+		}
+	}
+}
+
+func TestProbeWorkerVerifyIdentity(t *testing.T) {
+	metrics, err := probeMetricVecs.NewWorkerMetrics("identity")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// the gateway stands in for the service mirror's proxy, which fails the
+	// requests to a gateway that doesn't have the required identity
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id := r.Header.Get("l5d-require-id"); id != "" && id != "gateway.linkerd-multicluster.serviceaccount.identity.linkerd.cluster.local" {
+			w.Header().Set(proxyErrorHeader, "peer identity mismatch")
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer gateway.Close()
+	host, port, err := net.SplitHostPort(gateway.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	portNum, err := strconv.ParseUint(port, 10, 32)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	for _, tc := range []struct {
+		name          string
+		identity      string
+		expectedAlive bool
+		expectedError string
+	}{
+		{
+			name:          "expected identity",
+			identity:      "gateway.linkerd-multicluster.serviceaccount.identity.linkerd.cluster.local",
+			expectedAlive: true,
+		},
+		{
+			name:          "unexpected identity",
+			identity:      "impostor.linkerd-multicluster.serviceaccount.identity.linkerd.cluster.local",
+			expectedError: "unexpected status 502: peer identity mismatch",
+		},
+		{
+			name:          "no identity",
+			expectedError: "the Link has no gateway identity to verify",
+		},
+	} {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			spec := &multicluster.ProbeSpec{Path: "/ready", Port: uint32(portNum), Period: time.Minute, VerifyIdentity: true}
+			link := &multicluster.Link{TargetClusterName: "remote", GatewayIdentity: tc.identity}
+			pw := NewProbeWorker(host, spec, metrics, "identity", link, nil)
+
+			result := pw.doProbe()
+			if result.Alive != tc.expectedAlive {
+				t.Fatalf("Expected alive to be %t, got %t", tc.expectedAlive, result.Alive)
+			}
+			if result.Error != tc.expectedError {
+				t.Fatalf("Expected error %q, got %q", tc.expectedError, result.Error)
+			}
+		})
+	}
+}
+
+func TestProbeWorkerReportsGatewayAlive(t *testing.T) {
+	metrics, err := probeMetricVecs.NewWorkerMetrics("report")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	link := &multicluster.Link{
+		Name:              "remote",
+		Namespace:         "linkerd-multicluster",
+		TargetClusterName: "remote",
+		ProbeSpec:         multicluster.ProbeSpec{Path: "/ready", Period: 10 * time.Second},
+	}
+	u, err := link.ToUnstructured()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{multicluster.LinkGVR: "LinkList"},
+		&u,
+	)
+	pw := NewProbeWorker("gateway", &link.ProbeSpec, metrics, "report", link, client)
+	changes := []bool{}
+	pw.OnAliveChange(func(alive bool) { changes = append(changes, alive) })
+
+	start := time.Now().Truncate(time.Second)
+	for i, step := range []struct {
+		result         ProbeResult
+		expectedStatus metav1.ConditionStatus
+		expectedProbe  time.Time
+	}{
+		{
+			// the first result is reported right away
+			result:         ProbeResult{Alive: true, ProbedAt: start},
+			expectedStatus: metav1.ConditionTrue,
+			expectedProbe:  start,
+		},
+		{
+			// the same liveness isn't reported again before the refresh
+			result:         ProbeResult{Alive: true, ProbedAt: start.Add(10 * time.Second)},
+			expectedStatus: metav1.ConditionTrue,
+			expectedProbe:  start,
+		},
+		{
+			// the condition is refreshed so that it doesn't become stale
+			result:         ProbeResult{Alive: true, ProbedAt: start.Add(multicluster.GatewayAliveRefreshPeriod)},
+			expectedStatus: metav1.ConditionTrue,
+			expectedProbe:  start.Add(multicluster.GatewayAliveRefreshPeriod),
+		},
+		{
+			// liveness changes are reported right away
+			result:         ProbeResult{Alive: false, Error: "unexpected status 503", ProbedAt: start.Add(multicluster.GatewayAliveRefreshPeriod + 10*time.Second)},
+			expectedStatus: metav1.ConditionFalse,
+			expectedProbe:  start.Add(multicluster.GatewayAliveRefreshPeriod + 10*time.Second),
+		},
+	} {
+		pw.recordResult(step.result)
+
+		alive, err := multicluster.GetLinkGatewayAlive(context.Background(), client, link.Namespace, link.Name)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if alive == nil {
+			t.Fatalf("Expected a %s condition after probe %d", multicluster.LinkConditionGatewayAlive, i)
+		}
+		if alive.Status != step.expectedStatus {
+			t.Fatalf("Expected status %s after probe %d, got %s", step.expectedStatus, i, alive.Status)
+		}
+		if !alive.LastProbeTime.Time.Equal(step.expectedProbe) {
+			t.Fatalf("Expected probe time %s after probe %d, got %s", step.expectedProbe, i, alive.LastProbeTime.Time)
+		}
+		if alive.Stale(step.result.ProbedAt, link.GatewayAliveMaxAge()) {
+			t.Fatalf("Expected the condition not to be stale after probe %d", i)
+		}
+	}
+
+	if len(changes) != 2 || !changes[0] || changes[1] {
+		t.Fatalf("Expected the liveness to change to alive then dead, got %v", changes)
+	}
+}
@@ -0,0 +1,103 @@
+package servicemirror
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// maxRepairBackoff is the factor of the repair period the interval
+	// between the periodic repairs of the mirrored endpoints backs off to,
+	// at most.
+	maxRepairBackoff = 8
+
+	// repairFailureThreshold is the number of consecutive repairs that
+	// failed to write anything after which the repairs back off.
+	repairFailureThreshold = 3
+)
+
+// repairSchedule adapts the interval between the periodic repairs of the
+// mirrored endpoints to how often they find something to repair. It starts at
+// the repair period, doubles up to maxRepairBackoff times the repair period
+// after each repair that had nothing to write or once repairs keep failing,
+// and goes back to the repair period as soon as a repair writes something or
+// the gateway addresses change.
+type repairSchedule struct {
+	sync.Mutex
+	period   time.Duration
+	interval time.Duration
+	failures int
+
+	// changed is signaled when the interval changes, so that the next repair
+	// is rescheduled accordingly
+	changed chan struct{}
+}
+
+func newRepairSchedule(period time.Duration) *repairSchedule {
+	return &repairSchedule{
+		period:   period,
+		interval: period,
+		changed:  make(chan struct{}, 1),
+	}
+}
+
+// next returns the interval until the next periodic repair.
+func (s *repairSchedule) next() time.Duration {
+	s.Lock()
+	defer s.Unlock()
+	return s.interval
+}
+
+// record adapts the interval to the outcome of a repair: the number of
+// objects it wrote, and the number of writes that failed.
+func (s *repairSchedule) record(written, failed int) {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	switch {
+	case failed > 0 && written == 0:
+		s.failures++
+		if s.failures >= repairFailureThreshold {
+			s.backOff()
+		}
+	case written > 0:
+		s.failures = 0
+		s.setInterval(s.period)
+	default:
+		s.failures = 0
+		s.backOff()
+	}
+}
+
+// reset brings the interval back to the repair period, e.g. when the gateway
+// addresses change.
+func (s *repairSchedule) reset() {
+	if s == nil {
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.failures = 0
+	s.setInterval(s.period)
+}
+
+func (s *repairSchedule) backOff() {
+	interval := 2 * s.interval
+	if max := maxRepairBackoff * s.period; interval > max {
+		interval = max
+	}
+	s.setInterval(interval)
+}
+
+func (s *repairSchedule) setInterval(interval time.Duration) {
+	if interval == s.interval {
+		return
+	}
+	s.interval = interval
+	select {
+	case s.changed <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,49 @@
+package servicemirror
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRepairSchedule(t *testing.T) {
+	s := newRepairSchedule(time.Minute)
+
+	for i, step := range []struct {
+		written, failed  int
+		reset            bool
+		expectedInterval time.Duration
+	}{
+		// nothing to repair
+		{expectedInterval: 2 * time.Minute},
+		{expectedInterval: 4 * time.Minute},
+		{expectedInterval: 8 * time.Minute},
+		{expectedInterval: 8 * time.Minute},
+		// something was repaired
+		{written: 1, expectedInterval: time.Minute},
+		// failures only back off once they persist
+		{failed: 2, expectedInterval: time.Minute},
+		{failed: 2, expectedInterval: time.Minute},
+		{failed: 2, expectedInterval: 2 * time.Minute},
+		{failed: 2, expectedInterval: 4 * time.Minute},
+		// partial failures don't back off
+		{written: 1, failed: 1, expectedInterval: time.Minute},
+		{expectedInterval: 2 * time.Minute},
+		// the gateway addresses changed
+		{reset: true, expectedInterval: time.Minute},
+	} {
+		if step.reset {
+			s.reset()
+		} else {
+			s.record(step.written, step.failed)
+		}
+		if interval := s.next(); interval != step.expectedInterval {
+			t.Fatalf("Expected the interval to be %s after step %d, got %s", step.expectedInterval, i, interval)
+		}
+	}
+
+	select {
+	case <-s.changed:
+	default:
+		t.Fatal("Expected the change of interval to be signaled")
+	}
+}
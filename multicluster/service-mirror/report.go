@@ -0,0 +1,184 @@
+package servicemirror
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	consts "github.com/linkerd/linkerd2/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	mirrorCreate   = "create"
+	mirrorUpdate   = "update"
+	mirrorDelete   = "delete"
+	mirrorConflict = "conflict"
+
+	// reportKey is the key of the ConfigMap data holding the report
+	reportKey = "report.yaml"
+)
+
+type (
+	// MirrorReport describes the changes the service mirror would make to
+	// the mirror services of a Link to reconcile them with the current state
+	// of the target cluster.
+	MirrorReport struct {
+		Link        string         `json:"link"`
+		GeneratedAt metav1.Time    `json:"generatedAt"`
+		Summary     map[string]int `json:"summary"`
+		Changes     []MirrorChange `json:"changes"`
+	}
+
+	// MirrorChange is a change to a single mirror service.
+	MirrorChange struct {
+		Action        string `json:"action"`
+		Namespace     string `json:"namespace"`
+		Name          string `json:"name"`
+		RemoteService string `json:"remoteService,omitempty"`
+		Reason        string `json:"reason"`
+	}
+)
+
+// ReportConfigMapName returns the name of the ConfigMap the reconciliation
+// report of the given Link is written to.
+func ReportConfigMapName(linkName string) string {
+	return fmt.Sprintf("service-mirror-report-%s", linkName)
+}
+
+// Report computes the changes to the mirror services that reconciling them
+// with the current state of the target cluster would make, without making
+// any of them.
+func (rcsw *RemoteClusterServiceWatcher) Report() (*MirrorReport, error) {
+	report := &MirrorReport{
+		Link:        rcsw.link.Name,
+		GeneratedAt: metav1.Now(),
+		Summary:     map[string]int{mirrorCreate: 0, mirrorUpdate: 0, mirrorDelete: 0, mirrorConflict: 0},
+		Changes:     []MirrorChange{},
+	}
+	addChange := func(change MirrorChange) {
+		report.Summary[change.Action]++
+		report.Changes = append(report.Changes, change)
+	}
+
+	remoteServices, err := rcsw.remoteAPIClient.Svc().Lister().List(labels.Everything())
+	if err != nil {
+		return nil, err
+	}
+	for _, remote := range remoteServices {
+		if !rcsw.isExportedService(remote) {
+			continue
+		}
+		localName := rcsw.mirroredResourceName(remote.Name)
+		localNamespace := rcsw.link.LocalNamespace(remote.Namespace)
+		change := MirrorChange{
+			Namespace:     localNamespace,
+			Name:          localName,
+			RemoteService: fmt.Sprintf("%s/%s", remote.Namespace, remote.Name),
+		}
+
+		local, err := rcsw.localAPIClient.Svc().Lister().Services(localNamespace).Get(localName)
+		if err != nil {
+			if !kerrors.IsNotFound(err) {
+				return nil, err
+			}
+			change.Action = mirrorCreate
+			change.Reason = "exported service not mirrored yet"
+			addChange(change)
+			continue
+		}
+		if !rcsw.isOwnedMirror(local) {
+			change.Action = mirrorConflict
+			change.Reason = fmt.Sprintf("name taken by %s", mirrorOwner(local))
+			addChange(change)
+			continue
+		}
+		if version, ok := local.Annotations[consts.RemoteResourceVersionAnnotation]; ok && version != remote.ResourceVersion {
+			change.Action = mirrorUpdate
+			change.Reason = fmt.Sprintf("remote service changed since version %s", version)
+			addChange(change)
+		}
+	}
+
+	mirrors, err := rcsw.getMirrorServices()
+	if err != nil {
+		return nil, err
+	}
+	for _, mirror := range mirrors {
+		remoteName := rcsw.originalResourceName(mirror.Name)
+		change := MirrorChange{
+			Action:    mirrorDelete,
+			Namespace: mirror.Namespace,
+			Name:      mirror.Name,
+		}
+		remoteNamespace, ok := rcsw.link.RemoteNamespace(mirror.Namespace)
+		if !ok {
+			change.Reason = "namespace not mirrored anymore"
+			addChange(change)
+			continue
+		}
+		remote, err := rcsw.remoteAPIClient.Svc().Lister().Services(remoteNamespace).Get(remoteName)
+		if err != nil {
+			if !kerrors.IsNotFound(err) {
+				return nil, err
+			}
+			change.Reason = "remote service deleted"
+			addChange(change)
+			continue
+		}
+		if !rcsw.isExportedService(remote) {
+			change.RemoteService = fmt.Sprintf("%s/%s", remote.Namespace, remote.Name)
+			change.Reason = "remote service not exported anymore"
+			addChange(change)
+		}
+	}
+
+	sort.Slice(report.Changes, func(i, j int) bool {
+		if report.Changes[i].Namespace != report.Changes[j].Namespace {
+			return report.Changes[i].Namespace < report.Changes[j].Namespace
+		}
+		return report.Changes[i].Name < report.Changes[j].Name
+	})
+	return report, nil
+}
+
+// WriteReport computes the reconciliation report of the Link and writes it
+// to a ConfigMap in the namespace of the service mirror, replacing the
+// previous report.
+func (rcsw *RemoteClusterServiceWatcher) WriteReport(ctx context.Context) error {
+	report, err := rcsw.Report()
+	if err != nil {
+		return fmt.Errorf("failed to compute the mirror report: %s", err)
+	}
+	data, err := yaml.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      ReportConfigMapName(rcsw.link.Name),
+			Namespace: rcsw.serviceMirrorNamespace,
+			Labels: map[string]string{
+				consts.RemoteClusterNameLabel: rcsw.link.TargetClusterName,
+			},
+		},
+		Data: map[string]string{reportKey: string(data)},
+	}
+
+	configMaps := rcsw.localAPIClient.Client.CoreV1().ConfigMaps(rcsw.serviceMirrorNamespace)
+	_, err = configMaps.Update(ctx, cm, metav1.UpdateOptions{})
+	if kerrors.IsNotFound(err) {
+		_, err = configMaps.Create(ctx, cm, metav1.CreateOptions{})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write the mirror report: %s", err)
+	}
+	rcsw.log.Infof("Wrote mirror report to ConfigMap %s/%s: %d to create, %d to update, %d to delete, %d in conflict",
+		cm.Namespace, cm.Name, report.Summary[mirrorCreate], report.Summary[mirrorUpdate], report.Summary[mirrorDelete], report.Summary[mirrorConflict])
+	return nil
+}
@@ -0,0 +1,81 @@
+package servicemirror
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/linkerd/linkerd2/controller/k8s"
+	"github.com/linkerd/linkerd2/pkg/multicluster"
+	logging "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestReport(t *testing.T) {
+	remoteAPI, err := k8s.NewFakeAPI(
+		exportedRemoteServiceAsYaml("svc-new", "ns1"),
+		exportedRemoteServiceAsYaml("svc-changed", "ns1"),
+		exportedRemoteServiceAsYaml("svc-same", "ns1"),
+		exportedRemoteServiceAsYaml("svc-taken", "ns1"),
+		remoteServiceAsYaml("svc-unexported", "ns1", "1", nil),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	localAPI, err := newFakeAPI(
+		mirrorServiceAsYaml("svc-changed-remote", "ns1", "0", nil),
+		mirrorServiceAsYaml("svc-same-remote", "ns1", "1", nil),
+		foreignMirrorServiceAsYaml("svc-taken-remote", "ns1", "other"),
+		mirrorServiceAsYaml("svc-unexported-remote", "ns1", "1", nil),
+		mirrorServiceAsYaml("svc-gone-remote", "ns2", "1", nil),
+	)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	remoteAPI.Sync(nil)
+	localAPI.Sync(nil)
+
+	watcher := RemoteClusterServiceWatcher{
+		serviceMirrorNamespace: "linkerd-multicluster",
+		link: &multicluster.Link{
+			Name:              "remote",
+			TargetClusterName: clusterName,
+			Selector:          *defaultSelector,
+		},
+		remoteAPIClient: remoteAPI,
+		localAPIClient:  localAPI,
+		log:             logging.WithFields(logging.Fields{"cluster": clusterName}),
+	}
+
+	report, err := watcher.Report()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expectedChanges := []MirrorChange{
+		{Action: mirrorUpdate, Namespace: "ns1", Name: "svc-changed-remote", RemoteService: "ns1/svc-changed", Reason: "remote service changed since version 0"},
+		{Action: mirrorCreate, Namespace: "ns1", Name: "svc-new-remote", RemoteService: "ns1/svc-new", Reason: "exported service not mirrored yet"},
+		{Action: mirrorConflict, Namespace: "ns1", Name: "svc-taken-remote", RemoteService: "ns1/svc-taken", Reason: "name taken by mirror of target cluster other"},
+		{Action: mirrorDelete, Namespace: "ns1", Name: "svc-unexported-remote", RemoteService: "ns1/svc-unexported", Reason: "remote service not exported anymore"},
+		{Action: mirrorDelete, Namespace: "ns2", Name: "svc-gone-remote", Reason: "remote service deleted"},
+	}
+	if !reflect.DeepEqual(report.Changes, expectedChanges) {
+		t.Fatalf("Expected changes %+v, got %+v", expectedChanges, report.Changes)
+	}
+	expectedSummary := map[string]int{mirrorCreate: 1, mirrorUpdate: 1, mirrorDelete: 2, mirrorConflict: 1}
+	if !reflect.DeepEqual(report.Summary, expectedSummary) {
+		t.Fatalf("Expected summary %v, got %v", expectedSummary, report.Summary)
+	}
+
+	if err := watcher.WriteReport(context.Background()); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	cm, err := localAPI.Client.CoreV1().ConfigMaps("linkerd-multicluster").Get(context.Background(), ReportConfigMapName("remote"), metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !strings.Contains(cm.Data[reportKey], "name: svc-new-remote") {
+		t.Fatalf("Expected the report to list svc-new-remote, got:\n%s", cm.Data[reportKey])
+	}
+}
@@ -0,0 +1,66 @@
+package servicemirror
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// RequeueConfig controls how events that failed to be processed are retried.
+// Its defaults match workqueue.DefaultControllerRateLimiter().
+type RequeueConfig struct {
+	// Limit is the number of times an event is requeued before being dropped
+	Limit int
+	// BaseDelay is the delay before the first retry of an event, doubled on
+	// each subsequent retry
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between two retries of an event
+	MaxDelay time.Duration
+	// QPS is the overall number of retries allowed per second, across all
+	// events
+	QPS float64
+	// Burst is the number of retries allowed above QPS
+	Burst int
+}
+
+// DefaultRequeueConfig returns the configuration used when none is given.
+func DefaultRequeueConfig() RequeueConfig {
+	return RequeueConfig{
+		Limit:     3,
+		BaseDelay: 5 * time.Millisecond,
+		MaxDelay:  1000 * time.Second,
+		QPS:       10,
+		Burst:     100,
+	}
+}
+
+// Validate returns an error if the configuration can't be used.
+func (c RequeueConfig) Validate() error {
+	if c.Limit < 0 {
+		return fmt.Errorf("invalid requeue limit %d: must not be negative", c.Limit)
+	}
+	if c.BaseDelay <= 0 {
+		return fmt.Errorf("invalid requeue base delay %s: must be positive", c.BaseDelay)
+	}
+	if c.MaxDelay < c.BaseDelay {
+		return fmt.Errorf("invalid requeue max delay %s: must not be lower than the base delay %s", c.MaxDelay, c.BaseDelay)
+	}
+	if c.QPS <= 0 {
+		return fmt.Errorf("invalid requeue QPS %v: must be positive", c.QPS)
+	}
+	if c.Burst <= 0 {
+		return fmt.Errorf("invalid requeue burst %d: must be positive", c.Burst)
+	}
+	return nil
+}
+
+// rateLimiter returns a rate limiter delaying each event exponentially with
+// its number of retries, while limiting the overall rate of retries.
+func (c RequeueConfig) rateLimiter() workqueue.RateLimiter {
+	return workqueue.NewMaxOfRateLimiter(
+		workqueue.NewItemExponentialFailureRateLimiter(c.BaseDelay, c.MaxDelay),
+		&workqueue.BucketRateLimiter{Limiter: rate.NewLimiter(rate.Limit(c.QPS), c.Burst)},
+	)
+}
@@ -0,0 +1,59 @@
+package servicemirror
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRequeueConfigValidate(t *testing.T) {
+	if err := DefaultRequeueConfig().Validate(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		modify func(*RequeueConfig)
+	}{
+		{"negative limit", func(c *RequeueConfig) { c.Limit = -1 }},
+		{"zero base delay", func(c *RequeueConfig) { c.BaseDelay = 0 }},
+		{"max delay lower than base delay", func(c *RequeueConfig) { c.MaxDelay = time.Millisecond }},
+		{"zero qps", func(c *RequeueConfig) { c.QPS = 0 }},
+		{"zero burst", func(c *RequeueConfig) { c.Burst = 0 }},
+	} {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			config := DefaultRequeueConfig()
+			tc.modify(&config)
+			if err := config.Validate(); err == nil {
+				t.Fatal("Expected an error, got none")
+			}
+		})
+	}
+}
+
+func TestRequeueConfigRateLimiter(t *testing.T) {
+	config := DefaultRequeueConfig()
+	config.BaseDelay = 100 * time.Millisecond
+	config.MaxDelay = 300 * time.Millisecond
+	limiter := config.rateLimiter()
+
+	expectedDelays := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		300 * time.Millisecond,
+		300 * time.Millisecond,
+	}
+	for i, expected := range expectedDelays {
+		if delay := limiter.When("event"); delay != expected {
+			t.Fatalf("Expected retry %d to be delayed by %s, got %s", i+1, expected, delay)
+		}
+	}
+	if limiter.NumRequeues("event") != len(expectedDelays) {
+		t.Fatalf("Expected %d requeues, got %d", len(expectedDelays), limiter.NumRequeues("event"))
+	}
+
+	limiter.Forget("event")
+	if delay := limiter.When("event"); delay != config.BaseDelay {
+		t.Fatalf("Expected a forgotten event to be delayed by %s, got %s", config.BaseDelay, delay)
+	}
+}
@@ -0,0 +1,101 @@
+package servicemirror
+
+import (
+	"errors"
+
+	"github.com/linkerd/linkerd2/pkg/crash"
+	"github.com/linkerd/linkerd2/pkg/multicluster"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+// newRemoteExportsInformer returns an informer on the MCS API ServiceExports
+// of the target cluster.
+func newRemoteExportsInformer(cfg *rest.Config) (cache.SharedIndexInformer, error) {
+	client, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return dynamicinformer.NewFilteredDynamicInformer(
+		client,
+		multicluster.ServiceExportGVR,
+		metav1.NamespaceAll,
+		0,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+		nil,
+	).Informer(), nil
+}
+
+// hasServiceExport returns whether the given remote service has a
+// ServiceExport, when ServiceExports of the target cluster are watched.
+func (rcsw *RemoteClusterServiceWatcher) hasServiceExport(service *corev1.Service) bool {
+	if rcsw.remoteExports == nil {
+		return false
+	}
+	key, err := cache.MetaNamespaceKeyFunc(service)
+	if err != nil {
+		return false
+	}
+	_, exists, err := rcsw.remoteExports.GetIndexer().GetByKey(key)
+	if err != nil {
+		rcsw.log.Errorf("Failed to get ServiceExport %s: %s", key, err)
+		return false
+	}
+	return exists
+}
+
+// syncRemoteExports starts watching the ServiceExports of the target
+// cluster, and waits for the cache to be synced so that the exported
+// services are known before the initial sync.
+func (rcsw *RemoteClusterServiceWatcher) syncRemoteExports() error {
+	if rcsw.remoteExports == nil {
+		return nil
+	}
+	go rcsw.remoteExports.Run(rcsw.stopper)
+	if !cache.WaitForCacheSync(rcsw.stopper, rcsw.remoteExports.HasSynced) {
+		return errors.New("failed to sync the ServiceExports of the target cluster")
+	}
+	return nil
+}
+
+// watchRemoteExports reconciles the mirror of a remote service each time its
+// ServiceExport is created or deleted.
+func (rcsw *RemoteClusterServiceWatcher) watchRemoteExports() {
+	if rcsw.remoteExports == nil {
+		return
+	}
+	rcsw.remoteExports.AddEventHandler(
+		crash.Handler("service-mirror/remote-service-exports", cache.ResourceEventHandlerFuncs{
+			AddFunc:    rcsw.handleServiceExport,
+			UpdateFunc: func(_, obj interface{}) { rcsw.handleServiceExport(obj) },
+			DeleteFunc: rcsw.handleServiceExport,
+		}),
+	)
+}
+
+func (rcsw *RemoteClusterServiceWatcher) handleServiceExport(obj interface{}) {
+	key, err := cache.DeletionHandlingMetaNamespaceKeyFunc(obj)
+	if err != nil {
+		rcsw.log.Errorf("Failed to get key of ServiceExport %v: %s", obj, err)
+		return
+	}
+	namespace, name, err := cache.SplitMetaNamespaceKey(key)
+	if err != nil {
+		rcsw.log.Errorf("Invalid ServiceExport key %s: %s", key, err)
+		return
+	}
+	service, err := rcsw.remoteAPIClient.Svc().Lister().Services(namespace).Get(name)
+	if err != nil {
+		if !kerrors.IsNotFound(err) {
+			rcsw.log.Errorf("Failed to get exported service %s: %s", key, err)
+		}
+		// the service is handled when it's created
+		return
+	}
+	rcsw.enqueueRemoteEvent(&OnUpdateCalled{service})
+}
@@ -0,0 +1,61 @@
+package servicemirror
+
+import (
+	"testing"
+
+	consts "github.com/linkerd/linkerd2/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestIsExportedServiceWithServiceExport(t *testing.T) {
+	exports := cache.NewSharedIndexInformer(nil, &unstructured.Unstructured{}, 0, cache.Indexers{})
+	export := &unstructured.Unstructured{}
+	export.SetNamespace("ns")
+	export.SetName("exported")
+	if err := exports.GetIndexer().Add(export); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	rcsw := &RemoteClusterServiceWatcher{remoteExports: exports}
+	if !rcsw.hasServiceExport(&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "exported"}}) {
+		t.Fatal("Expected service with a ServiceExport to be exported")
+	}
+	if rcsw.hasServiceExport(&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "other"}}) {
+		t.Fatal("Expected service without a ServiceExport not to be exported")
+	}
+
+	rcsw = &RemoteClusterServiceWatcher{}
+	if rcsw.hasServiceExport(&corev1.Service{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "exported"}}) {
+		t.Fatal("Expected ServiceExports to be ignored when not watched")
+	}
+}
+
+func TestIsExportedServiceWithExportAnnotation(t *testing.T) {
+	link := harnessLink()
+	link.Name = "east"
+	rcsw := &RemoteClusterServiceWatcher{link: &link}
+
+	for _, tc := range []struct {
+		annotation string
+		exported   bool
+	}{
+		{annotation: "true", exported: true},
+		{annotation: "east", exported: true},
+		{annotation: "west, east", exported: true},
+		{annotation: "west", exported: false},
+		{annotation: "false", exported: false},
+		{annotation: "", exported: false},
+	} {
+		svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "ns",
+			Name:        "annotated",
+			Annotations: map[string]string{consts.ExportAnnotation: tc.annotation},
+		}}
+		if exported := rcsw.isExportedService(svc); exported != tc.exported {
+			t.Errorf("Expected service annotated with %q to be exported: %t, got %t", tc.annotation, tc.exported, exported)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package servicemirror
+
+import (
+	"context"
+
+	consts "github.com/linkerd/linkerd2/pkg/k8s"
+	"github.com/linkerd/linkerd2/pkg/multicluster"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// syncServiceImport creates or updates the MCS API ServiceImport describing
+// the given mirror service, so that tooling built on the Multi-Cluster
+// Services API can discover it. Failures are logged rather than retried, as
+// the ServiceImport CRD is optional.
+func (rcsw *RemoteClusterServiceWatcher) syncServiceImport(ctx context.Context, namespace, name string) {
+	if !rcsw.serviceImports || rcsw.linkClient == nil {
+		return
+	}
+	if rcsw.dryRun != nil {
+		rcsw.dryRun.write("sync", "serviceimport", namespace+"/"+name)
+		return
+	}
+
+	mirror, err := rcsw.localServices(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		rcsw.log.Errorf("Failed to get mirror service %s/%s for its ServiceImport: %s", namespace, name, err)
+		return
+	}
+	desired := rcsw.newServiceImport(mirror)
+
+	imports := rcsw.linkClient.Resource(multicluster.ServiceImportGVR).Namespace(namespace)
+	existing, err := imports.Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case kerrors.IsNotFound(err):
+		_, err = imports.Create(ctx, desired, metav1.CreateOptions{})
+	case err == nil:
+		desired.SetResourceVersion(existing.GetResourceVersion())
+		_, err = imports.Update(ctx, desired, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		rcsw.log.Errorf("Failed to sync ServiceImport %s/%s: %s", namespace, name, err)
+	}
+}
+
+// deleteServiceImport deletes the ServiceImport of the given mirror service,
+// if any.
+func (rcsw *RemoteClusterServiceWatcher) deleteServiceImport(ctx context.Context, namespace, name string) {
+	if !rcsw.serviceImports || rcsw.linkClient == nil {
+		return
+	}
+	if rcsw.dryRun != nil {
+		rcsw.dryRun.write("delete", "serviceimport", namespace+"/"+name)
+		return
+	}
+
+	err := rcsw.linkClient.Resource(multicluster.ServiceImportGVR).Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !kerrors.IsNotFound(err) {
+		rcsw.log.Errorf("Failed to delete ServiceImport %s/%s: %s", namespace, name, err)
+	}
+}
+
+func (rcsw *RemoteClusterServiceWatcher) newServiceImport(mirror *corev1.Service) *unstructured.Unstructured {
+	ports := []interface{}{}
+	for _, p := range mirror.Spec.Ports {
+		ports = append(ports, map[string]interface{}{
+			"name":     p.Name,
+			"protocol": string(p.Protocol),
+			"port":     int64(p.Port),
+		})
+	}
+	ips := []interface{}{}
+	if mirror.Spec.ClusterIP != "" && mirror.Spec.ClusterIP != corev1.ClusterIPNone {
+		ips = append(ips, mirror.Spec.ClusterIP)
+	}
+
+	labels := map[string]interface{}{}
+	for k, v := range rcsw.getMirroredServiceLabels() {
+		labels[k] = v
+	}
+
+	return &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": multicluster.MCSAPIGroupVersion,
+			"kind":       "ServiceImport",
+			"metadata": map[string]interface{}{
+				"name":      mirror.Name,
+				"namespace": mirror.Namespace,
+				"labels":    labels,
+				"annotations": map[string]interface{}{
+					consts.RemoteServiceFqName: mirror.Annotations[consts.RemoteServiceFqName],
+				},
+			},
+			"spec": map[string]interface{}{
+				"type":  "ClusterSetIP",
+				"ips":   ips,
+				"ports": ports,
+			},
+		},
+	}
+}
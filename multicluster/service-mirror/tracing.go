@@ -0,0 +1,147 @@
+package servicemirror
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"go.opencensus.io/trace"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Attributes of the spans of the mirroring operations.
+const (
+	linkAttribute             = "link"
+	targetClusterAttribute    = "target_cluster"
+	eventAttribute            = "event"
+	serviceAttribute          = "service"
+	serviceNamespaceAttribute = "service_namespace"
+	retryAttribute            = "retry"
+)
+
+// eventTraces keeps the span of the first attempt at processing each event
+// until the event succeeds or is given up on, so that the spans of its retries
+// are part of the same trace, which then shows the whole time it took to
+// converge. Its zero value is ready to use.
+type eventTraces struct {
+	sync.Mutex
+	firstAttempts map[interface{}]trace.SpanContext
+}
+
+// startEventSpan starts the span of an attempt at processing an event, with
+// the attributes of the Link, of the event's service, and the number of times
+// the event was retried. It returns a nil span for the nil events received on
+// shutdown.
+func (rcsw *RemoteClusterServiceWatcher) startEventSpan(ctx context.Context, event interface{}) (context.Context, *trace.Span) {
+	if event == nil {
+		return ctx, nil
+	}
+	name := fmt.Sprintf("service-mirror/%s", eventType(event))
+	retries := 0
+	if rcsw.eventsQueue != nil {
+		retries = rcsw.eventsQueue.NumRequeues(event)
+	}
+
+	rcsw.traces.Lock()
+	firstAttempt, retried := rcsw.traces.firstAttempts[event]
+	rcsw.traces.Unlock()
+
+	var span *trace.Span
+	if retried {
+		ctx, span = trace.StartSpanWithRemoteParent(ctx, name, firstAttempt)
+	} else {
+		ctx, span = trace.StartSpan(ctx, name)
+		rcsw.traces.Lock()
+		if rcsw.traces.firstAttempts == nil {
+			rcsw.traces.firstAttempts = make(map[interface{}]trace.SpanContext)
+		}
+		rcsw.traces.firstAttempts[event] = span.SpanContext()
+		rcsw.traces.Unlock()
+	}
+
+	attributes := append(rcsw.linkAttributes(),
+		trace.StringAttribute(eventAttribute, eventType(event)),
+		trace.Int64Attribute(retryAttribute, int64(retries)),
+	)
+	if name, namespace := eventService(event); name != "" {
+		attributes = append(attributes,
+			trace.StringAttribute(serviceAttribute, name),
+			trace.StringAttribute(serviceNamespaceAttribute, namespace),
+		)
+	}
+	span.AddAttributes(attributes...)
+	return ctx, span
+}
+
+// endEventSpan ends the span of an attempt at processing an event, with the
+// error it failed with, if any.
+func endEventSpan(span *trace.Span, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil {
+		code := trace.StatusCodeUnknown
+		if _, ok := err.(RetryableError); ok {
+			code = trace.StatusCodeUnavailable
+		}
+		span.SetStatus(trace.Status{Code: int32(code), Message: err.Error()})
+	}
+	span.End()
+}
+
+// forgetEventTrace is called once an event succeeded or was given up on.
+func (rcsw *RemoteClusterServiceWatcher) forgetEventTrace(event interface{}) {
+	rcsw.traces.Lock()
+	delete(rcsw.traces.firstAttempts, event)
+	rcsw.traces.Unlock()
+}
+
+// startServiceSpan starts the span of an operation on a mirror service, as
+// part of the processing of an event.
+func (rcsw *RemoteClusterServiceWatcher) startServiceSpan(ctx context.Context, operation string, service *corev1.Service) (context.Context, *trace.Span) {
+	ctx, span := trace.StartSpan(ctx, fmt.Sprintf("service-mirror/%s", operation))
+	span.AddAttributes(append(rcsw.linkAttributes(),
+		trace.StringAttribute(serviceAttribute, service.GetName()),
+		trace.StringAttribute(serviceNamespaceAttribute, service.GetNamespace()),
+	)...)
+	return ctx, span
+}
+
+func (rcsw *RemoteClusterServiceWatcher) linkAttributes() []trace.Attribute {
+	if rcsw.link == nil {
+		return nil
+	}
+	return []trace.Attribute{
+		trace.StringAttribute(linkAttribute, rcsw.link.Name),
+		trace.StringAttribute(targetClusterAttribute, rcsw.link.TargetClusterName),
+	}
+}
+
+// eventType returns the name of the type of an event, e.g.
+// RemoteServiceCreated.
+func eventType(event interface{}) string {
+	t := fmt.Sprintf("%T", event)
+	return t[strings.LastIndex(t, ".")+1:]
+}
+
+// eventService returns the name and namespace of the service an event is
+// about, if any.
+func eventService(event interface{}) (string, string) {
+	var svc *corev1.Service
+	switch ev := event.(type) {
+	case *OnAddCalled:
+		svc = ev.svc
+	case *OnUpdateCalled:
+		svc = ev.svc
+	case *OnDeleteCalled:
+		svc = ev.svc
+	case *RemoteServiceCreated:
+		svc = ev.service
+	case *RemoteServiceUpdated:
+		svc = ev.remoteUpdate
+	case *RemoteServiceDeleted:
+		return ev.Name, ev.Namespace
+	}
+	return svc.GetName(), svc.GetNamespace()
+}
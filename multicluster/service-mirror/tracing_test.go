@@ -0,0 +1,78 @@
+package servicemirror
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/linkerd/linkerd2/pkg/multicluster"
+	"go.opencensus.io/trace"
+	"k8s.io/client-go/util/workqueue"
+)
+
+type spanRecorder struct {
+	sync.Mutex
+	spans []*trace.SpanData
+}
+
+func (r *spanRecorder) ExportSpan(span *trace.SpanData) {
+	r.Lock()
+	defer r.Unlock()
+	r.spans = append(r.spans, span)
+}
+
+func TestEventSpans(t *testing.T) {
+	recorder := &spanRecorder{}
+	trace.RegisterExporter(recorder)
+	defer trace.UnregisterExporter(recorder)
+	trace.ApplyConfig(trace.Config{DefaultSampler: trace.AlwaysSample()})
+
+	rcsw := &RemoteClusterServiceWatcher{
+		link:        &multicluster.Link{Name: "east", TargetClusterName: "east"},
+		eventsQueue: workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+	}
+	event := &RemoteServiceDeleted{Name: "web", Namespace: "emojivoto"}
+
+	// a failed attempt, its retry, and an attempt at the same event once it
+	// succeeded
+	for i, err := range []error{RetryableError{}, nil, nil} {
+		if i == 1 {
+			rcsw.eventsQueue.AddRateLimited(event)
+		}
+		_, span := rcsw.startEventSpan(context.Background(), event)
+		endEventSpan(span, err)
+		if err == nil {
+			rcsw.eventsQueue.Forget(event)
+			rcsw.forgetEventTrace(event)
+		}
+	}
+
+	if len(recorder.spans) != 3 {
+		t.Fatalf("Expected 3 spans, got %d", len(recorder.spans))
+	}
+	first, retry, next := recorder.spans[0], recorder.spans[1], recorder.spans[2]
+	if first.Name != "service-mirror/RemoteServiceDeleted" {
+		t.Errorf("Unexpected span name %s", first.Name)
+	}
+	if first.Status.Code != trace.StatusCodeUnavailable {
+		t.Errorf("Expected the failed attempt to have the Unavailable status, got %d", first.Status.Code)
+	}
+	if retry.TraceID != first.TraceID || retry.ParentSpanID != first.SpanID {
+		t.Error("Expected the retry to be part of the trace of the first attempt")
+	}
+	if next.TraceID == first.TraceID {
+		t.Error("Expected a new trace once the event succeeded")
+	}
+
+	for key, expected := range map[string]interface{}{
+		linkAttribute:             "east",
+		targetClusterAttribute:    "east",
+		serviceAttribute:          "web",
+		serviceNamespaceAttribute: "emojivoto",
+		retryAttribute:            int64(1),
+	} {
+		if retry.Attributes[key] != expected {
+			t.Errorf("Expected the %s attribute to be %v, got %v", key, expected, retry.Attributes[key])
+		}
+	}
+}
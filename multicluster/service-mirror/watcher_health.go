@@ -0,0 +1,49 @@
+package servicemirror
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// WatcherHealth is the state of the processing of the events of a cluster
+// watcher, from which wedged watchers are detected.
+type WatcherHealth struct {
+	// Synced is true once the informers on the target cluster have synced
+	Synced bool
+	// LastProcessed is when an event was last processed successfully, or
+	// when the watcher was created if none was
+	LastProcessed time.Time
+	// Backlog is the number of events waiting to be processed, not counting
+	// the ones waiting to be retried
+	Backlog int
+	// Unreachable is true while the processing of the events is paused
+	// because the API server of the target cluster can't be reached
+	Unreachable bool
+}
+
+// Stalled returns whether events are waiting to be processed while none was
+// processed successfully for longer than the given threshold. A watcher
+// paused while its target cluster is unreachable isn't stalled, as restarting
+// it wouldn't help.
+func (h WatcherHealth) Stalled(threshold time.Duration, now time.Time) bool {
+	return !h.Unreachable && h.Backlog > 0 && now.Sub(h.LastProcessed) > threshold
+}
+
+// Health returns the state of the processing of the events of the watcher.
+func (rcsw *RemoteClusterServiceWatcher) Health() WatcherHealth {
+	synced := rcsw.remoteAPIClient.Svc().Informer().HasSynced()
+	if rcsw.remoteExports != nil {
+		synced = synced && rcsw.remoteExports.HasSynced()
+	}
+	return WatcherHealth{
+		Synced:        synced,
+		LastProcessed: time.Unix(0, atomic.LoadInt64(&rcsw.lastProcessed)),
+		Backlog:       rcsw.eventsQueue.Len(),
+		Unreachable:   rcsw.circuit.isOpen(),
+	}
+}
+
+// markProcessed records that an event was processed successfully.
+func (rcsw *RemoteClusterServiceWatcher) markProcessed() {
+	atomic.StoreInt64(&rcsw.lastProcessed, time.Now().UnixNano())
+}
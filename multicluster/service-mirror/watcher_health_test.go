@@ -0,0 +1,55 @@
+package servicemirror
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/linkerd/linkerd2/controller/k8s"
+	"k8s.io/client-go/util/workqueue"
+)
+
+func TestWatcherHealth(t *testing.T) {
+	remoteAPI, err := k8s.NewFakeAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+	remoteAPI.Sync(nil)
+
+	queue := workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter())
+	defer queue.ShutDown()
+	watcher := RemoteClusterServiceWatcher{
+		lastProcessed:   time.Now().Add(-time.Hour).UnixNano(),
+		remoteAPIClient: remoteAPI,
+		eventsQueue:     queue,
+		circuit:         newCircuitBreaker(1),
+	}
+
+	state := watcher.Health()
+	if !state.Synced {
+		t.Error("Expected the informers to be synced")
+	}
+	if state.Stalled(time.Minute, time.Now()) {
+		t.Error("Expected a watcher without events waiting not to be stalled")
+	}
+
+	queue.Add(&RepairEndpoints{})
+	state = watcher.Health()
+	if state.Backlog != 1 {
+		t.Errorf("Expected 1 event waiting, got %d", state.Backlog)
+	}
+	if !state.Stalled(time.Minute, time.Now()) {
+		t.Error("Expected a watcher with events waiting and none processed for an hour to be stalled")
+	}
+
+	watcher.circuit.record(errors.New("connection refused"))
+	if watcher.Health().Stalled(time.Minute, time.Now()) {
+		t.Error("Expected a watcher paused while its target cluster is unreachable not to be stalled")
+	}
+	watcher.circuit.record(nil)
+
+	watcher.markProcessed()
+	if watcher.Health().Stalled(time.Minute, time.Now()) {
+		t.Error("Expected a watcher that just processed an event not to be stalled")
+	}
+}
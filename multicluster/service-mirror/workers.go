@@ -0,0 +1,89 @@
+package servicemirror
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+)
+
+// workerQueueSize is the number of events that can be pending on each
+// worker before the dispatch of further events blocks.
+const workerQueueSize = 100
+
+// eventKey returns the namespace/name of the remote service an event relates
+// to, or an empty string for the events relating to the whole cluster.
+func eventKey(event interface{}) string {
+	switch ev := event.(type) {
+	case *OnAddCalled:
+		return fmt.Sprintf("%s/%s", ev.svc.Namespace, ev.svc.Name)
+	case *OnUpdateCalled:
+		return fmt.Sprintf("%s/%s", ev.svc.Namespace, ev.svc.Name)
+	case *OnDeleteCalled:
+		return fmt.Sprintf("%s/%s", ev.svc.Namespace, ev.svc.Name)
+	case *RemoteServiceCreated:
+		return fmt.Sprintf("%s/%s", ev.service.Namespace, ev.service.Name)
+	case *RemoteServiceUpdated:
+		return fmt.Sprintf("%s/%s", ev.remoteUpdate.Namespace, ev.remoteUpdate.Name)
+	case *RemoteServiceDeleted:
+		return fmt.Sprintf("%s/%s", ev.Namespace, ev.Name)
+	}
+	return ""
+}
+
+// workerIndex returns the worker the events of the given service are
+// dispatched to, so that they are processed in order by the same worker.
+func workerIndex(key string, workers int) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(workers))
+}
+
+// processEventsInParallel dispatches the events to a pool of workers. The
+// events of a given service are always handled by the same worker, in the
+// order they were received. The events relating to the whole cluster are
+// handled by the dispatcher once all the previous events have been handled,
+// and before any of the following ones are.
+func (rcsw *RemoteClusterServiceWatcher) processEventsInParallel(ctx context.Context) {
+	var inFlight sync.WaitGroup
+	workers := make([]chan interface{}, rcsw.workers)
+	for i := range workers {
+		workers[i] = make(chan interface{}, workerQueueSize)
+		go func(events <-chan interface{}) {
+			for event := range events {
+				err := rcsw.handleEvent(ctx, event, false)
+				rcsw.finishEvent(event, false, err)
+				inFlight.Done()
+			}
+		}(workers[i])
+	}
+	defer func() {
+		for _, events := range workers {
+			close(events)
+		}
+	}()
+
+	for {
+		event, done := rcsw.eventsQueue.Get()
+		// The event is held until the target cluster is reachable again
+		rcsw.circuit.wait(rcsw.stopper)
+		if done {
+			inFlight.Wait()
+			rcsw.log.Infof("Received: Stop")
+			rcsw.eventsQueue.Done(event)
+			rcsw.log.Infof("Shutting down events processor")
+			return
+		}
+		rcsw.log.Infof("Received: %s", event)
+
+		key := eventKey(event)
+		if key == "" {
+			inFlight.Wait()
+			err := rcsw.handleEvent(ctx, event, false)
+			rcsw.finishEvent(event, false, err)
+			continue
+		}
+		inFlight.Add(1)
+		workers[workerIndex(key, len(workers))] <- event
+	}
+}
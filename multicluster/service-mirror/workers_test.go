@@ -0,0 +1,79 @@
+package servicemirror
+
+import (
+	"fmt"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestEventKey(t *testing.T) {
+	svc := remoteService("service-one", "ns1", "1", nil, nil)
+	for _, tc := range []struct {
+		event    interface{}
+		expected string
+	}{
+		{&OnAddCalled{svc}, "ns1/service-one"},
+		{&OnUpdateCalled{svc}, "ns1/service-one"},
+		{&OnDeleteCalled{svc}, "ns1/service-one"},
+		{&RemoteServiceCreated{service: svc}, "ns1/service-one"},
+		{&RemoteServiceUpdated{remoteUpdate: svc}, "ns1/service-one"},
+		{&RemoteServiceDeleted{Name: "service-one", Namespace: "ns1"}, "ns1/service-one"},
+		{&RepairEndpoints{}, ""},
+		{&OrphanedServicesGcTriggered{}, ""},
+		{&ClusterUnregistered{}, ""},
+	} {
+		tc := tc // pin
+		t.Run(fmt.Sprintf("%T", tc.event), func(t *testing.T) {
+			if key := eventKey(tc.event); key != tc.expected {
+				t.Fatalf("Expected key %q, got %q", tc.expected, key)
+			}
+		})
+	}
+}
+
+func TestMirrorHarnessWithWorkers(t *testing.T) {
+	h := newMirrorHarnessWithWorkers(t, harnessLink(), 4, nil, nil)
+
+	const services = 20
+	ports := []corev1.ServicePort{{Name: "http", Protocol: "TCP", Port: 80}}
+	updatedPorts := []corev1.ServicePort{
+		{Name: "http", Protocol: "TCP", Port: 80},
+		{Name: "admin", Protocol: "TCP", Port: 9990},
+	}
+
+	for i := 0; i < services; i++ {
+		name := fmt.Sprintf("service-%d", i)
+		h.createRemote(remoteService(name, fmt.Sprintf("ns%d", i%3), "", exportedLabels(), ports))
+	}
+	for i := 0; i < services; i++ {
+		name := fmt.Sprintf("service-%d", i)
+		h.updateRemote(remoteService(name, fmt.Sprintf("ns%d", i%3), "2", exportedLabels(), updatedPorts))
+	}
+	h.eventually(func() error {
+		for i := 0; i < services; i++ {
+			name := fmt.Sprintf("service-%d", i)
+			svc, _, err := h.mirror(fmt.Sprintf("ns%d", i%3), name)
+			if err != nil {
+				return err
+			}
+			if len(svc.Spec.Ports) != 2 {
+				return fmt.Errorf("expected 2 ports on the mirror of %s, got %v", name, svc.Spec.Ports)
+			}
+		}
+		return nil
+	})
+
+	for i := 0; i < services; i++ {
+		h.deleteRemote(fmt.Sprintf("ns%d", i%3), fmt.Sprintf("service-%d", i))
+	}
+	h.eventually(func() error {
+		for i := 0; i < services; i++ {
+			name := fmt.Sprintf("service-%d", i)
+			if _, _, err := h.mirror(fmt.Sprintf("ns%d", i%3), name); err == nil {
+				return fmt.Errorf("mirror of %s still exists", name)
+			}
+		}
+		return nil
+	})
+}
@@ -18,23 +18,55 @@ const (
 
 // Values contains the top-level elements in the Helm charts
 type Values struct {
-	CliVersion                     string   `json:"cliVersion"`
-	ControllerImage                string   `json:"controllerImage"`
-	ControllerImageVersion         string   `json:"controllerImageVersion"`
-	Gateway                        *Gateway `json:"gateway"`
-	IdentityTrustDomain            string   `json:"identityTrustDomain"`
-	InstallNamespace               bool     `json:"installNamespace"`
-	LinkerdNamespace               string   `json:"linkerdNamespace"`
-	LinkerdVersion                 string   `json:"linkerdVersion"`
-	Namespace                      string   `json:"namespace"`
-	ProxyOutboundPort              uint32   `json:"proxyOutboundPort"`
-	ServiceMirror                  bool     `json:"serviceMirror"`
-	LogLevel                       string   `json:"logLevel"`
-	ServiceMirrorRetryLimit        uint32   `json:"serviceMirrorRetryLimit"`
-	ServiceMirrorUID               int64    `json:"serviceMirrorUID"`
-	RemoteMirrorServiceAccount     bool     `json:"remoteMirrorServiceAccount"`
-	RemoteMirrorServiceAccountName string   `json:"remoteMirrorServiceAccountName"`
-	TargetClusterName              string   `json:"targetClusterName"`
+	CliVersion                          string             `json:"cliVersion"`
+	ControllerImage                     string             `json:"controllerImage"`
+	ControllerImageVersion              string             `json:"controllerImageVersion"`
+	CredentialPlugins                   *CredentialPlugins `json:"credentialPlugins"`
+	EnableRemoteServiceExports          bool               `json:"enableRemoteServiceExports"`
+	EnableServiceImports                bool               `json:"enableServiceImports"`
+	Gateway                             *Gateway           `json:"gateway"`
+	IdentityTrustDomain                 string             `json:"identityTrustDomain"`
+	InstallNamespace                    bool               `json:"installNamespace"`
+	LinkerdNamespace                    string             `json:"linkerdNamespace"`
+	LinkerdVersion                      string             `json:"linkerdVersion"`
+	Namespace                           string             `json:"namespace"`
+	ProxyOutboundPort                   uint32             `json:"proxyOutboundPort"`
+	ServiceMirror                       bool               `json:"serviceMirror"`
+	ServiceMirrorAllLinks               bool               `json:"serviceMirrorAllLinks"`
+	LogLevel                            string             `json:"logLevel"`
+	ServiceMirrorDryRun                 bool               `json:"serviceMirrorDryRun"`
+	ServiceMirrorEventStallThreshold    string             `json:"serviceMirrorEventStallThreshold"`
+	ServiceMirrorLocalAPIQPS            float64            `json:"serviceMirrorLocalAPIQPS"`
+	ServiceMirrorLocalAPIBurst          uint32             `json:"serviceMirrorLocalAPIBurst"`
+	ServiceMirrorRemoteAPIQPS           float64            `json:"serviceMirrorRemoteAPIQPS"`
+	ServiceMirrorRemoteAPIBurst         uint32             `json:"serviceMirrorRemoteAPIBurst"`
+	ServiceMirrorRemoteCheckPeriod      string             `json:"serviceMirrorRemoteCheckPeriod"`
+	ServiceMirrorRemoteCheckTimeout     string             `json:"serviceMirrorRemoteCheckTimeout"`
+	ServiceMirrorRemoteFailureThreshold uint32             `json:"serviceMirrorRemoteFailureThreshold"`
+	ServiceMirrorReplicas               uint32             `json:"serviceMirrorReplicas"`
+	ServiceMirrorRetryLimit             uint32             `json:"serviceMirrorRetryLimit"`
+	ServiceMirrorRetryBaseDelay         string             `json:"serviceMirrorRetryBaseDelay"`
+	ServiceMirrorRetryMaxDelay          string             `json:"serviceMirrorRetryMaxDelay"`
+	ServiceMirrorRetryQPS               float64            `json:"serviceMirrorRetryQPS"`
+	ServiceMirrorRetryBurst             uint32             `json:"serviceMirrorRetryBurst"`
+	ServiceMirrorTraceCollector         string             `json:"serviceMirrorTraceCollector"`
+	ServiceMirrorUID                    int64              `json:"serviceMirrorUID"`
+	ServiceMirrorWorkers                uint32             `json:"serviceMirrorWorkers"`
+	ServiceMirrorWriteAttempts          uint32             `json:"serviceMirrorWriteAttempts"`
+	ServiceMirrorWriteBackoffBaseDelay  string             `json:"serviceMirrorWriteBackoffBaseDelay"`
+	ServiceMirrorWriteBackoffMaxDelay   string             `json:"serviceMirrorWriteBackoffMaxDelay"`
+	RemoteMirrorServiceAccount          bool               `json:"remoteMirrorServiceAccount"`
+	RemoteMirrorServiceAccountName      string             `json:"remoteMirrorServiceAccountName"`
+	ServiceExport                       *ServiceExport     `json:"serviceExport"`
+	ServiceFederation                   *ServiceFederation `json:"serviceFederation"`
+	TargetClusterName                   string             `json:"targetClusterName"`
+}
+
+// CredentialPlugins contains the options of the image providing the exec
+// credential plugins of the kubeconfig of the target cluster
+type CredentialPlugins struct {
+	Image string `json:"image"`
+	Path  string `json:"path"`
 }
 
 // Gateway contains all options related to the Gateway Service
@@ -49,6 +81,21 @@ type Gateway struct {
 	LoadBalancerIP     string            `json:"loadBalancerIP"`
 }
 
+// ServiceExport contains all options related to the ServiceExport controller
+type ServiceExport struct {
+	Enabled  bool   `json:"enabled"`
+	Image    string `json:"image"`
+	LogLevel string `json:"logLevel"`
+}
+
+// ServiceFederation contains all options related to the service federation
+// controller
+type ServiceFederation struct {
+	Enabled  bool   `json:"enabled"`
+	Image    string `json:"image"`
+	LogLevel string `json:"logLevel"`
+}
+
 // Probe contains all options for the Probe Service
 type Probe struct {
 	Path     string `json:"path"`
@@ -5,21 +5,55 @@ import (
 	"net/http"
 	"net/http/pprof"
 	"strings"
+	"sync/atomic"
 
+	"github.com/linkerd/linkerd2/pkg/health"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
 )
 
+// draining is set once the component started draining its connections
+// before shutting down.
+var draining int32
+
 type handler struct {
-	promHandler http.Handler
+	promHandler   http.Handler
+	healthHandler http.Handler
+	logLevels     http.Handler
+	handlers      map[string]http.Handler
+}
+
+// SetDraining makes the readiness endpoint of the admin server fail, so that
+// the component is taken out of the load balancing while it drains its
+// connections before shutting down.
+func SetDraining() {
+	atomic.StoreInt32(&draining, 1)
 }
 
 // StartServer starts an admin server listening on a given address.
 func StartServer(addr string) {
+	StartServerWithHealth(addr, nil)
+}
+
+// StartServerWithHealth starts an admin server listening on a given address,
+// which also serves the health report of an extension component.
+func StartServerWithHealth(addr string, reporter *health.Reporter) {
+	StartServerWithHandlers(addr, reporter, nil)
+}
+
+// StartServerWithHandlers starts an admin server listening on a given address,
+// which also serves the health report of an extension component, if any, and
+// the given component-specific handlers, keyed by path.
+func StartServerWithHandlers(addr string, reporter *health.Reporter, handlers map[string]http.Handler) {
 	log.Infof("starting admin server on %s", addr)
 
 	h := &handler{
 		promHandler: promhttp.Handler(),
+		logLevels:   newLogLevelHandler(levels, (&kubernetesAuthorizer{}).authorize),
+		handlers:    handlers,
+	}
+	if reporter != nil {
+		h.healthHandler = reporter
 	}
 
 	log.Fatal(http.ListenAndServe(addr, h))
@@ -34,6 +68,14 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		h.servePing(w)
 	case "/ready":
 		h.serveReady(w)
+	case health.Path:
+		if h.healthHandler == nil {
+			http.NotFound(w, req)
+			return
+		}
+		h.healthHandler.ServeHTTP(w, req)
+	case LogLevelPath:
+		h.logLevels.ServeHTTP(w, req)
 	case fmt.Sprintf("%scmdline", debugPathPrefix):
 		pprof.Cmdline(w, req)
 	case fmt.Sprintf("%sprofile", debugPathPrefix):
@@ -43,7 +85,9 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	case fmt.Sprintf("%ssymbol", debugPathPrefix):
 		pprof.Symbol(w, req)
 	default:
-		if strings.HasPrefix(req.URL.Path, "/debug/pprof/") {
+		if handler, ok := h.handlers[req.URL.Path]; ok {
+			handler.ServeHTTP(w, req)
+		} else if strings.HasPrefix(req.URL.Path, "/debug/pprof/") {
 			pprof.Index(w, req)
 		} else {
 			http.NotFound(w, req)
@@ -56,5 +100,9 @@ func (h *handler) servePing(w http.ResponseWriter) {
 }
 
 func (h *handler) serveReady(w http.ResponseWriter) {
+	if atomic.LoadInt32(&draining) == 1 {
+		http.Error(w, "draining", http.StatusServiceUnavailable)
+		return
+	}
 	w.Write([]byte("ok\n"))
 }
@@ -0,0 +1,260 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/linkerd/linkerd2/pkg/flags"
+	log "github.com/sirupsen/logrus"
+	authnv1 "k8s.io/api/authentication/v1"
+	authzv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// LogLevelPath is the path of the admin endpoint that serves the log levels
+// of a component on GET, and changes them on PUT.
+const LogLevelPath = "/log-level"
+
+// moduleField is the field of the log entries that identifies the module,
+// e.g. the endpoints-watcher, they were logged by.
+const moduleField = "component"
+
+type (
+	// LogLevels is the body of the requests and responses of the log level
+	// endpoint. Modules maps the modules to the level of their logs,
+	// overriding the level of the component; on PUT, an empty level resets
+	// a module to the level of the component.
+	LogLevels struct {
+		Level   string            `json:"level,omitempty"`
+		Modules map[string]string `json:"modules,omitempty"`
+	}
+
+	// logLevels holds the log levels of the running component.
+	logLevels struct {
+		sync.RWMutex
+		level   log.Level
+		modules map[string]log.Level
+	}
+
+	// moduleFormatter drops the entries of the modules whose level is less
+	// verbose than the level of the logger, which is the most verbose of the
+	// levels of the component and of its modules.
+	moduleFormatter struct {
+		log.Formatter
+		levels *logLevels
+	}
+
+	logLevelHandler struct {
+		levels    *logLevels
+		authorize func(*http.Request) (int, error)
+	}
+)
+
+// levels holds the log levels of the process, which are global to logrus.
+var levels = &logLevels{modules: map[string]log.Level{}}
+
+// set applies the given levels, and returns the resulting ones.
+func (l *logLevels) set(update LogLevels) (LogLevels, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	level := log.GetLevel()
+	if len(l.modules) > 0 {
+		level = l.level
+	}
+	if update.Level != "" {
+		parsed, err := log.ParseLevel(update.Level)
+		if err != nil {
+			return LogLevels{}, err
+		}
+		level = parsed
+	}
+	modules := make(map[string]log.Level, len(l.modules))
+	for module, moduleLevel := range l.modules {
+		modules[module] = moduleLevel
+	}
+	for module, moduleLevel := range update.Modules {
+		if module == "" {
+			return LogLevels{}, errors.New("module names can't be empty")
+		}
+		if moduleLevel == "" {
+			delete(modules, module)
+			continue
+		}
+		parsed, err := log.ParseLevel(moduleLevel)
+		if err != nil {
+			return LogLevels{}, fmt.Errorf("invalid level of module %s: %s", module, err)
+		}
+		modules[module] = parsed
+	}
+
+	l.level = level
+	l.modules = modules
+	flags.SetLogLevel(level)
+	effective := level
+	for _, moduleLevel := range modules {
+		if moduleLevel > effective {
+			effective = moduleLevel
+		}
+	}
+	log.SetLevel(effective)
+	if _, ok := log.StandardLogger().Formatter.(*moduleFormatter); !ok && len(modules) > 0 {
+		log.SetFormatter(&moduleFormatter{Formatter: log.StandardLogger().Formatter, levels: l})
+	}
+	return l.current(), nil
+}
+
+// get returns the current levels.
+func (l *logLevels) get() LogLevels {
+	l.RLock()
+	defer l.RUnlock()
+	return l.current()
+}
+
+// current must be called with the lock held.
+func (l *logLevels) current() LogLevels {
+	current := LogLevels{Level: log.GetLevel().String()}
+	if len(l.modules) == 0 {
+		return current
+	}
+	current.Level = l.level.String()
+	current.Modules = make(map[string]string, len(l.modules))
+	for module, level := range l.modules {
+		current.Modules[module] = level.String()
+	}
+	return current
+}
+
+// enabled returns whether an entry of the given module is logged at the
+// given level.
+func (l *logLevels) enabled(module string, level log.Level) bool {
+	l.RLock()
+	defer l.RUnlock()
+	if moduleLevel, ok := l.modules[module]; ok {
+		return level <= moduleLevel
+	}
+	return level <= l.level || len(l.modules) == 0
+}
+
+func (f *moduleFormatter) Format(entry *log.Entry) ([]byte, error) {
+	module, _ := entry.Data[moduleField].(string)
+	if !f.levels.enabled(module, entry.Level) {
+		return nil, nil
+	}
+	return f.Formatter.Format(entry)
+}
+
+func newLogLevelHandler(levels *logLevels, authorize func(*http.Request) (int, error)) *logLevelHandler {
+	return &logLevelHandler{levels: levels, authorize: authorize}
+}
+
+func (h *logLevelHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var current LogLevels
+	switch req.Method {
+	case http.MethodGet:
+		current = h.levels.get()
+	case http.MethodPut:
+		if status, err := h.authorize(req); err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		var update LogLevels
+		if err := json.NewDecoder(req.Body).Decode(&update); err != nil {
+			http.Error(w, fmt.Sprintf("invalid log levels: %s", err), http.StatusBadRequest)
+			return
+		}
+		var err error
+		current, err = h.levels.set(update)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Infof("Log levels changed to %+v", current)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(current)
+}
+
+// kubernetesAuthorizer authorizes the changes of the log levels with the
+// Kubernetes API, like the API server authorizes its own requests: the bearer
+// token of a request is authenticated with a TokenReview, and its user must
+// be allowed to PUT the LogLevelPath non-resource URL, which cluster admins
+// are. This requires the component to be bound to the system:auth-delegator
+// ClusterRole.
+type kubernetesAuthorizer struct {
+	once   sync.Once
+	client kubernetes.Interface
+	err    error
+}
+
+func (a *kubernetesAuthorizer) authorize(req *http.Request) (int, error) {
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	if token == "" || token == req.Header.Get("Authorization") {
+		return http.StatusUnauthorized, errors.New("changing the log levels requires a bearer token")
+	}
+
+	a.once.Do(func() {
+		if a.client != nil {
+			return
+		}
+		var config *rest.Config
+		config, a.err = rest.InClusterConfig()
+		if a.err == nil {
+			a.client, a.err = kubernetes.NewForConfig(config)
+		}
+	})
+	if a.err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("failed to initialize the Kubernetes client: %s", a.err)
+	}
+	return authorizeToken(req.Context(), a.client, token)
+}
+
+// authorizeToken authenticates the given token and checks that its user is
+// allowed to change the log levels.
+func authorizeToken(ctx context.Context, client kubernetes.Interface, token string) (int, error) {
+	review, err := client.AuthenticationV1().TokenReviews().Create(ctx, &authnv1.TokenReview{
+		Spec: authnv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("failed to authenticate the token: %s", err)
+	}
+	if !review.Status.Authenticated {
+		return http.StatusUnauthorized, fmt.Errorf("invalid token: %s", review.Status.Error)
+	}
+
+	user := review.Status.User
+	extra := make(map[string]authzv1.ExtraValue, len(user.Extra))
+	for k, v := range user.Extra {
+		extra[k] = authzv1.ExtraValue(v)
+	}
+	access, err := client.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authzv1.SubjectAccessReview{
+		Spec: authzv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			Groups: user.Groups,
+			UID:    user.UID,
+			Extra:  extra,
+			NonResourceAttributes: &authzv1.NonResourceAttributes{
+				Path: LogLevelPath,
+				Verb: "put",
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return http.StatusInternalServerError, fmt.Errorf("failed to authorize %s: %s", user.Username, err)
+	}
+	if !access.Status.Allowed {
+		return http.StatusForbidden, fmt.Errorf("%s isn't allowed to change the log levels: %s", user.Username, access.Status.Reason)
+	}
+	return http.StatusOK, nil
+}
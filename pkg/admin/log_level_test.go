@@ -0,0 +1,128 @@
+package admin
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	authnv1 "k8s.io/api/authentication/v1"
+	authzv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// withLogger runs the test with a fresh set of log levels and restores the
+// standard logger afterwards.
+func withLogger(t *testing.T) (*logLevels, *bytes.Buffer) {
+	logger := log.StandardLogger()
+	level, formatter, out := logger.GetLevel(), logger.Formatter, logger.Out
+	t.Cleanup(func() {
+		logger.SetLevel(level)
+		logger.SetFormatter(formatter)
+		logger.SetOutput(out)
+	})
+
+	var buf bytes.Buffer
+	logger.SetOutput(&buf)
+	logger.SetFormatter(&log.TextFormatter{DisableTimestamp: true})
+	logger.SetLevel(log.InfoLevel)
+	return &logLevels{modules: map[string]log.Level{}}, &buf
+}
+
+func TestModuleLevels(t *testing.T) {
+	levels, buf := withLogger(t)
+
+	if _, err := levels.set(LogLevels{Modules: map[string]string{"endpoints-watcher": "debug", "server": "warn"}}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	log.WithField("component", "endpoints-watcher").Debug("watcher debug")
+	log.WithField("component", "profile-watcher").Debug("profile debug")
+	log.WithField("component", "server").Info("server info")
+	log.Info("global info")
+
+	logs := buf.String()
+	for _, expected := range []string{"watcher debug", "global info"} {
+		if !strings.Contains(logs, expected) {
+			t.Errorf("Expected %q to be logged, got %s", expected, logs)
+		}
+	}
+	for _, unexpected := range []string{"profile debug", "server info"} {
+		if strings.Contains(logs, unexpected) {
+			t.Errorf("Expected %q not to be logged, got %s", unexpected, logs)
+		}
+	}
+
+	current, err := levels.set(LogLevels{Level: "warn", Modules: map[string]string{"endpoints-watcher": ""}})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if current.Level != "warning" || len(current.Modules) != 1 || current.Modules["server"] != "warning" {
+		t.Errorf("Unexpected levels %+v", current)
+	}
+
+	if _, err := levels.set(LogLevels{Modules: map[string]string{"server": "loud"}}); err == nil {
+		t.Error("Expected an invalid level to be rejected")
+	}
+}
+
+func TestLogLevelHandler(t *testing.T) {
+	levels, _ := withLogger(t)
+
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authnv1.TokenReview)
+		switch review.Spec.Token {
+		case "admin-token":
+			review.Status = authnv1.TokenReviewStatus{Authenticated: true, User: authnv1.UserInfo{Username: "admin"}}
+		case "viewer-token":
+			review.Status = authnv1.TokenReviewStatus{Authenticated: true, User: authnv1.UserInfo{Username: "viewer"}}
+		}
+		return true, review, nil
+	})
+	client.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authzv1.SubjectAccessReview)
+		review.Status.Allowed = review.Spec.User == "admin" &&
+			review.Spec.NonResourceAttributes.Path == LogLevelPath && review.Spec.NonResourceAttributes.Verb == "put"
+		return true, review, nil
+	})
+	handler := newLogLevelHandler(levels, (&kubernetesAuthorizer{client: client}).authorize)
+
+	for _, tc := range []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{name: "no token", status: http.StatusUnauthorized},
+		{name: "invalid token", token: "bogus", status: http.StatusUnauthorized},
+		{name: "forbidden", token: "viewer-token", status: http.StatusForbidden},
+		{name: "allowed", token: "admin-token", status: http.StatusOK},
+	} {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, LogLevelPath, strings.NewReader(`{"level": "debug"}`))
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Errorf("Expected status %d, got %d: %s", tc.status, rec.Code, rec.Body)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, LogLevelPath, nil))
+	var current LogLevels
+	if err := json.NewDecoder(rec.Body).Decode(&current); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if current.Level != "debug" {
+		t.Errorf("Expected the level to be changed to debug, got %+v", current)
+	}
+}
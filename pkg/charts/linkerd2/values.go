@@ -26,6 +26,7 @@ type (
 	// Values contains the top-level elements in the Helm charts
 	Values struct {
 		ControllerImage              string              `json:"controllerImage"`
+		ControllerImageDigest        string              `json:"controllerImageDigest,omitempty"`
 		ControllerReplicas           uint                `json:"controllerReplicas"`
 		ControllerUID                int64               `json:"controllerUID"`
 		EnableH2Upgrade              bool                `json:"enableH2Upgrade"`
@@ -33,6 +34,7 @@ type (
 		WebhookFailurePolicy         string              `json:"webhookFailurePolicy"`
 		OmitWebhookSideEffects       bool                `json:"omitWebhookSideEffects"`
 		DisableHeartBeat             bool                `json:"disableHeartBeat"`
+		DisableProfileValidator      bool                `json:"disableProfileValidator,omitempty"`
 		HeartbeatSchedule            string              `json:"heartbeatSchedule"`
 		InstallNamespace             bool                `json:"installNamespace"`
 		Configs                      ConfigJSONs         `json:"configs"`
@@ -59,6 +61,7 @@ type (
 
 		PodAnnotations map[string]string `json:"podAnnotations"`
 		PodLabels      map[string]string `json:"podLabels"`
+		FeatureGates   map[string]bool   `json:"featureGates,omitempty"`
 
 		Proxy            *Proxy            `json:"proxy"`
 		ProxyInit        *ProxyInit        `json:"proxyInit"`
@@ -107,9 +110,16 @@ type (
 		RequireIdentityOnInboundPorts string           `json:"requireIdentityOnInboundPorts"`
 		OutboundConnectTimeout        string           `json:"outboundConnectTimeout"`
 		InboundConnectTimeout         string           `json:"inboundConnectTimeout"`
+		InboundMaxInFlight            uint32           `json:"inboundMaxInFlight,omitempty"`
+		OutboundMaxInFlight           uint32           `json:"outboundMaxInFlight,omitempty"`
+		BufferCapacity                uint32           `json:"bufferCapacity,omitempty"`
 		PodInboundPorts               string           `json:"podInboundPorts"`
 		OpaquePorts                   string           `json:"opaquePorts"`
 		Await                         bool             `json:"await"`
+		JobShutdown                   bool             `json:"jobShutdown,omitempty"`
+		// ReadOnlyRootFilesystem defaults to true when unset
+		ReadOnlyRootFilesystem *bool           `json:"readOnlyRootFilesystem,omitempty"`
+		SeccompProfile         *SeccompProfile `json:"seccompProfile,omitempty"`
 	}
 
 	// ProxyInit contains the fields to set the proxy-init container
@@ -122,6 +132,9 @@ type (
 		XTMountPath          *VolumeMountPath `json:"xtMountPath"`
 		Resources            *Resources       `json:"resources"`
 		CloseWaitTimeoutSecs int64            `json:"closeWaitTimeoutSecs"`
+		// ReadOnlyRootFilesystem defaults to true when unset
+		ReadOnlyRootFilesystem *bool           `json:"readOnlyRootFilesystem,omitempty"`
+		SeccompProfile         *SeccompProfile `json:"seccompProfile,omitempty"`
 	}
 
 	// DebugContainer contains the fields to set the debugging sidecar
@@ -134,6 +147,8 @@ type (
 		Name       string `json:"name"`
 		PullPolicy string `json:"pullPolicy"`
 		Version    string `json:"version"`
+		// Digest, when set, pins the image to the given digest of the tag
+		Digest string `json:"digest,omitempty"`
 	}
 
 	// Ports contains all the port-related setups
@@ -157,6 +172,13 @@ type (
 		Drop []string `json:"drop"`
 	}
 
+	// SeccompProfile contains the SecurityContext seccomp profile of the
+	// injected containers
+	SeccompProfile struct {
+		Type             string `json:"type,omitempty"`
+		LocalhostProfile string `json:"localhostProfile,omitempty"`
+	}
+
 	// VolumeMountPath contains the details for volume mounts
 	VolumeMountPath struct {
 		Name      string `json:"name"`
@@ -173,12 +195,15 @@ type (
 	// Identity contains the fields to set the identity variables in the proxy
 	// sidecar container
 	Identity struct {
-		Issuer *Issuer `json:"issuer"`
+		// ExternalCA is set when the linkerd-identity-trust-roots ConfigMap is
+		// managed externally, in which case the trust anchors are read from
+		// it rather than from IdentityTrustAnchorsPEM
+		ExternalCA bool    `json:"externalCA"`
+		Issuer     *Issuer `json:"issuer"`
 	}
 
 	// Issuer has the Helm variables of the identity issuer
 	Issuer struct {
-		ExternalCA         bool       `json:"externalCA"`
 		Scheme             string     `json:"scheme"`
 		ClockSkewAllowance string     `json:"clockSkewAllowance"`
 		IssuanceLifetime   string     `json:"issuanceLifetime"`
@@ -190,12 +215,19 @@ type (
 	ProxyInjector struct {
 		*TLS
 		NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector"`
+		AllowedRegistries []string              `json:"allowedRegistries,omitempty"`
+		ServicePort       uint32                `json:"servicePort,omitempty"`
+		ContainerPort     uint32                `json:"containerPort,omitempty"`
+		BindAddress       string                `json:"bindAddress,omitempty"`
 	}
 
 	// ProfileValidator has all the profile validator's Helm variables
 	ProfileValidator struct {
 		*TLS
 		NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector"`
+		ServicePort       uint32                `json:"servicePort,omitempty"`
+		ContainerPort     uint32                `json:"containerPort,omitempty"`
+		BindAddress       string                `json:"bindAddress,omitempty"`
 	}
 
 	// TLS has a pair of PEM-encoded key and certificate variables used in the
@@ -16,6 +16,7 @@ func TestNewValues(t *testing.T) {
 	}
 
 	testVersion := "linkerd-dev"
+	readOnlyRootFilesystem := true
 
 	namespaceSelector := &metav1.LabelSelector{
 		MatchExpressions: []metav1.LabelSelectorRequirement{
@@ -84,6 +85,8 @@ func TestNewValues(t *testing.T) {
 			InboundConnectTimeout:  "100ms",
 			OpaquePorts:            "25,443,587,3306,4444,5432,6379,9300,11211",
 			Await:                  true,
+			ReadOnlyRootFilesystem: &readOnlyRootFilesystem,
+			SeccompProfile:         &SeccompProfile{},
 		},
 		ProxyInit: &ProxyInit{
 			IgnoreInboundPorts:  "4567,4568",
@@ -106,6 +109,8 @@ func TestNewValues(t *testing.T) {
 				Name:      "linkerd-proxy-init-xtables-lock",
 				MountPath: "/run",
 			},
+			ReadOnlyRootFilesystem: &readOnlyRootFilesystem,
+			SeccompProfile:         &SeccompProfile{},
 		},
 		Identity: &Identity{
 			Issuer: &Issuer{
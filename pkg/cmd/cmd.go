@@ -6,11 +6,13 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/linkerd/linkerd2/pkg/k8s"
 	"github.com/linkerd/linkerd2/pkg/k8s/resource"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
@@ -40,9 +42,14 @@ func GetDefaultNamespace(kubeconfigPath, kubeContext string) string {
 	return ns
 }
 
+// PreUninstallHook is run by Uninstall before the resources to delete are
+// printed, so that an extension can save or protect the data that would be
+// lost along with them. Returning an error aborts the uninstall.
+type PreUninstallHook func(ctx context.Context, k8sAPI *k8s.KubernetesAPI, selector string) error
+
 // Uninstall prints all cluster-scoped resources matching the given selector
-// for the purposes of deleting them.
-func Uninstall(ctx context.Context, k8sAPI *k8s.KubernetesAPI, selector string) error {
+// for the purposes of deleting them, once all the given hooks have run.
+func Uninstall(ctx context.Context, k8sAPI *k8s.KubernetesAPI, selector string, hooks ...PreUninstallHook) error {
 	resources, err := resource.FetchKubernetesResources(ctx, k8sAPI,
 		metav1.ListOptions{LabelSelector: selector},
 	)
@@ -53,6 +60,11 @@ func Uninstall(ctx context.Context, k8sAPI *k8s.KubernetesAPI, selector string)
 	if len(resources) == 0 {
 		return errors.New("No resources found to uninstall")
 	}
+	for _, hook := range hooks {
+		if err := hook(ctx, k8sAPI, selector); err != nil {
+			return err
+		}
+	}
 	for _, r := range resources {
 		if err := r.RenderResource(os.Stdout); err != nil {
 			return fmt.Errorf("error rendering Kubernetes resource: %v", err)
@@ -61,20 +73,50 @@ func Uninstall(ctx context.Context, k8sAPI *k8s.KubernetesAPI, selector string)
 	return nil
 }
 
+// AddKubeFlags registers the flags selecting the kubeconfig, context and
+// impersonated identity used to talk to the Kubernetes API, so that they are
+// defined identically by the linkerd CLI and its extensions. When running as
+// a kubectl plugin, they default to the values of kubectl's own flags.
+func AddKubeFlags(flags *pflag.FlagSet, kubeconfigPath, kubeContext, impersonate *string, impersonateGroup *[]string) {
+	flags.StringVar(kubeconfigPath, "kubeconfig", kubeFlagDefault("kubeconfig"), "Path to the kubeconfig file to use for CLI requests")
+	flags.StringVar(kubeContext, "context", kubeFlagDefault("context"), "Name of the kubeconfig context to use")
+	flags.StringVar(impersonate, "as", kubeFlagDefault("as"), "Username to impersonate for Kubernetes operations")
+	flags.StringArrayVar(impersonateGroup, "as-group", []string{}, "Group to impersonate for Kubernetes operations")
+}
+
+// NewAPIFromFlags returns a Kubernetes API client configured by the flags
+// registered with AddKubeFlags on the command or its parents. The flags are
+// read when called, so that completion functions registered before the
+// command line is parsed honor them.
+func NewAPIFromFlags(cmd *cobra.Command, timeout time.Duration) (*k8s.KubernetesAPI, error) {
+	kubeconfigPath, kubeContext, impersonate, impersonateGroup := kubeFlagValues(cmd)
+	return k8s.NewAPI(kubeconfigPath, kubeContext, impersonate, impersonateGroup, timeout)
+}
+
+func kubeFlagValues(cmd *cobra.Command) (kubeconfigPath, kubeContext, impersonate string, impersonateGroup []string) {
+	impersonateGroup = []string{}
+	if f := cmd.Flag("as-group"); f != nil {
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			impersonateGroup = sv.GetSlice()
+		}
+	}
+	return flagValue(cmd, "kubeconfig"), flagValue(cmd, "context"), flagValue(cmd, "as"), impersonateGroup
+}
+
+func flagValue(cmd *cobra.Command, name string) string {
+	if f := cmd.Flag(name); f != nil {
+		return f.Value.String()
+	}
+	return ""
+}
+
 // ConfigureNamespaceFlagCompletion sets up resource-aware completion for command
 // flags that accept a namespace name
-func ConfigureNamespaceFlagCompletion(
-	cmd *cobra.Command,
-	flagNames []string,
-	kubeconfigPath string,
-	impersonate string,
-	impersonateGroup []string,
-	kubeContext string,
-) {
+func ConfigureNamespaceFlagCompletion(cmd *cobra.Command, flagNames []string) {
 	for _, flagName := range flagNames {
 		cmd.RegisterFlagCompletionFunc(flagName,
 			func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-				k8sAPI, err := k8s.NewAPI(kubeconfigPath, kubeContext, impersonate, impersonateGroup, 0)
+				k8sAPI, err := NewAPIFromFlags(cmd, 0)
 				if err != nil {
 					return nil, cobra.ShellCompDirectiveError
 				}
@@ -101,11 +143,11 @@ func ConfigureOutputFlagCompletion(cmd *cobra.Command) {
 
 // ConfigureKubeContextFlagCompletion sets up resource-aware completion for command
 // flags based off of a kubeconfig
-func ConfigureKubeContextFlagCompletion(cmd *cobra.Command, kubeconfigPath string) {
+func ConfigureKubeContextFlagCompletion(cmd *cobra.Command) {
 	cmd.RegisterFlagCompletionFunc("context",
 		func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			rules := clientcmd.NewDefaultClientConfigLoadingRules()
-			rules.ExplicitPath = kubeconfigPath
+			rules.ExplicitPath = flagValue(cmd, "kubeconfig")
 			loader := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{})
 			config, err := loader.RawConfig()
 			if err != nil {
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestKubeFlagValues(t *testing.T) {
+	var kubeconfigPath, kubeContext, impersonate string
+	var impersonateGroup []string
+
+	root := &cobra.Command{Use: "root"}
+	AddKubeFlags(root.PersistentFlags(), &kubeconfigPath, &kubeContext, &impersonate, &impersonateGroup)
+	child := &cobra.Command{Use: "child", Run: func(*cobra.Command, []string) {}}
+	root.AddCommand(child)
+
+	root.SetArgs([]string{"child", "--kubeconfig", "/tmp/config", "--context", "east", "--as", "alice", "--as-group", "devs", "--as-group", "ops"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	path, context, user, groups := kubeFlagValues(child)
+	if path != "/tmp/config" || context != "east" || user != "alice" {
+		t.Fatalf("Unexpected flag values: %s, %s, %s", path, context, user)
+	}
+	if !reflect.DeepEqual(groups, []string{"devs", "ops"}) {
+		t.Fatalf("Expected groups [devs ops], got %v", groups)
+	}
+}
+
+func TestIsKubectlPlugin(t *testing.T) {
+	for path, expected := range map[string]bool{
+		"linkerd":                              false,
+		"/usr/local/bin/linkerd":               false,
+		"/home/me/.krew/bin/kubectl-linkerd":   true,
+		`C:\krew\bin\kubectl-linkerd.exe`:      true,
+		"/usr/local/bin/kubectl-linkerd-viz":   false,
+		"/usr/local/bin/linkerd2-cli-edge-rc1": false,
+	} {
+		if IsKubectlPlugin(path) != expected {
+			t.Errorf("Expected IsKubectlPlugin(%s) to be %t", path, expected)
+		}
+	}
+}
+
+func TestKubeFlagsFromKubectl(t *testing.T) {
+	for name, value := range map[string]string{
+		"KUBECTL_PLUGINS_CALLER":                 "/usr/local/bin/kubectl",
+		"KUBECTL_PLUGINS_GLOBAL_FLAG_KUBECONFIG": "/tmp/config",
+		"KUBECTL_PLUGINS_GLOBAL_FLAG_CONTEXT":    "east",
+	} {
+		os.Setenv(name, value)
+		defer os.Unsetenv(name)
+	}
+
+	var kubeconfigPath, kubeContext, impersonate string
+	var impersonateGroup []string
+	root := &cobra.Command{Use: "root", Run: func(*cobra.Command, []string) {}}
+	AddKubeFlags(root.PersistentFlags(), &kubeconfigPath, &kubeContext, &impersonate, &impersonateGroup)
+
+	root.SetArgs([]string{"--context", "west"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if kubeconfigPath != "/tmp/config" || kubeContext != "west" || impersonate != "" {
+		t.Fatalf("Unexpected flag values: %s, %s, %s", kubeconfigPath, kubeContext, impersonate)
+	}
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// KubectlPluginName is the name under which the linkerd CLI is installed to
+// run as a kubectl plugin, e.g. through krew, so that it's invoked with
+// `kubectl linkerd`.
+const KubectlPluginName = "kubectl-linkerd"
+
+// kubectlPluginCallerEnv is set by kubectl to its own path when it runs a
+// plugin.
+const kubectlPluginCallerEnv = "KUBECTL_PLUGINS_CALLER"
+
+// kubectlPluginFlagEnv maps the kube flags to the environment variables
+// through which kubectl passes the values of its own global flags to the
+// plugins it runs.
+var kubectlPluginFlagEnv = map[string]string{
+	"kubeconfig": "KUBECTL_PLUGINS_GLOBAL_FLAG_KUBECONFIG",
+	"context":    "KUBECTL_PLUGINS_GLOBAL_FLAG_CONTEXT",
+	"as":         "KUBECTL_PLUGINS_GLOBAL_FLAG_AS",
+}
+
+// IsKubectlPlugin returns whether the binary at the given path, usually
+// os.Args[0], is installed as a kubectl plugin.
+func IsKubectlPlugin(path string) bool {
+	return strings.TrimSuffix(filepath.Base(path), ".exe") == KubectlPluginName
+}
+
+// RunningAsKubectlPlugin returns whether the current process runs as a
+// kubectl plugin, either because it was installed as one or because kubectl
+// itself started it.
+func RunningAsKubectlPlugin() bool {
+	return IsKubectlPlugin(os.Args[0]) || os.Getenv(kubectlPluginCallerEnv) != ""
+}
+
+// kubeFlagDefault returns the default value of the given kube flag, which is
+// the value of the matching kubectl global flag when running as a kubectl
+// plugin, so that `kubectl --context east linkerd check` checks the east
+// cluster.
+func kubeFlagDefault(name string) string {
+	if !RunningAsKubectlPlugin() {
+		return ""
+	}
+	return os.Getenv(kubectlPluginFlagEnv[name])
+}
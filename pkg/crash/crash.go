@@ -0,0 +1,155 @@
+package crash
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/tools/cache"
+)
+
+const webhookTimeout = 5 * time.Second
+
+// Report describes a panic recovered while handling an event.
+type Report struct {
+	Component string    `json:"component"`
+	Handler   string    `json:"handler"`
+	Object    string    `json:"object"`
+	Panic     string    `json:"panic"`
+	Stack     string    `json:"stack"`
+	Time      time.Time `json:"time"`
+}
+
+var (
+	panicsRecovered = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "event_handler_panics_total",
+			Help: "Number of panics recovered while handling events, by handler.",
+		},
+		[]string{"component", "handler"},
+	)
+
+	config struct {
+		sync.RWMutex
+		component string
+		webhook   string
+	}
+)
+
+// Configure sets the name of the component reported along with the recovered
+// panics, and the URL the reports are POSTed to, if any.
+func Configure(component, webhook string) {
+	config.Lock()
+	defer config.Unlock()
+	config.component = component
+	config.webhook = webhook
+}
+
+// Recover recovers from a panic raised while handling the given object, and
+// reports it. It must be deferred directly by the handler:
+//
+//	defer crash.Recover("handler", obj)
+func Recover(handler string, obj interface{}) {
+	if r := recover(); r != nil {
+		report(handler, obj, r)
+	}
+}
+
+// RecoverError is like Recover, but also sets err so that the caller can
+// handle the panic as a failure to process the object.
+func RecoverError(err *error, handler string, obj interface{}) {
+	if r := recover(); r != nil {
+		report(handler, obj, r)
+		*err = fmt.Errorf("panic while handling %s: %v", describe(obj), r)
+	}
+}
+
+// Handler wraps an informer event handler so that a panic raised while
+// handling an object is recovered and reported, instead of crashing the
+// process.
+func Handler(name string, handler cache.ResourceEventHandler) cache.ResourceEventHandler {
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			defer Recover(name+"/add", obj)
+			handler.OnAdd(obj)
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			defer Recover(name+"/update", newObj)
+			handler.OnUpdate(oldObj, newObj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			defer Recover(name+"/delete", obj)
+			handler.OnDelete(obj)
+		},
+	}
+}
+
+func report(handler string, obj interface{}, r interface{}) {
+	config.RLock()
+	component, webhook := config.component, config.webhook
+	config.RUnlock()
+
+	rep := Report{
+		Component: component,
+		Handler:   handler,
+		Object:    describe(obj),
+		Panic:     fmt.Sprint(r),
+		Stack:     string(debug.Stack()),
+		Time:      time.Now(),
+	}
+	panicsRecovered.WithLabelValues(component, handler).Inc()
+	log.WithFields(log.Fields{
+		"handler": rep.Handler,
+		"object":  rep.Object,
+	}).Errorf("Recovered from panic: %s\n%s", rep.Panic, rep.Stack)
+
+	if webhook != "" {
+		go postReport(webhook, rep)
+	}
+}
+
+func postReport(url string, rep Report) {
+	body, err := json.Marshal(rep)
+	if err != nil {
+		log.Errorf("Failed to encode crash report: %s", err)
+		return
+	}
+	client := http.Client{Timeout: webhookTimeout}
+	rsp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Errorf("Failed to send crash report: %s", err)
+		return
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode >= 300 {
+		log.Errorf("Failed to send crash report: unexpected status %d", rsp.StatusCode)
+	}
+}
+
+// describe identifies the object being handled in a report.
+func describe(obj interface{}) string {
+	if key, ok := obj.(string); ok {
+		return key
+	}
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		return fmt.Sprintf("%T %s", tombstone.Obj, tombstone.Key)
+	}
+	if m, err := meta.Accessor(obj); err == nil {
+		if m.GetNamespace() == "" {
+			return fmt.Sprintf("%T %s", obj, m.GetName())
+		}
+		return fmt.Sprintf("%T %s/%s", obj, m.GetNamespace(), m.GetName())
+	}
+	if s, ok := obj.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", obj)
+}
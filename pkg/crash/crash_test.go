@@ -0,0 +1,113 @@
+package crash
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestHandler(t *testing.T) {
+	reports := make(chan Report, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rep Report
+		if err := json.NewDecoder(r.Body).Decode(&rep); err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+		reports <- rep
+	}))
+	defer server.Close()
+	Configure("test", server.URL)
+	defer Configure("", "")
+
+	handled := false
+	handler := Handler("pods", cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			handled = true
+			var labels map[string]string
+			labels["boom"] = "true"
+		},
+	})
+
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "emoji", Namespace: "emojivoto"}}
+	before := testutil.ToFloat64(panicsRecovered.WithLabelValues("test", "pods/add"))
+	handler.OnAdd(pod)
+	if !handled {
+		t.Fatal("Expected the wrapped handler to be called")
+	}
+	after := testutil.ToFloat64(panicsRecovered.WithLabelValues("test", "pods/add"))
+	if after-before != 1 {
+		t.Fatalf("Expected 1 recovered panic, got %v", after-before)
+	}
+
+	select {
+	case rep := <-reports:
+		if rep.Component != "test" || rep.Handler != "pods/add" {
+			t.Fatalf("Unexpected report source: %s %s", rep.Component, rep.Handler)
+		}
+		if rep.Object != "*v1.Pod emojivoto/emoji" {
+			t.Fatalf("Unexpected report object: %s", rep.Object)
+		}
+		if rep.Stack == "" {
+			t.Fatal("Expected report to include a stack")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the crash report")
+	}
+}
+
+func TestRecoverError(t *testing.T) {
+	process := func() (err error) {
+		defer RecoverError(&err, "queue", "emojivoto/emoji")
+		panic(errors.New("malformed object"))
+	}
+
+	err := process()
+	if err == nil {
+		t.Fatal("Expected the panic to be returned as an error")
+	}
+	expected := "panic while handling emojivoto/emoji: malformed object"
+	if err.Error() != expected {
+		t.Fatalf("Expected error %q, got %q", expected, err)
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	testCases := []struct {
+		obj      interface{}
+		expected string
+	}{
+		{
+			obj:      &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "emoji", Namespace: "emojivoto"}},
+			expected: "*v1.Pod emojivoto/emoji",
+		},
+		{
+			obj:      &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}},
+			expected: "*v1.Node node-1",
+		},
+		{
+			obj:      cache.DeletedFinalStateUnknown{Key: "emojivoto/emoji", Obj: &corev1.Pod{}},
+			expected: "*v1.Pod emojivoto/emoji",
+		},
+		{
+			obj:      42,
+			expected: "int",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // pin
+		t.Run(tc.expected, func(t *testing.T) {
+			if actual := describe(tc.obj); actual != tc.expected {
+				t.Fatalf("Expected %q, got %q", tc.expected, actual)
+			}
+		})
+	}
+}
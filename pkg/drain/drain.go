@@ -0,0 +1,67 @@
+package drain
+
+import (
+	"flag"
+	"time"
+
+	"github.com/linkerd/linkerd2/pkg/admin"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+// Config controls how a gRPC server drains its connections when the
+// component shuts down, so that its clients move to the other replicas
+// instead of seeing their streams cut.
+type Config struct {
+	// Delay is the time between the readiness flip and the server no longer
+	// accepting connections, for the component to be taken out of the load
+	// balancing first
+	Delay time.Duration
+	// Period is the time left to the RPCs in flight to complete once the
+	// server sent GOAWAY to its clients, after which they're cut
+	Period time.Duration
+}
+
+// AddFlags adds the drain-delay and drain-period flags to the flagSet and
+// returns the configuration they set.
+func AddFlags(cmd *flag.FlagSet) *Config {
+	config := &Config{}
+	cmd.DurationVar(&config.Delay, "drain-delay", 5*time.Second, "time between the readiness probe failing and the gRPC server no longer accepting connections, on shutdown")
+	cmd.DurationVar(&config.Period, "drain-period", 20*time.Second, "time left to the gRPC calls in flight to complete once the clients were told to go away, on shutdown, after which they're cut")
+	return config
+}
+
+// GracefulStop drains the connections of the gRPC server and stops it:
+//
+// 1. the readiness endpoint of the admin server fails;
+// 2. after c.Delay, the server stops accepting connections and sends GOAWAY
+// to its clients, which open their new streams elsewhere;
+// 3. onGoAway, if any, is called, e.g. to end the long-lived streams, which
+// never complete on their own;
+// 4. the server waits for the RPCs in flight to complete, for at most
+// c.Period, before closing the remaining connections.
+func (c Config) GracefulStop(name string, server *grpc.Server, onGoAway func()) {
+	admin.SetDraining()
+	if c.Delay > 0 {
+		log.Infof("draining %s in %s", name, c.Delay)
+		time.Sleep(c.Delay)
+	}
+
+	log.Infof("draining %s", name)
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	if onGoAway != nil {
+		onGoAway()
+	}
+
+	select {
+	case <-stopped:
+	case <-time.After(c.Period):
+		log.Warnf("%s not drained after %s; closing the remaining connections", name, c.Period)
+		server.Stop()
+		<-stopped
+	}
+}
@@ -0,0 +1,52 @@
+package drain
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestGracefulStop(t *testing.T) {
+	lis, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("Could not start listener: %v", err)
+	}
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(lis)
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	// Watch streams never complete on their own
+	stream, err := healthpb.NewHealthClient(conn).Watch(context.Background(), &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	config := Config{Period: 100 * time.Millisecond}
+	calledOnGoAway := false
+	start := time.Now()
+	config.GracefulStop("test server", server, func() { calledOnGoAway = true })
+
+	if !calledOnGoAway {
+		t.Error("Expected onGoAway to be called")
+	}
+	if elapsed := time.Since(start); elapsed < config.Period {
+		t.Errorf("Expected the server to wait %s for the stream to complete, stopped after %s", config.Period, elapsed)
+	}
+	if _, err := stream.Recv(); err == nil {
+		t.Error("Expected the stream to be cut once the drain period elapsed")
+	}
+}
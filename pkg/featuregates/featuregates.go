@@ -0,0 +1,249 @@
+package featuregates
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linkerd/linkerd2/pkg/k8s"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+// Feature is the name of an experimental feature that can be switched on or
+// off through a feature gate.
+type Feature string
+
+const (
+	// EndpointSlices enables the usage of EndpointSlice informers and
+	// resources in the destination controller. It's only read at startup.
+	EndpointSlices Feature = "EndpointSlices"
+
+	// NativeSidecars makes the proxy injector add the proxy as a native
+	// sidecar, i.e. an init container that keeps running, on Kubernetes 1.28
+	// and later.
+	NativeSidecars Feature = "NativeSidecars"
+
+	// FederatedServices enables the aggregation of the mirrors of a service
+	// exported by several clusters into a federated service, by the service
+	// federation controller of the multicluster extension.
+	FederatedServices Feature = "FederatedServices"
+)
+
+// defaults holds every known feature and whether it's enabled when no
+// override is provided.
+var defaults = map[Feature]bool{
+	EndpointSlices:    false,
+	NativeSidecars:    false,
+	FederatedServices: false,
+}
+
+// FeatureGates holds the state of all known features. Overrides can come from
+// two sources: the command line, set once at startup, and the linkerd-config
+// ConfigMap, which can be reloaded at runtime. Command line overrides take
+// precedence over the ConfigMap. FeatureGates is safe for concurrent use.
+type FeatureGates struct {
+	sync.RWMutex
+	flagOverrides   map[Feature]bool
+	configOverrides map[Feature]bool
+	listeners       []func(Feature, bool)
+}
+
+// configValues is the subset of the linkerd-config values that is relevant
+// to feature gates.
+type configValues struct {
+	FeatureGates map[string]bool `json:"featureGates"`
+}
+
+// New returns a FeatureGates with every feature set to its default.
+func New() *FeatureGates {
+	return &FeatureGates{
+		flagOverrides:   make(map[Feature]bool),
+		configOverrides: make(map[Feature]bool),
+	}
+}
+
+// Known returns the names of all known features, sorted.
+func Known() []Feature {
+	features := make([]Feature, 0, len(defaults))
+	for f := range defaults {
+		features = append(features, f)
+	}
+	sort.Slice(features, func(i, j int) bool { return features[i] < features[j] })
+	return features
+}
+
+// Enabled returns whether the given feature is enabled.
+func (fg *FeatureGates) Enabled(f Feature) bool {
+	fg.RLock()
+	defer fg.RUnlock()
+	return fg.enabled(f)
+}
+
+func (fg *FeatureGates) enabled(f Feature) bool {
+	if enabled, ok := fg.flagOverrides[f]; ok {
+		return enabled
+	}
+	if enabled, ok := fg.configOverrides[f]; ok {
+		return enabled
+	}
+	return defaults[f]
+}
+
+// OnChange registers a function called with a feature and its new state
+// whenever a reload of the overrides from the linkerd-config ConfigMap
+// switches the feature on or off.
+func (fg *FeatureGates) OnChange(listener func(f Feature, enabled bool)) {
+	fg.Lock()
+	defer fg.Unlock()
+	fg.listeners = append(fg.listeners, listener)
+}
+
+// All returns the effective state of every known feature.
+func (fg *FeatureGates) All() map[Feature]bool {
+	all := make(map[Feature]bool, len(defaults))
+	for f := range defaults {
+		all[f] = fg.Enabled(f)
+	}
+	return all
+}
+
+// String renders the effective state of every known feature in the same
+// format accepted by ParseFlag.
+func (fg *FeatureGates) String() string {
+	var gates []string
+	for _, f := range Known() {
+		gates = append(gates, fmt.Sprintf("%s=%t", f, fg.Enabled(f)))
+	}
+	return strings.Join(gates, ",")
+}
+
+// ParseFlag sets command line overrides from a comma-separated list of
+// Feature=bool pairs, e.g. "EndpointSlices=true".
+func (fg *FeatureGates) ParseFlag(value string) error {
+	overrides, err := parse(value)
+	if err != nil {
+		return err
+	}
+	fg.Lock()
+	defer fg.Unlock()
+	for f, enabled := range overrides {
+		fg.flagOverrides[f] = enabled
+	}
+	return nil
+}
+
+// SetConfigOverrides replaces the overrides read from the linkerd-config
+// ConfigMap. Unknown features are logged and ignored so that a newer config
+// doesn't break older controllers.
+func (fg *FeatureGates) SetConfigOverrides(overrides map[string]bool) {
+	parsed := make(map[Feature]bool)
+	for name, enabled := range overrides {
+		f := Feature(name)
+		if _, ok := defaults[f]; !ok {
+			log.Warnf("Ignoring unknown feature gate %s", name)
+			continue
+		}
+		parsed[f] = enabled
+	}
+
+	fg.Lock()
+	before := make(map[Feature]bool, len(defaults))
+	for f := range defaults {
+		before[f] = fg.enabled(f)
+	}
+	fg.configOverrides = parsed
+	changed := make(map[Feature]bool)
+	for f, enabled := range before {
+		if fg.enabled(f) != enabled {
+			changed[f] = !enabled
+		}
+	}
+	listeners := fg.listeners
+	fg.Unlock()
+
+	// The listeners are called without holding the lock, so that they can
+	// read the gates
+	for _, f := range Known() {
+		enabled, ok := changed[f]
+		if !ok {
+			continue
+		}
+		log.Infof("Feature gate %s set to %t", f, enabled)
+		for _, listener := range listeners {
+			listener(f, enabled)
+		}
+	}
+}
+
+// FromConfigValues extracts the feature gate overrides from the values stored
+// in the linkerd-config ConfigMap.
+func FromConfigValues(values string) (map[string]bool, error) {
+	var cv configValues
+	if err := yaml.Unmarshal([]byte(values), &cv); err != nil {
+		return nil, err
+	}
+	return cv.FeatureGates, nil
+}
+
+// Load reads the feature gate overrides from the linkerd-config ConfigMap in
+// the given namespace.
+func (fg *FeatureGates) Load(ctx context.Context, client kubernetes.Interface, namespace string) error {
+	cm, err := client.CoreV1().ConfigMaps(namespace).Get(ctx, k8s.ConfigConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	overrides, err := FromConfigValues(cm.Data["values"])
+	if err != nil {
+		return fmt.Errorf("failed to parse feature gates from %s: %s", k8s.ConfigConfigMapName, err)
+	}
+	fg.SetConfigOverrides(overrides)
+	return nil
+}
+
+// Watch reloads the overrides from the linkerd-config ConfigMap every
+// interval until the context is cancelled, notifying the listeners registered
+// with OnChange of the features switched on or off.
+func (fg *FeatureGates) Watch(ctx context.Context, client kubernetes.Interface, namespace string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := fg.Load(ctx, client, namespace); err != nil {
+				log.Warnf("Failed to reload feature gates: %s", err)
+			}
+		}
+	}
+}
+
+func parse(value string) (map[Feature]bool, error) {
+	overrides := make(map[Feature]bool)
+	if strings.TrimSpace(value) == "" {
+		return overrides, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid feature gate %q, expected Feature=bool", pair)
+		}
+		f := Feature(kv[0])
+		if _, ok := defaults[f]; !ok {
+			return nil, fmt.Errorf("unknown feature gate %s", kv[0])
+		}
+		enabled, err := strconv.ParseBool(kv[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for feature gate %s: %s", kv[0], err)
+		}
+		overrides[f] = enabled
+	}
+	return overrides, nil
+}
@@ -0,0 +1,102 @@
+package featuregates
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFlag(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected bool
+		err      bool
+	}{
+		{"", false, false},
+		{"EndpointSlices=true", true, false},
+		{"EndpointSlices=false", false, false},
+		{"EndpointSlices", false, true},
+		{"EndpointSlices=maybe", false, true},
+		{"Unknown=true", false, true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // pin
+		t.Run(tc.value, func(t *testing.T) {
+			fg := New()
+			err := fg.ParseFlag(tc.value)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("Expected error parsing %q", tc.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if fg.Enabled(EndpointSlices) != tc.expected {
+				t.Fatalf("Expected EndpointSlices to be %t", tc.expected)
+			}
+		})
+	}
+}
+
+func TestOverridePrecedence(t *testing.T) {
+	fg := New()
+	fg.SetConfigOverrides(map[string]bool{"EndpointSlices": true, "Unknown": true})
+	if !fg.Enabled(EndpointSlices) {
+		t.Fatal("Expected config override to enable EndpointSlices")
+	}
+
+	if err := fg.ParseFlag("EndpointSlices=false"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if fg.Enabled(EndpointSlices) {
+		t.Fatal("Expected flag override to take precedence over config")
+	}
+
+	if fg.String() != "EndpointSlices=false" {
+		t.Fatalf("Unexpected string representation: %s", fg.String())
+	}
+}
+
+func TestFromConfigValues(t *testing.T) {
+	overrides, err := FromConfigValues("clusterDomain: cluster.local\nfeatureGates:\n  EndpointSlices: true\n")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !overrides["EndpointSlices"] {
+		t.Fatalf("Expected EndpointSlices override, got %v", overrides)
+	}
+}
+
+func TestOnChange(t *testing.T) {
+	fg := New()
+	if err := fg.ParseFlag("NativeSidecars=false"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	changes := map[Feature]bool{}
+	fg.OnChange(func(f Feature, enabled bool) {
+		if fg.Enabled(f) != enabled {
+			t.Errorf("Expected %s to be %t when notified", f, enabled)
+		}
+		changes[f] = enabled
+	})
+
+	fg.SetConfigOverrides(map[string]bool{"EndpointSlices": true, "NativeSidecars": true})
+	expected := map[Feature]bool{EndpointSlices: true}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("Expected changes %v, got %v", expected, changes)
+	}
+
+	changes = map[Feature]bool{}
+	fg.SetConfigOverrides(map[string]bool{"EndpointSlices": true})
+	if len(changes) != 0 {
+		t.Fatalf("Expected no changes, got %v", changes)
+	}
+
+	fg.SetConfigOverrides(map[string]bool{"FederatedServices": true})
+	expected = map[Feature]bool{EndpointSlices: false, FederatedServices: true}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("Expected changes %v, got %v", expected, changes)
+	}
+}
@@ -3,8 +3,10 @@ package flags
 import (
 	"flag"
 	"fmt"
+	"io/ioutil"
 	"os"
 
+	"github.com/linkerd/linkerd2/pkg/crash"
 	"github.com/linkerd/linkerd2/pkg/version"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
@@ -26,9 +28,13 @@ func ConfigureAndParse(cmd *flag.FlagSet, args []string) {
 	logFormat := cmd.String("log-format", "plain",
 		"log format, must be one of: plain, json")
 	printVersion := cmd.Bool("version", false, "print version and exit")
+	crashWebhook := cmd.String("crash-report-webhook", "",
+		"URL to POST a JSON report to when a panic is recovered while handling an event")
 
 	cmd.Parse(args)
 
+	crash.Configure(cmd.Name(), *crashWebhook)
+
 	// set log timestamps
 	log.SetFormatter(getFormatter(*logFormat))
 
@@ -44,19 +50,39 @@ func AddTraceFlags(cmd *flag.FlagSet) *string {
 	return traceCollector
 }
 
+// AddFeatureGatesFlag adds the feature-gates flag to the flagSet and returns
+// its pointer for usage
+func AddFeatureGatesFlag(cmd *flag.FlagSet) *string {
+	featureGates := cmd.String("feature-gates", "", "comma-separated list of Feature=bool pairs enabling or disabling experimental features; takes precedence over the featureGates set in linkerd-config")
+
+	return featureGates
+}
+
 func setLogLevel(logLevel string) {
 	level, err := log.ParseLevel(logLevel)
 	if err != nil {
 		log.Fatalf("invalid log-level: %s", logLevel)
 	}
+	SetLogLevel(level)
+}
+
+// SetLogLevel sets the level of the logs, and makes the klog entries of the
+// Kubernetes clients follow it, as they're only logged at the debug level.
+// It's also used to change the level of a running component.
+func SetLogLevel(level log.Level) {
 	log.SetLevel(level)
 
-	if level == log.DebugLevel {
+	if level >= log.DebugLevel {
 		flag.Set("stderrthreshold", "INFO")
 		flag.Set("logtostderr", "true")
 		flag.Set("v", "12") // At 7 and higher, authorization tokens get logged.
 		// pipe klog entries to logrus
 		klog.SetOutput(log.StandardLogger().Writer())
+	} else {
+		flag.Set("stderrthreshold", "FATAL")
+		flag.Set("logtostderr", "false")
+		flag.Set("v", "0")
+		klog.SetOutput(ioutil.Discard)
 	}
 }
 
@@ -0,0 +1,112 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+
+	"github.com/linkerd/linkerd2/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// adminPortName is the name of the container port of the admin servers
+// serving the health reports.
+const adminPortName = "admin-http"
+
+// errNotImplemented is returned when a component doesn't serve a report.
+var errNotImplemented = fmt.Errorf("%s not served", Path)
+
+// FetchReports returns the reports of the components of the extension
+// installed in the namespace. Each running container exposing an admin server
+// is queried through a port-forward; the ones that don't serve a report are
+// ignored, and the ones that can't be reached are reported as unhealthy.
+func FetchReports(ctx context.Context, k8sAPI *k8s.KubernetesAPI, namespace, extension string) ([]Report, error) {
+	pods, err := k8sAPI.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	reports := []Report{}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		for _, container := range pod.Spec.Containers {
+			if !hasPort(container, adminPortName) {
+				continue
+			}
+			report, err := fetchReport(k8sAPI, pod, container)
+			if err == errNotImplemented {
+				continue
+			}
+			if err != nil {
+				report = &Report{
+					Extension: extension,
+					Component: container.Name,
+					Checks: []Check{{
+						Name:    "reachable",
+						Message: err.Error(),
+					}},
+				}
+			}
+			report.Pod = pod.Name
+			reports = append(reports, *report)
+		}
+	}
+
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].Component != reports[j].Component {
+			return reports[i].Component < reports[j].Component
+		}
+		return reports[i].Pod < reports[j].Pod
+	})
+	return reports, nil
+}
+
+func fetchReport(k8sAPI *k8s.KubernetesAPI, pod corev1.Pod, container corev1.Container) (*Report, error) {
+	portForward, err := k8s.NewContainerMetricsForward(k8sAPI, pod, container, false, adminPortName)
+	if err != nil {
+		return nil, err
+	}
+	defer portForward.Stop()
+	if err := portForward.Init(); err != nil {
+		return nil, err
+	}
+
+	rsp, err := http.Get(portForward.URLFor(Path))
+	if err != nil {
+		return nil, err
+	}
+	defer rsp.Body.Close()
+
+	switch rsp.StatusCode {
+	case http.StatusOK, http.StatusServiceUnavailable:
+	case http.StatusNotFound:
+		return nil, errNotImplemented
+	default:
+		return nil, fmt.Errorf("unexpected status %d", rsp.StatusCode)
+	}
+
+	body, err := ioutil.ReadAll(rsp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var report Report
+	if err := json.Unmarshal(body, &report); err != nil {
+		return nil, fmt.Errorf("invalid health report: %s", err)
+	}
+	return &report, nil
+}
+
+func hasPort(container corev1.Container, name string) bool {
+	for _, p := range container.Ports {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,120 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Path is the path of the admin server endpoint at which extension components
+// report their health.
+const Path = "/extension-health"
+
+// checkTimeout bounds the time a Checker can take to produce its results.
+const checkTimeout = 5 * time.Second
+
+type (
+	// Check is the result of one of the checks making up the health of an
+	// extension component.
+	Check struct {
+		Name    string `json:"name"`
+		Healthy bool   `json:"healthy"`
+		Message string `json:"message,omitempty"`
+	}
+
+	// Report is the health of an extension component, as served at Path.
+	Report struct {
+		Extension string  `json:"extension"`
+		Component string  `json:"component"`
+		Pod       string  `json:"pod,omitempty"`
+		Healthy   bool    `json:"healthy"`
+		Checks    []Check `json:"checks"`
+	}
+
+	// Checker returns the results of a set of checks.
+	Checker func(ctx context.Context) []Check
+
+	// Reporter aggregates the checks of an extension component into a
+	// Report, and serves it over HTTP.
+	Reporter struct {
+		extension string
+		component string
+
+		sync.RWMutex
+		checkers []Checker
+	}
+)
+
+// NewReporter returns a Reporter for a component of the extension, without
+// any checks.
+func NewReporter(extension, component string) *Reporter {
+	return &Reporter{
+		extension: extension,
+		component: component,
+	}
+}
+
+// AddChecker adds a set of checks to the report.
+func (r *Reporter) AddChecker(checker Checker) {
+	r.Lock()
+	defer r.Unlock()
+	r.checkers = append(r.checkers, checker)
+}
+
+// AddCheck adds a single check to the report; the check is healthy when the
+// given function doesn't return an error.
+func (r *Reporter) AddCheck(name string, check func(ctx context.Context) error) {
+	r.AddChecker(func(ctx context.Context) []Check {
+		result := Check{Name: name, Healthy: true}
+		if err := check(ctx); err != nil {
+			result.Healthy = false
+			result.Message = err.Error()
+		}
+		return []Check{result}
+	})
+}
+
+// Report runs all the checks and returns their results. The component is
+// healthy when all of its checks are.
+func (r *Reporter) Report(ctx context.Context) Report {
+	r.RLock()
+	checkers := r.checkers
+	r.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	report := Report{
+		Extension: r.extension,
+		Component: r.component,
+		Healthy:   true,
+		Checks:    []Check{},
+	}
+	for _, checker := range checkers {
+		for _, check := range checker(ctx) {
+			if !check.Healthy {
+				report.Healthy = false
+			}
+			report.Checks = append(report.Checks, check)
+		}
+	}
+	return report
+}
+
+// ServeHTTP serves the report as JSON. The response status is 503 when the
+// component is unhealthy, so that the endpoint can also be probed.
+func (r *Reporter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	report := r.Report(req.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	if !report.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Errorf("Failed to write health report: %s", err)
+	}
+}
@@ -0,0 +1,70 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReporter(t *testing.T) {
+	t.Run("Serves a healthy report", func(t *testing.T) {
+		reporter := NewReporter("viz", "metrics-api")
+		reporter.AddCheck("kubernetes-api", func(context.Context) error { return nil })
+
+		rec := httptest.NewRecorder()
+		reporter.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		var report Report
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if !report.Healthy || report.Extension != "viz" || report.Component != "metrics-api" {
+			t.Fatalf("Unexpected report: %+v", report)
+		}
+		if len(report.Checks) != 1 || !report.Checks[0].Healthy {
+			t.Fatalf("Unexpected checks: %+v", report.Checks)
+		}
+	})
+
+	t.Run("Serves an unhealthy report when any check fails", func(t *testing.T) {
+		reporter := NewReporter("multicluster", "service-mirror-east")
+		reporter.AddCheck("target-cluster-watched", func(context.Context) error { return nil })
+		reporter.AddChecker(func(context.Context) []Check {
+			return []Check{{Name: "gateway-alive", Message: "gateway probe failed"}}
+		})
+		reporter.AddCheck("other", func(context.Context) error { return errors.New("boom") })
+
+		rec := httptest.NewRecorder()
+		reporter.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path, nil))
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("Expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+		}
+
+		var report Report
+		if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if report.Healthy {
+			t.Fatalf("Expected the report to be unhealthy: %+v", report)
+		}
+		expected := []Check{
+			{Name: "target-cluster-watched", Healthy: true},
+			{Name: "gateway-alive", Message: "gateway probe failed"},
+			{Name: "other", Message: "boom"},
+		}
+		if len(report.Checks) != len(expected) {
+			t.Fatalf("Expected checks %+v, got %+v", expected, report.Checks)
+		}
+		for i, check := range expected {
+			if report.Checks[i] != check {
+				t.Fatalf("Expected check %+v, got %+v", check, report.Checks[i])
+			}
+		}
+	})
+}
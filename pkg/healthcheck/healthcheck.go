@@ -70,6 +70,13 @@ const (
 	// is set.
 	LinkerdPreInstallCapabilityChecks CategoryID = "pre-kubernetes-capability"
 
+	// LinkerdPreInstallNetworkChecks adds checks to detect network settings
+	// that are known to break the data plane, such as small conntrack tables,
+	// CNI MTUs that don't account for encapsulation, firewalls blocking the
+	// webhook ports and unsupported kube-proxy modes. These checks only warn,
+	// as the settings can't always be fully inspected from the API.
+	LinkerdPreInstallNetworkChecks CategoryID = "pre-kubernetes-network"
+
 	// LinkerdPreInstallGlobalResourcesChecks adds a series of checks to determine
 	// the existence of the global resources like cluster roles, cluster role
 	// bindings, mutating webhook configuration validating webhook configuration
@@ -154,6 +161,8 @@ const (
 	linkerdCNIResourceName       = "linkerd-cni"
 	linkerdCNIConfigMapName      = "linkerd-cni-config"
 
+	spValidatorDisabledSkipReason = "skipping check because the sp-validator is disabled"
+
 	podCIDRUnavailableSkipReason = "skipping check because the nodes aren't exposing podCIDR"
 
 	proxyInjectorOldTLSSecretName = "linkerd-proxy-injector-tls"
@@ -401,6 +410,10 @@ type Options struct {
 	RetryDeadline         time.Time
 	CNIEnabled            bool
 	InstallManifest       string
+	// ExpiryWindow is how long before their expiration certificates are
+	// reported as expiring soon; it defaults to
+	// issuercerts.DefaultExpiryWindow
+	ExpiryWindow time.Duration
 }
 
 // HealthChecker encapsulates all health check checkers, and clients required to
@@ -653,6 +666,44 @@ func (hc *HealthChecker) allCategories() []*Category {
 			},
 			false,
 		),
+		NewCategory(
+			LinkerdPreInstallNetworkChecks,
+			[]Checker{
+				{
+					description: "conntrack table is large enough",
+					hintAnchor:  "pre-k8s-conntrack",
+					warning:     true,
+					check: func(ctx context.Context) error {
+						return hc.checkConntrackTableSize(ctx)
+					},
+				},
+				{
+					description: "CNI MTU accounts for encapsulation overhead",
+					hintAnchor:  "pre-k8s-mtu",
+					warning:     true,
+					check: func(ctx context.Context) error {
+						return hc.checkCNIMTU(ctx)
+					},
+				},
+				{
+					description: "webhook ports are not blocked",
+					hintAnchor:  "pre-k8s-webhook-ports",
+					warning:     true,
+					check: func(ctx context.Context) error {
+						return hc.checkWebhookPorts(ctx)
+					},
+				},
+				{
+					description: "kube-proxy mode is supported",
+					hintAnchor:  "pre-k8s-kube-proxy-mode",
+					warning:     true,
+					check: func(ctx context.Context) error {
+						return hc.checkKubeProxyMode(ctx)
+					},
+				},
+			},
+			false,
+		),
 		NewCategory(
 			LinkerdPreInstallGlobalResourcesChecks,
 			[]Checker{
@@ -1048,13 +1099,13 @@ func (hc *HealthChecker) allCategories() []*Category {
 					},
 				},
 				{
-					description: "trust anchors are valid for at least 60 days",
+					description: fmt.Sprintf("trust anchors are valid for at least %s", hc.ExpiryWindowDescription()),
 					hintAnchor:  "l5d-identity-trustAnchors-not-expiring-soon",
 					warning:     true,
 					check: func(ctx context.Context) error {
 						var expiringAnchors []string
 						for _, anchor := range hc.trustAnchors {
-							if err := issuercerts.CheckExpiringSoon(anchor); err != nil {
+							if err := issuercerts.CheckExpiringWithin(anchor, hc.ExpiryWindow()); err != nil {
 								expiringAnchors = append(expiringAnchors, fmt.Sprintf("* %v %s %s", anchor.SerialNumber, anchor.Subject.CommonName, err))
 							}
 						}
@@ -1087,11 +1138,11 @@ func (hc *HealthChecker) allCategories() []*Category {
 					},
 				},
 				{
-					description: "issuer cert is valid for at least 60 days",
+					description: fmt.Sprintf("issuer cert is valid for at least %s", hc.ExpiryWindowDescription()),
 					warning:     true,
 					hintAnchor:  "l5d-identity-issuer-cert-not-expiring-soon",
 					check: func(context.Context) error {
-						if err := issuercerts.CheckExpiringSoon(hc.issuerCert.Certificate); err != nil {
+						if err := issuercerts.CheckExpiringWithin(hc.issuerCert.Certificate, hc.ExpiryWindow()); err != nil {
 							return fmt.Errorf("issuer certificate %s", err)
 						}
 						return nil
@@ -1132,7 +1183,7 @@ func (hc *HealthChecker) allCategories() []*Category {
 					},
 				},
 				{
-					description: "proxy-injector cert is valid for at least 60 days",
+					description: fmt.Sprintf("proxy-injector cert is valid for at least %s", hc.ExpiryWindowDescription()),
 					warning:     true,
 					hintAnchor:  "l5d-proxy-injector-webhook-cert-not-expiring-soon",
 					check: func(ctx context.Context) error {
@@ -1152,6 +1203,9 @@ func (hc *HealthChecker) allCategories() []*Category {
 					hintAnchor:  "l5d-sp-validator-webhook-cert-valid",
 					fatal:       true,
 					check: func(ctx context.Context) (err error) {
+						if hc.isProfileValidatorDisabled() {
+							return &SkipError{Reason: spValidatorDisabledSkipReason}
+						}
 						anchors, err := hc.fetchSpValidatorCaBundle(ctx)
 						if err != nil {
 							return err
@@ -1168,10 +1222,13 @@ func (hc *HealthChecker) allCategories() []*Category {
 					},
 				},
 				{
-					description: "sp-validator cert is valid for at least 60 days",
+					description: fmt.Sprintf("sp-validator cert is valid for at least %s", hc.ExpiryWindowDescription()),
 					warning:     true,
 					hintAnchor:  "l5d-sp-validator-webhook-cert-not-expiring-soon",
 					check: func(ctx context.Context) error {
+						if hc.isProfileValidatorDisabled() {
+							return &SkipError{Reason: spValidatorDisabledSkipReason}
+						}
 						cert, err := hc.FetchCredsFromSecret(ctx, hc.ControlPlaneNamespace, spValidatorTLSSecretName)
 						if kerrors.IsNotFound(err) {
 							cert, err = hc.FetchCredsFromOldSecret(ctx, hc.ControlPlaneNamespace, spValidatorOldTLSSecretName)
@@ -1535,6 +1592,26 @@ func (hc *HealthChecker) CheckProxyHealth(ctx context.Context, controlPlaneNames
 	return nil
 }
 
+// ExpiryWindow returns how long before their expiration certificates are
+// reported as expiring soon.
+func (hc *HealthChecker) ExpiryWindow() time.Duration {
+	if hc.Options.ExpiryWindow > 0 {
+		return hc.Options.ExpiryWindow
+	}
+	return issuercerts.DefaultExpiryWindow
+}
+
+// ExpiryWindowDescription returns the expiry window in a form suitable for
+// check descriptions, e.g. "60 days".
+func (hc *HealthChecker) ExpiryWindowDescription() string {
+	window := hc.ExpiryWindow()
+	day := 24 * time.Hour
+	if window%day == 0 {
+		return fmt.Sprintf("%d days", window/day)
+	}
+	return window.String()
+}
+
 // CheckCertAndAnchorsExpiringSoon checks if the given cert and anchors expire soon, and returns an
 // error if they do.
 func (hc *HealthChecker) CheckCertAndAnchorsExpiringSoon(cert *tls.Cred) error {
@@ -1542,7 +1619,7 @@ func (hc *HealthChecker) CheckCertAndAnchorsExpiringSoon(cert *tls.Cred) error {
 	var expiringAnchors []string
 	for _, anchor := range cert.TrustChain {
 		anchor := anchor
-		if err := issuercerts.CheckExpiringSoon(anchor); err != nil {
+		if err := issuercerts.CheckExpiringWithin(anchor, hc.ExpiryWindow()); err != nil {
 			expiringAnchors = append(expiringAnchors, fmt.Sprintf("* %v %s %s", anchor.SerialNumber, anchor.Subject.CommonName, err))
 		}
 	}
@@ -1551,7 +1628,7 @@ func (hc *HealthChecker) CheckCertAndAnchorsExpiringSoon(cert *tls.Cred) error {
 	}
 
 	// check cert not expiring soon
-	if err := issuercerts.CheckExpiringSoon(cert.Certificate); err != nil {
+	if err := issuercerts.CheckExpiringWithin(cert.Certificate, hc.ExpiryWindow()); err != nil {
 		return fmt.Errorf("certificate %s", err)
 	}
 	return nil
@@ -1700,7 +1777,8 @@ func (hc *HealthChecker) checkLinkerdConfigConfigMap(ctx context.Context) (strin
 // Checks whether the configuration of the linkerd-identity-issuer is correct. This means:
 // 1. There is a config map present with identity context
 // 2. The scheme in the identity context corresponds to the format of the issuer secret
-// 3. The trust anchors (if scheme == kubernetes.io/tls) in the secret equal the ones in config
+// 3. The trust anchors (if scheme == kubernetes.io/tls) in the secret equal the ones in config,
+// unless they're read from an externally managed linkerd-identity-trust-roots config map
 // 4. The certs and key are parsable
 func (hc *HealthChecker) checkCertificatesConfig(ctx context.Context) (*tls.Cred, []*x509.Certificate, error) {
 	_, values, err := FetchCurrentConfiguration(ctx, hc.kubeAPI, hc.ControlPlaneNamespace)
@@ -1710,7 +1788,9 @@ func (hc *HealthChecker) checkCertificatesConfig(ctx context.Context) (*tls.Cred
 
 	var data *issuercerts.IssuerCertData
 
-	if values.Identity.Issuer.Scheme == "" || values.Identity.Issuer.Scheme == k8s.IdentityIssuerSchemeLinkerd {
+	if values.Identity.ExternalCA {
+		data, err = issuercerts.FetchExternalCAIssuerData(ctx, hc.kubeAPI, values.Identity.Issuer.Scheme, hc.ControlPlaneNamespace)
+	} else if values.Identity.Issuer.Scheme == "" || values.Identity.Issuer.Scheme == k8s.IdentityIssuerSchemeLinkerd {
 		data, err = issuercerts.FetchIssuerData(ctx, hc.kubeAPI, values.IdentityTrustAnchorsPEM, hc.ControlPlaneNamespace)
 	} else {
 		data, err = issuercerts.FetchExternalIssuerData(ctx, hc.kubeAPI, hc.ControlPlaneNamespace)
@@ -1733,6 +1813,16 @@ func (hc *HealthChecker) checkCertificatesConfig(ctx context.Context) (*tls.Cred
 	return issuerCreds, anchors, nil
 }
 
+// FetchTrustAnchors returns the trust anchors of the control plane. They're
+// read from the linkerd-identity-trust-roots ConfigMap when it's managed
+// externally, as they're then not part of the configuration.
+func FetchTrustAnchors(ctx context.Context, k kubernetes.Interface, controlPlaneNamespace string, values *l5dcharts.Values) (string, error) {
+	if values.Identity == nil || !values.Identity.ExternalCA {
+		return values.IdentityTrustAnchorsPEM, nil
+	}
+	return issuercerts.FetchTrustRoots(ctx, k, controlPlaneNamespace)
+}
+
 // FetchCurrentConfiguration retrieves the current Linkerd configuration
 func FetchCurrentConfiguration(ctx context.Context, k kubernetes.Interface, controlPlaneNamespace string) (*corev1.ConfigMap, *l5dcharts.Values, error) {
 
@@ -2019,6 +2109,10 @@ func (hc *HealthChecker) isHeartbeatDisabled() bool {
 	return hc.linkerdConfig.DisableHeartBeat
 }
 
+func (hc *HealthChecker) isProfileValidatorDisabled() bool {
+	return hc.linkerdConfig != nil && hc.linkerdConfig.DisableProfileValidator
+}
+
 func (hc *HealthChecker) checkServiceAccounts(ctx context.Context, saNames []string, ns, labelSelector string) error {
 	return CheckServiceAccounts(ctx, hc.kubeAPI, saNames, ns, labelSelector)
 }
@@ -2204,7 +2298,10 @@ func checkPodsProxiesCertificate(ctx context.Context, kubeAPI k8s.KubernetesAPI,
 		return err
 	}
 
-	trustAnchorsPem := values.IdentityTrustAnchorsPEM
+	trustAnchorsPem, err := FetchTrustAnchors(ctx, kubeAPI, controlPlaneNamespace, values)
+	if err != nil {
+		return err
+	}
 	offendingPods := []string{}
 	for _, pod := range meshedPods {
 		// Skip control plane pods since they load their trust anchors from the linkerd-identity-trust-anchors configmap.
@@ -0,0 +1,243 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	kubeProxyConfigMapName = "kube-proxy"
+	kubeProxyConfigKey     = "config.conf"
+
+	// These mirror the kube-proxy defaults for sizing the conntrack table.
+	defaultConntrackMaxPerCore = 32768
+	defaultConntrackMin        = 131072
+
+	// minConntrackEntries is the smallest conntrack table that is not
+	// reported. Every meshed connection is tracked twice (application to
+	// proxy and proxy to destination), so small tables fill up quickly.
+	minConntrackEntries = 131072
+
+	// standardMTU is the MTU assumed for node network interfaces.
+	standardMTU = 1500
+
+	// vxlanOverhead and ipipOverhead are the bytes added to each packet by
+	// the respective encapsulation.
+	vxlanOverhead = 50
+	ipipOverhead  = 20
+
+	gceProviderIDPrefix = "gce://"
+
+	noKubeProxySkipReason = "kube-proxy configuration not found"
+)
+
+// webhookPorts are the ports the API server uses to reach the Linkerd
+// admission webhooks and API services.
+var webhookPorts = []int{4443, 8443, 9443}
+
+// kubeProxyConfig is the subset of the KubeProxyConfiguration relevant to
+// the network checks.
+type kubeProxyConfig struct {
+	Mode      string `json:"mode"`
+	Conntrack struct {
+		MaxPerCore *int32 `json:"maxPerCore"`
+		Min        *int32 `json:"min"`
+	} `json:"conntrack"`
+}
+
+// cniMTUConfig describes where a CNI plugin stores its MTU and how to
+// determine the encapsulation overhead it adds.
+type cniMTUConfig struct {
+	name          string
+	configMap     string
+	mtuKey        string
+	encapOverhead func(data map[string]string) (int, string)
+}
+
+var cniMTUConfigs = []cniMTUConfig{
+	{
+		name:      "Calico",
+		configMap: "calico-config",
+		mtuKey:    "veth_mtu",
+		encapOverhead: func(data map[string]string) (int, string) {
+			switch data["calico_backend"] {
+			case "vxlan":
+				return vxlanOverhead, "VXLAN"
+			case "bird":
+				return ipipOverhead, "IP-in-IP"
+			default:
+				return 0, ""
+			}
+		},
+	},
+	{
+		name:      "Cilium",
+		configMap: "cilium-config",
+		mtuKey:    "mtu",
+		encapOverhead: func(data map[string]string) (int, string) {
+			switch data["tunnel"] {
+			case "disabled":
+				return 0, ""
+			case "geneve":
+				return vxlanOverhead, "Geneve"
+			default:
+				return vxlanOverhead, "VXLAN"
+			}
+		},
+	},
+}
+
+// getKubeProxyConfig returns the kube-proxy configuration, or nil if it can't
+// be found, e.g. when kube-proxy is replaced by the CNI plugin.
+func (hc *HealthChecker) getKubeProxyConfig(ctx context.Context) (*kubeProxyConfig, error) {
+	cm, err := hc.kubeAPI.CoreV1().ConfigMaps(metav1.NamespaceSystem).Get(ctx, kubeProxyConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	data, ok := cm.Data[kubeProxyConfigKey]
+	if !ok {
+		return nil, nil
+	}
+
+	var config kubeProxyConfig
+	if err := yaml.Unmarshal([]byte(data), &config); err != nil {
+		return nil, fmt.Errorf("failed to parse the %s ConfigMap: %s", kubeProxyConfigMapName, err)
+	}
+	return &config, nil
+}
+
+// checkConntrackTableSize verifies that kube-proxy sizes the conntrack table
+// of every node to at least minConntrackEntries.
+func (hc *HealthChecker) checkConntrackTableSize(ctx context.Context) error {
+	config, err := hc.getKubeProxyConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if config == nil {
+		return &SkipError{Reason: noKubeProxySkipReason}
+	}
+
+	maxPerCore := int64(defaultConntrackMaxPerCore)
+	if config.Conntrack.MaxPerCore != nil {
+		maxPerCore = int64(*config.Conntrack.MaxPerCore)
+	}
+	if maxPerCore == 0 {
+		return &SkipError{Reason: "conntrack table size is not managed by kube-proxy"}
+	}
+	conntrackMin := int64(defaultConntrackMin)
+	if config.Conntrack.Min != nil {
+		conntrackMin = int64(*config.Conntrack.Min)
+	}
+
+	nodes, err := hc.kubeAPI.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	var smallNodes []string
+	for _, node := range nodes.Items {
+		size := maxPerCore * node.Status.Capacity.Cpu().Value()
+		if size < conntrackMin {
+			size = conntrackMin
+		}
+		if size < minConntrackEntries {
+			smallNodes = append(smallNodes, fmt.Sprintf("%s (%d)", node.Name, size))
+		}
+	}
+
+	if len(smallNodes) > 0 {
+		return fmt.Errorf("conntrack table has fewer than %d entries on node(s): %s\n    increase conntrack.maxPerCore or conntrack.min in the %s ConfigMap", minConntrackEntries, strings.Join(smallNodes, ", "), kubeProxyConfigMapName)
+	}
+	return nil
+}
+
+// checkCNIMTU verifies that the MTU configured for known CNI plugins leaves
+// room for their encapsulation overhead on nodes using the standard MTU.
+func (hc *HealthChecker) checkCNIMTU(ctx context.Context) error {
+	for _, cni := range cniMTUConfigs {
+		cm, err := hc.kubeAPI.CoreV1().ConfigMaps(metav1.NamespaceSystem).Get(ctx, cni.configMap, metav1.GetOptions{})
+		if err != nil {
+			if kerrors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+
+		value := strings.TrimSpace(cm.Data[cni.mtuKey])
+		if value == "" || value == "0" {
+			// The MTU is auto-detected by the CNI plugin
+			return nil
+		}
+		mtu, err := strconv.Atoi(value)
+		if err != nil {
+			return &SkipError{Reason: fmt.Sprintf("unable to parse %s MTU %q", cni.name, value)}
+		}
+
+		overhead, encap := cni.encapOverhead(cm.Data)
+		if mtu+overhead > standardMTU {
+			return fmt.Errorf("%s is configured with an MTU of %d, which with the %d bytes of %s overhead exceeds the standard node MTU of %d\n    unless the nodes use a larger MTU, set %s to %d or less in the %s ConfigMap", cni.name, mtu, overhead, encap, standardMTU, cni.mtuKey, standardMTU-overhead, cni.configMap)
+		}
+		return nil
+	}
+
+	return &SkipError{Reason: "no CNI plugin with a known MTU configuration found"}
+}
+
+// checkWebhookPorts detects clusters whose firewall is known to block the API
+// server from reaching the webhook ports on the nodes. Currently this covers
+// private GKE clusters, whose nodes have no external IP addresses.
+func (hc *HealthChecker) checkWebhookPorts(ctx context.Context) error {
+	nodes, err := hc.kubeAPI.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	if len(nodes.Items) == 0 {
+		return nil
+	}
+
+	for _, node := range nodes.Items {
+		if !strings.HasPrefix(node.Spec.ProviderID, gceProviderIDPrefix) {
+			return nil
+		}
+		for _, address := range node.Status.Addresses {
+			if address.Type == corev1.NodeExternalIP {
+				return nil
+			}
+		}
+	}
+
+	ports := make([]string, len(webhookPorts))
+	for i, port := range webhookPorts {
+		ports[i] = strconv.Itoa(port)
+	}
+	return fmt.Errorf("this looks like a private GKE cluster, whose firewall blocks the control plane from reaching the webhook ports\n    add a firewall rule allowing the control plane to reach the nodes on TCP ports %s", strings.Join(ports, ", "))
+}
+
+// checkKubeProxyMode verifies that kube-proxy isn't running in userspace
+// mode, in which it terminates connections and the proxy can't recover their
+// original destination.
+func (hc *HealthChecker) checkKubeProxyMode(ctx context.Context) error {
+	config, err := hc.getKubeProxyConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if config == nil {
+		return &SkipError{Reason: noKubeProxySkipReason}
+	}
+
+	if config.Mode == "userspace" {
+		return fmt.Errorf("kube-proxy is running in userspace mode, which hides the original destination of connections from the proxy\n    set mode to iptables or ipvs in the %s ConfigMap", kubeProxyConfigMapName)
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package healthcheck
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/linkerd/linkerd2/pkg/k8s"
+)
+
+const kubeProxyConfigMap = `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: kube-proxy
+  namespace: kube-system
+data:
+  config.conf: |
+    mode: %s
+    conntrack:
+      maxPerCore: %d
+      min: %d`
+
+const twoCPUNode = `apiVersion: v1
+kind: Node
+metadata:
+  name: test-node
+spec:
+  providerID: %s
+status:
+  capacity:
+    cpu: "2"
+  addresses:
+  - type: InternalIP
+    address: 10.0.0.2`
+
+func runNetworkCheck(t *testing.T, k8sConfigs []string, check func(*HealthChecker, context.Context) error) error {
+	t.Helper()
+	hc := NewHealthChecker([]CategoryID{}, &Options{})
+	var err error
+	hc.kubeAPI, err = k8s.NewFakeAPI(k8sConfigs...)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return check(hc, context.Background())
+}
+
+func expectNetworkCheckResult(t *testing.T, err error, expected string) {
+	t.Helper()
+	switch expected {
+	case "":
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	case "skip":
+		if _, ok := err.(*SkipError); !ok {
+			t.Fatalf("Expected check to be skipped, got: %v", err)
+		}
+	default:
+		if err == nil {
+			t.Fatalf("Expected error containing %q, got nil", expected)
+		}
+		if _, ok := err.(*SkipError); ok {
+			t.Fatalf("Expected error containing %q, got skip: %s", expected, err)
+		}
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("Expected error containing %q, got: %s", expected, err)
+		}
+	}
+}
+
+func TestCheckConntrackTableSize(t *testing.T) {
+	node := fmt.Sprintf(twoCPUNode, "kind://docker/kind/kind-control-plane")
+	testCases := []struct {
+		name       string
+		k8sConfigs []string
+		expected   string
+	}{
+		{"no kube-proxy", []string{node}, "skip"},
+		{"unmanaged", []string{node, fmt.Sprintf(kubeProxyConfigMap, "iptables", 0, 131072)}, "skip"},
+		{"defaults", []string{node, fmt.Sprintf(kubeProxyConfigMap, "iptables", 32768, 131072)}, ""},
+		{"too small", []string{node, fmt.Sprintf(kubeProxyConfigMap, "iptables", 16384, 0)}, "test-node (32768)"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			err := runNetworkCheck(t, tc.k8sConfigs, (*HealthChecker).checkConntrackTableSize)
+			expectNetworkCheckResult(t, err, tc.expected)
+		})
+	}
+}
+
+func TestCheckCNIMTU(t *testing.T) {
+	calico := `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: calico-config
+  namespace: kube-system
+data:
+  calico_backend: %s
+  veth_mtu: "%s"`
+
+	testCases := []struct {
+		name       string
+		k8sConfigs []string
+		expected   string
+	}{
+		{"unknown CNI", []string{}, "skip"},
+		{"auto-detected", []string{fmt.Sprintf(calico, "vxlan", "0")}, ""},
+		{"IP-in-IP fits", []string{fmt.Sprintf(calico, "bird", "1480")}, ""},
+		{"VXLAN too large", []string{fmt.Sprintf(calico, "vxlan", "1480")}, "set veth_mtu to 1450 or less"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			err := runNetworkCheck(t, tc.k8sConfigs, (*HealthChecker).checkCNIMTU)
+			expectNetworkCheckResult(t, err, tc.expected)
+		})
+	}
+}
+
+func TestCheckWebhookPorts(t *testing.T) {
+	publicGKENode := `apiVersion: v1
+kind: Node
+metadata:
+  name: public-node
+spec:
+  providerID: gce://project/zone/public-node
+status:
+  addresses:
+  - type: ExternalIP
+    address: 35.0.0.1`
+
+	testCases := []struct {
+		name       string
+		k8sConfigs []string
+		expected   string
+	}{
+		{"no nodes", []string{}, ""},
+		{"not GKE", []string{fmt.Sprintf(twoCPUNode, "aws:///us-east-1a/i-0123")}, ""},
+		{"public GKE", []string{publicGKENode}, ""},
+		{"private GKE", []string{fmt.Sprintf(twoCPUNode, "gce://project/zone/test-node")}, "TCP ports 4443, 8443, 9443"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			err := runNetworkCheck(t, tc.k8sConfigs, (*HealthChecker).checkWebhookPorts)
+			expectNetworkCheckResult(t, err, tc.expected)
+		})
+	}
+}
+
+func TestCheckKubeProxyMode(t *testing.T) {
+	testCases := []struct {
+		name       string
+		k8sConfigs []string
+		expected   string
+	}{
+		{"no kube-proxy", []string{}, "skip"},
+		{"default", []string{fmt.Sprintf(kubeProxyConfigMap, `""`, 32768, 131072)}, ""},
+		{"ipvs", []string{fmt.Sprintf(kubeProxyConfigMap, "ipvs", 32768, 131072)}, ""},
+		{"userspace", []string{fmt.Sprintf(kubeProxyConfigMap, "userspace", 32768, 131072)}, "userspace mode"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			err := runNetworkCheck(t, tc.k8sConfigs, (*HealthChecker).checkKubeProxyMode)
+			expectNetworkCheckResult(t, err, tc.expected)
+		})
+	}
+}
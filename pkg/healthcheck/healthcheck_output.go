@@ -14,6 +14,7 @@ import (
 
 	"github.com/briandowns/spinner"
 	"github.com/fatih/color"
+	"github.com/linkerd/linkerd2/pkg/health"
 	"github.com/linkerd/linkerd2/pkg/version"
 	"github.com/mattn/go-isatty"
 )
@@ -71,8 +72,9 @@ func PrintCoreChecksHeader(wout io.Writer) {
 
 // RunExtensionsChecks runs checks for each extension name passed into the `extensions` parameter
 // and handles formatting the output for each extension's check. This function also handles
-// finding the extension in the user's path and runs it.
-func RunExtensionsChecks(wout io.Writer, werr io.Writer, extensions []string, flags []string, output string) bool {
+// finding the extension in the user's path and runs it. The health reported by the components
+// of each extension, if any, is appended to its checks.
+func RunExtensionsChecks(wout io.Writer, werr io.Writer, extensions []string, flags []string, reports map[string][]health.Report, output string) bool {
 	if output != JSONOutput {
 		headerTxt := "Linkerd extensions checks"
 		fmt.Fprintln(wout)
@@ -146,6 +148,7 @@ func RunExtensionsChecks(wout io.Writer, werr io.Writer, extensions []string, fl
 				results.Results = append(results.Results, extensionResults.Results...)
 			}
 		}
+		results.Results = append(results.Results, ExtensionHealthResults(extension, reports[extension])...)
 		// add a new line to space out each check output
 		fmt.Fprintln(wout)
 		extensionSuccess := RunChecks(wout, werr, results, fmt.Sprintf("extension-%s", output))
@@ -157,6 +160,30 @@ func RunExtensionsChecks(wout io.Writer, werr io.Writer, extensions []string, fl
 	return success
 }
 
+// ExtensionHealthResults turns the health reported by the components of an
+// extension into check results.
+func ExtensionHealthResults(extension string, reports []health.Report) []CheckResult {
+	results := []CheckResult{}
+	for _, report := range reports {
+		for _, check := range report.Checks {
+			var err error
+			if !check.Healthy {
+				err = errors.New(check.Message)
+				if check.Message == "" {
+					err = errors.New("unhealthy")
+				}
+			}
+			results = append(results, CheckResult{
+				Category:    CategoryID(fmt.Sprintf("linkerd-%s-health", extension)),
+				Description: fmt.Sprintf("%s (%s): %s", report.Component, report.Pod, check.Name),
+				Err:         err,
+				HintURL:     HintBaseURL(version.Version) + "extensions",
+			})
+		}
+	}
+	return results
+}
+
 // RunChecks runs the checks that are part of hc
 func RunChecks(wout io.Writer, werr io.Writer, hc Runner, output string) bool {
 	if output == JSONOutput {
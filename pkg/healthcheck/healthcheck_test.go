@@ -479,6 +479,35 @@ status:
 
 }
 
+func TestExpiryWindow(t *testing.T) {
+	tests := []struct {
+		window              time.Duration
+		expectedWindow      time.Duration
+		expectedDescription string
+	}{
+		{0, issuercerts.DefaultExpiryWindow, "60 days"},
+		{7 * 24 * time.Hour, 7 * 24 * time.Hour, "7 days"},
+		{36 * time.Hour, 36 * time.Hour, "36h0m0s"},
+	}
+
+	for i, test := range tests {
+		test := test // pin
+		t.Run(fmt.Sprintf("%d: returns the expiry window", i), func(t *testing.T) {
+			hc := NewHealthChecker(
+				[]CategoryID{},
+				&Options{ExpiryWindow: test.window},
+			)
+
+			if window := hc.ExpiryWindow(); window != test.expectedWindow {
+				t.Fatalf("Expected window %s, got %s", test.expectedWindow, window)
+			}
+			if description := hc.ExpiryWindowDescription(); description != test.expectedDescription {
+				t.Fatalf("Expected description %q, got %q", test.expectedDescription, description)
+			}
+		})
+	}
+}
+
 func TestCheckCapability(t *testing.T) {
 	tests := []struct {
 		k8sConfigs []string
@@ -0,0 +1,161 @@
+package identity
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/linkerd/linkerd2/pkg/crash"
+	"github.com/linkerd/linkerd2/pkg/k8s"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+)
+
+// IssuancePolicyGVR is the Group Version and Resource of the IssuancePolicy
+// custom resource.
+var IssuancePolicyGVR = schema.GroupVersionResource{
+	Group:    k8s.IssuancePolicyAPIGroup,
+	Version:  k8s.IssuancePolicyAPIVersion,
+	Resource: k8s.IssuancePolicyResource,
+}
+
+var (
+	issuancePolicyLifetime = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "identity_issuance_policy_lifetime_seconds",
+			Help: "Lifetime of the certificates issued to namespaces with an IssuancePolicy.",
+		},
+		[]string{"namespace"},
+	)
+
+	issuancePolicyInvalid = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "identity_issuance_policy_invalid",
+			Help: "Number of IssuancePolicies in a namespace that are ignored because they are invalid.",
+		},
+		[]string{"namespace"},
+	)
+
+	issuanceLifetimeClamped = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "identity_issuance_lifetime_clamped_total",
+			Help: "Number of certificates whose lifetime was cut short by the issuer's remaining lifetime.",
+		},
+		[]string{"namespace"},
+	)
+)
+
+// IssuancePolicies holds the per-namespace overrides of the issuance
+// lifetime, read from IssuancePolicy resources. Overrides can only shorten
+// the lifetime of issued certificates: policies exceeding the maximum
+// lifetime configured for the identity service are ignored. When a namespace
+// has more than one policy, the shortest lifetime applies.
+type IssuancePolicies struct {
+	sync.RWMutex
+	maxLifetime time.Duration
+	lifetimes   map[string]time.Duration
+}
+
+// NewIssuancePolicies returns an IssuancePolicies with no overrides.
+func NewIssuancePolicies(maxLifetime time.Duration) *IssuancePolicies {
+	return &IssuancePolicies{
+		maxLifetime: maxLifetime,
+		lifetimes:   make(map[string]time.Duration),
+	}
+}
+
+// Lifetime returns the issuance lifetime override for the given namespace,
+// if any.
+func (p *IssuancePolicies) Lifetime(namespace string) (time.Duration, bool) {
+	p.RLock()
+	defer p.RUnlock()
+	lifetime, ok := p.lifetimes[namespace]
+	return lifetime, ok
+}
+
+// Watch keeps the overrides in sync with the IssuancePolicy resources in the
+// cluster until the context is cancelled.
+func (p *IssuancePolicies) Watch(ctx context.Context, client dynamic.Interface) {
+	factory := dynamicinformer.NewDynamicSharedInformerFactory(client, 10*time.Minute)
+	informer := factory.ForResource(IssuancePolicyGVR).Informer()
+	sync := func() {
+		policies := []*unstructured.Unstructured{}
+		for _, obj := range informer.GetStore().List() {
+			if policy, ok := obj.(*unstructured.Unstructured); ok {
+				policies = append(policies, policy)
+			}
+		}
+		p.update(policies)
+	}
+	informer.AddEventHandler(crash.Handler("issuance-policies", cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(interface{}) { sync() },
+		UpdateFunc: func(interface{}, interface{}) { sync() },
+		DeleteFunc: func(interface{}) { sync() },
+	}))
+	factory.Start(ctx.Done())
+}
+
+// update replaces the overrides with the ones set by the given policies.
+func (p *IssuancePolicies) update(policies []*unstructured.Unstructured) {
+	lifetimes := make(map[string]time.Duration)
+	invalid := make(map[string]float64)
+	for _, policy := range policies {
+		lifetime, err := p.parseLifetime(policy)
+		if err != nil {
+			log.Warnf("Ignoring IssuancePolicy %s/%s: %s", policy.GetNamespace(), policy.GetName(), err)
+			invalid[policy.GetNamespace()]++
+			continue
+		}
+		if current, ok := lifetimes[policy.GetNamespace()]; !ok || lifetime < current {
+			lifetimes[policy.GetNamespace()] = lifetime
+		}
+	}
+
+	p.Lock()
+	defer p.Unlock()
+	for ns := range p.lifetimes {
+		if _, ok := lifetimes[ns]; !ok {
+			log.Infof("Removed issuance lifetime override for namespace %s", ns)
+			issuancePolicyLifetime.DeleteLabelValues(ns)
+		}
+	}
+	for ns, lifetime := range lifetimes {
+		if p.lifetimes[ns] != lifetime {
+			log.Infof("Issuance lifetime for namespace %s set to %s", ns, lifetime)
+		}
+		issuancePolicyLifetime.WithLabelValues(ns).Set(lifetime.Seconds())
+	}
+	issuancePolicyInvalid.Reset()
+	for ns, count := range invalid {
+		issuancePolicyInvalid.WithLabelValues(ns).Set(count)
+	}
+	p.lifetimes = lifetimes
+}
+
+func (p *IssuancePolicies) parseLifetime(policy *unstructured.Unstructured) (time.Duration, error) {
+	value, found, err := unstructured.NestedString(policy.Object, "spec", "issuanceLifetime")
+	if err != nil {
+		return 0, err
+	}
+	if !found {
+		return 0, fmt.Errorf("spec.issuanceLifetime is required")
+	}
+	lifetime, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid issuanceLifetime: %s", err)
+	}
+	if lifetime <= 0 {
+		return 0, fmt.Errorf("issuanceLifetime must be positive, got %s", lifetime)
+	}
+	if lifetime > p.maxLifetime {
+		return 0, fmt.Errorf("issuanceLifetime %s exceeds the maximum of %s", lifetime, p.maxLifetime)
+	}
+	return lifetime, nil
+}
@@ -0,0 +1,70 @@
+package identity
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func issuancePolicy(namespace, name string, lifetime interface{}) *unstructured.Unstructured {
+	policy := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "identity.linkerd.io/v1alpha1",
+		"kind":       "IssuancePolicy",
+		"metadata": map[string]interface{}{
+			"namespace": namespace,
+			"name":      name,
+		},
+		"spec": map[string]interface{}{},
+	}}
+	if lifetime != nil {
+		policy.Object["spec"] = map[string]interface{}{"issuanceLifetime": lifetime}
+	}
+	return policy
+}
+
+func TestIssuancePolicies(t *testing.T) {
+	policies := NewIssuancePolicies(24 * time.Hour)
+	policies.update([]*unstructured.Unstructured{
+		issuancePolicy("short", "a", "1h"),
+		issuancePolicy("multiple", "a", "2h"),
+		issuancePolicy("multiple", "b", "30m"),
+		issuancePolicy("too-long", "a", "48h"),
+		issuancePolicy("invalid", "a", "forever"),
+		issuancePolicy("negative", "a", "-1h"),
+		issuancePolicy("not-a-string", "a", int64(3600)),
+		issuancePolicy("missing", "a", nil),
+	})
+
+	testCases := []struct {
+		namespace string
+		lifetime  time.Duration
+		found     bool
+	}{
+		{"short", time.Hour, true},
+		{"multiple", 30 * time.Minute, true},
+		{"too-long", 0, false},
+		{"invalid", 0, false},
+		{"negative", 0, false},
+		{"not-a-string", 0, false},
+		{"missing", 0, false},
+		{"no-policy", 0, false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // pin
+		t.Run(tc.namespace, func(t *testing.T) {
+			lifetime, found := policies.Lifetime(tc.namespace)
+			if found != tc.found || lifetime != tc.lifetime {
+				t.Fatalf("Expected (%s, %t), got (%s, %t)", tc.lifetime, tc.found, lifetime, found)
+			}
+		})
+	}
+
+	t.Run("removed policies", func(t *testing.T) {
+		policies.update([]*unstructured.Unstructured{})
+		if lifetime, found := policies.Lifetime("short"); found {
+			t.Fatalf("Expected no override, got %s", lifetime)
+		}
+	})
+}
@@ -18,6 +18,8 @@ import (
 	"github.com/golang/protobuf/ptypes"
 	pb "github.com/linkerd/linkerd2-proxy-api/go/identity"
 	"github.com/linkerd/linkerd2/pkg/tls"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	log "github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -38,6 +40,23 @@ const (
 	eventTypeIssuedLeafCert = "IssuedLeafCertificate"
 )
 
+var (
+	issuerExpiration = promauto.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "identity_issuer_expiration_timestamp_seconds",
+			Help: "Time at which the issuer certificate expires, in seconds since the epoch.",
+		},
+	)
+
+	trustAnchorExpiration = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "identity_trust_anchor_expiration_timestamp_seconds",
+			Help: "Time at which each trust anchor expires, in seconds since the epoch.",
+		},
+		[]string{"common_name", "serial_number"},
+	)
+)
+
 type (
 	// Service implements the gRPC service in terms of a Validator and Issuer.
 	Service struct {
@@ -47,6 +66,7 @@ type (
 		issuer       *tls.Issuer
 		issuerMutex  *sync.RWMutex
 		validity     *tls.Validity
+		policies     *IssuancePolicies
 		recordEvent  func(parent runtime.Object, eventType, reason, message string)
 
 		expectedName, issuerPathCrt, issuerPathKey string
@@ -90,6 +110,21 @@ func (svc *Service) updateIssuer(newIssuer tls.Issuer) {
 	svc.issuer = &newIssuer
 	log.Debug("Issuer has been updated")
 	svc.issuerMutex.Unlock()
+
+	if ca, ok := newIssuer.(*tls.CA); ok {
+		issuerExpiration.Set(float64(ca.Cred.Certificate.NotAfter.Unix()))
+	}
+}
+
+// RecordTrustAnchorsExpiration exposes the expiration time of each of the
+// trust anchors as a metric.
+func RecordTrustAnchorsExpiration(anchors []*x509.Certificate) {
+	trustAnchorExpiration.Reset()
+	for _, anchor := range anchors {
+		trustAnchorExpiration.
+			WithLabelValues(anchor.Subject.CommonName, anchor.SerialNumber.String()).
+			Set(float64(anchor.NotAfter.Unix()))
+	}
 }
 
 // Run reads from the issuer and error channels and reloads the issuer certs when necessary
@@ -136,7 +171,7 @@ func (svc *Service) loadCredentials() (tls.Issuer, error) {
 }
 
 // NewService creates a new identity service.
-func NewService(validator Validator, trustAnchors *x509.CertPool, validity *tls.Validity, recordEvent func(parent runtime.Object, eventType, reason, message string), expectedName, issuerPathCrt, issuerPathKey string) *Service {
+func NewService(validator Validator, trustAnchors *x509.CertPool, validity *tls.Validity, policies *IssuancePolicies, recordEvent func(parent runtime.Object, eventType, reason, message string), expectedName, issuerPathCrt, issuerPathKey string) *Service {
 	return &Service{
 		pb.UnimplementedIdentityServer{},
 		validator,
@@ -144,6 +179,7 @@ func NewService(validator Validator, trustAnchors *x509.CertPool, validity *tls.
 		nil,
 		&sync.RWMutex{},
 		validity,
+		policies,
 		recordEvent,
 		expectedName,
 		issuerPathCrt,
@@ -170,6 +206,32 @@ func (svc *Service) ensureIssuerStillValid() error {
 	}
 }
 
+// issueEndEntityCrt issues a certificate for the given namespace, honoring its
+// IssuancePolicy if there's one. The lifetime of the certificate is cut short
+// when the issuer expires before it.
+func (svc *Service) issueEndEntityCrt(csr *x509.CertificateRequest, namespace string) (tls.Crt, error) {
+	issuer := *svc.issuer
+	if svc.policies == nil {
+		return issuer.IssueEndEntityCrt(csr)
+	}
+	lifetime, ok := svc.policies.Lifetime(namespace)
+	if !ok {
+		return issuer.IssueEndEntityCrt(csr)
+	}
+
+	switch is := issuer.(type) {
+	case *tls.CA:
+		if remaining := time.Until(is.Expiration()); lifetime > remaining {
+			log.Warnf("Issuance lifetime %s for namespace %s exceeds the remaining lifetime of the issuer; using %s", lifetime, namespace, remaining)
+			issuanceLifetimeClamped.WithLabelValues(namespace).Inc()
+			lifetime = remaining
+		}
+		return is.IssueEndEntityCrtWithLifetime(csr, lifetime)
+	default:
+		return tls.Crt{}, fmt.Errorf("unsupported issuer type. Expected *tls.CA, got %v", is)
+	}
+}
+
 // Certify validates identity and signs certificates.
 func (svc *Service) Certify(ctx context.Context, req *pb.CertifyRequest) (*pb.CertifyResponse, error) {
 	svc.issuerMutex.RLock()
@@ -225,8 +287,8 @@ func (svc *Service) Certify(ctx context.Context, req *pb.CertifyRequest) (*pb.Ce
 	}
 
 	// Create a certificate
-	issuer := *svc.issuer
-	crt, err := issuer.IssueEndEntityCrt(csr)
+	identitySegments := strings.Split(tokIdentity, ".")
+	crt, err := svc.issueEndEntityCrt(csr, identitySegments[1])
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
@@ -244,7 +306,6 @@ func (svc *Service) Certify(ctx context.Context, req *pb.CertifyRequest) (*pb.Ce
 	hasher := md5.New()
 	hasher.Write(crts[0])
 	hash := hex.EncodeToString(hasher.Sum(nil))
-	identitySegments := strings.Split(tokIdentity, ".")
 	msg := fmt.Sprintf("issued certificate for %s until %s: %s", tokIdentity, crt.Certificate.NotAfter, hash)
 	sa := v1.ServiceAccount{
 		ObjectMeta: metav1.ObjectMeta{
@@ -19,6 +19,7 @@ import (
 	"github.com/linkerd/linkerd2/pkg/charts/linkerd2"
 	l5dcharts "github.com/linkerd/linkerd2/pkg/charts/linkerd2"
 	"github.com/linkerd/linkerd2/pkg/charts/static"
+	"github.com/linkerd/linkerd2/pkg/featuregates"
 	"github.com/linkerd/linkerd2/pkg/k8s"
 	"github.com/linkerd/linkerd2/pkg/util"
 	log "github.com/sirupsen/logrus"
@@ -31,12 +32,22 @@ import (
 	k8sResource "k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/version"
 	"sigs.k8s.io/yaml"
 )
 
 var (
 	rTrail = regexp.MustCompile(`\},\s*\]`)
 
+	// nativeSidecarVersion is the first version of Kubernetes supporting
+	// native sidecars, behind its SidecarContainers feature gate
+	nativeSidecarVersion = version.MustParseGeneric("1.28.0")
+
+	// nativeSidecarDefaultVersion is the first version of Kubernetes
+	// enabling native sidecars by default, which make the proxy shutdown
+	// coordination of Jobs unnecessary
+	nativeSidecarDefaultVersion = version.MustParseGeneric("1.29.0")
+
 	// ProxyAnnotations is the list of possible annotations that can be applied on a pod or namespace
 	ProxyAnnotations = []string{
 		k8s.ProxyAdminPortAnnotation,
@@ -65,12 +76,16 @@ var (
 		k8s.ProxyIgnoreOutboundPortsAnnotation,
 		k8s.ProxyOutboundConnectTimeout,
 		k8s.ProxyInboundConnectTimeout,
+		k8s.ProxyInboundMaxInFlightAnnotation,
+		k8s.ProxyOutboundMaxInFlightAnnotation,
+		k8s.ProxyBufferCapacityAnnotation,
 		k8s.ProxyAwait,
 	}
 	// ProxyAlphaConfigAnnotations is the list of all alpha configuration
 	// (config.alpha prefix) that can be applied to a pod or namespace.
 	ProxyAlphaConfigAnnotations = []string{
 		k8s.ProxyWaitBeforeExitSecondsAnnotation,
+		k8s.ProxyJobShutdownAnnotation,
 	}
 )
 
@@ -108,6 +123,8 @@ type ResourceConfig struct {
 	nsAnnotations  map[string]string
 	ownerRetriever OwnerRetrieverFunc
 	origin         Origin
+	kubeVersion    *version.Version
+	featureGates   *featuregates.FeatureGates
 
 	workload struct {
 		obj      runtime.Object
@@ -141,6 +158,25 @@ type podPatch struct {
 	AddRootVolumes        bool                      `json:"addRootVolumes"`
 	Labels                map[string]string         `json:"labels"`
 	DebugContainer        *l5dcharts.DebugContainer `json:"debugContainer"`
+	JobShutdown           *jobShutdown              `json:"jobShutdown"`
+	NativeSidecar         bool                      `json:"nativeSidecar"`
+}
+
+// jobShutdown holds the patch information needed to coordinate the shutdown
+// of the proxy with the application containers of a Job, see
+// injectJobShutdown.
+type jobShutdown struct {
+	// Image is the image linkerd-await is copied from
+	Image      *l5dcharts.Image `json:"image"`
+	Containers []jobContainer   `json:"containers"`
+}
+
+// jobContainer holds the patch information needed to wrap the command of an
+// application container with linkerd-await, see injectJobShutdown.
+type jobContainer struct {
+	Index               int      `json:"index"`
+	Command             []string `json:"command"`
+	AddRootVolumeMounts bool     `json:"addRootVolumeMounts"`
 }
 
 type annotationPatch struct {
@@ -189,6 +225,20 @@ func (conf *ResourceConfig) WithOwnerRetriever(f OwnerRetrieverFunc) *ResourceCo
 	return conf
 }
 
+// WithKubernetesVersion enriches ResourceConfig with the version of the
+// cluster the resource is injected into, if known
+func (conf *ResourceConfig) WithKubernetesVersion(v *version.Version) *ResourceConfig {
+	conf.kubeVersion = v
+	return conf
+}
+
+// WithFeatureGates enriches ResourceConfig with the feature gates of the
+// injector. Without them, the feature gates of the values are used.
+func (conf *ResourceConfig) WithFeatureGates(gates *featuregates.FeatureGates) *ResourceConfig {
+	conf.featureGates = gates
+	return conf
+}
+
 // GetOwnerRef returns a reference to the resource's owner resource, if any
 func (conf *ResourceConfig) GetOwnerRef() *metav1.OwnerReference {
 	return conf.workload.ownerRef
@@ -259,6 +309,7 @@ func (conf *ResourceConfig) GetOverriddenValues() (*linkerd2.Values, error) {
 
 	copyValues.Proxy.PodInboundPorts = getPodInboundPorts(conf.pod.spec)
 	conf.applyAnnotationOverrides(copyValues)
+	conf.applyRegistryOverride(copyValues)
 	return copyValues, nil
 }
 
@@ -301,6 +352,10 @@ func (conf *ResourceConfig) GetPodPatch(injectProxy bool) ([]byte, error) {
 		if injectProxy {
 			conf.injectObjectMeta(patch)
 			conf.injectPodSpec(patch)
+			conf.injectNativeSidecar(patch)
+			if err := conf.injectJobShutdown(patch); err != nil {
+				return nil, err
+			}
 		} else {
 			patch.Proxy = nil
 			patch.ProxyInit = nil
@@ -653,6 +708,7 @@ func (conf *ResourceConfig) injectPodSpec(values *podPatch) {
 					Name:       conf.values.DebugContainer.Image.Name,
 					Version:    conf.values.DebugContainer.Image.Version,
 					PullPolicy: conf.values.DebugContainer.Image.PullPolicy,
+					Digest:     conf.values.DebugContainer.Image.Digest,
 				},
 			}
 		}
@@ -662,6 +718,101 @@ func (conf *ResourceConfig) injectPodSpec(values *podPatch) {
 	values.AddRootVolumes = len(conf.pod.spec.Volumes) == 0
 }
 
+// injectJobShutdown prepares the patch for Job pods opted into proxy shutdown
+// coordination. Without native sidecar support, a Job's pod never completes
+// because the proxy keeps running after the application exits. To avoid
+// that, linkerd-await is copied from the debug image into a shared volume
+// and the command of every application container is wrapped with
+// `linkerd-await --shutdown`, which waits for the proxy to be ready, runs the
+// command and then asks the proxy to shut down. The entrypoint of an image
+// can't be known here, so containers without an explicit command are
+// rejected.
+func (conf *ResourceConfig) injectJobShutdown(values *podPatch) error {
+	if values.Proxy == nil || !values.Proxy.JobShutdown {
+		return nil
+	}
+	if !conf.isJob() {
+		log.Warnf("%s is only supported for Job and CronJob workloads; ignoring", k8s.ProxyJobShutdownAnnotation)
+		values.Proxy.JobShutdown = false
+		return nil
+	}
+	if values.NativeSidecar {
+		log.Infof("%s is not needed as the proxy is injected as a native sidecar; ignoring", k8s.ProxyJobShutdownAnnotation)
+		values.Proxy.JobShutdown = false
+		return nil
+	}
+
+	values.JobShutdown = &jobShutdown{
+		Image: &l5dcharts.Image{
+			Name:       conf.values.DebugContainer.Image.Name,
+			Version:    conf.values.DebugContainer.Image.Version,
+			PullPolicy: conf.values.DebugContainer.Image.PullPolicy,
+			Digest:     conf.values.DebugContainer.Image.Digest,
+		},
+	}
+	for i, c := range conf.pod.spec.Containers {
+		if len(c.Command) == 0 {
+			return fmt.Errorf("container %s has no explicit command to be wrapped with linkerd-await; set its command, or disable %s", c.Name, k8s.ProxyJobShutdownAnnotation)
+		}
+		command := append([]string{k8s.MountPathAwait + "/linkerd-await", "--shutdown", "--"}, c.Command...)
+		values.JobShutdown.Containers = append(values.JobShutdown.Containers, jobContainer{
+			Index:               i,
+			Command:             command,
+			AddRootVolumeMounts: len(c.VolumeMounts) == 0,
+		})
+	}
+	return nil
+}
+
+// injectNativeSidecar decides whether the proxy is added as a native sidecar,
+// i.e. an init container restarted Always, which Kubernetes starts before the
+// application containers and stops once they're done. That's the case when
+// the NativeSidecars feature gate is enabled, and for the Jobs opted into
+// proxy shutdown coordination on the versions of Kubernetes enabling native
+// sidecars by default, which makes the coordination unnecessary.
+func (conf *ResourceConfig) injectNativeSidecar(values *podPatch) {
+	if values.Proxy == nil {
+		return
+	}
+	switch {
+	case conf.featureEnabled(featuregates.NativeSidecars):
+		if conf.kubeVersion != nil && !conf.kubeVersion.AtLeast(nativeSidecarVersion) {
+			log.Warnf("the %s feature gate requires Kubernetes %s or later, found %s; injecting the proxy as a regular container", featuregates.NativeSidecars, nativeSidecarVersion, conf.kubeVersion)
+			return
+		}
+		values.NativeSidecar = true
+	case values.Proxy.JobShutdown && conf.isJob():
+		values.NativeSidecar = conf.kubeVersion != nil && conf.kubeVersion.AtLeast(nativeSidecarDefaultVersion)
+	}
+}
+
+// featureEnabled returns whether the given feature is enabled, by the
+// feature gates of the injector if any, or else by those of the values.
+func (conf *ResourceConfig) featureEnabled(f featuregates.Feature) bool {
+	if conf.featureGates != nil {
+		return conf.featureGates.Enabled(f)
+	}
+	return conf.values != nil && conf.values.FeatureGates[string(f)]
+}
+
+// isJob returns true if the workload being injected is a Job or CronJob, or
+// a pod created by one of them.
+func (conf *ResourceConfig) isJob() bool {
+	kind := strings.ToLower(conf.workload.metaType.Kind)
+	if conf.workload.ownerRef != nil {
+		kind = strings.ToLower(conf.workload.ownerRef.Kind)
+	}
+	if kind == k8s.Job || kind == k8s.CronJob {
+		return true
+	}
+	for _, ref := range conf.pod.meta.OwnerReferences {
+		if strings.ToLower(ref.Kind) == k8s.Job {
+			return true
+		}
+	}
+	return false
+}
+
 func (conf *ResourceConfig) injectProxyInit(values *podPatch) {
 
 	// Fill common fields from Proxy into ProxyInit
@@ -744,6 +895,45 @@ func (conf *ResourceConfig) injectPodAnnotations(values *podPatch) {
 	}
 }
 
+// applyRegistryOverride replaces the registry of the proxy, proxy-init and
+// debug images with the one set by the namespace's image-registry annotation,
+// provided it's allowed by the injector's configuration. It's applied after
+// the other overrides, so that it also covers images set through annotations.
+func (conf *ResourceConfig) applyRegistryOverride(values *l5dcharts.Values) {
+	registry, ok := conf.nsAnnotations[k8s.ImageRegistryAnnotation]
+	if !ok {
+		return
+	}
+	registry = strings.TrimSuffix(registry, "/")
+
+	allowed := false
+	if values.ProxyInjector != nil {
+		for _, r := range values.ProxyInjector.AllowedRegistries {
+			if strings.TrimSuffix(r, "/") == registry {
+				allowed = true
+				break
+			}
+		}
+	}
+	if !allowed {
+		log.Warnf("ignoring the %s annotation of namespace %s: registry %q is not allowed by the proxy injector configuration", k8s.ImageRegistryAnnotation, conf.workload.Meta.Namespace, registry)
+		return
+	}
+
+	values.Proxy.Image.Name = overrideRegistry(values.Proxy.Image.Name, registry)
+	values.ProxyInit.Image.Name = overrideRegistry(values.ProxyInit.Image.Name, registry)
+	values.DebugContainer.Image.Name = overrideRegistry(values.DebugContainer.Image.Name, registry)
+}
+
+// overrideRegistry replaces the registry portion of the image with the given
+// registry.
+func overrideRegistry(image, registry string) string {
+	if image == "" {
+		return image
+	}
+	return registry + "/" + image[strings.LastIndex(image, "/")+1:]
+}
+
 func (conf *ResourceConfig) applyAnnotationOverrides(values *l5dcharts.Values) {
 	annotations := make(map[string]string)
 	for k, v := range conf.pod.meta.Annotations {
@@ -767,10 +957,16 @@ func (conf *ResourceConfig) applyAnnotationOverrides(values *l5dcharts.Values) {
 	}
 
 	if override, ok := annotations[k8s.ProxyImageAnnotation]; ok {
+		if override != values.Proxy.Image.Name {
+			values.Proxy.Image.Digest = ""
+		}
 		values.Proxy.Image.Name = override
 	}
 
 	if override, ok := annotations[k8s.ProxyVersionOverrideAnnotation]; ok {
+		if override != values.Proxy.Image.Version {
+			values.Proxy.Image.Digest = ""
+		}
 		values.Proxy.Image.Version = override
 	}
 
@@ -779,6 +975,9 @@ func (conf *ResourceConfig) applyAnnotationOverrides(values *l5dcharts.Values) {
 	}
 
 	if override, ok := annotations[k8s.ProxyInitImageVersionAnnotation]; ok {
+		if override != values.ProxyInit.Image.Version {
+			values.ProxyInit.Image.Digest = ""
+		}
 		values.ProxyInit.Image.Version = override
 	}
 
@@ -851,6 +1050,30 @@ func (conf *ResourceConfig) applyAnnotationOverrides(values *l5dcharts.Values) {
 		}
 	}
 
+	if override, ok := annotations[k8s.ProxyInboundMaxInFlightAnnotation]; ok {
+		if v, err := parseProxyLimit(override); err != nil {
+			log.Warnf("%s (%s)", err, k8s.ProxyInboundMaxInFlightAnnotation)
+		} else {
+			values.Proxy.InboundMaxInFlight = v
+		}
+	}
+
+	if override, ok := annotations[k8s.ProxyOutboundMaxInFlightAnnotation]; ok {
+		if v, err := parseProxyLimit(override); err != nil {
+			log.Warnf("%s (%s)", err, k8s.ProxyOutboundMaxInFlightAnnotation)
+		} else {
+			values.Proxy.OutboundMaxInFlight = v
+		}
+	}
+
+	if override, ok := annotations[k8s.ProxyBufferCapacityAnnotation]; ok {
+		if v, err := parseProxyLimit(override); err != nil {
+			log.Warnf("%s (%s)", err, k8s.ProxyBufferCapacityAnnotation)
+		} else {
+			values.Proxy.BufferCapacity = v
+		}
+	}
+
 	if override, ok := annotations[k8s.ProxyEnableGatewayAnnotation]; ok {
 		value, err := strconv.ParseBool(override)
 		if err == nil {
@@ -925,6 +1148,9 @@ func (conf *ResourceConfig) applyAnnotationOverrides(values *l5dcharts.Values) {
 	}
 
 	if override, ok := annotations[k8s.ProxyInitImageAnnotation]; ok {
+		if override != values.ProxyInit.Image.Name {
+			values.ProxyInit.Image.Digest = ""
+		}
 		values.ProxyInit.Image.Name = override
 	}
 
@@ -946,10 +1172,16 @@ func (conf *ResourceConfig) applyAnnotationOverrides(values *l5dcharts.Values) {
 	}
 
 	if override, ok := annotations[k8s.DebugImageAnnotation]; ok {
+		if override != values.DebugContainer.Image.Name {
+			values.DebugContainer.Image.Digest = ""
+		}
 		values.DebugContainer.Image.Name = override
 	}
 
 	if override, ok := annotations[k8s.DebugImageVersionAnnotation]; ok {
+		if override != values.DebugContainer.Image.Version {
+			values.DebugContainer.Image.Digest = ""
+		}
 		values.DebugContainer.Image.Version = override
 	}
 
@@ -964,6 +1196,14 @@ func (conf *ResourceConfig) applyAnnotationOverrides(values *l5dcharts.Values) {
 			log.Warnf("unrecognized value used for the %s annotation, valid values are: [%s, %s]", k8s.ProxyAwait, k8s.Enabled, k8s.Disabled)
 		}
 	}
+
+	if override, ok := annotations[k8s.ProxyJobShutdownAnnotation]; ok {
+		if override == k8s.Enabled || override == k8s.Disabled {
+			values.Proxy.JobShutdown = override == k8s.Enabled
+		} else {
+			log.Warnf("unrecognized value used for the %s annotation, valid values are: [%s, %s]", k8s.ProxyJobShutdownAnnotation, k8s.Enabled, k8s.Disabled)
+		}
+	}
 }
 
 // GetOverriddenConfiguration returns a map of the overridden proxy annotations
@@ -1067,6 +1307,16 @@ func ToWholeCPUCores(q k8sResource.Quantity) (int64, error) {
 	return 0, fmt.Errorf("Could not parse cores: %s", q.String())
 }
 
+// parseProxyLimit parses the value of an annotation capping a proxy
+// resource, which must be a positive integer.
+func parseProxyLimit(value string) (uint32, error) {
+	v, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || v == 0 {
+		return 0, fmt.Errorf("invalid value %q: a positive integer is expected", value)
+	}
+	return uint32(v), nil
+}
+
 func getPodInboundPorts(podSpec *corev1.PodSpec) string {
 	ports := []string{}
 	if podSpec != nil {
@@ -5,12 +5,15 @@ import (
 	"testing"
 
 	l5dcharts "github.com/linkerd/linkerd2/pkg/charts/linkerd2"
+	"github.com/linkerd/linkerd2/pkg/featuregates"
 	"github.com/linkerd/linkerd2/pkg/k8s"
 	"github.com/linkerd/linkerd2/pkg/version"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	k8sResource "k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sversion "k8s.io/apimachinery/pkg/util/version"
 	"sigs.k8s.io/yaml"
 )
 
@@ -231,6 +234,43 @@ func TestGetOverriddenValues(t *testing.T) {
 				return values
 			},
 		},
+		{id: "use valid proxy limits",
+			nsAnnotations: map[string]string{
+				k8s.ProxyInboundMaxInFlightAnnotation:  "5000",
+				k8s.ProxyOutboundMaxInFlightAnnotation: "1000",
+				k8s.ProxyBufferCapacityAnnotation:      "200",
+			},
+			spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{},
+					Spec:       corev1.PodSpec{},
+				},
+			},
+			expected: func() *l5dcharts.Values {
+				values, _ := l5dcharts.NewValues()
+				values.Proxy.InboundMaxInFlight = 5000
+				values.Proxy.OutboundMaxInFlight = 1000
+				values.Proxy.BufferCapacity = 200
+				return values
+			},
+		},
+		{id: "use invalid proxy limits",
+			nsAnnotations: map[string]string{
+				k8s.ProxyInboundMaxInFlightAnnotation:  "0",
+				k8s.ProxyOutboundMaxInFlightAnnotation: "-10",
+				k8s.ProxyBufferCapacityAnnotation:      "lots",
+			},
+			spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{},
+					Spec:       corev1.PodSpec{},
+				},
+			},
+			expected: func() *l5dcharts.Values {
+				values, _ := l5dcharts.NewValues()
+				return values
+			},
+		},
 		{id: "use named port for opaque ports",
 			nsAnnotations: make(map[string]string),
 			spec: appsv1.DeploymentSpec{
@@ -290,6 +330,250 @@ func TestGetOverriddenValues(t *testing.T) {
 	}
 }
 
+func TestRegistryOverride(t *testing.T) {
+	testConfig, err := l5dcharts.NewValues()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	testConfig.ProxyInjector.AllowedRegistries = []string{"registry.example.com/linkerd/"}
+
+	testCases := []struct {
+		id             string
+		nsAnnotations  map[string]string
+		podAnnotations map[string]string
+		expected       func() *l5dcharts.Values
+	}{
+		{
+			id:            "no override",
+			nsAnnotations: map[string]string{},
+			expected: func() *l5dcharts.Values {
+				values, _ := l5dcharts.NewValues()
+				return values
+			},
+		},
+		{
+			id:            "allowed registry",
+			nsAnnotations: map[string]string{k8s.ImageRegistryAnnotation: "registry.example.com/linkerd"},
+			podAnnotations: map[string]string{
+				k8s.DebugImageAnnotation: "other.example.com/debug",
+			},
+			expected: func() *l5dcharts.Values {
+				values, _ := l5dcharts.NewValues()
+				values.Proxy.Image.Name = "registry.example.com/linkerd/proxy"
+				values.ProxyInit.Image.Name = "registry.example.com/linkerd/proxy-init"
+				values.DebugContainer.Image.Name = "registry.example.com/linkerd/debug"
+				return values
+			},
+		},
+		{
+			id:            "registry not allowed",
+			nsAnnotations: map[string]string{k8s.ImageRegistryAnnotation: "evil.example.com"},
+			expected: func() *l5dcharts.Values {
+				values, _ := l5dcharts.NewValues()
+				return values
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // pin
+		t.Run(tc.id, func(t *testing.T) {
+			deployment := appsv1.Deployment{Spec: appsv1.DeploymentSpec{
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{Annotations: tc.podAnnotations},
+				},
+			}}
+			data, err := yaml.Marshal(&deployment)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			resourceConfig := NewResourceConfig(testConfig, OriginUnknown).WithKind("Deployment").WithNsAnnotations(tc.nsAnnotations)
+			if err := resourceConfig.parse(data); err != nil {
+				t.Fatal(err)
+			}
+
+			actual, err := resourceConfig.GetOverriddenValues()
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := tc.expected()
+			expected.ProxyInjector.AllowedRegistries = testConfig.ProxyInjector.AllowedRegistries
+			if !reflect.DeepEqual(actual, expected) {
+				t.Fatalf("Expected values to be \n%v\n but was \n%v", expected.String(), actual.String())
+			}
+		})
+	}
+}
+
+func TestInjectJobShutdown(t *testing.T) {
+	testConfig, err := l5dcharts.NewValues()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	jobWith := func(containers ...corev1.Container) []byte {
+		job := &batchv1.Job{
+			Spec: batchv1.JobSpec{
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Annotations: map[string]string{
+							k8s.ProxyJobShutdownAnnotation: k8s.Enabled,
+						},
+					},
+					Spec: corev1.PodSpec{Containers: containers},
+				},
+			},
+		}
+		data, err := yaml.Marshal(job)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	migrate := corev1.Container{Name: "migrate", Command: []string{"/bin/migrate", "up"}}
+
+	for _, tc := range []struct {
+		name           string
+		kind           string
+		kubeVersion    string
+		gates          string
+		job            []byte
+		expected       *jobShutdown
+		expectedNative bool
+		expectedErr    bool
+	}{
+		{
+			name: "job",
+			kind: "Job",
+			job:  jobWith(migrate),
+			expected: &jobShutdown{
+				Image: &l5dcharts.Image{
+					Name:       testConfig.DebugContainer.Image.Name,
+					Version:    testConfig.DebugContainer.Image.Version,
+					PullPolicy: testConfig.DebugContainer.Image.PullPolicy,
+				},
+				Containers: []jobContainer{
+					{
+						Index:               0,
+						Command:             []string{k8s.MountPathAwait + "/linkerd-await", "--shutdown", "--", "/bin/migrate", "up"},
+						AddRootVolumeMounts: true,
+					},
+				},
+			},
+		},
+		{
+			name:        "job without native sidecars",
+			kind:        "Job",
+			kubeVersion: "v1.27.3",
+			job:         jobWith(migrate),
+			expected: &jobShutdown{
+				Image: &l5dcharts.Image{
+					Name:       testConfig.DebugContainer.Image.Name,
+					Version:    testConfig.DebugContainer.Image.Version,
+					PullPolicy: testConfig.DebugContainer.Image.PullPolicy,
+				},
+				Containers: []jobContainer{
+					{
+						Index:               0,
+						Command:             []string{k8s.MountPathAwait + "/linkerd-await", "--shutdown", "--", "/bin/migrate", "up"},
+						AddRootVolumeMounts: true,
+					},
+				},
+			},
+		},
+		{
+			name:           "job with native sidecars",
+			kind:           "Job",
+			kubeVersion:    "v1.29.0",
+			job:            jobWith(migrate),
+			expectedNative: true,
+		},
+		{
+			name:           "job with the NativeSidecars feature gate",
+			kind:           "Job",
+			kubeVersion:    "v1.28.0",
+			gates:          "NativeSidecars=true",
+			job:            jobWith(migrate),
+			expectedNative: true,
+		},
+		{
+			name:        "NativeSidecars feature gate without native sidecars",
+			kind:        "Job",
+			kubeVersion: "v1.27.3",
+			gates:       "NativeSidecars=true",
+			job:         jobWith(migrate),
+			expected: &jobShutdown{
+				Image: &l5dcharts.Image{
+					Name:       testConfig.DebugContainer.Image.Name,
+					Version:    testConfig.DebugContainer.Image.Version,
+					PullPolicy: testConfig.DebugContainer.Image.PullPolicy,
+				},
+				Containers: []jobContainer{
+					{
+						Index:               0,
+						Command:             []string{k8s.MountPathAwait + "/linkerd-await", "--shutdown", "--", "/bin/migrate", "up"},
+						AddRootVolumeMounts: true,
+					},
+				},
+			},
+		},
+		{
+			name:        "container without command",
+			kind:        "Job",
+			job:         jobWith(migrate, corev1.Container{Name: "no-command"}),
+			expectedErr: true,
+		},
+		{
+			name: "not a job",
+			kind: "Deployment",
+			job:  jobWith(migrate),
+		},
+	} {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			resourceConfig := NewResourceConfig(testConfig, OriginUnknown).WithKind(tc.kind)
+			if tc.kubeVersion != "" {
+				resourceConfig.WithKubernetesVersion(k8sversion.MustParseGeneric(tc.kubeVersion))
+			}
+			gates := featuregates.New()
+			if err := gates.ParseFlag(tc.gates); err != nil {
+				t.Fatal(err)
+			}
+			resourceConfig.WithFeatureGates(gates)
+			if err := resourceConfig.parse(tc.job); err != nil {
+				t.Fatal(err)
+			}
+			values, err := resourceConfig.GetOverriddenValues()
+			if err != nil {
+				t.Fatal(err)
+			}
+			patch := &podPatch{Values: *values}
+			resourceConfig.injectNativeSidecar(patch)
+			err = resourceConfig.injectJobShutdown(patch)
+			if tc.expectedErr {
+				if err == nil {
+					t.Fatal("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+
+			if patch.NativeSidecar != tc.expectedNative {
+				t.Fatalf("Expected the proxy to be a native sidecar: %t", tc.expectedNative)
+			}
+			if patch.Proxy.JobShutdown != (tc.expected != nil) {
+				t.Fatalf("Expected job shutdown to be %t", tc.expected != nil)
+			}
+			if !reflect.DeepEqual(patch.JobShutdown, tc.expected) {
+				t.Fatalf("Expected job shutdown %+v but got %+v", tc.expected, patch.JobShutdown)
+			}
+		})
+	}
+}
+
 func TestWholeCPUCores(t *testing.T) {
 	for _, c := range []struct {
 		v string
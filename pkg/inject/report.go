@@ -22,6 +22,7 @@ const (
 	invalidInjectAnnotationNamespace     = "invalid_inject_annotation_at_ns"
 	disabledAutomountServiceAccountToken = "disabled_automount_service_account_token_account"
 	udpPortsEnabled                      = "udp_ports_enabled"
+	staticPod                            = "static_pod"
 )
 
 var (
@@ -36,6 +37,7 @@ var (
 		invalidInjectAnnotationNamespace:     fmt.Sprintf("invalid value for annotation \"%s\" at namespace", k8s.ProxyInjectAnnotation),
 		disabledAutomountServiceAccountToken: "automountServiceAccountToken set to \"false\"",
 		udpPortsEnabled:                      "UDP port(s) configured on pod spec",
+		staticPod:                            "pod is the mirror of a static pod run by the kubelet",
 	}
 )
 
@@ -47,6 +49,7 @@ type Report struct {
 	HostNetwork                  bool
 	Sidecar                      bool
 	UDP                          bool // true if any port in any container has `protocol: UDP`
+	StaticPod                    bool // true if the pod is the mirror of a static pod
 	UnsupportedResource          bool
 	InjectDisabled               bool
 	InjectDisabledReason         string
@@ -90,6 +93,7 @@ func newReport(conf *ResourceConfig) *Report {
 		report.HostNetwork = conf.pod.spec.HostNetwork
 		report.Sidecar = healthcheck.HasExistingSidecars(conf.pod.spec)
 		report.UDP = checkUDPPorts(conf.pod.spec)
+		_, report.StaticPod = conf.pod.meta.Annotations[k8s.KubeletMirrorPodAnnotation]
 		if conf.pod.spec.AutomountServiceAccountToken != nil {
 			report.AutomountServiceAccountToken = *conf.pod.spec.AutomountServiceAccountToken
 		}
@@ -115,7 +119,8 @@ func (r *Report) ResName() string {
 }
 
 // Injectable returns false if the report flags indicate that the workload is on a host network
-// or there is already a sidecar or the resource is not supported or inject is explicitly disabled.
+// or there is already a sidecar or the pod is a static pod or the resource is not supported or
+// inject is explicitly disabled.
 // If false, the second returned value describes the reason.
 func (r *Report) Injectable() (bool, []string) {
 	var reasons []string
@@ -125,6 +130,12 @@ func (r *Report) Injectable() (bool, []string) {
 	if r.Sidecar {
 		reasons = append(reasons, sidecarExists)
 	}
+	// The kubelet runs static pods from their manifests, so any change made
+	// to their mirror pods wouldn't be applied and would only make the mirror
+	// diverge from the pod that actually runs
+	if r.StaticPod {
+		reasons = append(reasons, staticPod)
+	}
 	if r.UnsupportedResource {
 		reasons = append(reasons, unsupportedResource)
 	}
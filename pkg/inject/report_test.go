@@ -253,6 +253,29 @@ func TestInjectable(t *testing.T) {
 			injectable: false,
 			reasons:    []string{disabledAutomountServiceAccountToken},
 		},
+		{
+			podSpec: &corev1.PodSpec{
+				Containers: []corev1.Container{
+					{
+						VolumeMounts: []corev1.VolumeMount{
+							{
+								MountPath: k8s.MountPathServiceAccount,
+							},
+						},
+					},
+				},
+			},
+			podMeta: &metav1.ObjectMeta{
+				Annotations: map[string]string{
+					k8s.KubeletMirrorPodAnnotation: "5f4a0e7b8c1d2e3f",
+				},
+			},
+			nsAnnotations: map[string]string{
+				k8s.ProxyInjectAnnotation: k8s.ProxyInjectEnabled,
+			},
+			injectable: false,
+			reasons:    []string{staticPod},
+		},
 	}
 
 	for i, testCase := range testCases {
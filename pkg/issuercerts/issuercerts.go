@@ -6,6 +6,7 @@ import (
 	"crypto/x509"
 	"fmt"
 	"io/ioutil"
+	"strings"
 	"time"
 
 	"k8s.io/client-go/kubernetes"
@@ -17,7 +18,10 @@ import (
 )
 
 const keyMissingError = "key %s containing the %s needs to exist in secret %s if --identity-external-issuer=%v"
-const expirationWarningThresholdInDays = 60
+
+// DefaultExpiryWindow is how long before their expiration certificates are
+// considered to be expiring soon, unless configured otherwise.
+const DefaultExpiryWindow = 60 * 24 * time.Hour
 
 // IssuerCertData holds the trust anchors cert data used by the CA
 type IssuerCertData struct {
@@ -64,6 +68,32 @@ func FetchExternalIssuerData(ctx context.Context, api kubernetes.Interface, cont
 		return nil, fmt.Errorf(keyMissingError, k8s.IdentityIssuerTrustAnchorsNameExternal, "trust anchors", k8s.IdentityIssuerSecretName, true)
 	}
 
+	return externalIssuerData(secret, string(anchors))
+}
+
+// FetchExternalCAIssuerData fetches the issuer data from the
+// linkerd-identity-issuer secret of the given scheme, along with the trust
+// anchors of the linkerd-identity-trust-roots ConfigMap, for when the latter
+// is managed externally (identity.externalCA). The secret's ca.crt key, if
+// any, is then ignored.
+func FetchExternalCAIssuerData(ctx context.Context, api kubernetes.Interface, scheme, controlPlaneNamespace string) (*IssuerCertData, error) {
+	anchors, err := FetchTrustRoots(ctx, api, controlPlaneNamespace)
+	if err != nil {
+		return nil, err
+	}
+
+	if scheme == "" || scheme == k8s.IdentityIssuerSchemeLinkerd {
+		return FetchIssuerData(ctx, api, anchors, controlPlaneNamespace)
+	}
+
+	secret, err := api.CoreV1().Secrets(controlPlaneNamespace).Get(ctx, k8s.IdentityIssuerSecretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return externalIssuerData(secret, anchors)
+}
+
+func externalIssuerData(secret *corev1.Secret, anchors string) (*IssuerCertData, error) {
 	crt, ok := secret.Data[corev1.TLSCertKey]
 	if !ok {
 		return nil, fmt.Errorf(keyMissingError, corev1.TLSCertKey, "issuer certificate", k8s.IdentityIssuerSecretName, true)
@@ -79,7 +109,28 @@ func FetchExternalIssuerData(ctx context.Context, api kubernetes.Interface, cont
 		return nil, fmt.Errorf("could not parse issuer certificate: %w", err)
 	}
 
-	return &IssuerCertData{string(anchors), string(crt), string(key), &cert.Certificate.NotAfter}, nil
+	return &IssuerCertData{anchors, string(crt), string(key), &cert.Certificate.NotAfter}, nil
+}
+
+// FetchTrustRoots fetches the trust anchors from the
+// linkerd-identity-trust-roots ConfigMap, which is where the proxies and the
+// identity controller read them from at runtime
+func FetchTrustRoots(ctx context.Context, api kubernetes.Interface, controlPlaneNamespace string) (string, error) {
+	cm, err := api.CoreV1().ConfigMaps(controlPlaneNamespace).Get(ctx, k8s.IdentityTrustRootsConfigMapName, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	anchors := strings.TrimSpace(cm.Data[k8s.IdentityIssuerTrustAnchorsName])
+	if anchors == "" {
+		return "", fmt.Errorf("key %s containing the trust anchors needs to exist in configmap %s if --identity-external-ca=true", k8s.IdentityIssuerTrustAnchorsName, k8s.IdentityTrustRootsConfigMapName)
+	}
+
+	if _, err := tls.DecodePEMCertificates(anchors); err != nil {
+		return "", fmt.Errorf("could not parse the trust anchors of configmap %s: %w", k8s.IdentityTrustRootsConfigMapName, err)
+	}
+
+	return anchors, nil
 }
 
 // LoadIssuerCrtAndKeyFromFiles loads the issuer certificate and key from files
@@ -124,9 +175,10 @@ func CheckCertValidityPeriod(cert *x509.Certificate) error {
 	return nil
 }
 
-// CheckExpiringSoon returns an error if a certificate is expiring soon
-func CheckExpiringSoon(cert *x509.Certificate) error {
-	if time.Now().AddDate(0, 0, expirationWarningThresholdInDays).After(cert.NotAfter) {
+// CheckExpiringWithin returns an error if a certificate expires within the
+// given window
+func CheckExpiringWithin(cert *x509.Certificate, window time.Duration) error {
+	if time.Now().Add(window).After(cert.NotAfter) {
 		return fmt.Errorf("will expire on %s", cert.NotAfter.Format(time.RFC3339))
 	}
 	return nil
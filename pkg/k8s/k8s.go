@@ -33,6 +33,10 @@ const (
 	LinkAPIGroupVersion = "multicluster.linkerd.io/v1alpha1"
 	LinkKind            = "Link"
 
+	IssuancePolicyAPIGroup   = "identity.linkerd.io"
+	IssuancePolicyAPIVersion = "v1alpha1"
+	IssuancePolicyResource   = "issuancepolicies"
+
 	// special case k8s job label, to not conflict with Prometheus' job label
 	l5dJob = "k8s_job"
 )
@@ -151,6 +151,11 @@ const (
 	// DebugImageAnnotation can be used to override the debugImage config.
 	DebugImageAnnotation = ProxyConfigAnnotationsPrefix + "/debug-image"
 
+	// ImageRegistryAnnotation can be set on a namespace to pull the proxy,
+	// proxy-init and debug images of its pods from a different registry. The
+	// registry must be listed in the injector's proxyInjector.allowedRegistries.
+	ImageRegistryAnnotation = ProxyConfigAnnotationsPrefix + "/image-registry"
+
 	// DebugImageVersionAnnotation can be used to override the debugImageVersion config.
 	DebugImageVersionAnnotation = ProxyConfigAnnotationsPrefix + "/debug-image-version"
 
@@ -229,6 +234,19 @@ const (
 	// timeout in the proxy
 	ProxyInboundConnectTimeout = ProxyConfigAnnotationsPrefix + "/proxy-inbound-connect-timeout"
 
+	// ProxyInboundMaxInFlightAnnotation can be used to cap the number of
+	// requests the proxy processes concurrently on inbound connections.
+	ProxyInboundMaxInFlightAnnotation = ProxyConfigAnnotationsPrefix + "/proxy-inbound-max-in-flight"
+
+	// ProxyOutboundMaxInFlightAnnotation can be used to cap the number of
+	// requests the proxy processes concurrently on outbound connections.
+	ProxyOutboundMaxInFlightAnnotation = ProxyConfigAnnotationsPrefix + "/proxy-outbound-max-in-flight"
+
+	// ProxyBufferCapacityAnnotation can be used to configure the number of
+	// requests the proxy buffers for each of its services while they are not
+	// ready.
+	ProxyBufferCapacityAnnotation = ProxyConfigAnnotationsPrefix + "/proxy-buffer-capacity"
+
 	// ProxyEnableGatewayAnnotation can be used to configure the proxy
 	// to operate as a gateway, routing requests that target the inbound router.
 	ProxyEnableGatewayAnnotation = ProxyConfigAnnotationsPrefix + "/enable-gateway"
@@ -248,10 +266,22 @@ const (
 	// configured for the Pod
 	ProxyWaitBeforeExitSecondsAnnotation = ProxyConfigAnnotationsPrefixAlpha + "/proxy-wait-before-exit-seconds"
 
+	// ConsistentHashAnnotation can be set on a Service to request that the
+	// clients balance its endpoints with a consistent hash of the given
+	// header, `header:<name>`, or of their identity, `source-identity`, so
+	// that each key sticks to the same endpoint.
+	ConsistentHashAnnotation = ProxyConfigAnnotationsPrefixAlpha + "/consistent-hash"
+
 	// ProxyAwait can be used to force the application to wait for the proxy
 	// to be ready.
 	ProxyAwait = ProxyConfigAnnotationsPrefix + "/proxy-await"
 
+	// ProxyJobShutdownAnnotation can be used on Job and CronJob workloads to
+	// wrap the application containers' commands with linkerd-await so that
+	// the proxy is shut down once the application exits, allowing the Job to
+	// complete.
+	ProxyJobShutdownAnnotation = ProxyConfigAnnotationsPrefixAlpha + "/proxy-job-shutdown"
+
 	// IdentityModeDefault is assigned to IdentityModeAnnotation to
 	// use the control plane's default identity scheme.
 	IdentityModeDefault = "default"
@@ -308,6 +338,10 @@ const (
 	// IdentityIssuerTrustAnchorsName is the trust anchors name.
 	IdentityIssuerTrustAnchorsName = "ca-bundle.crt"
 
+	// IdentityTrustRootsConfigMapName is the name of the ConfigMap that stores
+	// the trust anchors bundle.
+	IdentityTrustRootsConfigMapName = "linkerd-identity-trust-roots"
+
 	// ProxyPortName is the name of the Linkerd Proxy's proxy port.
 	ProxyPortName = "linkerd-proxy"
 
@@ -335,6 +369,10 @@ const (
 	// AdmissionWebhookLabel indicates whether admission webhooks are enabled for a namespace
 	AdmissionWebhookLabel = ProxyConfigAnnotationsPrefix + "/admission-webhooks"
 
+	// KubeletMirrorPodAnnotation is set by the kubelet on the mirror pods it
+	// creates in the API server to reflect the static pods it runs.
+	KubeletMirrorPodAnnotation = "kubernetes.io/config.mirror"
+
 	/*
 	 * Mount paths
 	 */
@@ -377,6 +415,11 @@ const (
 	// MountPathTLSCrtPEM is the path at which the TLS cert PEM file is mounted.
 	MountPathTLSCrtPEM = MountPathTLSBase + "/tls.crt"
 
+	// MountPathAwait is the path at which the linkerd-await binary is made
+	// available to application containers of Jobs opted into proxy shutdown
+	// coordination.
+	MountPathAwait = MountPathBase + "/await"
+
 	// MountPathXtablesLock is the path at which the proxy init container mounts xtables
 	// This is necessary for xtables-legacy support
 	MountPathXtablesLock = "/run"
@@ -403,6 +446,17 @@ const (
 	// services.
 	DefaultExportedServiceSelector = SvcMirrorPrefix + "/exported"
 
+	// ExportAnnotation exports a service regardless of the selector of the
+	// Links, for the deployment tooling that can't easily label services. It
+	// holds either "true", exporting the service to all the Links, or a
+	// comma-separated list of the names of the Links it is exported to.
+	ExportAnnotation = SvcMirrorPrefix + "/export"
+
+	// ServiceExportAnnotation is put on a service whose exported label was
+	// set on behalf of an MCS API ServiceExport, so that the label is only
+	// removed with the ServiceExport if it was set for it.
+	ServiceExportAnnotation = SvcMirrorPrefix + "/service-export"
+
 	// MirroredResourceLabel indicates that this resource is the result
 	// of a mirroring operation (can be a namespace or a service)
 	MirroredResourceLabel = SvcMirrorPrefix + "/mirrored-service"
@@ -430,6 +484,45 @@ const (
 	// RemoteGatewayIdentity follows the same kind of logic as RemoteGatewayNameLabel
 	RemoteGatewayIdentity = SvcMirrorPrefix + "/remote-gateway-identity"
 
+	// RemoteGatewayWeights is set on mirrored Endpoints whose Link assigns
+	// weights to its gateway addresses. It holds a comma-separated list of
+	// ip=weight pairs.
+	RemoteGatewayWeights = SvcMirrorPrefix + "/remote-gateway-weights"
+
+	// RemoteGatewayIdentities is set on federated Endpoints, whose addresses
+	// belong to the gateways of several clusters. It holds a comma-separated
+	// list of ip=identity pairs, taking precedence over
+	// RemoteGatewayIdentity.
+	RemoteGatewayIdentities = SvcMirrorPrefix + "/remote-gateway-identities"
+
+	// FederatedServiceLabel is put on the services and endpoints aggregating
+	// the mirrors of the same service exported by several clusters.
+	FederatedServiceLabel = SvcMirrorPrefix + "/federated-service"
+
+	// FederatedClustersAnnotation is put on a federated service. It holds the
+	// comma-separated list of the clusters whose mirrors are aggregated.
+	FederatedClustersAnnotation = SvcMirrorPrefix + "/federated-clusters"
+
+	// FailoverPrioritiesAnnotation can be put on a service to serve the
+	// endpoints of its mirrors when it has none. It holds a comma-separated
+	// list of clusters in priority order, "local" designating the service
+	// itself, e.g. "local,west,east": the endpoints of the first service
+	// among itself, its west mirror and its east mirror which has any are
+	// served.
+	FailoverPrioritiesAnnotation = SvcMirrorPrefix + "/failover-priorities"
+
+	// ReservedClusterIPsAnnotation is put on a namespace holding mirrored
+	// services. It maps the names of the mirrored services that were deleted
+	// to their ClusterIPs, so they can be reused if the services are mirrored
+	// again.
+	ReservedClusterIPsAnnotation = SvcMirrorPrefix + "/reserved-cluster-ips"
+
+	// MirrorReportAnnotation can be put on a Link, with any value (e.g.
+	// "now"), to have its service mirror write a report of the changes it
+	// would make to the mirror services to a ConfigMap. The service mirror
+	// removes the annotation once the report is written.
+	MirrorReportAnnotation = SvcMirrorPrefix + "/report"
+
 	// GatewayIdentity can be found on the remote gateway service
 	GatewayIdentity = SvcMirrorPrefix + "/gateway-identity"
 
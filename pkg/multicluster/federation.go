@@ -0,0 +1,46 @@
+package multicluster
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FederatedServiceSuffix is appended to the name of a service exported by
+// several clusters to name the local service aggregating their mirrors.
+const FederatedServiceSuffix = "federated"
+
+// FederatedServiceName returns the name of the federated service aggregating
+// the mirrors of the remote service with the given name.
+func FederatedServiceName(name string) string {
+	return fmt.Sprintf("%s-%s", name, FederatedServiceSuffix)
+}
+
+// ParseGatewayIdentities parses a comma-separated list of ip=identity pairs,
+// as found in the remote-gateway-identities annotation of federated
+// Endpoints.
+func ParseGatewayIdentities(s string) (map[string]string, error) {
+	identities := map[string]string{}
+	if strings.TrimSpace(s) == "" {
+		return identities, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid gateway identity '%s', expected <address>=<identity>", pair)
+		}
+		identities[parts[0]] = parts[1]
+	}
+	return identities, nil
+}
+
+// FormatGatewayIdentities is the inverse of ParseGatewayIdentities. Pairs are
+// sorted by address so that the output is stable.
+func FormatGatewayIdentities(identities map[string]string) string {
+	pairs := make([]string, 0, len(identities))
+	for addr, identity := range identities {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", addr, identity))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,129 @@
+package multicluster
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// gatewayHostRegexp matches the hostnames and SRV names (whose labels start
+// with an underscore) allowed in a gateway address.
+var gatewayHostRegexp = regexp.MustCompile(`^([a-z0-9_]([-a-z0-9_]{0,61}[a-z0-9])?)(\.[a-z0-9_]([-a-z0-9_]{0,61}[a-z0-9])?)*$`)
+
+// GatewayAddressEntry is an entry of a Link's gateway address: an IP, a
+// hostname, or an SRV name, along with an optional port that overrides the
+// gateway port.
+type GatewayAddressEntry struct {
+	Host string
+	// Port is 0 unless the entry has a port suffix
+	Port uint32
+}
+
+// IsSRV returns whether the entry is an SRV name, whose records provide the
+// gateway port.
+func (e GatewayAddressEntry) IsSRV() bool {
+	return strings.HasPrefix(e.Host, "_")
+}
+
+// String returns the normalized form of the entry. IPv6 addresses are only
+// bracketed when followed by a port.
+func (e GatewayAddressEntry) String() string {
+	if e.Port == 0 {
+		return e.Host
+	}
+	return net.JoinHostPort(e.Host, strconv.FormatUint(uint64(e.Port), 10))
+}
+
+// ParseGatewayAddressEntry parses and normalizes an entry of a gateway
+// address, e.g. "10.0.0.1", "gateway.example.com:4143", "[fd00::1]:4143" or
+// "_gateway._tcp.example.com". Hostnames are lowercased and stripped of their
+// trailing dot, and IPs are written in their canonical form.
+func ParseGatewayAddressEntry(s string) (GatewayAddressEntry, error) {
+	entry := strings.TrimSpace(s)
+	if entry == "" {
+		return GatewayAddressEntry{}, fmt.Errorf("empty entry")
+	}
+
+	host, portStr := entry, ""
+	switch {
+	case strings.HasPrefix(entry, "["):
+		end := strings.Index(entry, "]")
+		if end < 0 {
+			return GatewayAddressEntry{}, fmt.Errorf("missing ']' in address")
+		}
+		host = entry[1:end]
+		rest := entry[end+1:]
+		if rest != "" {
+			if !strings.HasPrefix(rest, ":") {
+				return GatewayAddressEntry{}, fmt.Errorf("unexpected '%s' after address", rest)
+			}
+			portStr = rest[1:]
+		}
+		if ip := net.ParseIP(host); ip == nil || ip.To4() != nil {
+			return GatewayAddressEntry{}, fmt.Errorf("'%s' is not an IPv6 address", host)
+		}
+	case strings.Count(entry, ":") == 1:
+		i := strings.Index(entry, ":")
+		host, portStr = entry[:i], entry[i+1:]
+	}
+
+	var port uint32
+	if portStr != "" || strings.HasSuffix(entry, ":") {
+		p, err := strconv.ParseUint(portStr, 10, 16)
+		if err != nil || p == 0 {
+			return GatewayAddressEntry{}, fmt.Errorf("invalid port '%s'", portStr)
+		}
+		port = uint32(p)
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return GatewayAddressEntry{Host: ip.String(), Port: port}, nil
+	}
+
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if len(host) > 253 || !gatewayHostRegexp.MatchString(host) {
+		return GatewayAddressEntry{}, fmt.Errorf("'%s' is neither an IP nor a valid hostname", host)
+	}
+	normalized := GatewayAddressEntry{Host: host, Port: port}
+	if normalized.IsSRV() && port != 0 {
+		return GatewayAddressEntry{}, fmt.Errorf("SRV name '%s' can't have a port, as its records provide it", host)
+	}
+	return normalized, nil
+}
+
+// ParseGatewayAddress parses the comma-separated entries of a gateway
+// address. It returns the valid entries, along with an error listing the
+// invalid ones if any, so that the gateway can still be reached through the
+// valid entries.
+func ParseGatewayAddress(s string) ([]GatewayAddressEntry, error) {
+	var entries []GatewayAddressEntry
+	var invalid []string
+	for _, raw := range strings.Split(s, ",") {
+		entry, err := ParseGatewayAddressEntry(raw)
+		if err != nil {
+			invalid = append(invalid, fmt.Sprintf("'%s' (%s)", strings.TrimSpace(raw), err))
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if len(invalid) > 0 {
+		return entries, fmt.Errorf("invalid gateway address entries: %s", strings.Join(invalid, ", "))
+	}
+	return entries, nil
+}
+
+// NormalizeGatewayAddress returns the normalized form of a gateway address,
+// failing if any of its entries is invalid.
+func NormalizeGatewayAddress(s string) (string, error) {
+	entries, err := ParseGatewayAddress(s)
+	if err != nil {
+		return "", err
+	}
+	normalized := make([]string, len(entries))
+	for i, entry := range entries {
+		normalized[i] = entry.String()
+	}
+	return strings.Join(normalized, ","), nil
+}
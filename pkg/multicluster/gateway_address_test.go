@@ -0,0 +1,71 @@
+package multicluster
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseGatewayAddressEntry(t *testing.T) {
+	for _, tc := range []struct {
+		entry    string
+		expected GatewayAddressEntry
+		err      string
+	}{
+		{entry: "10.0.0.1", expected: GatewayAddressEntry{Host: "10.0.0.1"}},
+		{entry: " 10.0.0.1:4143 ", expected: GatewayAddressEntry{Host: "10.0.0.1", Port: 4143}},
+		{entry: "2001:DB8:0::1", expected: GatewayAddressEntry{Host: "2001:db8::1"}},
+		{entry: "[2001:db8::1]", expected: GatewayAddressEntry{Host: "2001:db8::1"}},
+		{entry: "[2001:db8::1]:4143", expected: GatewayAddressEntry{Host: "2001:db8::1", Port: 4143}},
+		{entry: "Gateway.Example.com.", expected: GatewayAddressEntry{Host: "gateway.example.com"}},
+		{entry: "gateway.example.com:4143", expected: GatewayAddressEntry{Host: "gateway.example.com", Port: 4143}},
+		{entry: "_gateway._tcp.example.com", expected: GatewayAddressEntry{Host: "_gateway._tcp.example.com"}},
+		{entry: "", err: "empty entry"},
+		{entry: "gateway.example.com:", err: "invalid port"},
+		{entry: "gateway.example.com:0", err: "invalid port"},
+		{entry: "gateway.example.com:65536", err: "invalid port"},
+		{entry: "[2001:db8::1", err: "missing ']'"},
+		{entry: "[2001:db8::1]4143", err: "unexpected '4143'"},
+		{entry: "[10.0.0.1]:4143", err: "not an IPv6 address"},
+		{entry: "gateway example.com", err: "neither an IP nor a valid hostname"},
+		{entry: "-gateway.example.com", err: "neither an IP nor a valid hostname"},
+		{entry: "_gateway._tcp.example.com:4143", err: "can't have a port"},
+	} {
+		tc := tc // pin
+		t.Run(tc.entry, func(t *testing.T) {
+			entry, err := ParseGatewayAddressEntry(tc.entry)
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("Expected error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if entry != tc.expected {
+				t.Errorf("Expected %+v, got %+v", tc.expected, entry)
+			}
+		})
+	}
+}
+
+func TestParseGatewayAddress(t *testing.T) {
+	entries, err := ParseGatewayAddress("10.0.0.1, bad host,[2001:db8::1]:4143,")
+	if err == nil || !strings.Contains(err.Error(), "'bad host'") || !strings.Contains(err.Error(), "'' (empty entry)") {
+		t.Errorf("Expected the invalid entries to be listed, got %v", err)
+	}
+	if len(entries) != 2 || entries[0].String() != "10.0.0.1" || entries[1].String() != "[2001:db8::1]:4143" {
+		t.Errorf("Expected the valid entries to be returned, got %v", entries)
+	}
+
+	normalized, err := NormalizeGatewayAddress("Gateway.Example.com:4143, 2001:DB8::1")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if normalized != "gateway.example.com:4143,2001:db8::1" {
+		t.Errorf("Unexpected normalized gateway address %s", normalized)
+	}
+	if _, err := NormalizeGatewayAddress("10.0.0.1,bad host"); err == nil {
+		t.Error("Expected an invalid gateway address to be rejected")
+	}
+}
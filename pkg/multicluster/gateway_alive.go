@@ -0,0 +1,155 @@
+package multicluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+)
+
+const (
+	// GatewayAliveRefreshPeriod is the maximum time between two updates of
+	// the GatewayAlive condition of a Link while the liveness of its gateway
+	// doesn't change, so that its lastProbeTime tells whether the gateway is
+	// still being probed.
+	GatewayAliveRefreshPeriod = time.Minute
+
+	// gatewayAliveMissedRefreshes is the number of refreshes of the
+	// GatewayAlive condition that can be missed before it's stale
+	gatewayAliveMissedRefreshes = 3
+
+	// gatewayProbeTimeout bounds the probes of the consumers of a stale
+	// GatewayAlive condition, unless the Link's probe spec has a timeout
+	gatewayProbeTimeout = 5 * time.Second
+
+	lastProbeTimeField = "lastProbeTime"
+)
+
+// GatewayAlive is the GatewayAlive condition of a Link, along with the time of
+// the latest probe of the gateway it reflects.
+type GatewayAlive struct {
+	metav1.Condition
+	LastProbeTime metav1.Time
+}
+
+// Stale returns true if the condition wasn't refreshed within maxAge of now,
+// e.g. because the service mirror probing the gateway is down. The conditions
+// without a probe time, reported by older service mirrors, are always stale.
+func (g *GatewayAlive) Stale(now time.Time, maxAge time.Duration) bool {
+	return g.LastProbeTime.IsZero() || now.Sub(g.LastProbeTime.Time) > maxAge
+}
+
+// GatewayAliveMaxAge returns the age after which the GatewayAlive condition of
+// the Link is stale. The condition is refreshed after each probe once
+// GatewayAliveRefreshPeriod has passed, so at least once per probe period when
+// it's longer.
+func (l Link) GatewayAliveMaxAge() time.Duration {
+	period := l.ProbeSpec.Period
+	if period < GatewayAliveRefreshPeriod {
+		period = GatewayAliveRefreshPeriod
+	}
+	return gatewayAliveMissedRefreshes * period
+}
+
+// LinkGatewayAlive returns the GatewayAlive condition from the status of the
+// given Link object, or nil if it doesn't have one.
+func LinkGatewayAlive(link *unstructured.Unstructured) (*GatewayAlive, error) {
+	conditions, _, err := unstructured.NestedSlice(link.Object, "status", "conditions")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range conditions {
+		cObj, ok := c.(map[string]interface{})
+		if !ok || cObj["type"] != LinkConditionGatewayAlive {
+			continue
+		}
+		var alive GatewayAlive
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(cObj, &alive.Condition); err != nil {
+			return nil, err
+		}
+		if ts, ok := cObj[lastProbeTimeField].(string); ok {
+			if err := alive.LastProbeTime.UnmarshalQueryParameter(ts); err != nil {
+				return nil, err
+			}
+		}
+		return &alive, nil
+	}
+	return nil, nil
+}
+
+// GetLinkGatewayAlive returns the GatewayAlive condition from the status of
+// the Link with the given name/namespace, or nil if the Link doesn't have one.
+func GetLinkGatewayAlive(ctx context.Context, client dynamic.Interface, namespace, name string) (*GatewayAlive, error) {
+	u, err := client.Resource(LinkGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return LinkGatewayAlive(u)
+}
+
+// SetLinkGatewayAlive sets the GatewayAlive condition in the status of the
+// Link with the given name/namespace, along with its probe time.
+func SetLinkGatewayAlive(ctx context.Context, client dynamic.Interface, namespace, name string, alive GatewayAlive) error {
+	lastProbeTime, err := alive.LastProbeTime.MarshalQueryParameter()
+	if err != nil {
+		return err
+	}
+	alive.Type = LinkConditionGatewayAlive
+	return setLinkCondition(ctx, client, namespace, name, alive.Condition, map[string]interface{}{
+		lastProbeTimeField: lastProbeTime,
+	})
+}
+
+// ProbeGateway probes the gateway of the Link at each entry of its gateway
+// address, other than SRV names, and returns nil as soon as one of them
+// responds as expected by its probe spec. It's used by the consumers of the
+// GatewayAlive condition when it's stale. The identity of the gateway isn't
+// verified, as that requires the probe to go through the service mirror's
+// proxy.
+func (l Link) ProbeGateway(ctx context.Context) error {
+	// the valid entries are probed even if others are invalid
+	entries, _ := ParseGatewayAddress(l.GatewayAddress)
+
+	timeout := gatewayProbeTimeout
+	if l.ProbeSpec.Timeout > 0 {
+		timeout = l.ProbeSpec.Timeout
+	}
+	client := http.Client{Timeout: timeout}
+
+	errs := []string{}
+	for _, entry := range entries {
+		if entry.IsSRV() {
+			continue
+		}
+		host := net.JoinHostPort(entry.Host, strconv.FormatUint(uint64(l.ProbeSpec.Port), 10))
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.ProbeSpec.URL(host), nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		resp.Body.Close()
+		if !l.ProbeSpec.ExpectsStatus(resp.StatusCode) {
+			errs = append(errs, fmt.Sprintf("%s: unexpected status %d", host, resp.StatusCode))
+			continue
+		}
+		return nil
+	}
+	if len(errs) == 0 {
+		return errors.New("the gateway address has no entry that can be probed")
+	}
+	return errors.New(strings.Join(errs, "; "))
+}
@@ -0,0 +1,165 @@
+package multicluster
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func newFakeLinkClient(t *testing.T, link Link) *dynamicfake.FakeDynamicClient {
+	t.Helper()
+	u, err := link.ToUnstructured()
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	return dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{LinkGVR: "LinkList"},
+		&u,
+	)
+}
+
+func TestGatewayAliveStale(t *testing.T) {
+	now := time.Now()
+	for _, tc := range []struct {
+		name          string
+		period        time.Duration
+		lastProbeTime time.Time
+		expected      bool
+	}{
+		{name: "fresh", period: 10 * time.Second, lastProbeTime: now.Add(-time.Minute), expected: false},
+		{name: "missed refreshes", period: 10 * time.Second, lastProbeTime: now.Add(-4 * time.Minute), expected: true},
+		{name: "long probe period", period: 5 * time.Minute, lastProbeTime: now.Add(-10 * time.Minute), expected: false},
+		{name: "no probe time", period: 10 * time.Second, expected: true},
+	} {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			link := Link{ProbeSpec: ProbeSpec{Period: tc.period}}
+			alive := GatewayAlive{}
+			if !tc.lastProbeTime.IsZero() {
+				alive.LastProbeTime = metav1.NewTime(tc.lastProbeTime)
+			}
+			if stale := alive.Stale(now, link.GatewayAliveMaxAge()); stale != tc.expected {
+				t.Fatalf("Expected stale to be %t, got %t", tc.expected, stale)
+			}
+		})
+	}
+}
+
+func TestLinkGatewayAliveRoundTrip(t *testing.T) {
+	link := Link{Name: "remote", Namespace: "linkerd-multicluster", TargetClusterName: "remote"}
+	client := newFakeLinkClient(t, link)
+
+	lastProbeTime := time.Now().Truncate(time.Second)
+	err := SetLinkGatewayAlive(context.Background(), client, link.Namespace, link.Name, GatewayAlive{
+		Condition: metav1.Condition{
+			Status:  metav1.ConditionFalse,
+			Reason:  "ProbeFailed",
+			Message: "Gateway probe failed: unexpected status 503",
+		},
+		LastProbeTime: metav1.NewTime(lastProbeTime),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	alive, err := GetLinkGatewayAlive(context.Background(), client, link.Namespace, link.Name)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if alive == nil {
+		t.Fatal("Expected a GatewayAlive condition")
+	}
+	if alive.Type != LinkConditionGatewayAlive || alive.Status != metav1.ConditionFalse || alive.Reason != "ProbeFailed" {
+		t.Fatalf("Unexpected condition: %+v", alive.Condition)
+	}
+	if !alive.LastProbeTime.Time.Equal(lastProbeTime) {
+		t.Fatalf("Expected probe time %s, got %s", lastProbeTime, alive.LastProbeTime.Time)
+	}
+
+	// the conditions set by the service mirrors without probe times have none
+	err = SetLinkCondition(context.Background(), client, link.Namespace, link.Name, metav1.Condition{
+		Type:   LinkConditionGatewayAlive,
+		Status: metav1.ConditionTrue,
+		Reason: "ProbeSucceeded",
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	alive, err = GetLinkGatewayAlive(context.Background(), client, link.Namespace, link.Name)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !alive.LastProbeTime.IsZero() {
+		t.Fatalf("Expected no probe time, got %s", alive.LastProbeTime.Time)
+	}
+}
+
+func TestProbeGateway(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		status   int
+		address  func(host string) string
+		expected bool
+	}{
+		{
+			name:     "healthy gateway",
+			status:   http.StatusOK,
+			address:  func(host string) string { return host },
+			expected: true,
+		},
+		{
+			name:    "unhealthy gateway",
+			status:  http.StatusServiceUnavailable,
+			address: func(host string) string { return host },
+		},
+		{
+			name:     "SRV entries are skipped",
+			status:   http.StatusOK,
+			address:  func(host string) string { return "_gateway._tcp.example.com," + host },
+			expected: true,
+		},
+		{
+			name:    "no entry can be probed",
+			status:  http.StatusOK,
+			address: func(string) string { return "_gateway._tcp.example.com" },
+		},
+	} {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+			}))
+			defer gateway.Close()
+			host, port, err := net.SplitHostPort(gateway.Listener.Addr().String())
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			portNum, err := strconv.ParseUint(port, 10, 32)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+
+			link := Link{
+				GatewayAddress: tc.address(host),
+				ProbeSpec:      ProbeSpec{Path: "/ready", Port: uint32(portNum), Timeout: time.Second},
+			}
+			err = link.ProbeGateway(context.Background())
+			if tc.expected && err != nil {
+				t.Fatalf("Expected the gateway to be alive, got %s", err)
+			}
+			if !tc.expected && err == nil {
+				t.Fatal("Expected the gateway probe to fail")
+			}
+		})
+	}
+}
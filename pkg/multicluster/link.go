@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"path"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -14,18 +16,37 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/dynamic"
 )
 
 type (
 	// ProbeSpec defines how a gateway should be queried for health. Once per
 	// period, the probe workers will send an HTTP request to the remote gateway
-	// on the given  port with the given path and expect a HTTP 200 response.
+	// on the given  port with the given path and expect a HTTP 200 response,
+	// or one of the ExpectedStatuses. The zero values of the other fields
+	// stand for the defaults of the probe workers.
 	ProbeSpec struct {
 		Path   string
 		Port   uint32
 		Period time.Duration
+		// Timeout bounds each probe
+		Timeout time.Duration
+		// FailureThreshold is the number of consecutive failed probes after
+		// which the gateway is considered unhealthy, and SuccessThreshold the
+		// number of consecutive successful probes after which it's
+		// considered healthy again
+		FailureThreshold uint32
+		SuccessThreshold uint32
+		// Scheme is http, when empty, or https
+		Scheme           string
+		ExpectedStatuses []StatusRange
+		// VerifyIdentity makes the probes go over mTLS, with the identity of
+		// the service mirror's proxy, and fail unless the gateway has the
+		// Link's GatewayIdentity
+		VerifyIdentity bool
 	}
 
 	// Link is an internal representation of the link.multicluster.linkerd.io
@@ -44,9 +65,68 @@ type (
 		GatewayIdentity               string
 		ProbeSpec                     ProbeSpec
 		Selector                      metav1.LabelSelector
+
+		// GatewayAddressWeights optionally assigns a relative weight to
+		// entries of GatewayAddress. Entries without a weight default to 1.
+		GatewayAddressWeights map[string]uint32
+
+		// PropagatedLabels and PropagatedAnnotations are glob patterns, as
+		// understood by path.Match, of the keys of the remote services'
+		// labels and annotations copied onto their mirrors.
+		PropagatedLabels      []string
+		PropagatedAnnotations []string
+
+		// NamespaceMappings maps the namespaces of the target cluster to the
+		// local namespaces their services are mirrored into. The namespaces
+		// that aren't mapped are mirrored into the namespace of the same
+		// name, prefixed with NamespacePrefix.
+		NamespaceMappings map[string]string
+		NamespacePrefix   string
 	}
 )
 
+// LinkConditionMirrorConflict is the type of the Link status condition that
+// is set when services exported by the Link's target cluster cannot be
+// mirrored because their mirror names are already taken by resources that
+// belong to a different Link or were not created by a service mirror.
+const LinkConditionMirrorConflict = "MirrorConflict"
+
+// LinkConditionGatewayAlive is the type of the Link status condition that
+// reports the result of the service mirror's probes of the Link's gateway.
+const LinkConditionGatewayAlive = "GatewayAlive"
+
+// LinkConditionMirrorCleanup is the type of the Link status condition that
+// reports the progress of the removal of the resources mirrored for a Link
+// that is being deleted.
+const LinkConditionMirrorCleanup = "MirrorCleanup"
+
+// LinkConditionUnreachable is the type of the Link status condition that is
+// set while the API server of the Link's target cluster can't be reached and
+// the service mirror has paused the processing of its events.
+const LinkConditionUnreachable = "Unreachable"
+
+// LinkConditionEndpointSlices is the type of the Link status condition that
+// reports whether the endpoints of the mirror services are exposed through
+// EndpointSlices in the local cluster, as negotiated from the capabilities of
+// both clusters when the service mirror starts watching the Link.
+const LinkConditionEndpointSlices = "EndpointSlices"
+
+// LinkConditionGatewayAddress is the type of the Link status condition that
+// reports whether all the entries of the Link's gateway address are valid and
+// resolve, listing the ones that don't.
+const LinkConditionGatewayAddress = "GatewayAddressResolved"
+
+// LinkConditionAuthenticated is the type of the Link status condition that
+// reports whether the service mirror can authenticate to the API server of
+// the Link's target cluster with the kubeconfig of its credentials secret,
+// including through an exec credential plugin or an auth provider.
+const LinkConditionAuthenticated = "Authenticated"
+
+// LinkFinalizer is set on the Links watched by a service mirror, so that a
+// deleted Link is only removed once the services, endpoints and gateway
+// mirror created on its behalf have been removed.
+const LinkFinalizer = "multicluster.linkerd.io/mirror-cleanup"
+
 // LinkGVR is the Group Version and Resource of the Link custom resource.
 var LinkGVR = schema.GroupVersionResource{
 	Group:    k8s.LinkAPIGroup,
@@ -55,7 +135,8 @@ var LinkGVR = schema.GroupVersionResource{
 }
 
 func (ps ProbeSpec) String() string {
-	return fmt.Sprintf("ProbeSpec: {path: %s, port: %d, period: %s}", ps.Path, ps.Port, ps.Period)
+	return fmt.Sprintf("ProbeSpec: {path: %s, port: %d, period: %s, timeout: %s, failureThreshold: %d, successThreshold: %d, scheme: %s, expectedStatuses: %s, verifyIdentity: %t}",
+		ps.Path, ps.Port, ps.Period, ps.Timeout, ps.FailureThreshold, ps.SuccessThreshold, ps.Scheme, FormatStatusRanges(ps.ExpectedStatuses), ps.VerifyIdentity)
 }
 
 // NewLink parses an unstructured link.multicluster.linkerd.io resource and
@@ -124,6 +205,51 @@ func NewLink(u unstructured.Unstructured) (Link, error) {
 		return Link{}, err
 	}
 
+	var gatewayAddressWeights map[string]uint32
+	if _, ok := specObj["gatewayAddressWeights"]; ok {
+		weightsStr, err := stringField(specObj, "gatewayAddressWeights")
+		if err != nil {
+			return Link{}, err
+		}
+		gatewayAddressWeights, err = ParseGatewayAddressWeights(weightsStr)
+		if err != nil {
+			return Link{}, err
+		}
+	}
+
+	propagatedLabels, err := patternsField(specObj, "propagatedLabels")
+	if err != nil {
+		return Link{}, err
+	}
+
+	propagatedAnnotations, err := patternsField(specObj, "propagatedAnnotations")
+	if err != nil {
+		return Link{}, err
+	}
+
+	var namespaceMappings map[string]string
+	if _, ok := specObj["namespaceMappings"]; ok {
+		mappingsStr, err := stringField(specObj, "namespaceMappings")
+		if err != nil {
+			return Link{}, err
+		}
+		namespaceMappings, err = ParseNamespaceMappings(mappingsStr)
+		if err != nil {
+			return Link{}, err
+		}
+	}
+
+	var namespacePrefix string
+	if _, ok := specObj["namespacePrefix"]; ok {
+		namespacePrefix, err = stringField(specObj, "namespacePrefix")
+		if err != nil {
+			return Link{}, err
+		}
+	}
+	if err := ValidateNamespaceMappings(namespaceMappings, namespacePrefix); err != nil {
+		return Link{}, err
+	}
+
 	selector := metav1.LabelSelector{}
 	if selectorObj, ok := specObj["selector"]; ok {
 		bytes, err := json.Marshal(selectorObj)
@@ -148,6 +274,11 @@ func NewLink(u unstructured.Unstructured) (Link, error) {
 		GatewayIdentity:               gatewayIdentity,
 		ProbeSpec:                     probeSpec,
 		Selector:                      selector,
+		GatewayAddressWeights:         gatewayAddressWeights,
+		PropagatedLabels:              propagatedLabels,
+		PropagatedAnnotations:         propagatedAnnotations,
+		NamespaceMappings:             namespaceMappings,
+		NamespacePrefix:               namespacePrefix,
 	}, nil
 }
 
@@ -162,11 +293,7 @@ func (l Link) ToUnstructured() (unstructured.Unstructured, error) {
 		"gatewayAddress":                l.GatewayAddress,
 		"gatewayPort":                   fmt.Sprintf("%d", l.GatewayPort),
 		"gatewayIdentity":               l.GatewayIdentity,
-		"probeSpec": map[string]interface{}{
-			"path":   l.ProbeSpec.Path,
-			"port":   fmt.Sprintf("%d", l.ProbeSpec.Port),
-			"period": l.ProbeSpec.Period.String(),
-		},
+		"probeSpec":                     probeSpecToUnstructured(l.ProbeSpec),
 	}
 
 	data, err := json.Marshal(l.Selector)
@@ -180,6 +307,22 @@ func (l Link) ToUnstructured() (unstructured.Unstructured, error) {
 	}
 	spec["selector"] = selector
 
+	if len(l.GatewayAddressWeights) > 0 {
+		spec["gatewayAddressWeights"] = FormatGatewayAddressWeights(l.GatewayAddressWeights)
+	}
+	if len(l.PropagatedLabels) > 0 {
+		spec["propagatedLabels"] = toInterfaceSlice(l.PropagatedLabels)
+	}
+	if len(l.PropagatedAnnotations) > 0 {
+		spec["propagatedAnnotations"] = toInterfaceSlice(l.PropagatedAnnotations)
+	}
+	if len(l.NamespaceMappings) > 0 {
+		spec["namespaceMappings"] = FormatNamespaceMappings(l.NamespaceMappings)
+	}
+	if l.NamespacePrefix != "" {
+		spec["namespacePrefix"] = l.NamespacePrefix
+	}
+
 	return unstructured.Unstructured{
 		Object: map[string]interface{}{
 			"apiVersion": k8s.LinkAPIGroupVersion,
@@ -193,6 +336,159 @@ func (l Link) ToUnstructured() (unstructured.Unstructured, error) {
 	}, nil
 }
 
+// GatewayAddressWeight returns the weight assigned to the given gateway
+// address entry, defaulting to 1.
+func (l Link) GatewayAddressWeight(addr string) uint32 {
+	if w, ok := l.GatewayAddressWeights[addr]; ok {
+		return w
+	}
+	return 1
+}
+
+// PropagatesLabel returns true if the remote services' label with the given
+// key is copied onto their mirrors.
+func (l Link) PropagatesLabel(key string) bool {
+	return matchesAny(l.PropagatedLabels, key)
+}
+
+// PropagatesAnnotation returns true if the remote services' annotation with
+// the given key is copied onto their mirrors.
+func (l Link) PropagatesAnnotation(key string) bool {
+	return matchesAny(l.PropagatedAnnotations, key)
+}
+
+// LocalNamespace returns the local namespace the services of the given
+// namespace of the target cluster are mirrored into.
+func (l Link) LocalNamespace(remote string) string {
+	if local, ok := l.NamespaceMappings[remote]; ok {
+		return local
+	}
+	return l.NamespacePrefix + remote
+}
+
+// RemoteNamespace is the inverse of LocalNamespace. It returns false if no
+// namespace of the target cluster is mirrored into the given local namespace.
+func (l Link) RemoteNamespace(local string) (string, bool) {
+	for remote, mapped := range l.NamespaceMappings {
+		if mapped == local {
+			return remote, true
+		}
+	}
+	if !strings.HasPrefix(local, l.NamespacePrefix) {
+		return "", false
+	}
+	remote := strings.TrimPrefix(local, l.NamespacePrefix)
+	if _, ok := l.NamespaceMappings[remote]; ok {
+		// that namespace is mirrored elsewhere
+		return "", false
+	}
+	return remote, true
+}
+
+// ParseNamespaceMappings parses a comma-separated list of remote=local
+// namespace pairs, e.g. "payments=east-payments,web=frontend".
+func ParseNamespaceMappings(s string) (map[string]string, error) {
+	mappings := map[string]string{}
+	if strings.TrimSpace(s) == "" {
+		return mappings, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid namespace mapping '%s', expected <remote namespace>=<local namespace>", pair)
+		}
+		if _, ok := mappings[parts[0]]; ok {
+			return nil, fmt.Errorf("namespace '%s' is mapped more than once", parts[0])
+		}
+		mappings[parts[0]] = parts[1]
+	}
+	return mappings, nil
+}
+
+// FormatNamespaceMappings is the inverse of ParseNamespaceMappings. Pairs are
+// sorted by remote namespace so that the output is stable.
+func FormatNamespaceMappings(mappings map[string]string) string {
+	pairs := make([]string, 0, len(mappings))
+	for remote, local := range mappings {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", remote, local))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// ValidateNamespaceMappings returns an error if two namespaces of the target
+// cluster would be mirrored into the same local namespace.
+func ValidateNamespaceMappings(mappings map[string]string, prefix string) error {
+	mappedFrom := map[string]string{}
+	for remote, local := range mappings {
+		if other, ok := mappedFrom[local]; ok {
+			return fmt.Errorf("namespaces '%s' and '%s' are both mapped to '%s'", other, remote, local)
+		}
+		mappedFrom[local] = remote
+		if prefix != "" && strings.HasPrefix(local, prefix) {
+			return fmt.Errorf("namespace '%s' is mapped to '%s', which has the namespace prefix '%s'", remote, local, prefix)
+		}
+	}
+	return nil
+}
+
+// ValidatePropagationPatterns returns an error if any of the given label or
+// annotation key patterns is malformed.
+func ValidatePropagationPatterns(patterns []string) error {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid propagation pattern '%s': %s", pattern, err)
+		}
+	}
+	return nil
+}
+
+func matchesAny(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseGatewayAddressWeights parses a comma-separated list of address=weight
+// pairs, e.g. "10.0.0.1=3,gateway.example.com=1". Weights must be positive.
+// The addresses are normalized like the entries of the gateway address.
+func ParseGatewayAddressWeights(s string) (map[string]uint32, error) {
+	weights := map[string]uint32{}
+	if strings.TrimSpace(s) == "" {
+		return weights, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid gateway address weight '%s', expected <address>=<weight>", pair)
+		}
+		weight, err := strconv.ParseUint(parts[1], 10, 32)
+		if err != nil || weight == 0 {
+			return nil, fmt.Errorf("invalid weight for gateway address '%s': must be a positive integer", parts[0])
+		}
+		addr := parts[0]
+		if entry, err := ParseGatewayAddressEntry(addr); err == nil {
+			addr = entry.String()
+		}
+		weights[addr] = uint32(weight)
+	}
+	return weights, nil
+}
+
+// FormatGatewayAddressWeights is the inverse of ParseGatewayAddressWeights.
+// Pairs are sorted by address so that the output is stable.
+func FormatGatewayAddressWeights(weights map[string]uint32) string {
+	pairs := make([]string, 0, len(weights))
+	for addr, weight := range weights {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", addr, weight))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
 // ExtractProbeSpec parses the ProbSpec from a gateway service's annotations.
 func ExtractProbeSpec(gateway *corev1.Service) (ProbeSpec, error) {
 	path := gateway.Annotations[consts.GatewayProbePath]
@@ -248,6 +544,157 @@ func GetLink(ctx context.Context, client dynamic.Interface, namespace, name stri
 	return NewLink(*unstructured)
 }
 
+// SetLinkCondition sets the given condition in the status of the Link with the
+// given name/namespace, replacing any existing condition of the same type. The
+// condition's LastTransitionTime is preserved when its status is unchanged.
+func SetLinkCondition(ctx context.Context, client dynamic.Interface, namespace, name string, condition metav1.Condition) error {
+	return setLinkCondition(ctx, client, namespace, name, condition, nil)
+}
+
+// setLinkCondition sets the given condition, along with the given extra
+// fields, in the status of the Link with the given name/namespace.
+func setLinkCondition(ctx context.Context, client dynamic.Interface, namespace, name string, condition metav1.Condition, extra map[string]interface{}) error {
+	links := client.Resource(LinkGVR).Namespace(namespace)
+	u, err := links.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	existing, _, err := unstructured.NestedSlice(u.Object, "status", "conditions")
+	if err != nil {
+		return err
+	}
+
+	conditions := []interface{}{}
+	for _, c := range existing {
+		cObj, ok := c.(map[string]interface{})
+		if !ok || cObj["type"] != condition.Type {
+			conditions = append(conditions, c)
+			continue
+		}
+		if cObj["status"] == string(condition.Status) {
+			if ts, ok := cObj["lastTransitionTime"].(string); ok {
+				if err := condition.LastTransitionTime.UnmarshalQueryParameter(ts); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if condition.LastTransitionTime.IsZero() {
+		condition.LastTransitionTime = metav1.Now()
+	}
+
+	cObj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&condition)
+	if err != nil {
+		return err
+	}
+	for k, v := range extra {
+		cObj[k] = v
+	}
+	conditions = append(conditions, cObj)
+
+	if err := unstructured.SetNestedSlice(u.Object, conditions, "status", "conditions"); err != nil {
+		return err
+	}
+	_, err = links.UpdateStatus(ctx, u, metav1.UpdateOptions{})
+	return err
+}
+
+// HasLinkFinalizer returns true if the given Link has LinkFinalizer set.
+func HasLinkFinalizer(link metav1.Object) bool {
+	for _, f := range link.GetFinalizers() {
+		if f == LinkFinalizer {
+			return true
+		}
+	}
+	return false
+}
+
+// AddLinkFinalizer sets LinkFinalizer on the Link with the given
+// name/namespace, if it isn't set already.
+func AddLinkFinalizer(ctx context.Context, client dynamic.Interface, namespace, name string) error {
+	return updateLinkFinalizers(ctx, client, namespace, name, func(finalizers []string) []string {
+		for _, f := range finalizers {
+			if f == LinkFinalizer {
+				return nil
+			}
+		}
+		return append(finalizers, LinkFinalizer)
+	})
+}
+
+// RemoveLinkFinalizer removes LinkFinalizer from the Link with the given
+// name/namespace, if it's set, allowing the Link to be deleted.
+func RemoveLinkFinalizer(ctx context.Context, client dynamic.Interface, namespace, name string) error {
+	return updateLinkFinalizers(ctx, client, namespace, name, func(finalizers []string) []string {
+		remaining := []string{}
+		for _, f := range finalizers {
+			if f != LinkFinalizer {
+				remaining = append(remaining, f)
+			}
+		}
+		if len(remaining) == len(finalizers) {
+			return nil
+		}
+		return remaining
+	})
+}
+
+// updateLinkFinalizers replaces the finalizers of the Link with the ones
+// returned by update, unless it returns nil. The resource version of the Link
+// is part of the patch so that concurrent changes of the finalizers aren't
+// overwritten.
+func updateLinkFinalizers(ctx context.Context, client dynamic.Interface, namespace, name string, update func([]string) []string) error {
+	links := client.Resource(LinkGVR).Namespace(namespace)
+	u, err := links.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	finalizers := update(u.GetFinalizers())
+	if finalizers == nil {
+		return nil
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"finalizers":      finalizers,
+			"resourceVersion": u.GetResourceVersion(),
+		},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = links.Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// GetLinkCondition returns the condition of the given type from the status of
+// the Link with the given name/namespace, or nil if the Link doesn't have it.
+func GetLinkCondition(ctx context.Context, client dynamic.Interface, namespace, name, conditionType string) (*metav1.Condition, error) {
+	u, err := client.Resource(LinkGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	conditions, _, err := unstructured.NestedSlice(u.Object, "status", "conditions")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range conditions {
+		cObj, ok := c.(map[string]interface{})
+		if !ok || cObj["type"] != conditionType {
+			continue
+		}
+		var condition metav1.Condition
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(cObj, &condition); err != nil {
+			return nil, err
+		}
+		return &condition, nil
+	}
+	return nil, nil
+}
+
 func extractPort(spec corev1.ServiceSpec, portName string) (uint32, error) {
 	for _, p := range spec.Ports {
 		if p.Name == portName {
@@ -284,11 +731,79 @@ func newProbeSpec(obj map[string]interface{}) (ProbeSpec, error) {
 		return ProbeSpec{}, err
 	}
 
-	return ProbeSpec{
+	spec := ProbeSpec{
 		Path:   path,
 		Port:   uint32(port),
 		Period: period,
-	}, nil
+	}
+
+	// the fields added to the probe spec are optional, so that the Links
+	// created before them keep their behavior
+	if timeout, ok := obj["timeout"].(string); ok && timeout != "" {
+		spec.Timeout, err = time.ParseDuration(timeout)
+		if err != nil {
+			return ProbeSpec{}, fmt.Errorf("invalid probe timeout: %s", err)
+		}
+	}
+	for key, threshold := range map[string]*uint32{
+		"failureThreshold": &spec.FailureThreshold,
+		"successThreshold": &spec.SuccessThreshold,
+	} {
+		if str, ok := obj[key].(string); ok && str != "" {
+			value, err := strconv.ParseUint(str, 10, 32)
+			if err != nil {
+				return ProbeSpec{}, fmt.Errorf("invalid probe %s: %s", key, err)
+			}
+			*threshold = uint32(value)
+		}
+	}
+	spec.Scheme, _ = obj["scheme"].(string)
+	if err := ValidateProbeScheme(spec.Scheme); err != nil {
+		return ProbeSpec{}, err
+	}
+	if statuses, ok := obj["expectedStatuses"].(string); ok {
+		spec.ExpectedStatuses, err = ParseStatusRanges(statuses)
+		if err != nil {
+			return ProbeSpec{}, fmt.Errorf("invalid probe expectedStatuses: %s", err)
+		}
+	}
+	if verify, ok := obj["verifyIdentity"].(string); ok && verify != "" {
+		spec.VerifyIdentity, err = strconv.ParseBool(verify)
+		if err != nil {
+			return ProbeSpec{}, fmt.Errorf("invalid probe verifyIdentity: %s", err)
+		}
+	}
+	if err := ValidateProbeIdentity(spec); err != nil {
+		return ProbeSpec{}, err
+	}
+	return spec, nil
+}
+
+func probeSpecToUnstructured(ps ProbeSpec) map[string]interface{} {
+	obj := map[string]interface{}{
+		"path":   ps.Path,
+		"port":   fmt.Sprintf("%d", ps.Port),
+		"period": ps.Period.String(),
+	}
+	if ps.Timeout != 0 {
+		obj["timeout"] = ps.Timeout.String()
+	}
+	if ps.FailureThreshold != 0 {
+		obj["failureThreshold"] = fmt.Sprintf("%d", ps.FailureThreshold)
+	}
+	if ps.SuccessThreshold != 0 {
+		obj["successThreshold"] = fmt.Sprintf("%d", ps.SuccessThreshold)
+	}
+	if ps.Scheme != "" {
+		obj["scheme"] = ps.Scheme
+	}
+	if len(ps.ExpectedStatuses) > 0 {
+		obj["expectedStatuses"] = FormatStatusRanges(ps.ExpectedStatuses)
+	}
+	if ps.VerifyIdentity {
+		obj["verifyIdentity"] = "true"
+	}
+	return obj
 }
 
 func stringField(obj map[string]interface{}, key string) (string, error) {
@@ -302,3 +817,22 @@ func stringField(obj map[string]interface{}, key string) (string, error) {
 	}
 	return str, nil
 }
+
+func patternsField(obj map[string]interface{}, key string) ([]string, error) {
+	patterns, _, err := unstructured.NestedStringSlice(obj, key)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidatePropagationPatterns(patterns); err != nil {
+		return nil, err
+	}
+	return patterns, nil
+}
+
+func toInterfaceSlice(strs []string) []interface{} {
+	slice := make([]interface{}, len(strs))
+	for i, s := range strs {
+		slice[i] = s
+	}
+	return slice
+}
@@ -0,0 +1,25 @@
+package multicluster
+
+import (
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// MCSAPIGroupVersion is the group and version of the Kubernetes Multi-Cluster
+// Services API resources supported by linkerd multicluster.
+const MCSAPIGroupVersion = "multicluster.x-k8s.io/v1alpha1"
+
+// ServiceExportGVR is the Group Version and Resource of the MCS API
+// ServiceExport, which marks a service as exported to the clusterset.
+var ServiceExportGVR = schema.GroupVersionResource{
+	Group:    "multicluster.x-k8s.io",
+	Version:  "v1alpha1",
+	Resource: "serviceexports",
+}
+
+// ServiceImportGVR is the Group Version and Resource of the MCS API
+// ServiceImport, which describes a service imported from the clusterset.
+var ServiceImportGVR = schema.GroupVersionResource{
+	Group:    "multicluster.x-k8s.io",
+	Version:  "v1alpha1",
+	Resource: "serviceimports",
+}
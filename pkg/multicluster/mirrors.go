@@ -0,0 +1,97 @@
+package multicluster
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/linkerd/linkerd2/pkg/k8s"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// MirrorLabelSelector returns the label selector of the resources mirrored
+// from the given target cluster, including the namespaces created to hold
+// them.
+func MirrorLabelSelector(clusterName string) string {
+	return fmt.Sprintf("%s=true,%s=%s", k8s.MirroredResourceLabel, k8s.RemoteClusterNameLabel, clusterName)
+}
+
+// DeleteUnusedMirrorNamespaces deletes the namespaces that were created to
+// hold the mirrors of the services of the given target cluster, once nothing
+// else is left in them: no service and no pod. Namespaces whose mirror labels
+// were removed are kept. The namespaces that still hold the mirrors of other
+// target clusters are handed over to one of them instead, so that they're
+// deleted along with the last of the mirrors they hold. It returns the names
+// of the namespaces it deleted.
+func DeleteUnusedMirrorNamespaces(ctx context.Context, client kubernetes.Interface, clusterName string) ([]string, error) {
+	namespaces, err := client.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: MirrorLabelSelector(clusterName)})
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve the mirror namespaces: %s", err)
+	}
+
+	var deleted []string
+	for i := range namespaces.Items {
+		ns := &namespaces.Items[i]
+		if ns.DeletionTimestamp != nil {
+			continue
+		}
+		inUse, successor, err := namespaceUsage(ctx, client, ns.Name, clusterName)
+		if err != nil {
+			return deleted, err
+		}
+		if successor != "" {
+			ns.Labels[k8s.RemoteClusterNameLabel] = successor
+			if _, err := client.CoreV1().Namespaces().Update(ctx, ns, metav1.UpdateOptions{}); err != nil {
+				return deleted, fmt.Errorf("could not hand namespace %s over to target cluster %s: %s", ns.Name, successor, err)
+			}
+			continue
+		}
+		if inUse {
+			continue
+		}
+		err = client.CoreV1().Namespaces().Delete(ctx, ns.Name, metav1.DeleteOptions{})
+		if err != nil && !kerrors.IsNotFound(err) {
+			return deleted, fmt.Errorf("could not delete namespace %s: %s", ns.Name, err)
+		}
+		deleted = append(deleted, ns.Name)
+	}
+	return deleted, nil
+}
+
+// namespaceUsage returns whether there's any service or pod in the namespace
+// and, if it holds the mirrors of other target clusters but none of the given
+// one, the first of these clusters.
+func namespaceUsage(ctx context.Context, client kubernetes.Interface, namespace, clusterName string) (bool, string, error) {
+	services, err := client.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return false, "", fmt.Errorf("could not list the services of namespace %s: %s", namespace, err)
+	}
+	if len(services.Items) > 0 {
+		var others []string
+		for _, svc := range services.Items {
+			if svc.Labels[k8s.MirroredResourceLabel] != "true" {
+				continue
+			}
+			cluster := svc.Labels[k8s.RemoteClusterNameLabel]
+			if cluster == clusterName {
+				// the namespace is kept until the own mirrors are gone
+				return true, "", nil
+			}
+			if cluster != "" {
+				others = append(others, cluster)
+			}
+		}
+		if len(others) == 0 {
+			return true, "", nil
+		}
+		sort.Strings(others)
+		return true, others[0], nil
+	}
+	pods, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{Limit: 1})
+	if err != nil {
+		return false, "", fmt.Errorf("could not list the pods of namespace %s: %s", namespace, err)
+	}
+	return len(pods.Items) > 0, "", nil
+}
@@ -0,0 +1,82 @@
+package multicluster
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/linkerd/linkerd2/pkg/k8s"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDeleteUnusedMirrorNamespaces(t *testing.T) {
+	mirrorNamespace := func(name, clusterName string) *corev1.Namespace {
+		return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				k8s.MirroredResourceLabel:  "true",
+				k8s.RemoteClusterNameLabel: clusterName,
+			},
+		}}
+	}
+
+	client := fake.NewSimpleClientset(
+		mirrorNamespace("empty", "east"),
+		mirrorNamespace("with-service", "east"),
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "with-service"}},
+		mirrorNamespace("with-pod", "east"),
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "with-pod"}},
+		mirrorNamespace("other-cluster", "west"),
+		mirrorNamespace("shared", "east"),
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web-west", Namespace: "shared", Labels: map[string]string{
+			k8s.MirroredResourceLabel:  "true",
+			k8s.RemoteClusterNameLabel: "west",
+		}}},
+		mirrorNamespace("shared-draining", "east"),
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web-east", Namespace: "shared-draining", Labels: map[string]string{
+			k8s.MirroredResourceLabel:  "true",
+			k8s.RemoteClusterNameLabel: "east",
+		}}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web-west", Namespace: "shared-draining", Labels: map[string]string{
+			k8s.MirroredResourceLabel:  "true",
+			k8s.RemoteClusterNameLabel: "west",
+		}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "unlabeled"}},
+	)
+
+	deleted, err := DeleteUnusedMirrorNamespaces(context.Background(), client, "east")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(deleted, []string{"empty"}) {
+		t.Fatalf("Expected only the empty namespace to be deleted, got %v", deleted)
+	}
+
+	namespaces, err := client.CoreV1().Namespaces().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	var remaining []string
+	owners := make(map[string]string)
+	for _, ns := range namespaces.Items {
+		remaining = append(remaining, ns.Name)
+		owners[ns.Name] = ns.Labels[k8s.RemoteClusterNameLabel]
+	}
+	sort.Strings(remaining)
+	expected := []string{"other-cluster", "shared", "shared-draining", "unlabeled", "with-pod", "with-service"}
+	if !reflect.DeepEqual(remaining, expected) {
+		t.Fatalf("Expected the namespaces %v to remain, got %v", expected, remaining)
+	}
+
+	// the namespace holding the mirrors of another cluster is handed over to
+	// it, unless mirrors of the unlinked cluster are still left in it
+	if owners["shared"] != "west" {
+		t.Fatalf("Expected namespace shared to be handed over to cluster west, got %q", owners["shared"])
+	}
+	if owners["shared-draining"] != "east" {
+		t.Fatalf("Expected namespace shared-draining to be kept by cluster east, got %q", owners["shared-draining"])
+	}
+}
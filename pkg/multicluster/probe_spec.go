@@ -0,0 +1,118 @@
+package multicluster
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Schemes of the gateway probes.
+const (
+	ProbeSchemeHTTP  = "http"
+	ProbeSchemeHTTPS = "https"
+)
+
+// StatusRange is an inclusive range of HTTP status codes.
+type StatusRange struct {
+	Min int
+	Max int
+}
+
+// ParseStatusRanges parses a comma separated list of HTTP status codes and
+// ranges of codes, such as "200-299,301".
+func ParseStatusRanges(s string) ([]StatusRange, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	var ranges []StatusRange
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		bounds := strings.SplitN(entry, "-", 2)
+		min, err := parseStatus(bounds[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid status range '%s': %s", entry, err)
+		}
+		max := min
+		if len(bounds) == 2 {
+			max, err = parseStatus(bounds[1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid status range '%s': %s", entry, err)
+			}
+		}
+		if max < min {
+			return nil, fmt.Errorf("invalid status range '%s': %d is less than %d", entry, max, min)
+		}
+		ranges = append(ranges, StatusRange{Min: min, Max: max})
+	}
+	return ranges, nil
+}
+
+func parseStatus(s string) (int, error) {
+	status, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil {
+		return 0, err
+	}
+	if status < 100 || status > 599 {
+		return 0, fmt.Errorf("%d is not an HTTP status code", status)
+	}
+	return status, nil
+}
+
+// FormatStatusRanges is the inverse of ParseStatusRanges.
+func FormatStatusRanges(ranges []StatusRange) string {
+	entries := make([]string, len(ranges))
+	for i, r := range ranges {
+		if r.Min == r.Max {
+			entries[i] = strconv.Itoa(r.Min)
+		} else {
+			entries[i] = fmt.Sprintf("%d-%d", r.Min, r.Max)
+		}
+	}
+	return strings.Join(entries, ",")
+}
+
+// ValidateProbeScheme checks that the scheme of the gateway probes is
+// supported; an empty scheme stands for http.
+func ValidateProbeScheme(scheme string) error {
+	switch scheme {
+	case "", ProbeSchemeHTTP, ProbeSchemeHTTPS:
+		return nil
+	default:
+		return fmt.Errorf("invalid probe scheme '%s', must be %s or %s", scheme, ProbeSchemeHTTP, ProbeSchemeHTTPS)
+	}
+}
+
+// ValidateProbeIdentity checks that the gateway's identity can be verified by
+// the probes of the given spec: the service mirror's proxy can only originate
+// mTLS for plain http probes.
+func ValidateProbeIdentity(spec ProbeSpec) error {
+	if spec.VerifyIdentity && spec.Scheme == ProbeSchemeHTTPS {
+		return fmt.Errorf("the identity of the gateway can only be verified by %s probes", ProbeSchemeHTTP)
+	}
+	return nil
+}
+
+// URL returns the URL of the probe endpoint of the gateway at the given
+// host, which may include a port.
+func (ps ProbeSpec) URL(host string) string {
+	scheme := ps.Scheme
+	if scheme == "" {
+		scheme = ProbeSchemeHTTP
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, host, ps.Path)
+}
+
+// ExpectsStatus returns whether a probe response with the given status is
+// successful: only 200 is, unless ExpectedStatuses is set.
+func (ps ProbeSpec) ExpectsStatus(status int) bool {
+	if len(ps.ExpectedStatuses) == 0 {
+		return status == http.StatusOK
+	}
+	for _, r := range ps.ExpectedStatuses {
+		if status >= r.Min && status <= r.Max {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package multicluster
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseStatusRanges(t *testing.T) {
+	for _, tc := range []struct {
+		input    string
+		expected []StatusRange
+		err      bool
+	}{
+		{input: "", expected: nil},
+		{input: "200", expected: []StatusRange{{200, 200}}},
+		{input: "200-299, 301", expected: []StatusRange{{200, 299}, {301, 301}}},
+		{input: "299-200", err: true},
+		{input: "600", err: true},
+		{input: "ok", err: true},
+		{input: "200,", err: true},
+	} {
+		tc := tc // pin
+		t.Run(tc.input, func(t *testing.T) {
+			ranges, err := ParseStatusRanges(tc.input)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("Expected an error, got %v", ranges)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(ranges, tc.expected) {
+				t.Fatalf("Expected %v, got %v", tc.expected, ranges)
+			}
+		})
+	}
+}
+
+func TestExpectsStatus(t *testing.T) {
+	spec := ProbeSpec{}
+	if !spec.ExpectsStatus(200) || spec.ExpectsStatus(204) {
+		t.Error("Expected only 200 to be expected by default")
+	}
+
+	spec.ExpectedStatuses = []StatusRange{{200, 299}, {301, 301}}
+	for status, expected := range map[int]bool{200: true, 204: true, 301: true, 302: false, 503: false} {
+		if spec.ExpectsStatus(status) != expected {
+			t.Errorf("Expected ExpectsStatus(%d) to be %t", status, expected)
+		}
+	}
+}
+
+func TestProbeSpecRoundTrip(t *testing.T) {
+	for _, spec := range []ProbeSpec{
+		{Path: "/ready", Port: 4191, Period: 3 * time.Second},
+		{
+			Path:             "/ready",
+			Port:             4191,
+			Period:           10 * time.Second,
+			Timeout:          30 * time.Second,
+			FailureThreshold: 5,
+			SuccessThreshold: 2,
+			Scheme:           ProbeSchemeHTTPS,
+			ExpectedStatuses: []StatusRange{{200, 299}},
+		},
+		{Path: "/ready", Port: 4191, Period: 3 * time.Second, VerifyIdentity: true},
+	} {
+		parsed, err := newProbeSpec(probeSpecToUnstructured(spec))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if !reflect.DeepEqual(parsed, spec) {
+			t.Errorf("Expected %s, got %s", spec, parsed)
+		}
+	}
+
+	obj := probeSpecToUnstructured(ProbeSpec{Path: "/ready", Port: 4191, Period: time.Second})
+	obj["scheme"] = "ftp"
+	if _, err := newProbeSpec(obj); err == nil {
+		t.Error("Expected an invalid scheme to be rejected")
+	}
+
+	obj = probeSpecToUnstructured(ProbeSpec{Path: "/ready", Port: 4191, Period: time.Second, Scheme: ProbeSchemeHTTPS, VerifyIdentity: true})
+	if _, err := newProbeSpec(obj); err == nil {
+		t.Error("Expected the identity verification of https probes to be rejected")
+	}
+}
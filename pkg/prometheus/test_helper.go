@@ -17,6 +17,7 @@ import (
 // TODO: move this into something shared under /controller, or into /pkg
 type MockProm struct {
 	Res             model.Value
+	RulesRes        promv1.RulesResult
 	QueriesExecuted []string // expose the queries our Mock Prometheus receives, to test query generation
 	rwLock          sync.Mutex
 }
@@ -109,7 +110,7 @@ func (m *MockProm) Metadata(ctx context.Context, metric string, limit string) (m
 
 // Rules returns a list of alerting and recording rules that are currently loaded.
 func (m *MockProm) Rules(ctx context.Context) (promv1.RulesResult, error) {
-	return promv1.RulesResult{}, nil
+	return m.RulesRes, nil
 }
 
 // TargetsMetadata returns metadata about metrics currently scraped by the target.
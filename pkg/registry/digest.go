@@ -0,0 +1,163 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+const (
+	dockerHub = "registry-1.docker.io"
+
+	// digestHeader is the header holding the digest of a manifest in the
+	// responses of the registries.
+	digestHeader = "Docker-Content-Digest"
+)
+
+// manifestTypes are the manifest media types accepted when resolving a tag.
+// Indexes and manifest lists come first so that the digest of a
+// multi-architecture image is the digest of the whole image, not of the
+// manifest of one of its platforms.
+var manifestTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+var authParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// DigestResolver resolves image tags to digests by querying the registries
+// serving the images, through the Docker Registry HTTP API V2. Registries
+// requiring authentication are only supported if they hand out anonymous
+// pull tokens.
+type DigestResolver struct {
+	client *http.Client
+}
+
+// NewDigestResolver returns a DigestResolver sending its requests with the
+// given client.
+func NewDigestResolver(client *http.Client) *DigestResolver {
+	return &DigestResolver{client}
+}
+
+// Resolve returns the digest of the given tag of an image, e.g.
+// "sha256:4f3c...", for the image "cr.l5d.io/linkerd/proxy" and the tag
+// "stable-2.10.2".
+func (r *DigestResolver) Resolve(ctx context.Context, image, tag string) (string, error) {
+	host, repository := splitImage(image)
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", host, repository, tag)
+
+	rsp, err := r.headManifest(ctx, manifestURL, "")
+	if err != nil {
+		return "", err
+	}
+	if rsp.StatusCode == http.StatusUnauthorized {
+		token, err := r.anonymousToken(ctx, rsp.Header.Get("WWW-Authenticate"), repository)
+		if err != nil {
+			return "", fmt.Errorf("failed to authenticate to %s: %s", host, err)
+		}
+		rsp, err = r.headManifest(ctx, manifestURL, token)
+		if err != nil {
+			return "", err
+		}
+	}
+	if rsp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to resolve %s:%s: unexpected status %s", image, tag, rsp.Status)
+	}
+
+	digest := rsp.Header.Get(digestHeader)
+	if !strings.HasPrefix(digest, "sha256:") {
+		return "", fmt.Errorf("failed to resolve %s:%s: invalid digest '%s'", image, tag, digest)
+	}
+	return digest, nil
+}
+
+func (r *DigestResolver) headManifest(ctx context.Context, manifestURL, token string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestTypes, ","))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rsp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	rsp.Body.Close()
+	return rsp, nil
+}
+
+// anonymousToken requests a pull token for the repository from the
+// authorization server designated by the challenge of a registry.
+func (r *DigestResolver) anonymousToken(ctx context.Context, challenge, repository string) (string, error) {
+	if !strings.HasPrefix(challenge, "Bearer ") {
+		return "", fmt.Errorf("unsupported authentication challenge '%s'", challenge)
+	}
+	params := map[string]string{}
+	for _, match := range authParam.FindAllStringSubmatch(challenge, -1) {
+		params[match[1]] = match[2]
+	}
+	realm, ok := params["realm"]
+	if !ok {
+		return "", fmt.Errorf("no realm in authentication challenge '%s'", challenge)
+	}
+
+	query := url.Values{}
+	if service, ok := params["service"]; ok {
+		query.Set("service", service)
+	}
+	scope, ok := params["scope"]
+	if !ok {
+		scope = fmt.Sprintf("repository:%s:pull", repository)
+	}
+	query.Set("scope", scope)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	rsp, err := r.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s", rsp.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(rsp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", fmt.Errorf("no token returned by %s", realm)
+}
+
+// splitImage splits an image name into the host of its registry and its
+// repository, following the conventions of the docker CLI: images without a
+// registry host are served by Docker Hub.
+func splitImage(image string) (string, string) {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		return parts[0], parts[1]
+	}
+	if len(parts) == 1 {
+		return dockerHub, "library/" + image
+	}
+	return dockerHub, image
+}
@@ -0,0 +1,79 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const testDigest = "sha256:4f3c8b2d1a7e9f0b5c6d7e8f9a0b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b"
+
+func TestResolve(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			if r.URL.Query().Get("scope") != "repository:linkerd/proxy:pull" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			fmt.Fprint(w, `{"token": "anonymous"}`)
+		case "/v2/linkerd/proxy/manifests/stable-2.10.2":
+			if r.Header.Get("Authorization") != "Bearer anonymous" {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry"`, server.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if !strings.Contains(r.Header.Get("Accept"), "manifest.list.v2+json") {
+				t.Errorf("Expected manifest lists to be accepted, got %s", r.Header.Get("Accept"))
+			}
+			w.Header().Set(digestHeader, testDigest)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	host := strings.TrimPrefix(server.URL, "https://")
+	resolver := NewDigestResolver(server.Client())
+
+	digest, err := resolver.Resolve(context.Background(), host+"/linkerd/proxy", "stable-2.10.2")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if digest != testDigest {
+		t.Fatalf("Expected digest %s, got %s", testDigest, digest)
+	}
+
+	_, err = resolver.Resolve(context.Background(), host+"/linkerd/proxy", "missing")
+	if err == nil {
+		t.Fatal("Expected an error resolving a missing tag")
+	}
+}
+
+func TestSplitImage(t *testing.T) {
+	testCases := []struct {
+		image      string
+		host       string
+		repository string
+	}{
+		{"cr.l5d.io/linkerd/proxy", "cr.l5d.io", "linkerd/proxy"},
+		{"localhost:5000/proxy", "localhost:5000", "proxy"},
+		{"localhost/proxy", "localhost", "proxy"},
+		{"linkerd/proxy", dockerHub, "linkerd/proxy"},
+		{"busybox", dockerHub, "library/busybox"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // pin
+		t.Run(tc.image, func(t *testing.T) {
+			host, repository := splitImage(tc.image)
+			if host != tc.host || repository != tc.repository {
+				t.Fatalf("Expected %s %s, got %s %s", tc.host, tc.repository, host, repository)
+			}
+		})
+	}
+}
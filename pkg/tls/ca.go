@@ -164,7 +164,7 @@ func (ca *CA) GenerateCA(name string, maxPathLen int) (*CA, error) {
 		return nil, err
 	}
 
-	t := ca.createTemplate(&key.PublicKey)
+	t := ca.createTemplate(&key.PublicKey, ca.Validity)
 	t.Subject = pkix.Name{CommonName: name}
 	t.IsCA = true
 	t.MaxPathLen = maxPathLen
@@ -204,12 +204,30 @@ func (ca *CA) GenerateEndEntityCred(dnsName string) (*Cred, error) {
 // IssueEndEntityCrt creates a new certificate that is valid for the
 // given DNS name, generating a new keypair for it.
 func (ca *CA) IssueEndEntityCrt(csr *x509.CertificateRequest) (Crt, error) {
+	return ca.issueEndEntityCrt(csr, ca.Validity)
+}
+
+// IssueEndEntityCrtWithLifetime is like IssueEndEntityCrt, but the issued
+// certificate is valid for the given lifetime instead of the CA's.
+func (ca *CA) IssueEndEntityCrtWithLifetime(csr *x509.CertificateRequest, lifetime time.Duration) (Crt, error) {
+	validity := ca.Validity
+	validity.Lifetime = lifetime
+	return ca.issueEndEntityCrt(csr, validity)
+}
+
+// Expiration returns the time at which the first certificate in the CA's
+// trust chain expires. Certificates issued by the CA never outlive it.
+func (ca *CA) Expiration() time.Time {
+	return ca.firstCrtExpiration
+}
+
+func (ca *CA) issueEndEntityCrt(csr *x509.CertificateRequest, validity Validity) (Crt, error) {
 	pubkey, ok := csr.PublicKey.(*ecdsa.PublicKey)
 	if !ok {
 		return Crt{}, fmt.Errorf("CSR must contain an ECDSA public key: %+v", csr.PublicKey)
 	}
 
-	t := ca.createTemplate(pubkey)
+	t := ca.createTemplate(pubkey, validity)
 	t.Issuer = ca.Cred.Crt.Certificate.Subject
 	t.Subject = csr.Subject
 	t.Extensions = csr.Extensions
@@ -225,8 +243,8 @@ func (ca *CA) IssueEndEntityCrt(csr *x509.CertificateRequest) (Crt, error) {
 // createTemplate returns a certificate t for a non-CA certificate with
 // no subject name, no subjectAltNames. The t can then be modified into
 // a (root) CA t or an end-entity t by the caller.
-func (ca *CA) createTemplate(pubkey *ecdsa.PublicKey) *x509.Certificate {
-	c := createTemplate(ca.nextSerialNumber, pubkey, ca.Validity)
+func (ca *CA) createTemplate(pubkey *ecdsa.PublicKey, validity Validity) *x509.Certificate {
+	c := createTemplate(ca.nextSerialNumber, pubkey, validity)
 	ca.nextSerialNumber++
 	// if our trust chain contains a certificate that expires
 	// sooner than the one we intend to issue, we clamp the
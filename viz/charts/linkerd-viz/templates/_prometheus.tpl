@@ -0,0 +1,18 @@
+{{/*
+The URL Grafana and the metrics-api query Prometheus at: prometheusUrl when it's
+set, the query endpoint of the remote store when the bundled Prometheus runs in
+agent mode, as it can't be queried, and the bundled Prometheus otherwise.
+*/}}
+{{- define "linkerd-viz.prometheus-url" -}}
+{{- if .Values.prometheusUrl -}}
+{{ .Values.prometheusUrl }}
+{{- else if and .Values.prometheus.enabled .Values.prometheus.agentMode.enabled -}}
+{{- if .Values.prometheus.agentMode.queryUrl -}}
+{{ .Values.prometheus.agentMode.queryUrl }}
+{{- else -}}
+{{- fail "prometheus.agentMode.queryUrl or prometheusUrl must be set when prometheus.agentMode.enabled is true, as the agent can't be queried" -}}
+{{- end -}}
+{{- else if .Values.prometheus.enabled -}}
+http://prometheus.{{.Values.namespace}}.svc.{{.Values.clusterDomain}}:9090
+{{- end -}}
+{{- end -}}
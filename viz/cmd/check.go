@@ -8,21 +8,24 @@ import (
 
 	pkgcmd "github.com/linkerd/linkerd2/pkg/cmd"
 	"github.com/linkerd/linkerd2/pkg/healthcheck"
+	"github.com/linkerd/linkerd2/pkg/issuercerts"
 	vizHealthCheck "github.com/linkerd/linkerd2/viz/pkg/healthcheck"
 	"github.com/spf13/cobra"
 )
 
 type checkOptions struct {
-	proxy     bool
-	wait      time.Duration
-	namespace string
-	output    string
+	proxy        bool
+	wait         time.Duration
+	namespace    string
+	output       string
+	expiryWindow time.Duration
 }
 
 func newCheckOptions() *checkOptions {
 	return &checkOptions{
-		wait:   300 * time.Second,
-		output: healthcheck.TableOutput,
+		wait:         300 * time.Second,
+		output:       healthcheck.TableOutput,
+		expiryWindow: issuercerts.DefaultExpiryWindow,
 	}
 }
 
@@ -30,6 +33,9 @@ func (options *checkOptions) validate() error {
 	if options.output != healthcheck.TableOutput && options.output != healthcheck.JSONOutput {
 		return fmt.Errorf("Invalid output type '%s'. Supported output types are: %s, %s", options.output, healthcheck.JSONOutput, healthcheck.TableOutput)
 	}
+	if options.expiryWindow <= 0 {
+		return fmt.Errorf("--expiry-window must be positive")
+	}
 	return nil
 }
 
@@ -58,10 +64,9 @@ code.`,
 	cmd.Flags().BoolVar(&options.proxy, "proxy", options.proxy, "Also run data-plane checks, to determine if the data plane is healthy")
 	cmd.Flags().DurationVar(&options.wait, "wait", options.wait, "Maximum allowed time for all tests to pass")
 	cmd.Flags().StringVarP(&options.namespace, "namespace", "n", options.namespace, "Namespace to use for --proxy checks (default: all namespaces)")
+	cmd.Flags().DurationVar(&options.expiryWindow, "expiry-window", options.expiryWindow, "Warn about certificates expiring within this window")
 
-	pkgcmd.ConfigureNamespaceFlagCompletion(
-		cmd, []string{"namespace"},
-		kubeconfigPath, impersonate, impersonateGroup, kubeContext)
+	pkgcmd.ConfigureNamespaceFlagCompletion(cmd, []string{"namespace"})
 	return cmd
 }
 
@@ -80,6 +85,7 @@ func configureAndRunChecks(wout io.Writer, werr io.Writer, options *checkOptions
 		APIAddr:               apiAddr,
 		RetryDeadline:         time.Now().Add(options.wait),
 		DataPlaneNamespace:    options.namespace,
+		ExpiryWindow:          options.expiryWindow,
 	})
 	err = hc.InitializeKubeAPIClient()
 	if err != nil {
@@ -9,6 +9,7 @@ import (
 	"sort"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/fatih/color"
 	pkgcmd "github.com/linkerd/linkerd2/pkg/cmd"
@@ -31,12 +32,16 @@ type edgesOptions struct {
 	namespace     string
 	outputFormat  string
 	allNamespaces bool
+	watch         bool
+	watchInterval time.Duration
 }
 
 func newEdgesOptions() *edgesOptions {
 	return &edgesOptions{
 		outputFormat:  tableOutput,
 		allNamespaces: false,
+		watch:         false,
+		watchInterval: 10 * time.Second,
 	}
 }
 
@@ -83,7 +88,11 @@ func NewCmdEdges() *cobra.Command {
   linkerd viz edges po
 
   # Get all edges between pods in all namespaces.
-  linkerd viz edges po --all-namespaces`,
+  linkerd viz edges po --all-namespaces
+
+  # Stream the edges between deployments in all namespaces as they are added,
+  # removed or change identities, as JSON lines.
+  linkerd viz edges deploy --all-namespaces --watch -o json`,
 		Args: cobra.ExactArgs(1),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			// This command requires only one argument. If we already have
@@ -136,26 +145,14 @@ func NewCmdEdges() *cobra.Command {
 				APIAddr:               apiAddr,
 			})
 
-			c := make(chan indexedEdgeResults, len(reqs))
-			for num, req := range reqs {
-				go func(num int, req *pb.EdgesRequest) {
-					resp, err := requestEdgesFromAPI(client, req)
-					rows := edgesRespToRows(resp)
-					c <- indexedEdgeResults{num, rows, err}
-				}(num, req)
+			if options.watch {
+				return watchEdges(cmd.Context(), client, reqs, options, os.Stdout)
 			}
 
-			totalRows := make([]*pb.Edge, 0)
-			i := 0
-			for res := range c {
-				if res.err != nil {
-					fmt.Fprint(os.Stderr, res.err.Error())
-					os.Exit(1)
-				}
-				totalRows = append(totalRows, res.rows...)
-				if i++; i == len(reqs) {
-					close(c)
-				}
+			totalRows, err := fetchEdges(client, reqs)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err.Error())
+				os.Exit(1)
 			}
 
 			output := renderEdgeStats(totalRows, options)
@@ -168,10 +165,10 @@ func NewCmdEdges() *cobra.Command {
 	cmd.PersistentFlags().StringVarP(&options.namespace, "namespace", "n", options.namespace, "Namespace of the specified resource")
 	cmd.PersistentFlags().StringVarP(&options.outputFormat, "output", "o", options.outputFormat, "Output format; one of: \"table\" or \"json\" or \"wide\"")
 	cmd.PersistentFlags().BoolVarP(&options.allNamespaces, "all-namespaces", "A", options.allNamespaces, "If present, returns edges across all namespaces, ignoring the \"--namespace\" flag")
+	cmd.PersistentFlags().BoolVarP(&options.watch, "watch", "w", options.watch, "If present, streams the edges that are added, removed, or whose identities change, with timestamps")
+	cmd.PersistentFlags().DurationVar(&options.watchInterval, "watch-interval", options.watchInterval, "Interval between two polls of the edges in watch mode")
 
-	pkgcmd.ConfigureNamespaceFlagCompletion(
-		cmd, []string{"namespace"},
-		kubeconfigPath, impersonate, impersonateGroup, kubeContext)
+	pkgcmd.ConfigureNamespaceFlagCompletion(cmd, []string{"namespace"})
 	return cmd
 }
 
@@ -188,6 +185,10 @@ func validateEdgesRequestInputs(targets []*pb.Resource, options *edgesOptions) e
 		}
 	}
 
+	if options.watch && options.watchInterval <= 0 {
+		return fmt.Errorf("--watch-interval must be positive")
+	}
+
 	switch options.outputFormat {
 	case tableOutput, jsonOutput, wideOutput:
 		return nil
@@ -224,6 +225,29 @@ func buildEdgesRequests(resources []string, options *edgesOptions) ([]*pb.EdgesR
 	return requests, nil
 }
 
+// fetchEdges sends the requests concurrently and returns all the edges.
+func fetchEdges(client pb.ApiClient, reqs []*pb.EdgesRequest) ([]*pb.Edge, error) {
+	c := make(chan indexedEdgeResults, len(reqs))
+	for num, req := range reqs {
+		go func(num int, req *pb.EdgesRequest) {
+			resp, err := requestEdgesFromAPI(client, req)
+			rows := edgesRespToRows(resp)
+			c <- indexedEdgeResults{num, rows, err}
+		}(num, req)
+	}
+
+	totalRows := make([]*pb.Edge, 0)
+	var err error
+	for range reqs {
+		res := <-c
+		if res.err != nil && err == nil {
+			err = res.err
+		}
+		totalRows = append(totalRows, res.rows...)
+	}
+	return totalRows, err
+}
+
 func edgesRespToRows(resp *pb.EdgesResponse) []*pb.Edge {
 	rows := make([]*pb.Edge, 0)
 	if resp != nil {
@@ -272,6 +296,31 @@ const (
 	msgHeader          = "SECURED"
 )
 
+// newEdgeRow converts an edge, shortening its identities to the service
+// account and namespace.
+func newEdgeRow(r *pb.Edge) edgeRow {
+	clientID := r.ClientId
+	serverID := r.ServerId
+	if len(clientID) > 0 {
+		parts := strings.Split(clientID, ".")
+		clientID = parts[0] + "." + parts[1]
+	}
+	if len(serverID) > 0 {
+		parts := strings.Split(serverID, ".")
+		serverID = parts[0] + "." + parts[1]
+	}
+
+	return edgeRow{
+		client:       clientID,
+		server:       serverID,
+		msg:          r.NoIdentityMsg,
+		src:          r.Src.Name,
+		srcNamespace: r.Src.Namespace,
+		dst:          r.Dst.Name,
+		dstNamespace: r.Dst.Namespace,
+	}
+}
+
 func writeEdgesToBuffer(rows []*pb.Edge, w *tabwriter.Writer, options *edgesOptions) {
 	maxSrcLength := len(srcHeader)
 	maxDstLength := len(dstHeader)
@@ -282,56 +331,34 @@ func writeEdgesToBuffer(rows []*pb.Edge, w *tabwriter.Writer, options *edgesOpti
 	maxMsgLength := len(msgHeader)
 
 	edgeRows := []edgeRow{}
-	if len(rows) != 0 {
-		for _, r := range rows {
-			clientID := r.ClientId
-			serverID := r.ServerId
-			msg := r.NoIdentityMsg
-			if msg == "" && options.outputFormat != jsonOutput {
-				msg = okStatus
-			}
-			if len(clientID) > 0 {
-				parts := strings.Split(clientID, ".")
-				clientID = parts[0] + "." + parts[1]
-			}
-			if len(serverID) > 0 {
-				parts := strings.Split(serverID, ".")
-				serverID = parts[0] + "." + parts[1]
-			}
-
-			row := edgeRow{
-				client:       clientID,
-				server:       serverID,
-				msg:          msg,
-				src:          r.Src.Name,
-				srcNamespace: r.Src.Namespace,
-				dst:          r.Dst.Name,
-				dstNamespace: r.Dst.Namespace,
-			}
+	for _, r := range rows {
+		row := newEdgeRow(r)
+		if row.msg == "" && options.outputFormat != jsonOutput {
+			row.msg = okStatus
+		}
 
-			edgeRows = append(edgeRows, row)
+		edgeRows = append(edgeRows, row)
 
-			if len(r.Src.Name) > maxSrcLength {
-				maxSrcLength = len(r.Src.Name)
-			}
-			if len(r.Src.Namespace) > maxSrcNamespaceLength {
-				maxSrcNamespaceLength = len(r.Src.Namespace)
-			}
-			if len(r.Dst.Name) > maxDstLength {
-				maxDstLength = len(r.Dst.Name)
-			}
-			if len(r.Dst.Namespace) > maxDstNamespaceLength {
-				maxDstNamespaceLength = len(r.Dst.Namespace)
-			}
-			if len(clientID) > maxClientLength {
-				maxClientLength = len(clientID)
-			}
-			if len(serverID) > maxServerLength {
-				maxServerLength = len(serverID)
-			}
-			if len(msg) > maxMsgLength {
-				maxMsgLength = len(msg)
-			}
+		if len(row.src) > maxSrcLength {
+			maxSrcLength = len(row.src)
+		}
+		if len(row.srcNamespace) > maxSrcNamespaceLength {
+			maxSrcNamespaceLength = len(row.srcNamespace)
+		}
+		if len(row.dst) > maxDstLength {
+			maxDstLength = len(row.dst)
+		}
+		if len(row.dstNamespace) > maxDstNamespaceLength {
+			maxDstNamespaceLength = len(row.dstNamespace)
+		}
+		if len(row.client) > maxClientLength {
+			maxClientLength = len(row.client)
+		}
+		if len(row.server) > maxServerLength {
+			maxServerLength = len(row.server)
+		}
+		if len(row.msg) > maxMsgLength {
+			maxMsgLength = len(row.msg)
 		}
 	}
 
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"testing"
+	"time"
 
 	api "github.com/linkerd/linkerd2/viz/metrics-api"
 )
@@ -120,3 +121,47 @@ func testEdgesCall(exp edgesParamsExp, t *testing.T) {
 
 	testDataDiffer.DiffTestdata(t, exp.file, output)
 }
+
+func TestDiffEdges(t *testing.T) {
+	web := edgeRow{src: "web", srcNamespace: "emojivoto", dst: "emoji", dstNamespace: "emojivoto", client: "web.emojivoto", server: "emoji.emojivoto"}
+	vote := edgeRow{src: "web", srcNamespace: "emojivoto", dst: "voting", dstNamespace: "emojivoto", client: "web.emojivoto", server: "voting.emojivoto"}
+	unsecured := vote
+	unsecured.client = ""
+	unsecured.msg = "no_tls_from_remote"
+	intruder := web
+	intruder.client = "default.emojivoto"
+
+	previous := map[string]edgeRow{edgeKey(web): web, edgeKey(vote): vote}
+	now := time.Now()
+
+	t.Run("Reports no events when the edges are unchanged", func(t *testing.T) {
+		if events := diffEdges(previous, previous, now); len(events) != 0 {
+			t.Fatalf("Expected no events, got %v", events)
+		}
+	})
+
+	t.Run("Reports added and removed edges", func(t *testing.T) {
+		events := diffEdges(previous, map[string]edgeRow{edgeKey(web): web}, now)
+		if len(events) != 1 || events[0].eventType != edgeRemoved || events[0].edge != vote {
+			t.Fatalf("Expected the voting edge to be removed, got %v", events)
+		}
+
+		events = diffEdges(map[string]edgeRow{}, previous, now)
+		if len(events) != 2 || events[0].eventType != edgeAdded || events[1].eventType != edgeAdded {
+			t.Fatalf("Expected two added edges, got %v", events)
+		}
+	})
+
+	t.Run("Reports identity changes and dropped mTLS", func(t *testing.T) {
+		events := diffEdges(previous, map[string]edgeRow{edgeKey(intruder): intruder, edgeKey(unsecured): unsecured}, now)
+		if len(events) != 2 {
+			t.Fatalf("Expected two events, got %v", events)
+		}
+		if events[0].eventType != edgeIdentityChanged || events[0].previous.client != "web.emojivoto" {
+			t.Fatalf("Expected the client identity of the emoji edge to change, got %v", events[0])
+		}
+		if events[1].eventType != edgeTLSDropped {
+			t.Fatalf("Expected mTLS to be dropped on the voting edge, got %v", events[1])
+		}
+	})
+}
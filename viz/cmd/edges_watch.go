@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	pb "github.com/linkerd/linkerd2/viz/metrics-api/gen/viz"
+)
+
+const (
+	edgeAdded           = "ADDED"
+	edgeRemoved         = "REMOVED"
+	edgeIdentityChanged = "IDENTITY_CHANGED"
+	edgeTLSDropped      = "TLS_DROPPED"
+	edgeTLSRestored     = "TLS_RESTORED"
+)
+
+// edgeEvent is a change in the edges observed between two polls.
+type edgeEvent struct {
+	time      time.Time
+	eventType string
+	edge      edgeRow
+	previous  *edgeRow
+}
+
+type edgeEventJSON struct {
+	Time           string `json:"time"`
+	Event          string `json:"event"`
+	PreviousClient string `json:"previous_client_id,omitempty"`
+	PreviousServer string `json:"previous_server_id,omitempty"`
+	edgesJSONStats
+}
+
+func edgeKey(row edgeRow) string {
+	return fmt.Sprintf("%s/%s->%s/%s", row.srcNamespace, row.src, row.dstNamespace, row.dst)
+}
+
+// diffEdges returns the events turning the previous edges into the current
+// ones, ordered by edge.
+func diffEdges(previous, current map[string]edgeRow, now time.Time) []edgeEvent {
+	events := []edgeEvent{}
+	for key, row := range current {
+		prev, ok := previous[key]
+		switch {
+		case !ok:
+			events = append(events, edgeEvent{time: now, eventType: edgeAdded, edge: row})
+		case prev.msg == "" && row.msg != "":
+			events = append(events, edgeEvent{time: now, eventType: edgeTLSDropped, edge: row, previous: &prev})
+		case prev.msg != "" && row.msg == "":
+			events = append(events, edgeEvent{time: now, eventType: edgeTLSRestored, edge: row, previous: &prev})
+		case prev.client != row.client || prev.server != row.server:
+			events = append(events, edgeEvent{time: now, eventType: edgeIdentityChanged, edge: row, previous: &prev})
+		}
+	}
+	for key, row := range previous {
+		if _, ok := current[key]; !ok {
+			events = append(events, edgeEvent{time: now, eventType: edgeRemoved, edge: row})
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return edgeKey(events[i].edge) < edgeKey(events[j].edge)
+	})
+	return events
+}
+
+// watchEdges polls the edges until the context is cancelled, and writes the
+// changes between two polls as they're observed. The edges found by the first
+// poll are reported as added.
+func watchEdges(ctx context.Context, client pb.ApiClient, reqs []*pb.EdgesRequest, options *edgesOptions, w io.Writer) error {
+	ticker := time.NewTicker(options.watchInterval)
+	defer ticker.Stop()
+
+	previous := map[string]edgeRow{}
+	for {
+		rows, err := fetchEdges(client, reqs)
+		if err != nil {
+			// keep the previous edges, so that a failed poll doesn't report
+			// every edge as removed
+			fmt.Fprintf(os.Stderr, "Failed to fetch edges: %s\n", err)
+		} else {
+			current := make(map[string]edgeRow, len(rows))
+			for _, r := range rows {
+				row := newEdgeRow(r)
+				current[edgeKey(row)] = row
+			}
+			for _, ev := range diffEdges(previous, current, time.Now()) {
+				if err := writeEdgeEvent(w, ev, options.outputFormat); err != nil {
+					return err
+				}
+			}
+			previous = current
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func writeEdgeEvent(w io.Writer, ev edgeEvent, outputFormat string) error {
+	ts := ev.time.UTC().Format(time.RFC3339)
+	if outputFormat == jsonOutput {
+		entry := edgeEventJSON{
+			Time:  ts,
+			Event: ev.eventType,
+			edgesJSONStats: edgesJSONStats{
+				Src:          ev.edge.src,
+				SrcNamespace: ev.edge.srcNamespace,
+				Dst:          ev.edge.dst,
+				DstNamespace: ev.edge.dstNamespace,
+				Client:       ev.edge.client,
+				Server:       ev.edge.server,
+				Msg:          ev.edge.msg,
+			},
+		}
+		if ev.previous != nil {
+			entry.PreviousClient = ev.previous.client
+			entry.PreviousServer = ev.previous.server
+		}
+		b, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+	}
+
+	secured := okStatus
+	if ev.edge.msg != "" {
+		secured = ev.edge.msg
+	}
+	line := fmt.Sprintf("%s %-16s %s/%s -> %s/%s", ts, ev.eventType, ev.edge.srcNamespace, ev.edge.src, ev.edge.dstNamespace, ev.edge.dst)
+	if outputFormat == wideOutput || ev.eventType == edgeIdentityChanged {
+		line += fmt.Sprintf(" client=%s server=%s", ev.edge.client, ev.edge.server)
+		if ev.previous != nil {
+			line += fmt.Sprintf(" (was client=%s server=%s)", ev.previous.client, ev.previous.server)
+		}
+	}
+	line += fmt.Sprintf(" secured=%s", secured)
+	_, err := fmt.Fprintln(w, line)
+	return err
+}
@@ -22,6 +22,7 @@ import (
 
 var (
 	templatesViz = []string{
+		"templates/_prometheus.tpl",
 		"templates/namespace.yaml",
 		"templates/metrics-api-rbac.yaml",
 		"templates/grafana-rbac.yaml",
@@ -32,6 +33,7 @@ var (
 		"templates/metrics-api.yaml",
 		"templates/grafana.yaml",
 		"templates/prometheus.yaml",
+		"templates/recording-rules.yaml",
 		"templates/tap.yaml",
 		"templates/tap-injector-rbac.yaml",
 		"templates/tap-injector.yaml",
@@ -3,6 +3,7 @@ package cmd
 import (
 	"bytes"
 	"fmt"
+	"strings"
 	"testing"
 
 	charts "github.com/linkerd/linkerd2/pkg/charts"
@@ -95,6 +96,21 @@ func TestRender(t *testing.T) {
 			},
 			"install_grafana_disabled.golden",
 		},
+		{
+			map[string]interface{}{
+				"prometheus": map[string]interface{}{
+					"agentMode": map[string]interface{}{
+						"enabled":  true,
+						"queryUrl": "http://thanos-query.monitoring.svc.cluster.local:9090",
+					},
+					"image": map[string]interface{}{"tag": "v2.32.1"},
+					"remoteWrite": []interface{}{
+						map[string]interface{}{"url": "http://thanos-receive.monitoring.svc.cluster.local:19291/api/v1/receive"},
+					},
+				},
+			},
+			"install_prometheus_agent_mode.golden",
+		},
 	}
 
 	for i, tc := range testCases {
@@ -109,3 +125,85 @@ func TestRender(t *testing.T) {
 		})
 	}
 }
+
+func TestRenderPrometheusAgentMode(t *testing.T) {
+	remoteWrite := []interface{}{
+		map[string]interface{}{"url": "https://metrics.example.com/api/v1/write"},
+	}
+	queryURL := map[string]interface{}{"queryUrl": "https://query.example.com/prometheus"}
+
+	testCases := []struct {
+		name          string
+		prometheus    map[string]interface{}
+		prometheusURL string
+		expectedURL   string
+		expectedErr   string
+	}{
+		{
+			name: "query endpoint of the remote store",
+			prometheus: map[string]interface{}{
+				"agentMode":   queryURL,
+				"remoteWrite": remoteWrite,
+			},
+			expectedURL: "https://query.example.com/prometheus",
+		},
+		{
+			name:          "external prometheus",
+			prometheus:    map[string]interface{}{"remoteWrite": remoteWrite},
+			prometheusURL: "https://external.example.com",
+			expectedURL:   "https://external.example.com",
+		},
+		{
+			name:        "no query endpoint",
+			prometheus:  map[string]interface{}{"remoteWrite": remoteWrite},
+			expectedErr: "prometheus.agentMode.queryUrl or prometheusUrl must be set",
+		},
+		{
+			name:        "no remote write",
+			prometheus:  map[string]interface{}{"agentMode": queryURL},
+			expectedErr: "prometheus.remoteWrite must be set",
+		},
+		{
+			name: "old prometheus",
+			prometheus: map[string]interface{}{
+				"agentMode":   queryURL,
+				"image":       map[string]interface{}{"tag": "v2.31.0"},
+				"remoteWrite": remoteWrite,
+			},
+			expectedErr: "requires a prometheus.image.tag of v2.32.0 or later",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			values := map[string]interface{}{
+				"prometheus": charts.MergeMaps(map[string]interface{}{
+					"agentMode": map[string]interface{}{"enabled": true},
+					"image":     map[string]interface{}{"tag": "v2.32.1"},
+				}, tc.prometheus),
+				"prometheusUrl": tc.prometheusURL,
+			}
+
+			var buf bytes.Buffer
+			err := render(&buf, values)
+			if tc.expectedErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedErr) {
+					t.Fatalf("Expected error containing %q, got %v", tc.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			for _, expected := range []string{
+				fmt.Sprintf("- -prometheus-url=%s\n", tc.expectedURL),
+				fmt.Sprintf("url: %s\n", tc.expectedURL),
+			} {
+				if !strings.Contains(buf.String(), expected) {
+					t.Errorf("Expected the output to contain %q", expected)
+				}
+			}
+		})
+	}
+}
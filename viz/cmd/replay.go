@@ -0,0 +1,399 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	pkgcmd "github.com/linkerd/linkerd2/pkg/cmd"
+	"github.com/linkerd/linkerd2/pkg/healthcheck"
+	"github.com/linkerd/linkerd2/pkg/k8s"
+	"github.com/linkerd/linkerd2/pkg/protohttp"
+	"github.com/linkerd/linkerd2/viz/pkg/api"
+	tapPb "github.com/linkerd/linkerd2/viz/tap/gen/tap"
+	"github.com/linkerd/linkerd2/viz/tap/pkg"
+	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	defaultReplayImage  = "curlimages/curl"
+	replayContainerName = "replay"
+	replayLabel         = "viz.linkerd.io/replay"
+)
+
+// skippedReplayHeaders are the headers of a captured request that aren't
+// replayed: the hop-by-hop headers, which the proxies handle, and the ones
+// describing the body, which tap doesn't capture.
+var skippedReplayHeaders = map[string]struct{}{
+	"connection":        {},
+	"content-length":    {},
+	"host":              {},
+	"keep-alive":        {},
+	"proxy-connection":  {},
+	"te":                {},
+	"trailer":           {},
+	"transfer-encoding": {},
+	"upgrade":           {},
+}
+
+type replayOptions struct {
+	namespace   string
+	toResource  string
+	toNamespace string
+	method      string
+	authority   string
+	path        string
+	requestID   string
+	image       string
+	asCaller    bool
+	timeout     time.Duration
+	dryRun      bool
+}
+
+type replayHeader struct {
+	name  string
+	value string
+}
+
+// replayRequest is a request captured by tap, along with the identity of the
+// workload that sent it.
+type replayRequest struct {
+	method               string
+	authority            string
+	path                 string
+	headers              []replayHeader
+	callerNamespace      string
+	callerServiceAccount string
+}
+
+func newReplayOptions() *replayOptions {
+	return &replayOptions{
+		image:    defaultReplayImage,
+		asCaller: true,
+		timeout:  2 * time.Minute,
+	}
+}
+
+func (o *replayOptions) validate() error {
+	if o.timeout <= 0 {
+		return errors.New("--timeout must be positive")
+	}
+	if o.image == "" {
+		return errors.New("--image must not be empty")
+	}
+	return nil
+}
+
+// NewCmdReplay creates a new cobra command `replay` that replays a request
+// captured by tap
+func NewCmdReplay() *cobra.Command {
+	options := newReplayOptions()
+
+	cmd := &cobra.Command{
+		Use:   "replay [flags] (RESOURCE)",
+		Short: "Replay a request captured by tap",
+		Long: `Replay a request captured by tap.
+
+  The first request of the RESOURCE matching the filters is captured with its
+  method, :authority, path and headers, and re-issued with curl from an
+  ephemeral meshed pod, which is deleted once the response is printed. The
+  pod runs with the service account of the workload that sent the captured
+  request, so that the request is replayed with the caller's identity, when
+  you're allowed to create pods in its namespace; otherwise, or with
+  --as-caller=false, it runs with the default service account of the
+  RESOURCE's namespace.
+
+  Tap doesn't capture the bodies of the requests, so requests are replayed
+  without a body. The headers are replayed as is, including the ones carrying
+  credentials.
+
+  The RESOURCE argument specifies the target resource(s) to tap, as for the
+  tap command: (TYPE [NAME] | TYPE/NAME)`,
+		Example: `  # replay a request of the web deployment to the voting service
+  linkerd viz replay deploy/web --to svc/voting-svc
+
+  # replay the request with a given x-request-id
+  linkerd viz replay deploy/web --request-id 0f5e7b2c
+
+  # show the captured request and the pod replaying it, without creating it
+  linkerd viz replay deploy/web --path /api/vote --dry-run`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if options.namespace == "" {
+				options.namespace = pkgcmd.GetDefaultNamespace(kubeconfigPath, kubeContext)
+			}
+			if err := options.validate(); err != nil {
+				return fmt.Errorf("validation error when executing replay command: %v", err)
+			}
+
+			api.CheckClientOrExit(healthcheck.Options{
+				ControlPlaneNamespace: controlPlaneNamespace,
+				KubeConfig:            kubeconfigPath,
+				Impersonate:           impersonate,
+				ImpersonateGroup:      impersonateGroup,
+				KubeContext:           kubeContext,
+				APIAddr:               apiAddr,
+			})
+
+			req, err := pkg.BuildTapByResourceRequest(pkg.TapRequestParams{
+				Resource:    strings.Join(args, "/"),
+				Namespace:   options.namespace,
+				ToResource:  options.toResource,
+				ToNamespace: options.toNamespace,
+				MaxRps:      maxRps,
+				Method:      options.method,
+				Authority:   options.authority,
+				Path:        options.path,
+				Extract:     true,
+			})
+			if err != nil {
+				return err
+			}
+
+			k8sAPI, err := k8s.NewAPI(kubeconfigPath, kubeContext, impersonate, impersonateGroup, 0)
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintf(stderr, "Waiting for a request of %s...\n", strings.Join(args, "/"))
+			captured, err := captureRequest(cmd.Context(), k8sAPI, req, options)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, "Captured request:\n%s\n", renderReplayRequest(captured))
+
+			pod := newReplayPod(cmd.Context(), k8sAPI, captured, options)
+			if options.dryRun {
+				out, err := yaml.Marshal(pod)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(stdout, "---\n%s", out)
+				return nil
+			}
+			return replay(cmd.Context(), k8sAPI, pod, options.timeout, stdout)
+		},
+	}
+
+	cmd.PersistentFlags().StringVarP(&options.namespace, "namespace", "n", options.namespace,
+		"Namespace of the specified resource")
+	cmd.PersistentFlags().StringVar(&options.toResource, "to", options.toResource,
+		"Capture a request to this resource")
+	cmd.PersistentFlags().StringVar(&options.toNamespace, "to-namespace", options.toNamespace,
+		"Sets the namespace used to lookup the \"--to\" resource; by default the current \"--namespace\" is used")
+	cmd.PersistentFlags().StringVar(&options.method, "method", options.method,
+		"Capture a request with this HTTP method")
+	cmd.PersistentFlags().StringVar(&options.authority, "authority", options.authority,
+		"Capture a request with this :authority")
+	cmd.PersistentFlags().StringVar(&options.path, "path", options.path,
+		"Capture a request whose path starts with this prefix")
+	cmd.PersistentFlags().StringVar(&options.requestID, "request-id", options.requestID,
+		fmt.Sprintf("Capture the request whose %s header has this value", pkg.RequestIDHeader))
+	cmd.PersistentFlags().StringVar(&options.image, "image", options.image,
+		"Image of the pod replaying the request; it must provide curl")
+	cmd.PersistentFlags().BoolVar(&options.asCaller, "as-caller", options.asCaller,
+		"Replay the request with the service account of the workload that sent it, when allowed to create pods in its namespace")
+	cmd.PersistentFlags().DurationVar(&options.timeout, "timeout", options.timeout,
+		"Maximum time to wait for a request to capture, and then for its replay")
+	cmd.PersistentFlags().BoolVar(&options.dryRun, "dry-run", options.dryRun,
+		"Only print the captured request and the pod that would replay it")
+
+	pkgcmd.ConfigureNamespaceFlagCompletion(cmd, []string{"namespace", "to-namespace"})
+	return cmd
+}
+
+// captureRequest returns the first request of the tap stream.
+func captureRequest(ctx context.Context, k8sAPI *k8s.KubernetesAPI, req *tapPb.TapByResourceRequest, options *replayOptions) (*replayRequest, error) {
+	ctx, cancel := context.WithTimeout(ctx, options.timeout)
+	defer cancel()
+
+	reader, body, err := pkg.FilteredReader(ctx, k8sAPI, req, &pkg.StreamFilter{RequestID: options.requestID})
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	for {
+		event := tapPb.TapEvent{}
+		err := protohttp.FromByteStreamToProtocolBuffers(reader, &event)
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("no request captured within %s", options.timeout)
+		}
+		if err == io.EOF {
+			return nil, errors.New("the tap stream ended before a request was captured")
+		}
+		if err != nil {
+			return nil, err
+		}
+		if captured, ok := newReplayRequest(&event); ok {
+			return captured, nil
+		}
+	}
+}
+
+// newReplayRequest returns the request of a tap event, if it's the start of
+// a request.
+func newReplayRequest(event *tapPb.TapEvent) (*replayRequest, bool) {
+	reqInit := event.GetHttp().GetRequestInit()
+	if reqInit == nil || reqInit.GetAuthority() == "" {
+		return nil, false
+	}
+
+	req := &replayRequest{
+		method:    formatMethod(reqInit.GetMethod()),
+		authority: reqInit.GetAuthority(),
+		path:      reqInit.GetPath(),
+	}
+	for _, header := range formatHeadersTrailers(reqInit.GetHeaders()) {
+		// binary values can't be given to curl
+		str, ok := header.(*metadataStr)
+		if !ok {
+			continue
+		}
+		name := strings.ToLower(str.Name)
+		if _, skipped := skippedReplayHeaders[name]; skipped || strings.HasPrefix(name, ":") || strings.HasPrefix(name, "l5d-") {
+			continue
+		}
+		req.headers = append(req.headers, replayHeader{name: name, value: str.ValueStr})
+	}
+
+	labels := event.GetSourceMeta().GetLabels()
+	if sa, ok := labels["serviceaccount"]; ok && labels[k8s.Namespace] != "" {
+		req.callerNamespace = labels[k8s.Namespace]
+		req.callerServiceAccount = sa
+	}
+	return req, true
+}
+
+func (r *replayRequest) url() string {
+	return fmt.Sprintf("http://%s%s", r.authority, r.path)
+}
+
+func renderReplayRequest(r *replayRequest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s", r.method, r.url())
+	if r.callerServiceAccount != "" {
+		fmt.Fprintf(&b, " (from %s/%s)", r.callerNamespace, r.callerServiceAccount)
+	}
+	for _, header := range r.headers {
+		fmt.Fprintf(&b, "\n  %s: %s", header.name, header.value)
+	}
+	return b.String()
+}
+
+// curlArgs returns the arguments of the curl command replaying the request,
+// printing the response along with its headers.
+func curlArgs(r *replayRequest, timeout time.Duration) []string {
+	args := []string{"-sS", "-i", "--max-time", fmt.Sprintf("%d", int(timeout.Seconds())), "-X", r.method}
+	for _, header := range r.headers {
+		args = append(args, "-H", fmt.Sprintf("%s: %s", header.name, header.value))
+	}
+	return append(args, r.url())
+}
+
+// newReplayPod returns the pod replaying the request, which runs with the
+// service account of the caller if allowed to.
+func newReplayPod(ctx context.Context, k8sAPI *k8s.KubernetesAPI, r *replayRequest, options *replayOptions) *corev1.Pod {
+	namespace, serviceAccount := options.namespace, ""
+	if options.asCaller && r.callerServiceAccount != "" {
+		err := k8s.ResourceAuthz(ctx, k8sAPI, r.callerNamespace, "create", "", "v1", "pods", "")
+		if err != nil {
+			fmt.Fprintf(stderr, "Not replaying the request as %s/%s: %s\n", r.callerNamespace, r.callerServiceAccount, err)
+		} else {
+			namespace, serviceAccount = r.callerNamespace, r.callerServiceAccount
+		}
+	}
+	return buildReplayPod(r, namespace, serviceAccount, options.image, options.timeout)
+}
+
+func buildReplayPod(r *replayRequest, namespace, serviceAccount, image string, timeout time.Duration) *corev1.Pod {
+	return &corev1.Pod{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "linkerd-replay-",
+			Namespace:    namespace,
+			Labels:       map[string]string{replayLabel: "true"},
+			Annotations: map[string]string{
+				k8s.ProxyInjectAnnotation: k8s.ProxyInjectEnabled,
+				// don't send the request until the proxy is ready
+				k8s.ProxyAwait: k8s.Enabled,
+			},
+		},
+		Spec: corev1.PodSpec{
+			ServiceAccountName: serviceAccount,
+			RestartPolicy:      corev1.RestartPolicyNever,
+			Containers: []corev1.Container{
+				{
+					Name:    replayContainerName,
+					Image:   image,
+					Command: []string{"curl"},
+					Args:    curlArgs(r, timeout),
+				},
+			},
+		},
+	}
+}
+
+// replay creates the replay pod, waits for its request to complete, writes
+// its output, and deletes it.
+func replay(ctx context.Context, k8sAPI *k8s.KubernetesAPI, pod *corev1.Pod, timeout time.Duration, w io.Writer) error {
+	pod, err := k8sAPI.CoreV1().Pods(pod.Namespace).Create(ctx, pod, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// the meshed pod doesn't complete, as its proxy keeps running
+		err := k8sAPI.CoreV1().Pods(pod.Namespace).Delete(context.Background(), pod.Name, metav1.DeleteOptions{})
+		if err != nil {
+			fmt.Fprintf(stderr, "Failed to delete the replay pod %s/%s: %s\n", pod.Namespace, pod.Name, err)
+		}
+	}()
+	fmt.Fprintf(stderr, "Replaying the request from pod %s/%s...\n", pod.Namespace, pod.Name)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	exitCode, err := waitForReplay(ctx, k8sAPI, pod)
+	if err != nil {
+		return err
+	}
+
+	logs, err := k8sAPI.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, &corev1.PodLogOptions{Container: replayContainerName}).Do(ctx).Raw()
+	if err != nil {
+		return fmt.Errorf("failed to get the response: %s", err)
+	}
+	fmt.Fprintf(w, "Response:\n%s\n", logs)
+	if exitCode != 0 {
+		return fmt.Errorf("curl exited with code %d", exitCode)
+	}
+	return nil
+}
+
+// waitForReplay returns the exit code of the replay container once it
+// terminates.
+func waitForReplay(ctx context.Context, k8sAPI *k8s.KubernetesAPI, pod *corev1.Pod) (int32, error) {
+	for {
+		current, err := k8sAPI.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if err != nil && ctx.Err() == nil {
+			return 0, err
+		}
+		if current != nil {
+			for _, status := range current.Status.ContainerStatuses {
+				if status.Name == replayContainerName && status.State.Terminated != nil {
+					return status.State.Terminated.ExitCode, nil
+				}
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0, fmt.Errorf("the request wasn't replayed by pod %s/%s in time", pod.Namespace, pod.Name)
+		case <-time.After(time.Second):
+		}
+	}
+}
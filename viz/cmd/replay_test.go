@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	metricsPb "github.com/linkerd/linkerd2/viz/metrics-api/gen/viz"
+	tapPb "github.com/linkerd/linkerd2/viz/tap/gen/tap"
+	"github.com/linkerd/linkerd2/viz/tap/pkg"
+)
+
+func strHeader(name, value string) *metricsPb.Headers_Header {
+	return &metricsPb.Headers_Header{
+		Name:  name,
+		Value: &metricsPb.Headers_Header_ValueStr{ValueStr: value},
+	}
+}
+
+func TestNewReplayRequest(t *testing.T) {
+	event := pkg.CreateTapEvent(
+		&tapPb.TapEvent_Http{
+			Event: &tapPb.TapEvent_Http_RequestInit_{
+				RequestInit: &tapPb.TapEvent_Http_RequestInit{
+					Method: &metricsPb.HttpMethod{
+						Type: &metricsPb.HttpMethod_Registered_{
+							Registered: metricsPb.HttpMethod_POST,
+						},
+					},
+					Authority: "voting-svc.emojivoto:8080",
+					Path:      "/api/vote?choice=:doughnut:",
+					Headers: &metricsPb.Headers{
+						Headers: []*metricsPb.Headers_Header{
+							strHeader(":authority", "voting-svc.emojivoto:8080"),
+							strHeader("Content-Type", "application/json"),
+							strHeader("content-length", "12"),
+							strHeader("l5d-dst-canonical", "voting-svc.emojivoto.svc.cluster.local:8080"),
+							strHeader("x-request-id", "0f5e7b2c"),
+							{
+								Name:  "x-binary-bin",
+								Value: &metricsPb.Headers_Header_ValueBin{ValueBin: []byte{0x1}},
+							},
+						},
+					},
+				},
+			},
+		},
+		map[string]string{},
+		tapPb.TapEvent_OUTBOUND,
+	)
+	event.SourceMeta = &tapPb.TapEvent_EndpointMeta{
+		Labels: map[string]string{
+			"namespace":      "emojivoto",
+			"serviceaccount": "web",
+		},
+	}
+
+	req, ok := newReplayRequest(event)
+	if !ok {
+		t.Fatal("Expected the request to be captured")
+	}
+	expected := &replayRequest{
+		method:    "POST",
+		authority: "voting-svc.emojivoto:8080",
+		path:      "/api/vote?choice=:doughnut:",
+		headers: []replayHeader{
+			{name: "content-type", value: "application/json"},
+			{name: "x-request-id", value: "0f5e7b2c"},
+		},
+		callerNamespace:      "emojivoto",
+		callerServiceAccount: "web",
+	}
+	if !reflect.DeepEqual(req, expected) {
+		t.Errorf("Expected request %+v, got %+v", expected, req)
+	}
+
+	pod := buildReplayPod(req, req.callerNamespace, req.callerServiceAccount, defaultReplayImage, 30*time.Second)
+	if pod.Namespace != "emojivoto" || pod.Spec.ServiceAccountName != "web" {
+		t.Errorf("Expected the pod to run as emojivoto/web, got %s/%s", pod.Namespace, pod.Spec.ServiceAccountName)
+	}
+	expectedArgs := []string{
+		"-sS", "-i", "--max-time", "30", "-X", "POST",
+		"-H", "content-type: application/json",
+		"-H", "x-request-id: 0f5e7b2c",
+		"http://voting-svc.emojivoto:8080/api/vote?choice=:doughnut:",
+	}
+	if args := pod.Spec.Containers[0].Args; !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("Expected curl args %v, got %v", expectedArgs, args)
+	}
+
+	response := pkg.CreateTapEvent(
+		&tapPb.TapEvent_Http{
+			Event: &tapPb.TapEvent_Http_ResponseInit_{
+				ResponseInit: &tapPb.TapEvent_Http_ResponseInit{HttpStatus: 200},
+			},
+		},
+		map[string]string{},
+		tapPb.TapEvent_OUTBOUND,
+	)
+	if _, ok := newReplayRequest(response); ok {
+		t.Error("Expected a response not to be captured")
+	}
+}
@@ -70,10 +70,7 @@ func NewCmdViz() *cobra.Command {
 	}
 
 	vizCmd.PersistentFlags().StringVarP(&controlPlaneNamespace, "linkerd-namespace", "L", defaultLinkerdNamespace, "Namespace in which Linkerd is installed")
-	vizCmd.PersistentFlags().StringVar(&kubeconfigPath, "kubeconfig", "", "Path to the kubeconfig file to use for CLI requests")
-	vizCmd.PersistentFlags().StringVar(&kubeContext, "context", "", "Name of the kubeconfig context to use")
-	vizCmd.PersistentFlags().StringVar(&impersonate, "as", "", "Username to impersonate for Kubernetes operations")
-	vizCmd.PersistentFlags().StringArrayVar(&impersonateGroup, "as-group", []string{}, "Group to impersonate for Kubernetes operations")
+	pkgcmd.AddKubeFlags(vizCmd.PersistentFlags(), &kubeconfigPath, &kubeContext, &impersonate, &impersonateGroup)
 	vizCmd.PersistentFlags().StringVar(&apiAddr, "api-addr", "", "Override kubeconfig and communicate directly with the control plane at host:port (mostly for testing)")
 	vizCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "Turn on debug logging")
 	vizCmd.AddCommand(NewCmdCheck())
@@ -83,16 +80,16 @@ func NewCmdViz() *cobra.Command {
 	vizCmd.AddCommand(newCmdList())
 	vizCmd.AddCommand(newCmdProfile())
 	vizCmd.AddCommand(NewCmdRoutes())
+	vizCmd.AddCommand(NewCmdReplay())
 	vizCmd.AddCommand(NewCmdStat())
+	vizCmd.AddCommand(newCmdStatSummary())
 	vizCmd.AddCommand(NewCmdTap())
 	vizCmd.AddCommand(NewCmdTop())
 	vizCmd.AddCommand(newCmdUninstall())
 
 	// resource-aware completion flag configurations
-	pkgcmd.ConfigureNamespaceFlagCompletion(
-		vizCmd, []string{"linkerd-namespace"},
-		kubeconfigPath, impersonate, impersonateGroup, kubeContext)
+	pkgcmd.ConfigureNamespaceFlagCompletion(vizCmd, []string{"linkerd-namespace"})
 
-	pkgcmd.ConfigureKubeContextFlagCompletion(vizCmd, kubeconfigPath)
+	pkgcmd.ConfigureKubeContextFlagCompletion(vizCmd)
 	return vizCmd
 }
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"sort"
 	"strings"
@@ -33,8 +34,27 @@ type statOptions struct {
 	allNamespaces bool
 	labelSelector string
 	unmeshed      bool
+	protocol      string
+	// anomalyThreshold is the relative change of the success rate or P99
+	// latency from the preceding time window above which a row is annotated;
+	// zero disables the comparison
+	anomalyThreshold float64
+	// byClient breaks the inbound stats of each resource down by the
+	// identity of its clients
+	byClient bool
+	// deploymentMarkers adds the rollouts of each resource within the time
+	// window to its hints
+	deploymentMarkers bool
+	// successRateObjective is the success rate against which the error
+	// budget of each resource is computed; zero disables it
+	successRateObjective float64
 }
 
+const (
+	protocolHTTP = "http"
+	protocolTCP  = "tcp"
+)
+
 type statOptionsBase struct {
 	namespace    string
 	timeWindow   string
@@ -73,6 +93,7 @@ func newStatOptions() *statOptions {
 		allNamespaces:   false,
 		labelSelector:   "",
 		unmeshed:        false,
+		protocol:        protocolHTTP,
 	}
 }
 
@@ -125,7 +146,14 @@ func NewCmdStat() *cobra.Command {
   * all (all resource types, not supported in --from or --to)
 
 This command will hide resources that have completed, such as pods that are in the Succeeded or Failed phases.
-If no resource name is specified, displays stats about all resources of the specified RESOURCETYPE`,
+If no resource name is specified, displays stats about all resources of the specified RESOURCETYPE.
+
+Stats are computed from HTTP metrics by default. Traffic that isn't HTTP, such as opaque or TCP-only
+traffic to databases, only has TCP metrics; use --protocol tcp to display its connections and byte rates.
+
+Use --by-client to break the inbound stats of the resources down by the identity of their clients, so that
+noisy or failing callers can be identified. Clients without an identity, such as unmeshed ones, are grouped
+together.`,
 		Example: `  # Get all deployments in the test namespace.
   linkerd viz stat deployments -n test
 
@@ -166,7 +194,19 @@ If no resource name is specified, displays stats about all resources of the spec
   linkerd viz stat namespaces --from ns/default
 
   # Get all inbound stats to the test namespace.
-  linkerd viz stat ns/test`,
+  linkerd viz stat ns/test
+
+  # Get the TCP stats of the opaque traffic to the postgres statefulset.
+  linkerd viz stat sts/postgres --protocol tcp
+
+  # Get all deployments in the test namespace, flagging those whose success rate or P99 latency changed by more than 20% from the preceding minute.
+  linkerd viz stat deployments -n test --anomaly-threshold 0.2
+
+  # Get the clients of the web deployment in the test namespace, from the busiest to the quietest.
+  linkerd viz stat deploy/web -n test --by-client
+
+  # Get the web deployment in the test namespace over the last hour, along with its rollouts and its error budget against a 99.9% success rate objective.
+  linkerd viz stat deploy/web -n test -t 1h --deployment-markers --success-rate-objective 0.999`,
 		Args: cobra.MinimumNArgs(1),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 
@@ -213,26 +253,10 @@ If no resource name is specified, displays stats about all resources of the spec
 				APIAddr:               apiAddr,
 			})
 
-			c := make(chan indexedResults, len(reqs))
-			for num, req := range reqs {
-				go func(num int, req *pb.StatSummaryRequest) {
-					resp, err := requestStatsFromAPI(client, req)
-					rows := respToRows(resp)
-					c <- indexedResults{num, rows, err}
-				}(num, req)
-			}
-
-			totalRows := make([]*pb.StatTable_PodGroup_Row, 0)
-			i := 0
-			for res := range c {
-				if res.err != nil {
-					fmt.Fprint(os.Stderr, res.err.Error())
-					os.Exit(1)
-				}
-				totalRows = append(totalRows, res.rows...)
-				if i++; i == len(reqs) {
-					close(c)
-				}
+			totalRows, err := fetchStats(client, reqs)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err.Error())
+				os.Exit(1)
 			}
 
 			output := renderStatStats(totalRows, options)
@@ -252,13 +276,39 @@ If no resource name is specified, displays stats about all resources of the spec
 	cmd.PersistentFlags().StringVarP(&options.outputFormat, "output", "o", options.outputFormat, "Output format; one of: \"table\" or \"json\" or \"wide\"")
 	cmd.PersistentFlags().StringVarP(&options.labelSelector, "selector", "l", options.labelSelector, "Selector (label query) to filter on, supports '=', '==', and '!='")
 	cmd.PersistentFlags().BoolVar(&options.unmeshed, "unmeshed", options.unmeshed, "If present, include unmeshed resources in the output")
+	cmd.PersistentFlags().StringVar(&options.protocol, "protocol", options.protocol, "Protocol of the metrics to display; one of: \"http\" or \"tcp\". Use \"tcp\" for opaque traffic")
+	cmd.PersistentFlags().BoolVar(&options.byClient, "by-client", options.byClient, "If present, breaks the inbound stats down by client identity, listing the clients of each resource from the busiest to the quietest")
+	cmd.PersistentFlags().Float64Var(&options.anomalyThreshold, "anomaly-threshold", options.anomalyThreshold, "If greater than zero, compares the stats with those of the preceding time window and adds a HINTS column to the resources whose success rate or P99 latency changed by more than this fraction (for example: 0.2 for 20%)")
+	cmd.PersistentFlags().BoolVar(&options.deploymentMarkers, "deployment-markers", options.deploymentMarkers, "If present, adds a HINTS column listing the rollouts of each resource within the time window, read from the ReplicaSets of the deployments or recorded through the metrics API webhook")
+	cmd.PersistentFlags().Float64Var(&options.successRateObjective, "success-rate-objective", options.successRateObjective, "If greater than zero, adds a HINTS column with the error budget left to each resource over the time window against this success rate (for example: 0.999 for 99.9%)")
 
-	pkgcmd.ConfigureNamespaceFlagCompletion(
-		cmd, []string{"namespace", "to-namespace", "from-namespace"},
-		kubeconfigPath, impersonate, impersonateGroup, kubeContext)
+	pkgcmd.ConfigureNamespaceFlagCompletion(cmd, []string{"namespace", "to-namespace", "from-namespace"})
 	return cmd
 }
 
+// fetchStats sends the requests concurrently and returns all the rows.
+func fetchStats(client pb.ApiClient, reqs []*pb.StatSummaryRequest) ([]*pb.StatTable_PodGroup_Row, error) {
+	c := make(chan indexedResults, len(reqs))
+	for num, req := range reqs {
+		go func(num int, req *pb.StatSummaryRequest) {
+			resp, err := requestStatsFromAPI(client, req)
+			rows := respToRows(resp)
+			c <- indexedResults{num, rows, err}
+		}(num, req)
+	}
+
+	totalRows := make([]*pb.StatTable_PodGroup_Row, 0)
+	var err error
+	for range reqs {
+		res := <-c
+		if res.err != nil && err == nil {
+			err = res.err
+		}
+		totalRows = append(totalRows, res.rows...)
+	}
+	return totalRows, err
+}
+
 func respToRows(resp *pb.StatSummaryResponse) []*pb.StatTable_PodGroup_Row {
 	rows := make([]*pb.StatTable_PodGroup_Row, 0)
 	if resp != nil {
@@ -284,7 +334,11 @@ func requestStatsFromAPI(client pb.ApiClient, req *pb.StatSummaryRequest) (*pb.S
 func renderStatStats(rows []*pb.StatTable_PodGroup_Row, options *statOptions) string {
 	var buffer bytes.Buffer
 	w := tabwriter.NewWriter(&buffer, 0, 0, padding, ' ', tabwriter.AlignRight)
-	writeStatsToBuffer(rows, w, options)
+	if options.byClient {
+		writeClientStatsToBuffer(rows, w, options)
+	} else {
+		writeStatsToBuffer(rows, w, options)
+	}
 	w.Flush()
 
 	return renderStats(buffer, &options.statOptionsBase)
@@ -303,6 +357,7 @@ type rowStats struct {
 	tcpOpenConnections uint64
 	tcpReadBytes       float64
 	tcpWriteBytes      float64
+	hints              []string
 }
 
 type row struct {
@@ -330,6 +385,10 @@ func statHasRequestData(stat *pb.BasicStats) bool {
 	return stat.GetSuccessCount() != 0 || stat.GetFailureCount() != 0 || stat.GetActualSuccessCount() != 0 || stat.GetActualFailureCount() != 0
 }
 
+func statHasTCPData(stat *pb.TcpStats) bool {
+	return stat.GetOpenConnections() != 0 || stat.GetReadBytesTotal() != 0 || stat.GetWriteBytesTotal() != 0
+}
+
 func isPodOwnerResource(typ string) bool {
 	return typ != k8s.TrafficSplit && typ != k8s.Authority
 }
@@ -399,7 +458,11 @@ func writeStatsToBuffer(rows []*pb.StatTable_PodGroup_Row, w *tabwriter.Writer,
 			status: r.Status,
 		}
 
-		if r.Stats != nil && statHasRequestData(r.Stats) {
+		hasData := r.Stats != nil && statHasRequestData(r.Stats)
+		if options.protocol == protocolTCP {
+			hasData = statHasTCPData(r.GetTcpStats())
+		}
+		if hasData {
 			statTables[resourceKey][key].rowStats = &rowStats{
 				requestRate:        getRequestRate(r.Stats.GetSuccessCount(), r.Stats.GetFailureCount(), r.TimeWindow),
 				successRate:        getSuccessRate(r.Stats.GetSuccessCount(), r.Stats.GetFailureCount()),
@@ -410,6 +473,13 @@ func writeStatsToBuffer(rows []*pb.StatTable_PodGroup_Row, w *tabwriter.Writer,
 				tcpReadBytes:       getByteRate(r.GetTcpStats().GetReadBytesTotal(), r.TimeWindow),
 				tcpWriteBytes:      getByteRate(r.GetTcpStats().GetWriteBytesTotal(), r.TimeWindow),
 			}
+			if showHints(options) {
+				var hints []string
+				if options.anomalyThreshold > 0 {
+					hints = anomalyHints(r.Stats, r.BaselineStats, options.anomalyThreshold)
+				}
+				statTables[resourceKey][key].hints = append(hints, overlayHints(r, time.Now())...)
+			}
 		}
 		if r.TsStats != nil {
 			leaf := r.TsStats.Leaf
@@ -444,7 +514,7 @@ func writeStatsToBuffer(rows []*pb.StatTable_PodGroup_Row, w *tabwriter.Writer,
 		}
 		printStatTables(statTables, w, maxNameLength, maxNamespaceLength, maxLeafLength, maxApexLength, maxWeightLength, options)
 	case jsonOutput:
-		printStatJSON(statTables, w)
+		printStatJSON(statTables, w, options)
 	}
 }
 
@@ -471,14 +541,72 @@ func printStatTables(statTables map[string]map[string]*row, w *tabwriter.Writer,
 }
 
 func showTCPBytes(options *statOptions, resourceType string) bool {
-	return (options.outputFormat == wideOutput || options.outputFormat == jsonOutput) &&
+	return (options.outputFormat == wideOutput || options.outputFormat == jsonOutput || options.protocol == protocolTCP) &&
 		showTCPConns(resourceType)
 }
 
+func showHTTPStats(options *statOptions) bool {
+	return options.protocol != protocolTCP
+}
+
 func showTCPConns(resourceType string) bool {
 	return resourceType != k8s.Authority && resourceType != k8s.TrafficSplit
 }
 
+func showHints(options *statOptions) bool {
+	return (options.anomalyThreshold > 0 || options.deploymentMarkers || options.successRateObjective > 0) &&
+		showHTTPStats(options)
+}
+
+// anomalyHints compares the success rate and P99 latency of a resource with
+// those over the preceding time window, and describes the ones which changed
+// by more than the given fraction.
+func anomalyHints(stats, baseline *pb.BasicStats, threshold float64) []string {
+	if !statHasRequestData(stats) || !statHasRequestData(baseline) {
+		return nil
+	}
+
+	hints := []string{}
+	successRate := getSuccessRate(stats.GetSuccessCount(), stats.GetFailureCount())
+	baselineSuccessRate := getSuccessRate(baseline.GetSuccessCount(), baseline.GetFailureCount())
+	if change, ok := relativeChange(successRate, baselineSuccessRate); ok && math.Abs(change) > threshold {
+		hints = append(hints, fmt.Sprintf("success %+.0f%%", change*100))
+	}
+	if change, ok := relativeChange(float64(stats.GetLatencyMsP99()), float64(baseline.GetLatencyMsP99())); ok && math.Abs(change) > threshold {
+		hints = append(hints, fmt.Sprintf("p99 %+.0f%%", change*100))
+	}
+	return hints
+}
+
+// overlayHints describes the error budget left to a resource and its
+// rollouts, from the oldest to the latest.
+func overlayHints(r *pb.StatTable_PodGroup_Row, now time.Time) []string {
+	hints := []string{}
+	if budget := r.GetErrorBudget(); budget != nil {
+		if budget.GetRemaining() > 0 {
+			hints = append(hints, fmt.Sprintf("budget %.0f%% left", budget.GetRemaining()*100))
+		} else {
+			hints = append(hints, "budget exhausted")
+		}
+	}
+	for _, marker := range r.GetDeploymentMarkers() {
+		hint := "rollout"
+		if marker.GetRevision() != "" {
+			hint += " " + marker.GetRevision()
+		}
+		age := now.Sub(time.Unix(marker.GetTimestamp(), 0)).Round(time.Second)
+		hints = append(hints, fmt.Sprintf("%s %s ago", hint, age))
+	}
+	return hints
+}
+
+func relativeChange(value, baseline float64) (float64, bool) {
+	if baseline == 0 {
+		return 0, false
+	}
+	return (value - baseline) / baseline, true
+}
+
 func printSingleStatTable(stats map[string]*row, resourceTypeLabel, resourceType string, w *tabwriter.Writer, maxNameLength, maxNamespaceLength, maxLeafLength, maxApexLength, maxWeightLength int, options *statOptions) {
 	headers := make([]string, 0)
 	nameTemplate := fmt.Sprintf("%%-%ds", maxNameLength)
@@ -508,13 +636,15 @@ func printSingleStatTable(stats map[string]*row, resourceTypeLabel, resourceType
 		headers = append(headers, "MESHED")
 	}
 
-	headers = append(headers, []string{
-		"SUCCESS",
-		"RPS",
-		"LATENCY_P50",
-		"LATENCY_P95",
-		"LATENCY_P99",
-	}...)
+	if showHTTPStats(options) {
+		headers = append(headers, []string{
+			"SUCCESS",
+			"RPS",
+			"LATENCY_P50",
+			"LATENCY_P95",
+			"LATENCY_P99",
+		}...)
+	}
 
 	if resourceType != k8s.TrafficSplit {
 		headers = append(headers, "TCP_CONN")
@@ -527,6 +657,10 @@ func printSingleStatTable(stats map[string]*row, resourceTypeLabel, resourceType
 		}...)
 	}
 
+	if showHints(options) {
+		headers = append(headers, "HINTS")
+	}
+
 	headers[len(headers)-1] = headers[len(headers)-1] + "\t" // trailing \t is required to format last column
 
 	fmt.Fprintln(w, strings.Join(headers, "\t"))
@@ -547,6 +681,15 @@ func printSingleStatTable(stats map[string]*row, resourceTypeLabel, resourceType
 			templateStringEmpty = "%s\t%s\t%s\t%s\t-\t-\t-\t-\t-\t"
 		}
 
+		if !showHTTPStats(options) {
+			templateString = "%s\t%s\t"
+			templateStringEmpty = "%s\t%s\t-\t"
+			if resourceType == k8s.Pod {
+				templateString = "%s\t" + templateString
+				templateStringEmpty = "%s\t" + templateStringEmpty
+			}
+		}
+
 		if !showTCPConns(resourceType) {
 			if resourceType == k8s.Authority {
 				// always show TCP Connections as - for Authorities
@@ -561,6 +704,11 @@ func printSingleStatTable(stats map[string]*row, resourceTypeLabel, resourceType
 			templateStringEmpty = templateStringEmpty + "-\t-\t"
 		}
 
+		if showHints(options) {
+			templateString = templateString + "%s\t"
+			templateStringEmpty = templateStringEmpty + "-\t"
+		}
+
 		if options.allNamespaces {
 			values = append(values,
 				namespace+strings.Repeat(" ", maxNamespaceLength-len(namespace)))
@@ -606,13 +754,15 @@ func printSingleStatTable(stats map[string]*row, resourceTypeLabel, resourceType
 		}
 
 		if stats[key].rowStats != nil {
-			values = append(values, []interface{}{
-				stats[key].successRate * 100,
-				stats[key].requestRate,
-				stats[key].latencyP50,
-				stats[key].latencyP95,
-				stats[key].latencyP99,
-			}...)
+			if showHTTPStats(options) {
+				values = append(values, []interface{}{
+					stats[key].successRate * 100,
+					stats[key].requestRate,
+					stats[key].latencyP50,
+					stats[key].latencyP95,
+					stats[key].latencyP99,
+				}...)
+			}
 
 			if showTCPConns(resourceType) {
 				values = append(values, stats[key].tcpOpenConnections)
@@ -625,6 +775,14 @@ func printSingleStatTable(stats map[string]*row, resourceTypeLabel, resourceType
 				}...)
 			}
 
+			if showHints(options) {
+				hints := "-"
+				if len(stats[key].hints) > 0 {
+					hints = strings.Join(stats[key].hints, ", ")
+				}
+				values = append(values, hints)
+			}
+
 			fmt.Fprintf(w, templateString, values...)
 		} else {
 			fmt.Fprintf(w, templateStringEmpty, values...)
@@ -657,9 +815,10 @@ type jsonStats struct {
 	Apex           string   `json:"apex,omitempty"`
 	Leaf           string   `json:"leaf,omitempty"`
 	Weight         string   `json:"weight,omitempty"`
+	Hints          []string `json:"hints,omitempty"`
 }
 
-func printStatJSON(statTables map[string]map[string]*row, w *tabwriter.Writer) {
+func printStatJSON(statTables map[string]map[string]*row, w *tabwriter.Writer, options *statOptions) {
 	// avoid nil initialization so that if there are not stats it gets marshalled as an empty array vs null
 	entries := []*jsonStats{}
 	for _, resourceType := range k8s.AllResources {
@@ -676,11 +835,14 @@ func printStatJSON(statTables map[string]map[string]*row, w *tabwriter.Writer) {
 					entry.Meshed = stats[key].meshed
 				}
 				if stats[key].rowStats != nil {
-					entry.Success = &stats[key].successRate
-					entry.Rps = &stats[key].requestRate
-					entry.LatencyMSp50 = &stats[key].latencyP50
-					entry.LatencyMSp95 = &stats[key].latencyP95
-					entry.LatencyMSp99 = &stats[key].latencyP99
+					if showHTTPStats(options) {
+						entry.Success = &stats[key].successRate
+						entry.Rps = &stats[key].requestRate
+						entry.LatencyMSp50 = &stats[key].latencyP50
+						entry.LatencyMSp95 = &stats[key].latencyP95
+						entry.LatencyMSp99 = &stats[key].latencyP99
+						entry.Hints = stats[key].hints
+					}
 
 					if showTCPConns(resourceType) {
 						entry.TCPConnections = &stats[key].tcpOpenConnections
@@ -753,7 +915,12 @@ func buildStatSummaryRequests(resources []string, options *statOptions) ([]*pb.S
 			ToNamespace:   options.toNamespace,
 			FromNamespace: options.fromNamespace,
 			TCPStats:      true,
+			Baseline:      options.anomalyThreshold > 0,
+			ByClient:      options.byClient,
 			LabelSelector: options.labelSelector,
+
+			DeploymentMarkers:    options.deploymentMarkers,
+			SuccessRateObjective: options.successRateObjective,
 		}
 		if fromRes != nil {
 			requestParams.FromName = fromRes.Name
@@ -797,6 +964,26 @@ func (o *statOptions) validate(resourceType string) error {
 		}
 	}
 
+	err = o.validateProtocol(resourceType)
+	if err != nil {
+		return err
+	}
+
+	err = o.validateAnomalyThreshold()
+	if err != nil {
+		return err
+	}
+
+	err = o.validateByClient(resourceType)
+	if err != nil {
+		return err
+	}
+
+	err = o.validateOverlays()
+	if err != nil {
+		return err
+	}
+
 	return o.validateOutputFormat()
 }
 
@@ -818,6 +1005,74 @@ func (o *statOptions) validateConflictingFlags() error {
 	return nil
 }
 
+// validateProtocol validates the --protocol flag. TCP stats aren't collected
+// for authorities and traffic splits.
+func (o *statOptions) validateProtocol(resourceType string) error {
+	switch o.protocol {
+	case protocolHTTP:
+		return nil
+	case protocolTCP:
+		if resourceType == k8s.Authority || resourceType == k8s.TrafficSplit {
+			return fmt.Errorf("--protocol %s is not supported for %s resources", protocolTCP, resourceType)
+		}
+		return nil
+	default:
+		return fmt.Errorf("--protocol currently only supports %s and %s", protocolHTTP, protocolTCP)
+	}
+}
+
+// validateAnomalyThreshold validates the --anomaly-threshold flag. The
+// comparison with the preceding time window is only made for HTTP stats.
+func (o *statOptions) validateAnomalyThreshold() error {
+	if o.anomalyThreshold < 0 {
+		return fmt.Errorf("--anomaly-threshold must be positive")
+	}
+	if o.anomalyThreshold > 0 && o.protocol == protocolTCP {
+		return fmt.Errorf("--anomaly-threshold is not supported with --protocol %s", protocolTCP)
+	}
+	return nil
+}
+
+// validateByClient validates the --by-client flag. Client identities are
+// only recorded for the HTTP traffic received by the pods of a resource.
+func (o *statOptions) validateByClient(resourceType string) error {
+	if !o.byClient {
+		return nil
+	}
+	if o.toResource != "" || o.fromResource != "" {
+		return fmt.Errorf("--by-client is not supported with --to or --from")
+	}
+	if o.protocol == protocolTCP {
+		return fmt.Errorf("--by-client is not supported with --protocol %s", protocolTCP)
+	}
+	if o.anomalyThreshold > 0 {
+		return fmt.Errorf("--by-client and --anomaly-threshold flags are mutually exclusive")
+	}
+	if resourceType == k8s.Authority || resourceType == k8s.TrafficSplit {
+		return fmt.Errorf("--by-client is not supported for %s resources", resourceType)
+	}
+	return nil
+}
+
+// validateOverlays validates the --deployment-markers and
+// --success-rate-objective flags. Their hints are only shown along with the
+// HTTP stats of each resource.
+func (o *statOptions) validateOverlays() error {
+	if o.successRateObjective < 0 || o.successRateObjective >= 1 {
+		return fmt.Errorf("--success-rate-objective must be between 0 and 1")
+	}
+	if !o.deploymentMarkers && o.successRateObjective == 0 {
+		return nil
+	}
+	if o.protocol == protocolTCP {
+		return fmt.Errorf("--deployment-markers and --success-rate-objective are not supported with --protocol %s", protocolTCP)
+	}
+	if o.byClient {
+		return fmt.Errorf("--deployment-markers and --success-rate-objective are not supported with --by-client")
+	}
+	return nil
+}
+
 // validateNamespaceFlags performs additional validation for options when the target
 // resource type is a namespace.
 func (o *statOptions) validateNamespaceFlags() error {
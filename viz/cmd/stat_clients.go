@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/linkerd/linkerd2/pkg/k8s"
+	pb "github.com/linkerd/linkerd2/viz/metrics-api/gen/viz"
+	log "github.com/sirupsen/logrus"
+)
+
+// clientRow holds the inbound stats of a resource restricted to the requests
+// of one of its clients.
+type clientRow struct {
+	namespace string
+	name      string
+	client    string
+	*rowStats
+}
+
+type jsonClientStats struct {
+	Namespace    string  `json:"namespace"`
+	Kind         string  `json:"kind"`
+	Name         string  `json:"name"`
+	ClientID     string  `json:"client_id"`
+	Success      float64 `json:"success"`
+	Rps          float64 `json:"rps"`
+	LatencyMSp50 uint64  `json:"latency_ms_p50"`
+	LatencyMSp95 uint64  `json:"latency_ms_p95"`
+	LatencyMSp99 uint64  `json:"latency_ms_p99"`
+}
+
+// writeClientStatsToBuffer writes the stats broken down by client, listing
+// the clients of each resource from the busiest to the quietest.
+func writeClientStatsToBuffer(rows []*pb.StatTable_PodGroup_Row, w *tabwriter.Writer, options *statOptions) {
+	clientTables := make(map[string][]*clientRow)
+	for _, r := range rows {
+		if !statHasRequestData(r.Stats) {
+			continue
+		}
+		resourceType := r.Resource.Type
+		clientTables[resourceType] = append(clientTables[resourceType], &clientRow{
+			namespace: r.Resource.Namespace,
+			name:      r.Resource.Name,
+			client:    r.ClientId,
+			rowStats: &rowStats{
+				requestRate: getRequestRate(r.Stats.GetSuccessCount(), r.Stats.GetFailureCount(), r.TimeWindow),
+				successRate: getSuccessRate(r.Stats.GetSuccessCount(), r.Stats.GetFailureCount()),
+				latencyP50:  r.Stats.LatencyMsP50,
+				latencyP95:  r.Stats.LatencyMsP95,
+				latencyP99:  r.Stats.LatencyMsP99,
+			},
+		})
+	}
+	for _, clientRows := range clientTables {
+		sortClientRows(clientRows)
+	}
+
+	switch options.outputFormat {
+	case tableOutput, wideOutput:
+		if len(clientTables) == 0 {
+			fmt.Fprintln(os.Stderr, "No traffic found.")
+			return
+		}
+		printClientStatTables(clientTables, w, options)
+	case jsonOutput:
+		printClientStatJSON(clientTables, w)
+	}
+}
+
+// sortClientRows sorts the rows by resource, and the clients of a resource
+// by decreasing request rate.
+func sortClientRows(rows []*clientRow) {
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].namespace != rows[j].namespace {
+			return rows[i].namespace < rows[j].namespace
+		}
+		if rows[i].name != rows[j].name {
+			return rows[i].name < rows[j].name
+		}
+		if rows[i].requestRate != rows[j].requestRate {
+			return rows[i].requestRate > rows[j].requestRate
+		}
+		return rows[i].client < rows[j].client
+	})
+}
+
+func printClientStatTables(clientTables map[string][]*clientRow, w *tabwriter.Writer, options *statOptions) {
+	usePrefix := len(clientTables) > 1
+
+	// the first columns are left-aligned by padding them to their widest
+	// value, as the tabwriter aligns the columns to the right
+	maxNamespaceLength := len(namespaceHeader)
+	maxNameLength := len(nameHeader)
+	maxClientLength := len(clientHeader)
+	for resourceType, clientRows := range clientTables {
+		prefix := ""
+		if usePrefix {
+			prefix = getNamePrefix(resourceType)
+		}
+		for _, r := range clientRows {
+			if len(r.namespace) > maxNamespaceLength {
+				maxNamespaceLength = len(r.namespace)
+			}
+			if len(prefix+r.name) > maxNameLength {
+				maxNameLength = len(prefix + r.name)
+			}
+			if client := displayClientID(r.client, options.outputFormat); len(client) > maxClientLength {
+				maxClientLength = len(client)
+			}
+		}
+	}
+	namespaceTemplate := fmt.Sprintf("%%-%ds", maxNamespaceLength)
+	nameTemplate := fmt.Sprintf("%%-%ds", maxNameLength)
+	clientTemplate := fmt.Sprintf("%%-%ds", maxClientLength)
+
+	firstDisplayedStat := true // don't print a newline before the first stat
+	for _, resourceType := range k8s.AllResources {
+		clientRows, ok := clientTables[resourceType]
+		if !ok {
+			continue
+		}
+		if !firstDisplayedStat {
+			fmt.Fprint(w, "\n")
+		}
+		firstDisplayedStat = false
+
+		prefix := ""
+		if usePrefix {
+			prefix = getNamePrefix(resourceType)
+		}
+
+		headers := []string{fmt.Sprintf(nameTemplate, nameHeader), fmt.Sprintf(clientTemplate, clientHeader), "SUCCESS", "RPS", "LATENCY_P50", "LATENCY_P95", "LATENCY_P99\t"}
+		if options.allNamespaces {
+			headers = append([]string{fmt.Sprintf(namespaceTemplate, namespaceHeader)}, headers...)
+		}
+		fmt.Fprintln(w, strings.Join(headers, "\t"))
+
+		for _, r := range clientRows {
+			values := []interface{}{
+				fmt.Sprintf(nameTemplate, prefix+r.name),
+				fmt.Sprintf(clientTemplate, displayClientID(r.client, options.outputFormat)),
+				r.successRate * 100,
+				r.requestRate,
+				r.latencyP50,
+				r.latencyP95,
+				r.latencyP99,
+			}
+			templateString := "%s\t%s\t%.2f%%\t%.1frps\t%dms\t%dms\t%dms\t\n"
+			if options.allNamespaces {
+				values = append([]interface{}{fmt.Sprintf(namespaceTemplate, r.namespace)}, values...)
+				templateString = "%s\t" + templateString
+			}
+			fmt.Fprintf(w, templateString, values...)
+		}
+	}
+}
+
+// displayClientID shortens a client identity to its service account and
+// namespace, unless the wide output is requested. Clients without an
+// identity, e.g. unmeshed ones, are displayed as "-".
+func displayClientID(clientID, outputFormat string) string {
+	if clientID == "" {
+		return "-"
+	}
+	if outputFormat == wideOutput {
+		return clientID
+	}
+	parts := strings.Split(clientID, ".")
+	if len(parts) < 2 {
+		return clientID
+	}
+	return parts[0] + "." + parts[1]
+}
+
+func printClientStatJSON(clientTables map[string][]*clientRow, w *tabwriter.Writer) {
+	// avoid nil initialization so that if there are not stats it gets marshalled as an empty array vs null
+	entries := []*jsonClientStats{}
+	for _, resourceType := range k8s.AllResources {
+		for _, r := range clientTables[resourceType] {
+			entries = append(entries, &jsonClientStats{
+				Namespace:    r.namespace,
+				Kind:         resourceType,
+				Name:         r.name,
+				ClientID:     r.client,
+				Success:      r.successRate,
+				Rps:          r.requestRate,
+				LatencyMSp50: r.latencyP50,
+				LatencyMSp95: r.latencyP95,
+				LatencyMSp99: r.latencyP99,
+			})
+		}
+	}
+	b, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		log.Error(err.Error())
+		return
+	}
+	fmt.Fprintf(w, "%s\n", b)
+}
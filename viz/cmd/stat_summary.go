@@ -0,0 +1,315 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	pkgcmd "github.com/linkerd/linkerd2/pkg/cmd"
+	"github.com/linkerd/linkerd2/pkg/healthcheck"
+	pb "github.com/linkerd/linkerd2/viz/metrics-api/gen/viz"
+	"github.com/linkerd/linkerd2/viz/pkg/api"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+const (
+	subresourceWorkload = "workload"
+	subresourceRoute    = "route"
+	subresourceEdge     = "edge"
+	subresourceGateway  = "gateway"
+)
+
+// statSummaryFlags lists the flags that apply to each subresource of the
+// stat-summary command. Setting a flag that doesn't apply is an error rather
+// than being silently ignored.
+var statSummaryFlags = map[string][]string{
+	subresourceWorkload: {"namespace", "all-namespaces", "time-window", "output", "selector", "to", "to-namespace", "from", "from-namespace"},
+	subresourceRoute:    {"namespace", "time-window", "output", "selector", "to", "to-namespace"},
+	subresourceEdge:     {"namespace", "all-namespaces", "output"},
+	subresourceGateway:  {"time-window", "output", "cluster-name", "gateway-namespace"},
+}
+
+type statSummaryOptions struct {
+	statOptionsBase
+	allNamespaces    bool
+	labelSelector    string
+	toResource       string
+	toNamespace      string
+	fromResource     string
+	fromNamespace    string
+	clusterName      string
+	gatewayNamespace string
+}
+
+func newStatSummaryOptions() *statSummaryOptions {
+	return &statSummaryOptions{
+		statOptionsBase: *newStatOptionsBase(),
+	}
+}
+
+// newCmdStatSummary creates a new cobra command `stat-summary`, which exposes
+// the stats of workloads, routes, edges and gateways with a single set of
+// flags.
+func newCmdStatSummary() *cobra.Command {
+	options := newStatSummaryOptions()
+
+	cmd := &cobra.Command{
+		Use:   "stat-summary [flags] (SUBRESOURCE) [RESOURCES]",
+		Short: "Display the stats of workloads, routes, edges or gateways",
+		Long: `Display the stats of workloads, routes, edges or gateways.
+
+  The SUBRESOURCE argument selects the stats to display:
+  * workload: traffic stats about one or many resources, as displayed by "linkerd viz stat"
+  * route: the stats of the routes of a resource, as displayed by "linkerd viz routes"
+  * edge: the connections between resources of a type, as displayed by "linkerd viz edges"
+  * gateway: the stats of the multicluster gateways, as displayed by "linkerd multicluster gateways"
+
+  All subresources share the same flags. Flags that don't apply to the selected
+  subresource are rejected.`,
+		Example: `  # Get all deployments in the test namespace.
+  linkerd viz stat-summary workload deploy -n test
+
+  # Routes for calls from the traffic deployment to the webapp service in the test namespace.
+  linkerd viz stat-summary route deploy/traffic -n test --to svc/webapp
+
+  # Get all edges between pods in all namespaces.
+  linkerd viz stat-summary edge po --all-namespaces
+
+  # Get the stats of the gateway of the east cluster, as JSON.
+  linkerd viz stat-summary gateway --cluster-name east -o json`,
+		Args:      cobra.MinimumNArgs(1),
+		ValidArgs: []string{subresourceWorkload, subresourceRoute, subresourceEdge, subresourceGateway},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := validateStatSummaryFlags(cmd.LocalFlags(), args[0]); err != nil {
+				return err
+			}
+			if options.namespace == "" {
+				options.namespace = pkgcmd.GetDefaultNamespace(kubeconfigPath, kubeContext)
+			}
+
+			client := api.CheckClientOrExit(healthcheck.Options{
+				ControlPlaneNamespace: controlPlaneNamespace,
+				KubeConfig:            kubeconfigPath,
+				Impersonate:           impersonate,
+				ImpersonateGroup:      impersonateGroup,
+				KubeContext:           kubeContext,
+				APIAddr:               apiAddr,
+			})
+
+			output, err := requestStatSummaryFromAPI(client, args[0], args[1:], options)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+
+			_, err = fmt.Print(output)
+			return err
+		},
+	}
+
+	cmd.Flags().StringVarP(&options.namespace, "namespace", "n", options.namespace, "Namespace of the specified resource")
+	cmd.Flags().BoolVarP(&options.allNamespaces, "all-namespaces", "A", options.allNamespaces, "If present, returns stats across all namespaces, ignoring the \"--namespace\" flag")
+	cmd.Flags().StringVarP(&options.timeWindow, "time-window", "t", options.timeWindow, "Stat window (for example: \"15s\", \"1m\", \"10m\", \"1h\"). Needs to be at least 15s.")
+	cmd.Flags().StringVarP(&options.outputFormat, "output", "o", options.outputFormat, "Output format; one of: \"table\" or \"json\" or \"wide\"")
+	cmd.Flags().StringVarP(&options.labelSelector, "selector", "l", options.labelSelector, "Selector (label query) to filter on, supports '=', '==', and '!='")
+	cmd.Flags().StringVar(&options.toResource, "to", options.toResource, "If present, restricts outbound stats to the specified resource name")
+	cmd.Flags().StringVar(&options.toNamespace, "to-namespace", options.toNamespace, "Sets the namespace used to lookup the \"--to\" resource; by default the current \"--namespace\" is used")
+	cmd.Flags().StringVar(&options.fromResource, "from", options.fromResource, "If present, restricts outbound stats from the specified resource name")
+	cmd.Flags().StringVar(&options.fromNamespace, "from-namespace", options.fromNamespace, "Sets the namespace used from lookup the \"--from\" resource; by default the current \"--namespace\" is used")
+	cmd.Flags().StringVar(&options.clusterName, "cluster-name", options.clusterName, "The name of the target cluster of the gateways")
+	cmd.Flags().StringVar(&options.gatewayNamespace, "gateway-namespace", options.gatewayNamespace, "The namespace in which the gateways reside on the target cluster")
+
+	pkgcmd.ConfigureNamespaceFlagCompletion(cmd, []string{"namespace", "to-namespace", "from-namespace"})
+	return cmd
+}
+
+// validateStatSummaryFlags ensures that the subresource is known and that
+// only the flags that apply to it have been set.
+func validateStatSummaryFlags(flags *pflag.FlagSet, subresource string) error {
+	supported, ok := statSummaryFlags[subresource]
+	if !ok {
+		return fmt.Errorf("unknown subresource %q; must be one of: %s, %s, %s or %s", subresource, subresourceWorkload, subresourceRoute, subresourceEdge, subresourceGateway)
+	}
+
+	var unsupported []string
+	flags.Visit(func(f *pflag.Flag) {
+		for _, name := range supported {
+			if f.Name == name {
+				return
+			}
+		}
+		unsupported = append(unsupported, "--"+f.Name)
+	})
+	if len(unsupported) > 0 {
+		sort.Strings(unsupported)
+		return fmt.Errorf("%s cannot be used with the %s subresource", strings.Join(unsupported, ", "), subresource)
+	}
+	return nil
+}
+
+// requestStatSummaryFromAPI fetches the stats of the given subresource and
+// renders them according to the options.
+func requestStatSummaryFromAPI(client pb.ApiClient, subresource string, resources []string, options *statSummaryOptions) (string, error) {
+	switch subresource {
+	case subresourceWorkload:
+		if len(resources) == 0 {
+			return "", errors.New("the workload subresource requires at least one resource")
+		}
+		statOpts := &statOptions{
+			statOptionsBase: options.statOptionsBase,
+			toNamespace:     options.toNamespace,
+			toResource:      options.toResource,
+			fromNamespace:   options.fromNamespace,
+			fromResource:    options.fromResource,
+			allNamespaces:   options.allNamespaces,
+			labelSelector:   options.labelSelector,
+			protocol:        protocolHTTP,
+		}
+		reqs, err := buildStatSummaryRequests(resources, statOpts)
+		if err != nil {
+			return "", err
+		}
+		rows, err := fetchStats(client, reqs)
+		if err != nil {
+			return "", err
+		}
+		return renderStatStats(rows, statOpts), nil
+
+	case subresourceRoute:
+		if len(resources) != 1 {
+			return "", errors.New("the route subresource requires exactly one resource")
+		}
+		routesOpts := &routesOptions{
+			namespace:       options.namespace,
+			statOptionsBase: options.statOptionsBase,
+			toResource:      options.toResource,
+			toNamespace:     options.toNamespace,
+			labelSelector:   options.labelSelector,
+		}
+		req, err := buildTopRoutesRequest(resources[0], routesOpts)
+		if err != nil {
+			return "", err
+		}
+		return requestRouteStatsFromAPI(client, req, routesOpts)
+
+	case subresourceEdge:
+		if len(resources) != 1 {
+			return "", errors.New("the edge subresource requires exactly one resource type")
+		}
+		edgesOpts := &edgesOptions{
+			namespace:     options.namespace,
+			outputFormat:  options.outputFormat,
+			allNamespaces: options.allNamespaces,
+		}
+		reqs, err := buildEdgesRequests(resources, edgesOpts)
+		if err != nil {
+			return "", err
+		}
+		rows, err := fetchEdges(client, reqs)
+		if err != nil {
+			return "", err
+		}
+		return renderEdgeStats(rows, edgesOpts), nil
+
+	case subresourceGateway:
+		if len(resources) != 0 {
+			return "", errors.New("the gateway subresource doesn't take any resource")
+		}
+		if err := options.validateOutputFormat(); err != nil {
+			return "", err
+		}
+		resp, err := client.Gateways(context.Background(), &pb.GatewaysRequest{
+			RemoteClusterName: options.clusterName,
+			GatewayNamespace:  options.gatewayNamespace,
+			TimeWindow:        options.timeWindow,
+		})
+		if err != nil {
+			return "", fmt.Errorf("Gateways API error: %v", err)
+		}
+		if e := resp.GetError(); e != nil {
+			return "", fmt.Errorf("Gateways API response error: %v", e.Error)
+		}
+		return renderGatewayStats(resp.GetOk().GetGatewaysTable().GetRows(), options), nil
+
+	default:
+		return "", fmt.Errorf("unknown subresource %q", subresource)
+	}
+}
+
+type gatewayJSONStats struct {
+	ClusterName    string  `json:"cluster"`
+	Namespace      string  `json:"namespace"`
+	Name           string  `json:"name"`
+	Alive          bool    `json:"alive"`
+	PairedServices uint64  `json:"num_svc"`
+	LatencyMsP50   *uint64 `json:"latency_ms_p50"`
+	LatencyMsP95   *uint64 `json:"latency_ms_p95"`
+	LatencyMsP99   *uint64 `json:"latency_ms_p99"`
+}
+
+func renderGatewayStats(rows []*pb.GatewaysTable_Row, options *statSummaryOptions) string {
+	var buffer bytes.Buffer
+	w := tabwriter.NewWriter(&buffer, 0, 0, padding, ' ', tabwriter.AlignRight)
+
+	if options.outputFormat == jsonOutput {
+		entries := []*gatewayJSONStats{}
+		for _, row := range rows {
+			entry := &gatewayJSONStats{
+				ClusterName:    row.ClusterName,
+				Namespace:      row.Namespace,
+				Name:           row.Name,
+				Alive:          row.Alive,
+				PairedServices: row.PairedServices,
+			}
+			if row.Alive {
+				entry.LatencyMsP50 = &row.LatencyMsP50
+				entry.LatencyMsP95 = &row.LatencyMsP95
+				entry.LatencyMsP99 = &row.LatencyMsP99
+			}
+			entries = append(entries, entry)
+		}
+		b, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return fmt.Sprintf("Error marshalling JSON: %s\n", err)
+		}
+		fmt.Fprintf(w, "%s\n", b)
+		w.Flush()
+		return renderStats(buffer, &options.statOptionsBase)
+	}
+
+	if len(rows) == 0 {
+		return "No gateways found.\n"
+	}
+
+	// pad the cluster names so that the first column is left-aligned
+	maxClusterLength := len("CLUSTER")
+	for _, row := range rows {
+		if len(row.ClusterName) > maxClusterLength {
+			maxClusterLength = len(row.ClusterName)
+		}
+	}
+
+	fmt.Fprintln(w, strings.Join([]string{fmt.Sprintf("%-*s", maxClusterLength, "CLUSTER"), "ALIVE", "NUM_SVC", "LATENCY_P50", "LATENCY_P95", "LATENCY_P99\t"}, "\t"))
+	for _, row := range rows {
+		alive := "False"
+		latencies := []string{"-", "-", "-"}
+		if row.Alive {
+			alive = "True"
+			latencies = []string{
+				fmt.Sprintf("%dms", row.LatencyMsP50),
+				fmt.Sprintf("%dms", row.LatencyMsP95),
+				fmt.Sprintf("%dms", row.LatencyMsP99),
+			}
+		}
+		fmt.Fprintf(w, "%-*s\t%s\t%d\t%s\t%s\t%s\t\n", maxClusterLength, row.ClusterName, alive, row.PairedServices, latencies[0], latencies[1], latencies[2])
+	}
+	w.Flush()
+	return renderStats(buffer, &options.statOptionsBase)
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/linkerd/linkerd2/pkg/k8s"
+	api "github.com/linkerd/linkerd2/viz/metrics-api"
+	pb "github.com/linkerd/linkerd2/viz/metrics-api/gen/viz"
+)
+
+func TestStatSummary(t *testing.T) {
+	t.Run("Returns workload stats", func(t *testing.T) {
+		mockClient := &api.MockAPIClient{
+			StatSummaryResponseToReturn: api.GenStatSummaryResponse("emoji", k8s.Namespace, []string{"emojivoto1"}, &api.PodCounts{
+				MeshedPods:  1,
+				RunningPods: 2,
+			}, true, true),
+		}
+		options := newStatSummaryOptions()
+		options.namespace = "emojivoto1"
+
+		output, err := requestStatSummaryFromAPI(mockClient, subresourceWorkload, []string{"ns"}, options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		testDataDiffer.DiffTestdata(t, "stat_one_output.golden", output)
+	})
+
+	t.Run("Returns route stats", func(t *testing.T) {
+		mockClient := &api.MockAPIClient{
+			TopRoutesResponseToReturn: api.GenTopRoutesResponse([]string{"/a", "/b", "/c"}, []uint64{90, 60, 0, 30}, false, "foobar"),
+		}
+		options := newStatSummaryOptions()
+
+		output, err := requestStatSummaryFromAPI(mockClient, subresourceRoute, []string{"deploy/foobar"}, options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		testDataDiffer.DiffTestdata(t, "routes_one_output.golden", output)
+	})
+
+	t.Run("Returns edges", func(t *testing.T) {
+		mockClient := &api.MockAPIClient{
+			EdgesResponseToReturn: api.GenEdgesResponse("deployment", "all"),
+		}
+		options := newStatSummaryOptions()
+		options.allNamespaces = true
+
+		output, err := requestStatSummaryFromAPI(mockClient, subresourceEdge, []string{"deployment"}, options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		testDataDiffer.DiffTestdata(t, "edges_one_output.golden", output)
+	})
+
+	t.Run("Returns gateway stats", func(t *testing.T) {
+		mockClient := &api.MockAPIClient{
+			GatewaysResponseToReturn: &pb.GatewaysResponse{
+				Response: &pb.GatewaysResponse_Ok_{
+					Ok: &pb.GatewaysResponse_Ok{
+						GatewaysTable: &pb.GatewaysTable{
+							Rows: []*pb.GatewaysTable_Row{
+								{ClusterName: "east", Alive: true, PairedServices: 3, LatencyMsP50: 1, LatencyMsP95: 2, LatencyMsP99: 3},
+								{ClusterName: "west", Alive: false, PairedServices: 1},
+							},
+						},
+					},
+				},
+			},
+		}
+		options := newStatSummaryOptions()
+
+		output, err := requestStatSummaryFromAPI(mockClient, subresourceGateway, nil, options)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		expected := `CLUSTER   ALIVE   NUM_SVC   LATENCY_P50   LATENCY_P95   LATENCY_P99
+east       True         3           1ms           2ms           3ms
+west      False         1             -             -             -
+`
+		if output != expected {
+			t.Fatalf("Expected output:\n%s\ngot:\n%s", expected, output)
+		}
+	})
+
+	t.Run("Rejects invalid resources", func(t *testing.T) {
+		testCases := []struct {
+			subresource string
+			resources   []string
+		}{
+			{subresourceWorkload, nil},
+			{subresourceRoute, []string{"deploy/a", "deploy/b"}},
+			{subresourceEdge, nil},
+			{subresourceGateway, []string{"deploy/a"}},
+			{"unknown", nil},
+		}
+		for _, tc := range testCases {
+			tc := tc // pin
+			t.Run(tc.subresource, func(t *testing.T) {
+				_, err := requestStatSummaryFromAPI(&api.MockAPIClient{}, tc.subresource, tc.resources, newStatSummaryOptions())
+				if err == nil {
+					t.Fatal("Expected an error, got none")
+				}
+			})
+		}
+	})
+}
+
+func TestValidateStatSummaryFlags(t *testing.T) {
+	testCases := []struct {
+		subresource string
+		args        []string
+		valid       bool
+	}{
+		{subresourceWorkload, []string{"--from", "deploy/web", "-A"}, true},
+		{subresourceWorkload, []string{"--cluster-name", "east"}, false},
+		{subresourceRoute, []string{"--to", "svc/web", "-t", "10m"}, true},
+		{subresourceRoute, []string{"--all-namespaces"}, false},
+		{subresourceEdge, []string{"-o", "json"}, true},
+		{subresourceEdge, []string{"--time-window", "10m"}, false},
+		{subresourceGateway, []string{"--cluster-name", "east"}, true},
+		{subresourceGateway, []string{"-n", "test"}, false},
+		{"unknown", nil, false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // pin
+		t.Run(tc.subresource, func(t *testing.T) {
+			cmd := newCmdStatSummary()
+			if err := cmd.ParseFlags(tc.args); err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			err := validateStatSummaryFlags(cmd.LocalFlags(), tc.subresource)
+			if tc.valid && err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if !tc.valid && err == nil {
+				t.Fatalf("Expected %v to be rejected for %s", tc.args, tc.subresource)
+			}
+		})
+	}
+}
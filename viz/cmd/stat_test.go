@@ -1,11 +1,14 @@
 package cmd
 
 import (
+	"reflect"
 	"testing"
+	"time"
 
 	pkgcmd "github.com/linkerd/linkerd2/pkg/cmd"
 	"github.com/linkerd/linkerd2/pkg/k8s"
 	api "github.com/linkerd/linkerd2/viz/metrics-api"
+	pb "github.com/linkerd/linkerd2/viz/metrics-api/gen/viz"
 )
 
 type paramsExp struct {
@@ -118,6 +121,36 @@ func TestStat(t *testing.T) {
 		}, k8s.Namespace, t)
 	})
 
+	options = newStatOptions()
+	options.protocol = protocolTCP
+	t.Run("Returns TCP stats with --protocol tcp", func(t *testing.T) {
+		testStatCall(paramsExp{
+			counts: &api.PodCounts{
+				MeshedPods:  1,
+				RunningPods: 2,
+				FailedPods:  0,
+			},
+			options: options,
+			resNs:   []string{"emojivoto1"},
+			file:    "stat_one_tcp_protocol_output.golden",
+		}, k8s.Namespace, t)
+	})
+
+	t.Run("Rejects --protocol tcp for authorities", func(t *testing.T) {
+		options := newStatOptions()
+		if options.namespace == "" {
+			options.namespace = pkgcmd.GetDefaultNamespace(kubeconfigPath, kubeContext)
+		}
+		options.protocol = protocolTCP
+		args := []string{"au"}
+		expectedError := "--protocol tcp is not supported for authority resources"
+
+		_, err := buildStatSummaryRequests(args, options)
+		if err == nil || err.Error() != expectedError {
+			t.Fatalf("Expected error [%s] instead got [%s]", expectedError, err)
+		}
+	})
+
 	t.Run("Returns an error for named resource queries with the --all-namespaces flag", func(t *testing.T) {
 		options := newStatOptions()
 		options.allNamespaces = true
@@ -211,6 +244,22 @@ func TestStat(t *testing.T) {
 		}
 	})
 
+	t.Run("Rejects --by-client with --to", func(t *testing.T) {
+		options := newStatOptions()
+		if options.namespace == "" {
+			options.namespace = pkgcmd.GetDefaultNamespace(kubeconfigPath, kubeContext)
+		}
+		options.byClient = true
+		options.toResource = "deploy/web"
+		args := []string{"deploy/emoji"}
+		expectedError := "--by-client is not supported with --to or --from"
+
+		_, err := buildStatSummaryRequests(args, options)
+		if err == nil || err.Error() != expectedError {
+			t.Fatalf("Expected error [%s] instead got [%s]", expectedError, err)
+		}
+	})
+
 	t.Run("Returns an error if --time-window is not more than 15s", func(t *testing.T) {
 		options := newStatOptions()
 		if options.namespace == "" {
@@ -227,6 +276,130 @@ func TestStat(t *testing.T) {
 	})
 }
 
+func TestAnomalyHints(t *testing.T) {
+	baseline := &pb.BasicStats{SuccessCount: 100, FailureCount: 0, LatencyMsP99: 100}
+
+	testCases := []struct {
+		name     string
+		stats    *pb.BasicStats
+		baseline *pb.BasicStats
+		expected []string
+	}{
+		{
+			"Flags a success rate drop",
+			&pb.BasicStats{SuccessCount: 70, FailureCount: 30, LatencyMsP99: 110},
+			baseline,
+			[]string{"success -30%"},
+		},
+		{
+			"Flags a latency increase",
+			&pb.BasicStats{SuccessCount: 100, FailureCount: 0, LatencyMsP99: 250},
+			baseline,
+			[]string{"p99 +150%"},
+		},
+		{
+			"Ignores changes within the threshold",
+			&pb.BasicStats{SuccessCount: 90, FailureCount: 10, LatencyMsP99: 85},
+			baseline,
+			[]string{},
+		},
+		{
+			"Ignores resources without a baseline",
+			&pb.BasicStats{SuccessCount: 10, FailureCount: 90, LatencyMsP99: 1000},
+			nil,
+			nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			hints := anomalyHints(tc.stats, tc.baseline, 0.2)
+			if !reflect.DeepEqual(hints, tc.expected) {
+				t.Fatalf("Expected %v, got %v", tc.expected, hints)
+			}
+		})
+	}
+}
+
+func TestRenderClientStats(t *testing.T) {
+	clientRow := func(clientID string, success, failure, latency uint64) *pb.StatTable_PodGroup_Row {
+		return &pb.StatTable_PodGroup_Row{
+			Resource: &pb.Resource{
+				Namespace: "emojivoto",
+				Type:      k8s.Deployment,
+				Name:      "emoji",
+			},
+			TimeWindow: "1m",
+			Stats: &pb.BasicStats{
+				SuccessCount: success,
+				FailureCount: failure,
+				LatencyMsP50: latency,
+				LatencyMsP95: latency + 10,
+				LatencyMsP99: latency + 20,
+			},
+			ClientId: clientID,
+		}
+	}
+	rows := []*pb.StatTable_PodGroup_Row{
+		clientRow("", 6, 0, 1),
+		clientRow("vote-bot.emojivoto.serviceaccount.identity.linkerd.cluster.local", 54, 6, 5),
+		clientRow("web.emojivoto.serviceaccount.identity.linkerd.cluster.local", 120, 0, 10),
+	}
+	rows[0].Stats.LatencyMsP95 = 2
+	rows[0].Stats.LatencyMsP99 = 3
+
+	options := newStatOptions()
+	options.byClient = true
+	output := renderStatStats(rows, options)
+
+	testDataDiffer.DiffTestdata(t, "stat_by_client_output.golden", output)
+}
+
+func TestOverlayHints(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+
+	testCases := []struct {
+		name     string
+		row      *pb.StatTable_PodGroup_Row
+		expected []string
+	}{
+		{
+			"Describes the error budget left",
+			&pb.StatTable_PodGroup_Row{ErrorBudget: &pb.ErrorBudget{Remaining: 0.35}},
+			[]string{"budget 35% left"},
+		},
+		{
+			"Describes an exhausted error budget",
+			&pb.StatTable_PodGroup_Row{ErrorBudget: &pb.ErrorBudget{Remaining: -2}},
+			[]string{"budget exhausted"},
+		},
+		{
+			"Describes the rollouts from the oldest to the latest",
+			&pb.StatTable_PodGroup_Row{DeploymentMarkers: []*pb.DeploymentMarker{
+				{Timestamp: now.Add(-10 * time.Minute).Unix(), Revision: "3"},
+				{Timestamp: now.Add(-90 * time.Second).Unix()},
+			}},
+			[]string{"rollout 3 10m0s ago", "rollout 1m30s ago"},
+		},
+		{
+			"Describes nothing without overlays",
+			&pb.StatTable_PodGroup_Row{},
+			[]string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			hints := overlayHints(tc.row, now)
+			if !reflect.DeepEqual(hints, tc.expected) {
+				t.Fatalf("Expected %v, got %v", tc.expected, hints)
+			}
+		})
+	}
+}
+
 func testStatCall(exp paramsExp, resourceType string, t *testing.T) {
 	mockClient := &api.MockAPIClient{}
 	response := api.GenStatSummaryResponse("emoji", resourceType, exp.resNs, exp.counts, true, true)
@@ -8,6 +8,7 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/golang/protobuf/ptypes/duration"
 	netPb "github.com/linkerd/linkerd2/controller/gen/common/net"
@@ -38,6 +39,9 @@ type tapOptions struct {
 	path          string
 	output        string
 	labelSelector string
+	minLatency    time.Duration
+	incomplete    bool
+	requestID     string
 }
 
 type endpoint struct {
@@ -117,10 +121,16 @@ func newTapOptions() *tapOptions {
 		path:          "",
 		output:        "",
 		labelSelector: "",
+		minLatency:    0,
+		incomplete:    false,
 	}
 }
 
 func (o *tapOptions) validate() error {
+	if o.minLatency < 0 {
+		return fmt.Errorf("--min-latency must not be negative")
+	}
+
 	if o.output == "" || o.output == wideOutput || o.output == jsonOutput {
 		return nil
 	}
@@ -152,6 +162,7 @@ func NewCmdTap() *cobra.Command {
   * rs/my-replicaset
   * sts
   * sts/my-statefulset
+  * svc/my-service
 
   Valid resource types include:
   * cronjobs
@@ -162,16 +173,30 @@ func NewCmdTap() *cobra.Command {
   * pods
   * replicasets
   * replicationcontrollers
-  * statefulsets
-  * services (only supported as a --to resource)`,
+  * services
+  * statefulsets`,
 		Example: `  # tap the web deployment in the default namespace
   linkerd viz tap deploy/web
 
   # tap the web-dlbvj pod in the default namespace
   linkerd viz tap pod/web-dlbvj
 
+  # tap the web service in the default namespace
+  linkerd viz tap svc/web
+
   # tap the test namespace, filter by request to prod namespace
-  linkerd viz tap ns/test --to ns/prod`,
+  linkerd viz tap ns/test --to ns/prod
+
+  # tap the web deployment, only showing requests slower than 500ms
+  linkerd viz tap deploy/web --min-latency 500ms
+
+  # tap the web deployment, only showing requests that were reset or are still
+  # waiting for a response after 10s
+  linkerd viz tap deploy/web --incomplete --min-latency 10s
+
+  # tap the web deployment, only showing the request with a given x-request-id
+  # along with its headers
+  linkerd viz tap deploy/web --request-id 0f5e7b2c -o json`,
 		Args: cobra.RangeArgs(1, 2),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			// This command requires at most two arguments if we already have
@@ -223,7 +248,7 @@ func NewCmdTap() *cobra.Command {
 				Method:        options.method,
 				Authority:     options.authority,
 				Path:          options.path,
-				Extract:       options.output == jsonOutput,
+				Extract:       options.output == jsonOutput || options.requestID != "",
 				LabelSelector: options.labelSelector,
 			}
 
@@ -274,15 +299,24 @@ func NewCmdTap() *cobra.Command {
 		fmt.Sprintf("Output format. One of: \"%s\", \"%s\"", wideOutput, jsonOutput))
 	cmd.PersistentFlags().StringVarP(&options.labelSelector, "selector", "l", options.labelSelector,
 		"Selector (label query) to filter on, supports '=', '==', and '!='")
-
-	pkgcmd.ConfigureNamespaceFlagCompletion(
-		cmd, []string{"namespace", "to-namespace"},
-		kubeconfigPath, impersonate, impersonateGroup, kubeContext)
+	cmd.PersistentFlags().DurationVar(&options.minLatency, "min-latency", options.minLatency,
+		"Display requests whose response takes at least this long, or is still pending after this long (for example: \"500ms\")")
+	cmd.PersistentFlags().BoolVar(&options.incomplete, "incomplete", options.incomplete,
+		"Display requests whose stream was reset before completing")
+	cmd.PersistentFlags().StringVar(&options.requestID, "request-id", options.requestID,
+		fmt.Sprintf("Display the request whose %s header has this value", pkg.RequestIDHeader))
+
+	pkgcmd.ConfigureNamespaceFlagCompletion(cmd, []string{"namespace", "to-namespace"})
 	return cmd
 }
 
 func requestTapByResourceFromAPI(ctx context.Context, w io.Writer, k8sAPI *k8s.KubernetesAPI, req *tapPb.TapByResourceRequest, options *tapOptions) error {
-	reader, body, err := pkg.Reader(ctx, k8sAPI, req)
+	filter := &pkg.StreamFilter{
+		MinLatency: options.minLatency,
+		Incomplete: options.incomplete,
+		RequestID:  options.requestID,
+	}
+	reader, body, err := pkg.FilteredReader(ctx, k8sAPI, req, filter)
 	if err != nil {
 		return err
 	}
@@ -248,6 +248,18 @@ func TestRequestTapByResourceFromAPI(t *testing.T) {
 	})
 }
 
+func TestBuildTapByResourceRequestTargets(t *testing.T) {
+	for _, resource := range []string{"svc/web", "sts/web", "deploy/web"} {
+		req, err := pkg.BuildTapByResourceRequest(pkg.TapRequestParams{Resource: resource})
+		if err != nil {
+			t.Fatalf("Unexpected error building tap request for %s: %v", resource, err)
+		}
+		if req.GetTarget().GetResource().GetName() != "web" {
+			t.Fatalf("Expected target name web for %s, got %s", resource, req.GetTarget().GetResource().GetName())
+		}
+	}
+}
+
 func TestEventToString(t *testing.T) {
 	toTapEvent := func(httpEvent *tapPb.TapEvent_Http) *tapPb.TapEvent {
 		streamID := &tapPb.TapEvent_Http_StreamId{
@@ -302,6 +302,7 @@ func NewCmdTop() *cobra.Command {
   * rs/my-replicaset
   * sts
   * sts/my-statefulset
+  * svc/my-service
 
   Valid resource types include:
   * cronjobs
@@ -312,13 +313,16 @@ func NewCmdTop() *cobra.Command {
   * pods
   * replicasets
   * replicationcontrollers
-  * statefulsets
-  * services (only supported as a --to resource)`,
+  * services
+  * statefulsets`,
 		Example: `  # display traffic for the web deployment in the default namespace
   linkerd viz top deploy/web
 
   # display traffic for the web-dlbvj pod in the default namespace
-  linkerd viz top pod/web-dlbvj`,
+  linkerd viz top pod/web-dlbvj
+
+  # display traffic for the web service in the default namespace
+  linkerd viz top svc/web`,
 		Args: cobra.RangeArgs(1, 2),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			// This command requires at most two arguments if we already have
@@ -421,9 +425,7 @@ func NewCmdTop() *cobra.Command {
 	cmd.PersistentFlags().BoolVar(&options.routes, "routes", options.routes, "Display data per route instead of per path")
 	cmd.PersistentFlags().StringVarP(&options.labelSelector, "selector", "l", options.labelSelector, "Selector (label query) to filter on, supports '=', '==', and '!='")
 
-	pkgcmd.ConfigureNamespaceFlagCompletion(
-		cmd, []string{"namespace", "to-namespace"},
-		kubeconfigPath, impersonate, impersonateGroup, kubeContext)
+	pkgcmd.ConfigureNamespaceFlagCompletion(cmd, []string{"namespace", "to-namespace"})
 	return cmd
 }
 
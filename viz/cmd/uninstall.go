@@ -2,25 +2,43 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
 
 	pkgCmd "github.com/linkerd/linkerd2/pkg/cmd"
 	"github.com/linkerd/linkerd2/pkg/k8s"
 	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
 )
 
+type uninstallOptions struct {
+	force     bool
+	exportDir string
+}
+
 func newCmdUninstall() *cobra.Command {
+	options := uninstallOptions{}
+
 	cmd := &cobra.Command{
 		Use:   "uninstall",
 		Args:  cobra.NoArgs,
 		Short: "Output Kubernetes resources to uninstall the linkerd-viz extension",
 		Long: `Output Kubernetes resources to uninstall the linkerd-viz extension.
 
-This command provides all Kubernetes namespace-scoped and cluster-scoped resources (e.g services, deployments, RBACs, etc.) necessary to uninstall the Linkerd-viz extension.`,
-		Example: `linkerd viz uninstall | kubectl delete -f -`,
+This command provides all Kubernetes namespace-scoped and cluster-scoped resources (e.g services, deployments, RBACs, etc.) necessary to uninstall the Linkerd-viz extension.
+
+The metrics collected by the linkerd-viz Prometheus instance are deleted along with it. If they are stored in a persistent volume, this command fails unless --force is set. The Prometheus and Grafana configuration can be saved for a later reinstall with --export-dir.`,
+		Example: `  linkerd viz uninstall | kubectl delete -f -
+
+  # Save the Prometheus and Grafana configuration before uninstalling
+  linkerd viz uninstall --export-dir ./linkerd-viz-backup | kubectl delete -f -`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			err := uninstallRunE(cmd.Context())
+			err := uninstallRunE(cmd.Context(), options)
 			if err != nil {
 				fmt.Fprintln(os.Stderr, err)
 				os.Exit(1)
@@ -29,10 +47,13 @@ This command provides all Kubernetes namespace-scoped and cluster-scoped resourc
 		},
 	}
 
+	cmd.Flags().BoolVar(&options.force, "force", false, "Uninstall even if the metrics collected by Prometheus are stored in a persistent volume that would be deleted")
+	cmd.Flags().StringVar(&options.exportDir, "export-dir", "", "Directory to export the Prometheus and Grafana configuration to before uninstalling")
+
 	return cmd
 }
 
-func uninstallRunE(ctx context.Context) error {
+func uninstallRunE(ctx context.Context, options uninstallOptions) error {
 	k8sAPI, err := k8s.NewAPI(kubeconfigPath, kubeContext, impersonate, impersonateGroup, 0)
 	if err != nil {
 		return err
@@ -43,5 +64,74 @@ func uninstallRunE(ctx context.Context) error {
 		return err
 	}
 
-	return pkgCmd.Uninstall(ctx, k8sAPI, selector)
+	hooks := []pkgCmd.PreUninstallHook{}
+	if options.exportDir != "" {
+		hooks = append(hooks, exportConfigHook(options.exportDir))
+	}
+	hooks = append(hooks, prometheusDataHook(options.force))
+
+	return pkgCmd.Uninstall(ctx, k8sAPI, selector, hooks...)
+}
+
+// exportConfigHook writes the ConfigMaps of the extension, holding the
+// Prometheus configuration, recording rules and Grafana configuration, to
+// dir so that they can be applied again after a reinstall.
+func exportConfigHook(dir string) pkgCmd.PreUninstallHook {
+	return func(ctx context.Context, k8sAPI *k8s.KubernetesAPI, selector string) error {
+		configMaps, err := k8sAPI.CoreV1().ConfigMaps("").List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create export directory: %s", err)
+		}
+
+		for _, cm := range configMaps.Items {
+			cm.ObjectMeta = metav1.ObjectMeta{
+				Name:        cm.Name,
+				Namespace:   cm.Namespace,
+				Labels:      cm.Labels,
+				Annotations: cm.Annotations,
+			}
+			cm.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"}
+			out, err := yaml.Marshal(cm)
+			if err != nil {
+				return err
+			}
+			path := filepath.Join(dir, fmt.Sprintf("%s-%s.yaml", cm.Namespace, cm.Name))
+			if err := ioutil.WriteFile(path, out, 0644); err != nil {
+				return fmt.Errorf("failed to export ConfigMap %s/%s: %s", cm.Namespace, cm.Name, err)
+			}
+			fmt.Fprintf(os.Stderr, "Exported ConfigMap %s/%s to %s\n", cm.Namespace, cm.Name, path)
+		}
+		return nil
+	}
+}
+
+// prometheusDataHook prevents the metrics stored by Prometheus in a
+// persistent volume from being deleted, unless force is set. Metrics that
+// aren't persisted are deleted with a warning.
+func prometheusDataHook(force bool) pkgCmd.PreUninstallHook {
+	return func(ctx context.Context, k8sAPI *k8s.KubernetesAPI, selector string) error {
+		pvcs, err := k8sAPI.CoreV1().PersistentVolumeClaims("").List(ctx, metav1.ListOptions{LabelSelector: selector})
+		if err != nil {
+			return err
+		}
+
+		if len(pvcs.Items) == 0 {
+			fmt.Fprintln(os.Stderr, "Warning: the metrics collected by the linkerd-viz Prometheus instance will be deleted")
+			return nil
+		}
+
+		lines := []string{"The metrics collected by the linkerd-viz Prometheus instance are stored in the following persistent volume claims, which will be deleted:"}
+		for _, pvc := range pvcs.Items {
+			lines = append(lines, fmt.Sprintf("  * %s/%s", pvc.Namespace, pvc.Name))
+		}
+		if !force {
+			lines = append(lines, "Snapshot the data you want to keep, then use --force to uninstall")
+			return errors.New(strings.Join(lines, "\n"))
+		}
+		fmt.Fprintln(os.Stderr, "Warning: "+strings.Join(lines, "\n"))
+		return nil
+	}
 }
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/linkerd/linkerd2/pkg/k8s"
+)
+
+const uninstallSelector = "linkerd.io/extension=viz"
+
+func TestPrometheusDataHook(t *testing.T) {
+	pvc := `
+kind: PersistentVolumeClaim
+apiVersion: v1
+metadata:
+  name: prometheus
+  namespace: linkerd-viz
+  labels:
+    linkerd.io/extension: viz
+`
+
+	testCases := []struct {
+		name        string
+		configs     []string
+		force       bool
+		expectedErr bool
+	}{
+		{"no persistent volume", nil, false, false},
+		{"persistent volume", []string{pvc}, false, true},
+		{"persistent volume with force", []string{pvc}, true, false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			k8sAPI, err := k8s.NewFakeAPI(tc.configs...)
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			err = prometheusDataHook(tc.force)(context.Background(), k8sAPI, uninstallSelector)
+			if tc.expectedErr != (err != nil) {
+				t.Fatalf("Expected error: %t, got: %v", tc.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestExportConfigHook(t *testing.T) {
+	k8sAPI, err := k8s.NewFakeAPI(`
+kind: ConfigMap
+apiVersion: v1
+metadata:
+  name: prometheus-config
+  namespace: linkerd-viz
+  resourceVersion: "42"
+  labels:
+    linkerd.io/extension: viz
+data:
+  prometheus.yml: |-
+    global:
+      scrape_interval: 10s
+`, `
+kind: ConfigMap
+apiVersion: v1
+metadata:
+  name: unrelated
+  namespace: linkerd-viz
+`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	dir, err := ioutil.TempDir("", "viz-export")
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := exportConfigHook(dir)(context.Background(), k8sAPI, uninstallSelector); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(files) != 1 || files[0].Name() != "linkerd-viz-prometheus-config.yaml" {
+		t.Fatalf("Expected only the prometheus-config ConfigMap to be exported, got %v", files)
+	}
+	exported, err := ioutil.ReadFile(filepath.Join(dir, files[0].Name()))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if !strings.Contains(string(exported), "scrape_interval: 10s") {
+		t.Fatalf("Expected the ConfigMap data to be exported, got:\n%s", exported)
+	}
+	if strings.Contains(string(exported), "resourceVersion") {
+		t.Fatalf("Expected the ConfigMap to be exported without its server-side metadata, got:\n%s", exported)
+	}
+}
@@ -13,8 +13,10 @@ import (
 	"github.com/linkerd/linkerd2/pkg/protohttp"
 	pb "github.com/linkerd/linkerd2/viz/metrics-api/gen/viz"
 	log "github.com/sirupsen/logrus"
+	"go.opencensus.io/plugin/ocgrpc"
 	"go.opencensus.io/plugin/ochttp"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 )
 
 const (
@@ -31,6 +33,20 @@ const (
 	apiDeployment = "metrics-api"
 )
 
+type tokenContextKey struct{}
+
+// WithToken returns a context whose API requests carry the given bearer token,
+// which authenticates their caller with a metrics API in tenancy mode. The
+// dashboard uses it to forward the token of its users.
+func WithToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenContextKey{}, token)
+}
+
+func tokenFromContext(ctx context.Context) string {
+	token, _ := ctx.Value(tokenContextKey{}).(string)
+	return token
+}
+
 type grpcOverHTTPClient struct {
 	serverURL  *url.URL
 	httpClient *http.Client
@@ -112,6 +128,11 @@ func (c *grpcOverHTTPClient) post(ctx context.Context, url *url.URL, req proto.M
 	if err != nil {
 		return nil, err
 	}
+	// A token in the context takes precedence over the one the transport of
+	// an external client adds from the kubeconfig
+	if token := tokenFromContext(ctx); token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
 
 	rsp, err := c.httpClient.Do(httpReq.WithContext(ctx))
 	if err != nil {
@@ -143,6 +164,30 @@ func newClient(apiURL *url.URL, httpClientToUse *http.Client, namespace string)
 	}, nil
 }
 
+// forwardToken is a gRPC interceptor sending the token of the context of a
+// request in its metadata.
+func forwardToken(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if token := tokenFromContext(ctx); token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// NewGrpcClient creates a client for the gRPC interface of the Viz API,
+// served on the given address.
+func NewGrpcClient(addr string) (pb.ApiClient, *grpc.ClientConn, error) {
+	conn, err := grpc.Dial(addr,
+		grpc.WithInsecure(),
+		grpc.WithStatsHandler(&ocgrpc.ClientHandler{}),
+		grpc.WithUnaryInterceptor(forwardToken),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pb.NewApiClient(conn), conn, nil
+}
+
 // NewInternalClient creates a new Viz API client intended to run inside a
 // Kubernetes cluster.
 func NewInternalClient(namespace string, kubeAPIHost string) (pb.ApiClient, error) {
@@ -180,6 +225,10 @@ func NewExternalClient(ctx context.Context, namespace string, kubeAPI *k8s.Kuber
 		return nil, err
 	}
 
+	// The transport of the client authenticates the requests like the
+	// requests to the Kubernetes API, which forwards the bearer token of the
+	// kubeconfig, be it static, read from a file, or issued by an exec plugin
+	// or an auth provider, to a metrics API in tenancy mode.
 	httpClientToUse, err := kubeAPI.NewClient()
 	if err != nil {
 		return nil, err
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/linkerd/linkerd2/pkg/k8s"
+	"github.com/linkerd/linkerd2/pkg/protohttp"
+	pb "github.com/linkerd/linkerd2/viz/metrics-api/gen/viz"
+	"k8s.io/client-go/rest"
+)
+
+func TestClientForwardsToken(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		authorization = req.Header.Get("Authorization")
+		if err := protohttp.WriteProtoToHTTPResponse(w, &pb.ListServicesResponse{}); err != nil {
+			t.Errorf("Unexpected error: %s", err)
+		}
+	}))
+	defer server.Close()
+	serverURL, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	for _, tc := range []struct {
+		name     string
+		config   *rest.Config
+		token    string
+		expected string
+	}{
+		{
+			name:   "no token",
+			config: &rest.Config{},
+		},
+		{
+			name:     "kubeconfig token",
+			config:   &rest.Config{BearerToken: "kubeconfig-token"},
+			expected: "Bearer kubeconfig-token",
+		},
+		{
+			name:     "forwarded token",
+			config:   &rest.Config{BearerToken: "kubeconfig-token"},
+			token:    "user-token",
+			expected: "Bearer user-token",
+		},
+	} {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			httpClient, err := (&k8s.KubernetesAPI{Config: tc.config}).NewClient()
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			apiClient, err := newClient(serverURL, httpClient, "linkerd-viz")
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+
+			ctx := context.Background()
+			if tc.token != "" {
+				ctx = WithToken(ctx, tc.token)
+			}
+			authorization = ""
+			if _, err := apiClient.ListServices(ctx, &pb.ListServicesRequest{}); err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if authorization != tc.expected {
+				t.Fatalf("Expected the Authorization header %q, got %q", tc.expected, authorization)
+			}
+		})
+	}
+}
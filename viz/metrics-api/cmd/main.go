@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"flag"
+	"net"
 	"os"
 	"os/signal"
 	"strings"
@@ -10,25 +11,33 @@ import (
 
 	"github.com/linkerd/linkerd2/controller/k8s"
 	"github.com/linkerd/linkerd2/pkg/admin"
+	"github.com/linkerd/linkerd2/pkg/drain"
 	"github.com/linkerd/linkerd2/pkg/flags"
+	pkgK8s "github.com/linkerd/linkerd2/pkg/k8s"
 	"github.com/linkerd/linkerd2/pkg/trace"
 	api "github.com/linkerd/linkerd2/viz/metrics-api"
 	promApi "github.com/prometheus/client_golang/api"
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"k8s.io/client-go/dynamic"
 )
 
 func main() {
 	cmd := flag.NewFlagSet("metrics-api", flag.ExitOnError)
 
 	addr := cmd.String("addr", ":8085", "address to serve on")
+	grpcAddr := cmd.String("grpc-addr", ":8086", "address to serve the gRPC API on (disabled if empty)")
 	kubeConfigPath := cmd.String("kubeconfig", "", "path to kube config")
 	prometheusURL := cmd.String("prometheus-url", "", "prometheus url")
 	metricsAddr := cmd.String("metrics-addr", ":9995", "address to serve scrapable metrics on")
 	controllerNamespace := cmd.String("controller-namespace", "linkerd", "namespace in which Linkerd is installed")
 	ignoredNamespaces := cmd.String("ignore-namespaces", "kube-system", "comma separated list of namespaces to not list pods from")
 	clusterDomain := cmd.String("cluster-domain", "cluster.local", "kubernetes cluster domain")
+	recordingRules := cmd.Bool("recording-rules", false, "expect the viz recording rules to be loaded in prometheus and report them in the self-check")
+	tenancy := cmd.Bool("tenancy", false, "restrict the queries to the namespaces in which the caller, authenticated by its bearer token, can list pods")
 
 	traceCollector := flags.AddTraceFlags(cmd)
+	drainConfig := drain.AddFlags(cmd)
 
 	flags.ConfigureAndParse(cmd, os.Args[1:])
 	ctx := context.Background()
@@ -45,6 +54,20 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to initialize K8s API: %s", err)
 	}
+	// the stat summaries look up the pods of each workload
+	if err := k8sAPI.AddIndexes(k8s.PodOwner); err != nil {
+		log.Fatalf("Failed to add indexes: %s", err)
+	}
+
+	// the Links are read for the liveness of the multicluster gateways
+	config, err := pkgK8s.GetConfig(*kubeConfigPath, "")
+	if err != nil {
+		log.Fatalf("Failed to configure the Kubernetes API client: %s", err)
+	}
+	linkClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		log.Fatalf("Failed to initialize the Kubernetes dynamic client: %s", err)
+	}
 
 	var prometheusClient promApi.Client
 	if *prometheusURL != "" {
@@ -70,6 +93,32 @@ func main() {
 		*controllerNamespace,
 		*clusterDomain,
 		strings.Split(*ignoredNamespaces, ","),
+		*recordingRules,
+		*tenancy,
+		linkClient,
+	)
+
+	var grpcServer *grpc.Server
+	if *grpcAddr != "" {
+		grpcServer = api.NewGrpcAPIServer(
+			prometheusClient,
+			k8sAPI,
+			*controllerNamespace,
+			*clusterDomain,
+			strings.Split(*ignoredNamespaces, ","),
+			*recordingRules,
+			*tenancy,
+			linkClient,
+		)
+	}
+
+	reporter := api.NewHealthReporter(
+		prometheusClient,
+		k8sAPI,
+		*controllerNamespace,
+		*clusterDomain,
+		strings.Split(*ignoredNamespaces, ","),
+		*recordingRules,
 	)
 
 	k8sAPI.Sync(nil) // blocks until caches are synced
@@ -79,10 +128,25 @@ func main() {
 		server.ListenAndServe()
 	}()
 
-	go admin.StartServer(*metricsAddr)
+	if grpcServer != nil {
+		lis, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			log.Fatalf("Failed to listen on %s: %s", *grpcAddr, err)
+		}
+		go func() {
+			log.Infof("starting gRPC server on %+v", *grpcAddr)
+			grpcServer.Serve(lis)
+		}()
+	}
+
+	go admin.StartServerWithHealth(*metricsAddr, reporter)
 
 	<-stop
 
+	if grpcServer != nil {
+		log.Infof("shutting down gRPC server on %+v", *grpcAddr)
+		drainConfig.GracefulStop("gRPC server", grpcServer, nil)
+	}
 	log.Infof("shutting down HTTP server on %+v", *addr)
 	server.Shutdown(ctx)
 }
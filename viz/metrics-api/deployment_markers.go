@@ -0,0 +1,228 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	pkgK8s "github.com/linkerd/linkerd2/pkg/k8s"
+	pb "github.com/linkerd/linkerd2/viz/metrics-api/gen/viz"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	rolloutMarkerSource = "rollout"
+	webhookMarkerSource = "webhook"
+
+	// The markers recorded through the webhook are stored as Events on their
+	// resources, so that they're shared by the replicas of the metrics API
+	// and survive their restarts. They expire along with the other Events,
+	// after the --event-ttl of the API server, one hour by default.
+	markerEventReason        = "DeploymentMarker"
+	markerEventComponent     = "linkerd-metrics-api"
+	markerRevisionAnnotation = "viz.linkerd.io/revision"
+
+	revisionAnnotation    = "deployment.kubernetes.io/revision"
+	changeCauseAnnotation = "kubernetes.io/change-cause"
+)
+
+// markerKinds are the kinds of the resources deployment markers can be
+// recorded for.
+var markerKinds = map[string]schema.GroupVersionKind{
+	pkgK8s.CronJob:               {Group: "batch", Version: "v1beta1", Kind: "CronJob"},
+	pkgK8s.DaemonSet:             {Group: "apps", Version: "v1", Kind: "DaemonSet"},
+	pkgK8s.Deployment:            {Group: "apps", Version: "v1", Kind: "Deployment"},
+	pkgK8s.Job:                   {Group: "batch", Version: "v1", Kind: "Job"},
+	pkgK8s.Pod:                   {Version: "v1", Kind: "Pod"},
+	pkgK8s.ReplicaSet:            {Group: "apps", Version: "v1", Kind: "ReplicaSet"},
+	pkgK8s.ReplicationController: {Version: "v1", Kind: "ReplicationController"},
+	pkgK8s.StatefulSet:           {Group: "apps", Version: "v1", Kind: "StatefulSet"},
+}
+
+// markerRecorder is implemented by the servers recording the deployment
+// markers posted to the webhook.
+type markerRecorder interface {
+	recordDeploymentMarker(ctx context.Context, marker *pb.DeploymentMarker) error
+}
+
+// recordDeploymentMarker records the given marker as an Event on its
+// resource.
+func (s *grpcServer) recordDeploymentMarker(ctx context.Context, marker *pb.DeploymentMarker) error {
+	r := marker.GetResource()
+	gvk := markerKinds[r.GetType()]
+	timestamp := metav1.NewTime(time.Unix(marker.GetTimestamp(), 0))
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s.%x", r.GetName(), time.Now().UnixNano()),
+			Namespace:   r.GetNamespace(),
+			Annotations: map[string]string{markerRevisionAnnotation: marker.GetRevision()},
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: gvk.GroupVersion().String(),
+			Kind:       gvk.Kind,
+			Namespace:  r.GetNamespace(),
+			Name:       r.GetName(),
+		},
+		Reason:         markerEventReason,
+		Message:        marker.GetMessage(),
+		Type:           corev1.EventTypeNormal,
+		Source:         corev1.EventSource{Component: markerEventComponent},
+		FirstTimestamp: timestamp,
+		LastTimestamp:  timestamp,
+		Count:          1,
+	}
+	_, err := s.k8sAPI.Client.CoreV1().Events(r.GetNamespace()).Create(ctx, event, metav1.CreateOptions{})
+	return err
+}
+
+// listRecordedMarkers returns the markers recorded in the given namespace
+// since the given time.
+func (s *grpcServer) listRecordedMarkers(ctx context.Context, namespace string, since time.Time) ([]*pb.DeploymentMarker, error) {
+	events, err := s.k8sAPI.Client.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("reason", markerEventReason).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var markers []*pb.DeploymentMarker
+	for _, event := range events.Items {
+		if event.Reason != markerEventReason || event.Source.Component != markerEventComponent {
+			continue
+		}
+		if event.FirstTimestamp.Time.Before(since) {
+			continue
+		}
+		markers = append(markers, &pb.DeploymentMarker{
+			Resource: &pb.Resource{
+				Namespace: event.InvolvedObject.Namespace,
+				Type:      strings.ToLower(event.InvolvedObject.Kind),
+				Name:      event.InvolvedObject.Name,
+			},
+			Timestamp: event.FirstTimestamp.Unix(),
+			Revision:  event.Annotations[markerRevisionAnnotation],
+			Message:   event.Message,
+			Source:    webhookMarkerSource,
+		})
+	}
+	return markers, nil
+}
+
+// validateMarker checks that a marker recorded through the webhook designates
+// a resource, and fills in its defaults.
+func validateMarker(marker *pb.DeploymentMarker) error {
+	r := marker.GetResource()
+	if r.GetNamespace() == "" || r.GetType() == "" || r.GetName() == "" {
+		return fmt.Errorf("deployment markers require the namespace, type and name of their resource")
+	}
+	resourceType, err := pkgK8s.CanonicalResourceNameFromFriendlyName(r.GetType())
+	if err != nil {
+		return err
+	}
+	if _, ok := markerKinds[resourceType]; !ok {
+		return fmt.Errorf("deployment markers can't be recorded for %s resources", resourceType)
+	}
+	r.Type = resourceType
+	if marker.Timestamp == 0 {
+		marker.Timestamp = time.Now().Unix()
+	}
+	marker.Source = webhookMarkerSource
+	return nil
+}
+
+// getDeploymentMarkers returns the rollouts of the given resource since the
+// given time, from the oldest to the latest: its markers among the given
+// recorded ones and, for a Deployment, the creations of its ReplicaSets.
+func (s *grpcServer) getDeploymentMarkers(resource *pb.Resource, since time.Time, recorded []*pb.DeploymentMarker) ([]*pb.DeploymentMarker, error) {
+	var markers []*pb.DeploymentMarker
+	for _, marker := range recorded {
+		r := marker.GetResource()
+		if r.GetType() == resource.GetType() && r.GetNamespace() == resource.GetNamespace() && r.GetName() == resource.GetName() {
+			markers = append(markers, marker)
+		}
+	}
+
+	if resource.GetType() == pkgK8s.Deployment {
+		replicaSets, err := s.k8sAPI.RS().Lister().ReplicaSets(resource.GetNamespace()).List(labels.Everything())
+		if err != nil {
+			return nil, err
+		}
+		for _, rs := range replicaSets {
+			owner := metav1.GetControllerOf(rs)
+			if owner == nil || owner.Kind != "Deployment" || owner.Name != resource.GetName() {
+				continue
+			}
+			if rs.CreationTimestamp.Time.Before(since) {
+				continue
+			}
+			markers = append(markers, &pb.DeploymentMarker{
+				Resource:  resource,
+				Timestamp: rs.CreationTimestamp.Unix(),
+				Revision:  rs.Annotations[revisionAnnotation],
+				Message:   rs.Annotations[changeCauseAnnotation],
+				Source:    rolloutMarkerSource,
+			})
+		}
+	}
+
+	sort.SliceStable(markers, func(i, j int) bool {
+		return markers[i].GetTimestamp() < markers[j].GetTimestamp()
+	})
+	return markers, nil
+}
+
+// errorBudget returns the error budget of the given stats against the given
+// success rate objective.
+func errorBudget(stats *pb.BasicStats, objective float64) *pb.ErrorBudget {
+	failures := float64(stats.GetFailureCount())
+	allowed := float64(stats.GetSuccessCount()+stats.GetFailureCount()) * (1 - objective)
+	remaining := 1.0
+	if allowed > 0 {
+		remaining = 1 - failures/allowed
+	}
+	return &pb.ErrorBudget{
+		SuccessRateObjective: objective,
+		AllowedFailures:      allowed,
+		Remaining:            remaining,
+	}
+}
+
+// addOverlays adds the requested deployment markers and error budgets to the
+// rows of the given tables.
+func (s *grpcServer) addOverlays(ctx context.Context, req *pb.StatSummaryRequest, tables []*pb.StatTable) error {
+	window, err := time.ParseDuration(req.GetTimeWindow())
+	if err != nil {
+		return err
+	}
+	since := time.Now().Add(-window)
+
+	// the recorded markers are listed once per namespace
+	recorded := make(map[string][]*pb.DeploymentMarker)
+	for _, table := range tables {
+		for _, row := range table.GetPodGroup().GetRows() {
+			if _, ok := markerKinds[row.GetResource().GetType()]; ok && req.GetDeploymentMarkers() {
+				namespace := row.GetResource().GetNamespace()
+				if _, ok := recorded[namespace]; !ok {
+					recorded[namespace], err = s.listRecordedMarkers(ctx, namespace, since)
+					if err != nil {
+						return err
+					}
+				}
+				markers, err := s.getDeploymentMarkers(row.GetResource(), since, recorded[namespace])
+				if err != nil {
+					return err
+				}
+				row.DeploymentMarkers = markers
+			}
+			if req.GetSuccessRateObjective() > 0 && row.GetStats() != nil {
+				row.ErrorBudget = errorBudget(row.GetStats(), req.GetSuccessRateObjective())
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	pkgK8s "github.com/linkerd/linkerd2/pkg/k8s"
+	pb "github.com/linkerd/linkerd2/viz/metrics-api/gen/viz"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetDeploymentMarkers(t *testing.T) {
+	created := time.Now().Add(-30 * time.Second).UTC().Format(time.RFC3339)
+	replicaSet := func(name, owner, revision string) string {
+		return fmt.Sprintf(`
+apiVersion: apps/v1
+kind: ReplicaSet
+metadata:
+  name: %s
+  namespace: emojivoto
+  creationTimestamp: %s
+  annotations:
+    deployment.kubernetes.io/revision: "%s"
+    kubernetes.io/change-cause: bump the emoji image
+  ownerReferences:
+  - apiVersion: apps/v1
+    kind: Deployment
+    name: %s
+    uid: 4a1c0f2e-2f0e-4b8e-9c1f-000000000000
+    controller: true
+`, name, created, revision, owner)
+	}
+
+	_, server, err := newMockGrpcServer(expectedStatRPC{
+		k8sConfigs: []string{
+			replicaSet("web-5d8f7c", "web", "4"),
+			replicaSet("voting-7b9c4d", "voting", "2"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	web := &pb.Resource{Namespace: "emojivoto", Type: pkgK8s.Deployment, Name: "web"}
+	markers, err := server.getDeploymentMarkers(web, time.Now().Add(-time.Minute), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(markers) != 1 {
+		t.Fatalf("Expected 1 marker, got %v", markers)
+	}
+	if markers[0].Revision != "4" || markers[0].Message != "bump the emoji image" || markers[0].Source != rolloutMarkerSource {
+		t.Errorf("Unexpected marker: %v", markers[0])
+	}
+
+	markers, err = server.getDeploymentMarkers(web, time.Now(), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(markers) != 0 {
+		t.Errorf("Expected the rollouts before the time window to be ignored, got %v", markers)
+	}
+}
+
+func TestErrorBudget(t *testing.T) {
+	for _, tc := range []struct {
+		success, failure uint64
+		remaining        float64
+	}{
+		{success: 999, failure: 1, remaining: 0},
+		{success: 1998, failure: 2, remaining: 0},
+		{success: 3999, failure: 1, remaining: 0.75},
+		{success: 990, failure: 10, remaining: -9},
+		{success: 0, failure: 0, remaining: 1},
+	} {
+		budget := errorBudget(&pb.BasicStats{SuccessCount: tc.success, FailureCount: tc.failure}, 0.999)
+		if diff := budget.Remaining - tc.remaining; diff > 1e-9 || diff < -1e-9 {
+			t.Errorf("Expected %v of the budget left with %d successes and %d failures, got %v", tc.remaining, tc.success, tc.failure, budget.Remaining)
+		}
+	}
+}
+
+func TestRecordedMarkers(t *testing.T) {
+	_, server, err := newMockGrpcServer(expectedStatRPC{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	ctx := context.Background()
+	web := &pb.Resource{Namespace: "emojivoto", Type: pkgK8s.Deployment, Name: "web"}
+	now := time.Now()
+
+	for _, marker := range []*pb.DeploymentMarker{
+		{Resource: web, Timestamp: now.Add(-time.Hour).Unix(), Revision: "v1"},
+		{Resource: web, Timestamp: now.Unix(), Revision: "v2", Message: "bump the web image"},
+		{Resource: &pb.Resource{Namespace: "emojivoto", Type: pkgK8s.Deployment, Name: "voting"}, Timestamp: now.Unix(), Revision: "v3"},
+	} {
+		if err := validateMarker(marker); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if err := server.recordDeploymentMarker(ctx, marker); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+
+	events, err := server.k8sAPI.Client.CoreV1().Events("emojivoto").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(events.Items) != 3 {
+		t.Fatalf("Expected the markers to be recorded as 3 events, got %v", events.Items)
+	}
+	for _, event := range events.Items {
+		if event.InvolvedObject.Kind != "Deployment" || event.InvolvedObject.APIVersion != "apps/v1" || event.Reason != markerEventReason {
+			t.Errorf("Unexpected event: %v", event)
+		}
+	}
+
+	since := now.Add(-time.Minute)
+	recorded, err := server.listRecordedMarkers(ctx, "emojivoto", since)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(recorded) != 2 {
+		t.Fatalf("Expected the markers before the time window to be ignored, got %v", recorded)
+	}
+	markers, err := server.getDeploymentMarkers(web, since, recorded)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(markers) != 1 {
+		t.Fatalf("Expected only the marker of web, got %v", markers)
+	}
+	marker := markers[0]
+	if marker.Revision != "v2" || marker.Message != "bump the web image" || marker.Source != webhookMarkerSource || marker.Timestamp != now.Unix() {
+		t.Errorf("Unexpected marker: %v", marker)
+	}
+	if marker.GetResource().GetType() != pkgK8s.Deployment || marker.GetResource().GetName() != "web" {
+		t.Errorf("Unexpected resource: %v", marker.GetResource())
+	}
+}
@@ -11,7 +11,6 @@ import (
 )
 
 const (
-	clientIDLabel = model.LabelName("client_id")
 	serverIDLabel = model.LabelName("server_id")
 	resourceLabel = model.LabelName("deployment")
 )
@@ -3,10 +3,14 @@ package api
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"time"
 
 	"github.com/linkerd/linkerd2/pkg/k8s"
+	"github.com/linkerd/linkerd2/pkg/multicluster"
 	pb "github.com/linkerd/linkerd2/viz/metrics-api/gen/viz"
 	"github.com/prometheus/common/model"
+	log "github.com/sirupsen/logrus"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -16,9 +20,19 @@ const (
 	gatewayLatencyQuantileQuery = "histogram_quantile(%s, sum(irate(gateway_probe_latency_ms_bucket%s[%s])) by (le, %s))"
 )
 
+// invalidPromLabelChars matches the characters of the pod labels replaced by
+// underscores when Prometheus maps them to metric labels
+var invalidPromLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
 func (s *grpcServer) Gateways(ctx context.Context, req *pb.GatewaysRequest) (*pb.GatewaysResponse, error) {
 	array := []*pb.GatewaysTable_Row{}
-	metrics, err := s.getGatewaysMetrics(ctx, req, req.TimeWindow)
+	var metrics map[string]*pb.GatewaysTable_Row
+	var err error
+	if req.BySourceIdentity {
+		metrics, err = s.getGatewaysTrafficBySource(ctx, req)
+	} else {
+		metrics, err = s.getGatewaysMetrics(ctx, req, req.TimeWindow)
+	}
 
 	if err != nil {
 		return nil, err
@@ -133,6 +147,138 @@ func (s *grpcServer) getGatewaysMetrics(ctx context.Context, req *pb.GatewaysReq
 	}
 
 	rowsMap := processPrometheusResult(metricsResp, numSvcMap)
+	s.setGatewaysAlive(ctx, req, rowsMap, numSvcMap)
 
 	return rowsMap, nil
 }
+
+// setGatewaysAlive sets the liveness of the gateways from the GatewayAlive
+// condition of their Links, so that it's the same as the liveness the service
+// mirrors act upon, rather than the liveness exported as metrics. The gateways
+// whose condition is missing or stale, e.g. because their service mirror is
+// down, are probed directly. The gateway metrics are kept when the Links can't
+// be read, e.g. when the multicluster extension isn't installed.
+func (s *grpcServer) setGatewaysAlive(ctx context.Context, req *pb.GatewaysRequest, rows map[string]*pb.GatewaysTable_Row, numSvcMap map[string]uint64) {
+	if s.linkClient == nil {
+		return
+	}
+	links, err := s.linkClient.Resource(multicluster.LinkGVR).Namespace(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Debugf("Failed to list the Links, reporting the gateway metrics only: %s", err)
+		return
+	}
+
+	for _, u := range links.Items {
+		u := u // pin
+		link, err := multicluster.NewLink(u)
+		if err != nil {
+			log.Errorf("Failed to parse Link %s/%s: %s", u.GetNamespace(), u.GetName(), err)
+			continue
+		}
+		if req.RemoteClusterName != "" && link.TargetClusterName != req.RemoteClusterName {
+			continue
+		}
+
+		row := rows[link.TargetClusterName]
+		if row == nil {
+			// the Links don't tell the namespace of their gateway
+			if req.GatewayNamespace != "" {
+				continue
+			}
+			row = &pb.GatewaysTable_Row{
+				ClusterName:    link.TargetClusterName,
+				PairedServices: numSvcMap[link.TargetClusterName],
+			}
+			rows[link.TargetClusterName] = row
+		}
+
+		alive, err := multicluster.LinkGatewayAlive(&u)
+		if err != nil {
+			log.Errorf("Failed to read the %s condition of Link %s/%s: %s", multicluster.LinkConditionGatewayAlive, link.Namespace, link.Name, err)
+		}
+		if alive != nil && !alive.Stale(time.Now(), link.GatewayAliveMaxAge()) {
+			row.Alive = alive.Status == metav1.ConditionTrue
+			continue
+		}
+		err = link.ProbeGateway(ctx)
+		if err != nil {
+			log.Debugf("The gateway of Link %s/%s failed its probe: %s", link.Namespace, link.Name, err)
+		}
+		row.Alive = err == nil
+	}
+}
+
+// getGatewaysTrafficBySource returns the connections and bytes received by
+// the gateways of this cluster, as reported by their proxies, broken down by
+// the identity of their clients. The rows are keyed by gateway and identity.
+func (s *grpcServer) getGatewaysTrafficBySource(ctx context.Context, req *pb.GatewaysRequest) (map[string]*pb.GatewaysTable_Row, error) {
+	services, err := s.k8sAPI.Client.CoreV1().Services(req.GatewayNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make(map[string]*pb.GatewaysTable_Row)
+	for _, svc := range services.Items {
+		if _, ok := svc.Annotations[k8s.GatewayIdentity]; !ok || len(svc.Spec.Selector) == 0 {
+			continue
+		}
+
+		labels, groupBy := buildGatewayTrafficLabels(&svc)
+		promQueries := map[promType]string{
+			promTCPConnections: fmt.Sprintf(tcpConnectionsQuery, labels.String(), groupBy.String()),
+			promTCPReadBytes:   fmt.Sprintf(tcpReadBytesQuery, labels.String(), req.TimeWindow, groupBy.String()),
+			promTCPWriteBytes:  fmt.Sprintf(tcpWriteBytesQuery, labels.String(), req.TimeWindow, groupBy.String()),
+		}
+		results, err := s.getPrometheusMetrics(ctx, promQueries, nil)
+		if err != nil {
+			return nil, err
+		}
+		for id, row := range processGatewayTrafficResult(results, svc.Namespace, svc.Name) {
+			rows[id] = row
+		}
+	}
+	return rows, nil
+}
+
+// buildGatewayTrafficLabels returns the labels selecting the inbound traffic
+// of the proxies of the pods behind the given gateway service, and the labels
+// to group it by.
+func buildGatewayTrafficLabels(svc *corev1.Service) (model.LabelSet, model.LabelNames) {
+	labels := model.LabelSet{
+		namespaceLabel: model.LabelValue(svc.Namespace),
+		"direction":    "inbound",
+		"peer":         "src",
+	}
+	for k, v := range svc.Spec.Selector {
+		labels[model.LabelName(invalidPromLabelChars.ReplaceAllString(k, "_"))] = model.LabelValue(v)
+	}
+	return labels, model.LabelNames{clientIDLabel}
+}
+
+func processGatewayTrafficResult(results []promResult, namespace, name string) map[string]*pb.GatewaysTable_Row {
+	rows := make(map[string]*pb.GatewaysTable_Row)
+	for _, result := range results {
+		for _, sample := range result.vec {
+			id := string(sample.Metric[clientIDLabel])
+			key := fmt.Sprintf("%s/%s/%s", namespace, name, id)
+			if rows[key] == nil {
+				rows[key] = &pb.GatewaysTable_Row{
+					Namespace:      namespace,
+					Name:           name,
+					SourceIdentity: id,
+				}
+			}
+
+			value := extractSampleValue(sample)
+			switch result.prom {
+			case promTCPConnections:
+				rows[key].OpenConnections = value
+			case promTCPReadBytes:
+				rows[key].ReadBytesTotal = value
+			case promTCPWriteBytes:
+				rows[key].WriteBytesTotal = value
+			}
+		}
+	}
+	return rows
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/linkerd/linkerd2/pkg/multicluster"
+	pb "github.com/linkerd/linkerd2/viz/metrics-api/gen/viz"
+	"github.com/prometheus/common/model"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestGatewaysBySourceIdentity(t *testing.T) {
+	exp := expectedStatRPC{
+		k8sConfigs: []string{`
+apiVersion: v1
+kind: Service
+metadata:
+  name: linkerd-gateway
+  namespace: linkerd-multicluster
+  annotations:
+    mirror.linkerd.io/gateway-identity: linkerd-gateway.linkerd-multicluster.serviceaccount.identity.linkerd.cluster.local
+spec:
+  selector:
+    app: linkerd-gateway
+`, `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: emojivoto
+spec:
+  selector:
+    app: web
+`,
+		},
+		mockPromResponse: model.Vector{
+			&model.Sample{
+				Metric:    model.Metric{clientIDLabel: "web.emojivoto.serviceaccount.identity.linkerd.cluster.local"},
+				Value:     3,
+				Timestamp: 456,
+			},
+			&model.Sample{
+				Metric:    model.Metric{},
+				Value:     1,
+				Timestamp: 456,
+			},
+		},
+		expectedPrometheusQueries: []string{
+			`sum(increase(tcp_read_bytes_total{app="linkerd-gateway", direction="inbound", namespace="linkerd-multicluster", peer="src"}[1m])) by (client_id)`,
+			`sum(increase(tcp_write_bytes_total{app="linkerd-gateway", direction="inbound", namespace="linkerd-multicluster", peer="src"}[1m])) by (client_id)`,
+			`sum(tcp_open_connections{app="linkerd-gateway", direction="inbound", namespace="linkerd-multicluster", peer="src"}) by (client_id)`,
+		},
+	}
+
+	mockProm, fakeGrpcServer, err := newMockGrpcServer(exp)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	rsp, err := fakeGrpcServer.Gateways(context.Background(), &pb.GatewaysRequest{
+		TimeWindow:       "1m",
+		BySourceIdentity: true,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if err := exp.verifyPromQueries(mockProm); err != nil {
+		t.Fatal(err)
+	}
+
+	rows := map[string]*pb.GatewaysTable_Row{}
+	for _, row := range rsp.GetOk().GetGatewaysTable().GetRows() {
+		if row.Namespace != "linkerd-multicluster" || row.Name != "linkerd-gateway" {
+			t.Errorf("Unexpected gateway %s/%s", row.Namespace, row.Name)
+		}
+		rows[row.SourceIdentity] = row
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 rows, got %d", len(rows))
+	}
+
+	web := rows["web.emojivoto.serviceaccount.identity.linkerd.cluster.local"]
+	if web == nil || web.OpenConnections != 3 || web.ReadBytesTotal != 3 || web.WriteBytesTotal != 3 {
+		t.Errorf("Unexpected row for the web identity: %+v", web)
+	}
+	if unidentified := rows[""]; unidentified == nil || unidentified.OpenConnections != 1 {
+		t.Errorf("Unexpected row for the clients without an identity: %+v", unidentified)
+	}
+}
+
+func TestGatewaysAliveFromLinks(t *testing.T) {
+	// the gateway of the Link with a stale condition is probed and fails
+	gateway := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer gateway.Close()
+	host, portStr, err := net.SplitHostPort(gateway.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 32)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	now := time.Now()
+	links := []struct {
+		name          string
+		status        metav1.ConditionStatus
+		lastProbeTime time.Time
+	}{
+		// reported dead by its service mirror, but alive in the metrics
+		{name: "east", status: metav1.ConditionFalse, lastProbeTime: now},
+		// reported alive, without metrics
+		{name: "west", status: metav1.ConditionTrue, lastProbeTime: now},
+		// reported alive before its service mirror stopped
+		{name: "north", status: metav1.ConditionTrue, lastProbeTime: now.Add(-time.Hour)},
+	}
+
+	objs := []runtime.Object{}
+	for _, l := range links {
+		u, err := multicluster.Link{
+			Name:              l.name,
+			Namespace:         "linkerd-multicluster",
+			TargetClusterName: l.name,
+			GatewayAddress:    host,
+			ProbeSpec: multicluster.ProbeSpec{
+				Path:   "/ready",
+				Port:   uint32(port),
+				Period: time.Minute,
+			},
+		}.ToUnstructured()
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		objs = append(objs, &u)
+	}
+	linkClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(
+		runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{multicluster.LinkGVR: "LinkList"},
+		objs...,
+	)
+	for _, l := range links {
+		err := multicluster.SetLinkGatewayAlive(context.Background(), linkClient, "linkerd-multicluster", l.name, multicluster.GatewayAlive{
+			Condition:     metav1.Condition{Status: l.status, Reason: "Test", Message: "test"},
+			LastProbeTime: metav1.NewTime(l.lastProbeTime),
+		})
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+
+	exp := expectedStatRPC{
+		mockPromResponse: model.Vector{
+			&model.Sample{
+				Metric:    model.Metric{remoteClusterNameLabel: "east"},
+				Value:     1,
+				Timestamp: 456,
+			},
+		},
+	}
+	_, fakeGrpcServer, err := newMockGrpcServer(exp)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	fakeGrpcServer.linkClient = linkClient
+
+	rsp, err := fakeGrpcServer.Gateways(context.Background(), &pb.GatewaysRequest{TimeWindow: "1m"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	alive := map[string]bool{}
+	for _, row := range rsp.GetOk().GetGatewaysTable().GetRows() {
+		alive[row.ClusterName] = row.Alive
+	}
+	expected := map[string]bool{"east": false, "west": true, "north": false}
+	if len(alive) != len(expected) {
+		t.Fatalf("Expected rows for %v, got %v", expected, alive)
+	}
+	for name, exp := range expected {
+		if alive[name] != exp {
+			t.Errorf("Expected the gateway of %s to be alive: %t, got %t", name, exp, alive[name])
+		}
+	}
+}
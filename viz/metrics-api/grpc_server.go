@@ -12,10 +12,13 @@ import (
 	"github.com/linkerd/linkerd2/pkg/prometheus"
 	pb "github.com/linkerd/linkerd2/viz/metrics-api/gen/viz"
 	"github.com/linkerd/linkerd2/viz/metrics-api/util"
+	promApi "github.com/prometheus/client_golang/api"
 	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/dynamic"
 )
 
 // Server specifies the interface the Viz metric API server should implement
@@ -30,6 +33,16 @@ type grpcServer struct {
 	controllerNamespace string
 	clusterDomain       string
 	ignoredNamespaces   []string
+
+	// recordingRulesExpected is set when the recording rules are installed
+	// alongside viz, in which case SelfCheck verifies they're loaded.
+	recordingRulesExpected bool
+	recordingRules         recordingRulesState
+
+	// linkClient reads the multicluster Links, whose GatewayAlive condition
+	// reports the liveness of their gateways; the liveness is only read from
+	// the gateway metrics when it's nil
+	linkClient dynamic.Interface
 }
 
 type podReport struct {
@@ -51,21 +64,61 @@ func newGrpcServer(
 	controllerNamespace string,
 	clusterDomain string,
 	ignoredNamespaces []string,
+	recordingRulesExpected bool,
 ) *grpcServer {
 
 	grpcServer := &grpcServer{
-		prometheusAPI:       promAPI,
-		k8sAPI:              k8sAPI,
-		controllerNamespace: controllerNamespace,
-		clusterDomain:       clusterDomain,
-		ignoredNamespaces:   ignoredNamespaces,
+		prometheusAPI:          promAPI,
+		k8sAPI:                 k8sAPI,
+		controllerNamespace:    controllerNamespace,
+		clusterDomain:          clusterDomain,
+		ignoredNamespaces:      ignoredNamespaces,
+		recordingRulesExpected: recordingRulesExpected,
 	}
 
-	pb.RegisterApiServer(prometheus.NewGrpcServer(), grpcServer)
-
 	return grpcServer
 }
 
+// NewGrpcAPIServer returns a gRPC server serving the Viz metrics API defined
+// in viz/metrics-api/proto/viz.proto, for the clients that consume it
+// directly instead of through the HTTP handlers. In tenancy mode, the clients
+// must send a bearer token in the authorization metadata of their requests.
+func NewGrpcAPIServer(
+	prometheusClient promApi.Client,
+	k8sAPI *k8s.API,
+	controllerNamespace string,
+	clusterDomain string,
+	ignoredNamespaces []string,
+	recordingRulesExpected bool,
+	tenancy bool,
+	linkClient dynamic.Interface,
+) *grpc.Server {
+	var promAPI promv1.API
+	if prometheusClient != nil {
+		promAPI = promv1.NewAPI(prometheusClient)
+	}
+
+	server := newGrpcServer(
+		promAPI,
+		k8sAPI,
+		controllerNamespace,
+		clusterDomain,
+		ignoredNamespaces,
+		recordingRulesExpected,
+	)
+	server.linkClient = linkClient
+	if tenancy {
+		return registerGrpcServer(newTenantServer(server, k8sAPI))
+	}
+	return registerGrpcServer(server)
+}
+
+func registerGrpcServer(server Server) *grpc.Server {
+	srv := prometheus.NewGrpcServer()
+	pb.RegisterApiServer(srv, server)
+	return srv
+}
+
 func (s *grpcServer) ListPods(ctx context.Context, req *pb.ListPodsRequest) (*pb.ListPodsResponse, error) {
 	log.Debugf("ListPods request: %+v", req)
 
@@ -206,6 +259,21 @@ func (s *grpcServer) SelfCheck(ctx context.Context, in *pb.SelfCheckRequest) (*p
 		}
 
 		response.Results = append(response.Results, promClientCheck)
+
+		if s.recordingRulesExpected {
+			recordingRulesCheck := &pb.CheckResult{
+				SubsystemName:    recordingRulesSubsystemName,
+				CheckDescription: recordingRulesCheckDescription,
+				Status:           pb.CheckStatus_OK,
+			}
+			err = s.checkRecordingRules(ctx)
+			if err != nil {
+				recordingRulesCheck.Status = pb.CheckStatus_ERROR
+				recordingRulesCheck.FriendlyMessageToUser = fmt.Sprintf("Recording rules are not loaded in Prometheus: %s", err)
+			}
+
+			response.Results = append(response.Results, recordingRulesCheck)
+		}
 	}
 
 	return response, nil
@@ -403,6 +403,7 @@ status:
 				"linkerd",
 				"mycluster.local",
 				[]string{},
+				false,
 			)
 
 			k8sAPI.Sync(nil)
@@ -504,6 +505,7 @@ metadata:
 				"linkerd",
 				"mycluster.local",
 				[]string{},
+				false,
 			)
 
 			k8sAPI.Sync(nil)
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+
+	"github.com/linkerd/linkerd2/controller/k8s"
+	"github.com/linkerd/linkerd2/pkg/health"
+	pb "github.com/linkerd/linkerd2/viz/metrics-api/gen/viz"
+	promApi "github.com/prometheus/client_golang/api"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+)
+
+// NewHealthReporter returns the reporter of the health of the viz extension,
+// made of the results of the metrics API self-check.
+func NewHealthReporter(
+	prometheusClient promApi.Client,
+	k8sAPI *k8s.API,
+	controllerNamespace string,
+	clusterDomain string,
+	ignoredNamespaces []string,
+	recordingRulesExpected bool,
+) *health.Reporter {
+	var promAPI promv1.API
+	if prometheusClient != nil {
+		promAPI = promv1.NewAPI(prometheusClient)
+	}
+	server := newGrpcServer(promAPI, k8sAPI, controllerNamespace, clusterDomain, ignoredNamespaces, recordingRulesExpected)
+
+	reporter := health.NewReporter("viz", "metrics-api")
+	reporter.AddChecker(func(ctx context.Context) []health.Check {
+		rsp, err := server.SelfCheck(ctx, &pb.SelfCheckRequest{})
+		if err != nil {
+			return []health.Check{{Name: "self-check", Message: err.Error()}}
+		}
+		checks := []health.Check{}
+		for _, result := range rsp.GetResults() {
+			checks = append(checks, health.Check{
+				Name:    result.GetCheckDescription(),
+				Healthy: result.GetStatus() == pb.CheckStatus_OK,
+				Message: result.GetFriendlyMessageToUser(),
+			})
+		}
+		return checks
+	})
+	return reporter
+}
@@ -3,7 +3,9 @@ package api
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
+	"github.com/golang/protobuf/jsonpb"
 	"github.com/linkerd/linkerd2/controller/k8s"
 	"github.com/linkerd/linkerd2/pkg/prometheus"
 	"github.com/linkerd/linkerd2/pkg/protohttp"
@@ -12,6 +14,9 @@ import (
 	promApi "github.com/prometheus/client_golang/api"
 	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
 	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/client-go/dynamic"
 )
 
 var (
@@ -22,6 +27,9 @@ var (
 	listServicesPath = fullURLPathFor("ListServices")
 	selfCheckPath    = fullURLPathFor("SelfCheck")
 	edgesPath        = fullURLPathFor("Edges")
+
+	// deploymentMarkerPath serves the webhook recording deployment markers
+	deploymentMarkerPath = fullURLPathFor("DeploymentMarker")
 )
 
 type handler struct {
@@ -38,6 +46,11 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// The token is only used in tenancy mode, to authorize the caller
+	if token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "); token != req.Header.Get("Authorization") {
+		req = req.WithContext(withToken(req.Context(), token))
+	}
+
 	// Serve request
 	switch req.URL.Path {
 	case gatewaysPath:
@@ -54,6 +67,8 @@ func (h *handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 		h.handleSelfCheck(w, req)
 	case edgesPath:
 		h.handleEdges(w, req)
+	case deploymentMarkerPath:
+		h.handleDeploymentMarker(w, req)
 	default:
 		http.NotFound(w, req)
 	}
@@ -208,6 +223,44 @@ func (h *handler) handleListServices(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// handleDeploymentMarker records a deployment marker posted as JSON, e.g. by
+// a delivery pipeline once it rolled a resource out, as an Event on the
+// resource:
+//
+//	{"resource": {"namespace": "emojivoto", "type": "deploy", "name": "web"}, "revision": "v11"}
+//
+// Unlike the other handlers, it takes JSON rather than protobuf, so that it
+// can be called from any webhook. In tenancy mode, the caller must be
+// authorized for the namespace of the resource.
+func (h *handler) handleDeploymentMarker(w http.ResponseWriter, req *http.Request) {
+	var marker pb.DeploymentMarker
+	if err := jsonpb.Unmarshal(req.Body, &marker); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := validateMarker(&marker); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	recorder, ok := h.grpcServer.(markerRecorder)
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+	if err := recorder.recordDeploymentMarker(req.Context(), &marker); err != nil {
+		switch status.Code(err) {
+		case codes.Unauthenticated:
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+		case codes.PermissionDenied:
+			http.Error(w, err.Error(), http.StatusForbidden)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func fullURLPathFor(method string) string {
 	return client.APIRoot + client.APIPrefix + method
 }
@@ -220,6 +273,9 @@ func NewServer(
 	controllerNamespace string,
 	clusterDomain string,
 	ignoredNamespaces []string,
+	recordingRulesExpected bool,
+	tenancy bool,
+	linkClient dynamic.Interface,
 ) *http.Server {
 
 	var promAPI promv1.API
@@ -233,10 +289,15 @@ func NewServer(
 		controllerNamespace,
 		clusterDomain,
 		ignoredNamespaces,
+		recordingRulesExpected,
 	)
+	grpcServer.linkClient = linkClient
 	baseHandler := &handler{
 		grpcServer: grpcServer,
 	}
+	if tenancy {
+		baseHandler.grpcServer = newTenantServer(grpcServer, k8sAPI)
+	}
 
 	instrumentedHandler := prometheus.WithTelemetry(baseHandler)
 
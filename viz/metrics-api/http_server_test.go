@@ -5,7 +5,10 @@ import (
 	"errors"
 	"net"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	vizClient "github.com/linkerd/linkerd2/viz/metrics-api/client"
@@ -92,6 +95,104 @@ func TestServer(t *testing.T) {
 	})
 }
 
+func TestGrpcServer(t *testing.T) {
+	t.Run("Serves the RPC messages of the underlying grpc server", func(t *testing.T) {
+		mockGrpcServer := &mockGrpcServer{}
+
+		listener, err := net.Listen("tcp", "localhost:0")
+		if err != nil {
+			t.Fatalf("Could not start listener: %v", err)
+		}
+		server := registerGrpcServer(mockGrpcServer)
+		go server.Serve(listener)
+		defer server.Stop()
+
+		client, conn, err := vizClient.NewGrpcClient(listener.Addr().String())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		defer conn.Close()
+
+		gatewaysReq := &pb.GatewaysRequest{RemoteClusterName: "east"}
+		testGateways := grpcCallTestCase{
+			expectedRequest: gatewaysReq,
+			expectedResponse: &pb.GatewaysResponse{
+				Response: &pb.GatewaysResponse_Ok_{Ok: &pb.GatewaysResponse_Ok{}},
+			},
+			functionCall: func() (proto.Message, error) { return client.Gateways(context.TODO(), gatewaysReq) },
+		}
+
+		statSummaryReq := &pb.StatSummaryRequest{}
+		testStatSummary := grpcCallTestCase{
+			expectedRequest:  statSummaryReq,
+			expectedResponse: &pb.StatSummaryResponse{},
+			functionCall:     func() (proto.Message, error) { return client.StatSummary(context.TODO(), statSummaryReq) },
+		}
+
+		for _, testCase := range []grpcCallTestCase{testGateways, testStatSummary} {
+			assertCallWasForwarded(t, &mockGrpcServer.mockServer, testCase.expectedRequest, testCase.expectedResponse, testCase.functionCall)
+		}
+	})
+}
+
+func TestHandleDeploymentMarker(t *testing.T) {
+	_, server, err := newMockGrpcServer(expectedStatRPC{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	h := &handler{grpcServer: server}
+
+	for _, tc := range []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{
+			name:   "valid marker",
+			body:   `{"resource": {"namespace": "emojivoto", "type": "deploy", "name": "web"}, "revision": "v11"}`,
+			status: http.StatusNoContent,
+		},
+		{
+			name:   "missing name",
+			body:   `{"resource": {"namespace": "emojivoto", "type": "deploy"}}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "unknown type",
+			body:   `{"resource": {"namespace": "emojivoto", "type": "unknown", "name": "web"}}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "unsupported type",
+			body:   `{"resource": {"namespace": "emojivoto", "type": "svc", "name": "web"}}`,
+			status: http.StatusBadRequest,
+		},
+		{
+			name:   "invalid JSON",
+			body:   `not json`,
+			status: http.StatusBadRequest,
+		},
+	} {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, deploymentMarkerPath, strings.NewReader(tc.body))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Fatalf("Expected status %d, got %d: %s", tc.status, rec.Code, rec.Body)
+			}
+		})
+	}
+
+	markers, err := server.listRecordedMarkers(context.Background(), "emojivoto", time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if len(markers) != 1 || markers[0].Revision != "v11" || markers[0].GetResource().GetName() != "web" {
+		t.Fatalf("Expected only the valid marker to be recorded, got %v", markers)
+	}
+}
+
 func getServerVizClient(t *testing.T) (*mockGrpcServer, pb.ApiClient) {
 	mockGrpcServer := &mockGrpcServer{}
 
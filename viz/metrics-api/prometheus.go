@@ -35,6 +35,7 @@ const (
 	promLatencyP99     = promType("0.99")
 
 	namespaceLabel         = model.LabelName("namespace")
+	clientIDLabel          = model.LabelName("client_id")
 	dstNamespaceLabel      = model.LabelName("dst_namespace")
 	gatewayNameLabel       = model.LabelName("gateway_name")
 	gatewayNamespaceLabel  = model.LabelName("gateway_namespace")
@@ -55,6 +56,8 @@ func extractSampleValue(sample *model.Sample) uint64 {
 }
 
 func (s *grpcServer) queryProm(ctx context.Context, query string) (model.Vector, error) {
+	// in tenancy mode, only query the series of the caller's namespaces
+	query = tenantFromContext(ctx).restrict(query)
 	log.Debugf("Query request:\n\t%+v", query)
 
 	_, span := trace.StartSpan(ctx, "query.prometheus")
@@ -177,6 +180,13 @@ func generateQuantileQueries(quantileQuery, labels, timeWindow, groupBy string)
 	}
 }
 
+// offsetQuery shifts the range selectors of a query over the given time
+// window back by that window, so that it covers the preceding window instead.
+func offsetQuery(query, timeWindow string) string {
+	rangeSelector := fmt.Sprintf("[%s]", timeWindow)
+	return strings.ReplaceAll(query, rangeSelector, fmt.Sprintf("%s offset %s", rangeSelector, timeWindow))
+}
+
 // determine if we should add "namespace=<namespace>" to a named query
 func shouldAddNamespaceLabel(resource *pb.Resource) bool {
 	return resource.Type != k8s.Namespace && resource.Namespace != ""
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// recordingRulesWindow is the time window the recording rules aggregate
+	// over. Queries for any other window use the raw series.
+	recordingRulesWindow = "1m"
+
+	// recordingRulesRefreshInterval is how long the result of looking up the
+	// recording rules in Prometheus is cached for.
+	recordingRulesRefreshInterval = time.Minute
+
+	recordingRulesSubsystemName    = "prometheus"
+	recordingRulesCheckDescription = "prometheus recording rules are loaded"
+
+	// These mirror reqQuery, latencyQuantileQuery, tcpReadBytesQuery and
+	// tcpWriteBytesQuery, taking the same arguments, but query the series
+	// produced by the recording rules instead.
+	recordedReqQuery             = "sum(linkerd:response_total:increase1m%[1]s) by (%[3]s, classification, tls)"
+	recordedLatencyQuantileQuery = "histogram_quantile(%[1]s, sum(linkerd:response_latency_ms_bucket:irate1m%[2]s) by (le, %[4]s))"
+	recordedTCPReadBytesQuery    = "sum(linkerd:tcp_read_bytes_total:increase1m%[1]s) by (%[3]s)"
+	recordedTCPWriteBytesQuery   = "sum(linkerd:tcp_write_bytes_total:increase1m%[1]s) by (%[3]s)"
+)
+
+var (
+	// recordedSeries are the series produced by the recording rules shipped
+	// with the viz chart. All of them must be loaded and healthy for the
+	// recorded series to be preferred over the raw ones.
+	recordedSeries = []string{
+		"linkerd:response_total:increase1m",
+		"linkerd:response_latency_ms_bucket:irate1m",
+		"linkerd:tcp_read_bytes_total:increase1m",
+		"linkerd:tcp_write_bytes_total:increase1m",
+	}
+
+	// recordedSeriesDroppedLabels are aggregated away by the recording rules,
+	// so queries filtering or grouping by them must use the raw series.
+	recordedSeriesDroppedLabels = []model.LabelName{"instance", "pod", "pod_template_hash"}
+)
+
+// recordingRulesState caches whether the recording rules are loaded in
+// Prometheus.
+type recordingRulesState struct {
+	sync.Mutex
+	loaded    bool
+	checkedAt time.Time
+}
+
+// useRecordedSeries returns whether a stat query with the given labels,
+// grouping and time window can be served from the recorded series.
+func (s *grpcServer) useRecordedSeries(ctx context.Context, timeWindow string, labels model.LabelSet, groupBy model.LabelNames) bool {
+	if timeWindow != recordingRulesWindow {
+		return false
+	}
+	for _, dropped := range recordedSeriesDroppedLabels {
+		if _, ok := labels[dropped]; ok {
+			return false
+		}
+		for _, name := range groupBy {
+			if name == dropped {
+				return false
+			}
+		}
+	}
+	return s.recordingRulesLoaded(ctx)
+}
+
+// recordingRulesLoaded returns whether all the recording rules are loaded and
+// healthy in Prometheus. The result is cached for
+// recordingRulesRefreshInterval so the rules API isn't hit on every query.
+func (s *grpcServer) recordingRulesLoaded(ctx context.Context) bool {
+	s.recordingRules.Lock()
+	defer s.recordingRules.Unlock()
+
+	if !s.recordingRules.checkedAt.IsZero() && time.Since(s.recordingRules.checkedAt) < recordingRulesRefreshInterval {
+		return s.recordingRules.loaded
+	}
+
+	err := s.checkRecordingRules(ctx)
+	if err != nil {
+		log.Debugf("Not using recorded series: %s", err)
+	}
+	if loaded := err == nil; loaded != s.recordingRules.loaded {
+		log.Infof("Recording rules loaded in Prometheus: %t", loaded)
+		s.recordingRules.loaded = loaded
+	}
+	s.recordingRules.checkedAt = time.Now()
+	return s.recordingRules.loaded
+}
+
+// checkRecordingRules returns an error if any of the recorded series isn't
+// produced by a healthy recording rule in Prometheus.
+func (s *grpcServer) checkRecordingRules(ctx context.Context) error {
+	if s.prometheusAPI == nil {
+		return ErrNoPrometheusInstance
+	}
+
+	res, err := s.prometheusAPI.Rules(ctx)
+	if err != nil {
+		return err
+	}
+
+	health := make(map[string]promv1.RuleHealth)
+	for _, group := range res.Groups {
+		for _, rule := range group.Rules {
+			if recording, ok := rule.(promv1.RecordingRule); ok {
+				health[recording.Name] = recording.Health
+			}
+		}
+	}
+
+	for _, name := range recordedSeries {
+		h, ok := health[name]
+		if !ok {
+			return fmt.Errorf("recording rule %s is not loaded", name)
+		}
+		if h != promv1.RuleHealthGood {
+			return fmt.Errorf("recording rule %s is not healthy: %s", name, h)
+		}
+	}
+	return nil
+}
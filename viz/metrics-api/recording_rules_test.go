@@ -0,0 +1,100 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/linkerd/linkerd2/controller/k8s"
+	"github.com/linkerd/linkerd2/pkg/prometheus"
+	promv1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+func recordingRulesResult(health promv1.RuleHealth, names ...string) promv1.RulesResult {
+	rules := promv1.Rules{}
+	for _, name := range names {
+		rules = append(rules, promv1.RecordingRule{Name: name, Health: health})
+	}
+	return promv1.RulesResult{
+		Groups: []promv1.RuleGroup{{Name: "linkerd-viz-stat", Rules: rules}},
+	}
+}
+
+func TestUseRecordedSeries(t *testing.T) {
+	testCases := []struct {
+		name       string
+		rules      promv1.RulesResult
+		timeWindow string
+		labels     model.LabelSet
+		groupBy    model.LabelNames
+		expected   bool
+	}{
+		{
+			name:       "all rules loaded",
+			rules:      recordingRulesResult(promv1.RuleHealthGood, recordedSeries...),
+			timeWindow: "1m",
+			labels:     model.LabelSet{"direction": "inbound"},
+			groupBy:    model.LabelNames{"namespace", "deployment"},
+			expected:   true,
+		},
+		{
+			name:       "no rules loaded",
+			timeWindow: "1m",
+			groupBy:    model.LabelNames{"namespace", "deployment"},
+			expected:   false,
+		},
+		{
+			name:       "missing rule",
+			rules:      recordingRulesResult(promv1.RuleHealthGood, recordedSeries[1:]...),
+			timeWindow: "1m",
+			groupBy:    model.LabelNames{"namespace", "deployment"},
+			expected:   false,
+		},
+		{
+			name:       "unhealthy rules",
+			rules:      recordingRulesResult(promv1.RuleHealthBad, recordedSeries...),
+			timeWindow: "1m",
+			groupBy:    model.LabelNames{"namespace", "deployment"},
+			expected:   false,
+		},
+		{
+			name:       "different time window",
+			rules:      recordingRulesResult(promv1.RuleHealthGood, recordedSeries...),
+			timeWindow: "10m",
+			groupBy:    model.LabelNames{"namespace", "deployment"},
+			expected:   false,
+		},
+		{
+			name:       "grouped by pod",
+			rules:      recordingRulesResult(promv1.RuleHealthGood, recordedSeries...),
+			timeWindow: "1m",
+			groupBy:    model.LabelNames{"namespace", "pod"},
+			expected:   false,
+		},
+		{
+			name:       "filtered by pod",
+			rules:      recordingRulesResult(promv1.RuleHealthGood, recordedSeries...),
+			timeWindow: "1m",
+			labels:     model.LabelSet{"pod": "emoji-0"},
+			groupBy:    model.LabelNames{"namespace"},
+			expected:   false,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			k8sAPI, err := k8s.NewFakeAPI()
+			if err != nil {
+				t.Fatalf("NewFakeAPI returned an error: %s", err)
+			}
+			mockProm := &prometheus.MockProm{RulesRes: tc.rules}
+			server := newGrpcServer(mockProm, k8sAPI, "linkerd", "cluster.local", []string{}, true)
+
+			actual := server.useRecordedSeries(context.Background(), tc.timeWindow, tc.labels, tc.groupBy)
+			if actual != tc.expected {
+				t.Fatalf("Expected useRecordedSeries to be %t, got %t", tc.expected, actual)
+			}
+		})
+	}
+}
@@ -35,6 +35,13 @@ type rKey struct {
 	Name      string
 }
 
+// clientKey identifies the stats of a resource restricted to the requests of
+// the clients with a given identity.
+type clientKey struct {
+	rKey
+	clientID string
+}
+
 type tsKey struct {
 	Namespace string
 	Type      string
@@ -81,6 +88,19 @@ func (s *grpcServer) StatSummary(ctx context.Context, req *pb.StatSummaryRequest
 		return statSummaryError(req, "service only supported as a target on 'from' queries, or as a destination on 'to' queries"), nil
 	}
 
+	if req.ByClient {
+		if req.GetToResource() != nil || req.GetFromResource() != nil {
+			return statSummaryError(req, "stats broken down by client are only supported for inbound traffic"), nil
+		}
+		if !isClientBreakdownSupported(req.Selector.Resource.Type) {
+			return statSummaryError(req, fmt.Sprintf("stats broken down by client are not supported for %s resources", req.Selector.Resource.Type)), nil
+		}
+	}
+
+	if req.SuccessRateObjective < 0 || req.SuccessRateObjective >= 1 {
+		return statSummaryError(req, "success rate objective must be between 0 and 1"), nil
+	}
+
 	switch req.Outbound.(type) {
 	case *pb.StatSummaryRequest_ToResource:
 		if req.Outbound.(*pb.StatSummaryRequest_ToResource).ToResource.Type == k8s.All {
@@ -96,7 +116,11 @@ func (s *grpcServer) StatSummary(ctx context.Context, req *pb.StatSummaryRequest
 
 	var resourcesToQuery []string
 	if req.Selector.Resource.Type == k8s.All {
-		resourcesToQuery = k8s.StatAllResourceTypes
+		for _, resource := range k8s.StatAllResourceTypes {
+			if !req.ByClient || isClientBreakdownSupported(resource) {
+				resourcesToQuery = append(resourcesToQuery, resource)
+			}
+		}
 	} else {
 		resourcesToQuery = []string{req.Selector.Resource.Type}
 	}
@@ -111,6 +135,8 @@ func (s *grpcServer) StatSummary(ctx context.Context, req *pb.StatSummaryRequest
 		go func() {
 			if isNonK8sResourceQuery(statReq.GetSelector().GetResource().GetType()) {
 				resultChan <- s.nonK8sResourceQuery(ctx, statReq)
+			} else if statReq.ByClient {
+				resultChan <- s.clientResourceQuery(ctx, statReq)
 			} else if isTrafficSplitQuery(statReq.GetSelector().GetResource().GetType()) {
 				resultChan <- s.trafficSplitResourceQuery(ctx, statReq)
 			} else {
@@ -127,6 +153,12 @@ func (s *grpcServer) StatSummary(ctx context.Context, req *pb.StatSummaryRequest
 		statTables = append(statTables, result.res)
 	}
 
+	if req.DeploymentMarkers || req.SuccessRateObjective > 0 {
+		if err := s.addOverlays(ctx, req, statTables); err != nil {
+			return nil, util.GRPCError(err)
+		}
+	}
+
 	rsp := pb.StatSummaryResponse{
 		Response: &pb.StatSummaryResponse_Ok_{ // https://github.com/golang/protobuf/issues/205
 			Ok: &pb.StatSummaryResponse_Ok{
@@ -203,13 +235,19 @@ func (s *grpcServer) k8sResourceQuery(ctx context.Context, req *pb.StatSummaryRe
 		return resourceResult{res: nil, err: err}
 	}
 
-	var requestMetrics map[rKey]*pb.BasicStats
+	var requestMetrics, baselineMetrics map[rKey]*pb.BasicStats
 	var tcpMetrics map[rKey]*pb.TcpStats
 	if !req.SkipStats {
 		requestMetrics, tcpMetrics, err = s.getStatMetrics(ctx, req, req.TimeWindow)
 		if err != nil {
 			return resourceResult{res: nil, err: err}
 		}
+		if req.Baseline {
+			baselineMetrics, err = s.getBaselineStatMetrics(ctx, req)
+			if err != nil {
+				return resourceResult{res: nil, err: err}
+			}
+		}
 	}
 
 	rows := make([]*pb.StatTable_PodGroup_Row, 0)
@@ -238,9 +276,10 @@ func (s *grpcServer) k8sResourceQuery(ctx context.Context, req *pb.StatSummaryRe
 				Namespace: k8sResource.GetNamespace(),
 				Type:      req.GetSelector().GetResource().GetType(),
 			},
-			TimeWindow: req.TimeWindow,
-			Stats:      basicStats,
-			TcpStats:   tcpStats,
+			TimeWindow:    req.TimeWindow,
+			Stats:         basicStats,
+			TcpStats:      tcpStats,
+			BaselineStats: baselineMetrics[key],
 		}
 
 		podStat := objInfo.podStats
@@ -369,13 +408,19 @@ func sortTrafficSplitRows(rows []*pb.StatTable_PodGroup_Row) []*pb.StatTable_Pod
 }
 
 func (s *grpcServer) nonK8sResourceQuery(ctx context.Context, req *pb.StatSummaryRequest) resourceResult {
-	var requestMetrics map[rKey]*pb.BasicStats
+	var requestMetrics, baselineMetrics map[rKey]*pb.BasicStats
 	if !req.SkipStats {
 		var err error
 		requestMetrics, _, err = s.getStatMetrics(ctx, req, req.TimeWindow)
 		if err != nil {
 			return resourceResult{res: nil, err: err}
 		}
+		if req.Baseline {
+			baselineMetrics, err = s.getBaselineStatMetrics(ctx, req)
+			if err != nil {
+				return resourceResult{res: nil, err: err}
+			}
+		}
 	}
 	rows := make([]*pb.StatTable_PodGroup_Row, 0)
 
@@ -388,8 +433,9 @@ func (s *grpcServer) nonK8sResourceQuery(ctx context.Context, req *pb.StatSummar
 				Namespace: rkey.Namespace,
 				Name:      rkey.Name,
 			},
-			TimeWindow: req.TimeWindow,
-			Stats:      metrics,
+			TimeWindow:    req.TimeWindow,
+			Stats:         metrics,
+			BaselineStats: baselineMetrics[rkey],
 		}
 		rows = append(rows, &row)
 	}
@@ -404,6 +450,58 @@ func (s *grpcServer) nonK8sResourceQuery(ctx context.Context, req *pb.StatSummar
 	return resourceResult{res: &rsp, err: nil}
 }
 
+// clientResourceQuery returns the inbound stats of the requested resources
+// broken down by client identity, with a row for each client of each
+// resource, so that the callers of a workload can be told apart.
+func (s *grpcServer) clientResourceQuery(ctx context.Context, req *pb.StatSummaryRequest) resourceResult {
+	k8sObjects, err := s.getKubernetesObjectStats(req)
+	if err != nil {
+		return resourceResult{res: nil, err: err}
+	}
+
+	var clientMetrics map[clientKey]*pb.BasicStats
+	if !req.SkipStats {
+		clientMetrics, err = s.getClientStatMetrics(ctx, req)
+		if err != nil {
+			return resourceResult{res: nil, err: err}
+		}
+	}
+
+	rows := make([]*pb.StatTable_PodGroup_Row, 0)
+	for key, stats := range clientMetrics {
+		objInfo, ok := k8sObjects[key.rKey]
+		if !ok {
+			continue
+		}
+
+		k8sResource := objInfo.object
+		podStat := objInfo.podStats
+		rows = append(rows, &pb.StatTable_PodGroup_Row{
+			Resource: &pb.Resource{
+				Name:      k8sResource.GetName(),
+				Namespace: k8sResource.GetNamespace(),
+				Type:      req.GetSelector().GetResource().GetType(),
+			},
+			TimeWindow:      req.TimeWindow,
+			Status:          podStat.status,
+			MeshedPodCount:  podStat.inMesh,
+			RunningPodCount: podStat.total,
+			FailedPodCount:  podStat.failed,
+			Stats:           stats,
+			ClientId:        key.clientID,
+		})
+	}
+
+	rsp := pb.StatTable{
+		Table: &pb.StatTable_PodGroup_{
+			PodGroup: &pb.StatTable_PodGroup{
+				Rows: rows,
+			},
+		},
+	}
+	return resourceResult{res: &rsp, err: nil}
+}
+
 func isNonK8sResourceQuery(resourceType string) bool {
 	return resourceType == k8s.Authority
 }
@@ -412,6 +510,13 @@ func isTrafficSplitQuery(resourceType string) bool {
 	return resourceType == k8s.TrafficSplit
 }
 
+// isClientBreakdownSupported returns whether the inbound stats of the given
+// resource type can be broken down by client identity, which is only
+// recorded by the proxies of the pods receiving the requests.
+func isClientBreakdownSupported(resourceType string) bool {
+	return !isNonK8sResourceQuery(resourceType) && !isTrafficSplitQuery(resourceType) && resourceType != k8s.Service
+}
+
 // get the list of objects for which we want to return results
 func getResultKeys(
 	req *pb.StatSummaryRequest,
@@ -505,19 +610,27 @@ func buildTCPStatsRequestLabels(req *pb.StatSummaryRequest, reqLabels model.Labe
 
 func (s *grpcServer) getStatMetrics(ctx context.Context, req *pb.StatSummaryRequest, timeWindow string) (map[rKey]*pb.BasicStats, map[rKey]*pb.TcpStats, error) {
 	reqLabels, groupBy := buildRequestLabels(req)
+
+	// Prefer the series pre-aggregated by the recording rules when they're
+	// loaded and can serve this query.
+	requestQuery, quantileQuery, readBytesQuery, writeBytesQuery := reqQuery, latencyQuantileQuery, tcpReadBytesQuery, tcpWriteBytesQuery
+	if s.useRecordedSeries(ctx, timeWindow, reqLabels, groupBy) {
+		requestQuery, quantileQuery, readBytesQuery, writeBytesQuery = recordedReqQuery, recordedLatencyQuantileQuery, recordedTCPReadBytesQuery, recordedTCPWriteBytesQuery
+	}
+
 	promQueries := map[promType]string{
-		promRequests: fmt.Sprintf(reqQuery, reqLabels.String(), timeWindow, groupBy.String()),
+		promRequests: fmt.Sprintf(requestQuery, reqLabels.String(), timeWindow, groupBy.String()),
 	}
 
 	if req.TcpStats {
 		promQueries[promTCPConnections] = fmt.Sprintf(tcpConnectionsQuery, reqLabels.String(), groupBy.String())
 		// For TCP read/write bytes total we add an additional 'peer' label with a value of either 'src' or 'dst'
 		tcpLabels := buildTCPStatsRequestLabels(req, reqLabels)
-		promQueries[promTCPReadBytes] = fmt.Sprintf(tcpReadBytesQuery, tcpLabels, timeWindow, groupBy.String())
-		promQueries[promTCPWriteBytes] = fmt.Sprintf(tcpWriteBytesQuery, tcpLabels, timeWindow, groupBy.String())
+		promQueries[promTCPReadBytes] = fmt.Sprintf(readBytesQuery, tcpLabels, timeWindow, groupBy.String())
+		promQueries[promTCPWriteBytes] = fmt.Sprintf(writeBytesQuery, tcpLabels, timeWindow, groupBy.String())
 	}
 
-	quantileQueries := generateQuantileQueries(latencyQuantileQuery, reqLabels.String(), timeWindow, groupBy.String())
+	quantileQueries := generateQuantileQueries(quantileQuery, reqLabels.String(), timeWindow, groupBy.String())
 	results, err := s.getPrometheusMetrics(ctx, promQueries, quantileQueries)
 
 	if err != nil {
@@ -528,6 +641,79 @@ func (s *grpcServer) getStatMetrics(ctx context.Context, req *pb.StatSummaryRequ
 	return basicStats, tcpStats, nil
 }
 
+// getBaselineStatMetrics returns the request stats over the time window
+// preceding the requested one, which the current stats can be compared with.
+// The recorded series aren't used, as the range selectors of their queries
+// don't match the requested window.
+func (s *grpcServer) getBaselineStatMetrics(ctx context.Context, req *pb.StatSummaryRequest) (map[rKey]*pb.BasicStats, error) {
+	reqLabels, groupBy := buildRequestLabels(req)
+
+	promQueries := map[promType]string{
+		promRequests: offsetQuery(fmt.Sprintf(reqQuery, reqLabels.String(), req.TimeWindow, groupBy.String()), req.TimeWindow),
+	}
+	quantileQueries := generateQuantileQueries(latencyQuantileQuery, reqLabels.String(), req.TimeWindow, groupBy.String())
+	for quantile, query := range quantileQueries {
+		quantileQueries[quantile] = offsetQuery(query, req.TimeWindow)
+	}
+
+	results, err := s.getPrometheusMetrics(ctx, promQueries, quantileQueries)
+	if err != nil {
+		return nil, err
+	}
+
+	basicStats, _ := processPrometheusMetrics(req, results, groupBy)
+	return basicStats, nil
+}
+
+// getClientStatMetrics returns the inbound request stats of the requested
+// resources, further grouped by the identity of their clients. The recorded
+// series aren't used, as they aren't grouped by client.
+func (s *grpcServer) getClientStatMetrics(ctx context.Context, req *pb.StatSummaryRequest) (map[clientKey]*pb.BasicStats, error) {
+	reqLabels, groupBy := buildRequestLabels(req)
+	clientGroupBy := append(model.LabelNames{clientIDLabel}, groupBy...)
+
+	promQueries := map[promType]string{
+		promRequests: fmt.Sprintf(reqQuery, reqLabels.String(), req.TimeWindow, clientGroupBy.String()),
+	}
+	quantileQueries := generateQuantileQueries(latencyQuantileQuery, reqLabels.String(), req.TimeWindow, clientGroupBy.String())
+
+	results, err := s.getPrometheusMetrics(ctx, promQueries, quantileQueries)
+	if err != nil {
+		return nil, err
+	}
+
+	basicStats := make(map[clientKey]*pb.BasicStats)
+	for _, result := range results {
+		for _, sample := range result.vec {
+			key := clientKey{
+				rKey:     metricToKey(req, sample.Metric, groupBy),
+				clientID: string(sample.Metric[clientIDLabel]),
+			}
+			if basicStats[key] == nil {
+				basicStats[key] = &pb.BasicStats{}
+			}
+
+			value := extractSampleValue(sample)
+			switch result.prom {
+			case promRequests:
+				switch string(sample.Metric[model.LabelName("classification")]) {
+				case success:
+					basicStats[key].SuccessCount += value
+				case failure:
+					basicStats[key].FailureCount += value
+				}
+			case promLatencyP50:
+				basicStats[key].LatencyMsP50 = value
+			case promLatencyP95:
+				basicStats[key].LatencyMsP95 = value
+			case promLatencyP99:
+				basicStats[key].LatencyMsP99 = value
+			}
+		}
+	}
+	return basicStats, nil
+}
+
 func (s *grpcServer) getTrafficSplitMetrics(ctx context.Context, req *pb.StatSummaryRequest, tsStats *trafficSplitStats, timeWindow string) (map[tsKey]*pb.BasicStats, error) {
 	tsBasicStats := make(map[tsKey]*pb.BasicStats)
 	labels, groupBy := buildTrafficSplitRequestLabels(req)
@@ -1480,6 +1480,7 @@ status:
 				"linkerd",
 				"mycluster.local",
 				[]string{},
+				false,
 			)
 
 			_, err := fakeGrpcServer.StatSummary(context.TODO(), exp.req)
@@ -1504,6 +1505,7 @@ status:
 			"linkerd",
 			"mycluster.local",
 			[]string{},
+			false,
 		)
 
 		invalidRequests := []statSumExpected{
@@ -1955,4 +1957,119 @@ status:
 
 		testStatSummary(t, expectations)
 	})
+
+	t.Run("Queries prometheus for the stats of the preceding time window when a baseline is requested", func(t *testing.T) {
+		expectedResponse := GenStatSummaryResponse("emojivoto-1", pkgK8s.Pod, []string{"emojivoto"}, &PodCounts{
+			Status:      "Running",
+			MeshedPods:  1,
+			RunningPods: 1,
+			FailedPods:  0,
+		}, true, false)
+		for _, row := range expectedResponse.GetOk().StatTables[0].GetPodGroup().Rows {
+			row.BaselineStats = proto.Clone(row.Stats).(*pb.BasicStats)
+		}
+
+		expectations := []statSumExpected{
+			{
+				expectedStatRPC: expectedStatRPC{
+					err: nil,
+					k8sConfigs: []string{`
+apiVersion: v1
+kind: Pod
+metadata:
+  name: emojivoto-1
+  namespace: emojivoto
+  labels:
+    app: emoji-svc
+    linkerd.io/control-plane-ns: linkerd
+status:
+  phase: Running
+`,
+					},
+					mockPromResponse: prometheusMetric("emojivoto-1", "pod"),
+					expectedPrometheusQueries: []string{
+						`histogram_quantile(0.5, sum(irate(response_latency_ms_bucket{direction="inbound", namespace="emojivoto", pod="emojivoto-1"}[1m])) by (le, namespace, pod))`,
+						`histogram_quantile(0.5, sum(irate(response_latency_ms_bucket{direction="inbound", namespace="emojivoto", pod="emojivoto-1"}[1m] offset 1m)) by (le, namespace, pod))`,
+						`histogram_quantile(0.95, sum(irate(response_latency_ms_bucket{direction="inbound", namespace="emojivoto", pod="emojivoto-1"}[1m])) by (le, namespace, pod))`,
+						`histogram_quantile(0.95, sum(irate(response_latency_ms_bucket{direction="inbound", namespace="emojivoto", pod="emojivoto-1"}[1m] offset 1m)) by (le, namespace, pod))`,
+						`histogram_quantile(0.99, sum(irate(response_latency_ms_bucket{direction="inbound", namespace="emojivoto", pod="emojivoto-1"}[1m])) by (le, namespace, pod))`,
+						`histogram_quantile(0.99, sum(irate(response_latency_ms_bucket{direction="inbound", namespace="emojivoto", pod="emojivoto-1"}[1m] offset 1m)) by (le, namespace, pod))`,
+						`sum(increase(response_total{direction="inbound", namespace="emojivoto", pod="emojivoto-1"}[1m])) by (namespace, pod, classification, tls)`,
+						`sum(increase(response_total{direction="inbound", namespace="emojivoto", pod="emojivoto-1"}[1m] offset 1m)) by (namespace, pod, classification, tls)`,
+					},
+				},
+				req: &pb.StatSummaryRequest{
+					Selector: &pb.ResourceSelection{
+						Resource: &pb.Resource{
+							Name:      "emojivoto-1",
+							Namespace: "emojivoto",
+							Type:      pkgK8s.Pod,
+						},
+					},
+					TimeWindow: "1m",
+					Baseline:   true,
+				},
+				expectedResponse: expectedResponse,
+			},
+		}
+
+		testStatSummary(t, expectations)
+	})
+
+	t.Run("Breaks the inbound stats down by client identity when requested", func(t *testing.T) {
+		expectedResponse := GenStatSummaryResponse("emojivoto-1", pkgK8s.Pod, []string{"emojivoto"}, &PodCounts{
+			Status:      "Running",
+			MeshedPods:  1,
+			RunningPods: 1,
+			FailedPods:  0,
+		}, true, false)
+		for _, row := range expectedResponse.GetOk().StatTables[0].GetPodGroup().Rows {
+			row.ClientId = "web.emojivoto.serviceaccount.identity.linkerd.cluster.local"
+		}
+
+		sample := genPromSample("emojivoto-1", "pod", "emojivoto", false)
+		sample.Metric[clientIDLabel] = "web.emojivoto.serviceaccount.identity.linkerd.cluster.local"
+
+		expectations := []statSumExpected{
+			{
+				expectedStatRPC: expectedStatRPC{
+					err: nil,
+					k8sConfigs: []string{`
+apiVersion: v1
+kind: Pod
+metadata:
+  name: emojivoto-1
+  namespace: emojivoto
+  labels:
+    app: emoji-svc
+    linkerd.io/control-plane-ns: linkerd
+status:
+  phase: Running
+`,
+					},
+					mockPromResponse: model.Vector{sample},
+					expectedPrometheusQueries: []string{
+						`histogram_quantile(0.5, sum(irate(response_latency_ms_bucket{direction="inbound", namespace="emojivoto", pod="emojivoto-1"}[1m])) by (le, client_id, namespace, pod))`,
+						`histogram_quantile(0.95, sum(irate(response_latency_ms_bucket{direction="inbound", namespace="emojivoto", pod="emojivoto-1"}[1m])) by (le, client_id, namespace, pod))`,
+						`histogram_quantile(0.99, sum(irate(response_latency_ms_bucket{direction="inbound", namespace="emojivoto", pod="emojivoto-1"}[1m])) by (le, client_id, namespace, pod))`,
+						`sum(increase(response_total{direction="inbound", namespace="emojivoto", pod="emojivoto-1"}[1m])) by (client_id, namespace, pod, classification, tls)`,
+					},
+				},
+				req: &pb.StatSummaryRequest{
+					Selector: &pb.ResourceSelection{
+						Resource: &pb.Resource{
+							Name:      "emojivoto-1",
+							Namespace: "emojivoto",
+							Type:      pkgK8s.Pod,
+						},
+					},
+					TimeWindow: "1m",
+					ByClient:   true,
+				},
+				expectedResponse: expectedResponse,
+			},
+		}
+
+		testStatSummary(t, expectations)
+	})
 }
@@ -0,0 +1,408 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linkerd/linkerd2/controller/k8s"
+	pkgK8s "github.com/linkerd/linkerd2/pkg/k8s"
+	pb "github.com/linkerd/linkerd2/viz/metrics-api/gen/viz"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	authnv1 "k8s.io/api/authentication/v1"
+	authzv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// tenantCacheTTL is how long the namespaces a token is authorized for are
+// cached, so that the dashboards polling the API don't issue a
+// SubjectAccessReview per namespace and per request.
+const tenantCacheTTL = 30 * time.Second
+
+type (
+	tenantContextKey struct{}
+	tokenContextKey  struct{}
+)
+
+// tenant is the caller of a request in tenancy mode, who can only query the
+// metrics of the namespaces they're authorized to list the pods of. A nil
+// tenant is unrestricted.
+type tenant struct {
+	user       string
+	namespaces map[string]struct{}
+}
+
+func withTenant(ctx context.Context, t *tenant) context.Context {
+	return context.WithValue(ctx, tenantContextKey{}, t)
+}
+
+func tenantFromContext(ctx context.Context) *tenant {
+	t, _ := ctx.Value(tenantContextKey{}).(*tenant)
+	return t
+}
+
+// withToken stores the bearer token of an HTTP request in its context, where
+// the gRPC requests carry it in their metadata.
+func withToken(ctx context.Context, token string) context.Context {
+	return context.WithValue(ctx, tokenContextKey{}, token)
+}
+
+func tokenFromContext(ctx context.Context) string {
+	if token, ok := ctx.Value(tokenContextKey{}).(string); ok {
+		return token
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, value := range md.Get("authorization") {
+			if strings.HasPrefix(value, "Bearer ") {
+				return strings.TrimPrefix(value, "Bearer ")
+			}
+		}
+	}
+	return ""
+}
+
+func (t *tenant) allows(namespace string) bool {
+	if t == nil {
+		return true
+	}
+	_, ok := t.namespaces[namespace]
+	return ok
+}
+
+// check returns a PermissionDenied error if any of the given namespaces isn't
+// allowed; the empty namespace stands for all namespaces, whose results are
+// filtered instead.
+func (t *tenant) check(namespaces ...string) error {
+	for _, namespace := range namespaces {
+		if namespace != "" && !t.allows(namespace) {
+			return status.Errorf(codes.PermissionDenied, "%s isn't authorized to query the metrics of namespace %s", t.user, namespace)
+		}
+	}
+	return nil
+}
+
+// restrict adds a matcher on the namespace label to every selector of a
+// query, so that it only covers the series of the allowed namespaces. This
+// relies on the queries of this package always selecting series with braces,
+// even when they have no other matcher.
+func (t *tenant) restrict(query string) string {
+	if t == nil {
+		return query
+	}
+	names := make([]string, 0, len(t.namespaces))
+	for namespace := range t.namespaces {
+		names = append(names, regexp.QuoteMeta(namespace))
+	}
+	sort.Strings(names)
+	matcher := fmt.Sprintf("%s=~%q", namespaceLabel, strings.Join(names, "|"))
+
+	var b strings.Builder
+	inString := false
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		b.WriteByte(c)
+		switch {
+		case inString && c == '\\' && i+1 < len(query):
+			i++
+			b.WriteByte(query[i])
+		case c == '"':
+			inString = !inString
+		case !inString && c == '{':
+			b.WriteString(matcher)
+			if rest := strings.TrimLeft(query[i+1:], " "); !strings.HasPrefix(rest, "}") {
+				b.WriteString(", ")
+			}
+		}
+	}
+	return b.String()
+}
+
+// resourceNamespace returns the namespace a resource belongs to, or is.
+func resourceNamespace(resource *pb.Resource) string {
+	if resource.GetType() == pkgK8s.Namespace {
+		return resource.GetName()
+	}
+	return resource.GetNamespace()
+}
+
+type cachedTenant struct {
+	tenant *tenant
+	expiry time.Time
+}
+
+// tenantAuthorizer authenticates the bearer token of a request with a
+// TokenReview, and finds out the namespaces its user is authorized to list the
+// pods of with SubjectAccessReviews, like the API server would. This requires
+// the metrics-api to be bound to the system:auth-delegator ClusterRole.
+type tenantAuthorizer struct {
+	client kubernetes.Interface
+	k8sAPI *k8s.API
+
+	sync.Mutex
+	cache map[[sha256.Size]byte]cachedTenant
+}
+
+func newTenantAuthorizer(k8sAPI *k8s.API) *tenantAuthorizer {
+	return &tenantAuthorizer{
+		client: k8sAPI.Client,
+		k8sAPI: k8sAPI,
+		cache:  make(map[[sha256.Size]byte]cachedTenant),
+	}
+}
+
+// authorize returns the tenant of the given token, which is nil when its user
+// is authorized to list the pods of all namespaces.
+func (a *tenantAuthorizer) authorize(ctx context.Context, token string) (*tenant, error) {
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, "the metrics API is in tenancy mode and requires a bearer token")
+	}
+
+	key := sha256.Sum256([]byte(token))
+	a.Lock()
+	cached, ok := a.cache[key]
+	a.Unlock()
+	if ok && time.Now().Before(cached.expiry) {
+		return cached.tenant, nil
+	}
+
+	t, err := a.review(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	a.Lock()
+	now := time.Now()
+	for k, c := range a.cache {
+		if now.After(c.expiry) {
+			delete(a.cache, k)
+		}
+	}
+	a.cache[key] = cachedTenant{tenant: t, expiry: now.Add(tenantCacheTTL)}
+	a.Unlock()
+	return t, nil
+}
+
+func (a *tenantAuthorizer) review(ctx context.Context, token string) (*tenant, error) {
+	review, err := a.client.AuthenticationV1().TokenReviews().Create(ctx, &authnv1.TokenReview{
+		Spec: authnv1.TokenReviewSpec{Token: token},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to authenticate the token: %s", err)
+	}
+	if !review.Status.Authenticated {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid token: %s", review.Status.Error)
+	}
+	user := review.Status.User
+
+	// cluster-wide access needs no restriction
+	allowed, err := a.canListPods(ctx, user, "")
+	if err != nil {
+		return nil, err
+	}
+	if allowed {
+		return nil, nil
+	}
+
+	namespaces, err := a.k8sAPI.NS().Lister().List(labels.Everything())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to list the namespaces: %s", err)
+	}
+	t := &tenant{user: user.Username, namespaces: make(map[string]struct{})}
+	for _, ns := range namespaces {
+		allowed, err := a.canListPods(ctx, user, ns.Name)
+		if err != nil {
+			return nil, err
+		}
+		if allowed {
+			t.namespaces[ns.Name] = struct{}{}
+		}
+	}
+	if len(t.namespaces) == 0 {
+		return nil, status.Errorf(codes.PermissionDenied, "%s isn't authorized to query the metrics of any namespace", user.Username)
+	}
+	log.Debugf("%s is authorized to query the metrics of %d namespaces", user.Username, len(t.namespaces))
+	return t, nil
+}
+
+func (a *tenantAuthorizer) canListPods(ctx context.Context, user authnv1.UserInfo, namespace string) (bool, error) {
+	extra := make(map[string]authzv1.ExtraValue, len(user.Extra))
+	for k, v := range user.Extra {
+		extra[k] = authzv1.ExtraValue(v)
+	}
+	access, err := a.client.AuthorizationV1().SubjectAccessReviews().Create(ctx, &authzv1.SubjectAccessReview{
+		Spec: authzv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			Groups: user.Groups,
+			UID:    user.UID,
+			Extra:  extra,
+			ResourceAttributes: &authzv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      "list",
+				Resource:  "pods",
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return false, status.Errorf(codes.Internal, "failed to authorize %s: %s", user.Username, err)
+	}
+	return access.Status.Allowed, nil
+}
+
+// tenantServer enforces the tenancy mode: it authorizes the caller of each
+// request, rejects the requests targeting namespaces they aren't authorized
+// for, and filters the results of the requests across all namespaces. The
+// Prometheus queries of the wrapped server are restricted through the tenant
+// stored in the request's context.
+type tenantServer struct {
+	*grpcServer
+	authorizer *tenantAuthorizer
+}
+
+func newTenantServer(server *grpcServer, k8sAPI *k8s.API) *tenantServer {
+	return &tenantServer{grpcServer: server, authorizer: newTenantAuthorizer(k8sAPI)}
+}
+
+func (s *tenantServer) tenant(ctx context.Context) (context.Context, *tenant, error) {
+	t, err := s.authorizer.authorize(ctx, tokenFromContext(ctx))
+	if err != nil {
+		return nil, nil, err
+	}
+	return withTenant(ctx, t), t, nil
+}
+
+func (s *tenantServer) StatSummary(ctx context.Context, req *pb.StatSummaryRequest) (*pb.StatSummaryResponse, error) {
+	ctx, t, err := s.tenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.check(resourceNamespace(req.GetSelector().GetResource())); err != nil {
+		return nil, err
+	}
+	rsp, err := s.grpcServer.StatSummary(ctx, req)
+	if err != nil || t == nil {
+		return rsp, err
+	}
+	for _, table := range rsp.GetOk().GetStatTables() {
+		podGroup := table.GetPodGroup()
+		if podGroup == nil {
+			continue
+		}
+		rows := podGroup.Rows[:0]
+		for _, row := range podGroup.Rows {
+			if t.allows(resourceNamespace(row.GetResource())) {
+				rows = append(rows, row)
+			}
+		}
+		podGroup.Rows = rows
+	}
+	return rsp, nil
+}
+
+func (s *tenantServer) Edges(ctx context.Context, req *pb.EdgesRequest) (*pb.EdgesResponse, error) {
+	ctx, t, err := s.tenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.check(resourceNamespace(req.GetSelector().GetResource())); err != nil {
+		return nil, err
+	}
+	return s.grpcServer.Edges(ctx, req)
+}
+
+func (s *tenantServer) Gateways(ctx context.Context, req *pb.GatewaysRequest) (*pb.GatewaysResponse, error) {
+	ctx, t, err := s.tenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.check(req.GetGatewayNamespace()); err != nil {
+		return nil, err
+	}
+	return s.grpcServer.Gateways(ctx, req)
+}
+
+func (s *tenantServer) TopRoutes(ctx context.Context, req *pb.TopRoutesRequest) (*pb.TopRoutesResponse, error) {
+	ctx, t, err := s.tenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.check(resourceNamespace(req.GetSelector().GetResource())); err != nil {
+		return nil, err
+	}
+	return s.grpcServer.TopRoutes(ctx, req)
+}
+
+func (s *tenantServer) ListPods(ctx context.Context, req *pb.ListPodsRequest) (*pb.ListPodsResponse, error) {
+	ctx, t, err := s.tenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.check(req.GetNamespace(), resourceNamespace(req.GetSelector().GetResource())); err != nil {
+		return nil, err
+	}
+	rsp, err := s.grpcServer.ListPods(ctx, req)
+	if err != nil || t == nil {
+		return rsp, err
+	}
+	pods := rsp.Pods[:0]
+	for _, pod := range rsp.Pods {
+		// pod names are namespaced, e.g. emojivoto/web-5f86686c4d-58p7k
+		if t.allows(strings.SplitN(pod.GetName(), "/", 2)[0]) {
+			pods = append(pods, pod)
+		}
+	}
+	rsp.Pods = pods
+	return rsp, nil
+}
+
+func (s *tenantServer) ListServices(ctx context.Context, req *pb.ListServicesRequest) (*pb.ListServicesResponse, error) {
+	ctx, t, err := s.tenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.check(req.GetNamespace()); err != nil {
+		return nil, err
+	}
+	rsp, err := s.grpcServer.ListServices(ctx, req)
+	if err != nil || t == nil {
+		return rsp, err
+	}
+	services := rsp.Services[:0]
+	for _, svc := range rsp.Services {
+		if t.allows(svc.GetNamespace()) {
+			services = append(services, svc)
+		}
+	}
+	rsp.Services = services
+	return rsp, nil
+}
+
+func (s *tenantServer) SelfCheck(ctx context.Context, req *pb.SelfCheckRequest) (*pb.SelfCheckResponse, error) {
+	ctx, _, err := s.tenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.grpcServer.SelfCheck(ctx, req)
+}
+
+// recordDeploymentMarker only records the markers of the resources of the
+// namespaces the caller is authorized for.
+func (s *tenantServer) recordDeploymentMarker(ctx context.Context, marker *pb.DeploymentMarker) error {
+	ctx, t, err := s.tenant(ctx)
+	if err != nil {
+		return err
+	}
+	if err := t.check(marker.GetResource().GetNamespace()); err != nil {
+		return err
+	}
+	return s.grpcServer.recordDeploymentMarker(ctx, marker)
+}
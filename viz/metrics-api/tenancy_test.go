@@ -0,0 +1,454 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/linkerd/linkerd2/controller/k8s"
+	pkgK8s "github.com/linkerd/linkerd2/pkg/k8s"
+	"github.com/linkerd/linkerd2/pkg/prometheus"
+	pb "github.com/linkerd/linkerd2/viz/metrics-api/gen/viz"
+	"github.com/prometheus/common/model"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	authnv1 "k8s.io/api/authentication/v1"
+	authzv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestTenantRestrict(t *testing.T) {
+	team := &tenant{namespaces: map[string]struct{}{"emojivoto": {}, "books": {}}}
+
+	for _, tc := range []struct {
+		query    string
+		expected string
+	}{
+		{
+			query:    "max(process_start_time_seconds{}) by (pod, namespace)",
+			expected: `max(process_start_time_seconds{namespace=~"books|emojivoto"}) by (pod, namespace)`,
+		},
+		{
+			query:    `sum(increase(response_total{direction="inbound", namespace="emojivoto"}[1m])) by (namespace, deployment, classification, tls)`,
+			expected: `sum(increase(response_total{namespace=~"books|emojivoto", direction="inbound", namespace="emojivoto"}[1m])) by (namespace, deployment, classification, tls)`,
+		},
+		{
+			query:    `count(response_total{deployment=~"^web{1}.+"} or tcp_open_connections{ }) by (namespace)`,
+			expected: `count(response_total{namespace=~"books|emojivoto", deployment=~"^web{1}.+"} or tcp_open_connections{namespace=~"books|emojivoto" }) by (namespace)`,
+		},
+	} {
+		if restricted := team.restrict(tc.query); restricted != tc.expected {
+			t.Errorf("Expected\n%s\ngot\n%s", tc.expected, restricted)
+		}
+	}
+
+	var unrestricted *tenant
+	if query := "sum(tcp_open_connections{}) by (namespace)"; unrestricted.restrict(query) != query {
+		t.Error("Expected the queries of an unrestricted tenant to be left as is")
+	}
+}
+
+// newFakeTenantAPI returns a fake API with the emojivoto and books namespaces
+// along with the given objects, authenticating the tokens of three users:
+// admin can list the pods of all namespaces, emojivoto-team can only list the
+// ones of emojivoto through its group, and nobody can't list any. The
+// failing-token fails to be reviewed. The returned counter is incremented by
+// each TokenReview.
+func newFakeTenantAPI(t *testing.T, configs ...string) (*k8s.API, *int) {
+	t.Helper()
+	k8sAPI, err := k8s.NewFakeAPI(append([]string{`
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: emojivoto
+`, `
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: books
+`}, configs...)...)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	reviews := 0
+	client := k8sAPI.Client.(*fake.Clientset)
+	client.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		reviews++
+		review := action.(k8stesting.CreateAction).GetObject().(*authnv1.TokenReview)
+		switch review.Spec.Token {
+		case "admin-token":
+			review.Status = authnv1.TokenReviewStatus{Authenticated: true, User: authnv1.UserInfo{Username: "admin"}}
+		case "emojivoto-token":
+			review.Status = authnv1.TokenReviewStatus{Authenticated: true, User: authnv1.UserInfo{
+				Username: "emojivoto-team",
+				Groups:   []string{"emojivoto-devs"},
+			}}
+		case "nobody-token":
+			review.Status = authnv1.TokenReviewStatus{Authenticated: true, User: authnv1.UserInfo{Username: "nobody"}}
+		case "failing-token":
+			return true, nil, errors.New("the API server is unavailable")
+		default:
+			review.Status = authnv1.TokenReviewStatus{Error: "unknown token"}
+		}
+		return true, review, nil
+	})
+	client.PrependReactor("create", "subjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authzv1.SubjectAccessReview)
+		attrs := review.Spec.ResourceAttributes
+		inGroup := len(review.Spec.Groups) == 1 && review.Spec.Groups[0] == "emojivoto-devs"
+		review.Status.Allowed = attrs.Verb == "list" && attrs.Resource == "pods" &&
+			(review.Spec.User == "admin" || inGroup && attrs.Namespace == "emojivoto")
+		return true, review, nil
+	})
+	k8sAPI.Sync(nil)
+	return k8sAPI, &reviews
+}
+
+func TestTenantAuthorizerReview(t *testing.T) {
+	k8sAPI, _ := newFakeTenantAPI(t)
+	authorizer := newTenantAuthorizer(k8sAPI)
+
+	for _, tc := range []struct {
+		name       string
+		token      string
+		code       codes.Code
+		restricted bool
+		namespaces []string
+	}{
+		{name: "cluster-wide access", token: "admin-token"},
+		{name: "namespaced access", token: "emojivoto-token", restricted: true, namespaces: []string{"emojivoto"}},
+		{name: "no access", token: "nobody-token", code: codes.PermissionDenied},
+		{name: "unauthenticated token", token: "bogus", code: codes.Unauthenticated},
+		{name: "failed review", token: "failing-token", code: codes.Internal},
+	} {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			tenant, err := authorizer.review(context.Background(), tc.token)
+			if tc.code != codes.OK {
+				if status.Code(err) != tc.code {
+					t.Fatalf("Expected a %s error, got %v", tc.code, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if !tc.restricted {
+				if tenant != nil {
+					t.Fatalf("Expected an unrestricted tenant, got %+v", tenant)
+				}
+				return
+			}
+			if tenant == nil {
+				t.Fatal("Expected a restricted tenant")
+			}
+			var namespaces []string
+			for namespace := range tenant.namespaces {
+				namespaces = append(namespaces, namespace)
+			}
+			sort.Strings(namespaces)
+			if strings.Join(namespaces, ",") != strings.Join(tc.namespaces, ",") {
+				t.Fatalf("Expected the namespaces %v, got %v", tc.namespaces, namespaces)
+			}
+		})
+	}
+}
+
+func TestTenantAuthorizerCache(t *testing.T) {
+	k8sAPI, reviews := newFakeTenantAPI(t)
+	authorizer := newTenantAuthorizer(k8sAPI)
+	ctx := context.Background()
+
+	if _, err := authorizer.authorize(ctx, ""); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("Expected an Unauthenticated error, got %v", err)
+	}
+	if *reviews != 0 {
+		t.Fatalf("Expected no TokenReview without token, got %d", *reviews)
+	}
+
+	for i := 0; i < 3; i++ {
+		tenant, err := authorizer.authorize(ctx, "emojivoto-token")
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if !tenant.allows("emojivoto") || tenant.allows("books") {
+			t.Fatalf("Unexpected tenant: %+v", tenant)
+		}
+	}
+	if *reviews != 1 {
+		t.Fatalf("Expected the tenant to be cached after 1 TokenReview, got %d", *reviews)
+	}
+
+	if _, err := authorizer.authorize(ctx, "admin-token"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if *reviews != 2 {
+		t.Fatalf("Expected the tenants to be cached per token, got %d TokenReviews", *reviews)
+	}
+
+	// the errors aren't cached
+	for i := 0; i < 2; i++ {
+		if _, err := authorizer.authorize(ctx, "nobody-token"); status.Code(err) != codes.PermissionDenied {
+			t.Fatalf("Expected a PermissionDenied error, got %v", err)
+		}
+	}
+	if *reviews != 4 {
+		t.Fatalf("Expected the errors not to be cached, got %d TokenReviews", *reviews)
+	}
+
+	authorizer.Lock()
+	for key, cached := range authorizer.cache {
+		cached.expiry = time.Now().Add(-time.Second)
+		authorizer.cache[key] = cached
+	}
+	authorizer.Unlock()
+	if _, err := authorizer.authorize(ctx, "emojivoto-token"); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if *reviews != 5 {
+		t.Fatalf("Expected an expired tenant to be reviewed again, got %d TokenReviews", *reviews)
+	}
+	authorizer.Lock()
+	cached := len(authorizer.cache)
+	authorizer.Unlock()
+	if cached != 1 {
+		t.Fatalf("Expected the expired tenants to be evicted, got %d cached tenants", cached)
+	}
+}
+
+func TestTenantServer(t *testing.T) {
+	k8sAPI, _ := newFakeTenantAPI(t, `
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  namespace: emojivoto
+`, `
+apiVersion: v1
+kind: Service
+metadata:
+  name: webapp
+  namespace: books
+`, `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+  namespace: emojivoto
+spec:
+  selector:
+    matchLabels:
+      app: web-svc
+  template:
+    spec:
+      containers:
+      - image: buoyantio/emojivoto-web:v10
+`, `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: webapp
+  namespace: books
+spec:
+  selector:
+    matchLabels:
+      app: webapp
+  template:
+    spec:
+      containers:
+      - image: buoyantio/booksapp:v0.0.3
+`, `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: web-5f86686c4d-58p7k
+  namespace: emojivoto
+status:
+  phase: Running
+`, `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: webapp-7d5bcd8f6d-pdwnv
+  namespace: books
+status:
+  phase: Running
+`)
+
+	mockProm := &prometheus.MockProm{Res: model.Vector{}}
+	server := newTenantServer(newGrpcServer(mockProm, k8sAPI, "linkerd", "cluster.local", []string{}, false), k8sAPI)
+
+	deployments := func(namespace string) *pb.StatSummaryRequest {
+		return &pb.StatSummaryRequest{
+			Selector: &pb.ResourceSelection{
+				Resource: &pb.Resource{Namespace: namespace, Type: pkgK8s.Deployment},
+			},
+			TimeWindow: "1m",
+			SkipStats:  true,
+			Outbound:   &pb.StatSummaryRequest_None{None: &pb.Empty{}},
+		}
+	}
+	statSummary := func(ctx context.Context, namespace string) ([]string, error) {
+		rsp, err := server.StatSummary(ctx, deployments(namespace))
+		var rows []string
+		for _, table := range rsp.GetOk().GetStatTables() {
+			for _, row := range table.GetPodGroup().GetRows() {
+				rows = append(rows, row.GetResource().GetNamespace()+"/"+row.GetResource().GetName())
+			}
+		}
+		return rows, err
+	}
+	listServices := func(ctx context.Context, namespace string) ([]string, error) {
+		rsp, err := server.ListServices(ctx, &pb.ListServicesRequest{Namespace: namespace})
+		var services []string
+		for _, svc := range rsp.GetServices() {
+			services = append(services, svc.GetNamespace()+"/"+svc.GetName())
+		}
+		return services, err
+	}
+	listPods := func(ctx context.Context, namespace string) ([]string, error) {
+		rsp, err := server.ListPods(ctx, &pb.ListPodsRequest{Namespace: namespace})
+		var pods []string
+		for _, pod := range rsp.GetPods() {
+			pods = append(pods, pod.GetName())
+		}
+		return pods, err
+	}
+	edges := func(ctx context.Context, namespace string) ([]string, error) {
+		_, err := server.Edges(ctx, &pb.EdgesRequest{Selector: &pb.ResourceSelection{
+			Resource: &pb.Resource{Namespace: namespace, Type: pkgK8s.Deployment},
+		}})
+		return nil, err
+	}
+	topRoutes := func(ctx context.Context, namespace string) ([]string, error) {
+		_, err := server.TopRoutes(ctx, &pb.TopRoutesRequest{Selector: &pb.ResourceSelection{
+			Resource: &pb.Resource{Namespace: namespace, Type: pkgK8s.Deployment},
+		}})
+		return nil, err
+	}
+	gateways := func(ctx context.Context, namespace string) ([]string, error) {
+		_, err := server.Gateways(ctx, &pb.GatewaysRequest{GatewayNamespace: namespace})
+		return nil, err
+	}
+	selfCheck := func(ctx context.Context, _ string) ([]string, error) {
+		_, err := server.SelfCheck(ctx, &pb.SelfCheckRequest{})
+		return nil, err
+	}
+	recordMarker := func(ctx context.Context, namespace string) ([]string, error) {
+		err := server.recordDeploymentMarker(ctx, &pb.DeploymentMarker{
+			Resource: &pb.Resource{Namespace: namespace, Type: pkgK8s.Deployment, Name: "vote-bot"},
+		})
+		return nil, err
+	}
+
+	for _, tc := range []struct {
+		name      string
+		call      func(context.Context, string) ([]string, error)
+		token     string
+		namespace string
+		code      codes.Code
+		expected  []string
+	}{
+		{name: "no token", call: listServices, code: codes.Unauthenticated},
+		{name: "invalid token", call: listServices, token: "bogus", code: codes.Unauthenticated},
+		{name: "no namespace", call: listServices, token: "nobody-token", code: codes.PermissionDenied},
+		{name: "unauthorized namespace", call: listServices, token: "emojivoto-token", namespace: "books", code: codes.PermissionDenied},
+		{name: "authorized namespace", call: listServices, token: "emojivoto-token", namespace: "emojivoto", expected: []string{"emojivoto/web"}},
+		{name: "services of all namespaces", call: listServices, token: "emojivoto-token", expected: []string{"emojivoto/web"}},
+		{name: "services with cluster-wide access", call: listServices, token: "admin-token", expected: []string{"books/webapp", "emojivoto/web"}},
+
+		{name: "stat rows of all namespaces", call: statSummary, token: "emojivoto-token", expected: []string{"emojivoto/web"}},
+		{name: "stat rows with cluster-wide access", call: statSummary, token: "admin-token", expected: []string{"books/webapp", "emojivoto/web"}},
+		{name: "stat of an unauthorized namespace", call: statSummary, token: "emojivoto-token", namespace: "books", code: codes.PermissionDenied},
+
+		{name: "pods of all namespaces", call: listPods, token: "emojivoto-token", expected: []string{"emojivoto/web-5f86686c4d-58p7k"}},
+		{name: "pods with cluster-wide access", call: listPods, token: "admin-token", expected: []string{"books/webapp-7d5bcd8f6d-pdwnv", "emojivoto/web-5f86686c4d-58p7k"}},
+		{name: "pods of an unauthorized namespace", call: listPods, token: "emojivoto-token", namespace: "books", code: codes.PermissionDenied},
+
+		{name: "edges of an unauthorized namespace", call: edges, token: "emojivoto-token", namespace: "books", code: codes.PermissionDenied},
+		{name: "routes of an unauthorized namespace", call: topRoutes, token: "emojivoto-token", namespace: "books", code: codes.PermissionDenied},
+		{name: "gateways of an unauthorized namespace", call: gateways, token: "emojivoto-token", namespace: "books", code: codes.PermissionDenied},
+		{name: "self check without token", call: selfCheck, code: codes.Unauthenticated},
+		{name: "self check", call: selfCheck, token: "nobody-token", code: codes.PermissionDenied},
+		{name: "self check of a tenant", call: selfCheck, token: "emojivoto-token"},
+		{name: "marker of an unauthorized namespace", call: recordMarker, token: "emojivoto-token", namespace: "books", code: codes.PermissionDenied},
+		{name: "marker of an authorized namespace", call: recordMarker, token: "emojivoto-token", namespace: "emojivoto"},
+	} {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			results, err := tc.call(withToken(context.Background(), tc.token), tc.namespace)
+			if tc.code != codes.OK {
+				if status.Code(err) != tc.code {
+					t.Fatalf("Expected a %s error, got %v", tc.code, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			sort.Strings(results)
+			if strings.Join(results, ",") != strings.Join(tc.expected, ",") {
+				t.Fatalf("Expected %v, got %v", tc.expected, results)
+			}
+		})
+	}
+
+	mockProm.QueriesExecuted = nil
+	_, err := server.ListPods(withToken(context.Background(), "emojivoto-token"), &pb.ListPodsRequest{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	expected := `max(process_start_time_seconds{namespace=~"emojivoto"}) by (pod, namespace)`
+	if len(mockProm.QueriesExecuted) != 1 || mockProm.QueriesExecuted[0] != expected {
+		t.Errorf("Expected the query %s, got %v", expected, mockProm.QueriesExecuted)
+	}
+}
+
+func TestTenantDeploymentMarker(t *testing.T) {
+	k8sAPI, _ := newFakeTenantAPI(t)
+	server := newTenantServer(newGrpcServer(&prometheus.MockProm{}, k8sAPI, "linkerd", "cluster.local", []string{}, false), k8sAPI)
+	h := &handler{grpcServer: server}
+
+	for _, tc := range []struct {
+		name      string
+		token     string
+		namespace string
+		status    int
+	}{
+		{name: "no token", namespace: "emojivoto", status: http.StatusUnauthorized},
+		{name: "unauthorized namespace", token: "emojivoto-token", namespace: "books", status: http.StatusForbidden},
+		{name: "authorized namespace", token: "emojivoto-token", namespace: "emojivoto", status: http.StatusNoContent},
+	} {
+		tc := tc // pin
+		t.Run(tc.name, func(t *testing.T) {
+			body := `{"resource": {"namespace": "` + tc.namespace + `", "type": "deploy", "name": "vote-bot"}}`
+			req := httptest.NewRequest(http.MethodPost, deploymentMarkerPath, strings.NewReader(body))
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tc.status {
+				t.Fatalf("Expected status %d, got %d: %s", tc.status, rec.Code, rec.Body)
+			}
+		})
+	}
+
+	for namespace, expected := range map[string]int{"emojivoto": 1, "books": 0} {
+		markers, err := server.listRecordedMarkers(context.Background(), namespace, time.Now().Add(-time.Minute))
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if len(markers) != expected {
+			t.Fatalf("Expected %d markers in %s, got %v", expected, namespace, markers)
+		}
+	}
+}
@@ -374,6 +374,7 @@ func newMockGrpcServer(exp expectedStatRPC) (*prometheus.MockProm, *grpcServer,
 		"linkerd",
 		"cluster.local",
 		[]string{},
+		false,
 	)
 
 	k8sAPI.Sync(nil)
@@ -36,7 +36,15 @@ type StatsSummaryRequestParams struct {
 	FromName      string
 	SkipStats     bool
 	TCPStats      bool
+	Baseline      bool
+	ByClient      bool
 	LabelSelector string
+	// DeploymentMarkers requests the rollouts of the resources within the
+	// time window
+	DeploymentMarkers bool
+	// SuccessRateObjective requests the error budget of the resources
+	// against this success rate, between 0 and 1
+	SuccessRateObjective float64
 }
 
 // EdgesRequestParams contains parameters that are used to build
@@ -110,6 +118,11 @@ func BuildStatSummaryRequest(p StatsSummaryRequestParams) (*pb.StatSummaryReques
 		TimeWindow: window,
 		SkipStats:  p.SkipStats,
 		TcpStats:   p.TCPStats,
+		Baseline:   p.Baseline,
+		ByClient:   p.ByClient,
+
+		DeploymentMarkers:    p.DeploymentMarkers,
+		SuccessRateObjective: p.SuccessRateObjective,
 	}
 
 	if p.ToName != "" || p.ToType != "" || p.ToNamespace != "" {
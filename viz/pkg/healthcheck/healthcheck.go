@@ -117,7 +117,7 @@ func (hc *HealthChecker) VizCategory() *healthcheck.Category {
 				identityName := fmt.Sprintf("tap.%s.svc", hc.vizNamespace)
 				return hc.CheckCertAndAnchors(cert, anchors, identityName)
 			}),
-		*healthcheck.NewChecker("tap API server cert is valid for at least 60 days").
+		*healthcheck.NewChecker(fmt.Sprintf("tap API server cert is valid for at least %s", hc.ExpiryWindowDescription())).
 			WithHintAnchor("l5d-tap-cert-not-expiring-soon").
 			Warning().
 			WithCheck(func(ctx context.Context) error {
@@ -0,0 +1,216 @@
+package api
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/duration"
+	metricsPb "github.com/linkerd/linkerd2/viz/metrics-api/gen/viz"
+	pb "github.com/linkerd/linkerd2/viz/tap/gen/tap"
+	"github.com/linkerd/linkerd2/viz/tap/pkg"
+)
+
+// maxPendingStreams bounds the number of streams whose events are held by a
+// filteringStream while waiting to know whether they're selected.
+const maxPendingStreams = 10000
+
+type (
+	streamKey struct {
+		base   uint32
+		stream uint64
+	}
+
+	pendingStream struct {
+		started time.Time
+		events  []*pb.TapEvent
+	}
+
+	// filteringStream only sends the events of the streams selected by a
+	// StreamFilter. The events of a stream are held until it's known whether
+	// it's selected: when its response completes, or when it's been open for
+	// longer than the minimum latency.
+	filteringStream struct {
+		pb.Tap_TapByResourceServer
+		filter pkg.StreamFilter
+		now    func() time.Time
+
+		sync.Mutex
+		pending  map[streamKey]*pendingStream
+		selected map[streamKey]struct{}
+		stopped  bool
+	}
+)
+
+func newFilteringStream(stream pb.Tap_TapByResourceServer, filter pkg.StreamFilter) *filteringStream {
+	return &filteringStream{
+		Tap_TapByResourceServer: stream,
+		filter:                  filter,
+		now:                     time.Now,
+		pending:                 make(map[streamKey]*pendingStream),
+		selected:                make(map[streamKey]struct{}),
+	}
+}
+
+// Send holds or sends the event, depending on whether its stream is
+// selected.
+func (s *filteringStream) Send(event *pb.TapEvent) error {
+	s.Lock()
+	defer s.Unlock()
+
+	http := event.GetHttp()
+	switch ev := http.GetEvent().(type) {
+	case *pb.TapEvent_Http_RequestInit_:
+		if s.filter.RequestID != "" && requestID(ev.RequestInit.GetHeaders()) != s.filter.RequestID {
+			return nil
+		}
+		if len(s.pending) >= maxPendingStreams {
+			return nil
+		}
+		key := idToKey(ev.RequestInit.GetId())
+		s.pending[key] = &pendingStream{
+			started: s.now(),
+			events:  []*pb.TapEvent{event},
+		}
+		// Streams only selected by their request id don't need to be held
+		if s.filter.MinLatency == 0 && !s.filter.Incomplete {
+			return s.selectStream(key)
+		}
+		return nil
+
+	case *pb.TapEvent_Http_ResponseInit_:
+		key := idToKey(ev.ResponseInit.GetId())
+		if _, ok := s.selected[key]; ok {
+			return s.Tap_TapByResourceServer.Send(event)
+		}
+		pending, ok := s.pending[key]
+		if !ok {
+			return nil
+		}
+		pending.events = append(pending.events, event)
+		// Without the incomplete filter, a slow response is selected as soon
+		// as its headers are received
+		if !s.filter.Incomplete && s.isSlow(ev.ResponseInit.GetSinceRequestInit()) {
+			return s.selectStream(key)
+		}
+		return nil
+
+	case *pb.TapEvent_Http_ResponseEnd_:
+		key := idToKey(ev.ResponseEnd.GetId())
+		if _, ok := s.selected[key]; ok {
+			delete(s.selected, key)
+			return s.Tap_TapByResourceServer.Send(event)
+		}
+		pending, ok := s.pending[key]
+		if !ok {
+			return nil
+		}
+		pending.events = append(pending.events, event)
+		if (s.filter.MinLatency == 0 || s.isSlow(ev.ResponseEnd.GetSinceRequestInit())) &&
+			(!s.filter.Incomplete || isIncomplete(ev.ResponseEnd.GetEos())) {
+			err := s.selectStream(key)
+			delete(s.selected, key)
+			return err
+		}
+		delete(s.pending, key)
+		return nil
+	}
+
+	return nil
+}
+
+// expire selects the streams that have been open for longer than the
+// minimum latency; they're either slow or timing out.
+func (s *filteringStream) expire() error {
+	if s.filter.MinLatency == 0 {
+		return nil
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if s.stopped {
+		return nil
+	}
+	now := s.now()
+	for key, pending := range s.pending {
+		if now.Sub(pending.started) >= s.filter.MinLatency {
+			if err := s.selectStream(key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// expireEvery calls expire periodically until the context is cancelled or
+// the stream is stopped.
+func (s *filteringStream) expireEvery(ctx context.Context, period time.Duration) {
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.expire(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// stop prevents any further events from being sent by expire, once the
+// underlying stream is done.
+func (s *filteringStream) stop() {
+	s.Lock()
+	defer s.Unlock()
+	s.stopped = true
+}
+
+func (s *filteringStream) selectStream(key streamKey) error {
+	pending := s.pending[key]
+	delete(s.pending, key)
+	s.selected[key] = struct{}{}
+	for _, event := range pending.events {
+		if err := s.Tap_TapByResourceServer.Send(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *filteringStream) isSlow(d *duration.Duration) bool {
+	if s.filter.MinLatency == 0 {
+		return true
+	}
+	latency, err := ptypes.Duration(d)
+	return err == nil && latency >= s.filter.MinLatency
+}
+
+// isIncomplete returns true if the stream was reset before completing.
+func isIncomplete(eos *metricsPb.Eos) bool {
+	if eos == nil {
+		return true
+	}
+	_, reset := eos.GetEnd().(*metricsPb.Eos_ResetErrorCode)
+	return reset
+}
+
+// requestID returns the value of the request id header, if any.
+func requestID(headers *metricsPb.Headers) string {
+	for _, h := range headers.GetHeaders() {
+		if strings.EqualFold(h.GetName(), pkg.RequestIDHeader) {
+			if v := h.GetValueStr(); v != "" {
+				return v
+			}
+			return string(h.GetValueBin())
+		}
+	}
+	return ""
+}
+
+func idToKey(id *pb.TapEvent_Http_StreamId) streamKey {
+	return streamKey{base: id.GetBase(), stream: id.GetStream()}
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	metricsPb "github.com/linkerd/linkerd2/viz/metrics-api/gen/viz"
+	pb "github.com/linkerd/linkerd2/viz/tap/gen/tap"
+	"github.com/linkerd/linkerd2/viz/tap/pkg"
+)
+
+type recordingStream struct {
+	pb.Tap_TapByResourceServer
+	events []*pb.TapEvent
+}
+
+func (s *recordingStream) Send(event *pb.TapEvent) error {
+	s.events = append(s.events, event)
+	return nil
+}
+
+func streamID(id uint64) *pb.TapEvent_Http_StreamId {
+	return &pb.TapEvent_Http_StreamId{Base: 1, Stream: id}
+}
+
+func requestInit(id uint64) *pb.TapEvent {
+	return pkg.CreateTapEvent(&pb.TapEvent_Http{
+		Event: &pb.TapEvent_Http_RequestInit_{
+			RequestInit: &pb.TapEvent_Http_RequestInit{Id: streamID(id)},
+		},
+	}, nil, pb.TapEvent_INBOUND)
+}
+
+func responseEnd(id uint64, latency time.Duration, eos *metricsPb.Eos) *pb.TapEvent {
+	return pkg.CreateTapEvent(&pb.TapEvent_Http{
+		Event: &pb.TapEvent_Http_ResponseEnd_{
+			ResponseEnd: &pb.TapEvent_Http_ResponseEnd{
+				Id:               streamID(id),
+				SinceRequestInit: ptypes.DurationProto(latency),
+				Eos:              eos,
+			},
+		},
+	}, nil, pb.TapEvent_INBOUND)
+}
+
+func TestFilteringStream(t *testing.T) {
+	grpcOK := &metricsPb.Eos{End: &metricsPb.Eos_GrpcStatusCode{GrpcStatusCode: 0}}
+	reset := &metricsPb.Eos{End: &metricsPb.Eos_ResetErrorCode{ResetErrorCode: 2}}
+
+	t.Run("Only sends the events of slow requests", func(t *testing.T) {
+		inner := &recordingStream{}
+		s := newFilteringStream(inner, pkg.StreamFilter{MinLatency: 500 * time.Millisecond})
+
+		for _, ev := range []*pb.TapEvent{
+			requestInit(1),
+			requestInit(2),
+			responseEnd(1, 100*time.Millisecond, grpcOK),
+			responseEnd(2, time.Second, grpcOK),
+		} {
+			if err := s.Send(ev); err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+		}
+
+		if len(inner.events) != 2 {
+			t.Fatalf("Expected the 2 events of the slow request, got %d events", len(inner.events))
+		}
+		for _, ev := range inner.events {
+			if ev.GetHttp().GetRequestInit().GetId().GetStream() != 2 && ev.GetHttp().GetResponseEnd().GetId().GetStream() != 2 {
+				t.Fatalf("Unexpected event: %v", ev)
+			}
+		}
+	})
+
+	t.Run("Sends requests still pending after the minimum latency", func(t *testing.T) {
+		inner := &recordingStream{}
+		s := newFilteringStream(inner, pkg.StreamFilter{MinLatency: 500 * time.Millisecond})
+		now := time.Now()
+		s.now = func() time.Time { return now }
+
+		if err := s.Send(requestInit(1)); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if err := s.expire(); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if len(inner.events) != 0 {
+			t.Fatalf("Expected no events before the minimum latency, got %v", inner.events)
+		}
+
+		now = now.Add(time.Second)
+		if err := s.expire(); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if len(inner.events) != 1 {
+			t.Fatalf("Expected the pending request to be sent, got %v", inner.events)
+		}
+
+		// the rest of the stream is sent as it happens
+		if err := s.Send(responseEnd(1, 2*time.Second, grpcOK)); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if len(inner.events) != 2 {
+			t.Fatalf("Expected the response end to be sent, got %v", inner.events)
+		}
+	})
+
+	t.Run("Only sends the events of incomplete requests", func(t *testing.T) {
+		inner := &recordingStream{}
+		s := newFilteringStream(inner, pkg.StreamFilter{Incomplete: true})
+
+		for _, ev := range []*pb.TapEvent{
+			requestInit(1),
+			requestInit(2),
+			responseEnd(1, time.Second, grpcOK),
+			responseEnd(2, time.Millisecond, reset),
+		} {
+			if err := s.Send(ev); err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+		}
+
+		if len(inner.events) != 2 || inner.events[1].GetHttp().GetResponseEnd().GetEos() != reset {
+			t.Fatalf("Expected the 2 events of the reset request, got %v", inner.events)
+		}
+	})
+	t.Run("Only sends the events of the request with the given id", func(t *testing.T) {
+		inner := &recordingStream{}
+		s := newFilteringStream(inner, pkg.StreamFilter{RequestID: "abc"})
+
+		withID := func(ev *pb.TapEvent, id string) *pb.TapEvent {
+			ev.GetHttp().GetRequestInit().Headers = &metricsPb.Headers{
+				Headers: []*metricsPb.Headers_Header{
+					{Name: "X-Request-Id", Value: &metricsPb.Headers_Header_ValueStr{ValueStr: id}},
+				},
+			}
+			return ev
+		}
+
+		if err := s.Send(withID(requestInit(1), "xyz")); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if err := s.Send(withID(requestInit(2), "abc")); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		// the matching request is sent without waiting for its response
+		if len(inner.events) != 1 || inner.events[0].GetHttp().GetRequestInit().GetId().GetStream() != 2 {
+			t.Fatalf("Expected the matching request to be sent, got %v", inner.events)
+		}
+
+		for _, ev := range []*pb.TapEvent{
+			responseEnd(1, time.Millisecond, grpcOK),
+			responseEnd(2, time.Millisecond, grpcOK),
+		} {
+			if err := s.Send(ev); err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+		}
+		if len(inner.events) != 2 || inner.events[1].GetHttp().GetResponseEnd().GetId().GetStream() != 2 {
+			t.Fatalf("Expected the 2 events of the matching request, got %v", inner.events)
+		}
+	})
+}
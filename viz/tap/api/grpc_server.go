@@ -29,10 +29,8 @@ import (
 	"google.golang.org/grpc/status"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/client-go/tools/cache"
 )
 
-const ipIndex = "ip"
 const defaultMaxRps = 100.0
 
 // GRPCTapServer describes the gRPC server implementing pb.TapServer
@@ -533,8 +531,11 @@ func NewGrpcTapServer(
 	trustDomain string,
 	k8sAPI *k8s.API,
 ) *GRPCTapServer {
-	k8sAPI.Pod().Informer().AddIndexers(cache.Indexers{ipIndex: indexByIP})
-	k8sAPI.Node().Informer().AddIndexers(cache.Indexers{ipIndex: indexByIP})
+	// the IP indexes resolve the peers of the tapped requests, and the owner
+	// index the pods of the tapped workloads
+	if err := k8sAPI.AddIndexes(k8s.PodIP, k8s.NodeIP, k8s.PodOwner); err != nil {
+		log.Errorf("Failed to add indexes: %s", err)
+	}
 
 	return newGRPCTapServer(tapPort, controllerNamespace, trustDomain, k8sAPI)
 }
@@ -558,23 +559,6 @@ func newGRPCTapServer(
 	return srv
 }
 
-func indexByIP(obj interface{}) ([]string, error) {
-	switch v := obj.(type) {
-	case *corev1.Pod:
-		return []string{v.Status.PodIP}, nil
-	case *corev1.Node:
-		addresses := make([]string, 0)
-		for _, address := range v.Status.Addresses {
-			if address.Type == corev1.NodeInternalIP {
-				log.Debugf("Indexing node address: %s", address.Address)
-				addresses = append(addresses, address.Address)
-			}
-		}
-		return addresses, nil
-	}
-	return []string{""}, fmt.Errorf("object is not a pod nor a node")
-}
-
 // hydrateEventLabels attempts to hydrate the metadata labels for an event's
 // source and (if the event was reported by an inbound proxy) destination,
 // and adds them to the event's `SourceMeta` and `DestinationMeta` fields.
@@ -634,7 +618,7 @@ func (s *GRPCTapServer) hydrateIPLabels(ctx context.Context, ip *netPb.IPAddress
 func (s *GRPCTapServer) resourceForIP(ip *netPb.IPAddress) (runtime.Object, error) {
 	ipStr := addr.PublicIPToString(ip)
 
-	nodes, err := s.k8sAPI.Node().Informer().GetIndexer().ByIndex(ipIndex, ipStr)
+	nodes, err := s.k8sAPI.Node().Informer().GetIndexer().ByIndex(k8s.IPIndex, ipStr)
 	if err != nil {
 		return nil, err
 	}
@@ -643,7 +627,7 @@ func (s *GRPCTapServer) resourceForIP(ip *netPb.IPAddress) (runtime.Object, erro
 		return nodes[0].(*corev1.Node), nil
 	}
 
-	pods, err := s.k8sAPI.Pod().Informer().GetIndexer().ByIndex(ipIndex, ipStr)
+	pods, err := s.k8sAPI.Pod().Informer().GetIndexer().ByIndex(k8s.IPIndex, ipStr)
 	if err != nil {
 		return nil, err
 	}
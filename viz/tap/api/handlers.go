@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/go-openapi/spec"
 	"github.com/julienschmidt/httprouter"
@@ -22,11 +23,16 @@ import (
 	"k8s.io/apimachinery/pkg/version"
 )
 
+// filterExpirePeriod is how often the streams held by a filtered tap are
+// checked for having been open longer than the minimum latency.
+const filterExpirePeriod = 100 * time.Millisecond
+
 type handler struct {
 	k8sAPI         *k8s.API
 	usernameHeader string
 	groupHeader    string
 	grpcTapServer  pb.TapServer
+	quotas         *quotaTracker
 	log            *logrus.Entry
 }
 
@@ -163,6 +169,13 @@ func (h *handler) handleTap(w http.ResponseWriter, req *http.Request, p httprout
 		return
 	}
 
+	filter, err := pkg.ParseStreamFilter(req.URL.Query())
+	if err != nil {
+		h.log.Error(err)
+		protohttp.WriteErrorToHTTPResponse(w, err)
+		return
+	}
+
 	flushableWriter, err := protohttp.NewStreamingWriter(w)
 	if err != nil {
 		h.log.Error(err)
@@ -170,8 +183,23 @@ func (h *handler) handleTap(w http.ResponseWriter, req *http.Request, p httprout
 		return
 	}
 
-	serverStream := serverStream{w: flushableWriter, req: req, log: h.log}
-	err = h.grpcTapServer.TapByResource(&tapReq, &serverStream)
+	var stream pb.Tap_TapByResourceServer = &serverStream{w: flushableWriter, req: req, log: h.log}
+	release, stream, err := h.quotas.acquire(req.Header.Get(h.usernameHeader), stream)
+	if err != nil {
+		h.log.Error(err)
+		renderJSONError(w, err, http.StatusTooManyRequests)
+		return
+	}
+	defer release()
+	if !filter.IsEmpty() {
+		filtered := newFilteringStream(stream, *filter)
+		go filtered.expireEvery(req.Context(), filterExpirePeriod)
+		stream = filtered
+	}
+	err = h.grpcTapServer.TapByResource(&tapReq, stream)
+	if filtered, ok := stream.(*filteringStream); ok {
+		filtered.stop()
+	}
 	if err != nil {
 		h.log.Error(err)
 		protohttp.WriteErrorToHTTPResponse(flushableWriter, err)
@@ -26,6 +26,8 @@ func Main(args []string) {
 	tapPort := cmd.Uint("tap-port", 4190, "proxy tap port to connect to")
 	disableCommonNames := cmd.Bool("disable-common-names", false, "disable checks for Common Names (for development)")
 	trustDomain := cmd.String("identity-trust-domain", defaultDomain, "configures the name suffix used for identities")
+	maxConcurrentTaps := cmd.Int("max-concurrent-taps-per-user", 0, "maximum number of taps a user can have open at once (0 for no limit)")
+	maxEventsPerSec := cmd.Float64("max-events-per-sec-per-user", 0, "maximum number of tap events sent per second to a user, across all of its taps (0 for no limit)")
 	traceCollector := flags.AddTraceFlags(cmd)
 	flags.ConfigureAndParse(cmd, args)
 	ctx := context.Background()
@@ -50,6 +52,13 @@ func Main(args []string) {
 	if err != nil {
 		log.Fatalf("Failed to initialize K8s API: %s", err)
 	}
+	quotas := TapQuotas{
+		MaxConcurrentTaps: *maxConcurrentTaps,
+		MaxEventsPerSec:   *maxEventsPerSec,
+	}
+	if err := quotas.Validate(); err != nil {
+		log.Fatalf("Invalid tap quotas: %s", err)
+	}
 	log.Infof("Using trust domain: %s", *trustDomain)
 	if *traceCollector != "" {
 		if err := trace.InitializeTracing("linkerd-tap", *traceCollector); err != nil {
@@ -57,7 +66,7 @@ func Main(args []string) {
 		}
 	}
 	grpcTapServer := NewGrpcTapServer(*tapPort, *apiNamespace, *trustDomain, k8sAPI)
-	apiServer, err := NewServer(ctx, *apiServerAddr, k8sAPI, grpcTapServer, *disableCommonNames)
+	apiServer, err := NewServer(ctx, *apiServerAddr, k8sAPI, grpcTapServer, *disableCommonNames, quotas)
 	if err != nil {
 		log.Fatal(err.Error())
 	}
@@ -0,0 +1,132 @@
+package api
+
+import (
+	"fmt"
+	"sync"
+
+	pb "github.com/linkerd/linkerd2/viz/tap/gen/tap"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TapQuotas limits the taps of each user. A zero value disables the
+// corresponding limit.
+type TapQuotas struct {
+	// MaxConcurrentTaps is the number of taps a user can have open at once
+	MaxConcurrentTaps int
+	// MaxEventsPerSec is the number of events sent per second to a user,
+	// across all of its taps. The events above this rate are dropped.
+	MaxEventsPerSec float64
+}
+
+// Validate returns an error if the quotas can't be used.
+func (q TapQuotas) Validate() error {
+	if q.MaxConcurrentTaps < 0 {
+		return fmt.Errorf("invalid max concurrent taps %d: must not be negative", q.MaxConcurrentTaps)
+	}
+	if q.MaxEventsPerSec < 0 {
+		return fmt.Errorf("invalid max events per second %v: must not be negative", q.MaxEventsPerSec)
+	}
+	return nil
+}
+
+var (
+	tapQuotaRejections = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "tap_quota_rejections",
+			Help: "Increments when a tap is rejected because its user has reached the max number of concurrent taps",
+		},
+	)
+
+	tapQuotaDroppedEvents = promauto.NewCounter(
+		prometheus.CounterOpts{
+			Name: "tap_quota_dropped_events",
+			Help: "Increments when a tap event is dropped because its user has reached the max number of events per second",
+		},
+	)
+)
+
+type (
+	// quotaTracker enforces TapQuotas, keeping track of the taps open by
+	// each user.
+	quotaTracker struct {
+		quotas TapQuotas
+
+		sync.Mutex
+		users map[string]*userTaps
+	}
+
+	userTaps struct {
+		open    int
+		limiter *rate.Limiter
+	}
+
+	// rateLimitedStream drops the events sent above the rate of its limiter.
+	rateLimitedStream struct {
+		pb.Tap_TapByResourceServer
+		limiter *rate.Limiter
+	}
+)
+
+func newQuotaTracker(quotas TapQuotas) *quotaTracker {
+	return &quotaTracker{
+		quotas: quotas,
+		users:  make(map[string]*userTaps),
+	}
+}
+
+// acquire registers a new tap for the user, returning a function releasing
+// it once the tap is done and the stream its events must be sent to. It
+// returns a ResourceExhausted error if the user already has the max number of
+// concurrent taps open.
+func (q *quotaTracker) acquire(user string, stream pb.Tap_TapByResourceServer) (func(), pb.Tap_TapByResourceServer, error) {
+	if q == nil {
+		return func() {}, stream, nil
+	}
+
+	q.Lock()
+	defer q.Unlock()
+
+	taps, ok := q.users[user]
+	if !ok {
+		taps = &userTaps{}
+		if q.quotas.MaxEventsPerSec > 0 {
+			burst := int(q.quotas.MaxEventsPerSec)
+			if burst < 1 {
+				burst = 1
+			}
+			taps.limiter = rate.NewLimiter(rate.Limit(q.quotas.MaxEventsPerSec), burst)
+		}
+	}
+	if q.quotas.MaxConcurrentTaps > 0 && taps.open >= q.quotas.MaxConcurrentTaps {
+		tapQuotaRejections.Inc()
+		return nil, nil, status.Errorf(codes.ResourceExhausted, "user <%s> has reached the max number of concurrent taps (%d)", user, q.quotas.MaxConcurrentTaps)
+	}
+	taps.open++
+	q.users[user] = taps
+
+	release := func() {
+		q.Lock()
+		defer q.Unlock()
+		taps.open--
+		if taps.open == 0 {
+			delete(q.users, user)
+		}
+	}
+	if taps.limiter != nil {
+		stream = &rateLimitedStream{Tap_TapByResourceServer: stream, limiter: taps.limiter}
+	}
+	return release, stream, nil
+}
+
+// Send sends the event, unless the rate of the limiter has been exceeded.
+func (s *rateLimitedStream) Send(event *pb.TapEvent) error {
+	if !s.limiter.Allow() {
+		tapQuotaDroppedEvents.Inc()
+		return nil
+	}
+	return s.Tap_TapByResourceServer.Send(event)
+}
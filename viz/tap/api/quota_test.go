@@ -0,0 +1,77 @@
+package api
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestQuotaTrackerConcurrentTaps(t *testing.T) {
+	q := newQuotaTracker(TapQuotas{MaxConcurrentTaps: 2})
+
+	release1, _, err := q.acquire("alice", &recordingStream{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	if _, _, err := q.acquire("alice", &recordingStream{}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	_, _, err = q.acquire("alice", &recordingStream{})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Expected a ResourceExhausted error, got %v", err)
+	}
+
+	// the quota is per user
+	if _, _, err := q.acquire("bob", &recordingStream{}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	release1()
+	if _, _, err := q.acquire("alice", &recordingStream{}); err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+}
+
+func TestQuotaTrackerEventsPerSec(t *testing.T) {
+	q := newQuotaTracker(TapQuotas{MaxEventsPerSec: 5})
+
+	recorder1 := &recordingStream{}
+	_, stream1, err := q.acquire("alice", recorder1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+	recorder2 := &recordingStream{}
+	_, stream2, err := q.acquire("alice", recorder2)
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	// the rate is shared by all the taps of the user
+	for i := uint64(0); i < 5; i++ {
+		if err := stream1.Send(requestInit(i)); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if err := stream2.Send(requestInit(i)); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+	}
+	if sent := len(recorder1.events) + len(recorder2.events); sent != 5 {
+		t.Fatalf("Expected 5 events to be sent, got %d", sent)
+	}
+}
+
+func TestQuotaTrackerNoLimits(t *testing.T) {
+	q := newQuotaTracker(TapQuotas{})
+
+	recorder := &recordingStream{}
+	for i := 0; i < 10; i++ {
+		_, stream, err := q.acquire("alice", recorder)
+		if err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if stream != recorder {
+			t.Fatal("Expected the stream not to be wrapped without an events limit")
+		}
+	}
+}
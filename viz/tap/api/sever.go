@@ -38,6 +38,7 @@ func NewServer(
 	k8sAPI *k8s.API,
 	grpcTapServer pb.TapServer,
 	disableCommonNames bool,
+	quotas TapQuotas,
 ) (*Server, error) {
 	updateEvent := make(chan struct{})
 	errEvent := make(chan error)
@@ -81,6 +82,7 @@ func NewServer(
 		usernameHeader: usernameHeader,
 		groupHeader:    groupHeader,
 		grpcTapServer:  grpcTapServer,
+		quotas:         newQuotaTracker(quotas),
 		log:            log,
 	}
 
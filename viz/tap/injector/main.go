@@ -23,6 +23,7 @@ func Main(args []string) {
 		[]k8s.APIResource{k8s.NS},
 		Mutate(*tapSvcName),
 		"tap-injector",
+		"viz",
 		*metricsAddr,
 		*addr,
 		*kubeconfig,
@@ -0,0 +1,79 @@
+package pkg
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	minLatencyParam = "minLatency"
+	incompleteParam = "incomplete"
+	requestIDParam  = "requestId"
+
+	// RequestIDHeader is the request header whose value is matched by the
+	// RequestID filter.
+	RequestIDHeader = "x-request-id"
+)
+
+// StreamFilter selects the streams whose events are returned by the tap
+// APIServer, based on how they completed. It's applied server-side, so that
+// the events of the other streams aren't sent to the client.
+type StreamFilter struct {
+	// MinLatency only selects the streams whose response took at least this
+	// long, including the streams that are still open after this long.
+	MinLatency time.Duration
+
+	// Incomplete only selects the streams that were reset before completing.
+	Incomplete bool
+
+	// RequestID only selects the streams whose request carries this value in
+	// its RequestIDHeader. It requires the headers to be extracted.
+	RequestID string
+}
+
+// IsEmpty returns true if the filter selects all streams.
+func (f *StreamFilter) IsEmpty() bool {
+	return f == nil || (f.MinLatency == 0 && !f.Incomplete && f.RequestID == "")
+}
+
+// Query encodes the filter as the query parameters of a tap request.
+func (f *StreamFilter) Query() url.Values {
+	query := url.Values{}
+	if f.IsEmpty() {
+		return query
+	}
+	if f.MinLatency > 0 {
+		query.Set(minLatencyParam, f.MinLatency.String())
+	}
+	if f.Incomplete {
+		query.Set(incompleteParam, "true")
+	}
+	if f.RequestID != "" {
+		query.Set(requestIDParam, f.RequestID)
+	}
+	return query
+}
+
+// ParseStreamFilter decodes a filter from the query parameters of a tap
+// request.
+func ParseStreamFilter(query url.Values) (*StreamFilter, error) {
+	filter := &StreamFilter{}
+	if v := query.Get(minLatencyParam); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			return nil, fmt.Errorf("invalid %s parameter: %q", minLatencyParam, v)
+		}
+		filter.MinLatency = d
+	}
+	if v := query.Get(incompleteParam); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s parameter: %q", incompleteParam, v)
+		}
+		filter.Incomplete = b
+	}
+	filter.RequestID = query.Get(requestIDParam)
+	return filter, nil
+}
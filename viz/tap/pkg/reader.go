@@ -28,6 +28,12 @@ const TapRbacURL = "https://linkerd.io/tap-rbac"
 // Reader initiates a TapByResourceRequest and returns a buffered Reader.
 // It is the caller's responsibility to call Close() on the io.ReadCloser.
 func Reader(ctx context.Context, k8sAPI *k8s.KubernetesAPI, req *pb.TapByResourceRequest) (*bufio.Reader, io.ReadCloser, error) {
+	return FilteredReader(ctx, k8sAPI, req, nil)
+}
+
+// FilteredReader is like Reader, but only returns the events of the streams
+// selected by the filter.
+func FilteredReader(ctx context.Context, k8sAPI *k8s.KubernetesAPI, req *pb.TapByResourceRequest, filter *StreamFilter) (*bufio.Reader, io.ReadCloser, error) {
 	client, err := k8sAPI.NewClient()
 	if err != nil {
 		return nil, nil, err
@@ -43,6 +49,7 @@ func Reader(ctx context.Context, k8sAPI *k8s.KubernetesAPI, req *pb.TapByResourc
 		return nil, nil, err
 	}
 	url.Path = fmt.Sprintf("%s%s", url.Path, TapReqToURL(req))
+	url.RawQuery = filter.Query().Encode()
 
 	httpReq, err := http.NewRequest(
 		http.MethodPost,
@@ -9,6 +9,11 @@ import (
 	tapPb "github.com/linkerd/linkerd2/viz/tap/gen/tap"
 )
 
+// ValidTapTargets specifies resource types allowed as a tap target. Services
+// are resolved to the pods they select, so they can be tapped by the name
+// clients actually call.
+var ValidTapTargets = append([]string{k8s.Service}, util.ValidTargets...)
+
 // ValidTapDestinations specifies resource types allowed as a tap destination:
 // - destination resource on an outbound 'to' query
 var ValidTapDestinations = []string{
@@ -47,7 +52,7 @@ func BuildTapByResourceRequest(params TapRequestParams) (*tapPb.TapByResourceReq
 	if err != nil {
 		return nil, fmt.Errorf("target resource invalid: %s", err)
 	}
-	if !contains(util.ValidTargets, target.Type) {
+	if !contains(ValidTapTargets, target.Type) {
 		return nil, fmt.Errorf("unsupported resource type [%s]", target.Type)
 	}
 
@@ -2,6 +2,7 @@ package srv
 
 import (
 	"bytes"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -122,7 +123,12 @@ func (h *handler) handleAPIServices(w http.ResponseWriter, req *http.Request, p
 
 func (h *handler) handleAPIStat(w http.ResponseWriter, req *http.Request, p httprouter.Params) {
 	// Try to get stat summary from cache using the query as key
-	cachedResultJSON, ok := h.statCache.Get(req.URL.RawQuery)
+	// The results depend on the user in a metrics API in tenancy mode
+	cacheKey := req.URL.RawQuery
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		cacheKey = fmt.Sprintf("%x?%s", sha256.Sum256([]byte(auth)), cacheKey)
+	}
+	cachedResultJSON, ok := h.statCache.Get(cacheKey)
 	if ok {
 		// Cache hit, render cached json result
 		renderJSONBytes(w, cachedResultJSON.([]byte))
@@ -172,7 +178,7 @@ func (h *handler) handleAPIStat(w http.ResponseWriter, req *http.Request, p http
 		renderJSONError(w, err, http.StatusInternalServerError)
 		return
 	}
-	h.statCache.SetDefault(req.URL.RawQuery, resultJSON.Bytes())
+	h.statCache.SetDefault(cacheKey, resultJSON.Bytes())
 
 	renderJSONBytes(w, resultJSON.Bytes())
 }
@@ -468,6 +474,7 @@ func (h *handler) handleAPIGateways(w http.ResponseWriter, req *http.Request, _
 		TimeWindow:        window,
 		GatewayNamespace:  req.FormValue("gatewayNamespace"),
 		RemoteClusterName: req.FormValue("remoteClusterName"),
+		BySourceIdentity:  req.FormValue("bySourceIdentity") == "true",
 	}
 	result, err := h.apiClient.Gateways(req.Context(), gatewayRequest)
 	if err != nil {
@@ -8,6 +8,7 @@ import (
 	"path"
 	"path/filepath"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/julienschmidt/httprouter"
@@ -15,6 +16,7 @@ import (
 	"github.com/linkerd/linkerd2/pkg/healthcheck"
 	"github.com/linkerd/linkerd2/pkg/k8s"
 	"github.com/linkerd/linkerd2/pkg/prometheus"
+	vizClient "github.com/linkerd/linkerd2/viz/metrics-api/client"
 	vizPb "github.com/linkerd/linkerd2/viz/metrics-api/gen/viz"
 	"github.com/patrickmn/go-cache"
 	log "github.com/sirupsen/logrus"
@@ -74,6 +76,11 @@ Please see https://linkerd.io/dns-rebinding for an explanation of what is happen
 	w.Header().Set("X-Content-Type-Options", "nosniff")
 	w.Header().Set("X-Frame-Options", "SAMEORIGIN")
 	w.Header().Set("X-XSS-Protection", "1; mode=block")
+	// Forward the bearer token of the user, e.g. set by an authenticating
+	// proxy in front of the dashboard, to a metrics API in tenancy mode
+	if auth := req.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		req = req.WithContext(vizClient.WithToken(req.Context(), strings.TrimPrefix(auth, "Bearer ")))
+	}
 	s.router.ServeHTTP(w, req)
 }
 
@@ -178,6 +185,9 @@ func NewServer(
 	server.router.GET("/routes", handler.handleIndex)
 	server.router.GET("/profiles/new", handler.handleProfileDownload)
 
+	// shareable links to dashboard views
+	server.router.GET("/views/:state", handler.handleView)
+
 	// add catch-all parameter to match all files in dir
 	server.router.GET("/dist/*filepath", mkStaticHandler(staticDir))
 
@@ -196,6 +206,7 @@ func NewServer(
 	server.router.GET("/api/resource-definition", handler.handleAPIResourceDefinition)
 	server.router.GET("/api/gateways", handler.handleAPIGateways)
 	server.router.GET("/api/extension", handler.handleGetExtension)
+	server.router.GET("/api/views", handler.handleAPIView)
 
 	// grafana proxy
 	server.handleAllOperationsForPath("/grafana/*grafanapath", handler.handleGrafana)
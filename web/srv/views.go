@@ -0,0 +1,160 @@
+package srv
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// viewStateVersion is the version of the encoded view state schema. It's
+	// bumped on incompatible changes so old links can still be decoded.
+	viewStateVersion = 1
+
+	// maxViewStateSize bounds the size of an encoded view state, keeping the
+	// shareable links within the URL length limits of browsers and proxies.
+	maxViewStateSize = 2048
+
+	viewPathParam   = "path"
+	viewWindowParam = "window"
+)
+
+// viewState describes a dashboard view: the page being displayed along with
+// its time window and filters. It's encoded into the shareable links to the
+// view, so they don't depend on any state stored in the dashboard.
+type viewState struct {
+	Version    int               `json:"v"`
+	Path       string            `json:"path"`
+	TimeWindow string            `json:"window,omitempty"`
+	Filters    map[string]string `json:"filters,omitempty"`
+}
+
+func (v *viewState) validate() error {
+	if v.Version != viewStateVersion {
+		return fmt.Errorf("unsupported view version %d", v.Version)
+	}
+	// Only allow paths to dashboard pages, so that links can't be used to
+	// redirect to other hosts or to the API
+	if !strings.HasPrefix(v.Path, "/") || strings.HasPrefix(v.Path, "//") || strings.ContainsAny(v.Path, "\\?#") {
+		return fmt.Errorf("invalid view path %q", v.Path)
+	}
+	if strings.HasPrefix(v.Path, "/api/") || strings.HasPrefix(v.Path, "/dist/") || strings.HasPrefix(v.Path, "/views/") {
+		return fmt.Errorf("view path %q is not a dashboard page", v.Path)
+	}
+	if v.TimeWindow != "" {
+		if _, err := time.ParseDuration(v.TimeWindow); err != nil {
+			return fmt.Errorf("invalid view time window %q", v.TimeWindow)
+		}
+	}
+	return nil
+}
+
+func (v *viewState) encode() (string, error) {
+	if err := v.validate(); err != nil {
+		return "", err
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	state := base64.RawURLEncoding.EncodeToString(b)
+	if len(state) > maxViewStateSize {
+		return "", errors.New("view has too many filters to be shared")
+	}
+	return state, nil
+}
+
+func decodeViewState(state string) (*viewState, error) {
+	if len(state) > maxViewStateSize {
+		return nil, errors.New("view state is too large")
+	}
+	b, err := base64.RawURLEncoding.DecodeString(state)
+	if err != nil {
+		return nil, fmt.Errorf("invalid view state: %s", err)
+	}
+	var v viewState
+	if err := json.Unmarshal(b, &v); err != nil {
+		return nil, fmt.Errorf("invalid view state: %s", err)
+	}
+	if err := v.validate(); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// url returns the URL of the view's page, relative to the given path prefix.
+func (v *viewState) url(pathPfx string) string {
+	query := url.Values{}
+	if v.TimeWindow != "" {
+		query.Set(viewWindowParam, v.TimeWindow)
+	}
+	keys := make([]string, 0, len(v.Filters))
+	for key := range v.Filters {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		query.Set(key, v.Filters[key])
+	}
+
+	u := strings.TrimSuffix(pathPfx, "/") + v.Path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return u
+}
+
+// handleAPIView encodes the view described by the query parameters into a
+// shareable link. The path and window parameters hold the dashboard page and
+// time window; every other parameter is a filter.
+func (h *handler) handleAPIView(w http.ResponseWriter, req *http.Request, p httprouter.Params) {
+	view := viewState{
+		Version:    viewStateVersion,
+		Path:       req.FormValue(viewPathParam),
+		TimeWindow: req.FormValue(viewWindowParam),
+		Filters:    map[string]string{},
+	}
+	for key, values := range req.URL.Query() {
+		if key == viewPathParam || key == viewWindowParam || len(values) == 0 {
+			continue
+		}
+		view.Filters[key] = values[0]
+	}
+
+	state, err := view.encode()
+	if err != nil {
+		renderJSONError(w, err, http.StatusBadRequest)
+		return
+	}
+
+	pathPfx := proxyPathRegexp.FindString(req.URL.Path)
+	if pathPfx == "" {
+		pathPfx = "/"
+	}
+	renderJSON(w, map[string]string{
+		"state": state,
+		"link":  pathPfx + "views/" + state,
+	})
+}
+
+// handleView redirects a shareable link to the page of the view it encodes.
+func (h *handler) handleView(w http.ResponseWriter, req *http.Request, p httprouter.Params) {
+	view, err := decodeViewState(p.ByName("state"))
+	if err != nil {
+		log.Debugf("Invalid view link: %s", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pathPfx := proxyPathRegexp.FindString(req.URL.Path)
+	http.Redirect(w, req, view.url(pathPfx), http.StatusFound)
+}
@@ -0,0 +1,87 @@
+package srv
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func TestHandleAPIView(t *testing.T) {
+	handler := &handler{}
+
+	t.Run("Returns a link that redirects to the view", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/views?path=/namespaces/emojivoto/deployments/web&window=10m&resource_type=pod", nil)
+		handler.handleAPIView(recorder, req, httprouter.Params{})
+
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("Incorrect StatusCode: %+v", recorder.Code)
+		}
+
+		var rsp map[string]string
+		if err := json.Unmarshal(recorder.Body.Bytes(), &rsp); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+		if !strings.HasPrefix(rsp["link"], "/views/") {
+			t.Fatalf("Unexpected link: %s", rsp["link"])
+		}
+
+		recorder = httptest.NewRecorder()
+		req = httptest.NewRequest("GET", rsp["link"], nil)
+		handler.handleView(recorder, req, httprouter.Params{{Key: "state", Value: rsp["state"]}})
+
+		if recorder.Code != http.StatusFound {
+			t.Fatalf("Incorrect StatusCode: %+v", recorder.Code)
+		}
+		expected := "/namespaces/emojivoto/deployments/web?resource_type=pod&window=10m"
+		if location := recorder.Header().Get("Location"); location != expected {
+			t.Fatalf("Expected redirect to %s, got %s", expected, location)
+		}
+	})
+
+	t.Run("Keeps the proxy path prefix", func(t *testing.T) {
+		prefix := "/api/v1/namespaces/linkerd-viz/services/web:http/proxy/"
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", prefix+"api/views?path=/namespaces", nil)
+		handler.handleAPIView(recorder, req, httprouter.Params{})
+
+		var rsp map[string]string
+		if err := json.Unmarshal(recorder.Body.Bytes(), &rsp); err != nil {
+			t.Fatalf("Unexpected error: %s", err)
+		}
+
+		recorder = httptest.NewRecorder()
+		req = httptest.NewRequest("GET", rsp["link"], nil)
+		handler.handleView(recorder, req, httprouter.Params{{Key: "state", Value: rsp["state"]}})
+
+		if location := recorder.Header().Get("Location"); location != prefix+"namespaces" {
+			t.Fatalf("Expected redirect to %snamespaces, got %s", prefix, location)
+		}
+	})
+
+	t.Run("Rejects views outside the dashboard", func(t *testing.T) {
+		for _, path := range []string{"", "namespaces", "//evil.example.com", "/\\evil.example.com", "/api/check"} {
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/api/views?path="+path, nil)
+			handler.handleAPIView(recorder, req, httprouter.Params{})
+
+			if recorder.Code != http.StatusBadRequest {
+				t.Fatalf("Expected path %q to be rejected, got StatusCode %+v", path, recorder.Code)
+			}
+		}
+	})
+
+	t.Run("Rejects invalid links", func(t *testing.T) {
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/views/not-a-view", nil)
+		handler.handleView(recorder, req, httprouter.Params{{Key: "state", Value: "not-a-view"}})
+
+		if recorder.Code != http.StatusBadRequest {
+			t.Fatalf("Incorrect StatusCode: %+v", recorder.Code)
+		}
+	})
+}